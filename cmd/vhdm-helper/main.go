@@ -0,0 +1,49 @@
+// Package main is the entry point for vhdm-helper, the privileged helper run
+// by vhdm through sudo.
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/rjdinis/vhdm/internal/helper"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		fmt.Fprintf(os.Stderr, "Usage: %s VERB [ARGS]\n\nVerbs:\n%s", helper.Name, helper.Usage())
+		os.Exit(2)
+	}
+
+	argv, err := helper.Command(os.Args[1], os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", helper.Name, err)
+		os.Exit(2)
+	}
+
+	// Check the arguments against the running system: this binary runs as
+	// root without a password, so it must not be pointed at the system disk
+	// or at directories other than the mount points of attached VHDs
+	host, err := helper.LoadHost()
+	if err == nil {
+		err = helper.Check(os.Args[1], os.Args[2:], host)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", helper.Name, err)
+		os.Exit(2)
+	}
+
+	bin, err := exec.LookPath(argv[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", helper.Name, err)
+		os.Exit(127)
+	}
+
+	// Replace the helper so stdio and the exit status pass straight through
+	if err := syscall.Exec(bin, argv, os.Environ()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: exec %s: %v\n", helper.Name, bin, err)
+		os.Exit(126)
+	}
+}
@@ -2,11 +2,9 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/rjdinis/vhdm/internal/cli"
-	"github.com/rjdinis/vhdm/internal/types"
 )
 
 var (
@@ -17,14 +15,7 @@ var (
 
 func main() {
 	rootCmd := cli.NewRootCommand(version, commit, date)
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-
-		// If it's a VHDError with help text, print that too
-		if vhdErr, ok := err.(*types.VHDError); ok && vhdErr.Help != "" {
-			fmt.Fprintf(os.Stderr, "\n%s\n", vhdErr.Help)
-		}
-
-		os.Exit(1)
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
+		os.Exit(cli.ReportError(cmd, err))
 	}
 }
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newAdoptCmd() *cobra.Command {
+	var (
+		devName string
+		vhdPath string
+		force   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "adopt",
+		Short: "Track a VHD that was attached outside vhdm",
+		Long: `Bind an attached device to its VHD file in tracking, without detaching or
+remounting it.
+
+Use this for VHDs attached before vhdm was installed, or with 'wsl.exe --mount'
+by hand: vhdm cannot tell which device such a VHD became, so mount and attach
+stop with "already attached but cannot determine device". 'vhdm devices' lists
+the attached devices; 'vhdm which' can often tell the file of one.
+
+The VHD file must exist and be held open by Windows, as attached VHDs are, and
+its virtual size must equal the size of the device. --force skips these checks
+and rebinds devices or paths tracked otherwise.`,
+		Example: `  vhdm adopt --dev-name sde --vhd-path C:/VMs/disk.vhdx`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAdopt(appContext(cmd), devName, vhdPath, force)
+		},
+	}
+	cmd.Flags().StringVar(&devName, "dev-name", "", "Attached device name (e.g., sde)")
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().BoolVar(&force, "force", false, "Adopt even if the file is not in use, differs in size from the device or is already tracked with another device")
+	cmd.MarkFlagRequired("dev-name")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runAdopt(ctx *AppContext, devName, vhdPath string, force bool) error {
+	log := ctx.Logger
+
+	// Validate
+	devName = strings.TrimPrefix(devName, "/dev/")
+	if err := validation.ValidateDeviceName(devName); err != nil {
+		return &types.VHDError{Op: "adopt", Path: devName, Err: err}
+	}
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "adopt", Path: vhdPath, Err: err}
+	}
+	if !wsl.IsDynamicDevice(devName) {
+		return &types.VHDError{Op: "adopt", Path: "/dev/" + devName, Err: fmt.Errorf("device is a WSL system disk")}
+	}
+
+	log.Debug("Adopt operation starting")
+
+	devices, err := ctx.WSL.GetBlockDevicesWithInfo()
+	if err != nil {
+		return fmt.Errorf("failed to list block devices: %w", err)
+	}
+	var dev *wsl.BlockDevice
+	for i := range devices {
+		if devices[i].Name == devName {
+			dev = &devices[i]
+			break
+		}
+	}
+	if dev == nil {
+		return &types.VHDError{
+			Op:   "adopt",
+			Path: "/dev/" + devName,
+			Err:  types.ErrDeviceNotFound,
+			Help: "List the attached devices with: vhdm devices",
+		}
+	}
+
+	if !ctx.WSL.FileExists(ctx.WSL.ConvertPath(vhdPath)) {
+		return &types.VHDError{Op: "adopt", Path: vhdPath, Err: types.ErrVHDNotFound}
+	}
+
+	// Refuse to rebind a device or path that tracking knows otherwise
+	owner := adoptOwner(ctx, *dev, vhdPath)
+	if !force {
+		if err := checkAdoptConflicts(ctx, *dev, vhdPath, owner); err != nil {
+			return err
+		}
+		inUse, err := ctx.WSL.FileInUseByWindows(vhdPath)
+		if err != nil {
+			log.Debug("Skipping the in-use check: %v", err)
+		} else if !inUse {
+			return &types.VHDError{
+				Op:   "adopt",
+				Path: vhdPath,
+				Err:  fmt.Errorf("the VHD file is not open in Windows, so it is not attached"),
+				Help: "Check the path, or use --force to adopt anyway",
+			}
+		}
+		if err := checkAdoptSize(ctx, devName, vhdPath); err != nil {
+			return err
+		}
+	}
+
+	if owner != "" {
+		log.Warn("Unbinding /dev/%s from %s", devName, owner)
+		err := ctx.Tracker.Update(owner, func(e *types.TrackingEntry) {
+			e.UUID = ""
+			e.DeviceName = ""
+			e.MountPoints = nil
+		})
+		if err != nil {
+			log.Collect("Failed to update tracking of %s: %v", owner, err)
+		}
+	}
+
+	mountPoints := filterEmptyMountPoints(dev.MountPoints)
+	mountPoint := ""
+	if len(mountPoints) > 0 {
+		mountPoint = mountPoints[0]
+	}
+	if err := ctx.Tracker.SaveMapping(vhdPath, dev.UUID, mountPoint, devName); err != nil {
+		return fmt.Errorf("failed to save tracking info: %w", err)
+	}
+	err = ctx.Tracker.Update(vhdPath, func(e *types.TrackingEntry) {
+		e.MountPoints = mountPoints
+		e.Label = dev.Label
+		e.PartUUID = dev.PartUUID
+		if dev.FSType != "" {
+			e.FSType = dev.FSType
+		}
+	})
+	if err != nil {
+		log.Collect("Failed to update tracking: %v", err)
+	}
+
+	// Output
+	log.Success("VHD adopted")
+	return printResult(ctx, AdoptResult{
+		Path:        vhdPath,
+		UUID:        dev.UUID,
+		DeviceName:  devName,
+		MountPoints: mountPoints,
+	})
+}
+
+// adoptOwner returns the other tracked VHD that dev is bound to, if any.
+// Placeholders of auto-discovered VHDs do not count; adopting replaces them.
+func adoptOwner(ctx *AppContext, dev wsl.BlockDevice, vhdPath string) string {
+	var owner string
+	if dev.UUID != "" {
+		owner, _ = ctx.Tracker.LookupPathByUUID(dev.UUID)
+	} else {
+		owner, _ = ctx.Tracker.LookupPathByDevName(dev.Name)
+	}
+	if owner == "" || strings.HasPrefix(owner, "unknown-") || utils.NormalizePath(owner) == utils.NormalizePath(vhdPath) {
+		return ""
+	}
+	return owner
+}
+
+// checkAdoptConflicts fails when dev is tracked under another VHD (owner), or
+// vhdPath is tracked with another filesystem UUID
+func checkAdoptConflicts(ctx *AppContext, dev wsl.BlockDevice, vhdPath, owner string) error {
+	if owner != "" {
+		return &types.VHDError{
+			Op:   "adopt",
+			Path: "/dev/" + dev.Name,
+			Err:  fmt.Errorf("device is already tracked as %s", owner),
+			Help: "Use --force to bind it to this path instead",
+		}
+	}
+
+	if entry, err := ctx.Tracker.GetEntry(vhdPath); err == nil && entry.UUID != "" && entry.UUID != dev.UUID {
+		return &types.VHDError{
+			Op:   "adopt",
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD is tracked with UUID %s, but /dev/%s has %s", entry.UUID, dev.Name, valueOrNone(dev.UUID)),
+			Help: "Check the device name, or use --force if the VHD was reformatted",
+		}
+	}
+	return nil
+}
+
+// checkAdoptSize fails when the virtual size of the VHD file differs from the
+// size of the device, which then belongs to another VHD
+func checkAdoptSize(ctx *AppContext, devName, vhdPath string) error {
+	devSize, err := ctx.WSL.GetDeviceSize(devName)
+	if err != nil {
+		ctx.Logger.Debug("Skipping the size check: %v", err)
+		return nil
+	}
+	img, err := ctx.WSL.GetImageInfo(ctx.WSL.ConvertPath(vhdPath))
+	if err != nil {
+		ctx.Logger.Debug("Skipping the size check: %v", err)
+		return nil
+	}
+	if img.VirtualSize == devSize {
+		return nil
+	}
+	return &types.VHDError{
+		Op:   "adopt",
+		Path: vhdPath,
+		Err:  fmt.Errorf("VHD virtual size is %s, but /dev/%s has %s", utils.BytesToHumanPrecise(img.VirtualSize), devName, utils.BytesToHumanPrecise(devSize)),
+		Help: "Check the device name ('vhdm which' can tell the file of a device), or use --force to adopt anyway",
+	}
+}
+
+// AdoptResult is the outcome of 'vhdm adopt'
+type AdoptResult struct {
+	Path        string   `json:"path"`
+	UUID        string   `json:"uuid,omitempty"`
+	DeviceName  string   `json:"deviceName"`
+	MountPoints []string `json:"mountPoints,omitempty"`
+}
+
+func (r AdoptResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{{"Path", r.Path}}
+	if r.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", r.UUID})
+	}
+	pairs = appendDevice(pairs, r.DeviceName)
+	status := "attached"
+	if len(r.MountPoints) > 0 {
+		pairs = append(pairs, [2]string{"Mount Point", strings.Join(r.MountPoints, ", ")})
+		status = "mounted"
+	}
+	pairs = append(pairs, [2]string{"Status", status + " (adopted)"})
+	return "Adopt Result", pairs
+}
+
+func (r AdoptResult) quiet() quietLine {
+	return quietLine{Key: r.Path, UUID: r.UUID, State: "adopted /dev/" + r.DeviceName}
+}
@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newArchiveCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "Compress a detached VHD for cheap storage",
+		Long: `Compress a detached VHD file with zstd and mark it archived in tracking.
+
+The VHD file is replaced by <path>.zst (e.g., disk.vhdx -> disk.vhdx.zst).
+Use 'unarchive' to restore it before attaching or mounting again.
+
+The VHD must be detached before archiving.`,
+		Example: "  vhdm archive --vhd-path C:/VMs/disk.vhdx",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runArchive(appContext(cmd), vhdPath)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func newUnarchiveCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:   "unarchive",
+		Short: "Restore an archived VHD",
+		Long: `Decompress an archived VHD (<path>.zst) back to its original path
+and clear the archived flag in tracking.`,
+		Example: "  vhdm unarchive --vhd-path C:/VMs/disk.vhdx",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUnarchive(appContext(cmd), vhdPath)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runArchive(ctx *AppContext, vhdPath string) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "archive", Path: vhdPath, Err: err}
+	}
+
+	if err := ensureNotReference(ctx, "archive", vhdPath); err != nil {
+		return err
+	}
+
+	log.Debug("Archive operation starting")
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	archivePath := wslPath + wsl.ArchiveExt
+
+	// Check if file exists
+	if !ctx.WSL.FileExists(wslPath) {
+		if ctx.WSL.FileExists(archivePath) {
+			if ctx.Config.Quiet {
+				printQuiet(quietLine{Key: vhdPath, State: "already archived"})
+			} else {
+				log.Info("VHD is already archived: %s", archivePath)
+			}
+			return nil
+		}
+		return fmt.Errorf("VHD file not found: %s", vhdPath)
+	}
+	if ctx.WSL.FileExists(archivePath) {
+		return fmt.Errorf("archive file already exists: %s - please remove or rename it first", archivePath)
+	}
+
+	// Check if attached
+	uuid, _ := ctx.Tracker.LookupUUIDByPath(vhdPath)
+	if uuid != "" {
+		attached, _ := ctx.WSL.IsAttached(uuid)
+		if attached {
+			return fmt.Errorf("VHD is still attached. Run 'vhdm detach --vhd-path %s' first", vhdPath)
+		}
+	}
+	// Windows holds attached VHD files open, so this also catches VHDs
+	// attached outside vhdm. The original is deleted afterwards, so unlike
+	// other commands archive does not go ahead when the check cannot run.
+	inUse, err := ctx.WSL.FileInUseByWindows(vhdPath)
+	if err != nil {
+		return &types.VHDError{
+			Op:   "archive",
+			Path: vhdPath,
+			Err:  fmt.Errorf("cannot tell whether the VHD is attached: %w", err),
+			Help: "Archiving needs Windows interop to check that the VHD file is not attached or open",
+		}
+	}
+	if inUse {
+		return &types.VHDError{
+			Op:   "archive",
+			Path: vhdPath,
+			Err:  fmt.Errorf("%w: the VHD is attached or open in Windows", types.ErrFileInUse),
+			Help: "Detach it first (see 'vhdm devices' for VHDs attached outside vhdm), or close the program holding it",
+		}
+	}
+
+	originalSize, _ := ctx.WSL.FileSize(wslPath)
+
+	// Compress
+	log.Info("Compressing VHD (this may take a while)...")
+	if err := ctx.WSL.CompressFile(wslPath, archivePath); err != nil {
+		return fmt.Errorf("failed to archive: %w", err)
+	}
+
+	// Remove original only after the archive was written successfully
+	if err := ctx.WSL.DeleteVHD(wslPath); err != nil {
+		return fmt.Errorf("archive created but failed to remove original: %w", err)
+	}
+
+	archiveSize, _ := ctx.WSL.FileSize(archivePath)
+
+	// Update tracking - create entry if VHD was never tracked
+	if _, err := ctx.Tracker.GetEntry(vhdPath); err != nil {
+		if err := ctx.Tracker.SaveMapping(vhdPath, uuid, "", ""); err != nil {
+			log.Collect("Failed to save tracking info: %v", err)
+		}
+	}
+	if err := ctx.Tracker.SetArchived(vhdPath, true); err != nil {
+		log.Collect("Failed to update tracking: %v", err)
+	}
+
+	// Output
+	log.Success("VHD archived successfully")
+	return printResult(ctx, ArchiveResult{
+		Path:         vhdPath,
+		Archive:      archivePath,
+		OriginalSize: originalSize,
+		ArchiveSize:  archiveSize,
+	})
+}
+
+// ArchiveResult is the outcome of 'vhdm archive'
+type ArchiveResult struct {
+	Path         string `json:"path"`
+	Archive      string `json:"archive"`
+	OriginalSize int64  `json:"originalSize"`
+	ArchiveSize  int64  `json:"archiveSize"`
+}
+
+func (r ArchiveResult) table(utils.Theme) (string, [][2]string) {
+	return "Archive Result", [][2]string{
+		{"Path", r.Path},
+		{"Archive", r.Archive},
+		{"Original Size", utils.BytesToHuman(r.OriginalSize)},
+		{"Archive Size", utils.BytesToHuman(r.ArchiveSize)},
+		{"Status", "archived"},
+	}
+}
+
+func (r ArchiveResult) quiet() quietLine {
+	return quietLine{Key: r.Path, State: "archived"}
+}
+
+func runUnarchive(ctx *AppContext, vhdPath string) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "unarchive", Path: vhdPath, Err: err}
+	}
+
+	log.Debug("Unarchive operation starting")
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	archivePath := wslPath + wsl.ArchiveExt
+
+	if !ctx.WSL.FileExists(archivePath) {
+		if ctx.WSL.FileExists(wslPath) {
+			if ctx.Config.Quiet {
+				printQuiet(quietLine{Key: vhdPath, State: "not archived"})
+			} else {
+				log.Info("VHD is not archived")
+			}
+			return nil
+		}
+		return fmt.Errorf("archive file not found: %s", archivePath)
+	}
+	if ctx.WSL.FileExists(wslPath) {
+		return fmt.Errorf("VHD file already exists: %s - please remove or rename it first", vhdPath)
+	}
+
+	// Decompress
+	log.Info("Restoring VHD (this may take a while)...")
+	if err := ctx.WSL.DecompressFile(archivePath, wslPath); err != nil {
+		return fmt.Errorf("failed to unarchive: %w", err)
+	}
+
+	if err := ctx.WSL.DeleteVHD(archivePath); err != nil {
+		log.Warn("Failed to remove archive file: %v", err)
+	}
+
+	// Update tracking
+	if err := ctx.Tracker.SetArchived(vhdPath, false); err != nil {
+		log.Collect("Failed to update tracking: %v", err)
+	}
+
+	// Output
+	log.Success("VHD restored successfully")
+
+	size, _ := ctx.WSL.FileSize(wslPath)
+	if err := printResult(ctx, UnarchiveResult{Path: vhdPath, Size: size}); err != nil {
+		return err
+	}
+
+	log.Info("")
+	log.Info("To mount this VHD, run:")
+	log.Info("  vhdm mount --vhd-path %s --mount-point /mnt/your-mount-point", vhdPath)
+
+	return nil
+}
+
+// UnarchiveResult is the outcome of 'vhdm unarchive'
+type UnarchiveResult struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+func (r UnarchiveResult) table(utils.Theme) (string, [][2]string) {
+	return "Unarchive Result", [][2]string{
+		{"Path", r.Path},
+		{"Size", utils.BytesToHuman(r.Size)},
+		{"Status", "detached"},
+	}
+}
+
+func (r UnarchiveResult) quiet() quietLine {
+	return quietLine{Key: r.Path, State: "unarchived"}
+}
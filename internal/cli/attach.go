@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -18,19 +19,23 @@ func newAttachCmd() *cobra.Command {
 		Long: `Attach a VHD file to WSL as a block device.
 
 The VHD will be accessible as /dev/sdX after attachment.
-Use 'mount' command to attach AND mount in one step.`,
+Use 'mount' command to attach AND mount in one step.
+
+If the VHD is the system disk of a running WSL distribution, the distribution
+is stopped first when --yes is given; it cannot start again until the VHD is
+detached.`,
 		Example: "  vhdm attach --vhd-path C:/VMs/disk.vhdx",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runAttach(vhdPath)
+			return runAttach(appContext(cmd), vhdPath)
 		},
+		Annotations: map[string]string{annotationSudo: "true"},
 	}
 	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
 	cmd.MarkFlagRequired("vhd-path")
 	return cmd
 }
 
-func runAttach(vhdPath string) error {
-	ctx := getContext()
+func runAttach(ctx *AppContext, vhdPath string) error {
 	log := ctx.Logger
 
 	// Validate path
@@ -43,6 +48,10 @@ func runAttach(vhdPath string) error {
 		}
 	}
 
+	if err := ensureNotReference(ctx, "attach", vhdPath); err != nil {
+		return err
+	}
+
 	log.Debug("Attach operation starting for: %s", vhdPath)
 
 	// Check if VHD file exists
@@ -56,15 +65,24 @@ func runAttach(vhdPath string) error {
 		}
 	}
 
-	// Take snapshot of current devices before attach
-	oldDevices, err := ctx.WSL.GetBlockDevices()
-	if err != nil {
-		return fmt.Errorf("failed to get block devices: %w", err)
+	// Attempt to attach and detect the new device
+	devName, attached, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
+	if err != nil && !attached && !types.IsAlreadyAttached(err) {
+		// The system VHD of a running distribution is locked by WSL
+		if owner := systemDiskOwner(ctx, vhdPath); owner != "" {
+			stopped, stopErr := prepareDistroStop(ctx, "attach", owner)
+			if stopErr != nil {
+				return stopErr
+			}
+			if stopped.wasRunning {
+				if err := stopped.stop(ctx); err != nil {
+					return err
+				}
+				devName, attached, err = ctx.WSL.AttachVHDAndDetect(vhdPath)
+			}
+		}
 	}
-
-	// Attempt to attach
-	_, err = ctx.WSL.AttachVHD(vhdPath)
-	if err != nil {
+	if err != nil && !attached {
 		if types.IsAlreadyAttached(err) {
 			// VHD is already attached - find its UUID
 			log.Debug("VHD is already attached, looking up UUID...")
@@ -81,28 +99,28 @@ func runAttach(vhdPath string) error {
 				devName, _ = ctx.WSL.GetDeviceByUUID(uuid)
 			}
 			
-			if ctx.Config.Quiet {
-				if uuid != "" {
-					fmt.Printf("%s (%s): already attached\n", vhdPath, uuid)
-				} else {
-					fmt.Printf("%s: already attached\n", vhdPath)
-				}
-				return nil
-			}
-
 			log.Info("VHD is already attached")
-			printAttachResult(vhdPath, uuid, devName, false, uuid == "")
+			if uuid == "" {
+				log.Info("To track its device, run: vhdm adopt --dev-name <device> --vhd-path %s", vhdPath)
+			}
+			res := AttachResult{Path: vhdPath, UUID: uuid, DeviceName: devName}
+			if err := printResult(ctx, res); err != nil {
+				return err
+			}
+			printFormatHint(ctx, res)
 			return nil
 		}
-		return &types.VHDError{
+		vhdErr := &types.VHDError{
 			Op:   "attach",
 			Path: vhdPath,
 			Err:  err,
 		}
+		if errors.Is(err, types.ErrFileInUse) {
+			vhdErr.Help = fileInUseHelp
+		}
+		return vhdErr
 	}
 
-	// Detect new device
-	devName, err := ctx.WSL.DetectNewDevice(oldDevices)
 	if err != nil {
 		return fmt.Errorf("failed to detect attached device: %w", err)
 	}
@@ -112,57 +130,68 @@ func runAttach(vhdPath string) error {
 
 	// Save to tracking file
 	if err := ctx.Tracker.SaveMapping(vhdPath, uuid, "", devName); err != nil {
-		log.Warn("Failed to save tracking info: %v", err)
+		log.Collect("Failed to save tracking info: %v", err)
 	}
+	recordIdentifiers(ctx, vhdPath, uuid)
 
 	// Output
-	if ctx.Config.Quiet {
-		if uuid != "" {
-			fmt.Printf("%s (%s): attached\n", vhdPath, uuid)
-		} else {
-			fmt.Printf("%s (/dev/%s): attached,unformatted\n", vhdPath, devName)
-		}
-		return nil
-	}
-
 	log.Success("VHD attached successfully")
-	printAttachResult(vhdPath, uuid, devName, true, uuid == "")
+	res := AttachResult{Path: vhdPath, UUID: uuid, DeviceName: devName, NewlyAttached: true}
+	if err := printResult(ctx, res); err != nil {
+		return err
+	}
+	printFormatHint(ctx, res)
 	return nil
 }
 
-func printAttachResult(path, uuid, devName string, newlyAttached, unformatted bool) {
-	fmt.Println()
-	fmt.Println("VHD Attach Result")
-	fmt.Println()
-	
+// AttachResult is the outcome of 'vhdm attach'
+type AttachResult struct {
+	Path          string `json:"path"`
+	UUID          string `json:"uuid,omitempty"`
+	DeviceName    string `json:"deviceName,omitempty"`
+	NewlyAttached bool   `json:"newlyAttached"`
+}
+
+func (r AttachResult) table(utils.Theme) (string, [][2]string) {
 	pairs := [][2]string{
-		{"Path", path},
+		{"Path", r.Path},
 	}
-	
-	if uuid != "" {
-		pairs = append(pairs, [2]string{"UUID", uuid})
-	} else if unformatted {
+	if r.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", r.UUID})
+	} else {
 		pairs = append(pairs, [2]string{"UUID", "(unformatted)"})
 	}
-	
-	if devName != "" {
-		pairs = append(pairs, [2]string{"Device", "/dev/" + devName})
-	}
-	
+	pairs = appendDevice(pairs, r.DeviceName)
+
 	status := "attached"
-	if newlyAttached {
+	if r.NewlyAttached {
 		status = "attached (newly)"
 	}
-	if unformatted {
+	if r.UUID == "" {
 		status += " - needs formatting"
 	}
 	pairs = append(pairs, [2]string{"Status", status})
-	
-	utils.KeyValueTable("", pairs, 14, 50)
-	
-	if unformatted {
-		fmt.Println()
-		fmt.Printf("To format this VHD, run:\n")
-		fmt.Printf("  vhdm format --dev-name %s --type ext4\n", devName)
+
+	return "VHD Attach Result", pairs
+}
+
+func (r AttachResult) quiet() quietLine {
+	switch {
+	case !r.NewlyAttached:
+		return quietLine{Key: r.Path, UUID: r.UUID, State: "already attached"}
+	case r.UUID != "":
+		return quietLine{Key: r.Path, UUID: r.UUID, State: "attached"}
+	default:
+		return quietLine{Key: r.Path, State: "attached,unformatted as /dev/" + r.DeviceName}
+	}
+}
+
+// printFormatHint tells how to format a VHD attached without a filesystem
+func printFormatHint(ctx *AppContext, r AttachResult) {
+	if r.UUID != "" || r.DeviceName == "" {
+		return
 	}
+	ctx.Logger.Info("")
+	ctx.Logger.Info("To format this VHD, run:")
+	ctx.Logger.Info("  vhdm format --dev-name %s --type ext4", r.DeviceName)
 }
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// automountPrefix is the name prefix of the attach units generated for automounts
+const automountPrefix = "vhdm-automount-"
+
+// automountUnits holds the systemd units that implement on-demand mounting:
+// an .automount unit watching the mount point, a .mount unit for the filesystem
+// and a oneshot service that attaches the VHD (and detaches it when stopped).
+type automountUnits struct {
+	AttachName    string
+	MountName     string
+	AutomountName string
+
+	Attach    string
+	Mount     string
+	Automount string
+}
+
+// systemdEscapePath escapes a path the way 'systemd-escape --path' does, which
+// is how systemd derives mount and automount unit names from a mount point.
+func systemdEscapePath(path string) string {
+	path = strings.Trim(filepath.Clean(path), "/")
+	if path == "" {
+		return "-"
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '/':
+			b.WriteByte('-')
+		case c == '.' && i == 0:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == ':', c == '_', c == '.':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		}
+	}
+	return b.String()
+}
+
+// buildAutomountUnits generates the unit files for mounting a VHD on first access.
+// An empty fsType lets mount detect the filesystem type, and non-empty options
+// are passed to mount as Options=. The mount unit is
+// ordered after the units in deps (see dependencyUnits).
+// With a non-zero idleTimeout (seconds) the filesystem is unmounted after that
+// period of inactivity, which stops the attach unit and detaches the VHD.
+func buildAutomountUnits(vhdPath, uuid, mountPoint, fsType, options string, idleTimeout int, deps []string, vhdmPath, trackingFile string) automountUnits {
+	stem := systemdEscapePath(mountPoint)
+	u := automountUnits{
+		AttachName:    automountPrefix + stem + ".service",
+		MountName:     stem + ".mount",
+		AutomountName: stem + ".automount",
+	}
+
+	u.Attach = fmt.Sprintf(`[Unit]
+Description=Attach VHD on demand: %s
+%sStopWhenUnneeded=yes
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+Environment="PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin:/mnt/c/WINDOWS/system32:/mnt/c/WINDOWS"
+%s
+%s
+ExecStart=%s
+ExecStop=%s
+TimeoutStartSec=60
+TimeoutStopSec=30
+`, systemdEscapeSpecifiers(vhdPath), windowsMountLines(vhdPath),
+		systemdEnvironment("VHDM_TRACKING_FILE", trackingFile), systemdEnvironment("HOME", os.Getenv("HOME")),
+		systemdCommandLine(vhdmPath, "--quiet", "attach", "--vhd-path", vhdPath),
+		systemdCommandLine(vhdmPath, "--quiet", "detach", "--uuid", uuid))
+
+	u.Mount = fmt.Sprintf(`[Unit]
+Description=Mount VHD on demand: %s
+Requires=%s
+After=%s
+%s
+[Mount]
+What=/dev/disk/by-uuid/%s
+Where=%s
+`, systemdEscapeSpecifiers(vhdPath), u.AttachName, u.AttachName, unitDependencyLines(deps), uuid, systemdEscapeSpecifiers(mountPoint))
+	if fsType != "" {
+		u.Mount += fmt.Sprintf("Type=%s\n", fsType)
+	}
+	if options != "" {
+		u.Mount += fmt.Sprintf("Options=%s\n", systemdEscapeSpecifiers(options))
+	}
+
+	idle := ""
+	if idleTimeout > 0 {
+		idle = fmt.Sprintf("TimeoutIdleSec=%d\n", idleTimeout)
+	}
+	u.Automount = fmt.Sprintf(`[Unit]
+Description=Automount VHD: %s
+
+[Automount]
+Where=%s
+%s
+[Install]
+WantedBy=multi-user.target
+`, systemdEscapeSpecifiers(vhdPath), systemdEscapeSpecifiers(mountPoint), idle)
+
+	return u
+}
+
+// installAutomount writes the automount units, reloads systemd and enables the
+// automount so the VHD is attached and mounted on first access. With dryRun
+// the units are only printed (see previewUnitFile).
+func installAutomount(ctx *AppContext, vhdPath, uuid, mountPoint, fsType, options string, idleTimeout int, deps []string, dryRun bool) error {
+	log := ctx.Logger
+
+	vhdmPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get vhdm executable path: %w", err)
+	}
+
+	units := buildAutomountUnits(vhdPath, uuid, mountPoint, fsType, options, idleTimeout, deps, vhdmPath, ctx.Config.TrackingFile)
+	files := [][2]string{
+		{units.AttachName, units.Attach},
+		{units.MountName, units.Mount},
+		{units.AutomountName, units.Automount},
+	}
+
+	systemdDir := ctx.Config.UnitDir
+	if dryRun {
+		for _, f := range files {
+			if err := previewUnitFile(ctx, unitFilePath(ctx, f[0]), f[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("installing automount units requires root privileges. Please run with sudo")
+	}
+	if err := os.MkdirAll(systemdDir, 0755); err != nil {
+		return fmt.Errorf("failed to create systemd directory: %w", err)
+	}
+	if err := os.MkdirAll(mountPoint, 0755); err != nil {
+		return fmt.Errorf("failed to create mount point: %w", err)
+	}
+
+	for _, f := range files {
+		path := filepath.Join(systemdDir, f[0])
+		if err := os.WriteFile(path, []byte(f[1]), 0644); err != nil {
+			return fmt.Errorf("failed to write unit file %s: %w", path, err)
+		}
+		log.Debug("Wrote unit file: %s", path)
+		removeLegacyUnit(ctx, f[0])
+	}
+	recordService(ctx, vhdPath, units.AttachName)
+
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		log.Warn("Failed to reload systemd daemon: %v", err)
+	}
+
+	if output, err := systemctl(ctx, "enable", "--now", units.AutomountName); err != nil {
+		return fmt.Errorf("failed to enable automount: %w\n%s", err, string(output))
+	}
+
+	if ctx.Config.Quiet {
+		printQuiet(quietLine{Key: vhdPath, UUID: uuid, State: "automount at " + mountPoint})
+		return nil
+	}
+
+	log.Info("%s Automount created: %s", ctx.Theme.Success, units.AutomountName)
+	log.Info("  Unit directory: %s", systemdDir)
+	log.Info("  VHD Path: %s", vhdPath)
+	log.Info("  Mount Point: %s", mountPoint)
+	log.Info("  UUID: %s", uuid)
+	if idleTimeout > 0 {
+		log.Info("  Idle Timeout: %ds (unmount and detach when idle)", idleTimeout)
+	}
+	log.Info("")
+	log.Info("The VHD will be attached and mounted on first access to %s", mountPoint)
+	log.Info("Remove with: sudo vhdm service remove --name %s", strings.TrimSuffix(units.AttachName, ".service"))
+
+	return nil
+}
+
+// removeAutomount stops and removes the units created by installAutomount,
+// given the name of the attach unit (vhdm-automount-<escaped-mount-point>.service).
+func removeAutomount(ctx *AppContext, attachName string) error {
+	log := ctx.Logger
+
+	stem := strings.TrimSuffix(strings.TrimPrefix(attachName, automountPrefix), ".service")
+	automountName := stem + ".automount"
+	mountName := stem + ".mount"
+
+	// Stop in reverse dependency order: automount, mount, then attach (detaches the VHD)
+	for _, unit := range []string{automountName, mountName, attachName} {
+		if _, err := systemctl(ctx, "stop", unit); err != nil {
+			log.Debug("Unit %s not running or already stopped", unit)
+		}
+	}
+	if _, err := systemctl(ctx, "disable", automountName); err != nil {
+		log.Debug("Unit %s not enabled or already disabled", automountName)
+	}
+
+	attachPath := unitFilePath(ctx, attachName)
+	if _, err := os.Stat(attachPath); os.IsNotExist(err) {
+		return fmt.Errorf("service file not found: %s", attachPath)
+	}
+	for _, unit := range []string{automountName, mountName, attachName} {
+		path := unitFilePath(ctx, unit)
+		if err := removeUnitFile(ctx, unit); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove unit file %s: %w", path, err)
+		}
+	}
+	forgetService(ctx, attachName)
+
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		log.Debug("Failed to reload systemd daemon: %v", err)
+	}
+
+	log.Info("%s Automount removed: %s", ctx.Theme.Success, automountName)
+	return nil
+}
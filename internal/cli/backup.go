@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newBackupCmd() *cobra.Command {
+	var (
+		vhdPath  string
+		to       string
+		force    bool
+		noFreeze bool
+	)
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Copy a VHD file, freezing it while mounted",
+		Long: `Copy a VHD file to a backup file.
+
+A mounted VHD does not need to be unmounted: its filesystem is frozen with
+fsfreeze for the copy, which flushes pending writes and blocks new ones, so the
+backup is crash-consistent. Writers resume as soon as the copy ends, and the
+filesystem is thawed on any error or interruption too. A VHD that is detached,
+or attached but not mounted, is copied as is.
+
+The backup path may be a Linux path or a Windows path (C:/...).`,
+		Example: `  vhdm backup --vhd-path C:/VMs/disk.vhdx --to C:/Backups/disk.vhdx
+  vhdm backup --vhd-path C:/VMs/disk.vhdx --to /mnt/nas/disk.vhdx --force`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBackup(appContext(cmd), vhdPath, to, force, noFreeze)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&to, "to", "", "Backup file to write")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing backup file")
+	cmd.Flags().BoolVar(&noFreeze, "no-freeze", false, "Copy a mounted VHD without freezing it (the copy may be inconsistent)")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func runBackup(ctx *AppContext, vhdPath, to string, force, noFreeze bool) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "backup", Path: vhdPath, Err: err}
+	}
+
+	log.Debug("Backup operation starting")
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	backupPath := ctx.WSL.ConvertPath(to)
+	if !ctx.WSL.FileExists(wslPath) {
+		return &types.VHDError{Op: "backup", Path: vhdPath, Err: types.ErrVHDNotFound}
+	}
+	if backupPath == wslPath {
+		return &types.VHDError{Op: "backup", Path: vhdPath, Err: fmt.Errorf("backup file is the VHD itself")}
+	}
+	if ctx.WSL.FileExists(backupPath) {
+		if !force {
+			return fmt.Errorf("backup file already exists: %s (use --force to overwrite)", to)
+		}
+		if err := ctx.WSL.DeleteVHD(backupPath); err != nil {
+			return fmt.Errorf("failed to remove existing backup: %w", err)
+		}
+	}
+
+	mountPoint, err := copyVHDFile(ctx, vhdPath, backupPath, noFreeze)
+	if err != nil {
+		return &types.VHDError{Op: "backup", Path: vhdPath, Err: err}
+	}
+
+	size, _ := ctx.WSL.FileSize(backupPath)
+
+	// Output
+	log.Success("VHD backed up successfully")
+	return printResult(ctx, BackupResult{
+		Path:       vhdPath,
+		Backup:     backupPath,
+		Size:       size,
+		MountPoint: mountPoint,
+		Frozen:     mountPoint != "" && !noFreeze,
+	})
+}
+
+// copyVHDFile copies the file of a VHD to dst (WSL path). A mounted VHD is
+// frozen during the copy unless noFreeze is set. It returns where the VHD was
+// mounted, or "".
+func copyVHDFile(ctx *AppContext, vhdPath, dst string, noFreeze bool) (string, error) {
+	var mountPoint string
+	if uuid, _ := ctx.Tracker.LookupUUIDByPath(vhdPath); uuid != "" {
+		mountPoint, _ = ctx.WSL.GetMountPoint(uuid)
+	}
+
+	src := ctx.WSL.ConvertPath(vhdPath)
+	copyFile := func() error {
+		ctx.Logger.Info("Copying %s to %s (this may take a while)...", src, dst)
+		return ctx.WSL.CopyFile(src, dst)
+	}
+	switch {
+	case mountPoint == "":
+		return "", copyFile()
+	case noFreeze:
+		ctx.Logger.Warn("Copying mounted VHD without freezing %s: the copy may be inconsistent", mountPoint)
+		return mountPoint, copyFile()
+	default:
+		return mountPoint, withFrozen(ctx, mountPoint, copyFile)
+	}
+}
+
+// withFrozen runs fn with the filesystem mounted at mountPoint frozen, so a
+// copy of its VHD taken by fn is consistent. The filesystem is thawed however
+// fn ends; SIGINT and SIGTERM are held off meanwhile, since a filesystem left
+// frozen would hang every process writing to it.
+func withFrozen(ctx *AppContext, mountPoint string, fn func() error) (err error) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	ctx.Logger.Info("Freezing %s", mountPoint)
+	if err := ctx.WSL.Freeze(mountPoint); err != nil {
+		return err
+	}
+	defer func() {
+		if thawErr := ctx.WSL.Thaw(mountPoint); thawErr != nil {
+			ctx.Logger.Error("Failed to thaw %s - run 'sudo fsfreeze --unfreeze %s': %v", mountPoint, mountPoint, thawErr)
+			if err == nil {
+				err = thawErr
+			}
+			return
+		}
+		ctx.Logger.Debug("Thawed %s", mountPoint)
+	}()
+
+	err = fn()
+	select {
+	case sig := <-sigs:
+		if err == nil {
+			err = fmt.Errorf("interrupted by %v", sig)
+		}
+	default:
+	}
+	return err
+}
+
+// BackupResult is the outcome of 'vhdm backup'
+type BackupResult struct {
+	Path       string `json:"path"`
+	Backup     string `json:"backup"`
+	Size       int64  `json:"size"`
+	MountPoint string `json:"mountPoint,omitempty"` // Where the VHD was mounted during the copy
+	Frozen     bool   `json:"frozen"`
+}
+
+func (r BackupResult) table(utils.Theme) (string, [][2]string) {
+	consistency := "not mounted"
+	switch {
+	case r.Frozen:
+		consistency = "frozen during copy"
+	case r.MountPoint != "":
+		consistency = "copied while mounted, not frozen"
+	}
+	return "Backup Result", [][2]string{
+		{"Path", r.Path},
+		{"Backup", r.Backup},
+		{"Size", utils.BytesToHuman(r.Size)},
+		{"Consistency", consistency},
+		{"Status", "backed up"},
+	}
+}
+
+func (r BackupResult) quiet() quietLine {
+	return quietLine{Key: r.Path, State: "backed up to " + r.Backup}
+}
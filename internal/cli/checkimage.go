@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newCheckImageCmd() *cobra.Command {
+	var (
+		vhdPath string
+		maxAge  time.Duration
+		force   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "check-image",
+		Short: "Check tracked VHD files for image-level corruption",
+		Long: `Run 'qemu-img check' on tracked VHD files (or one with --vhd-path) and record
+the result in the tracking file. 'vhdm status' warns about VHDs whose last
+check found corruptions, catching image damage before it becomes filesystem
+damage.
+
+Attached VHDs are skipped because Windows may be writing to them; detach first
+or pass --force to check them anyway. With --max-age, VHDs checked more
+recently are skipped, so the command can run periodically from cron or a
+systemd timer. It fails when any checked VHD is damaged.`,
+		Example: `  vhdm check-image
+  vhdm check-image --vhd-path C:/VMs/disk.vhdx
+  vhdm check-image --max-age 24h`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCheckImage(appContext(cmd), vhdPath, maxAge, force)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format, default: all tracked)")
+	cmd.Flags().DurationVar(&maxAge, "max-age", 0, "Skip VHDs checked within this duration (e.g. 24h)")
+	cmd.Flags().BoolVar(&force, "force", false, "Also check attached VHDs")
+	return cmd
+}
+
+// imageCheckRow is one VHD of the check-image table
+type imageCheckRow struct {
+	Path   string `json:"path"`
+	Result string `json:"result"`
+	Bad    bool   `json:"damaged"`
+}
+
+func runCheckImage(ctx *AppContext, vhdPath string, maxAge time.Duration, force bool) error {
+	log := ctx.Logger
+
+	if err := checkListOutput(ctx, "check-image"); err != nil {
+		return err
+	}
+
+	var paths []string
+	if vhdPath != "" {
+		if _, err := trackedEntry(ctx, "check-image", vhdPath); err != nil {
+			return err
+		}
+		paths = []string{vhdPath}
+	} else {
+		var err error
+		if paths, err = ctx.Tracker.GetAllPaths(); err != nil {
+			return fmt.Errorf("failed to get tracked VHDs: %w", err)
+		}
+	}
+
+	log.Debug("Check-image operation starting for %d VHD(s)", len(paths))
+
+	var rows []imageCheckRow
+	damaged := 0
+	for _, path := range paths {
+		row := checkImage(ctx, path, maxAge, force)
+		if row.Bad {
+			damaged++
+		}
+		rows = append(rows, row)
+	}
+
+	switch {
+	case structuredOutput(ctx):
+		if err := printStructured(ctx, nonNil(rows)); err != nil {
+			return err
+		}
+	case ctx.Config.Quiet:
+		for _, row := range rows {
+			printQuiet(quietLine{Key: row.Path, State: row.Result})
+		}
+	default:
+		printImageCheckTable(ctx, rows)
+	}
+
+	if damaged > 0 {
+		return &types.VHDError{
+			Op:   "check-image",
+			Err:  fmt.Errorf("%d VHD(s) have image corruption", damaged),
+			Help: "Back up the data, then repair the detached VHD with: qemu-img check -r all <file>",
+		}
+	}
+	log.Success("Image check complete")
+	return nil
+}
+
+// checkImage checks one tracked VHD and records the result in tracking
+func checkImage(ctx *AppContext, path string, maxAge time.Duration, force bool) imageCheckRow {
+	row := imageCheckRow{Path: path}
+
+	entry, _ := ctx.Tracker.GetEntry(path)
+	wslPath := ctx.WSL.ConvertPath(path)
+	if !ctx.WSL.FileExists(wslPath) {
+		row.Result = "skipped (file not found)"
+		return row
+	}
+	if maxAge > 0 && entry.ImageCheck != nil {
+		if checked, err := time.Parse(time.RFC3339, entry.ImageCheck.Time); err == nil && time.Since(checked) < maxAge {
+			row.Result = "skipped (checked " + displayTime(ctx, entry.ImageCheck.Time) + ")"
+			return row
+		}
+	}
+	if !force && entry.UUID != "" {
+		if attached, _ := ctx.WSL.IsAttached(entry.UUID); attached {
+			row.Result = "skipped (attached)"
+			return row
+		}
+	}
+
+	result := recordImageCheck(ctx, path)
+	row.Result = result.Problem()
+	switch {
+	case row.Result == "" && result.Skipped != "":
+		row.Result = "skipped (" + result.Skipped + ")"
+	case row.Result == "":
+		row.Result = "ok"
+	}
+	row.Bad = result.Damaged()
+	return row
+}
+
+// recordImageCheck runs qemu-img check on a VHD file and records the result
+// in tracking when the VHD is tracked
+func recordImageCheck(ctx *AppContext, path string) types.ImageCheckResult {
+	result := types.ImageCheckResult{Time: time.Now().Format(time.RFC3339)}
+	check, err := ctx.WSL.CheckImage(ctx.WSL.ConvertPath(path))
+	switch {
+	case errors.Is(err, wsl.ErrCheckUnsupported):
+		result.Skipped = "no check for this format"
+	case err != nil:
+		result.Error = err.Error()
+	default:
+		result.Corruptions = check.Corruptions
+		result.Leaks = check.Leaks
+		result.CheckErrors = check.CheckErrors
+	}
+
+	if err := ctx.Tracker.Update(path, func(e *types.TrackingEntry) { e.ImageCheck = &result }); err != nil {
+		ctx.Logger.Warn("Failed to record image check of %s: %v", path, err)
+	}
+	return result
+}
+
+func printImageCheckTable(ctx *AppContext, rows []imageCheckRow) {
+	fmt.Println()
+	fmt.Println("Image Check")
+	fmt.Println()
+
+	colWidths := []int{50, 40}
+	headers := []string{"Path", "Result"}
+
+	utils.PrintTableHeader(colWidths, headers)
+
+	for _, row := range rows {
+		result := row.Result
+		switch {
+		case row.Bad:
+			result = ctx.Theme.Red(result)
+		case result == "ok":
+			result = ctx.Theme.Green(result)
+		}
+		utils.PrintTableRow(colWidths, row.Path, result)
+	}
+
+	utils.PrintTableFooter(colWidths)
+}
@@ -2,31 +2,53 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/rjdinis/vhdm/internal/config"
+	"github.com/rjdinis/vhdm/internal/helper"
 	"github.com/rjdinis/vhdm/internal/logging"
 	"github.com/rjdinis/vhdm/internal/tracking"
 	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
 )
 
 type AppContext struct {
 	Config  *config.Config
 	Logger  *logging.Logger
 	Tracker *tracking.Tracker
-	WSL     *wsl.Client
+	WSL     wsl.Interface // *wsl.Client, or a wslfake.Fake in tests
+
+	// Runner runs the external commands of the commands themselves, such as
+	// systemctl; the WSL client runs its own through the same one
+	Runner wsl.Runner
+
+	// Theme colors the tables and marks printed by the commands (VHDM_THEME)
+	Theme utils.Theme
 }
 
-var (
-	appCtx *AppContext
-	quiet  bool
-	debug  bool
-	yes    bool
-)
+// appContextKey is the cobra command context key of the *AppContext
+type appContextKey struct{}
+
+// annotationSudo marks commands that run privileged steps. The root command
+// validates sudo credentials once before running them, so the user is asked
+// for a password at most once per command.
+const annotationSudo = "vhdm/sudo"
 
 func NewRootCommand(version, commit, date string) *cobra.Command {
+	var (
+		quiet   bool
+		debug   bool
+		yes     bool
+		output  string
+		profile string
+	)
 	rootCmd := &cobra.Command{
 		Use:   "vhdm",
 		Short: "WSL VHD Disk Management Tool",
@@ -39,9 +61,24 @@ resize, and status.`,
 			if cmd.Name() == "help" || cmd.Name() == "version" || cmd.Name() == "completion" {
 				return nil
 			}
-			var err error
-			appCtx, err = initContext()
-			return err
+			ctx, err := initContext(quiet, debug, yes, output, profile)
+			if err != nil {
+				return err
+			}
+			cmd.SetContext(context.WithValue(cmd.Context(), appContextKey{}, ctx))
+			if err := resolveVHDPathFlags(ctx, cmd); err != nil {
+				return err
+			}
+			if cmd.Annotations[annotationSudo] == "true" {
+				return ctx.WSL.EnsureSudo()
+			}
+			return nil
+		},
+		// Report the warnings of commands that printed no result
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if ctx := appContext(cmd); ctx != nil {
+				ctx.Logger.FlushWarnings()
+			}
 		},
 		SilenceUsage:  true,
 		SilenceErrors: true,
@@ -50,52 +87,183 @@ resize, and status.`,
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Run in quiet mode")
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Run in debug mode")
 	rootCmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "Auto-confirm prompts")
+	rootCmd.PersistentFlags().StringVar(&output, "output", "", "Result format: table, json, yaml, csv or sh (default: $VHDM_OUTPUT, else table)")
+	rootCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(outputFormats, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Profile with its own tracking file and VHDM_<PROFILE>_* settings (default: $VHDM_PROFILE)")
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+	// --vhd is short for --vhd-path
+	rootCmd.SetGlobalNormalizationFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "vhd" {
+			name = "vhd-path"
+		}
+		return pflag.NormalizedName(name)
+	})
 
 	rootCmd.AddCommand(
 		newVersionCmd(version, commit, date),
 		newCompletionCmd(),
 		newStatusCmd(),
+		newListCmd(),
+		newInfoCmd(),
 		newAttachCmd(),
 		newDetachCmd(),
 		newMountCmd(),
 		newUmountCmd(),
+		newMountCheckCmd(),
 		newFormatCmd(),
 		newCreateCmd(),
 		newDeleteCmd(),
 		newResizeCmd(),
+		newBackupCmd(),
+		newRestoreCmd(),
+		newCloneCmd(),
+		newFlattenCmd(),
+		newCompactCmd(),
+		newConvertCmd(),
+		newMoveCmd(),
+		newRenameCmd(),
+		newSnapshotCmd(),
+		newArchiveCmd(),
+		newUnarchiveCmd(),
+		newExportCmd(),
+		newImportCmd(),
+		newFromDistroCmd(),
+		newMirrorCmd(),
+		newDuCmd(),
+		newFindCmd(),
+		newReportCmd(),
+		newWatchCmd(),
+		newDependCmd(),
+		newNoteCmd(),
+		newPinCmd(),
+		newUnpinCmd(),
+		newExecCmd(),
+		newOpenCmd(),
+		newDockerVolumeCmd(),
+		newDistroCmd(),
 		newServiceCmd(),
+		newSelfTestCmd(),
+		newCheckImageCmd(),
+		newVerifyCmd(),
+		newRefreshCmd(),
+		newTopCmd(),
+		newDevicesCmd(),
+		newWhichCmd(),
+		newAdoptCmd(),
+		newMigrateTrackingCmd(),
 	)
 
 	return rootCmd
 }
 
-func initContext() (*AppContext, error) {
-	cfg, err := config.Load()
+// vhdPathFlags are the flags naming VHD files, resolved against the base
+// directories before a command runs
+var vhdPathFlags = []string{"vhd-path", "src-vhd", "dst-vhd", "after", "exclude"}
+
+// resolveVHDPathFlags rewrites relative VHD paths given on the command line
+// into absolute ones, see utils.ResolveVHDPath
+func resolveVHDPathFlags(ctx *AppContext, cmd *cobra.Command) error {
+	for _, name := range vhdPathFlags {
+		f := cmd.Flags().Lookup(name)
+		if f == nil || !f.Changed {
+			continue
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			paths := sv.GetSlice()
+			for i, p := range paths {
+				paths[i] = utils.ResolveVHDPath(p, ctx.Config.BaseDirs)
+			}
+			if err := sv.Replace(paths); err != nil {
+				return err
+			}
+			continue
+		}
+		if resolved := utils.ResolveVHDPath(f.Value.String(), ctx.Config.BaseDirs); resolved != f.Value.String() {
+			ctx.Logger.Debug("Resolved --%s %s -> %s", name, f.Value.String(), resolved)
+			if err := f.Value.Set(resolved); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func initContext(quiet, debug, yes bool, output, profile string) (*AppContext, error) {
+	cfg, err := config.Load(profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 	cfg.SetQuiet(quiet)
 	cfg.SetDebug(debug)
 	cfg.SetYes(yes)
+	if output != "" {
+		cfg.SetOutput(output)
+	}
+	if !slices.Contains(outputFormats, cfg.Output) {
+		return nil, fmt.Errorf("invalid output format: %q (use %s)", cfg.Output, strings.Join(outputFormats, ", "))
+	}
+
+	theme, err := utils.ParseTheme(cfg.Theme)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VHDM_THEME: %w", err)
+	}
+
+	if !slices.Contains(utils.TimeFormats, cfg.TimeFormat) {
+		return nil, fmt.Errorf("invalid VHDM_TIME_FORMAT: %q (use %s)", cfg.TimeFormat, strings.Join(utils.TimeFormats, ", "))
+	}
+	if !slices.Contains(wsl.NotifyMethods, cfg.Notify) {
+		return nil, fmt.Errorf("invalid VHDM_NOTIFY: %q (use %s)", cfg.Notify, strings.Join(wsl.NotifyMethods, ", "))
+	}
+	if _, err := utils.ConvertSizeToBytes(cfg.ConfirmNameAbove); err != nil {
+		return nil, fmt.Errorf("invalid VHDM_CONFIRM_NAME_ABOVE: %q (use a size such as 100G, or 0 to disable)", cfg.ConfirmNameAbove)
+	}
 
 	logger := logging.New(cfg.Quiet, cfg.Debug)
+	logger.SetTheme(theme)
+	if cfg.LogTimestamps {
+		format := timeFormat(cfg)
+		logger.SetTimestamps(func(t time.Time) string { return utils.FormatTime(t, format) })
+	}
 
+	if cfg.Profile != "" {
+		logger.Debug("Using profile %s (tracking file %s)", cfg.Profile, cfg.TrackingFile)
+	}
 	tracker, err := tracking.New(cfg.TrackingFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize tracking: %w", err)
 	}
 
+	runner := wsl.ExecRunner{}
 	wslClient := wsl.NewClient(logger, cfg.SleepAfterAttach, cfg.DetachTimeout)
+	wslClient.SetRunner(runner)
+	wslClient.SetHelper(helper.Locate(cfg.Helper))
+	delays, err := tracker.DeviceDelays()
+	if err != nil {
+		logger.Debug("No attach delay samples: %v", err)
+	}
+	wslClient.SetDeviceDelays(delays, func(d time.Duration) {
+		if err := tracker.RecordDeviceDelay(d); err != nil {
+			logger.Debug("Failed to record attach delay: %v", err)
+		}
+	})
 
 	return &AppContext{
 		Config:  cfg,
 		Logger:  logger,
 		Tracker: tracker,
 		WSL:     wslClient,
+		Runner:  runner,
+		Theme:   theme,
 	}, nil
 }
 
-func getContext() *AppContext { return appCtx }
+// appContext returns the AppContext that the root command attached to cmd
+// before running it. Each command tree gets its own, so several can run side
+// by side.
+func appContext(cmd *cobra.Command) *AppContext {
+	ctx, _ := cmd.Context().Value(appContextKey{}).(*AppContext)
+	return ctx
+}
 
 func newVersionCmd(version, commit, date string) *cobra.Command {
 	return &cobra.Command{
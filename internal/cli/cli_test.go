@@ -0,0 +1,1719 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/config"
+	"github.com/rjdinis/vhdm/internal/logging"
+	"github.com/rjdinis/vhdm/internal/tracking"
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/internal/wsl/wslfake"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// newTestContext returns an AppContext backed by a fake WSL and a tracking
+// file in a temporary directory
+func newTestContext(t *testing.T) (*AppContext, *wslfake.Fake) {
+	t.Helper()
+	trackingFile := filepath.Join(t.TempDir(), "vhd_tracking.json")
+	tracker, err := tracking.New(trackingFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := wslfake.New()
+	cfg := &config.Config{
+		TrackingFile:     trackingFile,
+		Quiet:            true,
+		Yes:              true,
+		Output:           "table",
+		TimeFormat:       "rfc3339",
+		ConfirmNameAbove: "100G",
+		DetachTimeout:    time.Second,
+		UnitDir:          t.TempDir(),
+	}
+	return &AppContext{
+		Config:  cfg,
+		Logger:  logging.New(true, false),
+		Tracker: tracker,
+		WSL:     fake,
+		Runner:  wslfake.NewRunner(),
+		Theme:   utils.DefaultTheme(),
+	}, fake
+}
+
+// captureStdout returns what fn prints to standard output
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return capture(t, &os.Stdout, fn)
+}
+
+// captureStderr returns what fn prints to standard error, where logs go
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	return capture(t, &os.Stderr, fn)
+}
+
+func capture(t *testing.T, f **os.File, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := *f
+	*f = w
+	defer func() { *f = saved }()
+
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestRunAttachTracksDevice(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+
+	if err := runAttach(ctx, "C:/VMs/data.vhdx"); err != nil {
+		t.Fatalf("runAttach() error = %v", err)
+	}
+
+	if got := fake.Disk("C:/VMs/data.vhdx").Device; got != "sdd" {
+		t.Errorf("device = %q, want sdd", got)
+	}
+	devName, err := ctx.Tracker.LookupDevNameByPath("C:/VMs/data.vhdx")
+	if err != nil || devName != "sdd" {
+		t.Errorf("tracked device = %q, %v; want sdd", devName, err)
+	}
+}
+
+func TestResolveUUIDByLabel(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/backup.vhdx", 1<<30)
+	disk.UUID, disk.FSType, disk.Label, disk.PartUUID = "1A2B-3C4D", "vfat", "Backup", "0d9c1e5a-01"
+
+	if err := runAttach(ctx, "C:/VMs/backup.vhdx"); err != nil {
+		t.Fatalf("runAttach() error = %v", err)
+	}
+	entry, _ := ctx.Tracker.GetEntry("C:/VMs/backup.vhdx")
+	if entry.Label != "Backup" || entry.PartUUID != "0d9c1e5a-01" {
+		t.Errorf("tracked label, partuuid = %q, %q", entry.Label, entry.PartUUID)
+	}
+
+	// Detached VHDs are found by what tracking last saw
+	disk.Device = ""
+	for _, id := range []string{"LABEL=Backup", "Backup", "PARTUUID=0D9C1E5A-01", "0d9c1e5a-01", "1A2B-3C4D"} {
+		if uuid, err := resolveUUID(ctx, "mount", id); err != nil || uuid != "1A2B-3C4D" {
+			t.Errorf("resolveUUID(%q) = %q, %v; want 1A2B-3C4D", id, uuid, err)
+		}
+	}
+	if _, err := resolveUUID(ctx, "mount", "LABEL=backup"); err == nil {
+		t.Error("resolveUUID(LABEL=backup) matched a label of another case")
+	}
+}
+
+func TestRunBackupThawsOnError(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.Device, disk.UUID, disk.FSType, disk.MountPoints = "sdd", "44444444-4444-4444-8444-444444444444", "ext4", []string{"/mnt/data"}
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "/mnt/data", "sdd")
+
+	if err := runBackup(ctx, "C:/VMs/data.vhdx", "C:/Backups/data.vhdx", false, false); err != nil {
+		t.Fatalf("runBackup() error = %v", err)
+	}
+	want := []string{"Freeze /mnt/data", "CopyFile /mnt/c/VMs/data.vhdx /mnt/c/Backups/data.vhdx", "Thaw /mnt/data"}
+	if !slices.Equal(fake.Calls, want) {
+		t.Errorf("calls = %q, want %q", fake.Calls, want)
+	}
+
+	fake.Errors["CopyFile"] = errors.New("no space left on device")
+	if err := runBackup(ctx, "C:/VMs/data.vhdx", "C:/Backups/data2.vhdx", false, false); err == nil {
+		t.Error("runBackup() succeeded with a failing copy")
+	}
+	if len(fake.Frozen) != 0 {
+		t.Errorf("left frozen after a failed copy: %v", fake.Frozen)
+	}
+}
+
+func TestRunAdoptBindsAttachedDevice(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/old.vhdx", 1<<30)
+	disk.Device = "sde"
+	disk.UUID = "22222222-2222-4222-8222-222222222222"
+	disk.FSType = "ext4"
+	disk.MountPoints = []string{"/mnt/old"}
+
+	if err := runAdopt(ctx, "sde", "C:/VMs/old.vhdx", false); err != nil {
+		t.Fatalf("runAdopt() error = %v", err)
+	}
+
+	entry, err := ctx.Tracker.GetEntry("C:/VMs/old.vhdx")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if entry.UUID != disk.UUID || entry.DeviceName != "sde" || entry.FSType != "ext4" {
+		t.Errorf("entry = %+v, want UUID %s on sde with ext4", entry, disk.UUID)
+	}
+	if len(entry.MountPoints) != 1 || entry.MountPoints[0] != "/mnt/old" {
+		t.Errorf("mount points = %v, want [/mnt/old]", entry.MountPoints)
+	}
+	if len(fake.Calls) != 0 {
+		t.Errorf("adopt changed the system: %v", fake.Calls)
+	}
+}
+
+func TestRunAdoptRejectsDetachedFile(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	fake.AddVHD("C:/VMs/other.vhdx", 1<<30)
+	disk := fake.AddVHD("C:/VMs/old.vhdx", 1<<30)
+	disk.Device = "sde"
+
+	if err := runAdopt(ctx, "sde", "C:/VMs/other.vhdx", false); err == nil {
+		t.Fatal("runAdopt() of a file Windows does not hold open succeeded")
+	}
+	if _, err := ctx.Tracker.GetEntry("C:/VMs/other.vhdx"); err == nil {
+		t.Error("the file was tracked anyway")
+	}
+}
+
+func TestRunAdoptRejectsSizeMismatch(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	fake.AddVHD("C:/VMs/small.vhdx", 1<<30).Device = "sde"
+	fake.AddVHD("C:/VMs/big.vhdx", 2<<30).Device = "sdf"
+
+	if err := runAdopt(ctx, "sde", "C:/VMs/big.vhdx", false); err == nil || !strings.Contains(err.Error(), "virtual size") {
+		t.Fatalf("runAdopt() of a VHD of another size error = %v, want a size mismatch", err)
+	}
+	if _, err := ctx.Tracker.GetEntry("C:/VMs/big.vhdx"); err == nil {
+		t.Error("the file was tracked anyway")
+	}
+	if err := runAdopt(ctx, "sde", "C:/VMs/big.vhdx", true); err != nil {
+		t.Errorf("runAdopt() with --force error = %v", err)
+	}
+}
+
+func TestConfirmByNameRejectsInvalidThreshold(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	ctx.Config.ConfirmNameAbove = "100 gigs"
+	if err := confirmByName(ctx, "delete", "data", 1<<40); !errors.Is(err, types.ErrInvalidInput) {
+		t.Errorf("confirmByName() error = %v, want ErrInvalidInput", err)
+	}
+	ctx.Config.ConfirmNameAbove = "0"
+	if err := confirmByName(ctx, "delete", "data", 1<<40); err != nil {
+		t.Errorf("confirmByName() with the confirmation disabled error = %v", err)
+	}
+}
+
+func TestRunMountAllNotifiesFailure(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	ctx.Config.Notify = "toast"
+	ctx.Tracker.SaveMapping("C:/VMs/gone.vhdx", "55555555-5555-4555-8555-555555555555", "/mnt/gone", "")
+
+	if err := runMountAll(ctx, 1, false, false); err != nil {
+		t.Fatalf("runMountAll() error = %v", err)
+	}
+	if !slices.Contains(fake.Calls, "Notify toast vhdm: mount --all failed") {
+		t.Errorf("no failure notification in %v", fake.Calls)
+	}
+}
+
+func TestRunMountAllSummary(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	ctx.Logger = logging.New(false, false)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID = "44444444-4444-4444-8444-444444444444"
+	disk.FSType = "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "/mnt/data", "")
+	ctx.Tracker.SaveMapping("C:/VMs/gone.vhdx", "55555555-5555-4555-8555-555555555555", "/mnt/gone", "")
+
+	var err error
+	logs := captureStderr(t, func() { err = runMountAll(ctx, 1, false, false) })
+	if err != nil {
+		t.Fatalf("runMountAll() error = %v", err)
+	}
+	if !strings.Contains(logs, "Mounted 1 of 2 VHDs") {
+		t.Errorf("summary does not count only the mounted VHD:\n%s", logs)
+	}
+	if !strings.Contains(logs, "1 of 2 VHDs could not be mounted") {
+		t.Errorf("failure not reported separately:\n%s", logs)
+	}
+}
+
+func TestRunServiceMonitorNotifiesOnce(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	ctx.Config.Notify = "toast"
+	stateDir := monitorStateDir
+	defer func() { monitorStateDir = stateDir }()
+	monitorStateDir = t.TempDir()
+	const uuid = "55555555-5555-4555-8555-555555555555"
+
+	notified := func() int {
+		n := 0
+		for _, call := range fake.Calls {
+			if strings.HasPrefix(call, "Notify toast vhdm: boot mount of /mnt/gone failed") {
+				n++
+			}
+		}
+		return n
+	}
+	// Restarts by systemd notify only the first failure
+	for range 3 {
+		if err := runServiceMonitor(ctx, uuid, "/mnt/gone", 10, ""); err == nil {
+			t.Fatal("runServiceMonitor() of a missing VHD succeeded")
+		}
+	}
+	if n := notified(); n != 1 {
+		t.Errorf("notified %d times, want once", n)
+	}
+
+	monitorRecovered("/mnt/gone")
+	runServiceMonitor(ctx, uuid, "/mnt/gone", 10, "")
+	if n := notified(); n != 2 {
+		t.Errorf("notified %d times after a passed check, want twice", n)
+	}
+}
+
+func TestRunMountVerifiesMountCheck(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	mountPoint := t.TempDir()
+	disk := fake.AddVHD("C:/VMs/pgdata.vhdx", 1<<30)
+	disk.Device, disk.UUID, disk.FSType = "sdd", "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/pgdata.vhdx", disk.UUID, "", "sdd")
+
+	if err := runMountCheckSet(ctx, "C:/VMs/pgdata.vhdx", "PG_VERSION", `test "$VHDM_MOUNT_POINT" = "$PWD"`); err != nil {
+		t.Fatal(err)
+	}
+	err := runMount(ctx, "C:/VMs/pgdata.vhdx", "", "", mountPoint, "", false, false)
+	if types.ErrorCode(err) != types.CodeMountCheck {
+		t.Fatalf("runMount() without sentinel error = %v, want %s", err, types.CodeMountCheck)
+	}
+	if len(disk.MountPoints) != 0 {
+		t.Errorf("left mounted after a failed check: %v", disk.MountPoints)
+	}
+
+	fake.Files[mountPoint+"/PG_VERSION"] = 3
+	if err := runMount(ctx, "C:/VMs/pgdata.vhdx", "", "", mountPoint, "", false, false); err != nil {
+		t.Fatalf("runMount() with sentinel error = %v", err)
+	}
+	ctx.WSL.Unmount(mountPoint)
+
+	runMountCheckSet(ctx, "C:/VMs/pgdata.vhdx", "", "exit 3")
+	if err := runMount(ctx, "C:/VMs/pgdata.vhdx", "", "", mountPoint, "", false, false); !errors.Is(err, types.ErrMountCheckFailed) {
+		t.Errorf("runMount() with failing command error = %v", err)
+	}
+}
+
+func TestRunMountMove(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.Device, disk.UUID, disk.FSType, disk.MountPoints = "sdd", "44444444-4444-4444-8444-444444444444", "ext4", []string{"/mnt/a"}
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "/mnt/a", "sdd")
+
+	fake.Errors["MountByUUID"] = errors.New("mount failed")
+	if err := runMount(ctx, "C:/VMs/data.vhdx", "", "", "/mnt/b", "", true, false); err == nil {
+		t.Fatal("runMount() succeeded")
+	}
+	delete(fake.Errors, "MountByUUID")
+	if !slices.Equal(disk.MountPoints, []string{"/mnt/a"}) {
+		t.Errorf("mount points after a failed move = %q, want it still at /mnt/a", disk.MountPoints)
+	}
+
+	if err := runMount(ctx, "C:/VMs/data.vhdx", "", "", "/mnt/b", "", true, false); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(disk.MountPoints, []string{"/mnt/b"}) {
+		t.Errorf("mount points = %q, want /mnt/b", disk.MountPoints)
+	}
+}
+
+func TestMountCheckCredential(t *testing.T) {
+	trackingFile := filepath.Join(t.TempDir(), "vhd_tracking.json")
+	if err := os.WriteFile(trackingFile, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	uid := uint32(os.Getuid())
+
+	if cred, err := mountCheckCredential(1000, trackingFile); cred != nil || err != nil {
+		t.Errorf("mountCheckCredential() as a user = %v, %v, want the current user", cred, err)
+	}
+	cred, err := mountCheckCredential(0, trackingFile)
+	switch {
+	case err != nil:
+		t.Fatalf("mountCheckCredential() as root error = %v", err)
+	case uid == 0 && cred != nil:
+		t.Errorf("mountCheckCredential() for a root-owned file = %v, want root", cred)
+	case uid != 0 && (cred == nil || cred.Uid != uid):
+		t.Errorf("mountCheckCredential() = %v, want uid %d", cred, uid)
+	}
+
+	if uid == 0 {
+		os.Chmod(trackingFile, 0o666)
+		if _, err := mountCheckCredential(0, trackingFile); err == nil {
+			t.Error("mountCheckCredential() for a world-writable root-owned file succeeded")
+		}
+	}
+}
+
+func TestMountPointConflict(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	mountPoint := t.TempDir()
+	for i, vhd := range []string{"C:/VMs/old.vhdx", "C:/VMs/new.vhdx"} {
+		disk := fake.AddVHD(vhd, 1<<30)
+		disk.UUID, disk.FSType = fmt.Sprintf("4444444%d-4444-4444-8444-444444444444", i), "ext4"
+		ctx.Tracker.SaveMapping(vhd, disk.UUID, "", "")
+	}
+	ctx.Tracker.SaveMapping("C:/VMs/old.vhdx", fake.Disk("C:/VMs/old.vhdx").UUID, mountPoint, "")
+	ctx.Tracker.SaveMapping("C:/VMs/old.vhdx", fake.Disk("C:/VMs/old.vhdx").UUID, "", "")
+
+	err := runMount(ctx, "C:/VMs/new.vhdx", "", "", mountPoint, "", false, false)
+	if types.ErrorCode(err) != types.CodeMountConflict || !strings.Contains(err.Error(), "C:/VMs/old.vhdx") {
+		t.Fatalf("runMount() error = %v, want %s naming old.vhdx", err, types.CodeMountConflict)
+	}
+	err = runServiceCreate(ctx, "C:/VMs/new.vhdx", mountPoint, "", "", 30, false, 0, "", false)
+	if !errors.Is(err, types.ErrMountPointConflict) {
+		t.Errorf("runServiceCreate() error = %v, want a mount point conflict", err)
+	}
+	if err := runMount(ctx, "C:/VMs/old.vhdx", "", "", mountPoint, "", false, false); err != nil {
+		t.Errorf("runMount() of the owner error = %v", err)
+	}
+}
+
+func TestPreviewUnitFile(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	path := filepath.Join(t.TempDir(), "vhdm-data.service")
+	unit := "[Service]\nExecStart=/usr/bin/vhdm service monitor\nRestartSec=10\n"
+
+	if out := captureStdout(t, func() { previewUnitFile(ctx, path, unit) }); out != "# "+path+" (new)\n"+unit {
+		t.Errorf("preview of a new unit = %q", out)
+	}
+	os.WriteFile(path, []byte(unit), 0644)
+	if out := captureStdout(t, func() { previewUnitFile(ctx, path, unit) }); !strings.Contains(out, "(unchanged)") {
+		t.Errorf("preview of an unchanged unit = %q", out)
+	}
+	out := captureStdout(t, func() { previewUnitFile(ctx, path, strings.Replace(unit, "10", "30", 1)) })
+	if !strings.Contains(out, "-RestartSec=10\n+RestartSec=30\n") {
+		t.Errorf("preview of a changed unit = %q, want a diff", out)
+	}
+	if got, _ := os.ReadFile(path); string(got) != unit {
+		t.Error("dry run changed the unit file")
+	}
+}
+
+func TestServiceOverride(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	if err := runServiceOverride(ctx, "vhdm-mount-data", []string{"TimeoutStartSec=120"}, nil, false, true); err == nil {
+		t.Error("runServiceOverride() accepted a unit that is not installed")
+	}
+	os.WriteFile(filepath.Join(ctx.Config.UnitDir, "vhdm-mount-data.service"), []byte("[Service]\n"), 0644)
+	if err := runServiceOverride(ctx, "vhdm-mount-data", []string{"TimeoutStartSec"}, nil, false, true); err == nil {
+		t.Error("runServiceOverride() accepted a setting without a value")
+	}
+
+	out := captureStdout(t, func() {
+		runServiceOverride(ctx, "vhdm-mount-data", []string{"TimeoutStartSec=120", "After=docker.service"}, nil, false, true)
+	})
+	if !strings.Contains(out, "[Service]\nTimeoutStartSec=120\n") || !strings.Contains(out, "[Unit]\nAfter=docker.service\n") {
+		t.Errorf("dry run printed %q, want TimeoutStartSec in [Service] and After in [Unit]", out)
+	}
+	if _, err := os.Stat(filepath.Join(ctx.Config.UnitDir, "vhdm-mount-data.service.d")); err == nil {
+		t.Error("dry run wrote the drop-in")
+	}
+
+	d := parseDropIn("# Written by 'vhdm service override'\n\n[Service]\nTimeoutStartSec=120\nRestartSec=5\n\n[Unit]\nAfter=docker.service\n")
+	d.set("Service", "TimeoutStartSec", "300")
+	if !d.unset("After") || d.unset("Nice") {
+		t.Error("unset() reported the wrong settings as overridden")
+	}
+	want := "# Written by 'vhdm service override'\n\n[Service]\nTimeoutStartSec=300\nRestartSec=5\n"
+	if got := d.String(); got != want {
+		t.Errorf("drop-in = %q, want %q", got, want)
+	}
+	if got := overrideSection("data.automount", "TimeoutIdleSec"); got != "Automount" {
+		t.Errorf("overrideSection() = %q, want Automount", got)
+	}
+}
+
+func TestUnitFileQuotingRoundTrip(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	ctx.Config.TrackingFile = `/home/my user/100% "vhdm"/tracking.json`
+	vhdmPath := "/opt/v h d m/vhdm"
+	vhdPath := `C:/VMs/My Disk (1)/50%.vhdx`
+	mountPoint := `/mnt/my data/$HOME %h 'q' \x`
+	options := `uid=1000,context="system_u:object_r:tmp_t"`
+
+	service := unitSettings(buildServiceUnit(ctx, vhdPath, "uuid-1", mountPoint, 30, options, nil, vhdmPath))
+	want := []string{vhdmPath, "service", "monitor", "--uuid", "uuid-1", "--mount-point", mountPoint, "--interval", "30", "--options", options}
+	if got := parseUnitCommandLine(t, service["ExecStart"][0]); !slices.Equal(got, want) {
+		t.Errorf("service ExecStart = %q, want %q", got, want)
+	}
+	if got := splitUnitCommandLine(service["ExecStart"][0]); !slices.Equal(got, want) {
+		t.Errorf("splitUnitCommandLine() = %q, want %q", got, want)
+	}
+	wantEnv := []string{"PATH", "VHDM_TRACKING_FILE=" + ctx.Config.TrackingFile, "HOME=" + os.Getenv("HOME")}
+	for i, value := range service["Environment"] {
+		if got := parseUnitWords(t, expandUnitSpecifiers(t, value)); len(got) != 1 || !strings.HasPrefix(got[0], wantEnv[i]) {
+			t.Errorf("Environment = %q, want %s", got, wantEnv[i])
+		}
+	}
+	if got := expandUnitSpecifiers(t, service["Description"][0]); got != "Auto-mount VHD: "+vhdPath {
+		t.Errorf("service Description = %q", got)
+	}
+	if got := parseUnitWords(t, expandUnitSpecifiers(t, service["RequiresMountsFor"][0])); !slices.Equal(got, []string{"/mnt/c/VMs/My Disk (1)/50%.vhdx"}) {
+		t.Errorf("service RequiresMountsFor = %q", got)
+	}
+
+	units := buildAutomountUnits(vhdPath, "uuid-1", mountPoint, "ext4", options, 0, nil, vhdmPath, ctx.Config.TrackingFile)
+	attach := unitSettings(units.Attach)
+	if got := parseUnitCommandLine(t, attach["ExecStart"][0]); !slices.Equal(got, []string{vhdmPath, "--quiet", "attach", "--vhd-path", vhdPath}) {
+		t.Errorf("attach ExecStart = %q", got)
+	}
+	if got := parseUnitCommandLine(t, attach["ExecStop"][0]); !slices.Equal(got, []string{vhdmPath, "--quiet", "detach", "--uuid", "uuid-1"}) {
+		t.Errorf("attach ExecStop = %q", got)
+	}
+	mount := unitSettings(units.Mount)
+	if got := expandUnitSpecifiers(t, mount["Where"][0]); got != mountPoint {
+		t.Errorf("mount Where = %q, want %q", got, mountPoint)
+	}
+	if got := expandUnitSpecifiers(t, mount["Options"][0]); got != options {
+		t.Errorf("mount Options = %q, want %q", got, options)
+	}
+	if got := expandUnitSpecifiers(t, unitSettings(units.Automount)["Where"][0]); got != mountPoint {
+		t.Errorf("automount Where = %q, want %q", got, mountPoint)
+	}
+}
+
+func TestRunServiceList(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	ctx.Config.Output = "json"
+	runner := ctx.Runner.(*wslfake.Runner)
+	ctx.Tracker.SaveMapping("C:/VMs/my data.vhdx", "44444444-4444-4444-8444-444444444444", "", "")
+	recordService(ctx, "C:/VMs/my data.vhdx", "data-mount.service")
+	unit := buildServiceUnit(ctx, "C:/VMs/my data.vhdx", "44444444-4444-4444-8444-444444444444", "/mnt/my data", 30, "", nil, "/usr/bin/vhdm")
+	os.WriteFile(filepath.Join(ctx.Config.UnitDir, "data-mount.service"), []byte(unit), 0644)
+	runner.Outputs["systemctl show data-mount.service --property="+serviceShowProperties] =
+		"LoadState=loaded\nUnitFileState=enabled\nActiveState=failed\nResult=exit-code\nExecMainStatus=1\n" +
+			"ExecMainExitTimestamp=Wed 2026-10-14 10:00:00 UTC\nNRestarts=3\n"
+
+	out := captureStdout(t, func() {
+		if err := runServiceList(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+	var entries []ServiceEntry
+	if err := json.Unmarshal([]byte(out), &entries); err != nil {
+		t.Fatalf("output %q is not JSON: %v", out, err)
+	}
+	want := ServiceEntry{
+		Name:       "data-mount.service",
+		Enabled:    "enabled",
+		Active:     "failed",
+		Path:       "C:/VMs/my data.vhdx",
+		UUID:       "44444444-4444-4444-8444-444444444444",
+		MountPoint: "/mnt/my data",
+		Restarts:   3,
+	}
+	wantFailure := ServiceFailure{Result: "exit-code", ExitStatus: 1, Time: "2026-10-14T10:00:00Z"}
+	if len(entries) != 1 {
+		t.Fatalf("service list = %+v, want one service", entries)
+	}
+	got, failure := entries[0], entries[0].LastFailure
+	got.LastFailure = nil
+	if got != want || failure == nil || *failure != wantFailure {
+		t.Errorf("service = %+v, failure %+v; want %+v, %+v", got, failure, want, wantFailure)
+	}
+}
+
+func TestWindowsMountLines(t *testing.T) {
+	if got, want := windowsMountLines("C:/VMs/data.vhdx"), "After=local-fs.target mnt-c.mount\nRequires=mnt-c.mount\nRequiresMountsFor=/mnt/c/VMs/data.vhdx\n"; got != want {
+		t.Errorf("windowsMountLines(C:) = %q, want %q", got, want)
+	}
+	settings := unitSettings(windowsMountLines("e:/Disks/data.vhdx"))
+	if got := settings["Requires"]; !slices.Equal(got, []string{"mnt-e.mount mnt-c.mount"}) {
+		t.Errorf("windowsMountLines(e:) requires %q, want the E: and C: drives", got)
+	}
+}
+
+// unitSettings returns the values of each setting of a unit file
+func unitSettings(content string) map[string][]string {
+	settings := map[string][]string{}
+	for _, line := range strings.Split(content, "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(line, "#") {
+			settings[key] = append(settings[key], value)
+		}
+	}
+	return settings
+}
+
+// parseUnitCommandLine parses an ExecStart= value the way systemd does:
+// specifiers, then words, then $ references
+func parseUnitCommandLine(t *testing.T, value string) []string {
+	t.Helper()
+	words := parseUnitWords(t, expandUnitSpecifiers(t, value))
+	for i, word := range words {
+		var b strings.Builder
+		for j := 0; j < len(word); j++ {
+			if word[j] == '$' {
+				if j+1 >= len(word) || word[j+1] != '$' {
+					t.Fatalf("word %q references a variable", word)
+				}
+				j++
+			}
+			b.WriteByte(word[j])
+		}
+		words[i] = b.String()
+	}
+	return words
+}
+
+// expandUnitSpecifiers expands %%, failing on any other specifier
+func expandUnitSpecifiers(t *testing.T, value string) string {
+	t.Helper()
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '%' {
+			if i+1 >= len(value) || value[i+1] != '%' {
+				t.Fatalf("value %q has a specifier", value)
+			}
+			i++
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// parseUnitWords splits a value into words, unquoting and unescaping them
+func parseUnitWords(t *testing.T, value string) []string {
+	t.Helper()
+	var words []string
+	var word strings.Builder
+	inWord, quote := false, byte(0)
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\':
+			i++
+			if i >= len(value) {
+				t.Fatalf("value %q ends with a backslash", value)
+			}
+			switch value[i] {
+			case 'n':
+				word.WriteByte('\n')
+			case 't':
+				word.WriteByte('\t')
+			case 'x':
+				var r byte
+				fmt.Sscanf(value[i+1:i+3], "%02x", &r)
+				word.WriteByte(r)
+				i += 2
+			default:
+				word.WriteByte(value[i])
+			}
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && (c == ' ' || c == '\t'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			inWord = false
+			continue
+		default:
+			word.WriteByte(c)
+		}
+		inWord = true
+	}
+	if quote != 0 {
+		t.Fatalf("value %q has an unterminated quote", value)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+func TestRunFormatWritesIDFile(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/pgdata.vhdx", 1<<30)
+	disk.Device = "sdd"
+	ctx.Tracker.SaveMapping("C:/VMs/pgdata.vhdx", "", "", "sdd")
+
+	if err := runFormat(ctx, "sdd", "ext4", false); err != nil {
+		t.Fatal(err)
+	}
+	if disk.IDFile == nil {
+		t.Fatal("no ID file written")
+	}
+	if disk.IDFile.Path != "C:/VMs/pgdata.vhdx" || disk.IDFile.Name != "pgdata" || disk.IDFile.UUID != disk.UUID {
+		t.Errorf("ID file = %+v, want path, name and UUID %s", *disk.IDFile, disk.UUID)
+	}
+	if len(disk.MountPoints) != 0 {
+		t.Errorf("left mounted after writing the ID file: %v", disk.MountPoints)
+	}
+}
+
+func TestRunCloneLinkedProtectsBase(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	base := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	base.UUID, base.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", base.UUID, "", "")
+
+	if err := runClone(ctx, "C:/VMs/data.vhdx", "C:/VMs/data-test.vhdx", true); err != nil {
+		t.Fatal(err)
+	}
+	clone := fake.Disk("C:/VMs/data-test.vhdx")
+	if clone == nil || clone.UUID == "" || clone.UUID == base.UUID {
+		t.Fatalf("clone = %+v, want a new UUID", clone)
+	}
+	if clone.Device != "" {
+		t.Errorf("clone left attached as %s", clone.Device)
+	}
+	if clone.IDFile == nil || clone.IDFile.Path != "C:/VMs/data-test.vhdx" {
+		t.Errorf("clone ID file = %+v, want the clone path", clone.IDFile)
+	}
+	entry, err := ctx.Tracker.GetEntry("C:/VMs/data-test.vhdx")
+	if err != nil || entry.UUID != clone.UUID || entry.BackingFile != "C:/VMs/data.vhdx" {
+		t.Errorf("clone tracking = %+v (%v)", entry, err)
+	}
+
+	if err := runAttach(ctx, "C:/VMs/data.vhdx"); types.ErrorCode(err) != types.CodeReadOnly {
+		t.Errorf("runAttach() of the base error = %v, want %s", err, types.CodeReadOnly)
+	}
+	if err := runDelete(ctx, "C:/VMs/data.vhdx", false); types.ErrorCode(err) != types.CodeReadOnly {
+		t.Errorf("runDelete() of the base error = %v, want %s", err, types.CodeReadOnly)
+	}
+	if err := runMount(ctx, "", base.UUID, "", "/mnt/base", "", false, false); types.ErrorCode(err) != types.CodeReadOnly {
+		t.Errorf("runMount() of the base by UUID error = %v, want %s", err, types.CodeReadOnly)
+	}
+	base.Device = "sdd" // Attached by hand
+	if err := runMount(ctx, "", "", "sdd", "/mnt/base", "", false, false); types.ErrorCode(err) != types.CodeReadOnly {
+		t.Errorf("runMount() of the base by device error = %v, want %s", err, types.CodeReadOnly)
+	}
+	base.Device = ""
+	if fake.Disk("C:/VMs/data.vhdx") == nil {
+		t.Error("base deleted")
+	}
+}
+
+func TestRunCloneKeepsUUIDOfOtherFilesystems(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	base := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	base.UUID, base.FSType = "ABCD-1234", "vfat"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", base.UUID, "", "")
+
+	if err := runClone(ctx, "C:/VMs/data.vhdx", "C:/VMs/data-copy.vhdx", false); err != nil {
+		t.Fatal(err)
+	}
+	if clone := fake.Disk("C:/VMs/data-copy.vhdx"); clone == nil || clone.UUID != base.UUID {
+		t.Errorf("clone = %+v, want the UUID of the original", clone)
+	}
+	for _, call := range fake.Calls {
+		if strings.HasPrefix(call, "RegenerateUUID") {
+			t.Errorf("clone of a vfat filesystem called %s", call)
+		}
+	}
+}
+
+func TestRunFlatten(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	base := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	base.UUID, base.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", base.UUID, "", "")
+	for _, clone := range []string{"C:/VMs/a.vhdx", "C:/VMs/b.vhdx"} {
+		if err := runClone(ctx, "C:/VMs/data.vhdx", clone, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := runFlatten(ctx, "C:/VMs/a.vhdx", true); err == nil {
+		t.Fatal("runFlatten() merged into a base with another linked clone")
+	}
+	if err := runFlatten(ctx, "C:/VMs/b.vhdx", false); err != nil {
+		t.Fatal(err)
+	}
+	b := fake.Disk("C:/VMs/b.vhdx")
+	if b == nil || b.Parent != "" || b.Device != "" {
+		t.Errorf("flattened clone = %+v, want a detached standalone VHD", b)
+	}
+	if entry, _ := ctx.Tracker.GetEntry("C:/VMs/b.vhdx"); entry.BackingFile != "" || entry.UUID != b.UUID {
+		t.Errorf("flattened clone tracking = %+v", entry)
+	}
+
+	aUUID := fake.Disk("C:/VMs/a.vhdx").UUID
+	if err := runFlatten(ctx, "C:/VMs/a.vhdx", true); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Disk("C:/VMs/a.vhdx") != nil {
+		t.Error("merged clone not deleted")
+	}
+	if base.UUID != aUUID {
+		t.Errorf("base UUID = %s, want the clone's %s", base.UUID, aUUID)
+	}
+	if uuid, _ := ctx.Tracker.LookupUUIDByPath("C:/VMs/data.vhdx"); uuid != aUUID {
+		t.Errorf("tracked base UUID = %s, want %s", uuid, aUUID)
+	}
+	if err := ensureNotReference(ctx, "attach", "C:/VMs/data.vhdx"); err != nil {
+		t.Errorf("base still protected: %v", err)
+	}
+}
+
+func TestRunReportFormats(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID, disk.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+
+	// The global --output selects the format
+	ctx.Config.Output = "json"
+	out := captureStdout(t, func() {
+		if err := runReport(ctx, "", true); err != nil {
+			t.Error(err)
+		}
+	})
+	var report capacityReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil || len(report.VHDs) != 1 {
+		t.Errorf("report = %q (%v), want a JSON document with one VHD", out, err)
+	}
+	ctx.Config.Output = "csv"
+	if out := captureStdout(t, func() { runReport(ctx, "", true) }); !strings.HasPrefix(out, "path,") || !strings.Contains(out, "C:/VMs/data.vhdx") {
+		t.Errorf("CSV report = %q", out)
+	}
+
+	// --report-format overrides it
+	if out := captureStdout(t, func() { runReport(ctx, "html", true) }); !strings.Contains(out, "<html>") {
+		t.Errorf("HTML report = %q", out)
+	}
+	ctx.Config.Output = "sh"
+	if err := runReport(ctx, "", true); !errors.Is(err, types.ErrInvalidInput) {
+		t.Errorf("runReport() with --output sh error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestRunImportRollsBackCreatedVHD(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	mountPoint := t.TempDir()
+	fake.Files["/mnt/d/Backups/data.tar.zst"] = 10 << 20
+	fake.Errors["ExtractTarball"] = errors.New("tar: unexpected end of file")
+
+	if err := runImport(ctx, "C:/VMs/data.vhdx", "D:/Backups/data.tar.zst", mountPoint, "", ""); err == nil {
+		t.Fatal("runImport() with a failing extract succeeded")
+	}
+	if fake.Disk("C:/VMs/data.vhdx") != nil {
+		t.Error("half-filled VHD left behind")
+	}
+	if _, err := ctx.Tracker.GetEntry("C:/VMs/data.vhdx"); err == nil {
+		t.Error("tracking entry of the removed VHD left behind")
+	}
+
+	// A VHD that existed before the import is kept
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID, disk.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+	if err := runImport(ctx, "C:/VMs/data.vhdx", "D:/Backups/data.tar.zst", mountPoint, "", ""); err == nil {
+		t.Fatal("runImport() with a failing extract succeeded")
+	}
+	if fake.Disk("C:/VMs/data.vhdx") != disk {
+		t.Error("existing VHD removed")
+	}
+}
+
+func TestRunArchiveRefusesAttachedVHD(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	// Attached outside vhdm, so tracking knows nothing about it
+	fake.AddVHD("C:/VMs/data.vhdx", 1<<30).Device = "sde"
+
+	if err := runArchive(ctx, "C:/VMs/data.vhdx"); !errors.Is(err, types.ErrFileInUse) {
+		t.Errorf("runArchive() of an attached VHD error = %v, want ErrFileInUse", err)
+	}
+	fake.Errors["FileInUseByWindows"] = errors.New("interop disabled")
+	if err := runArchive(ctx, "C:/VMs/data.vhdx"); err == nil {
+		t.Error("runArchive() without an in-use check succeeded")
+	}
+	if fake.Disk("C:/VMs/data.vhdx") == nil {
+		t.Error("attached VHD archived")
+	}
+}
+
+func TestRunCompact(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.Device, disk.UUID, disk.FSType, disk.MountPoints = "sdd", "44444444-4444-4444-8444-444444444444", "ext4", []string{"/mnt/data"}
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "/mnt/data", "sdd")
+
+	ctx.Tracker.Update("C:/VMs/data.vhdx", func(e *types.TrackingEntry) { e.Pinned = true })
+	if err := runCompact(ctx, "C:/VMs/data.vhdx", false, true, false); !errors.Is(err, types.ErrVHDPinned) {
+		t.Fatalf("runCompact() of a pinned VHD error = %v, want ErrVHDPinned", err)
+	}
+	if err := runCompact(ctx, "C:/VMs/data.vhdx", false, true, true); err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := ctx.Tracker.GetEntry("C:/VMs/data.vhdx"); entry.Pinned {
+		t.Error("pin kept despite --unpin")
+	}
+	if !slices.Contains(fake.Calls, "Trim /mnt/data") {
+		t.Errorf("calls = %q, want fstrim of the mount point", fake.Calls)
+	}
+	compacted := fake.Disk("C:/VMs/data.vhdx")
+	if compacted == nil || compacted == disk || compacted.UUID != disk.UUID {
+		t.Fatalf("compacted VHD = %+v, want a copy with UUID %s", compacted, disk.UUID)
+	}
+	if !slices.Equal(compacted.MountPoints, []string{"/mnt/data"}) {
+		t.Errorf("mount points = %v, want it re-mounted at /mnt/data", compacted.MountPoints)
+	}
+	if fake.Disk("C:/VMs/data_bkp.vhdx") != nil {
+		t.Error("original kept despite --no-backup")
+	}
+}
+
+func TestRunConvert(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID, disk.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+	ctx.Tracker.SaveMapping("C:/VMs/app.vhdx", "55555555-4444-4444-8444-444444444444", "", "")
+	ctx.Tracker.SetAfter("C:/VMs/app.vhdx", []string{"C:/VMs/data.vhdx"})
+
+	ctx.Tracker.Update("C:/VMs/data.vhdx", func(e *types.TrackingEntry) { e.Pinned = true })
+	if err := runConvert(ctx, "C:/VMs/data.vhdx", "vhd", true, false); !errors.Is(err, types.ErrVHDPinned) {
+		t.Fatalf("runConvert() of a pinned VHD error = %v, want ErrVHDPinned", err)
+	}
+	if err := runConvert(ctx, "C:/VMs/data.vhdx", "vhd", true, true); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Disk("C:/VMs/data.vhd") == nil || fake.Disk("C:/VMs/data_bkp.vhdx") == nil || fake.Disk("C:/VMs/data.vhdx") != nil {
+		t.Errorf("disks = %v, want data.vhd and the original as data_bkp.vhdx", slices.Collect(maps.Keys(fake.Disks)))
+	}
+	if entry, _ := ctx.Tracker.GetEntry("C:/VMs/data.vhd"); entry.UUID != disk.UUID || !entry.Pinned {
+		t.Errorf("tracking of the converted VHD = %+v, want UUID %s and the pin", entry, disk.UUID)
+	}
+	if app, _ := ctx.Tracker.GetEntry("C:/VMs/app.vhdx"); !slices.Equal(app.After, []string{"C:/VMs/data.vhd"}) {
+		t.Errorf("dependency = %v, want the converted VHD", app.After)
+	}
+
+	if err := runConvert(ctx, "C:/VMs/data.vhd", "qcow2", false, true); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Disk("C:/VMs/data.qcow2") == nil || fake.Disk("C:/VMs/data.vhd") != nil {
+		t.Errorf("disks = %v, want data.qcow2 only", slices.Collect(maps.Keys(fake.Disks)))
+	}
+	if _, err := ctx.Tracker.GetEntry("C:/VMs/data.vhd"); err == nil {
+		t.Error("VHD converted to qcow2 still tracked")
+	}
+}
+
+func TestRunRestore(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.Device, disk.UUID, disk.FSType, disk.MountPoints = "sdd", "44444444-4444-4444-8444-444444444444", "ext4", []string{"/mnt/data"}
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "/mnt/data", "sdd")
+	backup := fake.AddVHD("D:/Backups/data.vhdx", 1<<30)
+	backup.UUID, backup.FSType = "55555555-4444-4444-8444-444444444444", "ext4"
+
+	ctx.Config.Yes = false
+	if err := runRestore(ctx, "C:/VMs/data.vhdx", "D:/Backups/data.vhdx", false, false); err == nil {
+		t.Fatal("runRestore() replaced the VHD without --yes")
+	}
+	ctx.Config.Yes = true
+	fake.Errors["CheckImage"] = errors.New("corrupt")
+	if err := runRestore(ctx, "C:/VMs/data.vhdx", "D:/Backups/data.vhdx", false, false); err == nil {
+		t.Fatal("runRestore() accepted a backup failing its check")
+	}
+	fake.Errors["CheckImage"] = wsl.ErrCheckUnsupported
+	if err := runRestore(ctx, "C:/VMs/data.vhdx", "D:/Backups/data.vhdx", false, false); err != nil {
+		t.Fatalf("runRestore() of a backup without a format check error = %v", err)
+	}
+	delete(fake.Errors, "CheckImage")
+	restored := fake.Disk("C:/VMs/data.vhdx")
+	if restored == nil || restored.UUID != backup.UUID || !slices.Equal(restored.MountPoints, []string{"/mnt/data"}) {
+		t.Errorf("restored VHD = %+v, want the backup mounted at /mnt/data", restored)
+	}
+	if entry, _ := ctx.Tracker.GetEntry("C:/VMs/data.vhdx"); entry.UUID != backup.UUID || !slices.Equal(entry.MountPoints, []string{"/mnt/data"}) {
+		t.Errorf("tracking = %+v, want UUID %s mounted at /mnt/data", entry, backup.UUID)
+	}
+	if fake.Disk("D:/Backups/data.vhdx") == nil || fake.Disk("C:/VMs/data_restore.vhdx") != nil {
+		t.Error("backup consumed or temporary copy left behind")
+	}
+}
+
+func TestRunMove(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.Device, disk.UUID, disk.FSType, disk.MountPoints = "sdd", "66666666-4444-4444-8444-444444444444", "ext4", []string{"/mnt/data"}
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "/mnt/data", "sdd")
+	fake.AddVHD("C:/VMs/data@before.vhdx", 1<<30)
+	ctx.Tracker.Update("C:/VMs/data.vhdx", func(entry *types.TrackingEntry) {
+		entry.Snapshots = []types.Snapshot{{Name: "before", File: "C:/VMs/data@before.vhdx"}}
+	})
+	ctx.Tracker.SaveMapping("C:/VMs/logs.vhdx", "", "", "")
+	ctx.Tracker.Update("C:/VMs/logs.vhdx", func(entry *types.TrackingEntry) { entry.After = []string{"C:/VMs/data.vhdx"} })
+	unit := filepath.Join(ctx.Config.UnitDir, automountPrefix+"mnt-data.service")
+	os.WriteFile(unit, []byte("[Unit]\nDescription=Attach VHD on demand: C:/VMs/data.vhdx\n"+
+		"After=local-fs.target mnt-c.mount\nRequires=mnt-c.mount\n\n[Service]\n"+
+		"ExecStart=/usr/bin/vhdm --quiet attach --vhd-path \"C:/VMs/data.vhdx\"\n"+
+		"ExecStop=/usr/bin/vhdm --quiet detach --vhd-path C:/VMs/data.vhdx.old\n"), 0644)
+
+	if err := runMove(ctx, "C:/VMs/data.vhdx", "D:/Disks/"); err == nil {
+		t.Fatal("runMove() moved into a missing directory")
+	}
+	fake.Files["/mnt/d/Disks"] = 0
+	if err := runMove(ctx, "C:/VMs/data.vhdx", "D:/Disks/"); err != nil {
+		t.Fatal(err)
+	}
+
+	moved := fake.Disk("D:/Disks/data.vhdx")
+	if fake.Disk("C:/VMs/data.vhdx") != nil || moved == nil || !slices.Equal(moved.MountPoints, []string{"/mnt/data"}) {
+		t.Fatalf("moved VHD = %+v, want it mounted at /mnt/data", moved)
+	}
+	if moved.IDFile == nil || moved.IDFile.Path != "D:/Disks/data.vhdx" {
+		t.Errorf("ID file = %+v, want it to name the new path", moved.IDFile)
+	}
+	entry, err := ctx.Tracker.GetEntry("D:/Disks/data.vhdx")
+	if err != nil || entry.UUID != disk.UUID || len(entry.Snapshots) != 1 || entry.Snapshots[0].File != "D:/Disks/data@before.vhdx" {
+		t.Errorf("tracking = %+v, %v", entry, err)
+	}
+	if fake.Disk("D:/Disks/data@before.vhdx") == nil {
+		t.Error("snapshot file not moved")
+	}
+	if logs, _ := ctx.Tracker.GetEntry("C:/VMs/logs.vhdx"); !slices.Equal(logs.After, []string{"D:/Disks/data.vhdx"}) {
+		t.Errorf("dependency = %q, want the new path", logs.After)
+	}
+	content, _ := os.ReadFile(unit)
+	want := "[Unit]\nDescription=Attach VHD on demand: D:/Disks/data.vhdx\n" +
+		"After=local-fs.target mnt-d.mount mnt-c.mount\nRequires=mnt-d.mount mnt-c.mount\nRequiresMountsFor=/mnt/d/Disks/data.vhdx\n\n[Service]\n" +
+		"ExecStart=/usr/bin/vhdm --quiet attach --vhd-path D:/Disks/data.vhdx\n" +
+		"ExecStop=/usr/bin/vhdm --quiet detach --vhd-path C:/VMs/data.vhdx.old\n"
+	if string(content) != want {
+		t.Errorf("unit = %q, want %q", content, want)
+	}
+}
+
+func TestRunRename(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	runner := ctx.Runner.(*wslfake.Runner)
+	fake.Files["/mnt/c/VMs"] = 0
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID, disk.FSType = "77777777-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+	os.WriteFile(filepath.Join(ctx.Config.UnitDir, "vhdm-mount-data.service"), []byte("[Unit]\nDescription=Auto-mount VHD: C:/VMs/data.vhdx\n\n[Service]\n"+
+		"ExecStart=/usr/bin/vhdm --quiet mount --uuid "+disk.UUID+" --mount-point /mnt/data\n"), 0644)
+	os.WriteFile(filepath.Join(ctx.Config.UnitDir, "vhdm-mount-logs.service"), []byte("[Unit]\nAfter=vhdm-mount-data.service\nRequires=vhdm-mount-data.service\n"), 0644)
+	runner.Outputs["systemctl is-enabled vhdm-mount-data.service"] = "enabled\n"
+	runner.Outputs["systemctl is-active vhdm-mount-data.service"] = "active\n"
+
+	if err := runRename(ctx, "C:/VMs/data.vhdx", "../data2"); err == nil {
+		t.Fatal("runRename() accepted a name with a directory")
+	}
+	if err := runRename(ctx, "C:/VMs/data.vhdx", "data2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.Disk("C:/VMs/data2.vhdx") == nil || fake.Disk("C:/VMs/data.vhdx") != nil {
+		t.Error("VHD file not renamed to data2.vhdx")
+	}
+	if entry, err := ctx.Tracker.GetEntry("C:/VMs/data2.vhdx"); err != nil || entry.UUID != disk.UUID {
+		t.Errorf("tracking = %+v, %v, want the UUID kept", entry, err)
+	}
+	if _, err := os.Stat(filepath.Join(ctx.Config.UnitDir, "vhdm-mount-data.service")); err == nil {
+		t.Error("service named after the old file still exists")
+	}
+	if content, _ := os.ReadFile(filepath.Join(ctx.Config.UnitDir, "vhdm-mount-data2.service")); !strings.HasPrefix(string(content), "[Unit]\nDescription=Auto-mount VHD: C:/VMs/data2.vhdx\n") {
+		t.Errorf("renamed service = %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(ctx.Config.UnitDir, "vhdm-mount-logs.service")); !strings.Contains(string(content), "Requires=vhdm-mount-data2.service\n") {
+		t.Errorf("dependent service = %q, want it to require the renamed one", content)
+	}
+	if i := slices.Index(runner.Commands, "systemctl stop vhdm-mount-data.service"); i < 0 || i > slices.Index(runner.Commands, "systemctl start vhdm-mount-data2.service") {
+		t.Errorf("commands = %q, want the active service stopped during the rename", runner.Commands)
+	}
+	for _, want := range []string{"systemctl disable vhdm-mount-data.service", "systemctl enable vhdm-mount-data2.service", "systemctl start vhdm-mount-data2.service"} {
+		if !slices.Contains(runner.Commands, want) {
+			t.Errorf("commands = %q, want %q", runner.Commands, want)
+		}
+	}
+	if entry, _ := ctx.Tracker.GetEntry("C:/VMs/data2.vhdx"); entry.Service != "vhdm-mount-data2.service" {
+		t.Errorf("tracked service = %q, want the renamed one", entry.Service)
+	}
+}
+
+func TestServiceTracking(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID, disk.FSType = "88888888-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+	unitPath := filepath.Join(ctx.Config.UnitDir, "data-disk.service")
+
+	if err := runServiceCreate(ctx, "C:/VMs/data.vhdx", "/mnt/data", "", "data-disk", 30, true, 0, "", true); !errors.Is(err, types.ErrInvalidInput) {
+		t.Errorf("runServiceCreate() with --name and --automount error = %v, want ErrInvalidInput", err)
+	}
+	if err := runServiceCreate(ctx, "C:/VMs/data.vhdx", "/mnt/data", "", "data-disk", 30, false, 0, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := ctx.Tracker.GetEntry("C:/VMs/data.vhdx"); entry.Service != "data-disk.service" {
+		t.Errorf("tracked service = %q, want data-disk.service", entry.Service)
+	}
+	if got := mountUnitFor(ctx, "C:/VMs/data.vhdx"); got != "data-disk.service" {
+		t.Errorf("mountUnitFor() = %q, want the tracked service", got)
+	}
+
+	// Formatted anew, e.g. by resize
+	updateServiceUUID(ctx, "C:/VMs/data.vhdx", disk.UUID, "99999999-4444-4444-8444-444444444444")
+	content, _ := os.ReadFile(unitPath)
+	if strings.Contains(string(content), disk.UUID) || !strings.Contains(string(content), " 99999999-4444-4444-8444-444444444444 ") {
+		t.Errorf("service after UUID change = %q", content)
+	}
+
+	if err := runDelete(ctx, "C:/VMs/data.vhdx", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(unitPath); err == nil {
+		t.Error("service of the deleted VHD still exists")
+	}
+
+	// A default-named unit only belongs to the VHD whose UUID it mounts
+	other := fake.AddVHD("D:/Other/data.vhdx", 1<<30)
+	other.UUID = "aaaaaaaa-4444-4444-8444-444444444444"
+	ctx.Tracker.SaveMapping("D:/Other/data.vhdx", other.UUID, "", "")
+	legacy := filepath.Join(ctx.Config.UnitDir, "vhdm-mount-data.service")
+	os.WriteFile(legacy, []byte("[Service]\nExecStart=/usr/bin/vhdm --quiet mount --uuid "+disk.UUID+" --mount-point /mnt/data\n"), 0644)
+	if got := trackedService(ctx, "D:/Other/data.vhdx"); got != "" {
+		t.Errorf("trackedService() = %q for a unit mounting another UUID", got)
+	}
+	if err := runDelete(ctx, "D:/Other/data.vhdx", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Error("deleting a VHD removed the service of another VHD with the same file name")
+	}
+}
+
+func TestRunSnapshotRevert(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	ctx.Config.Yes = true
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID, disk.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+
+	if err := runSnapshotCreate(ctx, "C:/VMs/data.vhdx", "before", false); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Disk("C:/VMs/data@before.vhdx") == nil {
+		t.Fatal("no snapshot file created")
+	}
+	if err := runSnapshotCreate(ctx, "C:/VMs/data.vhdx", "before", false); err == nil {
+		t.Error("runSnapshotCreate() accepted a duplicate name")
+	}
+
+	// Reformat, then roll back to the filesystem of the snapshot
+	disk.UUID = "55555555-5555-4555-8555-555555555555"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+	if err := runSnapshotRevert(ctx, "C:/VMs/data.vhdx", "before", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Disk("C:/VMs/data.vhdx"); got == nil || got.UUID != "44444444-4444-4444-8444-444444444444" {
+		t.Errorf("reverted disk = %+v", got)
+	}
+	if uuid, _ := ctx.Tracker.LookupUUIDByPath("C:/VMs/data.vhdx"); uuid != "44444444-4444-4444-8444-444444444444" {
+		t.Errorf("tracked UUID after revert = %s", uuid)
+	}
+
+	if err := runSnapshotDelete(ctx, "C:/VMs/data.vhdx", "before"); err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := ctx.Tracker.GetEntry("C:/VMs/data.vhdx")
+	if len(entry.Snapshots) != 0 || fake.Disk("C:/VMs/data@before.vhdx") != nil {
+		t.Errorf("snapshot left after delete: %+v", entry.Snapshots)
+	}
+}
+
+func TestRemoveAutomountStopsUnits(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	runner := ctx.Runner.(*wslfake.Runner)
+
+	if err := removeAutomount(ctx, automountPrefix+"mnt-test.service"); err == nil {
+		t.Fatal("removeAutomount() of a missing unit succeeded")
+	}
+	want := []string{
+		"systemctl stop mnt-test.automount",
+		"systemctl stop mnt-test.mount",
+		"systemctl stop " + automountPrefix + "mnt-test.service",
+		"systemctl disable mnt-test.automount",
+	}
+	if !slices.Equal(runner.Commands, want) {
+		t.Errorf("commands = %q, want %q", runner.Commands, want)
+	}
+}
+
+func TestRunListQuiet(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", "44444444-4444-4444-8444-444444444444", "", "")
+	ctx.Tracker.SaveMapping("C:/VMs/logs.vhdx", "55555555-5555-4555-8555-555555555555", "", "")
+
+	out := captureStdout(t, func() {
+		if err := runList(ctx); err != nil {
+			t.Fatalf("runList() error = %v", err)
+		}
+	})
+	want := "C:/VMs/data.vhdx (44444444-4444-4444-8444-444444444444): detached\n" +
+		"C:/VMs/logs.vhdx (55555555-5555-4555-8555-555555555555): not found\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if len(fake.Calls) != 0 {
+		t.Errorf("list changed the system: %v", fake.Calls)
+	}
+}
+
+func TestQuietLine(t *testing.T) {
+	tests := []struct {
+		line quietLine
+		want string
+	}{
+		{quietLine{Key: "C:/VMs/data.vhdx", UUID: "44444444-4444-4444-8444-444444444444", State: "mounted at /mnt/data"}, "C:/VMs/data.vhdx (44444444-4444-4444-8444-444444444444): mounted at /mnt/data"},
+		{quietLine{Key: "C:/VMs/data.vhdx", State: "deleted"}, "C:/VMs/data.vhdx: deleted"},
+		{quietLine{Key: "C:/VMs/data.vhdx", State: "damaged (leaks)\r\nsee log"}, "C:/VMs/data.vhdx: damaged (leaks) see log"},
+	}
+	for _, tt := range tests {
+		if got := tt.line.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestRunInfo(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	ctx.Config.Output = "json"
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID, disk.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	if err := runMount(ctx, "C:/VMs/data.vhdx", "", "", "/mnt/data", "", false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureStdout(t, func() {
+		if err := runInfo(ctx, "C:/VMs/data.vhdx"); err != nil {
+			t.Fatalf("runInfo() error = %v", err)
+		}
+	})
+	var res InfoResult
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatalf("output %q: %v", out, err)
+	}
+	if res.Format != "vhdx" || res.VirtualSize != 1<<30 || res.State != types.StateMounted || !slices.Equal(res.MountPoints, []string{"/mnt/data"}) {
+		t.Errorf("info = %+v", res)
+	}
+
+	if err := runInfo(ctx, "C:/VMs/missing.vhdx"); !errors.Is(err, types.ErrVHDNotFound) {
+		t.Errorf("runInfo(missing) error = %v, want ErrVHDNotFound", err)
+	}
+}
+
+func TestRunVerify(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID, disk.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+
+	if err := runVerify(ctx, "C:/VMs/data.vhdx", true, false); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(fake.Calls, "CheckFilesystem sdd ext4") {
+		t.Errorf("calls = %q, want a filesystem check", fake.Calls)
+	}
+	if disk.Device != "" {
+		t.Error("VHD left attached after the check")
+	}
+	if entry, _ := ctx.Tracker.GetEntry("C:/VMs/data.vhdx"); entry.ImageCheck == nil {
+		t.Error("image check not recorded")
+	}
+
+	fake.Errors["CheckImage"] = wsl.ErrCheckUnsupported
+	if err := runVerify(ctx, "C:/VMs/data.vhdx", false, false); err != nil {
+		t.Errorf("runVerify() of a format without a check error = %v", err)
+	}
+	if entry, _ := ctx.Tracker.GetEntry("C:/VMs/data.vhdx"); entry.ImageCheck == nil || entry.ImageCheck.Skipped == "" || entry.ImageCheck.Error != "" {
+		t.Errorf("image check = %+v, want it recorded as skipped", entry.ImageCheck)
+	}
+	delete(fake.Errors, "CheckImage")
+
+	disk.FSErrors = "Inode 12 has illegal blocks"
+	if err := runVerify(ctx, "C:/VMs/data.vhdx", true, false); err == nil || !strings.Contains(err.Error(), "ext4 filesystem: errors found") {
+		t.Errorf("runVerify(damaged) error = %v", err)
+	}
+
+	if err := runMount(ctx, "C:/VMs/data.vhdx", "", "", "/mnt/data", "", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := runVerify(ctx, "C:/VMs/data.vhdx", false, false); err == nil {
+		t.Error("runVerify() checked an attached VHD without --force")
+	}
+	if err := runVerify(ctx, "C:/VMs/data.vhdx", true, true); err == nil {
+		t.Error("runVerify(--fsck --force) checked a mounted filesystem")
+	}
+}
+
+func TestRunResizeGrow(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID, disk.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+	if err := runMount(ctx, "C:/VMs/data.vhdx", "", "", "/mnt/data", "", false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runResize(ctx, "C:/VMs/data.vhdx", "2G", false, false, "count", t.TempDir(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if disk.Size != 2<<30 {
+		t.Errorf("size = %d, want %d", disk.Size, 2<<30)
+	}
+	if !slices.ContainsFunc(fake.Calls, func(c string) bool { return strings.HasPrefix(c, "GrowFilesystem ") }) {
+		t.Errorf("calls = %q, want the filesystem grown", fake.Calls)
+	}
+	if fake.Disk("C:/VMs/data_bkp.vhdx") != nil || fake.Disk("C:/VMs/data_new.vhdx") != nil {
+		t.Error("growing copied the VHD")
+	}
+	entry, _ := ctx.Tracker.GetEntry("C:/VMs/data.vhdx")
+	if entry.UUID != disk.UUID || !slices.Equal(disk.MountPoints, []string{"/mnt/data"}) {
+		t.Errorf("entry = %+v, mount points = %q, want the same UUID mounted at /mnt/data", entry, disk.MountPoints)
+	}
+
+	// xfs grows mounted, and the VHD is left detached as it was
+	other := fake.AddVHD("C:/VMs/logs.vhdx", 1<<30)
+	other.UUID, other.FSType = "55555555-5555-4555-8555-555555555555", "xfs"
+	if err := runResize(ctx, "C:/VMs/logs.vhdx", "3G", false, false, "count", t.TempDir(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if other.Size != 3<<30 || other.Device != "" || len(other.MountPoints) > 0 {
+		t.Errorf("disk = %+v, want 3G and detached", other)
+	}
+
+	other.FSType = "vfat"
+	if err := runResize(ctx, "C:/VMs/logs.vhdx", "4G", false, false, "count", t.TempDir(), ""); err == nil {
+		t.Error("runResize() grew a vfat filesystem in place")
+	}
+}
+
+func TestRunResizeVerifyChecksum(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 2<<30)
+	disk.UUID, disk.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+
+	if err := runResize(ctx, "C:/VMs/data.vhdx", "1G", false, false, "size", t.TempDir(), ""); err == nil {
+		t.Error("runResize() accepted --verify size")
+	}
+
+	fake.Differences = []string{"data/corrupt.bin"}
+	err := runResize(ctx, "C:/VMs/data.vhdx", "1G", false, false, "checksum", t.TempDir(), "")
+	if err == nil || !strings.Contains(err.Error(), "data/corrupt.bin") {
+		t.Fatalf("runResize() error = %v, want the differing file", err)
+	}
+	if fake.Disk("C:/VMs/data.vhdx") != disk || disk.Size != 2<<30 || fake.Disk("C:/VMs/data_new.vhdx") != nil || fake.Disk("C:/VMs/data_bkp.vhdx") != nil {
+		t.Error("failed verification changed the VHD files")
+	}
+
+	fake.Differences = nil
+	if err := runResize(ctx, "C:/VMs/data.vhdx", "1G", false, false, "checksum", t.TempDir(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Disk("C:/VMs/data.vhdx"); got == nil || got.Size != 1<<30 || fake.Disk("C:/VMs/data_bkp.vhdx") != disk {
+		t.Errorf("resized = %+v, want 1G with the original as backup", got)
+	}
+}
+
+func TestGetVHDStatusUnformatted(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	fake.AddVHD("C:/VMs/new.vhdx", 1<<30)
+	if err := runAttach(ctx, "C:/VMs/new.vhdx"); err != nil {
+		t.Fatal(err)
+	}
+
+	info := getVHDStatus(ctx, "C:/VMs/new.vhdx")
+	if info.State != types.StateAttachedUnformatted || info.DeviceName != "sdd" {
+		t.Errorf("status = %q on %q, want attached (unformatted) on sdd", info.State, info.DeviceName)
+	}
+
+	// Detached outside vhdm
+	if err := fake.DetachVHD("C:/VMs/new.vhdx"); err != nil {
+		t.Fatal(err)
+	}
+	if info := getVHDStatus(ctx, "C:/VMs/new.vhdx"); info.State != types.StateDetached {
+		t.Errorf("status = %q, want detached", info.State)
+	}
+
+	// Its device name now belongs to another unformatted VHD
+	if err := runAttach(ctx, "C:/VMs/new.vhdx"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.DetachVHD("C:/VMs/new.vhdx"); err != nil {
+		t.Fatal(err)
+	}
+	fake.AddVHD("C:/VMs/other.vhdx", 1<<30).Device = "sdd"
+	if info := getVHDStatus(ctx, "C:/VMs/new.vhdx"); info.State != types.StateDetached || info.DeviceName != "" {
+		t.Errorf("status = %q on %q, want detached without a device", info.State, info.DeviceName)
+	}
+	if entry, _ := ctx.Tracker.GetEntry("C:/VMs/new.vhdx"); entry.DeviceName != "" {
+		t.Errorf("tracked device = %q, want it cleared", entry.DeviceName)
+	}
+}
+
+func TestCopyProgress(t *testing.T) {
+	var out bytes.Buffer
+	p := &copyProgress{out: &out, shown: -1}
+	for _, percent := range []int{0, 4, 12, 18, 55, 100} {
+		p.update(wsl.RsyncProgress{Bytes: int64(percent) << 20, Percent: percent, Rate: "10.00MB/s", ETA: "0:00:05"})
+	}
+	p.done()
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("progress lines = %q, want one per 10%% step", lines)
+	}
+	if want := "[################..............]  55%  55MB  10.00MB/s  ETA 0:00:05"; lines[2] != want {
+		t.Errorf("progress line = %q, want %q", lines[2], want)
+	}
+	if !strings.HasSuffix(lines[3], "in 0:00:05") {
+		t.Errorf("last line = %q, want the time taken", lines[3])
+	}
+
+	out.Reset()
+	p = &copyProgress{out: &out, terminal: true, shown: -1}
+	p.update(wsl.RsyncProgress{Percent: 10, ETA: "0:00:09"})
+	p.update(wsl.RsyncProgress{Percent: 20, ETA: "0:00:08"})
+	p.done()
+	if got := out.String(); strings.Count(got, "\r") != 2 || !strings.HasSuffix(got, "\n") {
+		t.Errorf("terminal output = %q, want the bar redrawn in place", got)
+	}
+
+	if newCopyProgress(&AppContext{Config: &config.Config{Quiet: true}}).callback() != nil {
+		t.Error("progress shown in quiet mode")
+	}
+}
+
+func TestResizeJournal(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 2<<30)
+	disk.UUID, disk.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+
+	// Interrupted once the data was copied
+	fake.Errors["RenameFile"] = errors.New("interrupted")
+	if err := runResize(ctx, "C:/VMs/data.vhdx", "1G", false, false, "count", t.TempDir(), ""); err == nil {
+		t.Fatal("runResize() succeeded")
+	}
+	delete(fake.Errors, "RenameFile")
+	if j, err := loadResizeJournal(ctx, "C:/VMs/data.vhdx"); err != nil || j == nil || j.last() != resizeStepCopied {
+		t.Fatalf("journal = %+v, %v; want the copy recorded", j, err)
+	}
+	if err := runResize(ctx, "C:/VMs/data.vhdx", "1G", false, false, "count", t.TempDir(), ""); err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Errorf("runResize() error = %v, want the interrupted resize reported", err)
+	}
+
+	// Rolled back
+	if err := abortResize(ctx, "C:/VMs/data.vhdx"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Disk("C:/VMs/data.vhdx") != disk || fake.Disk("C:/VMs/data_new.vhdx") != nil || fake.Disk("C:/VMs/data_bkp.vhdx") != nil {
+		t.Error("abort did not restore the original and remove the new VHD")
+	}
+	if j, _ := loadResizeJournal(ctx, "C:/VMs/data.vhdx"); j != nil {
+		t.Errorf("journal = %+v after abort, want none", j)
+	}
+
+	// Interrupted again, then resumed
+	fake.Errors["RenameFile"] = errors.New("interrupted")
+	runResize(ctx, "C:/VMs/data.vhdx", "1G", false, false, "count", t.TempDir(), "")
+	delete(fake.Errors, "RenameFile")
+	if err := resumeResize(ctx, "C:/VMs/data.vhdx", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	resized := fake.Disk("C:/VMs/data.vhdx")
+	if resized == nil || resized.Size != 1<<30 || fake.Disk("C:/VMs/data_bkp.vhdx") != disk {
+		t.Errorf("resized = %+v, want 1G with the original as backup", resized)
+	}
+	if entry, _ := ctx.Tracker.GetEntry("C:/VMs/data.vhdx"); entry.UUID != resized.UUID {
+		t.Errorf("tracked UUID = %q, want %q", entry.UUID, resized.UUID)
+	}
+	if err := resumeResize(ctx, "C:/VMs/data.vhdx", t.TempDir()); err == nil {
+		t.Error("resumeResize() succeeded without a journal")
+	}
+}
+
+func TestResizeJournalRenameWindows(t *testing.T) {
+	// vhdm killed after a rename, before recording it
+	tests := []struct {
+		name     string
+		renames  int
+		recorded string
+		abort    bool
+	}{
+		{"resume after backing up", 1, resizeStepCopied, false},
+		{"abort after backing up", 1, resizeStepCopied, true},
+		{"resume after renaming", 2, resizeStepBackedUp, false},
+		{"abort after renaming", 2, resizeStepBackedUp, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, fake := newTestContext(t)
+			disk := fake.AddVHD("C:/VMs/data.vhdx", 2<<30)
+			disk.UUID, disk.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+			ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+
+			fake.Errors["RenameFile"] = errors.New("interrupted")
+			runResize(ctx, "C:/VMs/data.vhdx", "1G", false, false, "count", t.TempDir(), "")
+			delete(fake.Errors, "RenameFile")
+			j, err := loadResizeJournal(ctx, "C:/VMs/data.vhdx")
+			if err != nil || j == nil {
+				t.Fatalf("journal = %+v, %v", j, err)
+			}
+			fake.RenameFile("/mnt/c/VMs/data.vhdx", "/mnt/c/VMs/data_bkp.vhdx")
+			if tt.renames == 2 {
+				fake.RenameFile("/mnt/c/VMs/data_new.vhdx", "/mnt/c/VMs/data.vhdx")
+			}
+			if tt.recorded != j.last() {
+				j.record(tt.recorded)
+			}
+
+			if tt.abort {
+				if err := abortResize(ctx, "C:/VMs/data.vhdx"); err != nil {
+					t.Fatal(err)
+				}
+				if fake.Disk("C:/VMs/data.vhdx") != disk || fake.Disk("C:/VMs/data_bkp.vhdx") != nil || fake.Disk("C:/VMs/data_new.vhdx") != nil {
+					t.Error("abort did not put the original back in place")
+				}
+				return
+			}
+			if err := resumeResize(ctx, "C:/VMs/data.vhdx", t.TempDir()); err != nil {
+				t.Fatal(err)
+			}
+			resized := fake.Disk("C:/VMs/data.vhdx")
+			if resized == nil || resized.Size != 1<<30 || fake.Disk("C:/VMs/data_bkp.vhdx") != disk {
+				t.Errorf("resized = %+v, want 1G with the original as backup", resized)
+			}
+		})
+	}
+}
+
+func TestShowAllStatus(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	ctx.Config.Output = "json"
+	data := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	data.UUID, data.FSType = "44444444-4444-4444-8444-444444444444", "ext4"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", data.UUID, "", "")
+	if err := runMount(ctx, "C:/VMs/data.vhdx", "", "", "/mnt/data", "", false, false); err != nil {
+		t.Fatal(err)
+	}
+	fake.AddVHD("C:/VMs/logs.vhdx", 1<<30)
+	ctx.Tracker.SaveMapping("C:/VMs/logs.vhdx", "55555555-5555-4555-8555-555555555555", "", "")
+	fake.Files["/mnt/c/VMs/old.vhdx.zst"] = 1 << 20
+	ctx.Tracker.SaveMapping("C:/VMs/old.vhdx", "66666666-6666-4666-8666-666666666666", "", "")
+	ctx.Tracker.SaveMapping("C:/VMs/gone.vhdx", "77777777-7777-4777-8777-777777777777", "", "")
+
+	out := captureStdout(t, func() {
+		if err := showAllStatus(ctx, "path"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	var report StatusReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("output %q: %v", out, err)
+	}
+	states := make(map[string]types.VHDState)
+	for _, vhd := range report.VHDs {
+		states[vhd.Path] = vhd.State
+	}
+	want := map[string]types.VHDState{
+		"C:/VMs/data.vhdx": types.StateMounted,
+		"C:/VMs/logs.vhdx": types.StateDetached,
+		"C:/VMs/old.vhdx":  types.StateArchived,
+	}
+	if !maps.Equal(states, want) {
+		t.Errorf("states = %v, want %v", states, want)
+	}
+	if report.VHDs[0].DeviceName != "sdd" || report.VHDs[0].MountPoint != "/mnt/data" {
+		t.Errorf("data = %+v, want mounted at /mnt/data from sdd", report.VHDs[0])
+	}
+}
+
+func TestRunListCSV(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	ctx.Config.Output = "csv"
+	fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", "44444444-4444-4444-8444-444444444444", "", "")
+
+	out := captureStdout(t, func() {
+		if err := runList(ctx); err != nil {
+			t.Fatalf("runList() error = %v", err)
+		}
+	})
+	want := "path,name,uuid,mountPoint,state\n" +
+		"C:/VMs/data.vhdx,data,44444444-4444-4444-8444-444444444444,,detached\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestReportErrorJSON(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	ctx.Config.Output = "json"
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.WithValue(context.Background(), appContextKey{}, ctx))
+
+	err := runNoteSet(ctx, "C:/VMs/missing.vhdx", "backup")
+	var code int
+	out := captureStdout(t, func() { code = ReportError(cmd, err) })
+
+	var doc errorDocument
+	if jsonErr := json.Unmarshal([]byte(out), &doc); jsonErr != nil {
+		t.Fatalf("output %q is not JSON: %v", out, jsonErr)
+	}
+	if code != 1 || doc.Error.Code != types.CodeNotTracked || doc.Error.Op != "note set" {
+		t.Errorf("ReportError() = %d, %+v; want 1, VHDM_NOT_TRACKED from note set", code, doc.Error)
+	}
+}
+
+func TestPrintResultWarnings(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	ctx.Config.Output = "json"
+	ctx.Logger.Collect("Failed to save tracking info: %v", os.ErrPermission)
+	ctx.Logger.Collect("Failed to save tracking info: %v", os.ErrPermission)
+
+	out := captureStdout(t, func() {
+		if err := printResult(ctx, InfoResult{Path: "C:/VMs/data.vhdx"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	var doc struct {
+		Path     string   `json:"path"`
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("output %q is not JSON: %v", out, err)
+	}
+	want := []string{"Failed to save tracking info: permission denied"}
+	if doc.Path != "C:/VMs/data.vhdx" || !slices.Equal(doc.Warnings, want) {
+		t.Errorf("result = %+v, want warnings %q", doc, want)
+	}
+
+	// Reported once
+	out = captureStdout(t, func() { printResult(ctx, InfoResult{Path: "C:/VMs/data.vhdx"}) })
+	if strings.Contains(out, "warnings") {
+		t.Errorf("warnings reported again: %s", out)
+	}
+}
+
+func TestPrintTemplate(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	if err := setFormat(ctx, "{{.Name}} {{.UUID}} {{json .State}}"); err != nil {
+		t.Fatal(err)
+	}
+	rows := []ListEntry{
+		{Path: "C:/VMs/data.vhdx", Name: "data", UUID: "44444444-4444-4444-8444-444444444444", State: "mounted"},
+		{Path: "C:/VMs/logs.vhdx", Name: "logs", State: "detached"},
+	}
+	out := captureStdout(t, func() {
+		if err := printTemplate(ctx, rows); err != nil {
+			t.Fatal(err)
+		}
+	})
+	want := "data 44444444-4444-4444-8444-444444444444 \"mounted\"\nlogs  \"detached\"\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	if err := setFormat(ctx, "{{.UUID"); err == nil {
+		t.Error("setFormat() accepted an unterminated action")
+	}
+	ctx.Config.SetFormat("{{.Missing}}")
+	if err := printTemplate(ctx, MountResult{UUID: "x"}); err == nil {
+		t.Error("printTemplate() accepted an unknown field")
+	}
+}
+
+func TestShellOutputOfLists(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	ctx.Config.SetOutput("sh")
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.UUID = "44444444-4444-4444-8444-444444444444"
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "", "")
+
+	lists := map[string]func() error{
+		"list":         func() error { return runList(ctx) },
+		"status --all": func() error { return runStatus(ctx, "", "", "", true, "path") },
+		"service list": func() error { return runServiceList(ctx) },
+		"devices":      func() error { return runDevices(ctx) },
+	}
+	for name, run := range lists {
+		out := captureStdout(t, func() {
+			if err := run(); !errors.Is(err, types.ErrInvalidInput) {
+				t.Errorf("%s with --output sh: error = %v, want ErrInvalidInput", name, err)
+			}
+		})
+		if out != "" {
+			t.Errorf("%s with --output sh printed %q", name, out)
+		}
+	}
+
+	// A single VHD is one result
+	out := captureStdout(t, func() {
+		if err := runStatus(ctx, "C:/VMs/data.vhdx", "", "", false, "path"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "VHDM_UUID="+disk.UUID+"\n") {
+		t.Errorf("status of one VHD with --output sh = %q", out)
+	}
+}
+
+func TestThemePerContext(t *testing.T) {
+	colored, _ := newTestContext(t)
+	plain, _ := newTestContext(t)
+	var err error
+	if plain.Theme, err = utils.ParseTheme("mono"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := colorizeStatus(plain.Theme, "mounted"); got != "mounted" {
+		t.Errorf("status with the mono theme = %q, want plain text", got)
+	}
+	if got := colorizeStatus(colored.Theme, "mounted"); got == "mounted" {
+		t.Error("status with the default theme is not colored after another context chose mono")
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{"table", config.Config{Output: "table", TimeFormat: "unix"}, "unix"},
+		{"quiet", config.Config{Output: "table", TimeFormat: "unix", Quiet: true}, "rfc3339"},
+		{"json", config.Config{Output: "json", TimeFormat: "unix"}, "rfc3339"},
+		{"sh", config.Config{Output: "sh", TimeFormat: "local"}, "rfc3339"},
+		{"template", config.Config{Output: "table", Format: "{{.LastSeen}}", TimeFormat: "unix"}, "rfc3339"},
+	}
+	for _, tt := range tests {
+		if got := timeFormat(&tt.cfg); got != tt.want {
+			t.Errorf("%s: timeFormat() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
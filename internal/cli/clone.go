@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newCloneCmd() *cobra.Command {
+	var (
+		vhdPath string
+		to      string
+		linked  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "clone",
+		Short: "Copy a VHD, or create a linked clone of it",
+		Long: `Create a new VHD with the contents of another one.
+
+By default the VHD file is copied; a mounted VHD is frozen during the copy,
+as with 'vhdm backup'. With --linked, the clone is a differencing VHDX that
+starts empty and reads unchanged blocks from the original, so cloning a large
+data disk is instant and the clone only grows by what is written to it. This
+makes cheap throwaway copies for testing. The original must be detached, and
+becomes the read-only base of the clone: vhdm refuses to attach, mount,
+resize or delete it while linked clones of it are tracked. Flatten ('vhdm
+flatten') or delete the clones to release it.
+
+The filesystem of the clone gets a new UUID (ext2/3/4, xfs and btrfs), so
+both can be attached at once. The clone is tracked and left detached.
+
+Linked clones are created with diskpart, which asks for administrator rights
+through a UAC prompt.`,
+		Example: `  vhdm clone --vhd-path C:/VMs/data.vhdx --to C:/VMs/data-copy.vhdx
+  vhdm clone --vhd-path C:/VMs/data.vhdx --to C:/VMs/data-test.vhdx --linked`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runClone(appContext(cmd), vhdPath, to, linked)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&to, "to", "", "Path of the new VHD (Windows format)")
+	cmd.Flags().BoolVar(&linked, "linked", false, "Create a differencing VHD backed by the original instead of a copy")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func runClone(ctx *AppContext, vhdPath, to string, linked bool) error {
+	log := ctx.Logger
+	to = utils.ResolveVHDPath(to, ctx.Config.BaseDirs)
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "clone", Path: vhdPath, Err: err}
+	}
+	if err := validation.ValidateWindowsPath(to); err != nil {
+		return &types.VHDError{Op: "clone", Path: to, Err: err}
+	}
+	if linked && !strings.EqualFold(filepath.Ext(to), filepath.Ext(vhdPath)) {
+		return &types.VHDError{
+			Op:   "clone",
+			Path: to,
+			Err:  fmt.Errorf("%w: a linked clone must have the extension of its base (%s)", types.ErrInvalidInput, filepath.Ext(vhdPath)),
+		}
+	}
+
+	log.Debug("Clone operation starting")
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	cloneWSLPath := ctx.WSL.ConvertPath(to)
+	if !ctx.WSL.FileExists(wslPath) {
+		return &types.VHDError{Op: "clone", Path: vhdPath, Err: types.ErrVHDNotFound}
+	}
+	if ctx.WSL.FileExists(cloneWSLPath) {
+		return &types.VHDError{Op: "clone", Path: to, Err: fmt.Errorf("file already exists")}
+	}
+	if _, err := ctx.Tracker.GetEntry(to); err == nil {
+		return &types.VHDError{
+			Op:   "clone",
+			Path: to,
+			Err:  fmt.Errorf("path is still tracked"),
+			Help: fmt.Sprintf("Remove the stale entry with 'vhdm delete --vhd-path %s' first", to),
+		}
+	}
+
+	lock, err := lockVHDOperation(ctx, "clone", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// Create the clone file
+	if linked {
+		if uuid, _ := ctx.Tracker.LookupUUIDByPath(vhdPath); uuid != "" {
+			if attached, _ := ctx.WSL.IsAttached(uuid); attached {
+				return &types.VHDError{
+					Op:   "clone",
+					Path: vhdPath,
+					Err:  fmt.Errorf("the base of a linked clone must be detached"),
+					Help: fmt.Sprintf("Detach it first with 'vhdm detach --vhd-path %s'", vhdPath),
+				}
+			}
+		}
+		log.Info("Creating linked clone %s...", to)
+		if err := ctx.WSL.CreateDifferencingVHD(vhdPath, to); err != nil {
+			return &types.VHDError{Op: "clone", Path: vhdPath, Err: err}
+		}
+	} else if _, err := copyVHDFile(ctx, vhdPath, cloneWSLPath, false); err != nil {
+		return &types.VHDError{Op: "clone", Path: vhdPath, Err: err}
+	}
+
+	res, err := finishClone(ctx, vhdPath, to, linked)
+	if err != nil {
+		if derr := ctx.WSL.DeleteVHD(cloneWSLPath); derr != nil {
+			log.Warn("Failed to remove %s: %v", cloneWSLPath, derr)
+		}
+		return &types.VHDError{Op: "clone", Path: to, Err: err}
+	}
+
+	// Output
+	log.Success("VHD cloned successfully")
+	return printResult(ctx, res)
+}
+
+// finishClone attaches a new clone, gives its filesystem a UUID of its own
+// where the filesystem type allows it, tracks it and writes its ID file, leaving it detached
+func finishClone(ctx *AppContext, vhdPath, to string, linked bool) (CloneResult, error) {
+	log := ctx.Logger
+	res := CloneResult{Path: vhdPath, Clone: to, Linked: linked}
+
+	devName, attached, err := ctx.WSL.AttachVHDAndDetect(to)
+	if err != nil {
+		if attached {
+			ctx.WSL.DetachVHD(to)
+		}
+		return res, fmt.Errorf("failed to attach clone: %w", err)
+	}
+	detach := func() {
+		if err := ctx.WSL.DetachVHD(to); err != nil {
+			log.Warn("Failed to detach %s: %v", to, err)
+		}
+	}
+
+	// Only ext2/3/4, xfs and btrfs UUIDs can be changed; other filesystems
+	// keep the UUID of the original
+	fsType, _ := ctx.WSL.GetFilesystemType(devName)
+	switch {
+	case slices.Contains(validation.FilesystemTypes, fsType):
+		log.Info("Giving the clone a new filesystem UUID...")
+		res.UUID, err = ctx.WSL.RegenerateUUID(devName, fsType)
+		if err != nil {
+			detach()
+			return res, err
+		}
+	case fsType != "":
+		res.UUID, _ = ctx.WSL.GetUUIDByDevice(devName)
+		log.Warn("Cannot change the UUID of a %s filesystem: the clone keeps UUID %s of the original, so attach only one of them at a time", fsType, valueOrNone(res.UUID))
+	}
+
+	if err := ctx.Tracker.SaveMapping(to, res.UUID, "", devName); err != nil {
+		log.Collect("Failed to save tracking info: %v", err)
+	}
+	if linked {
+		err := ctx.Tracker.Update(to, func(entry *types.TrackingEntry) {
+			entry.BackingFile = vhdPath
+		})
+		if err != nil {
+			detach()
+			ctx.Tracker.RemoveMapping(to)
+			return res, fmt.Errorf("failed to record the base of the clone: %w", err)
+		}
+	}
+	if res.UUID != "" {
+		createIDFile(ctx, to)
+	}
+
+	detach()
+	markDetached(ctx, to)
+	return res, nil
+}
+
+// linkedClones returns the tracked linked clones whose base is vhdPath
+func linkedClones(ctx *AppContext, vhdPath string) []string {
+	paths, err := ctx.Tracker.GetAllPaths()
+	if err != nil {
+		return nil
+	}
+	var clones []string
+	for _, path := range paths {
+		entry, err := ctx.Tracker.GetEntry(path)
+		if err == nil && entry.BackingFile != "" && samePath(ctx, entry.BackingFile, vhdPath) {
+			clones = append(clones, path)
+		}
+	}
+	return clones
+}
+
+// CloneResult is the outcome of 'vhdm clone'
+type CloneResult struct {
+	Path   string `json:"path"`
+	Clone  string `json:"clone"`
+	UUID   string `json:"uuid,omitempty"` // New filesystem UUID of the clone
+	Linked bool   `json:"linked"`
+}
+
+func (r CloneResult) table(utils.Theme) (string, [][2]string) {
+	kind := "full copy"
+	if r.Linked {
+		kind = "linked (differencing VHD)"
+	}
+	pairs := [][2]string{
+		{"Path", r.Path},
+		{"Clone", r.Clone},
+		{"Type", kind},
+	}
+	if r.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", r.UUID})
+	}
+	return "Clone Result", append(pairs, [2]string{"Status", "cloned (detached)"})
+}
+
+func (r CloneResult) quiet() quietLine {
+	return quietLine{Key: r.Path, State: "cloned to " + r.Clone}
+}
@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newCompactCmd() *cobra.Command {
+	var (
+		vhdPath  string
+		noTrim   bool
+		noBackup bool
+		unpin    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "compact",
+		Short: "Shrink a dynamic VHDX file to the data it holds",
+		Long: `Shrink the file of a dynamic VHDX whose filesystem has freed space.
+
+A dynamic VHDX grows as data is written but never shrinks when files are
+deleted. vhdm first runs fstrim on the filesystem (mounting it temporarily if
+needed) so freed blocks are discarded, then unmounts and detaches the VHD and
+writes a fresh copy with 'qemu-img convert', which leaves out unused blocks.
+The copy has the same filesystem and UUID, and replaces the original file.
+
+As with resize, the original is kept as *_bkp.vhdx unless --no-backup is
+given, and a VHD that was mounted is mounted again at the same place. Linked
+clones cannot be compacted; flatten them first. Pinned VHDs (see 'vhdm pin')
+are only compacted with --unpin.`,
+		Example: `  vhdm compact --vhd-path C:/VMs/disk.vhdx
+  vhdm compact --vhd-path C:/VMs/disk.vhdx --no-backup`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCompact(appContext(cmd), vhdPath, noTrim, noBackup, unpin)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().BoolVar(&noTrim, "no-trim", false, "Skip fstrim before compacting")
+	cmd.Flags().BoolVar(&noBackup, "no-backup", false, "Delete the original file once the compacted copy replaced it")
+	cmd.Flags().BoolVar(&unpin, "unpin", false, "Remove the pin of a pinned VHD and compact it")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runCompact(ctx *AppContext, vhdPath string, noTrim, noBackup, unpin bool) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "compact", Path: vhdPath, Err: err}
+	}
+	if !strings.EqualFold(filepath.Ext(vhdPath), ".vhdx") {
+		return &types.VHDError{
+			Op:   "compact",
+			Path: vhdPath,
+			Err:  fmt.Errorf("%w: only .vhdx files can be compacted", types.ErrInvalidInput),
+		}
+	}
+	if err := ensureNotReference(ctx, "compact", vhdPath); err != nil {
+		return err
+	}
+	if err := checkPinned(ctx, "compact", vhdPath, unpin); err != nil {
+		return err
+	}
+	if entry, err := ctx.Tracker.GetEntry(vhdPath); err == nil && entry.BackingFile != "" {
+		return &types.VHDError{
+			Op:   "compact",
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD is a linked clone of %s", entry.BackingFile),
+			Help: fmt.Sprintf("Flatten it first with 'vhdm flatten --vhd-path %s'", vhdPath),
+		}
+	}
+
+	lock, err := lockVHDOperation(ctx, "compact", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	log.Debug("Compact operation starting")
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	if !ctx.WSL.FileExists(wslPath) {
+		return &types.VHDError{Op: "compact", Path: vhdPath, Err: types.ErrVHDNotFound}
+	}
+	newVHDPath := generateNewVHDPath(vhdPath, "")
+	backupVHDPath := generateBackupPath(vhdPath)
+	newWSLPath := ctx.WSL.ConvertPath(newVHDPath)
+	backupWSLPath := ctx.WSL.ConvertPath(backupVHDPath)
+	if ctx.WSL.FileExists(newWSLPath) {
+		return fmt.Errorf("temporary file already exists: %s - please remove or rename it first", newVHDPath)
+	}
+	if ctx.WSL.FileExists(backupWSLPath) {
+		return fmt.Errorf("backup file already exists: %s - please remove or rename it first", backupVHDPath)
+	}
+	releasePin(ctx, vhdPath)
+
+	before, err := ctx.WSL.FileSize(wslPath)
+	if err != nil {
+		return &types.VHDError{Op: "compact", Path: vhdPath, Err: err}
+	}
+
+	// Discard freed blocks so qemu-img sees them as unused
+	if !noTrim {
+		if m, err := acquireTempMount(ctx, vhdPath, false); err != nil {
+			log.Warn("Could not mount the VHD for fstrim, less space may be reclaimed: %v", err)
+		} else {
+			log.Info("Trimming free space in %s...", m.MountPoint)
+			if err := ctx.WSL.Trim(m.MountPoint); err != nil {
+				log.Warn("fstrim failed, less space may be reclaimed: %v", err)
+			}
+			m.release(ctx)
+		}
+	}
+
+	// Unmount and detach, remembering the mount point to restore afterwards
+	var originalMountPoint string
+	uuid, _ := ctx.Tracker.LookupUUIDByPath(vhdPath)
+	if uuid != "" {
+		if attached, _ := ctx.WSL.IsAttached(uuid); attached {
+			originalMountPoint, _ = ctx.WSL.GetMountPoint(uuid)
+			if originalMountPoint != "" {
+				log.Info("VHD is mounted, unmounting first...")
+				if err := ctx.WSL.Unmount(originalMountPoint); err != nil {
+					return fmt.Errorf("failed to unmount VHD: %w", err)
+				}
+				log.Success("Unmounted from %s", originalMountPoint)
+			}
+			log.Info("VHD is attached, detaching first...")
+			if err := ctx.WSL.DetachVHD(vhdPath); err != nil && !types.IsNotAttached(err) {
+				return fmt.Errorf("failed to detach VHD: %w", err)
+			}
+			markDetached(ctx, vhdPath)
+		}
+	}
+
+	// remount attaches the VHD file at vhdPath again and mounts it back where
+	// it was, if it was mounted
+	remount := func() string {
+		if originalMountPoint == "" {
+			return ""
+		}
+		log.Info("Re-mounting to %s...", originalMountPoint)
+		devName, attached, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
+		if err != nil {
+			if attached {
+				log.Warn("Failed to detect device after re-attach: %v", err)
+			} else {
+				log.Warn("Failed to re-attach VHD: %v", err)
+			}
+			return ""
+		}
+		if err := ctx.WSL.MountByUUID(uuid, originalMountPoint); err != nil {
+			log.Warn("Failed to re-mount VHD: %v", err)
+			return devName
+		}
+		if err := ctx.Tracker.SaveMapping(vhdPath, uuid, originalMountPoint, devName); err != nil {
+			log.Collect("Failed to update tracking: %v", err)
+		}
+		log.Success("VHD re-mounted to %s", originalMountPoint)
+		return devName
+	}
+
+	// The original is renamed to the backup at the end; fail now rather than
+	// after the conversion when Windows holds it open
+	if err := ensureNotInUseByWindows(ctx, "compact", vhdPath); err != nil {
+		remount()
+		return err
+	}
+
+	log.Info("Writing compacted copy %s (this may take a while)...", newVHDPath)
+	if err := ctx.WSL.ConvertImage(wslPath, newWSLPath, "vhdx"); err != nil {
+		remount()
+		return &types.VHDError{Op: "compact", Path: vhdPath, Err: err}
+	}
+
+	// Swap the compacted copy in
+	if err := ctx.WSL.RenameFile(wslPath, backupWSLPath); err != nil {
+		ctx.WSL.DeleteVHD(newWSLPath)
+		remount()
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	if err := ctx.WSL.RenameFile(newWSLPath, wslPath); err != nil {
+		// Try to restore original
+		ctx.WSL.RenameFile(backupWSLPath, wslPath)
+		remount()
+		return fmt.Errorf("failed to rename compacted VHD: %w", err)
+	}
+	after, _ := ctx.WSL.FileSize(wslPath)
+
+	devName := remount()
+
+	res := CompactResult{
+		Path:       vhdPath,
+		Before:     before,
+		After:      after,
+		Reclaimed:  max(before-after, 0),
+		Backup:     backupVHDPath,
+		MountPoint: originalMountPoint,
+		DeviceName: devName,
+	}
+	if noBackup {
+		if err := ctx.WSL.DeleteVHD(backupWSLPath); err != nil {
+			log.Warn("Failed to delete the original file %s: %v", backupVHDPath, err)
+		} else {
+			res.Backup = ""
+		}
+	}
+
+	// Output
+	log.Success("VHD compacted successfully")
+	if err := printResult(ctx, res); err != nil {
+		return err
+	}
+	if res.Backup != "" {
+		log.Info("")
+		log.Info("Original VHD preserved as: %s", res.Backup)
+		log.Info("The space is only freed once the backup is deleted")
+	}
+	return nil
+}
+
+// CompactResult is the outcome of 'vhdm compact'. Sizes are the VHD file
+// sizes in bytes.
+type CompactResult struct {
+	Path       string `json:"path"`
+	Before     int64  `json:"before"`
+	After      int64  `json:"after"`
+	Reclaimed  int64  `json:"reclaimed"`
+	Backup     string `json:"backup,omitempty"`     // Original file, unless deleted with --no-backup
+	MountPoint string `json:"mountPoint,omitempty"` // Where the VHD was re-mounted
+	DeviceName string `json:"deviceName,omitempty"`
+}
+
+func (r CompactResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{
+		{"Path", r.Path},
+		{"Before", utils.BytesToHuman(r.Before)},
+		{"After", utils.BytesToHuman(r.After)},
+		{"Reclaimed", utils.BytesToHuman(r.Reclaimed)},
+	}
+	if r.Backup != "" {
+		pairs = append(pairs, [2]string{"Backup", r.Backup})
+	}
+	if r.MountPoint != "" {
+		pairs = append(pairs, [2]string{"Mount Point", r.MountPoint})
+	}
+	return "Compact Result", append(pairs, [2]string{"Status", "compacted"})
+}
+
+func (r CompactResult) quiet() quietLine {
+	return quietLine{Key: r.Path, State: "reclaimed " + utils.BytesToHuman(r.Reclaimed)}
+}
@@ -4,6 +4,9 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/config"
+	"github.com/rjdinis/vhdm/internal/validation"
 )
 
 func newCompletionCmd() *cobra.Command {
@@ -77,3 +80,19 @@ PowerShell:
 	}
 	return cmd
 }
+
+// completeSizes completes --size flags with common VHD sizes
+func completeSizes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return validation.CommonSizes, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFilesystemTypes completes filesystem type flags with the types
+// that validation accepts
+func completeFilesystemTypes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return validation.FilesystemTypes, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfiles completes --profile with the profiles used before
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return config.ProfileNames(), cobra.ShellCompDirectiveNoFileComp
+}
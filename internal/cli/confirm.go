@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// confirmByName makes the user type name before a destructive operation on a
+// disk of sizeBytes or more than VHDM_CONFIRM_NAME_ABOVE. It applies on top of
+// --yes, and only when stdin is a terminal; scripts rely on --yes alone. An
+// invalid threshold fails rather than silently disabling the confirmation.
+func confirmByName(ctx *AppContext, op, name string, sizeBytes int64) error {
+	threshold, err := utils.ConvertSizeToBytes(ctx.Config.ConfirmNameAbove)
+	if err != nil {
+		return &types.VHDError{
+			Op:   op,
+			Err:  fmt.Errorf("%w: invalid VHDM_CONFIRM_NAME_ABOVE %q", types.ErrInvalidInput, ctx.Config.ConfirmNameAbove),
+			Help: "Set it to a size such as 100G, or 0 to disable the confirmation",
+		}
+	}
+	if threshold <= 0 || sizeBytes < threshold || !stdinIsTerminal() {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "This disk is %s. Type %s to confirm: ",
+		utils.BytesToHuman(sizeBytes), ctx.Theme.Yellow(name))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != name {
+		return &types.VHDError{
+			Op:   op,
+			Err:  fmt.Errorf("confirmation did not match %q", name),
+			Help: "Raise or disable the threshold with VHDM_CONFIRM_NAME_ABOVE (0 disables)",
+		}
+	}
+	return nil
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// vhdDiskSize returns the virtual size of a VHD file, or its size on disk when
+// qemu-img cannot read it
+func vhdDiskSize(ctx *AppContext, wslPath string) int64 {
+	if info, err := ctx.WSL.GetImageInfo(wslPath); err == nil {
+		return info.VirtualSize
+	}
+	if fi, err := os.Stat(wslPath); err == nil {
+		return fi.Size()
+	}
+	return 0
+}
@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// imageFormat is a disk image format 'vhdm convert' writes
+type imageFormat struct {
+	Name       string // --to value
+	QemuFormat string // qemu-img output format
+	Ext        string
+	Attachable bool // WSL can attach it
+}
+
+var imageFormats = []imageFormat{
+	{"vhdx", "vhdx", ".vhdx", true},
+	{"vhd", "vpc", ".vhd", true},
+	{"raw", "raw", ".img", false},
+	{"qcow2", "qcow2", ".qcow2", false},
+}
+
+func imageFormatNames() []string {
+	names := make([]string, 0, len(imageFormats))
+	for _, f := range imageFormats {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func newConvertCmd() *cobra.Command {
+	var (
+		vhdPath      string
+		to           string
+		keepOriginal bool
+		unpin        bool
+	)
+	cmd := &cobra.Command{
+		Use:   "convert",
+		Short: "Convert a VHD to another disk image format",
+		Long: `Convert a disk image to vhdx, vhd, raw or qcow2 with 'qemu-img convert', to
+move disks between WSL, Hyper-V and QEMU.
+
+The converted file is written next to the original, with the extension of
+the new format (.vhdx, .vhd, .img or .qcow2). Its filesystem and UUID are
+unchanged. The tracking entry moves to the new file when it is a vhdx or vhd,
+which WSL can attach; raw and qcow2 images are not tracked.
+
+The original file is deleted, or kept as *_bkp with --keep-original. The VHD
+must be detached, and linked clones must be flattened first. Pinned VHDs (see
+'vhdm pin') are only converted with --unpin; the pin moves to the converted
+file when it stays tracked.`,
+		Example: `  vhdm convert --vhd-path C:/VMs/disk.vhdx --to qcow2
+  vhdm convert --vhd-path C:/VMs/disk.vhd --to vhdx --keep-original`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConvert(appContext(cmd), vhdPath, to, keepOriginal, unpin)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "Disk image file path (Windows format)")
+	cmd.Flags().StringVar(&to, "to", "", "Target format: "+strings.Join(imageFormatNames(), ", "))
+	cmd.Flags().BoolVar(&keepOriginal, "keep-original", false, "Keep the original file as *_bkp")
+	cmd.Flags().BoolVar(&unpin, "unpin", false, "Convert a pinned VHD, whose original file is replaced")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("to")
+	cmd.RegisterFlagCompletionFunc("to", cobra.FixedCompletions(imageFormatNames(), cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func runConvert(ctx *AppContext, vhdPath, to string, keepOriginal, unpin bool) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "convert", Path: vhdPath, Err: err}
+	}
+	idx := slices.IndexFunc(imageFormats, func(f imageFormat) bool { return f.Name == to })
+	if idx < 0 {
+		return &types.VHDError{
+			Op:  "convert",
+			Err: fmt.Errorf("%w: unknown format %q (use %s)", types.ErrInvalidInput, to, strings.Join(imageFormatNames(), ", ")),
+		}
+	}
+	format := imageFormats[idx]
+	ext := filepath.Ext(vhdPath)
+	if strings.EqualFold(ext, format.Ext) {
+		return &types.VHDError{Op: "convert", Path: vhdPath, Err: fmt.Errorf("%w: file is already a %s image", types.ErrInvalidInput, format.Name)}
+	}
+	if err := ensureNotReference(ctx, "convert", vhdPath); err != nil {
+		return err
+	}
+	if err := checkPinned(ctx, "convert", vhdPath, unpin); err != nil {
+		return err
+	}
+
+	entry, trackErr := ctx.Tracker.GetEntry(vhdPath)
+	if trackErr == nil && entry.BackingFile != "" {
+		return &types.VHDError{
+			Op:   "convert",
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD is a linked clone of %s", entry.BackingFile),
+			Help: fmt.Sprintf("Flatten it first with 'vhdm flatten --vhd-path %s'", vhdPath),
+		}
+	}
+	if len(entry.Snapshots) > 0 {
+		return &types.VHDError{
+			Op:   "convert",
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD has snapshots, which stay in the current format"),
+			Help: fmt.Sprintf("Delete them first with 'vhdm snapshot delete --vhd-path %s --name <name>'", vhdPath),
+		}
+	}
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	if !ctx.WSL.FileExists(wslPath) {
+		return &types.VHDError{Op: "convert", Path: vhdPath, Err: types.ErrVHDNotFound}
+	}
+	if entry.UUID != "" {
+		if attached, _ := ctx.WSL.IsAttached(entry.UUID); attached {
+			return &types.VHDError{
+				Op:   "convert",
+				Path: vhdPath,
+				Err:  fmt.Errorf("VHD is attached"),
+				Help: fmt.Sprintf("Detach it first with 'vhdm detach --vhd-path %s'", vhdPath),
+			}
+		}
+	}
+
+	outPath := strings.TrimSuffix(vhdPath, ext) + format.Ext
+	outWSLPath := ctx.WSL.ConvertPath(outPath)
+	if ctx.WSL.FileExists(outWSLPath) {
+		return &types.VHDError{Op: "convert", Path: outPath, Err: fmt.Errorf("file already exists")}
+	}
+	if _, err := ctx.Tracker.GetEntry(outPath); err == nil {
+		return &types.VHDError{
+			Op:   "convert",
+			Path: outPath,
+			Err:  fmt.Errorf("path is still tracked"),
+			Help: fmt.Sprintf("Remove the stale entry with 'vhdm delete --vhd-path %s' first", outPath),
+		}
+	}
+	backupPath := generateBackupPath(vhdPath)
+	backupWSLPath := ctx.WSL.ConvertPath(backupPath)
+	if keepOriginal && ctx.WSL.FileExists(backupWSLPath) {
+		return fmt.Errorf("backup file already exists: %s - please remove or rename it first", backupPath)
+	}
+
+	lock, err := lockVHDOperation(ctx, "convert", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := ensureNotInUseByWindows(ctx, "convert", vhdPath); err != nil {
+		return err
+	}
+
+	log.Debug("Convert operation starting")
+
+	log.Info("Converting %s to %s (this may take a while)...", vhdPath, outPath)
+	if err := ctx.WSL.ConvertImage(wslPath, outWSLPath, format.QemuFormat); err != nil {
+		return &types.VHDError{Op: "convert", Path: vhdPath, Err: err}
+	}
+
+	// Dispose of the original
+	res := ConvertResult{Path: vhdPath, Output: outPath, Format: format.Name}
+	if keepOriginal {
+		if err := ctx.WSL.RenameFile(wslPath, backupWSLPath); err != nil {
+			log.Warn("Failed to rename the original to %s, it is kept as is: %v", backupPath, err)
+			res.Backup = vhdPath
+		} else {
+			res.Backup = backupPath
+		}
+	} else if err := ctx.WSL.DeleteVHD(wslPath); err != nil {
+		log.Warn("Failed to delete the original %s: %v", vhdPath, err)
+		res.Backup = vhdPath
+	}
+
+	// Move the tracking entry, and with it the pin, to the new file
+	if trackErr == nil {
+		if format.Attachable {
+			if err := ctx.Tracker.Rename(vhdPath, outPath); err != nil {
+				log.Collect("Failed to update tracking: %v", err)
+			} else if entry.UUID != "" {
+				// The ID file names the original path
+				createIDFile(ctx, outPath)
+			}
+			res.Tracked = true
+		} else {
+			log.Info("WSL cannot attach %s images; %s is no longer tracked", format.Name, vhdPath)
+			if entry.Pinned {
+				log.Warn("Unpinned %s", vhdPath)
+			}
+			ctx.Tracker.RemoveMapping(vhdPath)
+		}
+	}
+
+	// Output
+	log.Success("VHD converted successfully")
+	return printResult(ctx, res)
+}
+
+// ConvertResult is the outcome of 'vhdm convert'
+type ConvertResult struct {
+	Path    string `json:"path"`
+	Output  string `json:"output"`
+	Format  string `json:"format"`
+	Backup  string `json:"backup,omitempty"` // Where the original was kept, if it was
+	Tracked bool   `json:"tracked"`          // The tracking entry moved to Output
+}
+
+func (r ConvertResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{
+		{"Path", r.Path},
+		{"Output", r.Output},
+		{"Format", r.Format},
+	}
+	if r.Backup != "" {
+		pairs = append(pairs, [2]string{"Original", r.Backup})
+	}
+	status := "converted"
+	if r.Tracked {
+		status = "converted (tracking moved)"
+	}
+	return "Convert Result", append(pairs, [2]string{"Status", status})
+}
+
+func (r ConvertResult) quiet() quietLine {
+	return quietLine{Key: r.Path, State: "converted to " + r.Output}
+}
@@ -27,8 +27,9 @@ With --format, creates, attaches, and formats the VHD.`,
 		Example: `  vhdm create --vhd-path C:/VMs/disk.vhdx --size 5G
   vhdm create --vhd-path C:/VMs/disk.vhdx --size 5G --format ext4`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCreate(vhdPath, size, fsType, force)
+			return runCreate(appContext(cmd), vhdPath, size, fsType, force)
 		},
+		Annotations: map[string]string{annotationSudo: "true"},
 	}
 	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
 	cmd.Flags().StringVar(&size, "size", "", "VHD size (e.g., 5G, 500M)")
@@ -36,11 +37,12 @@ With --format, creates, attaches, and formats the VHD.`,
 	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing file")
 	cmd.MarkFlagRequired("vhd-path")
 	cmd.MarkFlagRequired("size")
+	cmd.RegisterFlagCompletionFunc("size", completeSizes)
+	cmd.RegisterFlagCompletionFunc("format", completeFilesystemTypes)
 	return cmd
 }
 
-func runCreate(vhdPath, size, fsType string, force bool) error {
-	ctx := getContext()
+func runCreate(ctx *AppContext, vhdPath, size, fsType string, force bool) error {
 	log := ctx.Logger
 
 	// Validate
@@ -73,19 +75,11 @@ func runCreate(vhdPath, size, fsType string, force bool) error {
 
 	// If no format requested, we're done
 	if fsType == "" {
-		if ctx.Config.Quiet {
-			fmt.Printf("%s: created\n", vhdPath)
-			return nil
+		if err := printResult(ctx, CreateResult{Path: vhdPath, Size: size}); err != nil {
+			return err
 		}
-		
-		pairs := [][2]string{
-			{"Path", vhdPath},
-			{"Size", size},
-			{"Status", "created (unformatted)"},
-		}
-		utils.KeyValueTable("Create Result", pairs, 14, 50)
-		
-		fmt.Println()
+
+		log.Info("")
 		log.Info("To attach and format this VHD, run:")
 		log.Info("  vhdm attach --vhd-path %s", vhdPath)
 		log.Info("  vhdm format --dev-name <device> --type ext4")
@@ -94,18 +88,11 @@ func runCreate(vhdPath, size, fsType string, force bool) error {
 
 	// Attach VHD
 	log.Info("Attaching VHD...")
-	oldDevices, err := ctx.WSL.GetBlockDevices()
-	if err != nil {
-		return fmt.Errorf("failed to get block devices: %w", err)
-	}
-	
-	if _, err := ctx.WSL.AttachVHD(vhdPath); err != nil {
-		return fmt.Errorf("failed to attach: %w", err)
-	}
-
-	// Detect new device
-	devName, err := ctx.WSL.DetectNewDevice(oldDevices)
+	devName, attached, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
 	if err != nil {
+		if !attached {
+			return fmt.Errorf("failed to attach: %w", err)
+		}
 		return fmt.Errorf("failed to detect device: %w", err)
 	}
 	log.Success("VHD attached as /dev/%s", devName)
@@ -120,26 +107,52 @@ func runCreate(vhdPath, size, fsType string, force bool) error {
 
 	// Save tracking
 	ctx.Tracker.SaveMapping(vhdPath, uuid, "", devName)
+	createIDFile(ctx, vhdPath)
 
 	// Output
-	if ctx.Config.Quiet {
-		fmt.Printf("%s (%s): created,formatted\n", vhdPath, uuid)
-		return nil
+	res := CreateResult{Path: vhdPath, Size: size, UUID: uuid, DeviceName: devName, Filesystem: fsType}
+	if err := printResult(ctx, res); err != nil {
+		return err
 	}
 
-	pairs := [][2]string{
-		{"Path", vhdPath},
-		{"Size", size},
-		{"UUID", uuid},
-		{"Device", "/dev/" + devName},
-		{"Filesystem", fsType},
-		{"Status", "created and formatted"},
-	}
-	utils.KeyValueTable("Create Result", pairs, 14, 50)
-	
-	fmt.Println()
+	log.Info("")
 	log.Info("To mount this VHD, run:")
 	log.Info("  vhdm mount --vhd-path %s --mount-point /mnt/your-mount-point", vhdPath)
 	
 	return nil
 }
+
+// CreateResult is the outcome of 'vhdm create'. UUID, DeviceName and
+// Filesystem are empty when the VHD was created unformatted.
+type CreateResult struct {
+	Path       string `json:"path"`
+	Size       string `json:"size"`
+	UUID       string `json:"uuid,omitempty"`
+	DeviceName string `json:"deviceName,omitempty"`
+	Filesystem string `json:"filesystem,omitempty"`
+}
+
+func (r CreateResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{
+		{"Path", r.Path},
+		{"Size", r.Size},
+	}
+	if r.UUID == "" {
+		pairs = append(pairs, [2]string{"Status", "created (unformatted)"})
+		return "Create Result", pairs
+	}
+	pairs = append(pairs, [2]string{"UUID", r.UUID})
+	pairs = appendDevice(pairs, r.DeviceName)
+	pairs = append(pairs,
+		[2]string{"Filesystem", r.Filesystem},
+		[2]string{"Status", "created and formatted"},
+	)
+	return "Create Result", pairs
+}
+
+func (r CreateResult) quiet() quietLine {
+	if r.UUID == "" {
+		return quietLine{Key: r.Path, State: "created"}
+	}
+	return quietLine{Key: r.Path, UUID: r.UUID, State: "created,formatted"}
+}
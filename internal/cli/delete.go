@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
@@ -11,25 +12,36 @@ import (
 )
 
 func newDeleteCmd() *cobra.Command {
-	var vhdPath string
+	var (
+		vhdPath string
+		unpin   bool
+	)
 	cmd := &cobra.Command{
 		Use:   "delete",
 		Short: "Delete a VHD file",
 		Long: `Delete a VHD file from disk.
 
-The VHD must be detached before deletion.`,
+The VHD must be detached before deletion. Pinned VHDs (see 'vhdm pin') are
+only deleted with --unpin.
+
+In a terminal, disks of VHDM_CONFIRM_NAME_ABOVE (default 100G) or more also
+require typing the VHD file name, even with --yes.
+
+A service created for the VHD with 'vhdm service create' is stopped and
+removed along with it (this requires root privileges; without them it is kept
+and a warning names it).`,
 		Example: "  vhdm delete --vhd-path C:/VMs/disk.vhdx",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDelete(vhdPath)
+			return runDelete(appContext(cmd), vhdPath, unpin)
 		},
 	}
 	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().BoolVar(&unpin, "unpin", false, "Remove the pin of a pinned VHD and delete it")
 	cmd.MarkFlagRequired("vhd-path")
 	return cmd
 }
 
-func runDelete(vhdPath string) error {
-	ctx := getContext()
+func runDelete(ctx *AppContext, vhdPath string, unpin bool) error {
 	log := ctx.Logger
 
 	// Validate
@@ -37,6 +49,19 @@ func runDelete(vhdPath string) error {
 		return &types.VHDError{Op: "delete", Path: vhdPath, Err: err}
 	}
 
+	if err := ensureNotReference(ctx, "delete", vhdPath); err != nil {
+		return err
+	}
+	if err := checkPinned(ctx, "delete", vhdPath, unpin); err != nil {
+		return err
+	}
+
+	lock, err := lockVHDOperation(ctx, "delete", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	log.Debug("Delete operation starting")
 
 	// Check if file exists
@@ -53,6 +78,9 @@ func runDelete(vhdPath string) error {
 			return fmt.Errorf("VHD is still attached. Run 'vhdm detach --vhd-path %s' first", vhdPath)
 		}
 	}
+	if err := ensureNotInUseByWindows(ctx, "delete", vhdPath); err != nil {
+		return err
+	}
 
 	// Confirm deletion
 	if !ctx.Config.Yes {
@@ -60,6 +88,9 @@ func runDelete(vhdPath string) error {
 		log.Warn("Run with --yes to confirm")
 		return fmt.Errorf("operation cancelled")
 	}
+	if err := confirmByName(ctx, "delete", filepath.Base(vhdPath), vhdDiskSize(ctx, wslPath)); err != nil {
+		return err
+	}
 
 	// Delete file
 	log.Info("Deleting VHD file...")
@@ -67,22 +98,44 @@ func runDelete(vhdPath string) error {
 		return fmt.Errorf("failed to delete: %w", err)
 	}
 
+	// The service would fail to mount the VHD from now on
+	res := DeleteResult{Path: vhdPath}
+	if service := trackedService(ctx, vhdPath); service != "" {
+		if err := runServiceRemove(ctx, service); err != nil {
+			log.Warn("Service %s of the deleted VHD was kept: %v", service, err)
+			log.Warn("Remove it with: sudo vhdm service remove --name %s", service)
+		} else {
+			res.Service = service
+		}
+	}
+
 	// Remove from tracking
+	if entry, err := ctx.Tracker.GetEntry(vhdPath); err == nil {
+		for _, snap := range entry.Snapshots {
+			log.Warn("Snapshot %s is kept: %s", snap.Name, snap.File)
+		}
+	}
 	ctx.Tracker.RemoveMapping(vhdPath)
 
 	// Output
-	if ctx.Config.Quiet {
-		fmt.Printf("%s: deleted\n", vhdPath)
-		return nil
-	}
-
 	log.Success("VHD deleted successfully")
-	
-	pairs := [][2]string{
-		{"Path", vhdPath},
-		{"Status", "deleted"},
+	return printResult(ctx, res)
+}
+
+// DeleteResult is the outcome of 'vhdm delete'
+type DeleteResult struct {
+	Path    string `json:"path"`
+	Service string `json:"service,omitempty"` // Removed service of the VHD
+}
+
+func (r DeleteResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{{"Path", r.Path}}
+	if r.Service != "" {
+		pairs = append(pairs, [2]string{"Service", r.Service + " (removed)"})
 	}
-	utils.KeyValueTable("Delete Result", pairs, 14, 50)
-	
-	return nil
+	return "Delete Result", append(pairs, [2]string{"Status", "deleted"})
+}
+
+func (r DeleteResult) quiet() quietLine {
+	return quietLine{Key: r.Path, State: "deleted"}
 }
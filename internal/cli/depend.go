@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newDependCmd() *cobra.Command {
+	var (
+		vhdPath  string
+		after    []string
+		clearAll bool
+	)
+	cmd := &cobra.Command{
+		Use:   "depend",
+		Short: "Declare that a VHD must be mounted after other VHDs",
+		Long: `Declare mount ordering between tracked VHDs, e.g. when one VHD is mounted
+inside another VHD's mount point or layered on top of it.
+
+Dependencies are respected by 'vhdm mount --all' and encoded as After=/Requires=
+relationships in units generated by 'vhdm service create'. Recreate existing
+services after changing dependencies.
+
+Without --after or --clear, the current dependencies are shown.`,
+		Example: `  vhdm depend --vhd-path C:/VMs/overlay.vhdx --after C:/VMs/base.vhdx
+  vhdm depend --vhd-path C:/VMs/overlay.vhdx
+  vhdm depend --vhd-path C:/VMs/overlay.vhdx --clear`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDepend(appContext(cmd), vhdPath, after, clearAll)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringSliceVar(&after, "after", nil, "VHD that must be mounted first (repeatable)")
+	cmd.Flags().BoolVar(&clearAll, "clear", false, "Remove all dependencies")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runDepend(ctx *AppContext, vhdPath string, after []string, clearAll bool) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "depend", Path: vhdPath, Err: err}
+	}
+	if clearAll && len(after) > 0 {
+		return &types.VHDError{Op: "depend", Err: fmt.Errorf("--after and --clear cannot be used together")}
+	}
+
+	entry, err := ctx.Tracker.GetEntry(vhdPath)
+	if err != nil {
+		return &types.VHDError{
+			Op:   "depend",
+			Path: vhdPath,
+			Err:  types.ErrVHDNotTracked,
+			Help: "Attach or mount the VHD at least once so it is tracked",
+		}
+	}
+
+	// Show current dependencies
+	if !clearAll && len(after) == 0 {
+		if len(entry.After) == 0 && !structuredOutput(ctx) {
+			log.Info("%s has no dependencies", vhdPath)
+			return nil
+		}
+		return printResult(ctx, DependResult{Path: vhdPath, After: nonNil(entry.After), show: true})
+	}
+
+	for _, dep := range after {
+		if err := validation.ValidateWindowsPath(dep); err != nil {
+			return &types.VHDError{Op: "depend", Path: dep, Err: err}
+		}
+		if utils.NormalizePath(dep) == utils.NormalizePath(vhdPath) {
+			return &types.VHDError{Op: "depend", Path: dep, Err: fmt.Errorf("a VHD cannot depend on itself")}
+		}
+		if _, err := ctx.Tracker.GetEntry(dep); err != nil {
+			return &types.VHDError{Op: "depend", Path: dep, Err: fmt.Errorf("dependency is not tracked in the system"), Code: types.CodeNotTracked}
+		}
+	}
+
+	if err := ctx.Tracker.SetAfter(vhdPath, after); err != nil {
+		return fmt.Errorf("failed to save dependencies: %w", err)
+	}
+
+	// Reject changes that introduce a cycle
+	paths, err := ctx.Tracker.GetAllPaths()
+	if err != nil {
+		return fmt.Errorf("failed to get tracked VHDs: %w", err)
+	}
+	if _, err := ctx.Tracker.SortByDependencies(paths); err != nil {
+		if restoreErr := ctx.Tracker.SetAfter(vhdPath, entry.After); restoreErr != nil {
+			log.Warn("Failed to restore previous dependencies: %v", restoreErr)
+		}
+		return &types.VHDError{Op: "depend", Path: vhdPath, Err: err}
+	}
+
+	// Output
+	if clearAll {
+		log.Success("Dependencies cleared for %s", vhdPath)
+		if ctx.Config.Output == "table" && !ctx.Config.Quiet {
+			return nil
+		}
+	} else {
+		log.Success("Dependencies updated")
+	}
+	return printResult(ctx, DependResult{Path: vhdPath, After: nonNil(after)})
+}
+
+// DependResult is the outcome of 'vhdm depend': the dependencies shown or
+// set. After is empty when they were cleared.
+type DependResult struct {
+	Path  string   `json:"path"`
+	After []string `json:"after"`
+
+	show bool // Dependencies were shown, not changed
+}
+
+func (r DependResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{{"Path", r.Path}}
+	for _, dep := range r.After {
+		pairs = append(pairs, [2]string{"After", dep})
+	}
+	return "VHD Dependencies", pairs
+}
+
+func (r DependResult) quiet() quietLine {
+	switch {
+	case r.show && len(r.After) == 0:
+		return quietLine{Key: r.Path, State: "no dependencies"}
+	case len(r.After) == 0:
+		return quietLine{Key: r.Path, State: "dependencies cleared"}
+	}
+	return quietLine{Key: r.Path, State: "after " + strings.Join(r.After, ", ")}
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -27,17 +28,17 @@ If the VHD is mounted, it will be unmounted first.`,
   vhdm detach --uuid 57fd0f3a-4077-44b8-91ba-5abdee575293
   vhdm detach --dev-name sde`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDetach(vhdPath, uuid, devName)
+			return runDetach(appContext(cmd), vhdPath, uuid, devName)
 		},
+		Annotations: map[string]string{annotationSudo: "true"},
 	}
 	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
-	cmd.Flags().StringVar(&uuid, "uuid", "", "VHD UUID")
+	cmd.Flags().StringVar(&uuid, "uuid", "", uuidFlagUsage)
 	cmd.Flags().StringVar(&devName, "dev-name", "", "Device name (e.g., sde)")
 	return cmd
 }
 
-func runDetach(vhdPath, uuid, devName string) error {
-	ctx := getContext()
+func runDetach(ctx *AppContext, vhdPath, uuid, devName string) error {
 	log := ctx.Logger
 
 	// Validate inputs
@@ -51,9 +52,11 @@ func runDetach(vhdPath, uuid, devName string) error {
 		}
 	}
 	if uuid != "" {
-		if err := validation.ValidateUUID(uuid); err != nil {
-			return &types.VHDError{Op: "detach", Err: err}
+		resolved, err := resolveUUID(ctx, "detach", uuid)
+		if err != nil {
+			return err
 		}
+		uuid = resolved
 	}
 	if devName != "" {
 		if err := validation.ValidateDeviceName(devName); err != nil {
@@ -101,12 +104,13 @@ func runDetach(vhdPath, uuid, devName string) error {
 		mounted, _ := ctx.WSL.IsMounted(uuid)
 		if mounted {
 			log.Info("VHD is mounted, unmounting first...")
-			mountPoint, _ := ctx.WSL.GetMountPoint(uuid)
-			if mountPoint != "" {
-				if err := ctx.WSL.Unmount(mountPoint); err != nil {
+			// Bind mounts come after the original mount; undo them first
+			mountPoints, _ := ctx.WSL.GetMountPoints(uuid)
+			for i := len(mountPoints) - 1; i >= 0; i-- {
+				if err := ctx.WSL.Unmount(mountPoints[i]); err != nil {
 					return fmt.Errorf("failed to unmount: %w", err)
 				}
-				log.Success("Unmounted from %s", mountPoint)
+				log.Success("Unmounted from %s", mountPoints[i])
 			}
 		}
 	}
@@ -120,44 +124,52 @@ func runDetach(vhdPath, uuid, devName string) error {
 	if err := ctx.WSL.DetachVHD(vhdPath); err != nil {
 		if types.IsNotAttached(err) {
 			// Already detached - update tracking to reflect current state
-			if uuid != "" {
-				ctx.Tracker.SaveMapping(vhdPath, uuid, "", "")
-			}
-			if ctx.Config.Quiet {
-				fmt.Printf("%s: already detached\n", vhdPath)
-			} else {
-				log.Info("VHD is already detached")
-			}
-			return nil
+			markDetached(ctx, vhdPath)
+			log.Info("VHD is already detached")
+			return printResult(ctx, DetachResult{Path: vhdPath, UUID: uuid, DeviceName: devName, Status: "already detached"})
 		}
 		return fmt.Errorf("failed to detach: %w", err)
 	}
 
 	// Update tracking - keep entry but clear device/mount info
-	if uuid != "" {
-		ctx.Tracker.SaveMapping(vhdPath, uuid, "", "")
-	}
+	markDetached(ctx, vhdPath)
 
 	// Output
-	if ctx.Config.Quiet {
-		fmt.Printf("%s: detached\n", vhdPath)
-		return nil
-	}
-
 	log.Success("VHD detached successfully")
+	return printResult(ctx, DetachResult{Path: vhdPath, UUID: uuid, DeviceName: devName, Status: "detached"})
+}
 
+// DetachResult is the outcome of 'vhdm detach'
+type DetachResult struct {
+	Path       string `json:"path"`
+	UUID       string `json:"uuid,omitempty"`
+	DeviceName string `json:"deviceName,omitempty"`
+	Status     string `json:"status"`
+}
+
+func (r DetachResult) table(utils.Theme) (string, [][2]string) {
 	pairs := [][2]string{
-		{"Path", vhdPath},
+		{"Path", r.Path},
 	}
-	if uuid != "" {
-		pairs = append(pairs, [2]string{"UUID", uuid})
+	if r.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", r.UUID})
 	}
-	if devName != "" {
-		pairs = append(pairs, [2]string{"Device", "/dev/" + devName})
-	}
-	pairs = append(pairs, [2]string{"Status", "detached"})
+	pairs = appendDevice(pairs, r.DeviceName)
+	pairs = append(pairs, [2]string{"Status", r.Status})
+	return "VHD Detach Result", pairs
+}
 
-	utils.KeyValueTable("VHD Detach Result", pairs, 14, 50)
+func (r DetachResult) quiet() quietLine {
+	return quietLine{Key: r.Path, UUID: r.UUID, State: r.Status}
+}
 
-	return nil
+// markDetached clears the device and mount points of a detached VHD, keeping
+// the rest of its tracking entry (UUID, last mount, dependencies, ...)
+func markDetached(ctx *AppContext, vhdPath string) error {
+	return ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+		entry.DeviceName = ""
+		entry.MountPoints = nil
+		entry.External = false
+		entry.LastSeen = time.Now().Format(time.RFC3339)
+	})
 }
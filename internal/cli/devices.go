@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newDevicesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "devices",
+		Short: "List dynamically attached block devices",
+		Long: `List the block devices of attached VHDs, leaving out the WSL system disks,
+with their UUID, filesystem type, size, mount points and the tracked VHD each
+belongs to. Devices without a tracked VHD were attached outside vhdm or before
+their UUID was recorded; 'vhdm which' may still find their file.`,
+		Example: `  vhdm devices
+  vhdm devices --output json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDevices(appContext(cmd))
+		},
+	}
+}
+
+// deviceRow is one device of the devices list
+type deviceRow struct {
+	Name        string   `json:"name"`
+	UUID        string   `json:"uuid,omitempty"`
+	FSType      string   `json:"fsType,omitempty"`
+	Size        string   `json:"size,omitempty"`
+	MountPoints []string `json:"mountPoints,omitempty"`
+	VHDPath     string   `json:"vhdPath,omitempty"`
+}
+
+func runDevices(ctx *AppContext) error {
+	if err := checkListOutput(ctx, "devices"); err != nil {
+		return err
+	}
+	devices, err := ctx.WSL.GetBlockDevicesWithInfo()
+	if err != nil {
+		return fmt.Errorf("failed to list block devices: %w", err)
+	}
+
+	rows := []deviceRow{}
+	for _, dev := range devices {
+		if !wsl.IsDynamicDevice(dev.Name) {
+			continue
+		}
+		row := deviceRow{
+			Name:        dev.Name,
+			UUID:        dev.UUID,
+			FSType:      dev.FSType,
+			Size:        dev.Size,
+			MountPoints: filterEmptyMountPoints(dev.MountPoints),
+		}
+		if dev.UUID != "" {
+			row.VHDPath, _ = ctx.Tracker.LookupPathByUUID(dev.UUID)
+		} else {
+			// Unformatted devices have no UUID; fall back to the device name
+			// recorded at attach
+			row.VHDPath, _ = ctx.Tracker.LookupPathByDevName(dev.Name)
+		}
+		rows = append(rows, row)
+	}
+
+	switch {
+	case structuredOutput(ctx):
+		return printStructured(ctx, rows)
+	case ctx.Config.Quiet:
+		for _, row := range rows {
+			printQuiet(quietLine{Key: "/dev/" + row.Name, UUID: row.UUID, State: valueOrNone(row.VHDPath)})
+		}
+	case len(rows) == 0:
+		ctx.Logger.Info("No attached VHD devices found")
+	default:
+		printDevicesTable(ctx, rows)
+	}
+	return nil
+}
+
+func printDevicesTable(ctx *AppContext, rows []deviceRow) {
+	fmt.Println()
+	fmt.Println("Attached VHD Devices")
+	fmt.Println()
+
+	colWidths := []int{8, 36, 8, 8, 24, 40}
+	headers := []string{"Device", "UUID", "Type", "Size", "Mount Points", "Tracked VHD"}
+
+	utils.PrintTableHeader(colWidths, headers)
+
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	for _, row := range rows {
+		vhd := row.VHDPath
+		if vhd == "" {
+			vhd = ctx.Theme.Yellow("(untracked)")
+		}
+		utils.PrintTableRow(colWidths, row.Name, dash(row.UUID), dash(row.FSType), dash(row.Size),
+			dash(strings.Join(row.MountPoints, ", ")), vhd)
+	}
+
+	utils.PrintTableFooter(colWidths)
+}
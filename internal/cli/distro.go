@@ -0,0 +1,514 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newDistroCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "distro",
+		Short: "Inspect and manage WSL distributions' system VHDs",
+		Long: `Inspect and manage the system VHDs (ext4.vhdx) of registered WSL distributions.
+
+Distributions are read from the Windows registry.`,
+	}
+
+	cmd.AddCommand(
+		newDistroListCmd(),
+		newDistroResizeCmd(),
+		newDistroCompactCmd(),
+	)
+
+	return cmd
+}
+
+func newDistroListCmd() *cobra.Command {
+	var track bool
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List WSL distributions and their system VHDs",
+		Long: `List registered WSL distributions with their base path, system VHD location
+and on-disk size.
+
+With --track, the system VHDs are added to the tracking file as read-only
+references: they show up in status and reports, but vhdm refuses to attach,
+mount, resize or delete them.`,
+		Example: `  vhdm distro list
+  vhdm distro list --track`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDistroList(appContext(cmd), track)
+		},
+	}
+	cmd.Flags().BoolVar(&track, "track", false, "Track system VHDs as read-only references")
+	return cmd
+}
+
+func newDistroResizeCmd() *cobra.Command {
+	var (
+		distro string
+		size   string
+	)
+	cmd := &cobra.Command{
+		Use:   "resize",
+		Short: "Grow a WSL distribution's system VHD",
+		Long: `Grow the system VHD (ext4.vhdx) of a WSL distribution and its filesystem.
+
+The process:
+1. Stops the distribution (wsl.exe --terminate)
+2. Expands the VHD file with diskpart (Windows shows a UAC prompt)
+3. Starts the distribution and grows its root filesystem with resize2fs
+
+Only the target distribution is stopped, so vhdm must run from another
+distribution: 'wsl.exe --shutdown' would stop vhdm itself. A running
+distribution is only stopped with --yes, and is started again afterwards.
+Shrinking is not supported.`,
+		Example: `  wsl.exe -d Debian -- vhdm distro resize --distro Ubuntu --size 512G
+  vhdm distro resize --distro Ubuntu --size 512G -y`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDistroResize(appContext(cmd), distro, size)
+		},
+	}
+	cmd.Flags().StringVar(&distro, "distro", "", "WSL distribution name")
+	cmd.Flags().StringVar(&size, "size", "", "New VHD size (e.g., 256G, 512G)")
+	cmd.MarkFlagRequired("distro")
+	cmd.MarkFlagRequired("size")
+	cmd.RegisterFlagCompletionFunc("size", completeSizes)
+	return cmd
+}
+
+func newDistroCompactCmd() *cobra.Command {
+	var (
+		distro string
+		noTrim bool
+	)
+	cmd := &cobra.Command{
+		Use:   "compact",
+		Short: "Reclaim unused space in a WSL distribution's system VHD",
+		Long: `Shrink the on-disk size of a distribution's system VHD (ext4.vhdx) by
+compacting it, and report the space reclaimed.
+
+The process:
+1. Trims free blocks inside the distribution (fstrim), unless --no-trim
+2. Stops the distribution (wsl.exe --terminate)
+3. Compacts the VHD with Optimize-VHD if the Hyper-V module is installed,
+   diskpart otherwise (Windows shows a UAC prompt)
+
+Like 'distro resize', this must be run from another distribution. A running
+distribution is only stopped with --yes, and is started again afterwards.`,
+		Example: `  wsl.exe -d Debian -- vhdm distro compact --distro Ubuntu
+  vhdm distro compact --distro Ubuntu --no-trim -y`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDistroCompact(appContext(cmd), distro, noTrim)
+		},
+	}
+	cmd.Flags().StringVar(&distro, "distro", "", "WSL distribution name")
+	cmd.Flags().BoolVar(&noTrim, "no-trim", false, "Skip fstrim inside the distribution before compacting")
+	cmd.MarkFlagRequired("distro")
+	return cmd
+}
+
+// distroDisk is a WSL distribution with the size of its system VHD
+type distroDisk struct {
+	wsl.WSLDistribution
+	Path string `json:"path"` // System VHD path in vhdm format (forward slashes)
+	Size int64  `json:"size"` // On-disk size in bytes, -1 if the file is missing
+}
+
+func getDistroDisks(ctx *AppContext) ([]distroDisk, error) {
+	dists, err := ctx.WSL.GetWSLDistributions()
+	if err != nil {
+		return nil, err
+	}
+
+	disks := make([]distroDisk, 0, len(dists))
+	for _, dist := range dists {
+		disk := distroDisk{WSLDistribution: dist, Path: dist.SystemVHDPath(), Size: -1}
+		if disk.Path != "" {
+			if size, err := ctx.WSL.FileSize(ctx.WSL.ConvertPath(disk.Path)); err == nil {
+				disk.Size = size
+			}
+		}
+		disks = append(disks, disk)
+	}
+	return disks, nil
+}
+
+func runDistroList(ctx *AppContext, track bool) error {
+	log := ctx.Logger
+
+	if err := checkListOutput(ctx, "distro list"); err != nil {
+		return err
+	}
+
+	log.Debug("Distro list operation starting")
+
+	disks, err := getDistroDisks(ctx)
+	if err != nil {
+		return &types.VHDError{Op: "distro list", Err: err, Help: "Distributions are read with reg.exe; make sure Windows interop is enabled"}
+	}
+
+	if track {
+		for _, disk := range disks {
+			if disk.Path == "" || disk.Size < 0 {
+				continue
+			}
+			if err := ctx.Tracker.SaveReference(disk.Path, disk.Name); err != nil {
+				log.Warn("Failed to track %s: %v", disk.Path, err)
+				continue
+			}
+			log.Debug("Tracking %s as read-only reference", disk.Path)
+		}
+	}
+
+	// Output
+	if structuredOutput(ctx) {
+		return printStructured(ctx, nonNil(disks))
+	}
+	if ctx.Config.Quiet {
+		for _, disk := range disks {
+			printQuiet(quietLine{Key: disk.Name, State: fmt.Sprintf("%s (%s)", disk.Path, formatDistroSize(disk.Size))})
+		}
+		return nil
+	}
+
+	if len(disks) == 0 {
+		log.Info("No WSL distributions found")
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("WSL Distributions")
+	fmt.Println()
+
+	colWidths := []int{20, 10, 60, 12}
+	utils.PrintTableHeader(colWidths, []string{"Distribution", "Size", "Base Path", "VHD File"})
+	for _, disk := range disks {
+		basePath, file := "-", "-"
+		if disk.Path != "" {
+			basePath, file = path.Split(disk.Path)
+			basePath = strings.TrimSuffix(basePath, "/")
+		}
+		utils.PrintTableRow(colWidths, disk.Name, formatDistroSize(disk.Size), basePath, file)
+	}
+	utils.PrintTableFooter(colWidths)
+
+	if track {
+		fmt.Println()
+		log.Success("System VHDs tracked as read-only references")
+	}
+
+	return nil
+}
+
+// findOtherDistribution looks up a distribution's system VHD and rejects the
+// distribution vhdm is running in, which cannot be stopped from inside
+func findOtherDistribution(ctx *AppContext, op, name string) (*wsl.WSLDistribution, string, error) {
+	dist, err := ctx.WSL.FindDistribution(name)
+	if err != nil {
+		return nil, "", &types.VHDError{Op: op, Err: err, Help: "List distributions with: vhdm distro list"}
+	}
+	vhdPath := dist.SystemVHDPath()
+	if vhdPath == "" {
+		return nil, "", &types.VHDError{Op: op, Err: fmt.Errorf("distribution %s has no system VHD (WSL 1?)", dist.Name)}
+	}
+	if strings.EqualFold(dist.Name, os.Getenv("WSL_DISTRO_NAME")) {
+		return nil, "", &types.VHDError{
+			Op:   op,
+			Path: vhdPath,
+			Err:  fmt.Errorf("cannot %s the distribution vhdm is running in (%s)", strings.TrimPrefix(op, "distro "), dist.Name),
+			Help: fmt.Sprintf("Run vhdm from another distribution: wsl.exe -d <other> -- vhdm %s ...", op),
+		}
+	}
+	return dist, vhdPath, nil
+}
+
+// stoppedDistro is a distribution stopped for an operation on its system VHD
+type stoppedDistro struct {
+	name       string
+	wasRunning bool
+}
+
+// prepareDistroStop checks whether a distribution is running before an
+// operation that needs it stopped. A running distribution is only stopped
+// with --yes, and is started again afterwards (see restart).
+func prepareDistroStop(ctx *AppContext, op, name string) (*stoppedDistro, error) {
+	running, err := ctx.WSL.IsDistributionRunning(name)
+	if err != nil {
+		ctx.Logger.Warn("Could not determine whether %s is running: %v", name, err)
+		running = true
+	}
+	if running && !ctx.Config.Yes {
+		return nil, &types.VHDError{
+			Op:   op,
+			Err:  fmt.Errorf("distribution %s is running", name),
+			Help: fmt.Sprintf("Run with --yes to stop it (it is started again afterwards), or stop it first: wsl.exe --terminate %s", name),
+		}
+	}
+	return &stoppedDistro{name: name, wasRunning: running}, nil
+}
+
+// stop terminates the distribution. It is also called when the distribution
+// was not running, since preparation steps (e.g., fstrim) may have started it.
+func (s *stoppedDistro) stop(ctx *AppContext) error {
+	ctx.Logger.Info("Stopping distribution %s...", s.name)
+	if err := ctx.WSL.TerminateDistribution(s.name); err != nil {
+		return fmt.Errorf("failed to stop distribution: %w", err)
+	}
+	return nil
+}
+
+// restart starts the distribution again if it was running before
+func (s *stoppedDistro) restart(ctx *AppContext) {
+	if !s.wasRunning {
+		return
+	}
+	ctx.Logger.Info("Starting distribution %s again...", s.name)
+	if err := ctx.WSL.StartDistribution(s.name); err != nil {
+		ctx.Logger.Warn("Failed to start %s: %v", s.name, err)
+	}
+}
+
+// systemDiskOwner returns the distribution whose system VHD is vhdPath, or an
+// empty string
+func systemDiskOwner(ctx *AppContext, vhdPath string) string {
+	dists, err := ctx.WSL.GetWSLDistributions()
+	if err != nil {
+		return ""
+	}
+	for _, dist := range dists {
+		if strings.EqualFold(dist.SystemVHDPath(), vhdPath) {
+			return dist.Name
+		}
+	}
+	return ""
+}
+
+func runDistroResize(ctx *AppContext, distro, size string) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateSizeString(size); err != nil {
+		return &types.VHDError{Op: "distro resize", Err: err}
+	}
+	newBytes, err := utils.ConvertSizeToBytes(size)
+	if err != nil {
+		return &types.VHDError{Op: "distro resize", Err: err}
+	}
+
+	log.Debug("Distro resize operation starting")
+
+	dist, vhdPath, err := findOtherDistribution(ctx, "distro resize", distro)
+	if err != nil {
+		return err
+	}
+
+	img, err := ctx.WSL.GetImageInfo(ctx.WSL.ConvertPath(vhdPath))
+	if err != nil {
+		return &types.VHDError{Op: "distro resize", Path: vhdPath, Err: err}
+	}
+	if newBytes <= img.VirtualSize {
+		return &types.VHDError{
+			Op:   "distro resize",
+			Path: vhdPath,
+			Err:  fmt.Errorf("new size %s is not larger than the current size %s", size, utils.BytesToHuman(img.VirtualSize)),
+			Help: "Shrinking a system VHD is not supported",
+		}
+	}
+
+	// Confirm resize
+	if !ctx.Config.Yes {
+		log.Warn("This will grow the system VHD of %s from %s to %s", dist.Name, utils.BytesToHuman(img.VirtualSize), size)
+		log.Warn("Back up the distribution first: wsl.exe --export %s <file.tar>", dist.Name)
+		log.Warn("Run with --yes to confirm")
+		return fmt.Errorf("operation cancelled")
+	}
+
+	stopped, err := prepareDistroStop(ctx, "distro resize", dist.Name)
+	if err != nil {
+		return err
+	}
+	if err := stopped.stop(ctx); err != nil {
+		return err
+	}
+	defer stopped.restart(ctx)
+
+	log.Info("Expanding %s to %s (accept the UAC prompt)...", vhdPath, size)
+	if err := ctx.WSL.ExpandVHDFile(vhdPath, newBytes); err != nil {
+		return &types.VHDError{
+			Op:   "distro resize",
+			Path: vhdPath,
+			Err:  err,
+			Help: "diskpart needs administrator rights; the VHD is unchanged",
+		}
+	}
+
+	log.Info("Growing the root filesystem of %s...", dist.Name)
+	if _, err := ctx.WSL.RunInDistribution(dist.Name, "sh", "-c", `resize2fs "$(findmnt -no SOURCE /)"`); err != nil {
+		return &types.VHDError{
+			Op:   "distro resize",
+			Path: vhdPath,
+			Err:  err,
+			Help: fmt.Sprintf("The VHD was expanded. Grow the filesystem manually: wsl.exe -d %s -u root -- sh -c 'resize2fs $(findmnt -no SOURCE /)'", dist.Name),
+		}
+	}
+
+	// Output
+	log.Success("System VHD resized successfully")
+	return printResult(ctx, DistroResizeResult{
+		Distribution: dist.Name,
+		Path:         vhdPath,
+		OldSize:      img.VirtualSize,
+		NewSize:      newBytes,
+	})
+}
+
+// DistroResizeResult is the outcome of 'vhdm distro resize'
+type DistroResizeResult struct {
+	Distribution string `json:"distribution"`
+	Path         string `json:"path"`
+	OldSize      int64  `json:"oldSize"`
+	NewSize      int64  `json:"newSize"`
+}
+
+func (r DistroResizeResult) table(utils.Theme) (string, [][2]string) {
+	return "Distro Resize Result", [][2]string{
+		{"Distribution", r.Distribution},
+		{"Path", r.Path},
+		{"Old Size", utils.BytesToHuman(r.OldSize)},
+		{"New Size", utils.BytesToHuman(r.NewSize)},
+	}
+}
+
+func (r DistroResizeResult) quiet() quietLine {
+	return quietLine{Key: r.Distribution, State: "resized to " + utils.BytesToHuman(r.NewSize)}
+}
+
+func runDistroCompact(ctx *AppContext, distro string, noTrim bool) error {
+	log := ctx.Logger
+
+	log.Debug("Distro compact operation starting")
+
+	dist, vhdPath, err := findOtherDistribution(ctx, "distro compact", distro)
+	if err != nil {
+		return err
+	}
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	before, err := ctx.WSL.FileSize(wslPath)
+	if err != nil {
+		return &types.VHDError{Op: "distro compact", Path: vhdPath, Err: err}
+	}
+
+	stopped, err := prepareDistroStop(ctx, "distro compact", dist.Name)
+	if err != nil {
+		return err
+	}
+
+	if !noTrim {
+		log.Info("Trimming free space in %s...", dist.Name)
+		if _, err := ctx.WSL.RunInDistribution(dist.Name, "fstrim", "/"); err != nil {
+			log.Warn("fstrim failed, less space may be reclaimed: %v", err)
+		}
+	}
+
+	if err := stopped.stop(ctx); err != nil {
+		return err
+	}
+	defer stopped.restart(ctx)
+
+	log.Info("Compacting %s (accept the UAC prompt)...", vhdPath)
+	if err := ctx.WSL.CompactVHDFile(vhdPath); err != nil {
+		return &types.VHDError{
+			Op:   "distro compact",
+			Path: vhdPath,
+			Err:  err,
+			Help: "Compaction needs administrator rights and the VHD must not be in use",
+		}
+	}
+
+	after, err := ctx.WSL.FileSize(wslPath)
+	if err != nil {
+		return &types.VHDError{Op: "distro compact", Path: vhdPath, Err: err}
+	}
+	reclaimed := before - after
+	if reclaimed < 0 {
+		reclaimed = 0
+	}
+
+	// Output
+	log.Success("System VHD compacted successfully")
+	return printResult(ctx, DistroCompactResult{
+		Distribution: dist.Name,
+		Path:         vhdPath,
+		Before:       before,
+		After:        after,
+		Reclaimed:    reclaimed,
+	})
+}
+
+// DistroCompactResult is the outcome of 'vhdm distro compact'. Sizes are
+// the VHD file sizes in bytes.
+type DistroCompactResult struct {
+	Distribution string `json:"distribution"`
+	Path         string `json:"path"`
+	Before       int64  `json:"before"`
+	After        int64  `json:"after"`
+	Reclaimed    int64  `json:"reclaimed"`
+}
+
+func (r DistroCompactResult) table(utils.Theme) (string, [][2]string) {
+	return "Distro Compact Result", [][2]string{
+		{"Distribution", r.Distribution},
+		{"Path", r.Path},
+		{"Before", utils.BytesToHuman(r.Before)},
+		{"After", utils.BytesToHuman(r.After)},
+		{"Reclaimed", utils.BytesToHuman(r.Reclaimed)},
+	}
+}
+
+func (r DistroCompactResult) quiet() quietLine {
+	return quietLine{Key: r.Distribution, State: "reclaimed " + utils.BytesToHuman(r.Reclaimed)}
+}
+
+func formatDistroSize(size int64) string {
+	if size < 0 {
+		return "missing"
+	}
+	return utils.BytesToHuman(size)
+}
+
+// ensureNotReference rejects operations on system VHDs tracked as read-only
+// references (see 'vhdm distro list --track') and on the bases of linked
+// clones (see 'vhdm clone --linked'), which must stay unchanged
+func ensureNotReference(ctx *AppContext, op, vhdPath string) error {
+	if clones := linkedClones(ctx, vhdPath); len(clones) > 0 {
+		return &types.VHDError{
+			Op:   op,
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD is the base of linked clones: %s", strings.Join(clones, ", ")),
+			Help: "Any change to the base corrupts its linked clones. Flatten them with 'vhdm flatten' or delete them first",
+			Code: types.CodeReadOnly,
+		}
+	}
+	entry, err := ctx.Tracker.GetEntry(vhdPath)
+	if err != nil || !entry.ReadOnly {
+		return nil
+	}
+	return &types.VHDError{
+		Op:   op,
+		Path: vhdPath,
+		Err:  fmt.Errorf("VHD is a read-only reference to the system disk of WSL distribution %s", entry.Distro),
+		Help: "System VHDs of distributions are managed with 'vhdm distro' commands",
+		Code: types.CodeReadOnly,
+	}
+}
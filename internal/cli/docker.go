@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// dockerDropInDir holds vhdm's drop-ins that order docker.service after VHD mounts
+const dockerDropInDir = "/etc/systemd/system/docker.service.d"
+
+func newDockerVolumeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "docker-volume",
+		Short: "Use mounted VHDs as Docker volumes",
+		Long: `Integrate VHDs with Docker for keeping container data on dedicated VHDs.
+
+Register a mounted VHD (or a directory inside it) as a named Docker volume,
+print the equivalent bind-mount flags, or check that the VHD is mounted before
+the Docker service starts.`,
+	}
+
+	cmd.AddCommand(
+		newDockerVolumeCreateCmd(),
+		newDockerVolumeFlagsCmd(),
+		newDockerVolumeCheckCmd(),
+	)
+
+	return cmd
+}
+
+func newDockerVolumeCreateCmd() *cobra.Command {
+	var (
+		vhdPath string
+		name    string
+		subdir  string
+	)
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Register a mounted VHD as a named Docker volume",
+		Long: `Create a local Docker volume that bind-mounts the VHD's mount point (or a
+directory inside it). The VHD must be mounted.`,
+		Example: `  vhdm docker-volume create --vhd-path C:/VMs/pgdata.vhdx
+  vhdm docker-volume create --vhd-path C:/VMs/data.vhdx --name app-data --subdir app
+  docker run -v vhdm-pgdata:/var/lib/postgresql/data postgres`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDockerVolumeCreate(appContext(cmd), vhdPath, name, subdir)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&name, "name", "", "Volume name (default: vhdm-<vhd name>)")
+	cmd.Flags().StringVar(&subdir, "subdir", "", "Directory inside the VHD to use (created if missing)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func newDockerVolumeFlagsCmd() *cobra.Command {
+	var (
+		vhdPath string
+		target  string
+		subdir  string
+	)
+	cmd := &cobra.Command{
+		Use:     "flags",
+		Short:   "Print docker run bind-mount flags for a mounted VHD",
+		Example: `  docker run $(vhdm docker-volume flags --vhd-path C:/VMs/data.vhdx --target /data) alpine ls /data`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDockerVolumeFlags(appContext(cmd), vhdPath, target, subdir)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&target, "target", "/data", "Path inside the container")
+	cmd.Flags().StringVar(&subdir, "subdir", "", "Directory inside the VHD to use")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func newDockerVolumeCheckCmd() *cobra.Command {
+	var (
+		vhdPath string
+		fix     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check that a VHD is mounted before Docker starts",
+		Long: `Check that docker.service is ordered after the systemd unit that mounts the
+VHD (created with 'vhdm service create'), so containers never start on top of an
+empty mount point.
+
+With --fix, a drop-in is written to ` + dockerDropInDir + ` adding
+After= and Requires= on the VHD's unit (requires sudo).`,
+		Example: `  vhdm docker-volume check --vhd-path C:/VMs/pgdata.vhdx
+  sudo vhdm docker-volume check --vhd-path C:/VMs/pgdata.vhdx --fix`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDockerVolumeCheck(appContext(cmd), vhdPath, fix)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().BoolVar(&fix, "fix", false, "Install a docker.service drop-in ordering it after the VHD")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+// dockerMountSource returns the host directory to bind for a mounted VHD
+func dockerMountSource(ctx *AppContext, op, vhdPath, subdir string) (string, error) {
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return "", &types.VHDError{Op: op, Path: vhdPath, Err: err}
+	}
+	if strings.Contains(subdir, "..") {
+		return "", &types.VHDError{Op: op, Err: fmt.Errorf("subdir must not contain '..'")}
+	}
+
+	uuid, _ := ctx.Tracker.LookupUUIDByPath(vhdPath)
+	mountPoint := ""
+	if uuid != "" {
+		mountPoint, _ = ctx.WSL.GetMountPoint(uuid)
+	}
+	if mountPoint == "" {
+		return "", &types.VHDError{
+			Op:   op,
+			Path: vhdPath,
+			Err:  types.ErrVHDNotMounted,
+			Help: fmt.Sprintf("Mount it first: vhdm mount --vhd-path %q --mount-point <path>", vhdPath),
+		}
+	}
+
+	if subdir == "" {
+		return mountPoint, nil
+	}
+	return path.Join(mountPoint, subdir), nil
+}
+
+// dockerVolumeName derives a valid Docker volume name from the VHD file name
+func dockerVolumeName(vhdPath string) string {
+	base := strings.ToLower(utils.MountPointVars(vhdPath)["vhdname"])
+	var b strings.Builder
+	for _, r := range base {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	return "vhdm-" + b.String()
+}
+
+func runDockerVolumeCreate(ctx *AppContext, vhdPath, name, subdir string) error {
+	log := ctx.Logger
+
+	source, err := dockerMountSource(ctx, "docker-volume create", vhdPath, subdir)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = dockerVolumeName(vhdPath)
+	}
+
+	log.Debug("Docker volume create starting: %s -> %s", name, source)
+
+	if subdir != "" {
+		if err := os.MkdirAll(source, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", source, err)
+		}
+	}
+
+	existing, err := ctx.WSL.DockerVolumeDevice(name)
+	if err != nil {
+		return err
+	}
+	if existing != "" && existing != source {
+		return &types.VHDError{
+			Op:   "docker-volume create",
+			Err:  fmt.Errorf("volume %s already exists and points to %s", name, existing),
+			Help: "Choose another name with --name or remove it with: docker volume rm " + name,
+		}
+	}
+	if existing == "" {
+		if err := ctx.WSL.CreateDockerVolume(name, source); err != nil {
+			return err
+		}
+	}
+
+	// Output
+	if existing != "" {
+		log.Info("Docker volume already exists")
+	} else {
+		log.Success("Docker volume created")
+	}
+	return printResult(ctx, DockerVolumeResult{Volume: name, Path: vhdPath, Source: source})
+}
+
+// DockerVolumeResult is the outcome of 'vhdm docker-volume create'
+type DockerVolumeResult struct {
+	Volume string `json:"volume"`
+	Path   string `json:"path"`
+	Source string `json:"source"`
+}
+
+func (r DockerVolumeResult) table(utils.Theme) (string, [][2]string) {
+	return "Docker Volume", [][2]string{
+		{"Volume", r.Volume},
+		{"Path", r.Path},
+		{"Source", r.Source},
+		{"Usage", fmt.Sprintf("docker run -v %s:/data ...", r.Volume)},
+	}
+}
+
+func (r DockerVolumeResult) quiet() quietLine {
+	return quietLine{Key: r.Volume, State: r.Source}
+}
+
+func runDockerVolumeFlags(ctx *AppContext, vhdPath, target, subdir string) error {
+
+	if !strings.HasPrefix(target, "/") {
+		return &types.VHDError{Op: "docker-volume flags", Err: fmt.Errorf("target must be an absolute path")}
+	}
+	source, err := dockerMountSource(ctx, "docker-volume flags", vhdPath, subdir)
+	if err != nil {
+		return err
+	}
+
+	// Printed even in quiet mode: this output is meant for command substitution
+	fmt.Printf("--mount type=bind,source=%s,target=%s\n", source, target)
+	return nil
+}
+
+func runDockerVolumeCheck(ctx *AppContext, vhdPath string, fix bool) error {
+	log := ctx.Logger
+
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "docker-volume check", Path: vhdPath, Err: err}
+	}
+	if _, err := ctx.Tracker.GetEntry(vhdPath); err != nil {
+		return &types.VHDError{Op: "docker-volume check", Path: vhdPath, Err: types.ErrVHDNotTracked}
+	}
+
+	unit := mountUnitFor(ctx, vhdPath)
+	unitPath := unitFilePath(ctx, unit)
+	if strings.HasSuffix(unit, ".mount") {
+		unitPath = unitFilePath(ctx, automountPrefix+strings.TrimSuffix(unit, ".mount")+".service")
+	}
+	if _, err := os.Stat(unitPath); err != nil {
+		return &types.VHDError{
+			Op:   "docker-volume check",
+			Path: vhdPath,
+			Err:  fmt.Errorf("no systemd unit mounts this VHD (expected %s)", unit),
+			Help: fmt.Sprintf("Create one first: sudo vhdm service create --vhd-path %q --mount-point <path>", vhdPath),
+		}
+	}
+
+	output, err := systemctlOutput(ctx, "show", "docker.service", "--property=After", "--value")
+	if err != nil {
+		return fmt.Errorf("failed to query docker.service: %w", err)
+	}
+	ordered := false
+	for _, after := range strings.Fields(string(output)) {
+		if after == unit {
+			ordered = true
+			break
+		}
+	}
+
+	if ordered {
+		if ctx.Config.Quiet {
+			printQuiet(quietLine{Key: vhdPath, State: "docker.service after " + unit})
+			return nil
+		}
+		log.Success("docker.service starts after %s", unit)
+		return nil
+	}
+
+	if !fix {
+		return &types.VHDError{
+			Op:   "docker-volume check",
+			Path: vhdPath,
+			Err:  fmt.Errorf("docker.service is not ordered after %s", unit),
+			Help: fmt.Sprintf("Fix with: sudo vhdm docker-volume check --vhd-path %q --fix", vhdPath),
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("installing the docker.service drop-in requires root privileges. Please run with sudo")
+	}
+	if err := os.MkdirAll(dockerDropInDir, 0755); err != nil {
+		return fmt.Errorf("failed to create drop-in directory: %w", err)
+	}
+	dropIn := filepath.Join(dockerDropInDir, "vhdm-"+strings.TrimSuffix(unit, filepath.Ext(unit))+".conf")
+	content := fmt.Sprintf("# Generated by vhdm for %s\n[Unit]\n%s", vhdPath, unitDependencyLines([]string{unit}))
+	if err := os.WriteFile(dropIn, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write drop-in: %w", err)
+	}
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		log.Warn("Failed to reload systemd daemon: %v", err)
+	}
+
+	if ctx.Config.Quiet {
+		printQuiet(quietLine{Key: vhdPath, State: "docker.service after " + unit + " (fixed)"})
+		return nil
+	}
+	log.Success("docker.service will now start after %s", unit)
+	log.Info("  Drop-in: %s", dropIn)
+	return nil
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newDuCmd() *cobra.Command {
+	var (
+		vhdPath string
+		depth   int
+		top     int
+	)
+	cmd := &cobra.Command{
+		Use:   "du",
+		Short: "Show the largest directories inside a VHD",
+		Long: `Report directory usage inside a VHD, largest first.
+
+If the VHD is not mounted, it is temporarily attached and mounted read-only,
+then unmounted and detached again afterwards.`,
+		Example: `  vhdm du --vhd-path C:/VMs/disk.vhdx
+  vhdm du --vhd-path C:/VMs/disk.vhdx --depth 3 --top 50`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDu(appContext(cmd), vhdPath, depth, top)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().IntVar(&depth, "depth", 2, "Directory depth to report")
+	cmd.Flags().IntVar(&top, "top", 20, "Number of directories to show (0 for all)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runDu(ctx *AppContext, vhdPath string, depth, top int) error {
+	log := ctx.Logger
+
+	if err := checkListOutput(ctx, "du"); err != nil {
+		return err
+	}
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "du", Path: vhdPath, Err: err}
+	}
+	if depth < 0 {
+		return &types.VHDError{Op: "du", Err: fmt.Errorf("depth must not be negative")}
+	}
+
+	log.Debug("Du operation starting")
+
+	m, err := acquireTempMount(ctx, vhdPath, true)
+	if err != nil {
+		return &types.VHDError{Op: "du", Path: vhdPath, Err: err}
+	}
+	defer m.release(ctx)
+
+	usages, err := ctx.WSL.DiskUsage(m.MountPoint, depth)
+	if err != nil {
+		return fmt.Errorf("failed to compute usage: %w", err)
+	}
+
+	var total int64
+	for _, u := range usages {
+		if u.Path == "." {
+			total = u.Bytes
+		}
+	}
+
+	if top > 0 && len(usages) > top {
+		usages = usages[:top]
+	}
+
+	// Output
+	if structuredOutput(ctx) {
+		return printStructured(ctx, nonNil(usages))
+	}
+	if ctx.Config.Quiet {
+		for _, u := range usages {
+			printQuiet(quietLine{Key: u.Path, State: fmt.Sprint(u.Bytes)})
+		}
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Printf("Disk Usage: %s (%s)\n", vhdPath, utils.BytesToHuman(total))
+	fmt.Println()
+
+	colWidths := []int{10, 7, 60}
+	utils.PrintTableHeader(colWidths, []string{"Size", "Share", "Directory"})
+	for _, u := range usages {
+		share := "-"
+		if total > 0 {
+			share = utils.FormatPercentage(float64(u.Bytes) * 100 / float64(total))
+		}
+		utils.PrintTableRow(colWidths, utils.BytesToHuman(u.Bytes), share, u.Path)
+	}
+	utils.PrintTableFooter(colWidths)
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+// ErrorReport is the structured output of a failed command (--output
+// json|yaml|csv)
+type ErrorReport struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Op      string `json:"op,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Help    string `json:"help,omitempty"`
+}
+
+// errorDocument wraps ErrorReport so JSON and YAML output tell a failure apart
+// from a result
+type errorDocument struct {
+	Error    ErrorReport `json:"error"`
+	Warnings []string    `json:"warnings,omitempty"`
+}
+
+// ReportError prints the error a command failed with and returns the exit
+// status. Scripts get the error code (see types.ErrorCode): as a document on
+// stdout with --output json|yaml|csv, or in the message with --quiet. The
+// exit status of commands run by vhdm is propagated without output.
+func ReportError(cmd *cobra.Command, err error) int {
+	var exitErr *types.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	report := ErrorReport{Code: types.ErrorCode(err), Message: err.Error()}
+	var vhdErr *types.VHDError
+	if errors.As(err, &vhdErr) {
+		report.Op, report.Path, report.Help = vhdErr.Op, vhdErr.Path, vhdErr.Help
+	}
+
+	// The context is missing when flags failed to parse
+	var ctx *AppContext
+	if cmd != nil && cmd.Context() != nil {
+		ctx = appContext(cmd)
+	}
+	switch {
+	case ctx != nil && ctx.Config.Output == "csv":
+		ctx.Logger.FlushWarnings()
+		if printStructured(ctx, report) == nil {
+			return 1
+		}
+	case ctx != nil && structuredOutput(ctx):
+		if printStructured(ctx, errorDocument{Error: report, Warnings: ctx.Logger.TakeWarnings()}) == nil {
+			return 1
+		}
+	case ctx != nil:
+		// Warnings collected before the failure come first
+		ctx.Logger.FlushWarnings()
+	}
+	if ctx != nil && ctx.Config.Quiet {
+		fmt.Fprintf(os.Stderr, "Error [%s]: %s\n", report.Code, report.Message)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: %s\n", report.Message)
+	if report.Help != "" {
+		fmt.Fprintf(os.Stderr, "\n%s\n", report.Help)
+	}
+	return 1
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+)
+
+func newExecCmd() *cobra.Command {
+	var (
+		vhdPath  string
+		readOnly bool
+	)
+	cmd := &cobra.Command{
+		Use:   "exec --vhd-path <path> -- <command> [args...]",
+		Short: "Run a command with a VHD temporarily mounted",
+		Long: `Mount a VHD to a temporary directory, run a command, and always unmount and
+detach the VHD afterwards (only the steps vhdm performed are undone, so a VHD
+that was already mounted stays mounted).
+
+The command runs with these environment variables:
+  VHDM_MOUNT     Mount point of the VHD
+  VHDM_VHD_PATH  VHD file path
+  VHDM_UUID      Filesystem UUID
+
+vhdm exits with the command's exit status. Interrupt and terminate signals are
+forwarded to the command and cleanup still runs.`,
+		Example: `  vhdm exec --vhd-path C:/VMs/data.vhdx -- ls -la
+  vhdm exec --vhd-path C:/VMs/data.vhdx --read-only -- sh -c 'tar -C "$VHDM_MOUNT" -czf /tmp/backup.tgz .'`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExec(appContext(cmd), vhdPath, readOnly, args)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().BoolVar(&readOnly, "read-only", false, "Mount the VHD read-only")
+	cmd.MarkFlagRequired("vhd-path")
+	// Everything after the command name belongs to the command
+	cmd.Flags().SetInterspersed(false)
+	return cmd
+}
+
+func runExec(ctx *AppContext, vhdPath string, readOnly bool, command []string) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "exec", Path: vhdPath, Err: err}
+	}
+
+	log.Debug("Exec operation starting: %v", command)
+
+	m, err := acquireTempMount(ctx, vhdPath, readOnly)
+	if err != nil {
+		return &types.VHDError{Op: "exec", Path: vhdPath, Err: err}
+	}
+	defer m.release(ctx)
+
+	log.Debug("VHD available at %s", m.MountPoint)
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"VHDM_MOUNT="+m.MountPoint,
+		"VHDM_VHD_PATH="+vhdPath,
+		"VHDM_UUID="+m.UUID,
+	)
+
+	return runChild(cmd)
+}
+
+// runChild runs cmd, forwarding interrupt and terminate signals to it so that
+// deferred cleanup in the caller always runs. A non-zero exit status is
+// returned as *types.ExitError.
+func runChild(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", cmd.Path, err)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code := exitErr.ExitCode()
+			if code < 0 {
+				code = 1 // Killed by a signal
+			}
+			return &types.ExitError{Code: code}
+		}
+		return fmt.Errorf("command failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newExportCmd() *cobra.Command {
+	var (
+		vhdPath string
+		to      string
+		force   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export VHD contents as a tarball",
+		Long: `Export the contents of a VHD into a compressed tar archive.
+
+If the VHD is not mounted, it is temporarily attached and mounted read-only,
+then unmounted and detached again after the export. The compression format is
+chosen from the archive extension (.tar.zst, .tar.gz, .tar.xz or .tar).
+
+The archive path may be a Linux path or a Windows path (C:/...).`,
+		Example: `  vhdm export --vhd-path C:/VMs/disk.vhdx --to data.tar.zst
+  vhdm export --vhd-path C:/VMs/disk.vhdx --to C:/Backups/data.tar.gz`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(appContext(cmd), vhdPath, to, force)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&to, "to", "", "Archive file to write (e.g., data.tar.zst)")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing archive")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func runExport(ctx *AppContext, vhdPath, to string, force bool) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "export", Path: vhdPath, Err: err}
+	}
+
+	log.Debug("Export operation starting")
+
+	archivePath := ctx.WSL.ConvertPath(to)
+	if ctx.WSL.FileExists(archivePath) && !force {
+		return fmt.Errorf("archive already exists: %s (use --force to overwrite)", to)
+	}
+
+	m, err := acquireTempMount(ctx, vhdPath, true)
+	if err != nil {
+		return &types.VHDError{Op: "export", Path: vhdPath, Err: err}
+	}
+	defer m.release(ctx)
+
+	log.Info("Exporting %s to %s (this may take a while)...", m.MountPoint, archivePath)
+	if err := ctx.WSL.CreateTarball(m.MountPoint, archivePath); err != nil {
+		return fmt.Errorf("failed to export: %w", err)
+	}
+
+	size, _ := ctx.WSL.FileSize(archivePath)
+
+	// Output
+	log.Success("VHD exported successfully")
+	return printResult(ctx, ExportResult{Path: vhdPath, UUID: m.UUID, Archive: archivePath, ArchiveSize: size})
+}
+
+// ExportResult is the outcome of 'vhdm export'
+type ExportResult struct {
+	Path        string `json:"path"`
+	UUID        string `json:"uuid"`
+	Archive     string `json:"archive"`
+	ArchiveSize int64  `json:"archiveSize"`
+}
+
+func (r ExportResult) table(utils.Theme) (string, [][2]string) {
+	return "Export Result", [][2]string{
+		{"Path", r.Path},
+		{"UUID", r.UUID},
+		{"Archive", r.Archive},
+		{"Archive Size", utils.BytesToHuman(r.ArchiveSize)},
+		{"Status", "exported"},
+	}
+}
+
+func (r ExportResult) quiet() quietLine {
+	return quietLine{Key: r.Path, UUID: r.UUID, State: "exported to " + r.Archive}
+}
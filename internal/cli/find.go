@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// findMatch is a single search hit inside a tracked VHD
+type findMatch struct {
+	VHDPath string `json:"vhdPath"`
+	Path    string `json:"path"` // Absolute path under the VHD's mount point
+}
+
+func newFindCmd() *cobra.Command {
+	var (
+		autoMount bool
+		maxDepth  int
+		limit     int
+	)
+	cmd := &cobra.Command{
+		Use:   "find <pattern>",
+		Short: "Find which tracked VHD contains matching files",
+		Long: `Search mounted tracked VHDs for files and directories whose name matches a
+glob pattern (case-insensitive) and report which VHD contains them.
+
+With --mount, tracked VHDs that are not mounted are temporarily mounted
+read-only for the search and restored to their previous state afterwards.`,
+		Example: `  vhdm find myproject
+  vhdm find '*.sql' --mount
+  vhdm find 'go.mod' --max-depth 3`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFind(appContext(cmd), args[0], autoMount, maxDepth, limit)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().BoolVar(&autoMount, "mount", false, "Temporarily mount unmounted VHDs read-only")
+	cmd.Flags().IntVar(&maxDepth, "max-depth", 0, "Maximum directory depth to search (0 for unlimited)")
+	cmd.Flags().IntVar(&limit, "limit", 20, "Maximum matches to show per VHD (0 for all)")
+	return cmd
+}
+
+func runFind(ctx *AppContext, pattern string, autoMount bool, maxDepth, limit int) error {
+	log := ctx.Logger
+
+	if err := checkListOutput(ctx, "find"); err != nil {
+		return err
+	}
+
+	if pattern == "" {
+		return fmt.Errorf("pattern cannot be empty")
+	}
+
+	log.Debug("Find operation starting for pattern: %s", pattern)
+
+	paths, err := ctx.Tracker.GetAllPaths()
+	if err != nil {
+		return fmt.Errorf("failed to get tracked VHDs: %w", err)
+	}
+
+	var matches []findMatch
+	searched := 0
+	for _, vhdPath := range paths {
+		root := ""
+		var m *tempMount
+
+		uuid, _ := ctx.Tracker.LookupUUIDByPath(vhdPath)
+		if uuid != "" {
+			root, _ = ctx.WSL.GetMountPoint(uuid)
+		}
+		if root == "" {
+			if !autoMount {
+				log.Debug("Skipping %s (not mounted)", vhdPath)
+				continue
+			}
+			m, err = acquireTempMount(ctx, vhdPath, true)
+			if err != nil {
+				log.Warn("Skipping %s: %v", vhdPath, err)
+				continue
+			}
+			root = m.MountPoint
+		}
+
+		log.Debug("Searching %s at %s", vhdPath, root)
+		found, err := ctx.WSL.FindFiles(root, pattern, maxDepth)
+		searched++
+		if err != nil {
+			log.Warn("Search failed in %s: %v", vhdPath, err)
+		}
+		if limit > 0 && len(found) > limit {
+			log.Info("%s: showing %d of %d matches", vhdPath, limit, len(found))
+			found = found[:limit]
+		}
+		for _, rel := range found {
+			display := filepath.Join(root, rel)
+			if m != nil {
+				// Temporary mount points are meaningless after release
+				display = rel
+			}
+			matches = append(matches, findMatch{VHDPath: vhdPath, Path: display})
+		}
+
+		if m != nil {
+			m.release(ctx)
+		}
+	}
+
+	// Output
+	if structuredOutput(ctx) {
+		return printStructured(ctx, nonNil(matches))
+	}
+	if ctx.Config.Quiet {
+		for _, match := range matches {
+			printQuiet(quietLine{Key: match.VHDPath, State: match.Path})
+		}
+		return nil
+	}
+
+	if searched == 0 {
+		log.Info("No mounted tracked VHDs to search (use --mount to search unmounted VHDs)")
+		return nil
+	}
+	if len(matches) == 0 {
+		log.Info("No matches for %q in %d VHD(s)", pattern, searched)
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Printf("Matches for %q\n", pattern)
+	fmt.Println()
+
+	colWidths := []int{40, 60}
+	utils.PrintTableHeader(colWidths, []string{"VHD", "Path"})
+	for _, match := range matches {
+		utils.PrintTableRow(colWidths, match.VHDPath, match.Path)
+	}
+	utils.PrintTableFooter(colWidths)
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newFlattenCmd() *cobra.Command {
+	var (
+		vhdPath  string
+		intoBase bool
+	)
+	cmd := &cobra.Command{
+		Use:   "flatten",
+		Short: "Turn a linked clone into a standalone VHD, or merge it into its base",
+		Long: `Turn a linked clone (see 'vhdm clone --linked') into a standalone VHD.
+
+By default the contents of the clone, as read through its base, are copied
+block by block into a new dynamic VHDX that replaces the clone file. The clone
+keeps its path and filesystem UUID, no longer depends on its base, and the
+base is released once no other linked clone uses it.
+
+With --into-base, the changes of the clone are merged into its base instead
+(diskpart merge), the clone file is deleted and its tracking entry removed.
+The base then holds the filesystem of the clone, including its UUID. This is
+refused while the base has other linked clones, which the merge would corrupt.
+
+The clone must be detached. Merging into the base asks for administrator
+rights through a UAC prompt.`,
+		Example: `  vhdm flatten --vhd-path C:/VMs/data-test.vhdx
+  vhdm flatten --vhd-path C:/VMs/data-test.vhdx --into-base`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFlatten(appContext(cmd), vhdPath, intoBase)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "Linked clone file path (Windows format)")
+	cmd.Flags().BoolVar(&intoBase, "into-base", false, "Merge the clone into its base and delete it")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runFlatten(ctx *AppContext, vhdPath string, intoBase bool) error {
+	log := ctx.Logger
+
+	entry, err := trackedEntry(ctx, "flatten", vhdPath)
+	if err != nil {
+		return err
+	}
+	if entry.BackingFile == "" {
+		return &types.VHDError{
+			Op:   "flatten",
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD is not a linked clone"),
+			Help: "Only VHDs created with 'vhdm clone --linked' can be flattened",
+		}
+	}
+	base := entry.BackingFile
+	if err := ensureNotReference(ctx, "flatten", vhdPath); err != nil {
+		return err
+	}
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	if !ctx.WSL.FileExists(wslPath) {
+		return &types.VHDError{Op: "flatten", Path: vhdPath, Err: types.ErrVHDNotFound}
+	}
+	if entry.UUID != "" {
+		if attached, _ := ctx.WSL.IsAttached(entry.UUID); attached {
+			return &types.VHDError{
+				Op:   "flatten",
+				Path: vhdPath,
+				Err:  fmt.Errorf("VHD is attached"),
+				Help: fmt.Sprintf("Detach it first with 'vhdm detach --vhd-path %s'", vhdPath),
+			}
+		}
+	}
+
+	lock, err := lockVHDOperation(ctx, "flatten", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := ensureNotInUseByWindows(ctx, "flatten", vhdPath); err != nil {
+		return err
+	}
+
+	log.Debug("Flatten operation starting")
+
+	if intoBase {
+		if err := mergeIntoBase(ctx, vhdPath, base, entry.UUID); err != nil {
+			return err
+		}
+		log.Success("Linked clone merged into %s", base)
+		return printResult(ctx, FlattenResult{Path: vhdPath, Base: base, IntoBase: true, UUID: entry.UUID})
+	}
+
+	if err := flattenClone(ctx, vhdPath); err != nil {
+		return &types.VHDError{Op: "flatten", Path: vhdPath, Err: err}
+	}
+	err = ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+		entry.BackingFile = ""
+	})
+	if err != nil {
+		log.Collect("Failed to save tracking info: %v", err)
+	}
+
+	// Output
+	log.Success("Linked clone flattened into a standalone VHD")
+	return printResult(ctx, FlattenResult{Path: vhdPath, Base: base, UUID: entry.UUID})
+}
+
+// flattenClone replaces the file of a detached linked clone with a standalone
+// VHD holding a block copy of its contents
+func flattenClone(ctx *AppContext, vhdPath string) error {
+	log := ctx.Logger
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	ext := path.Ext(vhdPath)
+	tmpPath := strings.TrimSuffix(vhdPath, ext) + "_flatten" + ext
+	tmpWSLPath := ctx.WSL.ConvertPath(tmpPath)
+	if ctx.WSL.FileExists(tmpWSLPath) {
+		return fmt.Errorf("temporary file already exists: %s", tmpPath)
+	}
+
+	var attached []string
+	cleanup := func() {
+		for _, p := range attached {
+			if err := ctx.WSL.DetachVHD(p); err != nil {
+				log.Warn("Failed to detach %s: %v", p, err)
+			}
+		}
+		attached = nil
+	}
+	defer cleanup()
+
+	log.Info("Attaching linked clone...")
+	srcDev, ok, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
+	if ok {
+		attached = append(attached, vhdPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to attach clone: %w", err)
+	}
+	size, err := ctx.WSL.GetDeviceSize(srcDev)
+	if err != nil || size <= 0 {
+		return fmt.Errorf("failed to read the size of /dev/%s", srcDev)
+	}
+
+	log.Info("Creating standalone VHD %s...", tmpPath)
+	if err := ctx.WSL.CreateVHD(tmpWSLPath, strconv.FormatInt(size, 10)); err != nil {
+		return fmt.Errorf("failed to create VHD: %w", err)
+	}
+	dstDev, ok, err := ctx.WSL.AttachVHDAndDetect(tmpPath)
+	if ok {
+		attached = append(attached, tmpPath)
+	}
+	if err != nil {
+		cleanup()
+		ctx.WSL.DeleteVHD(tmpWSLPath)
+		return fmt.Errorf("failed to attach new VHD: %w", err)
+	}
+
+	log.Info("Copying /dev/%s to /dev/%s (this may take a while)...", srcDev, dstDev)
+	if err := ctx.WSL.CopyDevice(srcDev, dstDev); err != nil {
+		cleanup()
+		ctx.WSL.DeleteVHD(tmpWSLPath)
+		return err
+	}
+
+	// Both now hold the same filesystem UUID; detach before anything mounts it
+	cleanup()
+	markDetached(ctx, vhdPath)
+	if err := ctx.WSL.RenameFile(tmpWSLPath, wslPath); err != nil {
+		ctx.WSL.DeleteVHD(tmpWSLPath)
+		return fmt.Errorf("failed to replace the clone: %w", err)
+	}
+	return nil
+}
+
+// mergeIntoBase merges a detached linked clone into its base, then deletes
+// the clone; the base takes over the filesystem UUID of the clone
+func mergeIntoBase(ctx *AppContext, vhdPath, base, uuid string) error {
+	log := ctx.Logger
+
+	for _, clone := range linkedClones(ctx, base) {
+		if !samePath(ctx, clone, vhdPath) {
+			return &types.VHDError{
+				Op:   "flatten",
+				Path: vhdPath,
+				Err:  fmt.Errorf("base %s has other linked clones: %s", base, clone),
+				Help: "Merging would corrupt them. Flatten or delete them first, or flatten this clone without --into-base",
+			}
+		}
+	}
+	if err := ensureNotInUseByWindows(ctx, "flatten", base); err != nil {
+		return err
+	}
+
+	log.Info("Merging %s into %s...", vhdPath, base)
+	if err := ctx.WSL.MergeDifferencingVHD(vhdPath); err != nil {
+		return &types.VHDError{Op: "flatten", Path: vhdPath, Err: err}
+	}
+
+	if err := ctx.WSL.DeleteVHD(ctx.WSL.ConvertPath(vhdPath)); err != nil {
+		log.Warn("Failed to delete the merged clone %s: %v", vhdPath, err)
+	}
+	ctx.Tracker.RemoveMapping(vhdPath)
+
+	if _, err := ctx.Tracker.GetEntry(base); err == nil {
+		err := ctx.Tracker.Update(base, func(entry *types.TrackingEntry) {
+			entry.UUID = uuid
+		})
+		if err != nil {
+			log.Collect("Failed to save tracking info: %v", err)
+		}
+	} else if err := ctx.Tracker.SaveMapping(base, uuid, "", ""); err != nil {
+		log.Collect("Failed to save tracking info: %v", err)
+	}
+	if uuid != "" {
+		// The ID file in the merged filesystem names the clone
+		createIDFile(ctx, base)
+	}
+	return nil
+}
+
+// FlattenResult is the outcome of 'vhdm flatten'
+type FlattenResult struct {
+	Path     string `json:"path"`
+	Base     string `json:"base"`
+	IntoBase bool   `json:"intoBase"` // Merged into the base, which replaces the clone
+	UUID     string `json:"uuid,omitempty"`
+}
+
+func (r FlattenResult) table(utils.Theme) (string, [][2]string) {
+	status := "standalone"
+	if r.IntoBase {
+		status = "merged into base (clone deleted)"
+	}
+	pairs := [][2]string{
+		{"Path", r.Path},
+		{"Base", r.Base},
+	}
+	if r.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", r.UUID})
+	}
+	return "Flatten Result", append(pairs, [2]string{"Status", status})
+}
+
+func (r FlattenResult) quiet() quietLine {
+	if r.IntoBase {
+		return quietLine{Key: r.Path, UUID: r.UUID, State: "merged into " + r.Base}
+	}
+	return quietLine{Key: r.Path, UUID: r.UUID, State: "flattened"}
+}
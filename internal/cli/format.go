@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -15,27 +16,35 @@ func newFormatCmd() *cobra.Command {
 	var (
 		devName string
 		fsType  string
+		unpin   bool
 	)
 	cmd := &cobra.Command{
 		Use:   "format",
 		Short: "Format a VHD with a filesystem",
 		Long: `Format an attached VHD with a filesystem.
 
-WARNING: This will erase all data on the device!`,
+WARNING: This will erase all data on the device!
+
+Devices of pinned VHDs (see 'vhdm pin') are only formatted with --unpin.
+
+In a terminal, reformatting a disk of VHDM_CONFIRM_NAME_ABOVE (default 100G)
+or more also requires typing the VHD file name (or device name), even with --yes.`,
 		Example: `  vhdm format --dev-name sde --type ext4
   vhdm format --dev-name sde --type xfs`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runFormat(devName, fsType)
+			return runFormat(appContext(cmd), devName, fsType, unpin)
 		},
+		Annotations: map[string]string{annotationSudo: "true"},
 	}
 	cmd.Flags().StringVar(&devName, "dev-name", "", "Device name (e.g., sde)")
 	cmd.Flags().StringVar(&fsType, "type", "ext4", "Filesystem type")
+	cmd.Flags().BoolVar(&unpin, "unpin", false, "Remove the pin of a pinned VHD and format it")
 	cmd.MarkFlagRequired("dev-name")
+	cmd.RegisterFlagCompletionFunc("type", completeFilesystemTypes)
 	return cmd
 }
 
-func runFormat(devName, fsType string) error {
-	ctx := getContext()
+func runFormat(ctx *AppContext, devName, fsType string, unpin bool) error {
 	log := ctx.Logger
 
 	// Validate
@@ -56,6 +65,23 @@ func runFormat(devName, fsType string) error {
 		return fmt.Errorf("device /dev/%s not found", devName)
 	}
 
+	path, _ := ctx.Tracker.LookupPathByDevName(devName)
+	if path != "" {
+		if err := checkPinned(ctx, "format", path, unpin); err != nil {
+			return err
+		}
+	}
+
+	lockKey := path
+	if lockKey == "" {
+		lockKey = "/dev/" + devName
+	}
+	lock, err := lockVHDOperation(ctx, "format", lockKey)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	// Check if already formatted
 	isFormatted, _ := ctx.WSL.IsFormatted(devName)
 	if isFormatted && !ctx.Config.Yes {
@@ -63,6 +89,19 @@ func runFormat(devName, fsType string) error {
 		log.Warn("Run with --yes to confirm, or use 'vhdm format --dev-name %s --type %s -y'", devName, fsType)
 		return fmt.Errorf("operation cancelled")
 	}
+	if isFormatted {
+		name := devName
+		if path != "" {
+			name = filepath.Base(path)
+		}
+		size, _ := ctx.WSL.GetDeviceSize(devName)
+		if err := confirmByName(ctx, "format", name, size); err != nil {
+			return err
+		}
+	}
+	if path != "" {
+		releasePin(ctx, path)
+	}
 
 	// Format
 	log.Info("Formatting /dev/%s with %s...", devName, fsType)
@@ -71,35 +110,48 @@ func runFormat(devName, fsType string) error {
 		return fmt.Errorf("format failed: %w", err)
 	}
 
-	// Update tracking if we can find the path
-	path, _ := ctx.Tracker.LookupPathByDevName(devName)
+	// Update tracking and write the ID file if we can find the path
 	if path != "" {
 		ctx.Tracker.SaveMapping(path, uuid, "", devName)
+		createIDFile(ctx, path)
 	}
 
 	// Output
-	if ctx.Config.Quiet {
-		fmt.Printf("/dev/%s: formatted (%s)\n", devName, uuid)
-		return nil
-	}
-
 	log.Success("Device formatted successfully")
-
-	pairs := [][2]string{
-		{"Device", "/dev/" + devName},
-		{"Filesystem", fsType},
-		{"UUID", uuid},
-		{"Status", "formatted"},
+	res := FormatResult{Path: path, DeviceName: devName, Filesystem: fsType, UUID: uuid}
+	if err := printResult(ctx, res); err != nil {
+		return err
 	}
-	if path != "" {
-		pairs = append([][2]string{{"Path", path}}, pairs...)
-	}
-
-	utils.KeyValueTable("Format Result", pairs, 14, 50)
 
-	fmt.Println()
+	log.Info("")
 	log.Info("To mount this VHD, run:")
 	log.Info("  vhdm mount --uuid %s --mount-point /mnt/your-mount-point", uuid)
 
 	return nil
 }
+
+// FormatResult is the outcome of 'vhdm format'
+type FormatResult struct {
+	Path       string `json:"path,omitempty"`
+	DeviceName string `json:"deviceName"`
+	Filesystem string `json:"filesystem"`
+	UUID       string `json:"uuid"`
+}
+
+func (r FormatResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{}
+	if r.Path != "" {
+		pairs = append(pairs, [2]string{"Path", r.Path})
+	}
+	pairs = appendDevice(pairs, r.DeviceName)
+	pairs = append(pairs,
+		[2]string{"Filesystem", r.Filesystem},
+		[2]string{"UUID", r.UUID},
+		[2]string{"Status", "formatted"},
+	)
+	return "Format Result", pairs
+}
+
+func (r FormatResult) quiet() quietLine {
+	return quietLine{Key: "/dev/" + r.DeviceName, UUID: r.UUID, State: "formatted"}
+}
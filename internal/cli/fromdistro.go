@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+)
+
+func newFromDistroCmd() *cobra.Command {
+	var (
+		distro     string
+		vhdPath    string
+		mountPoint string
+		size       string
+		fsType     string
+		keepExport bool
+	)
+	cmd := &cobra.Command{
+		Use:   "from-distro",
+		Short: "Create a VHD from a WSL distribution export",
+		Long: `Export a WSL distribution with 'wsl.exe --export' and extract it into a new,
+formatted VHD that can be mounted independently of the distribution.
+
+The export is written next to the VHD as <name>.export.tar and removed once
+imported (unless --keep-export). Without --size, the VHD is sized to fit the
+export plus headroom. Exporting a running distribution captures it as-is;
+stop it first (wsl.exe --terminate <distro>) for a consistent copy.`,
+		Example: `  vhdm from-distro --distro Ubuntu --vhd-path C:/VMs/ubuntu-data.vhdx --mount-point /mnt/ubuntu
+  vhdm from-distro --distro Debian --vhd-path C:/VMs/debian.vhdx --mount-point /mnt/debian --size 20G`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFromDistro(appContext(cmd), distro, vhdPath, mountPoint, size, fsType, keepExport)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&distro, "distro", "", "WSL distribution name")
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path to create (Windows format)")
+	cmd.Flags().StringVar(&mountPoint, "mount-point", "", "Mount point path")
+	cmd.Flags().StringVar(&size, "size", "", "VHD size (default: sized to fit)")
+	cmd.Flags().StringVar(&fsType, "type", "", "Filesystem type (default: ext4)")
+	cmd.Flags().BoolVar(&keepExport, "keep-export", false, "Keep the exported tar file")
+	cmd.MarkFlagRequired("distro")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("mount-point")
+	cmd.RegisterFlagCompletionFunc("size", completeSizes)
+	cmd.RegisterFlagCompletionFunc("type", completeFilesystemTypes)
+	return cmd
+}
+
+func runFromDistro(ctx *AppContext, distro, vhdPath, mountPoint, size, fsType string, keepExport bool) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "from-distro", Path: vhdPath, Err: err}
+	}
+	if distro == "" {
+		return &types.VHDError{Op: "from-distro", Err: fmt.Errorf("distribution name cannot be empty")}
+	}
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	if ctx.WSL.FileExists(wslPath) {
+		return &types.VHDError{
+			Op:   "from-distro",
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD file already exists"),
+			Help: "from-distro creates a fresh VHD. Use 'vhdm import' to extract into an existing VHD",
+		}
+	}
+
+	log.Debug("From-distro operation starting")
+
+	dist, err := ctx.WSL.FindDistribution(distro)
+	if err != nil {
+		return &types.VHDError{Op: "from-distro", Err: err, Help: "List distributions with: wsl.exe --list --verbose"}
+	}
+	if strings.EqualFold(dist.Name, os.Getenv("WSL_DISTRO_NAME")) {
+		log.Warn("Exporting the running distribution %s; files changing during export may be inconsistent", dist.Name)
+	}
+
+	exportPath := strings.TrimSuffix(vhdPath, filepath.Ext(vhdPath)) + ".export.tar"
+	exportWSLPath := ctx.WSL.ConvertPath(exportPath)
+	if ctx.WSL.FileExists(exportWSLPath) {
+		return &types.VHDError{Op: "from-distro", Path: exportPath, Err: fmt.Errorf("export file already exists")}
+	}
+
+	log.Info("Exporting distribution %s to %s (this may take a while)...", dist.Name, exportPath)
+	if err := ctx.WSL.ExportDistribution(dist.Name, exportPath); err != nil {
+		os.Remove(exportWSLPath)
+		return fmt.Errorf("failed to export distribution: %w", err)
+	}
+	if !keepExport {
+		defer func() {
+			if err := os.Remove(exportWSLPath); err != nil {
+				log.Warn("Failed to remove export %s: %v", exportPath, err)
+			}
+		}()
+	}
+
+	return runImport(ctx, vhdPath, exportWSLPath, mountPoint, size, fsType)
+}
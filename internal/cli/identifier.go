@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
+)
+
+// uuidFlagUsage is the help of the --uuid flags that accept any identifier
+const uuidFlagUsage = "VHD UUID, LABEL=<label> or PARTUUID=<partuuid> (detected when bare)"
+
+// resolveUUID resolves the value of a --uuid flag to a filesystem UUID. The
+// value is a UUID, or LABEL=, PARTUUID= or UUID= as in fstab. A bare value is
+// taken as a UUID when a disk or tracked VHD has it, and otherwise looked up
+// as a PARTUUID and then as a label, first among the attached disks and then
+// among the tracked VHDs, so detached VHDs are found by what was last seen.
+func resolveUUID(ctx *AppContext, op, id string) (string, error) {
+	kind, value, err := validation.ParseIdentifier(id)
+	if err != nil {
+		return "", &types.VHDError{Op: op, Err: err}
+	}
+	if kind == validation.IdentifierUUID {
+		return value, nil
+	}
+
+	devices, err := ctx.WSL.GetBlockDevicesWithInfo()
+	if err != nil {
+		return "", &types.VHDError{Op: op, Err: fmt.Errorf("failed to list block devices: %w", err)}
+	}
+	var entries []types.TrackingEntry
+	if paths, err := ctx.Tracker.GetAllPaths(); err == nil {
+		for _, path := range paths {
+			if entry, err := ctx.Tracker.GetEntry(path); err == nil {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	kinds := []string{kind}
+	if kind == "" {
+		if validation.ValidateUUID(value) == nil {
+			if hasUUID(devices, entries, value) {
+				return value, nil
+			}
+		}
+		kinds = []string{validation.IdentifierPartUUID, validation.IdentifierLabel}
+	}
+
+	for _, k := range kinds {
+		uuids := matchIdentifier(devices, entries, k, value)
+		switch {
+		case len(uuids) == 1:
+			ctx.Logger.Debug("Resolved %s=%s to UUID %s", k, value, uuids[0])
+			return uuids[0], nil
+		case len(uuids) > 1:
+			return "", &types.VHDError{
+				Op:   op,
+				Err:  fmt.Errorf("%s=%s matches several disks: %s", k, value, strings.Join(uuids, ", ")),
+				Help: "Use the UUID of the disk instead",
+			}
+		}
+	}
+
+	if kind == "" && validation.ValidateUUID(value) == nil {
+		// Unknown UUID: let the command report it as not found
+		return value, nil
+	}
+	if kind == "" {
+		kind = "label or PARTUUID"
+	}
+	return "", &types.VHDError{
+		Op:   op,
+		Err:  fmt.Errorf("no attached or tracked disk with %s %s", kind, value),
+		Help: "Run 'vhdm status --all' to see the attached disks",
+	}
+}
+
+// hasUUID reports whether an attached disk or a tracked VHD has a UUID
+func hasUUID(devices []wsl.BlockDevice, entries []types.TrackingEntry, uuid string) bool {
+	for _, dev := range devices {
+		if strings.EqualFold(dev.UUID, uuid) {
+			return true
+		}
+	}
+	for _, entry := range entries {
+		if strings.EqualFold(entry.UUID, uuid) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIdentifier returns the distinct filesystem UUIDs of the attached disks
+// and tracked VHDs whose label or PARTUUID (kind) is value. Labels are case
+// sensitive, PARTUUIDs are not.
+func matchIdentifier(devices []wsl.BlockDevice, entries []types.TrackingEntry, kind, value string) []string {
+	equal := func(a string) bool {
+		if kind == validation.IdentifierLabel {
+			return a == value
+		}
+		return a != "" && strings.EqualFold(a, value)
+	}
+
+	var uuids []string
+	add := func(uuid string) {
+		for _, u := range uuids {
+			if strings.EqualFold(u, uuid) {
+				return
+			}
+		}
+		uuids = append(uuids, uuid)
+	}
+	for _, dev := range devices {
+		id := dev.Label
+		if kind == validation.IdentifierPartUUID {
+			id = dev.PartUUID
+		}
+		if dev.UUID != "" && id != "" && equal(id) {
+			add(dev.UUID)
+		}
+	}
+	for _, entry := range entries {
+		id := entry.Label
+		if kind == validation.IdentifierPartUUID {
+			id = entry.PartUUID
+		}
+		if entry.UUID != "" && id != "" && equal(id) {
+			add(entry.UUID)
+		}
+	}
+	return uuids
+}
+
+// recordIdentifiers stores the label and PARTUUID of the attached disk with
+// uuid in the tracking entry of path, when they changed
+func recordIdentifiers(ctx *AppContext, path, uuid string) {
+	if path == "" || uuid == "" {
+		return
+	}
+	devices, err := ctx.WSL.GetBlockDevicesWithInfo()
+	if err != nil {
+		return
+	}
+	if dev := deviceByUUID(devices, uuid); dev != nil {
+		recordDeviceIdentifiers(ctx, path, *dev)
+	}
+}
+
+// recordDeviceIdentifiers stores the label and PARTUUID of an attached device
+// in the tracking entry of path, when they changed
+func recordDeviceIdentifiers(ctx *AppContext, path string, dev wsl.BlockDevice) {
+	entry, err := ctx.Tracker.GetEntry(path)
+	if err != nil || (entry.Label == dev.Label && entry.PartUUID == dev.PartUUID) {
+		return
+	}
+	if err := ctx.Tracker.SetIdentifiers(path, dev.Label, dev.PartUUID); err != nil {
+		ctx.Logger.Debug("Failed to record identifiers of %s: %v", path, err)
+	}
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// createIDFile writes the ID file (.vhdm.json) into the freshly formatted
+// filesystem of a VHD, mounting it at a temporary directory meanwhile. A
+// failure only costs the later cross-checks, so it is a warning.
+func createIDFile(ctx *AppContext, vhdPath string) {
+	m, err := acquireTempMount(ctx, vhdPath, false)
+	if err != nil {
+		ctx.Logger.Warn("Failed to write the ID file of %s: %v", vhdPath, err)
+		return
+	}
+	defer m.release(ctx)
+	if err := stampIDFile(ctx, vhdPath, m.UUID, m.MountPoint); err != nil {
+		ctx.Logger.Warn("Failed to write the ID file of %s: %v", vhdPath, err)
+	}
+}
+
+// stampIDFile writes the ID file of the VHD at vhdPath into its filesystem
+// mounted at mountPoint. The creation time of an ID file already naming the
+// VHD is kept, so it stays the time of the format.
+func stampIDFile(ctx *AppContext, vhdPath, uuid, mountPoint string) error {
+	id := types.IDFile{
+		Path:    vhdPath,
+		Name:    utils.VHDName(vhdPath),
+		UUID:    uuid,
+		Created: time.Now().UTC().Format(time.RFC3339),
+	}
+	if old, err := ctx.WSL.ReadIDFile(mountPoint); err == nil && old != nil && samePath(ctx, old.Path, vhdPath) && old.Created != "" {
+		id.Created = old.Created
+	}
+	ctx.Logger.Debug("Writing ID file to %s", mountPoint)
+	return ctx.WSL.WriteIDFile(mountPoint, id)
+}
+
+// checkIDFile cross-checks the ID file of a mounted VHD against tracking and
+// warns when it names another VHD file or filesystem UUID, which happens when
+// VHD files were moved, copied or swapped outside vhdm. Filesystems without an
+// ID file pass.
+func checkIDFile(ctx *AppContext, vhdPath, uuid, mountPoint string) {
+	id, err := ctx.WSL.ReadIDFile(mountPoint)
+	if err != nil {
+		ctx.Logger.Warn("Cannot read the ID file at %s: %v", mountPoint, err)
+		return
+	}
+	if id == nil {
+		return
+	}
+
+	mismatch := false
+	if vhdPath != "" && id.Path != "" && !samePath(ctx, id.Path, vhdPath) {
+		ctx.Logger.Warn("The filesystem mounted at %s was created in %s, but is tracked as %s", mountPoint, id.Path, vhdPath)
+		mismatch = true
+	}
+	if id.UUID != "" && !strings.EqualFold(id.UUID, uuid) {
+		ctx.Logger.Warn("The ID file at %s names UUID %s, but the filesystem has UUID %s", mountPoint, id.UUID, uuid)
+		mismatch = true
+	}
+	if mismatch {
+		if vhdPath == "" {
+			vhdPath = "<path>"
+		}
+		ctx.Logger.Warn("Check that the right VHD is mounted. If it was moved or copied on purpose, run 'vhdm mount-check stamp --vhd-path %s'", vhdPath)
+	}
+}
+
+// samePath reports whether two Windows paths name the same VHD file
+func samePath(ctx *AppContext, a, b string) bool {
+	return strings.EqualFold(ctx.WSL.ConvertPath(a), ctx.WSL.ConvertPath(b))
+}
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// importHeadroomPercent is the extra space added on top of the archive contents
+// when sizing a new VHD for import (filesystem metadata, journal, free space)
+const importHeadroomPercent = 25
+
+// importMinSize is the smallest VHD created by import
+const importMinSize = 64 * utils.MB
+
+func newImportCmd() *cobra.Command {
+	var (
+		vhdPath    string
+		from       string
+		mountPoint string
+		size       string
+		fsType     string
+	)
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import a tarball into a VHD",
+		Long: `Extract a tar archive into a VHD and mount it (the inverse of export).
+
+If the VHD file does not exist, it is created, attached and formatted first,
+and removed again when the import fails. Without --size, the new VHD is sized to fit the archive contents plus headroom.
+The compression format is chosen from the archive extension (.tar.zst, .tar.gz,
+.tar.xz or .tar).`,
+		Example: `  vhdm import --vhd-path C:/VMs/disk.vhdx --from data.tar.zst --mount-point /mnt/data
+  vhdm import --vhd-path C:/VMs/disk.vhdx --from data.tar.zst --mount-point /mnt/data --size 20G --type xfs`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImport(appContext(cmd), vhdPath, from, mountPoint, size, fsType)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&from, "from", "", "Archive file to import (e.g., data.tar.zst)")
+	cmd.Flags().StringVar(&mountPoint, "mount-point", "", "Mount point path")
+	cmd.Flags().StringVar(&size, "size", "", "Size for a new VHD (default: sized to fit)")
+	cmd.Flags().StringVar(&fsType, "type", "", "Filesystem type for a new VHD (default: ext4)")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("from")
+	cmd.MarkFlagRequired("mount-point")
+	cmd.RegisterFlagCompletionFunc("size", completeSizes)
+	cmd.RegisterFlagCompletionFunc("type", completeFilesystemTypes)
+	return cmd
+}
+
+func runImport(ctx *AppContext, vhdPath, from, mountPoint, size, fsType string) error {
+	log := ctx.Logger
+
+	if fsType == "" {
+		fsType = ctx.Config.DefaultFSType
+	}
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "import", Path: vhdPath, Err: err}
+	}
+	mountPoint = expandMountPoint(ctx, mountPoint, vhdPath, "")
+	if err := validation.ValidateMountPoint(mountPoint); err != nil {
+		return &types.VHDError{Op: "import", Err: err}
+	}
+	if size != "" {
+		if err := validation.ValidateSizeString(size); err != nil {
+			return &types.VHDError{Op: "import", Err: err}
+		}
+	}
+	if err := validation.ValidateFilesystemType(fsType); err != nil {
+		return &types.VHDError{Op: "import", Err: err}
+	}
+
+	if err := ensureNotReference(ctx, "import", vhdPath); err != nil {
+		return err
+	}
+
+	log.Debug("Import operation starting")
+
+	archivePath := ctx.WSL.ConvertPath(from)
+	if !ctx.WSL.FileExists(archivePath) {
+		return fmt.Errorf("archive not found: %s", from)
+	}
+
+	// Create and format the VHD if missing
+	created := false
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	var m *tempMount
+	// discard removes the VHD this import created when a later step fails,
+	// so no half-filled VHD and tracking entry are left behind. A VHD that
+	// existed before is left as is.
+	discard := func() {
+		if !created {
+			return
+		}
+		log.Warn("Removing the VHD created for the import: %s", vhdPath)
+		if m != nil && m.MountPoint != "" {
+			if err := ctx.WSL.Unmount(m.MountPoint); err != nil {
+				log.Warn("Failed to unmount %s, the VHD is kept: %v", m.MountPoint, err)
+				return
+			}
+		}
+		if err := ctx.WSL.DetachVHD(vhdPath); err != nil && !types.IsNotAttached(err) {
+			log.Warn("Failed to detach %s, the VHD is kept: %v", vhdPath, err)
+			return
+		}
+		if err := ctx.WSL.DeleteVHD(wslPath); err != nil {
+			log.Warn("Failed to delete %s: %v", vhdPath, err)
+		}
+		ctx.Tracker.RemoveMapping(vhdPath)
+	}
+
+	if !ctx.WSL.FileExists(wslPath) {
+		if size == "" {
+			log.Info("Measuring archive contents...")
+			contentSize, err := ctx.WSL.TarballSize(archivePath)
+			if err != nil {
+				return fmt.Errorf("failed to measure archive: %w", err)
+			}
+			size = importSizeFor(contentSize)
+			log.Debug("Archive contents: %s, new VHD size: %s", utils.BytesToHuman(contentSize), size)
+		}
+
+		log.Info("Creating VHD: %s (%s)...", vhdPath, size)
+		if err := ctx.WSL.CreateVHD(wslPath, size); err != nil {
+			return fmt.Errorf("failed to create VHD: %w", err)
+		}
+		created = true
+
+		devName, attached, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
+		if err != nil {
+			discard()
+			if !attached {
+				return fmt.Errorf("failed to attach: %w", err)
+			}
+			return fmt.Errorf("failed to detect device: %w", err)
+		}
+
+		log.Info("Formatting with %s...", fsType)
+		uuid, err := ctx.WSL.Format(devName, fsType)
+		if err != nil {
+			discard()
+			return fmt.Errorf("failed to format: %w", err)
+		}
+		if err := ctx.Tracker.SaveMapping(vhdPath, uuid, "", devName); err != nil {
+			log.Collect("Failed to save tracking info: %v", err)
+		}
+		log.Success("VHD created and formatted (UUID: %s)", uuid)
+	}
+
+	m, err := acquireMount(ctx, vhdPath, mountPoint, "")
+	if err != nil {
+		discard()
+		return &types.VHDError{Op: "import", Path: vhdPath, Err: err}
+	}
+
+	log.Info("Extracting %s to %s (this may take a while)...", archivePath, m.MountPoint)
+	if err := ctx.WSL.ExtractTarball(archivePath, m.MountPoint); err != nil {
+		discard()
+		return fmt.Errorf("failed to import: %w", err)
+	}
+	// The archive may carry the ID file of the VHD it was exported from
+	if err := stampIDFile(ctx, vhdPath, m.UUID, m.MountPoint); err != nil {
+		log.Warn("Failed to write the ID file: %v", err)
+	}
+
+	// Update tracking
+	if err := ctx.Tracker.SaveMapping(vhdPath, m.UUID, m.MountPoint, m.DeviceName); err != nil {
+		log.Collect("Failed to save tracking info: %v", err)
+	}
+
+	// Output
+	log.Success("Archive imported successfully")
+	return printResult(ctx, ImportResult{
+		Path:       vhdPath,
+		UUID:       m.UUID,
+		DeviceName: m.DeviceName,
+		Archive:    archivePath,
+		MountPoint: m.MountPoint,
+		Created:    created,
+	})
+}
+
+// ImportResult is the outcome of 'vhdm import'
+type ImportResult struct {
+	Path       string `json:"path"`
+	UUID       string `json:"uuid"`
+	DeviceName string `json:"deviceName,omitempty"`
+	Archive    string `json:"archive"`
+	MountPoint string `json:"mountPoint"`
+	Created    bool   `json:"created"` // The VHD was created for the import
+}
+
+func (r ImportResult) table(utils.Theme) (string, [][2]string) {
+	status := "imported and mounted"
+	if r.Created {
+		status = "created, imported and mounted"
+	}
+	pairs := appendDevice([][2]string{
+		{"Path", r.Path},
+		{"UUID", r.UUID},
+	}, r.DeviceName)
+	pairs = append(pairs,
+		[2]string{"Archive", r.Archive},
+		[2]string{"Mount Point", r.MountPoint},
+		[2]string{"Status", status},
+	)
+	return "Import Result", pairs
+}
+
+func (r ImportResult) quiet() quietLine {
+	return quietLine{Key: r.Path, UUID: r.UUID, State: "imported, mounted at " + r.MountPoint}
+}
+
+// importSizeFor returns a VHD size string (in MB) large enough to hold
+// contentBytes plus headroom
+func importSizeFor(contentBytes int64) string {
+	size := contentBytes + contentBytes*importHeadroomPercent/100
+	if size < importMinSize {
+		size = importMinSize
+	}
+	mb := (size + utils.MB - 1) / utils.MB
+	return fmt.Sprintf("%dM", mb)
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newInfoCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:   "info",
+		Short: "Show the image details of a VHD file",
+		Long: `Show the format, virtual size, size on disk and backing file of a VHD file
+(from 'qemu-img info') together with its attach and mount state.
+
+The size on disk is what the image takes on the Windows drive: a dynamic VHDX
+only grows as data is written, up to its virtual size. 'vhdm compact' gives
+space freed inside the filesystem back to Windows.
+
+The VHD does not need to be tracked; the image is read in shared mode, so
+attached VHDs work too.`,
+		Example: `  vhdm info --vhd-path C:/VMs/data.vhdx
+  vhdm info --vhd-path C:/VMs/data.vhdx --output json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInfo(appContext(cmd), vhdPath)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runInfo(ctx *AppContext, vhdPath string) error {
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "info", Path: vhdPath, Err: err}
+	}
+
+	status := getVHDStatus(ctx, vhdPath)
+	switch status.State {
+	case types.StateNotFound:
+		return &types.VHDError{Op: "info", Path: vhdPath, Err: types.ErrVHDNotFound}
+	case types.StateArchived:
+		return &types.VHDError{
+			Op:   "info",
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD is archived to %s", vhdPath+wsl.ArchiveExt),
+			Help: fmt.Sprintf("Restore it with 'vhdm unarchive --vhd-path %s'", vhdPath),
+		}
+	}
+
+	img, err := ctx.WSL.GetImageInfo(ctx.WSL.ConvertPath(vhdPath))
+	if err != nil {
+		return &types.VHDError{Op: "info", Path: vhdPath, Err: err}
+	}
+
+	res := InfoResult{
+		Path:        vhdPath,
+		Format:      img.Format,
+		VirtualSize: img.VirtualSize,
+		ActualSize:  img.ActualSize,
+		BackingFile: img.BackingFile,
+		State:       status.State,
+		UUID:        status.UUID,
+		DeviceName:  status.DeviceName,
+	}
+	if status.MountPoint != "" {
+		res.MountPoints = strings.Split(status.MountPoint, ",")
+	}
+	return printResult(ctx, res)
+}
+
+// InfoResult is the outcome of 'vhdm info'
+type InfoResult struct {
+	Path        string         `json:"path"`
+	Format      string         `json:"format"`
+	VirtualSize int64          `json:"virtualSize"`
+	ActualSize  int64          `json:"actualSize"` // Bytes taken on the Windows drive
+	BackingFile string         `json:"backingFile,omitempty"`
+	State       types.VHDState `json:"state"`
+	UUID        string         `json:"uuid,omitempty"`
+	DeviceName  string         `json:"deviceName,omitempty"`
+	MountPoints []string       `json:"mountPoints,omitempty"`
+}
+
+func (r InfoResult) table(t utils.Theme) (string, [][2]string) {
+	actual := utils.BytesToHuman(r.ActualSize)
+	if r.VirtualSize > 0 {
+		actual += fmt.Sprintf(" (%.0f%% of virtual)", float64(r.ActualSize)*100/float64(r.VirtualSize))
+	}
+	pairs := [][2]string{
+		{"Path", r.Path},
+		{"Format", r.Format},
+		{"Virtual Size", utils.BytesToHuman(r.VirtualSize)},
+		{"Size on Disk", actual},
+	}
+	if r.BackingFile != "" {
+		pairs = append(pairs, [2]string{"Backing File", r.BackingFile})
+	}
+	if r.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", r.UUID})
+	}
+	pairs = appendDevice(pairs, r.DeviceName)
+	if len(r.MountPoints) > 0 {
+		pairs = append(pairs, [2]string{"Mount Point", strings.Join(r.MountPoints, ", ")})
+	}
+	pairs = append(pairs, [2]string{"Status", colorizeStatus(t, string(r.State))})
+	return "VHD Info", pairs
+}
+
+func (r InfoResult) quiet() quietLine {
+	return quietLine{Key: r.Path, UUID: r.UUID, State: fmt.Sprintf("%s, %s, %s of %s on disk", r.State, r.Format,
+		utils.BytesToHuman(r.ActualSize), utils.BytesToHuman(r.VirtualSize))}
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// ListEntry is a tracked VHD as printed by 'vhdm list'
+type ListEntry struct {
+	Path       string `json:"path"`
+	Name       string `json:"name"`
+	UUID       string `json:"uuid,omitempty"`
+	MountPoint string `json:"mountPoint,omitempty"`
+	State      string `json:"state"`
+}
+
+func newListCmd() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List tracked VHDs",
+		Long: `List the tracked VHDs with their name (the file name without extension,
+as in {vhdname} mount points), UUID, mount point and state, without the disks
+table of 'vhdm status --all'.
+
+With --quiet each VHD is printed on one line as '<path> (<uuid>): <state>',
+like status, and --format prints each VHD with a Go template (.Path, .Name,
+.UUID, .MountPoint, .State); use --format '{{.Path}}' for the bare paths. Unlike status, list never updates the tracking file.`,
+		Example: `  vhdm list
+  vhdm list --format '{{.Path}}' | xargs -n1 vhdm detach --vhd-path
+  vhdm list --output json
+  vhdm list --format '{{.Name}}: {{.State}}'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := appContext(cmd)
+			if err := setFormat(ctx, format); err != nil {
+				return err
+			}
+			return runList(ctx)
+		},
+	}
+	addFormatFlag(cmd, &format)
+	return cmd
+}
+
+func runList(ctx *AppContext) error {
+	if err := checkListOutput(ctx, "list"); err != nil {
+		return err
+	}
+	vhds, err := statusSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]ListEntry, 0, len(vhds))
+	for _, vhd := range vhds {
+		rows = append(rows, ListEntry{
+			Path:       vhd.Path,
+			Name:       utils.VHDName(vhd.Path),
+			UUID:       vhd.UUID,
+			MountPoint: vhd.MountPoint,
+			State:      string(vhd.State),
+		})
+	}
+
+	if ctx.Config.Format != "" {
+		return printTemplate(ctx, rows)
+	}
+	if structuredOutput(ctx) {
+		return printStructured(ctx, rows)
+	}
+	if ctx.Config.Quiet {
+		for _, vhd := range vhds {
+			printQuiet(vhdQuietLine(vhd))
+		}
+		return nil
+	}
+
+	if len(rows) == 0 {
+		ctx.Logger.Info("No tracked VHDs")
+		return nil
+	}
+
+	fmt.Println()
+	colWidths := []int{40, 16, 36, 20, 12}
+	utils.PrintTableHeader(colWidths, []string{"Path", "Name", "UUID", "Mount Point", "State"})
+	for _, row := range rows {
+		uuid, mp := row.UUID, row.MountPoint
+		if uuid == "" {
+			uuid = "(none)"
+		}
+		if mp == "" {
+			mp = "-"
+		}
+		utils.PrintTableRow(colWidths, row.Path, row.Name, uuid, mp, colorizeStatus(ctx.Theme, row.State))
+	}
+	utils.PrintTableFooter(colWidths)
+	return nil
+}
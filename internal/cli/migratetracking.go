@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newMigrateTrackingCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "migrate-tracking",
+		Short: "Convert a tracking file of the bash version of vhdm",
+		Long: `Convert the tracking file written by the bash version of vhdm to the current
+schema, once, when upgrading.
+
+Legacy keys are renamed (last_attached, devname/device, mount_point and
+path/vhd_path become last_seen, dev_name, mount_points and original_path),
+paths are normalized, the original casing of each path is recorded (taken
+from the detach history when it has the path, else from the path with an
+upper-case drive letter) and the detach history is dropped. Entries that
+differ only in casing are merged, keeping the one seen last.
+
+The file is copied to <tracking file>.<timestamp>.bak first. A file already
+in the current format is left alone. With --dry-run, only what would change is
+reported.`,
+		Example: `  vhdm migrate-tracking --dry-run
+  vhdm migrate-tracking`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrateTracking(appContext(cmd), dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report what would change without writing")
+	return cmd
+}
+
+func runMigrateTracking(ctx *AppContext, dryRun bool) error {
+	log := ctx.Logger
+
+	backup := fmt.Sprintf("%s.%s.bak", ctx.Config.TrackingFile, time.Now().Format("20060102-150405"))
+	m, err := ctx.Tracker.MigrateLegacy(backup, dryRun)
+	if err != nil {
+		return err
+	}
+
+	res := MigrateTrackingResult{
+		File:    ctx.Config.TrackingFile,
+		Changed: m.Changed(),
+		Entries: m.Entries,
+		Keys:    m.Keys,
+		Paths:   m.Paths,
+		Merged:  m.Merged,
+		History: m.History,
+		Backup:  m.BackupPath,
+		DryRun:  dryRun,
+	}
+	switch {
+	case !m.Changed():
+		log.Info("Tracking file is already in the current format")
+	case dryRun:
+		log.Info("Dry run: the tracking file was not changed")
+	default:
+		log.Success("Tracking file migrated")
+	}
+	return printResult(ctx, res)
+}
+
+// MigrateTrackingResult is the outcome of 'vhdm migrate-tracking'
+type MigrateTrackingResult struct {
+	File    string `json:"file"`
+	Changed bool   `json:"changed"`
+	Entries int    `json:"entries"`
+	Keys    int    `json:"keysRenamed"`
+	Paths   int    `json:"pathsFixed"`
+	Merged  int    `json:"merged,omitempty"`
+	History int    `json:"historyDropped,omitempty"`
+	Backup  string `json:"backup,omitempty"`
+	DryRun  bool   `json:"dryRun,omitempty"`
+}
+
+func (r MigrateTrackingResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{
+		{"Tracking File", r.File},
+		{"Entries", fmt.Sprint(r.Entries)},
+		{"Keys Renamed", fmt.Sprint(r.Keys)},
+		{"Paths Fixed", fmt.Sprint(r.Paths)},
+	}
+	if r.Merged > 0 {
+		pairs = append(pairs, [2]string{"Entries Merged", fmt.Sprint(r.Merged)})
+	}
+	if r.History > 0 {
+		pairs = append(pairs, [2]string{"History Dropped", fmt.Sprintf("%d record(s)", r.History)})
+	}
+	if r.Backup != "" {
+		pairs = append(pairs, [2]string{"Backup", r.Backup})
+	}
+	return "Tracking Migration", append(pairs, [2]string{"Status", r.status()})
+}
+
+func (r MigrateTrackingResult) quiet() quietLine {
+	return quietLine{Key: r.File, State: r.status()}
+}
+
+func (r MigrateTrackingResult) status() string {
+	switch {
+	case !r.Changed:
+		return "already current"
+	case r.DryRun:
+		return "would be migrated"
+	default:
+		return "migrated"
+	}
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newMirrorCmd() *cobra.Command {
+	var (
+		srcVHD      string
+		dstVHD      string
+		deleteExtra bool
+	)
+	cmd := &cobra.Command{
+		Use:   "mirror",
+		Short: "Sync the contents of one VHD into another",
+		Long: `Mirror the contents of a source VHD into a destination VHD using rsync.
+
+Both VHDs are mounted temporarily if needed (the source read-only) and restored
+to their previous state afterwards. Use --delete to remove files from the
+destination that no longer exist in the source, keeping an exact warm copy.`,
+		Example: `  vhdm mirror --src-vhd C:/VMs/work.vhdx --dst-vhd D:/Backups/work.vhdx
+  vhdm mirror --src-vhd C:/VMs/work.vhdx --dst-vhd D:/Backups/work.vhdx --delete`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMirror(appContext(cmd), srcVHD, dstVHD, deleteExtra)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&srcVHD, "src-vhd", "", "Source VHD file path (Windows format)")
+	cmd.Flags().StringVar(&dstVHD, "dst-vhd", "", "Destination VHD file path (Windows format)")
+	cmd.Flags().BoolVar(&deleteExtra, "delete", false, "Delete destination files not present in source")
+	cmd.MarkFlagRequired("src-vhd")
+	cmd.MarkFlagRequired("dst-vhd")
+	return cmd
+}
+
+func runMirror(ctx *AppContext, srcVHD, dstVHD string, deleteExtra bool) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(srcVHD); err != nil {
+		return &types.VHDError{Op: "mirror", Path: srcVHD, Err: err}
+	}
+	if err := validation.ValidateWindowsPath(dstVHD); err != nil {
+		return &types.VHDError{Op: "mirror", Path: dstVHD, Err: err}
+	}
+	if utils.NormalizePath(srcVHD) == utils.NormalizePath(dstVHD) {
+		return &types.VHDError{Op: "mirror", Err: fmt.Errorf("source and destination must be different VHDs")}
+	}
+
+	if err := ensureNotReference(ctx, "mirror", dstVHD); err != nil {
+		return err
+	}
+
+	log.Debug("Mirror operation starting")
+
+	src, err := acquireTempMount(ctx, srcVHD, true)
+	if err != nil {
+		return &types.VHDError{Op: "mirror", Path: srcVHD, Err: err}
+	}
+	defer src.release(ctx)
+
+	dst, err := acquireTempMount(ctx, dstVHD, false)
+	if err != nil {
+		return &types.VHDError{Op: "mirror", Path: dstVHD, Err: err}
+	}
+	defer dst.release(ctx)
+
+	if src.UUID == dst.UUID {
+		return &types.VHDError{Op: "mirror", Err: fmt.Errorf("source and destination have the same UUID (%s)", src.UUID)}
+	}
+
+	log.Info("Mirroring %s -> %s...", src.MountPoint, dst.MountPoint)
+	opts := wsl.RsyncOptions{Delete: deleteExtra, Progress: !ctx.Config.Quiet}
+	if err := ctx.WSL.Rsync(src.MountPoint, dst.MountPoint, opts); err != nil {
+		return fmt.Errorf("failed to mirror: %w", err)
+	}
+
+	// Output
+	log.Success("VHD mirrored successfully")
+	return printResult(ctx, MirrorResult{
+		Source:      srcVHD,
+		SourceUUID:  src.UUID,
+		Destination: dstVHD,
+		DestUUID:    dst.UUID,
+		Delete:      deleteExtra,
+	})
+}
+
+// MirrorResult is the outcome of 'vhdm mirror'
+type MirrorResult struct {
+	Source      string `json:"source"`
+	SourceUUID  string `json:"sourceUUID"`
+	Destination string `json:"destination"`
+	DestUUID    string `json:"destUUID"`
+	Delete      bool   `json:"delete"` // Extra destination files were deleted
+}
+
+func (r MirrorResult) table(utils.Theme) (string, [][2]string) {
+	mode := "update"
+	if r.Delete {
+		mode = "exact (--delete)"
+	}
+	return "Mirror Result", [][2]string{
+		{"Source", r.Source},
+		{"Source UUID", r.SourceUUID},
+		{"Destination", r.Destination},
+		{"Dest UUID", r.DestUUID},
+		{"Mode", mode},
+		{"Status", "mirrored"},
+	}
+}
+
+func (r MirrorResult) quiet() quietLine {
+	return quietLine{Key: r.Source, UUID: r.SourceUUID, State: fmt.Sprintf("mirrored to %s (%s)", r.Destination, r.DestUUID)}
+}
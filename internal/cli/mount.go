@@ -2,7 +2,10 @@ package cli
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
 
@@ -13,10 +16,24 @@ import (
 
 func newMountCmd() *cobra.Command {
 	var (
-		vhdPath    string
-		uuid       string
-		devName    string
-		mountPoint string
+		vhdPath     string
+		uuid        string
+		devName     string
+		mountPoint  string
+		automount   bool
+		idleTimeout int
+		all         bool
+		parallel    int
+		keepGoing   bool
+		failFast    bool
+		strict      bool
+		uid         string
+		gid         string
+		umask       string
+		move        bool
+		add         bool
+		mountOpts   string
+		format      string
 	)
 	cmd := &cobra.Command{
 		Use:   "mount",
@@ -30,24 +47,120 @@ This is an orchestration command that:
 The VHD must be formatted before mounting.
 
 When using --uuid, the VHD path is automatically looked up from the tracking file,
-allowing services to mount VHDs by UUID without specifying the path.`,
+allowing services to mount VHDs by UUID without specifying the path.
+
+With --automount, nothing is mounted immediately. Instead systemd automount units
+are installed so the VHD is attached and mounted on first access under the mount
+point (requires sudo and a tracked VHD). With --idle-timeout the VHD is unmounted
+and detached again after that many seconds without access.
+
+With --all, every tracked VHD is mounted at its last used mount point, in the
+order declared with 'vhdm depend'. VHDs whose dependencies fail are skipped.
+With --parallel N, up to N independent VHDs are mounted concurrently; attaching
+and device detection stay serialized, and each VHD still waits for its
+dependencies.
+
+Like fstab's nofail, a VHD that fails to mount does not stop the others
+(--continue-on-error, the default) and the command still exits successfully.
+With --fail-fast no further VHDs are mounted after the first failure, and with
+--strict the command exits non-zero when any VHD could not be mounted.
+
+Filesystems without Unix ownership (vfat, exfat, ntfs) are mounted owned by the
+invoking user with umask 022; override with --uid, --gid and --umask.
+
+Options given with --options (and --uid, --gid, --umask) are recorded in
+tracking, and 'mount --all' and services created afterwards mount the VHD
+with the same options.
+
+If the VHD is already mounted at another mount point, mount fails unless --move
+is given, which mounts it at the requested one and then unmounts it from the
+old one (a failing mount leaves it where it was), or
+--add, which bind-mounts it at the requested mount point as well. Each mount
+point is recorded in tracking; remove one with 'vhdm umount --mount-point'.`,
 		Example: `  vhdm mount --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/data
   vhdm mount --uuid 57fd0f3a-4077-44b8-91ba-5abdee575293 --mount-point /mnt/data
-  vhdm mount --dev-name sde --mount-point /mnt/data`,
+  vhdm mount --dev-name sde --mount-point /mnt/data
+  vhdm mount --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/data --options noatime,discard
+  sudo vhdm mount --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/data --automount --idle-timeout 600
+  vhdm mount --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/new --move
+  vhdm mount --vhd-path C:/VMs/disk.vhdx --mount-point /srv/data --add
+  vhdm mount --all
+  vhdm mount --all --parallel 4
+  vhdm mount --all --fail-fast --strict
+  vhdm mount --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/data --format '{{.DeviceName}}'`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runMount(vhdPath, uuid, devName, mountPoint)
+			if err := setFormat(appContext(cmd), format); err != nil {
+				return err
+			}
+			if all {
+				if vhdPath != "" || uuid != "" || devName != "" || mountPoint != "" || automount || move || add || mountOpts != "" {
+					return fmt.Errorf("--all cannot be combined with other mount options")
+				}
+				if failFast && cmd.Flags().Changed("continue-on-error") && keepGoing {
+					return fmt.Errorf("--continue-on-error and --fail-fast are mutually exclusive")
+				}
+				return runMountAll(appContext(cmd), parallel, failFast || !keepGoing, strict)
+			}
+			if parallel != 1 {
+				return fmt.Errorf("--parallel requires --all")
+			}
+			if failFast || strict || cmd.Flags().Changed("continue-on-error") {
+				return fmt.Errorf("--continue-on-error, --fail-fast and --strict require --all")
+			}
+			options, err := ownershipMountOptions(uid, gid, umask)
+			if err != nil {
+				return err
+			}
+			if mountOpts != "" {
+				if err := validation.ValidateMountOptions(mountOpts); err != nil {
+					return err
+				}
+				options = joinMountOptions(mountOpts, options)
+			}
+			if options != "" && automount {
+				return fmt.Errorf("--options, --uid, --gid and --umask cannot be combined with --automount")
+			}
+			if move && add {
+				return fmt.Errorf("--move and --add are mutually exclusive")
+			}
+			if (move || add) && automount {
+				return fmt.Errorf("--move and --add cannot be combined with --automount")
+			}
+			if add && options != "" {
+				return fmt.Errorf("--options, --uid, --gid and --umask cannot be combined with --add")
+			}
+			if automount {
+				return runMountAutomount(appContext(cmd), vhdPath, uuid, mountPoint, idleTimeout)
+			}
+			if idleTimeout != 0 {
+				return fmt.Errorf("--idle-timeout requires --automount")
+			}
+			return runMount(appContext(cmd), vhdPath, uuid, devName, mountPoint, options, move, add)
 		},
+		Annotations: map[string]string{annotationSudo: "true"},
 	}
 	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
-	cmd.Flags().StringVar(&uuid, "uuid", "", "VHD UUID")
+	cmd.Flags().StringVar(&uuid, "uuid", "", uuidFlagUsage)
 	cmd.Flags().StringVar(&devName, "dev-name", "", "Device name (e.g., sde)")
 	cmd.Flags().StringVar(&mountPoint, "mount-point", "", "Mount point path")
-	cmd.MarkFlagRequired("mount-point")
+	cmd.Flags().BoolVar(&automount, "automount", false, "Install a systemd automount that mounts on first access")
+	cmd.Flags().IntVar(&idleTimeout, "idle-timeout", 0, "With --automount, unmount and detach after this many idle seconds (0 to never)")
+	cmd.Flags().BoolVar(&all, "all", false, "Mount all tracked VHDs at their last mount point, respecting dependencies")
+	cmd.Flags().IntVar(&parallel, "parallel", 1, "With --all, number of VHDs to mount concurrently")
+	cmd.Flags().BoolVar(&keepGoing, "continue-on-error", true, "With --all, keep mounting the other VHDs when one fails")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "With --all, stop mounting after the first failure")
+	cmd.Flags().BoolVar(&strict, "strict", false, "With --all, exit non-zero when any VHD could not be mounted")
+	cmd.Flags().StringVar(&uid, "uid", "", "Owner uid for vfat/exfat/ntfs (default: invoking user)")
+	cmd.Flags().StringVar(&gid, "gid", "", "Owner gid for vfat/exfat/ntfs (default: invoking user)")
+	cmd.Flags().StringVar(&umask, "umask", "", "Permission mask for vfat/exfat/ntfs, octal (default: 022)")
+	cmd.Flags().BoolVar(&move, "move", false, "Unmount the VHD from its current mount point and mount it at --mount-point")
+	cmd.Flags().BoolVar(&add, "add", false, "Also mount an already mounted VHD at --mount-point (bind mount)")
+	cmd.Flags().StringVarP(&mountOpts, "options", "o", "", "Mount options (e.g., ro,noatime,discard), reused by 'mount --all' and services")
+	addFormatFlag(cmd, &format)
 	return cmd
 }
 
-func runMount(vhdPath, uuid, devName, mountPoint string) error {
-	ctx := getContext()
+func runMount(ctx *AppContext, vhdPath, uuid, devName, mountPoint, options string, move, add bool) error {
 	log := ctx.Logger
 
 	// Validate inputs
@@ -61,9 +174,11 @@ func runMount(vhdPath, uuid, devName, mountPoint string) error {
 		}
 	}
 	if uuid != "" {
-		if err := validation.ValidateUUID(uuid); err != nil {
-			return &types.VHDError{Op: "mount", Err: err}
+		resolved, err := resolveUUID(ctx, "mount", uuid)
+		if err != nil {
+			return err
 		}
+		uuid = resolved
 	}
 	if devName != "" {
 		if err := validation.ValidateDeviceName(devName); err != nil {
@@ -72,14 +187,39 @@ func runMount(vhdPath, uuid, devName, mountPoint string) error {
 		// Normalize device name (strip /dev/ prefix if present)
 		devName = strings.TrimPrefix(devName, "/dev/")
 	}
+	mountPoint = expandMountPoint(ctx, mountPoint, vhdPath, uuid)
 	if err := validation.ValidateMountPoint(mountPoint); err != nil {
 		return &types.VHDError{Op: "mount", Err: err}
 	}
 
+	// Resolve the tracked VHD of --uuid and --dev-name, so references and
+	// claimed mount points are checked whichever way the VHD is named
+	claimPath := vhdPath
+	if claimPath == "" && uuid != "" {
+		claimPath, _ = ctx.Tracker.LookupPathByUUID(uuid)
+	}
+	if claimPath == "" && devName != "" {
+		claimPath, _ = ctx.Tracker.LookupPathByDevName(devName)
+		if devUUID, _ := ctx.WSL.GetUUIDByDevice(devName); claimPath == "" && devUUID != "" {
+			claimPath, _ = ctx.Tracker.LookupPathByUUID(devUUID)
+		}
+	}
+	if claimPath != "" {
+		if err := ensureNotReference(ctx, "mount", claimPath); err != nil {
+			return err
+		}
+	}
+
 	log.Debug("Mount operation starting")
 
 	var wasAttached bool
 
+	if claimPath != "" {
+		if err := checkMountPointClaims(ctx, "mount", claimPath, mountPoint); err != nil {
+			return err
+		}
+	}
+
 	// Early check: If mount point already has something mounted, try to use that
 	if mountPoint != "" {
 		existingUUID, _ := ctx.WSL.GetUUIDByMountPoint(mountPoint)
@@ -93,17 +233,12 @@ func runMount(vhdPath, uuid, devName, mountPoint string) error {
 				if vhdPath != "" {
 					devName, _ := ctx.WSL.GetDeviceByUUID(uuid)
 					if err := ctx.Tracker.SaveMapping(vhdPath, uuid, mountPoint, devName); err != nil {
-						log.Warn("Failed to save tracking: %v", err)
+						log.Collect("Failed to save tracking: %v", err)
 					} else {
 						log.Debug("Updated tracking for already-mounted VHD")
 					}
-					if ctx.Config.Quiet {
-						fmt.Printf("%s (%s): already mounted at %s\n", vhdPath, uuid, mountPoint)
-					} else {
-						log.Info("VHD is already mounted at %s", mountPoint)
-						printMountResult(vhdPath, uuid, devName, mountPoint, false)
-					}
-					return nil
+					log.Info("VHD is already mounted at %s", mountPoint)
+					return printResult(ctx, MountResult{Path: vhdPath, UUID: uuid, DeviceName: devName, MountPoint: mountPoint, Status: "already mounted"})
 				}
 			} else if uuid != existingUUID {
 				return fmt.Errorf("mount point %s already has a different VHD mounted (UUID: %s)", mountPoint, existingUUID)
@@ -170,17 +305,18 @@ func runMount(vhdPath, uuid, devName, mountPoint string) error {
 
 		// Attach if not already attached
 		if !wasAttached {
-			// Capture device list BEFORE attaching (for new device detection)
-			var oldDevices []string
+			// Without an expected UUID, the new device is detected under the
+			// attach lock
 			var err error
+			attachedNow := false
 			if expectedUUID == "" {
-				oldDevices, err = ctx.WSL.GetBlockDevices()
-				if err != nil {
-					return fmt.Errorf("failed to get block devices before attach: %w", err)
-				}
+				devName, attachedNow, err = ctx.WSL.AttachVHDAndDetect(vhdPath)
+			} else {
+				_, err = ctx.WSL.AttachVHD(vhdPath)
+			}
+			if err != nil && attachedNow {
+				return fmt.Errorf("failed to detect device: %w", err)
 			}
-
-			_, err = ctx.WSL.AttachVHD(vhdPath)
 			alreadyAttached := types.IsAlreadyAttached(err)
 			if err != nil && !alreadyAttached {
 				return fmt.Errorf("failed to attach: %w", err)
@@ -204,20 +340,13 @@ func runMount(vhdPath, uuid, devName, mountPoint string) error {
 						Op:   "mount",
 						Path: vhdPath,
 						Err:  fmt.Errorf("VHD is already attached but cannot determine device"),
-						Help: "The VHD is already attached but not tracked. Either:\n" +
-							"  1. Detach the VHD first: wsl.exe --unmount <vhd-path>\n" +
-							"  2. Find the device manually and use: vhdm mount --dev-name <device> --mount-point <path>\n" +
-							"  3. If you know the UUID, use: vhdm mount --uuid <uuid> --mount-point <path>",
+						Help: "The VHD is already attached but not tracked. Find its device with 'vhdm devices'\n" +
+							"(or 'vhdm which --dev-name <device>'), then track it without detaching:\n" +
+							"  vhdm adopt --dev-name <device> --vhd-path " + vhdPath,
 					}
 				}
 			} else {
-				// Successfully attached - detect new device
-				devName, err = ctx.WSL.DetectNewDevice(oldDevices)
-				if err != nil {
-					return fmt.Errorf("failed to detect device: %w", err)
-				}
-
-				// Get UUID from the newly attached device
+				// Successfully attached - get UUID from the newly attached device
 				uuid, _ = ctx.WSL.GetUUIDByDevice(devName)
 				log.Debug("Attached new device: %s (UUID: %s)", devName, uuid)
 			}
@@ -251,68 +380,418 @@ func runMount(vhdPath, uuid, devName, mountPoint string) error {
 	}
 
 	// Check if already mounted
-	existingMP, _ := ctx.WSL.GetMountPoint(uuid)
+	existingMPs, _ := ctx.WSL.GetMountPoints(uuid)
+	existingMP := ""
+	if len(existingMPs) > 0 {
+		existingMP = existingMPs[0]
+	}
 	if existingMP != "" {
-		if existingMP == mountPoint {
+		if slices.Contains(existingMPs, mountPoint) {
 			// Already mounted at same location
 			// Update tracking to ensure OriginalPath is set (for migration from old format)
 			if vhdPath != "" {
 				if err := ctx.Tracker.SaveMapping(vhdPath, uuid, mountPoint, devName); err != nil {
-					log.Warn("Failed to save tracking: %v", err)
+					log.Collect("Failed to save tracking: %v", err)
+				}
+				if len(existingMPs) > 1 {
+					ctx.Tracker.UpdateMountPoints(vhdPath, existingMPs)
 				}
 			}
-			if ctx.Config.Quiet {
-				fmt.Printf("%s: already mounted at %s\n", vhdPath, mountPoint)
-			} else {
-				log.Info("VHD is already mounted at %s", mountPoint)
-				printMountResult(vhdPath, uuid, devName, mountPoint, false)
-			}
-			return nil
+			log.Info("VHD is already mounted at %s", mountPoint)
+			return printResult(ctx, MountResult{Path: vhdPath, UUID: uuid, DeviceName: devName, MountPoint: mountPoint, Status: "already mounted"})
 		}
 		// Mounted at different location
-		return fmt.Errorf("VHD is already mounted at %s", existingMP)
+		if add {
+			return addMountPoint(ctx, vhdPath, uuid, devName, existingMPs, mountPoint)
+		}
+		if !move {
+			return &types.VHDError{
+				Op:   "mount",
+				Path: vhdPath,
+				Err:  fmt.Errorf("VHD is already mounted at %s", strings.Join(existingMPs, ", ")),
+				Help: "Pass --move to unmount it from there and mount it at " + mountPoint +
+					", or --add to mount it there as well",
+			}
+		}
+		wasAttached = true
 	}
 
-	// Step 2: Mount
-	if err := ctx.WSL.MountByUUID(uuid, mountPoint); err != nil {
+	// Step 2: Mount. A moved VHD is mounted at the new place before leaving
+	// the old one, so a failing mount leaves it where it was.
+	if err := ctx.WSL.MountByUUIDWithOptions(uuid, mountPoint, options); err != nil {
 		return fmt.Errorf("failed to mount: %w", err)
 	}
 
+	// Verify the contents before declaring success (see 'vhdm mount-check')
+	if err := verifyMount(ctx, vhdPath, uuid, mountPoint); err != nil {
+		log.Info("Unmounting %s after the failed mount check...", mountPoint)
+		if uerr := ctx.WSL.Unmount(mountPoint); uerr != nil {
+			log.Warn("Failed to unmount %s: %v", mountPoint, uerr)
+		}
+		return err
+	}
+
+	// Bind mounts come after the original mount; undo them first
+	for i := len(existingMPs) - 1; i >= 0; i-- {
+		log.Info("Unmounting from %s...", existingMPs[i])
+		if err := ctx.WSL.Unmount(existingMPs[i]); err != nil {
+			if vhdPath != "" {
+				ctx.Tracker.UpdateMountPoints(vhdPath, append(slices.Clone(existingMPs[:i+1]), mountPoint))
+			}
+			return &types.VHDError{
+				Op:   "mount",
+				Path: vhdPath,
+				Err:  fmt.Errorf("mounted at %s, but failed to unmount %s: %w", mountPoint, existingMPs[i], err),
+				Help: fmt.Sprintf("Check for processes using it: lsof +D %s", existingMPs[i]),
+			}
+		}
+	}
+
 	// Update tracking
 	if vhdPath != "" {
 		if err := ctx.Tracker.SaveMapping(vhdPath, uuid, mountPoint, devName); err != nil {
-			log.Warn("Failed to save tracking: %v", err)
+			log.Collect("Failed to save tracking: %v", err)
+		}
+		if err := ctx.Tracker.SetMountInfo(vhdPath, options, ctx.WSL.FilesystemTypeByUUID(uuid)); err != nil {
+			log.Warn("Failed to save mount options: %v", err)
+		}
+		recordIdentifiers(ctx, vhdPath, uuid)
+	} else if existingMP != "" {
+		// Moved without a known path: keep the tracked mount point current
+		if err := ctx.Tracker.SaveMappingByUUID(uuid, mountPoint, devName); err != nil {
+			log.Collect("Failed to save tracking: %v", err)
 		}
 	}
 
 	// Output
-	if ctx.Config.Quiet {
-		fmt.Printf("%s (%s): mounted at %s\n", vhdPath, uuid, mountPoint)
+	res := MountResult{Path: vhdPath, UUID: uuid, DeviceName: devName, MountPoint: mountPoint, Status: "mounted"}
+	switch {
+	case existingMP != "":
+		log.Success("VHD moved from %s", existingMP)
+		res.Status = "moved"
+		res.MovedFrom = existingMP
+	case !wasAttached:
+		log.Success("VHD mounted successfully")
+		res.Status = "attached and mounted"
+	default:
+		log.Success("VHD mounted successfully")
+	}
+	return printResult(ctx, res)
+}
+
+// addMountPoint bind-mounts an already mounted VHD at one more mount point and
+// records it alongside the existing ones
+func addMountPoint(ctx *AppContext, vhdPath, uuid, devName string, existingMPs []string, mountPoint string) error {
+	log := ctx.Logger
+
+	log.Info("Bind-mounting %s at %s...", existingMPs[0], mountPoint)
+	if err := ctx.WSL.BindMount(existingMPs[0], mountPoint); err != nil {
+		return fmt.Errorf("failed to mount: %w", err)
+	}
+
+	mountPoints := append(slices.Clone(existingMPs), mountPoint)
+	if vhdPath != "" {
+		if err := ctx.Tracker.UpdateMountPoints(vhdPath, mountPoints); err != nil {
+			log.Collect("Failed to save tracking: %v", err)
+		}
+	}
+
+	log.Success("VHD also mounted at %s", mountPoint)
+	return printResult(ctx, MountResult{
+		Path:        vhdPath,
+		UUID:        uuid,
+		DeviceName:  devName,
+		MountPoint:  mountPoint,
+		MountPoints: mountPoints,
+		Status:      "also mounted",
+	})
+}
+
+// joinMountOptions joins comma-separated mount option lists, skipping empty ones
+func joinMountOptions(lists ...string) string {
+	var opts []string
+	for _, l := range lists {
+		if l != "" {
+			opts = append(opts, l)
+		}
+	}
+	return strings.Join(opts, ",")
+}
+
+// ownershipMountOptions builds uid=, gid= and umask= mount options from flags
+func ownershipMountOptions(uid, gid, umask string) (string, error) {
+	var opts []string
+	for _, o := range []struct{ key, value string }{{"uid", uid}, {"gid", gid}} {
+		if o.value == "" {
+			continue
+		}
+		if _, err := strconv.ParseUint(o.value, 10, 32); err != nil {
+			return "", fmt.Errorf("invalid --%s: %s (must be numeric)", o.key, o.value)
+		}
+		opts = append(opts, o.key+"="+o.value)
+	}
+	if umask != "" {
+		if _, err := strconv.ParseUint(umask, 8, 16); err != nil || len(umask) > 4 {
+			return "", fmt.Errorf("invalid --umask: %s (must be octal, e.g., 022)", umask)
+		}
+		opts = append(opts, "umask="+umask)
+	}
+	return strings.Join(opts, ","), nil
+}
+
+// runMountAll mounts every tracked VHD at its last used mount point in
+// dependency order. Failures only fail the command when strict is set; with
+// failFast no new mounts start after the first failure.
+func runMountAll(ctx *AppContext, parallel int, failFast, strict bool) error {
+	log := ctx.Logger
+
+	if parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+
+	paths, err := ctx.Tracker.GetAllPaths()
+	if err != nil {
+		return fmt.Errorf("failed to get tracked VHDs: %w", err)
+	}
+
+	var candidates []string
+	for _, path := range paths {
+		entry, err := ctx.Tracker.GetEntry(path)
+		if err != nil || entry.Archived || strings.HasPrefix(path, "unknown-") {
+			continue
+		}
+		if entry.LastMount == "" && len(entry.MountPoints) == 0 {
+			log.Debug("Skipping %s (no known mount point)", path)
+			continue
+		}
+		candidates = append(candidates, path)
+	}
+
+	ordered, err := ctx.Tracker.SortByDependencies(candidates)
+	if err != nil {
+		return &types.VHDError{Op: "mount", Err: err, Help: "Fix the cycle with: vhdm depend --vhd-path <path> --clear"}
+	}
+
+	if len(ordered) == 0 {
+		log.Info("No tracked VHDs with a known mount point")
 		return nil
 	}
 
-	log.Success("VHD mounted successfully")
-	printMountResult(vhdPath, uuid, devName, mountPoint, !wasAttached)
+	// Each VHD waits for its dependencies to finish, then for a free slot.
+	// Dependencies outside the candidate set are not waited for.
+	var (
+		mu      sync.Mutex
+		failed  = make(map[string]bool)
+		mounted int
+		stopped bool // set by the first failure with failFast
+		wg      sync.WaitGroup
+	)
+	done := make(map[string]chan struct{}, len(ordered))
+	for _, path := range ordered {
+		done[utils.NormalizePath(path)] = make(chan struct{})
+	}
+	slots := make(chan struct{}, parallel)
+
+	for _, path := range ordered {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			defer close(done[utils.NormalizePath(path)])
+
+			entry, _ := ctx.Tracker.GetEntry(path)
+			for _, dep := range entry.After {
+				if ch, ok := done[utils.NormalizePath(dep)]; ok {
+					<-ch
+				}
+			}
+
+			var blocked []string
+			mu.Lock()
+			for _, dep := range entry.After {
+				if failed[utils.NormalizePath(dep)] {
+					blocked = append(blocked, dep)
+				}
+			}
+			mu.Unlock()
+
+			var err error
+			if len(blocked) > 0 {
+				log.Warn("Skipping %s: dependency not mounted (%s)", path, strings.Join(blocked, ", "))
+				err = fmt.Errorf("dependency not mounted")
+			} else {
+				slots <- struct{}{}
+				mu.Lock()
+				skip := stopped
+				mu.Unlock()
+				if skip {
+					log.Warn("Skipping %s: an earlier mount failed (--fail-fast)", path)
+					err = fmt.Errorf("skipped after failure")
+				} else {
+					mountPoint := entry.LastMount
+					if len(entry.MountPoints) > 0 {
+						mountPoint = entry.MountPoints[0]
+					}
+					if err = runMount(ctx, path, "", "", mountPoint, entry.MountOptions, false, false); err != nil {
+						log.Error("Failed to mount %s: %v", path, err)
+					}
+				}
+				<-slots
+			}
+
+			mu.Lock()
+			if err != nil {
+				failed[utils.NormalizePath(path)] = true
+				stopped = stopped || failFast
+			} else {
+				mounted++
+			}
+			mu.Unlock()
+		}(path)
+
+		// Sequential runs keep the dependency order in the output
+		if parallel == 1 {
+			wg.Wait()
+		}
+	}
+	wg.Wait()
+
+	if mounted > 0 {
+		log.Success("Mounted %d of %d VHDs", mounted, len(ordered))
+	}
+	if len(failed) > 0 {
+		err := fmt.Errorf("%d of %d VHDs could not be mounted", len(failed), len(ordered))
+		notifyFailure(ctx, "mount --all failed", err)
+		if strict {
+			return err
+		}
+		log.Warn("%v (use --strict to fail)", err)
+	}
 	return nil
 }
 
-func printMountResult(path, uuid, devName, mountPoint string, wasNewlyAttached bool) {
-	pairs := [][2]string{}
+// runMountAutomount installs on-demand mounting for a tracked VHD instead of mounting it now
+func runMountAutomount(ctx *AppContext, vhdPath, uuid, mountPoint string, idleTimeout int) error {
 
-	if path != "" {
-		pairs = append(pairs, [2]string{"Path", path})
+	// Validate inputs
+	if vhdPath == "" && uuid == "" {
+		return fmt.Errorf("--automount requires --vhd-path or --uuid")
 	}
-	pairs = append(pairs, [2]string{"UUID", uuid})
-	if devName != "" {
-		pairs = append(pairs, [2]string{"Device", "/dev/" + devName})
+	if vhdPath != "" {
+		if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+			return &types.VHDError{Op: "mount", Path: vhdPath, Err: err}
+		}
+	}
+	if uuid != "" {
+		resolved, err := resolveUUID(ctx, "mount", uuid)
+		if err != nil {
+			return err
+		}
+		uuid = resolved
+	}
+	mountPoint = expandMountPoint(ctx, mountPoint, vhdPath, uuid)
+	if err := validation.ValidateMountPoint(mountPoint); err != nil {
+		return &types.VHDError{Op: "mount", Err: err}
+	}
+	if idleTimeout < 0 {
+		return &types.VHDError{Op: "mount", Err: fmt.Errorf("idle timeout must not be negative")}
+	}
+
+	// The attach unit needs the path and the mount unit needs the filesystem UUID
+	if vhdPath == "" {
+		vhdPath, _ = ctx.Tracker.LookupPathByUUID(uuid)
+	}
+	if uuid == "" {
+		uuid, _ = ctx.Tracker.LookupUUIDByPath(vhdPath)
+	}
+	if vhdPath == "" || uuid == "" {
+		return &types.VHDError{
+			Op:   "mount",
+			Path: vhdPath,
+			Err:  types.ErrVHDNotTracked,
+			Help: "Mount the VHD once without --automount so its UUID is known, then retry",
+		}
+	}
+
+	var options string
+	if entry, err := ctx.Tracker.GetEntry(vhdPath); err == nil {
+		options = entry.MountOptions
+	}
+	return installAutomount(ctx, vhdPath, uuid, mountPoint, "", options, idleTimeout, dependencyUnits(ctx, vhdPath), false)
+}
+
+// expandMountPoint expands {user}, {hostname} and {vhdname} placeholders in a
+// mount point. The VHD path is looked up by UUID when only the UUID is known.
+func expandMountPoint(ctx *AppContext, mountPoint, vhdPath, uuid string) string {
+	if !strings.Contains(mountPoint, "{") {
+		return mountPoint
+	}
+	if vhdPath == "" && uuid != "" {
+		vhdPath, _ = ctx.Tracker.LookupPathByUUID(uuid)
+	}
+	expanded := utils.ExpandMountPoint(mountPoint, vhdPath)
+	ctx.Logger.Debug("Expanded mount point %s -> %s", mountPoint, expanded)
+	return expanded
+}
+
+// checkMountPointClaims fails when another tracked VHD is mounted at
+// mountPoint or was last mounted there: both would be mounted there at boot,
+// and whichever comes second would fail
+func checkMountPointClaims(ctx *AppContext, op, vhdPath, mountPoint string) error {
+	claims, err := ctx.Tracker.MountPointClaims(mountPoint)
+	if err != nil {
+		return nil
+	}
+	others := slices.DeleteFunc(claims, func(p string) bool { return samePath(ctx, p, vhdPath) })
+	if len(others) == 0 {
+		return nil
+	}
+	return &types.VHDError{
+		Op:   op,
+		Path: vhdPath,
+		Err:  fmt.Errorf("%w: %s is claimed by both %s and %s", types.ErrMountPointConflict, mountPoint, vhdPath, strings.Join(others, ", ")),
+		Help: fmt.Sprintf("Each mount point belongs to one VHD. Choose another mount point, or mount %s somewhere else first", others[0]),
 	}
-	pairs = append(pairs, [2]string{"Mount Point", mountPoint})
+}
+
+// MountResult is the outcome of 'vhdm mount'
+type MountResult struct {
+	Path        string   `json:"path,omitempty"`
+	UUID        string   `json:"uuid"`
+	DeviceName  string   `json:"deviceName,omitempty"`
+	MountPoint  string   `json:"mountPoint"`
+	MountPoints []string `json:"mountPoints,omitempty"` // Every mount point, after --add
+	MovedFrom   string   `json:"movedFrom,omitempty"`
+	Status      string   `json:"status"`
+}
+
+func (r MountResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{}
 
-	status := "mounted"
-	if wasNewlyAttached {
-		status = "attached and mounted"
+	if r.Path != "" {
+		pairs = append(pairs, [2]string{"Path", r.Path})
 	}
-	pairs = append(pairs, [2]string{"Status", status})
+	pairs = append(pairs, [2]string{"UUID", r.UUID})
+	pairs = appendDevice(pairs, r.DeviceName)
+	if len(r.MountPoints) > 0 {
+		pairs = append(pairs, [2]string{"Mount Point", strings.Join(r.MountPoints, ", ")})
+	} else {
+		pairs = append(pairs, [2]string{"Mount Point", r.MountPoint})
+	}
+	if r.MovedFrom != "" {
+		pairs = append(pairs, [2]string{"Moved From", r.MovedFrom})
+	}
+	pairs = append(pairs, [2]string{"Status", r.Status})
 
-	utils.KeyValueTable("VHD Mount Result", pairs, 14, 50)
+	return "VHD Mount Result", pairs
+}
+
+func (r MountResult) quiet() quietLine {
+	switch r.Status {
+	case "moved":
+		return quietLine{Key: r.Path, UUID: r.UUID, State: fmt.Sprintf("moved from %s to %s", r.MovedFrom, r.MountPoint)}
+	case "already mounted", "also mounted":
+		return quietLine{Key: r.Path, UUID: r.UUID, State: r.Status + " at " + r.MountPoint}
+	default:
+		return quietLine{Key: r.Path, UUID: r.UUID, State: "mounted at " + r.MountPoint}
+	}
 }
@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+// mountCheckTimeout bounds a mount check command
+const mountCheckTimeout = 60 * time.Second
+
+func newMountCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mount-check",
+		Short: "Verify the contents of a VHD after each mount",
+		Long: `Configure a check that every mount of a tracked VHD must pass before it
+counts as successful: a sentinel file that must exist in the VHD, a command
+that must exit with status 0, or both.
+
+When the check fails, the VHD is unmounted again and the mount fails with
+VHDM_MOUNT_CHECK_FAILED, so services depending on the data fail fast when the
+wrong or an empty disk got mounted. The command runs with sh -c in the mount
+point, with VHDM_VHD_PATH, VHDM_UUID and VHDM_MOUNT_POINT set, and is killed
+after 60 seconds. When vhdm runs as root, as boot services do, the command runs
+as the owner of the tracking file instead.
+
+Independently of any check, filesystems formatted by vhdm hold a .vhdm.json ID
+file naming their VHD file and UUID. Every mount cross-checks it against
+tracking and warns when they differ, e.g. after VHD files were moved or
+swapped by hand. 'vhdm mount-check stamp' rewrites it for the current path.`,
+	}
+
+	cmd.AddCommand(
+		newMountCheckSetCmd(),
+		newMountCheckClearCmd(),
+		newMountCheckRunCmd(),
+		newMountCheckStampCmd(),
+	)
+
+	return cmd
+}
+
+func newMountCheckSetCmd() *cobra.Command {
+	var vhdPath, sentinel, command string
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Set the mount check of a VHD",
+		Example: `  vhdm mount-check set --vhd-path C:/VMs/pgdata.vhdx --sentinel PG_VERSION
+  vhdm mount-check set --vhd-path C:/VMs/pgdata.vhdx --command 'test -s PG_VERSION'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMountCheckSet(appContext(cmd), vhdPath, sentinel, command)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&sentinel, "sentinel", "", "File that must exist, relative to the mount point")
+	cmd.Flags().StringVar(&command, "command", "", "Command that must succeed, run with sh -c in the mount point")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func newMountCheckClearCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:     "clear",
+		Short:   "Remove the mount check of a VHD",
+		Example: `  vhdm mount-check clear --vhd-path C:/VMs/pgdata.vhdx`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMountCheckClear(appContext(cmd), vhdPath)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func newMountCheckRunCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:     "run",
+		Short:   "Run the mount check of a mounted VHD now",
+		Example: `  vhdm mount-check run --vhd-path C:/VMs/pgdata.vhdx`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMountCheckRun(appContext(cmd), vhdPath)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func newMountCheckStampCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:   "stamp",
+		Short: "Write the ID file of a mounted VHD for its current path",
+		Long: `Write the .vhdm.json ID file at the root of a mounted VHD, naming its current
+path and UUID. Use it after moving or copying a VHD file on purpose, or to
+add an ID file to a VHD formatted by an older vhdm.`,
+		Example: `  vhdm mount-check stamp --vhd-path C:/VMs/pgdata.vhdx`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMountCheckStamp(appContext(cmd), vhdPath)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runMountCheckSet(ctx *AppContext, vhdPath, sentinel, command string) error {
+	if sentinel == "" && command == "" {
+		return &types.VHDError{Op: "mount-check set", Err: fmt.Errorf("--sentinel or --command is required")}
+	}
+	if sentinel != "" {
+		clean := path.Clean(sentinel)
+		if path.IsAbs(sentinel) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+			return &types.VHDError{Op: "mount-check set", Err: fmt.Errorf("%w: sentinel must be a path inside the VHD, relative to its mount point", types.ErrInvalidInput)}
+		}
+		sentinel = clean
+	}
+	if strings.ContainsAny(command, "\n\r") {
+		return &types.VHDError{Op: "mount-check set", Err: fmt.Errorf("%w: command must be a single line", types.ErrInvalidInput)}
+	}
+	if _, err := trackedEntry(ctx, "mount-check set", vhdPath); err != nil {
+		return err
+	}
+
+	err := ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+		entry.MountCheck = &types.MountCheck{Sentinel: sentinel, Command: command}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save mount check: %w", err)
+	}
+
+	if ctx.Config.Quiet {
+		printQuiet(quietLine{Key: vhdPath, State: "mount check set"})
+		return nil
+	}
+	ctx.Logger.Success("Mount check saved for %s", vhdPath)
+	return nil
+}
+
+func runMountCheckClear(ctx *AppContext, vhdPath string) error {
+	if _, err := trackedEntry(ctx, "mount-check clear", vhdPath); err != nil {
+		return err
+	}
+
+	err := ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+		entry.MountCheck = nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove mount check: %w", err)
+	}
+
+	if ctx.Config.Quiet {
+		printQuiet(quietLine{Key: vhdPath, State: "mount check removed"})
+		return nil
+	}
+	ctx.Logger.Success("Mount check removed from %s", vhdPath)
+	return nil
+}
+
+func runMountCheckRun(ctx *AppContext, vhdPath string) error {
+	entry, err := trackedEntry(ctx, "mount-check run", vhdPath)
+	if err != nil {
+		return err
+	}
+	if entry.MountCheck == nil {
+		return &types.VHDError{
+			Op:   "mount-check run",
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD has no mount check"),
+			Help: fmt.Sprintf("Set one with 'vhdm mount-check set --vhd-path %s --sentinel <file>'", vhdPath),
+		}
+	}
+	mountPoint, _ := ctx.WSL.GetMountPoint(entry.UUID)
+	if entry.UUID == "" || mountPoint == "" {
+		return &types.VHDError{Op: "mount-check run", Path: vhdPath, Err: types.ErrVHDNotMounted}
+	}
+
+	if err := verifyMount(ctx, vhdPath, entry.UUID, mountPoint); err != nil {
+		return err
+	}
+	if ctx.Config.Quiet {
+		printQuiet(quietLine{Key: vhdPath, State: "mount check passed"})
+		return nil
+	}
+	ctx.Logger.Success("Mount check of %s passed", vhdPath)
+	return nil
+}
+
+func runMountCheckStamp(ctx *AppContext, vhdPath string) error {
+	entry, err := trackedEntry(ctx, "mount-check stamp", vhdPath)
+	if err != nil {
+		return err
+	}
+	mountPoint, _ := ctx.WSL.GetMountPoint(entry.UUID)
+	if entry.UUID == "" || mountPoint == "" {
+		return &types.VHDError{Op: "mount-check stamp", Path: vhdPath, Err: types.ErrVHDNotMounted}
+	}
+	if err := ensureNotReference(ctx, "mount-check stamp", vhdPath); err != nil {
+		return err
+	}
+
+	if err := stampIDFile(ctx, vhdPath, entry.UUID, mountPoint); err != nil {
+		return &types.VHDError{Op: "mount-check stamp", Path: vhdPath, Err: err}
+	}
+	if ctx.Config.Quiet {
+		printQuiet(quietLine{Key: vhdPath, State: "ID file written"})
+		return nil
+	}
+	ctx.Logger.Success("ID file of %s written to %s", vhdPath, mountPoint)
+	return nil
+}
+
+// verifyMount runs the mount check of a VHD (see 'vhdm mount-check') against
+// its mount at mountPoint, after cross-checking its ID file. VHDs without a
+// check, or not tracked, pass.
+func verifyMount(ctx *AppContext, vhdPath, uuid, mountPoint string) error {
+	if vhdPath == "" {
+		vhdPath, _ = ctx.Tracker.LookupPathByUUID(uuid)
+	}
+	checkIDFile(ctx, vhdPath, uuid, mountPoint)
+
+	entry, err := ctx.Tracker.GetEntry(vhdPath)
+	if err != nil || entry.MountCheck == nil {
+		return nil
+	}
+	check := entry.MountCheck
+	fail := func(err error) error {
+		return &types.VHDError{
+			Op:   "mount",
+			Path: vhdPath,
+			Err:  fmt.Errorf("%w: %v", types.ErrMountCheckFailed, err),
+			Help: fmt.Sprintf("The wrong or an empty disk may be mounted at %s. Check it, or change the check with 'vhdm mount-check set'", mountPoint),
+		}
+	}
+
+	if check.Sentinel != "" {
+		ctx.Logger.Debug("Mount check: looking for %s in %s", check.Sentinel, mountPoint)
+		if !ctx.WSL.FileExists(path.Join(mountPoint, check.Sentinel)) {
+			return fail(fmt.Errorf("sentinel %s not found", check.Sentinel))
+		}
+	}
+
+	if check.Command != "" {
+		cred, err := mountCheckCredential(os.Geteuid(), ctx.Config.TrackingFile)
+		if err != nil {
+			return fail(err)
+		}
+		ctx.Logger.Debug("Mount check: running %s", check.Command)
+		runCtx, cancel := context.WithTimeout(context.Background(), mountCheckTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(runCtx, "sh", "-c", check.Command)
+		cmd.Dir = mountPoint
+		if cred != nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+		}
+		cmd.Env = append(os.Environ(),
+			"VHDM_VHD_PATH="+vhdPath,
+			"VHDM_UUID="+uuid,
+			"VHDM_MOUNT_POINT="+mountPoint,
+		)
+		// stdout carries only the result of vhdm
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("timed out after %s", mountCheckTimeout)
+			}
+			return fail(fmt.Errorf("command %q: %v", check.Command, err))
+		}
+	}
+	return nil
+}
+
+// mountCheckCredential returns the user a mount check command runs as. The
+// command comes from the tracking file, so when vhdm runs as root (as boot
+// services do) it runs as the owner of that file rather than as root; whoever
+// can edit the file must not gain root by it. A root-owned tracking file that
+// others can write is refused. nil means the current user.
+func mountCheckCredential(euid int, trackingFile string) (*syscall.Credential, error) {
+	if euid != 0 {
+		return nil, nil
+	}
+	info, err := os.Stat(trackingFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot determine the owner of the tracking file: %w", err)
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, fmt.Errorf("cannot determine the owner of the tracking file %s", trackingFile)
+	}
+	if st.Uid != 0 {
+		return &syscall.Credential{Uid: st.Uid, Gid: st.Gid, Groups: []uint32{}}, nil
+	}
+	if info.Mode().Perm()&0o022 != 0 {
+		return nil, fmt.Errorf("refusing to run the command as root: tracking file %s is writable by other users", trackingFile)
+	}
+	return nil, nil
+}
@@ -0,0 +1,408 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newMoveCmd() *cobra.Command {
+	var vhdPath, to string
+	cmd := &cobra.Command{
+		Use:   "move",
+		Short: "Move a VHD file, keeping tracking and services consistent",
+		Long: `Move a VHD file to another directory or drive.
+
+A mounted VHD is unmounted and detached first, and mounted again at the same
+place afterwards. The tracking entry moves to the new path, along with the
+dependencies ('vhdm depend') and linked clones naming it, and the snapshots of
+the VHD move next to it. The ID file of the filesystem is updated, and units
+created by 'vhdm service' that name the old path are rewritten and reloaded;
+a service with the default name (vhdm-mount-<name>) is renamed when the file
+name changes. Updating units requires root, which is checked before anything
+moves; active services of the VHD are stopped for the move and started again
+afterwards.
+
+--to may be a directory ending in '/' to keep the file name. The extension
+cannot change (use 'vhdm convert'), and bases of linked clones cannot move
+since the clones name them.`,
+		Example: `  vhdm move --vhd-path C:/VMs/data.vhdx --to D:/Disks/data.vhdx
+  vhdm move --vhd-path C:/VMs/data.vhdx --to D:/Disks/`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMove(appContext(cmd), vhdPath, to)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&to, "to", "", "New path of the VHD, or a directory ending in '/' (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func runMove(ctx *AppContext, vhdPath, to string) error {
+	to = utils.ResolveVHDPath(to, ctx.Config.BaseDirs)
+	if strings.HasSuffix(to, "/") || strings.HasSuffix(to, `\`) {
+		to += path.Base(strings.ReplaceAll(vhdPath, `\`, "/"))
+	}
+	return moveVHD(ctx, "move", vhdPath, to)
+}
+
+// moveVHD moves the VHD file at vhdPath to the path to, detaching it and
+// mounting it again, and moves everything that names it along (see 'vhdm
+// move'); op is the command name for errors
+func moveVHD(ctx *AppContext, op, vhdPath, to string) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: op, Path: vhdPath, Err: err}
+	}
+	if err := validation.ValidateWindowsPath(to); err != nil {
+		return &types.VHDError{Op: op, Path: to, Err: err}
+	}
+	if !strings.EqualFold(path.Ext(to), path.Ext(vhdPath)) {
+		return &types.VHDError{
+			Op:   op,
+			Path: to,
+			Err:  fmt.Errorf("%w: the extension cannot change (%s)", types.ErrInvalidInput, path.Ext(vhdPath)),
+			Help: "Use 'vhdm convert' to change the format of a VHD",
+		}
+	}
+	if samePath(ctx, vhdPath, to) {
+		return &types.VHDError{Op: op, Path: to, Err: fmt.Errorf("%w: source and destination are the same file", types.ErrInvalidInput)}
+	}
+	if err := ensureNotReference(ctx, op, vhdPath); err != nil {
+		return err
+	}
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	toWSLPath := ctx.WSL.ConvertPath(to)
+	if !ctx.WSL.FileExists(wslPath) {
+		return &types.VHDError{Op: op, Path: vhdPath, Err: types.ErrVHDNotFound}
+	}
+	if ctx.WSL.FileExists(toWSLPath) {
+		return &types.VHDError{Op: op, Path: to, Err: fmt.Errorf("file already exists")}
+	}
+	if !ctx.WSL.FileExists(path.Dir(toWSLPath)) {
+		return &types.VHDError{Op: op, Path: to, Err: fmt.Errorf("directory does not exist: %s", path.Dir(to))}
+	}
+	if _, err := ctx.Tracker.GetEntry(to); err == nil {
+		return &types.VHDError{
+			Op:   op,
+			Path: to,
+			Err:  fmt.Errorf("path is still tracked"),
+			Help: fmt.Sprintf("Remove the stale entry with 'vhdm delete --vhd-path %s' first", to),
+		}
+	}
+	entry, trackErr := ctx.Tracker.GetEntry(vhdPath)
+	service := trackedService(ctx, vhdPath)
+	for _, snap := range entry.Snapshots {
+		if ctx.WSL.FileExists(ctx.WSL.ConvertPath(snapshotFile(to, snap.Name))) {
+			return &types.VHDError{Op: op, Path: to, Err: fmt.Errorf("snapshot file already exists: %s", snapshotFile(to, snap.Name))}
+		}
+	}
+
+	// The units naming the VHD are rewritten after the file moved, so make
+	// sure that can be done before moving anything
+	units, err := unitsNamingPath(ctx, vhdPath, to)
+	if err != nil {
+		return &types.VHDError{Op: op, Path: vhdPath, Err: fmt.Errorf("failed to read systemd units: %w", err)}
+	}
+	vhdUnits := units
+	if service != "" {
+		vhdUnits = append(serviceUnits(service), units...)
+	}
+	if len(vhdUnits) > 0 && os.Geteuid() != 0 {
+		return &types.VHDError{
+			Op:   op,
+			Path: vhdPath,
+			Err:  fmt.Errorf("systemd units of the VHD must be updated, which requires root privileges: %s", strings.Join(vhdUnits, ", ")),
+			Help: "Please run with sudo",
+		}
+	}
+
+	lock, err := lockVHDOperation(ctx, op, vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	log.Debug("%s operation starting", op)
+
+	// Services mounting the VHD would mount it again while it moves
+	stopped := stopActiveUnits(ctx, vhdUnits)
+
+	// Unmount and detach, remembering the mount point to restore afterwards
+	var mountPoint string
+	if entry.UUID != "" {
+		if attached, _ := ctx.WSL.IsAttached(entry.UUID); attached {
+			for _, mp := range entry.MountPoints {
+				log.Info("Unmounting %s...", mp)
+				if err := ctx.WSL.Unmount(mp); err != nil {
+					startUnits(ctx, stopped)
+					return &types.VHDError{Op: op, Path: vhdPath, Err: fmt.Errorf("failed to unmount VHD: %w", err)}
+				}
+			}
+			if len(entry.MountPoints) > 0 {
+				mountPoint = entry.MountPoints[0]
+			}
+			log.Info("Detaching VHD...")
+			if err := ctx.WSL.DetachVHD(vhdPath); err != nil && !types.IsNotAttached(err) {
+				startUnits(ctx, stopped)
+				return &types.VHDError{Op: op, Path: vhdPath, Err: fmt.Errorf("failed to detach VHD: %w", err)}
+			}
+			markDetached(ctx, vhdPath)
+		}
+	}
+
+	// remount mounts the VHD at p back where it was
+	remount := func(p string) bool {
+		if mountPoint == "" {
+			return false
+		}
+		if err := restoreMount(ctx, p, entry.UUID, mountPoint, entry.MountOptions); err != nil {
+			log.Warn("Failed to mount the VHD again at %s: %v", mountPoint, err)
+			return false
+		}
+		return true
+	}
+
+	if err := ensureNotInUseByWindows(ctx, op, vhdPath); err != nil {
+		remount(vhdPath)
+		startUnits(ctx, stopped)
+		return err
+	}
+
+	log.Info("Moving %s to %s...", vhdPath, to)
+	if err := ctx.WSL.RenameFile(wslPath, toWSLPath); err != nil {
+		remount(vhdPath)
+		startUnits(ctx, stopped)
+		return &types.VHDError{Op: op, Path: vhdPath, Err: err}
+	}
+
+	// Move the tracking entry and the snapshots
+	res := MoveResult{Path: vhdPath, To: to, op: op}
+	if trackErr == nil {
+		if err := ctx.Tracker.Rename(vhdPath, to); err != nil {
+			log.Collect("Failed to update tracking: %v", err)
+		} else {
+			res.Tracked = true
+			res.Snapshots = moveSnapshots(ctx, to, entry.Snapshots)
+		}
+	}
+
+	// Units name the VHD path in their descriptions and attach commands
+	units, err = rewriteUnitPaths(ctx, vhdPath, to)
+	if err != nil {
+		log.Warn("Failed to update systemd units: %v", err)
+	}
+	res.Units = units
+	if len(units) > 0 {
+		if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+			log.Warn("Failed to reload systemd daemon: %v", err)
+		}
+	}
+
+	// A service with the default name follows the file name
+	newUnit := defaultServiceName(to) + ".service"
+	if service == defaultServiceName(vhdPath)+".service" && service != newUnit {
+		if _, err = renameUnit(ctx, service, newUnit); err != nil {
+			log.Warn("Failed to rename service %s to %s: %v", service, newUnit, err)
+		} else {
+			res.Service = newUnit
+			recordService(ctx, to, newUnit)
+			for i, unit := range stopped {
+				if unit == service {
+					stopped[i] = newUnit
+				}
+			}
+		}
+	}
+
+	if remount(to) {
+		res.MountPoint = mountPoint
+	} else if entry.UUID != "" && mountPoint == "" {
+		// The ID file names the old path
+		createIDFile(ctx, to)
+	}
+	startUnits(ctx, stopped)
+
+	// Output
+	log.Success("VHD %s to %s", res.verb(), to)
+	return printResult(ctx, res)
+}
+
+// stopActiveUnits stops those of units that are active and returns them, in
+// the order to start them again
+func stopActiveUnits(ctx *AppContext, units []string) []string {
+	var stopped []string
+	for i := len(units) - 1; i >= 0; i-- {
+		unit := units[i]
+		if slices.Contains(stopped, unit) {
+			continue
+		}
+		output, _ := systemctlOutput(ctx, "is-active", unit)
+		if strings.TrimSpace(string(output)) != "active" {
+			continue
+		}
+		ctx.Logger.Info("Stopping %s...", unit)
+		if output, err := systemctl(ctx, "stop", unit); err != nil {
+			ctx.Logger.Warn("Failed to stop %s: %v\n%s", unit, err, output)
+			continue
+		}
+		stopped = append([]string{unit}, stopped...)
+	}
+	return stopped
+}
+
+// startUnits starts the units stopActiveUnits stopped
+func startUnits(ctx *AppContext, units []string) {
+	for _, unit := range units {
+		if output, err := systemctl(ctx, "start", unit); err != nil {
+			ctx.Logger.Warn("Failed to start %s: %v\n%s", unit, err, output)
+		}
+	}
+}
+
+// moveSnapshots moves the snapshot files of a VHD next to its new path and
+// records their new paths, returning how many moved
+func moveSnapshots(ctx *AppContext, to string, snapshots []types.Snapshot) int {
+	moved := 0
+	for i, snap := range snapshots {
+		file := snapshotFile(to, snap.Name)
+		if err := ctx.WSL.RenameFile(ctx.WSL.ConvertPath(snap.File), ctx.WSL.ConvertPath(file)); err != nil {
+			ctx.Logger.Warn("Failed to move snapshot %s, it stays at %s: %v", snap.Name, snap.File, err)
+			continue
+		}
+		snapshots[i].File = file
+		moved++
+	}
+	if moved == 0 {
+		return 0
+	}
+	err := ctx.Tracker.Update(to, func(entry *types.TrackingEntry) {
+		entry.Snapshots = snapshots
+	})
+	if err != nil {
+		ctx.Logger.Collect("Failed to save tracking info: %v", err)
+	}
+	return moved
+}
+
+// renameUnit renames an installed unit along with its drop-ins and the
+// dependencies of other units on it, enabling the new unit when the old one
+// was. It reports whether the old unit was active, so the caller starts the
+// new one when ready; on errors the old unit is left as it was.
+func renameUnit(ctx *AppContext, oldUnit, newUnit string) (bool, error) {
+	oldPath := unitFilePath(ctx, oldUnit)
+	newPath := filepath.Join(ctx.Config.UnitDir, newUnit)
+	if _, err := os.Stat(unitFilePath(ctx, newUnit)); err == nil {
+		return false, fmt.Errorf("unit %s already exists", newUnit)
+	}
+	content, err := os.ReadFile(oldPath)
+	if err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(newPath, content, 0644); err != nil {
+		return false, err
+	}
+
+	output, _ := systemctlOutput(ctx, "is-enabled", oldUnit)
+	enabled := strings.TrimSpace(string(output)) == "enabled"
+	output, _ = systemctlOutput(ctx, "is-active", oldUnit)
+	active := strings.TrimSpace(string(output)) == "active"
+	if active {
+		if _, err := systemctl(ctx, "stop", oldUnit); err != nil {
+			ctx.Logger.Debug("Failed to stop %s: %v", oldUnit, err)
+		}
+	}
+	if enabled {
+		if _, err := systemctl(ctx, "disable", oldUnit); err != nil {
+			ctx.Logger.Debug("Failed to disable %s: %v", oldUnit, err)
+		}
+	}
+
+	if err := os.Remove(oldPath); err != nil {
+		ctx.Logger.Warn("Failed to remove %s: %v", oldPath, err)
+	}
+	dropIns := filepath.Join(ctx.Config.UnitDir, oldUnit+".d")
+	if err := os.Rename(dropIns, filepath.Join(ctx.Config.UnitDir, newUnit+".d")); err != nil && !os.IsNotExist(err) {
+		ctx.Logger.Warn("Failed to move the drop-ins of %s: %v", oldUnit, err)
+	}
+	if _, err := rewriteUnitReferences(ctx, oldUnit, newUnit); err != nil {
+		ctx.Logger.Warn("Failed to update units depending on %s: %v", oldUnit, err)
+	}
+
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		ctx.Logger.Warn("Failed to reload systemd daemon: %v", err)
+	}
+	if enabled {
+		if output, err := systemctl(ctx, "enable", newUnit); err != nil {
+			ctx.Logger.Warn("Failed to enable %s: %v\n%s", newUnit, err, output)
+		}
+	}
+	return active, nil
+}
+
+// MoveResult is the outcome of 'vhdm move' and 'vhdm rename'
+type MoveResult struct {
+	Path       string   `json:"path"`
+	To         string   `json:"to"`
+	Tracked    bool     `json:"tracked"`              // The tracking entry moved to To
+	Snapshots  int      `json:"snapshots,omitempty"`  // Snapshot files moved along
+	Units      []string `json:"units,omitempty"`      // systemd units rewritten
+	Service    string   `json:"service,omitempty"`    // New name of the service named after the file
+	MountPoint string   `json:"mountPoint,omitempty"` // Where the VHD was mounted again
+
+	op string
+}
+
+// verb is "moved" or "renamed", after the command
+func (r MoveResult) verb() string {
+	if r.op == "rename" {
+		return "renamed"
+	}
+	return "moved"
+}
+
+func (r MoveResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{
+		{"Path", r.Path},
+		{"New Path", r.To},
+	}
+	if r.Snapshots > 0 {
+		pairs = append(pairs, [2]string{"Snapshots", fmt.Sprintf("%d moved", r.Snapshots)})
+	}
+	if len(r.Units) > 0 {
+		pairs = append(pairs, [2]string{"Units", strings.Join(r.Units, ", ")})
+	}
+	if r.Service != "" {
+		pairs = append(pairs, [2]string{"Service", r.Service})
+	}
+	if r.MountPoint != "" {
+		pairs = append(pairs, [2]string{"Mount Point", r.MountPoint})
+	}
+	status := r.verb()
+	if r.Tracked {
+		status += " (tracking updated)"
+	}
+	title := "Move Result"
+	if r.op == "rename" {
+		title = "Rename Result"
+	}
+	return title, append(pairs, [2]string{"Status", status})
+}
+
+func (r MoveResult) quiet() quietLine {
+	return quietLine{Key: r.Path, State: r.verb() + " to " + r.To}
+}
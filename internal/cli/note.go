@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+)
+
+func newNoteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "note",
+		Short: "Attach a description to a tracked VHD",
+		Long: `Store a free-form note with a tracked VHD (what it holds, which project it
+belongs to, ...). Notes are shown by 'vhdm status'.`,
+	}
+
+	cmd.AddCommand(
+		newNoteSetCmd(),
+		newNoteGetCmd(),
+	)
+
+	return cmd
+}
+
+func newNoteSetCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:   "set <text>",
+		Short: "Set the note of a VHD (an empty text removes it)",
+		Example: `  vhdm note set --vhd-path C:/VMs/pgdata.vhdx "PostgreSQL data for project X"
+  vhdm note set --vhd-path C:/VMs/pgdata.vhdx ""`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runNoteSet(appContext(cmd), vhdPath, strings.Join(args, " "))
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func newNoteGetCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:     "get",
+		Short:   "Print the note of a VHD",
+		Example: `  vhdm note get --vhd-path C:/VMs/pgdata.vhdx`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runNoteGet(appContext(cmd), vhdPath)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+// trackedEntry validates vhdPath and returns its tracking entry
+func trackedEntry(ctx *AppContext, op, vhdPath string) (types.TrackingEntry, error) {
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return types.TrackingEntry{}, &types.VHDError{Op: op, Path: vhdPath, Err: err}
+	}
+	entry, err := ctx.Tracker.GetEntry(vhdPath)
+	if err != nil {
+		return types.TrackingEntry{}, &types.VHDError{
+			Op:   op,
+			Path: vhdPath,
+			Err:  types.ErrVHDNotTracked,
+			Help: "Attach or mount the VHD at least once so it is tracked",
+		}
+	}
+	return entry, nil
+}
+
+func runNoteSet(ctx *AppContext, vhdPath, text string) error {
+	log := ctx.Logger
+
+	text = strings.TrimSpace(text)
+	if strings.ContainsAny(text, "\n\r") {
+		return &types.VHDError{Op: "note set", Err: fmt.Errorf("note must be a single line")}
+	}
+	if _, err := trackedEntry(ctx, "note set", vhdPath); err != nil {
+		return err
+	}
+
+	err := ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+		entry.Note = text
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save note: %w", err)
+	}
+
+	// Output
+	if ctx.Config.Quiet {
+		if text == "" {
+			printQuiet(quietLine{Key: vhdPath, State: "note removed"})
+		} else {
+			printQuiet(quietLine{Key: vhdPath, State: "note set"})
+		}
+		return nil
+	}
+
+	if text == "" {
+		log.Success("Note removed from %s", vhdPath)
+	} else {
+		log.Success("Note saved for %s", vhdPath)
+	}
+	return nil
+}
+
+func runNoteGet(ctx *AppContext, vhdPath string) error {
+
+	entry, err := trackedEntry(ctx, "note get", vhdPath)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Config.Quiet {
+		printQuiet(quietLine{Key: vhdPath, State: valueOrNone(entry.Note)})
+		return nil
+	}
+	if entry.Note == "" {
+		ctx.Logger.Info("%s has no note", vhdPath)
+		return nil
+	}
+	fmt.Println(entry.Note)
+	return nil
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	"github.com/rjdinis/vhdm/internal/wsl"
+)
+
+// monitorStateDir records which mount point monitors have already notified
+// a failure. It is on tmpfs, so a reboot notifies again.
+var monitorStateDir = "/run/vhdm"
+
+// notifyFailure shows a desktop notification of a failure when VHDM_NOTIFY
+// is set, so failures of boot services and scripts do not go unnoticed.
+// Notification errors are only logged.
+func notifyFailure(ctx *AppContext, title string, err error) {
+	method := ctx.Config.Notify
+	if method == "" || method == wsl.NotifyOff {
+		return
+	}
+	if err := ctx.WSL.Notify(method, "vhdm: "+title, err.Error()); err != nil {
+		ctx.Logger.Warn("%v", err)
+	}
+}
+
+// notifyEnvLine returns the unit Environment line passing VHDM_NOTIFY on to
+// a service, or an empty string when notifications are off
+func notifyEnvLine(ctx *AppContext) string {
+	method := ctx.Config.Notify
+	if method == "" || method == wsl.NotifyOff {
+		return ""
+	}
+	return systemdEnvironment("VHDM_NOTIFY", method) + "\n"
+}
+
+// notifyMonitorFailure notifies a failure of the monitor of a mount point
+// once until a health check passes again: systemd restarts a failing monitor
+// every few seconds, and each restart would show another notification. The
+// notification is remembered in a marker file that outlives the process.
+func notifyMonitorFailure(ctx *AppContext, mountPoint, title string, err error) {
+	marker := monitorMarkerPath(mountPoint)
+	if _, statErr := os.Stat(marker); statErr == nil {
+		ctx.Logger.Debug("Failure of %s already notified", mountPoint)
+		return
+	}
+	notifyFailure(ctx, title, err)
+	if err := os.MkdirAll(monitorStateDir, 0755); err == nil {
+		err = os.WriteFile(marker, nil, 0644)
+	}
+	if err != nil {
+		ctx.Logger.Debug("Failed to record the notification: %v", err)
+	}
+}
+
+// monitorRecovered lets the monitor of a mount point notify the next failure
+func monitorRecovered(mountPoint string) {
+	os.Remove(monitorMarkerPath(mountPoint))
+}
+
+// monitorMarkerPath returns the marker file of a notified mount point
+func monitorMarkerPath(mountPoint string) string {
+	sum := sha256.Sum256([]byte(mountPoint))
+	return filepath.Join(monitorStateDir, "monitor-"+hex.EncodeToString(sum[:8])+".notified")
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+)
+
+func newOpenCmd() *cobra.Command {
+	var (
+		vhdPath    string
+		mountPoint string
+		readOnly   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "open",
+		Short: "Mount a VHD and open a shell in it",
+		Long: `Ensure a VHD is mounted and start an interactive shell ($SHELL) with the
+working directory at the mount point.
+
+If vhdm attached or mounted the VHD, it is unmounted and detached again when the
+shell exits. Without --mount-point, an unmounted VHD is mounted to a temporary
+directory. The mount point is also exported as $VHDM_MOUNT.`,
+		Example: `  vhdm open --vhd-path C:/VMs/data.vhdx
+  vhdm open --vhd-path C:/VMs/data.vhdx --mount-point /mnt/data
+  vhdm open --vhd-path C:/VMs/data.vhdx --read-only`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOpen(appContext(cmd), vhdPath, mountPoint, readOnly)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&mountPoint, "mount-point", "", "Mount point path (temporary directory if not set)")
+	cmd.Flags().BoolVar(&readOnly, "read-only", false, "Mount the VHD read-only")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runOpen(ctx *AppContext, vhdPath, mountPoint string, readOnly bool) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "open", Path: vhdPath, Err: err}
+	}
+	if mountPoint != "" {
+		mountPoint = expandMountPoint(ctx, mountPoint, vhdPath, "")
+		if err := validation.ValidateMountPoint(mountPoint); err != nil {
+			return &types.VHDError{Op: "open", Err: err}
+		}
+	}
+
+	log.Debug("Open operation starting")
+
+	options := ""
+	if readOnly {
+		options = "ro"
+	}
+	m, err := acquireMount(ctx, vhdPath, mountPoint, options)
+	if err != nil {
+		return &types.VHDError{Op: "open", Path: vhdPath, Err: err}
+	}
+	defer m.release(ctx)
+
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	if m.mountedByUs {
+		log.Info("Mounted %s at %s (unmounted when the shell exits)", vhdPath, m.MountPoint)
+	} else {
+		log.Info("%s is mounted at %s", vhdPath, m.MountPoint)
+	}
+
+	cmd := exec.Command(shell)
+	cmd.Dir = m.MountPoint
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"VHDM_MOUNT="+m.MountPoint,
+		"VHDM_VHD_PATH="+vhdPath,
+		"VHDM_UUID="+m.UUID,
+	)
+
+	return runChild(cmd)
+}
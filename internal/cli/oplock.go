@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// opLockDir holds the per-VHD operation locks. Like the attach lock it lives
+// in /run/lock, shared by root and users, with a temp dir fallback.
+const opLockDir = "/run/lock"
+
+// lockVHDOperation takes the machine-wide lock that keeps two vhdm processes
+// from resizing, formatting or deleting the same VHD at once. It fails
+// immediately when another process holds it. key is the VHD path, or
+// /dev/<name> for devices whose VHD is unknown.
+func lockVHDOperation(ctx *AppContext, op, key string) (*utils.FileLock, error) {
+	path := opLockPath(key)
+	ctx.Logger.Debug("Acquiring operation lock for %s: %s", key, path)
+
+	lock, err := utils.LockFile(path, 0)
+	if err == nil {
+		return lock, nil
+	}
+	if !errors.Is(err, utils.ErrLockTimeout) {
+		return nil, fmt.Errorf("failed to lock %s: %w", key, err)
+	}
+
+	holder := "another vhdm process"
+	if pid, _ := utils.LockHolder(path); pid != 0 {
+		holder = fmt.Sprintf("PID %d", pid)
+	}
+	return nil, &types.VHDError{
+		Op:   op,
+		Path: key,
+		Err:  fmt.Errorf("operation in progress by %s", holder),
+		Help: "Wait for it to finish and try again",
+	}
+}
+
+// ensureNotInUseByWindows fails with types.ErrFileInUse when the VHD file is
+// open in a Windows process, which would otherwise surface later as a generic
+// delete or rename failure. When the check itself fails it is skipped.
+func ensureNotInUseByWindows(ctx *AppContext, op, vhdPath string) error {
+	inUse, err := ctx.WSL.FileInUseByWindows(vhdPath)
+	if err != nil {
+		ctx.Logger.Debug("Skipping Windows file lock check: %v", err)
+		return nil
+	}
+	if !inUse {
+		return nil
+	}
+	return &types.VHDError{
+		Op:   op,
+		Path: vhdPath,
+		Err:  types.ErrFileInUse,
+		Help: fileInUseHelp,
+	}
+}
+
+// fileInUseHelp is the help of types.ErrFileInUse failures
+const fileInUseHelp = "Close the program holding it (Hyper-V, a backup tool, an Explorer preview) and try again.\n" +
+	"Resource Monitor > CPU > Associated Handles shows which process has it open"
+
+// opLockPath returns the lock file of a VHD path or device. Paths are
+// compared case-insensitively, like the tracking file does.
+func opLockPath(key string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.ReplaceAll(key, "\\", "/"))))
+	name := "vhdm-op-" + hex.EncodeToString(sum[:8]) + ".lock"
+
+	dir := opLockDir
+	if _, err := os.Stat(dir); err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, name)
+}
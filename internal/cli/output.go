@@ -0,0 +1,449 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/rjdinis/vhdm/internal/config"
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// result is the outcome of a command. Runners fill a typed result
+// (MountResult, ResizeResult, ...) and hand it to printResult, so every
+// command renders the same way in table, quiet, JSON, YAML and CSV output.
+type result interface {
+	// table returns the title and rows of the key/value result table, colored
+	// with the theme
+	table(utils.Theme) (string, [][2]string)
+	// quiet returns the one-line summary printed in quiet mode
+	quiet() quietLine
+}
+
+// quietLine is what quiet mode prints for one object: exactly one line, in
+// the grammar scripts parse, "<key> (<uuid>): <state>", or "<key>: <state>"
+// when there is no UUID. The key is the VHD path, or the device, mount point
+// or other name the line is about.
+type quietLine struct {
+	Key   string
+	UUID  string
+	State string
+}
+
+// quietBreaks are replaced in the parts of a quiet line so it stays one line
+var quietBreaks = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+func (l quietLine) String() string {
+	key, state := quietBreaks.Replace(l.Key), quietBreaks.Replace(l.State)
+	if l.UUID != "" {
+		return fmt.Sprintf("%s (%s): %s", key, quietBreaks.Replace(l.UUID), state)
+	}
+	return key + ": " + state
+}
+
+// printQuiet writes quiet lines to stdout. Everything printed in quiet mode
+// goes through it, and informational messages go through the logger, which
+// drops them, so stdout holds one line per object and nothing else.
+func printQuiet(lines ...quietLine) {
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+// outputFormats are the values of --output and VHDM_OUTPUT
+var outputFormats = []string{"table", "json", "yaml", "csv", "sh"}
+
+// printResult renders r with the --format template, as JSON, YAML or CSV
+// (--output json|yaml|csv), as shell assignments (--output sh), as its quiet
+// line, or as a key/value table.
+// Progress and hints go through the logger on stderr, so stdout only ever
+// carries the result. Warnings collected by the logger (Logger.Collect) are
+// reported once with the result: in a Warnings section of the table, under
+// "warnings" in JSON and YAML, and on stderr in the other formats.
+func printResult(ctx *AppContext, r result) error {
+	warnings := ctx.Logger.TakeWarnings()
+	switch {
+	case ctx.Config.Format != "":
+		defer logWarnings(ctx, warnings)
+		return printTemplate(ctx, r)
+	case ctx.Config.Output == "csv":
+		defer logWarnings(ctx, warnings)
+		return printStructured(ctx, r)
+	case structuredOutput(ctx):
+		return printStructured(ctx, withWarnings(r, warnings))
+	case ctx.Config.Output == "sh":
+		for _, line := range shellAssignments(r) {
+			fmt.Println(line)
+		}
+		logWarnings(ctx, warnings)
+	case ctx.Config.Quiet:
+		printQuiet(r.quiet())
+		logWarnings(ctx, warnings)
+	default:
+		title, pairs := r.table(ctx.Theme)
+		utils.KeyValueTable(title, pairs, 14, 50)
+		printWarnings(ctx, warnings)
+	}
+	return nil
+}
+
+// withWarnings returns the JSON object of v with the warnings added under
+// "warnings", or v itself when there are none
+func withWarnings(v any, warnings []string) any {
+	if len(warnings) == 0 {
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil || !bytes.HasSuffix(data, []byte("}")) {
+		return v
+	}
+	list, _ := json.Marshal(warnings)
+	data = data[:len(data)-1]
+	if !bytes.HasSuffix(data, []byte("{")) {
+		data = append(data, ',')
+	}
+	data = append(data, `"warnings":`...)
+	data = append(append(data, list...), '}')
+	return json.RawMessage(data)
+}
+
+// printWarnings prints the Warnings section below a result table
+func printWarnings(ctx *AppContext, warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Printf("\n%s\n", ctx.Theme.Yellow("Warnings"))
+	for _, msg := range warnings {
+		fmt.Printf("  - %s\n", msg)
+	}
+}
+
+// logWarnings logs warnings on stderr, for the formats with no place for them
+func logWarnings(ctx *AppContext, warnings []string) {
+	for _, msg := range warnings {
+		ctx.Logger.Warn("%s", msg)
+	}
+}
+
+// addFormatFlag adds --format to a command printing results. The command
+// calls setFormat with its value before running.
+func addFormatFlag(cmd *cobra.Command, format *string) {
+	cmd.Flags().StringVar(format, "format", "", "Print each result with a Go template, e.g. '{{.UUID}} {{.MountPoint}}' (fields as in --output json, by Go name)")
+}
+
+// setFormat validates a --format template and makes the results use it
+func setFormat(ctx *AppContext, format string) error {
+	if format == "" {
+		return nil
+	}
+	if _, err := parseFormat(format); err != nil {
+		return err
+	}
+	ctx.Config.SetFormat(format)
+	return nil
+}
+
+// formatFuncs are the functions available in --format templates
+var formatFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": func(items []string, sep string) string {
+		return strings.Join(items, sep)
+	},
+}
+
+func parseFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(formatFuncs).Option("missingkey=error").Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// printTemplate prints v with the --format template, once per element when v
+// is a slice. Each output ends with a newline, like docker's --format.
+func printTemplate(ctx *AppContext, v any) error {
+	tmpl, err := parseFormat(ctx.Config.Format)
+	if err != nil {
+		return err
+	}
+	items := []any{v}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		items = make([]any, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, item := range items {
+		if err := tmpl.Execute(&buf, item); err != nil {
+			return fmt.Errorf("--format: %w", err)
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+	}
+	fmt.Print(buf.String())
+	return nil
+}
+
+// structuredOutput reports whether --output asks for JSON, YAML or CSV.
+// Commands listing several items check it to print their items instead of a
+// table.
+func structuredOutput(ctx *AppContext) bool {
+	return ctx.Config.Output == "json" || ctx.Config.Output == "yaml" || ctx.Config.Output == "csv"
+}
+
+// checkListOutput fails on --output sh in commands listing several items:
+// shell assignments hold a single result, so the items would overwrite each
+// other. --format templates still apply.
+func checkListOutput(ctx *AppContext, op string) error {
+	if ctx.Config.Output != "sh" || ctx.Config.Format != "" {
+		return nil
+	}
+	return &types.VHDError{
+		Op:   op,
+		Err:  fmt.Errorf("%w: --output sh prints a single result, not a list", types.ErrInvalidInput),
+		Help: "Use --output json, yaml or csv to list the items",
+	}
+}
+
+// printStructured prints v as JSON, YAML or CSV, following --output. The YAML
+// document uses the JSON field names and order, so both formats have the same
+// keys; CSV uses them as the header (see printCSV).
+func printStructured(ctx *AppContext, v any) error {
+	if ctx.Config.Output == "csv" {
+		return printCSV(v)
+	}
+	var data []byte
+	var err error
+	if ctx.Config.Output == "yaml" {
+		data, err = encodeYAML(v)
+	} else {
+		data, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	fmt.Println(strings.TrimRight(string(data), "\n"))
+	return nil
+}
+
+// printCSV prints v, a slice of structs or a single struct, as CSV: a header
+// of the JSON field names, then a record per item. Lists are joined with
+// spaces and nested objects are written as JSON, so every row keeps the same
+// columns for spreadsheets.
+func printCSV(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	itemType := rv.Type()
+	items := []reflect.Value{rv}
+	if rv.Kind() == reflect.Slice {
+		itemType = itemType.Elem()
+		if itemType.Kind() == reflect.Pointer {
+			itemType = itemType.Elem()
+		}
+		items = make([]reflect.Value, rv.Len())
+		for i := range items {
+			items[i] = reflect.Indirect(rv.Index(i))
+		}
+	}
+	if itemType.Kind() != reflect.Struct {
+		return fmt.Errorf("--output csv is not supported for this result")
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	header, _ := resultFields(reflect.New(itemType).Elem())
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, item := range items {
+		_, values := resultFields(item)
+		if err := w.Write(values); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// encodeYAML encodes v as YAML by way of its JSON encoding, so json tags,
+// omitempty and custom marshalers apply to YAML output too
+func encodeYAML(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	plainYAMLStyle(&doc)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yaml11Bools are strings that YAML 1.1 parsers (PyYAML, and so Ansible)
+// read as booleans, while the YAML 1.2 encoder leaves them unquoted
+var yaml11Bools = map[string]bool{
+	"y": true, "yes": true, "n": true, "no": true, "on": true, "off": true,
+}
+
+// plainYAMLStyle drops the flow style and quoting that JSON input leaves on
+// the nodes, so the encoder writes block YAML and quotes only where needed
+func plainYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" && yaml11Bools[strings.ToLower(n.Value)] {
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	for _, c := range n.Content {
+		plainYAMLStyle(c)
+	}
+}
+
+// nonNil returns s, or an empty slice when s is nil, so structured output
+// shows an empty list rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// appendDevice adds a Device row for devName when it is known
+func appendDevice(pairs [][2]string, devName string) [][2]string {
+	if devName == "" {
+		return pairs
+	}
+	return append(pairs, [2]string{"Device", "/dev/" + devName})
+}
+
+// shellVarNames renames result fields whose variable name would otherwise be
+// clumsy, keyed by JSON name
+var shellVarNames = map[string]string{
+	"deviceName": "VHDM_DEVICE",
+}
+
+// shellSafeRe matches values that need no quoting in a shell assignment
+var shellSafeRe = regexp.MustCompile(`^[A-Za-z0-9_./:,@%+=-]+$`)
+
+// shellAssignments returns r as VHDM_<FIELD>=value lines for
+// eval "$(vhdm ... --output sh)", one per field of the JSON result in field
+// order. Empty fields are assigned too, so scripts see every variable; lists
+// are joined with spaces.
+func shellAssignments(r any) []string {
+	v := reflect.Indirect(reflect.ValueOf(r))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	names, values := resultFields(v)
+	lines := make([]string, len(names))
+	for i, name := range names {
+		varName, ok := shellVarNames[name]
+		if !ok {
+			varName = "VHDM_" + upperSnake(name)
+		}
+		lines[i] = varName + "=" + shellQuote(values[i])
+	}
+	return lines
+}
+
+// resultFields returns the JSON names and the values as text of the fields of
+// a struct, in field order, for the flat formats (sh, csv). Lists are joined
+// with spaces, nested objects are JSON and nil pointers are empty.
+func resultFields(v reflect.Value) (names, values []string) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		var value string
+		switch fv := v.Field(i); fv.Kind() {
+		case reflect.Slice:
+			items := make([]string, fv.Len())
+			for j := range items {
+				items[j] = fmt.Sprint(fv.Index(j).Interface())
+			}
+			value = strings.Join(items, " ")
+		case reflect.Pointer, reflect.Struct, reflect.Map:
+			if !fv.IsZero() {
+				data, _ := json.Marshal(fv.Interface())
+				value = string(data)
+			}
+		default:
+			value = fmt.Sprint(fv.Interface())
+		}
+		names = append(names, name)
+		values = append(values, value)
+	}
+	return names, values
+}
+
+// upperSnake converts a camelCase JSON name to UPPER_SNAKE_CASE, keeping
+// acronyms together (newUUID -> NEW_UUID)
+func upperSnake(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// shellQuote quotes s for a POSIX shell when it contains special characters
+func shellQuote(s string) string {
+	if shellSafeRe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// timeFormat returns the timestamp format to display: VHDM_TIME_FORMAT, or
+// always RFC 3339 in quiet, JSON, YAML, CSV and sh output and with --format
+// templates so they stay machine-parsable
+func timeFormat(cfg *config.Config) string {
+	if cfg.Quiet || cfg.Format != "" || cfg.Output != "table" {
+		return "rfc3339"
+	}
+	return cfg.TimeFormat
+}
+
+// displayTime formats a stored RFC 3339 timestamp for display (see
+// timeFormat). Values that do not parse are shown as stored.
+func displayTime(ctx *AppContext, stored string) string {
+	t, err := time.Parse(time.RFC3339, stored)
+	if err != nil {
+		return stored
+	}
+	return utils.FormatTime(t, timeFormat(ctx.Config))
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+func newPinCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:   "pin",
+		Short: "Protect a VHD against delete, format and resize",
+		Long: `Mark a tracked VHD as pinned. delete, format and resize refuse to touch a
+pinned VHD unless --unpin is passed, which removes the pin and proceeds.`,
+		Example: "  vhdm pin --vhd-path C:/VMs/pgdata.vhdx",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPin(appContext(cmd), vhdPath, true)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func newUnpinCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:     "unpin",
+		Short:   "Remove the protection set by 'vhdm pin'",
+		Example: "  vhdm unpin --vhd-path C:/VMs/pgdata.vhdx",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPin(appContext(cmd), vhdPath, false)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runPin(ctx *AppContext, vhdPath string, pinned bool) error {
+	log := ctx.Logger
+
+	op := "pin"
+	state := "pinned"
+	if !pinned {
+		op = "unpin"
+		state = "unpinned"
+	}
+
+	if _, err := trackedEntry(ctx, op, vhdPath); err != nil {
+		return err
+	}
+
+	err := ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+		entry.Pinned = pinned
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update tracking: %w", err)
+	}
+
+	if ctx.Config.Quiet {
+		printQuiet(quietLine{Key: vhdPath, State: state})
+		return nil
+	}
+	log.Success("VHD %s: %s", state, vhdPath)
+	return nil
+}
+
+// checkPinned rejects a destructive operation on a pinned VHD unless unpin
+// (the command's --unpin flag) is set
+func checkPinned(ctx *AppContext, op, vhdPath string, unpin bool) error {
+	entry, err := ctx.Tracker.GetEntry(vhdPath)
+	if err != nil || !entry.Pinned || unpin {
+		return nil
+	}
+	return &types.VHDError{
+		Op:   op,
+		Path: vhdPath,
+		Err:  types.ErrVHDPinned,
+		Help: fmt.Sprintf("Unpin it first with 'vhdm unpin --vhd-path %s', or pass --unpin", vhdPath),
+	}
+}
+
+// releasePin removes the pin of a VHD once a destructive operation run with
+// --unpin has been confirmed
+func releasePin(ctx *AppContext, vhdPath string) {
+	entry, err := ctx.Tracker.GetEntry(vhdPath)
+	if err != nil || !entry.Pinned {
+		return
+	}
+	err = ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+		entry.Pinned = false
+	})
+	if err != nil {
+		ctx.Logger.Warn("Failed to unpin %s: %v", vhdPath, err)
+		return
+	}
+	ctx.Logger.Warn("Unpinned %s", vhdPath)
+}
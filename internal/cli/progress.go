@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// progressBarWidth is the number of cells of a progress bar
+const progressBarWidth = 30
+
+// copyProgress shows the progress of a long rsync copy on stderr: a bar
+// redrawn in place on a terminal, else a line every 10%. Nothing is shown in
+// quiet mode.
+type copyProgress struct {
+	out      io.Writer
+	terminal bool
+	shown    int // Last percentage shown, -1 before the first report
+}
+
+// newCopyProgress returns the progress display of a copy, or nil in quiet
+// mode; a nil *copyProgress passes a nil callback to RsyncCopy
+func newCopyProgress(ctx *AppContext) *copyProgress {
+	if ctx.Config.Quiet {
+		return nil
+	}
+	return &copyProgress{out: os.Stderr, terminal: stderrIsTerminal(), shown: -1}
+}
+
+// callback returns the function to pass to RsyncCopy
+func (p *copyProgress) callback() func(wsl.RsyncProgress) {
+	if p == nil {
+		return nil
+	}
+	return p.update
+}
+
+func (p *copyProgress) update(r wsl.RsyncProgress) {
+	percent := min(max(r.Percent, 0), 100)
+	if p.terminal {
+		fmt.Fprintf(p.out, "\r%s", progressLine(r, percent))
+		p.shown = percent
+		return
+	}
+	if percent/10 > p.shown/10 || p.shown < 0 {
+		fmt.Fprintln(p.out, progressLine(r, percent))
+		p.shown = percent
+	}
+}
+
+// done ends the bar's line, once the copy finished or failed
+func (p *copyProgress) done() {
+	if p != nil && p.terminal && p.shown >= 0 {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// progressLine renders a progress report, e.g.
+// "[#########.....................]  30%  1.2GB  250.00MB/s  ETA 0:00:12"
+func progressLine(r wsl.RsyncProgress, percent int) string {
+	filled := percent * progressBarWidth / 100
+	bar := strings.Repeat("#", filled) + strings.Repeat(".", progressBarWidth-filled)
+	eta := "ETA " + r.ETA
+	if percent == 100 {
+		eta = "in " + r.ETA
+	}
+	return fmt.Sprintf("[%s] %3d%%  %s  %s  %s", bar, percent, utils.BytesToHuman(r.Bytes), r.Rate, eta)
+}
+
+// stderrIsTerminal reports whether stderr is an interactive terminal
+func stderrIsTerminal() bool {
+	fi, err := os.Stderr.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
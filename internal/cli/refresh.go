@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newRefreshCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:   "refresh",
+		Short: "Update tracking of a VHD from the system without changing it",
+		Long: `Re-probe the device, mount points and filesystem type of a tracked VHD and
+update its tracking entry to match. Nothing is attached, mounted or unmounted.
+
+Useful after mounting or unmounting a VHD by hand (mount, umount, wsl.exe
+--mount) outside vhdm; this also clears the "externally managed" mark that
+'vhdm status' sets when it notices such changes. The VHD is found by its
+tracked UUID; when no attached device has that UUID it is recorded as detached.`,
+		Example: `  vhdm refresh --vhd-path C:/VMs/disk.vhdx`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRefresh(appContext(cmd), vhdPath)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runRefresh(ctx *AppContext, vhdPath string) error {
+	log := ctx.Logger
+
+	entry, err := trackedEntry(ctx, "refresh", vhdPath)
+	if err != nil {
+		return err
+	}
+
+	log.Debug("Refresh operation starting")
+
+	res := RefreshResult{Path: vhdPath, UUID: entry.UUID, Status: "detached"}
+	fsType := ""
+	if entry.UUID != "" {
+		devices, err := ctx.WSL.GetBlockDevicesWithInfo()
+		if err != nil {
+			return &types.VHDError{Op: "refresh", Path: vhdPath, Err: err}
+		}
+		for _, dev := range devices {
+			if dev.UUID != entry.UUID {
+				continue
+			}
+			res.DeviceName = dev.Name
+			res.MountPoints = filterEmptyMountPoints(dev.MountPoints)
+			fsType = dev.FSType
+			res.Status = "attached"
+			if len(res.MountPoints) > 0 {
+				res.Status = "mounted"
+			}
+			break
+		}
+	} else {
+		log.Warn("No UUID is tracked for %s; recording it as detached", vhdPath)
+	}
+
+	if entry.DeviceName != res.DeviceName {
+		res.Changes = append(res.Changes, fmt.Sprintf("device %s -> %s", valueOrNone(entry.DeviceName), valueOrNone(res.DeviceName)))
+	}
+	if !sameMountPoints(entry.MountPoints, res.MountPoints) {
+		res.Changes = append(res.Changes, fmt.Sprintf("mount points %s -> %s",
+			valueOrNone(strings.Join(entry.MountPoints, ",")), valueOrNone(strings.Join(res.MountPoints, ","))))
+	}
+	if fsType != "" && entry.FSType != fsType {
+		res.Changes = append(res.Changes, fmt.Sprintf("filesystem %s -> %s", valueOrNone(entry.FSType), fsType))
+	}
+
+	err = ctx.Tracker.Update(vhdPath, func(e *types.TrackingEntry) {
+		e.DeviceName = res.DeviceName
+		e.MountPoints = res.MountPoints
+		if len(res.MountPoints) > 0 {
+			e.LastMount = res.MountPoints[0]
+		}
+		if fsType != "" {
+			e.FSType = fsType
+		}
+		e.External = false
+		e.LastSeen = time.Now().Format(time.RFC3339)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update tracking: %w", err)
+	}
+
+	if len(res.Changes) == 0 {
+		log.Success("Tracking already up to date")
+	} else {
+		log.Success("Tracking updated")
+	}
+	return printResult(ctx, res)
+}
+
+// valueOrNone returns s, or "(none)" when it is empty
+func valueOrNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// RefreshResult is the outcome of refresh
+type RefreshResult struct {
+	Path        string   `json:"path"`
+	UUID        string   `json:"uuid,omitempty"`
+	DeviceName  string   `json:"deviceName,omitempty"`
+	MountPoints []string `json:"mountPoints,omitempty"`
+	Status      string   `json:"status"`
+	Changes     []string `json:"changes,omitempty"`
+}
+
+func (r RefreshResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{{"Path", r.Path}}
+
+	if r.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", r.UUID})
+	}
+	pairs = appendDevice(pairs, r.DeviceName)
+	if len(r.MountPoints) > 0 {
+		pairs = append(pairs, [2]string{"Mount Point", strings.Join(r.MountPoints, ", ")})
+	}
+	pairs = append(pairs, [2]string{"Status", r.Status})
+	changes := "none"
+	if len(r.Changes) > 0 {
+		changes = strings.Join(r.Changes, "; ")
+	}
+	pairs = append(pairs, [2]string{"Changes", changes})
+
+	return "VHD Refresh Result", pairs
+}
+
+func (r RefreshResult) quiet() quietLine {
+	return quietLine{Key: r.Path, UUID: r.UUID, State: r.Status}
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+func newRenameCmd() *cobra.Command {
+	var vhdPath, newName string
+	cmd := &cobra.Command{
+		Use:   "rename",
+		Short: "Rename a VHD file, keeping tracking and services consistent",
+		Long: `Rename a VHD file within its directory.
+
+This is 'vhdm move' to a new file name: a mounted VHD is unmounted, detached
+and mounted again, its filesystem UUID is unchanged, and the tracking entry,
+snapshots, ID file and units created by 'vhdm service' follow the new name.
+A service with the default name (vhdm-mount-<name>) is renamed after the file
+and enabled and started again if it was.
+
+The extension of the file is kept when --new-name has none.`,
+		Example: `  vhdm rename --vhd-path C:/VMs/data.vhdx --new-name data2.vhdx
+  vhdm rename --vhd-path C:/VMs/data.vhdx --new-name archive-2024`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRename(appContext(cmd), vhdPath, newName)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&newName, "new-name", "", "New file name, without directory")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("new-name")
+	return cmd
+}
+
+func runRename(ctx *AppContext, vhdPath, newName string) error {
+	if newName == "" || strings.ContainsAny(newName, `/\:`) || newName == "." || newName == ".." {
+		return &types.VHDError{
+			Op:   "rename",
+			Path: vhdPath,
+			Err:  fmt.Errorf("%w: new name must be a file name without directory: %q", types.ErrInvalidInput, newName),
+			Help: "Use 'vhdm move' to move a VHD to another directory",
+		}
+	}
+	dir, _ := path.Split(strings.ReplaceAll(vhdPath, `\`, "/"))
+	if ext := path.Ext(vhdPath); !strings.EqualFold(path.Ext(newName), ext) {
+		newName += ext
+	}
+	return moveVHD(ctx, "rename", vhdPath, dir+newName)
+}
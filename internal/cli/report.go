@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// reportEntry is a single VHD row of the capacity report
+type reportEntry struct {
+	Path          string         `json:"path"`
+	UUID          string         `json:"uuid,omitempty"`
+	State         types.VHDState `json:"state"`
+	Format        string         `json:"format,omitempty"`
+	VirtualSize   int64          `json:"virtualSize"`
+	AllocatedSize int64          `json:"allocatedSize"`
+	FSUse         string         `json:"fsUse,omitempty"`
+	Growth        *int64         `json:"growth,omitempty"` // Allocated bytes since last report
+	LastReport    string         `json:"lastReport,omitempty"`
+}
+
+// reportDrive summarizes the footprint of tracked VHDs on one Windows drive
+type reportDrive struct {
+	Drive         string `json:"drive"`
+	Count         int    `json:"count"`
+	VirtualSize   int64  `json:"virtualSize"`
+	AllocatedSize int64  `json:"allocatedSize"`
+}
+
+// capacityReport is the full report rendered as table, JSON or HTML
+type capacityReport struct {
+	GeneratedAt string        `json:"generatedAt"`
+	VHDs        []reportEntry `json:"vhds"`
+	Drives      []reportDrive `json:"drives"`
+}
+
+func newReportCmd() *cobra.Command {
+	var (
+		reportFormat string
+		noSave       bool
+	)
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Capacity planning report for tracked VHDs",
+		Long: `Summarize all tracked VHDs: virtual size, allocated (on-disk) size, filesystem
+usage, growth since the previous report, and total footprint per Windows drive.
+
+Each run records a size sample in the tracking file (up to VHDM_HISTORY_LIMIT
+samples per VHD) which is used to compute growth on the next run.
+
+The report follows the global --output (table, json, yaml or csv, which lists
+the VHDs); --report-format overrides it and also offers an HTML page.`,
+		Example: `  vhdm report
+  vhdm report --output json
+  vhdm report --report-format html > report.html`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReport(appContext(cmd), reportFormat, noSave)
+		},
+	}
+	cmd.Flags().StringVar(&reportFormat, "report-format", "", "Report format: "+strings.Join(reportFormats, ", ")+" (default: --output)")
+	cmd.RegisterFlagCompletionFunc("report-format", cobra.FixedCompletions(reportFormats, cobra.ShellCompDirectiveNoFileComp))
+	cmd.Flags().BoolVar(&noSave, "no-save", false, "Do not record size samples for this report")
+	return cmd
+}
+
+// reportFormats are the values of 'vhdm report --report-format'
+var reportFormats = []string{"table", "json", "yaml", "csv", "html"}
+
+func runReport(ctx *AppContext, reportFormat string, noSave bool) error {
+	log := ctx.Logger
+
+	format := ctx.Config.Output
+	if reportFormat != "" {
+		format = reportFormat
+	}
+	if !slices.Contains(reportFormats, format) {
+		return &types.VHDError{
+			Op:  "report",
+			Err: fmt.Errorf("%w: unsupported report format %q (use %s)", types.ErrInvalidInput, format, strings.Join(reportFormats, ", ")),
+		}
+	}
+
+	log.Debug("Report operation starting")
+
+	paths, err := ctx.Tracker.GetAllPaths()
+	if err != nil {
+		return fmt.Errorf("failed to get tracked VHDs: %w", err)
+	}
+	sort.Strings(paths)
+
+	now := time.Now().Format(time.RFC3339)
+	report := capacityReport{GeneratedAt: now}
+	drives := make(map[string]*reportDrive)
+
+	for _, path := range paths {
+		entry, err := ctx.Tracker.GetEntry(path)
+		if err != nil {
+			continue
+		}
+		info := getVHDStatus(ctx, path)
+		if info.State == types.StateNotFound {
+			continue
+		}
+
+		row := reportEntry{
+			Path:  path,
+			UUID:  info.UUID,
+			State: info.State,
+			FSUse: info.FSUse,
+		}
+
+		wslPath := ctx.WSL.ConvertPath(path)
+		if info.State == types.StateArchived {
+			row.Format = "zstd"
+			row.AllocatedSize, _ = ctx.WSL.FileSize(wslPath + wsl.ArchiveExt)
+		} else if img, err := ctx.WSL.GetImageInfo(wslPath); err == nil {
+			row.Format = img.Format
+			row.VirtualSize = img.VirtualSize
+			row.AllocatedSize = img.ActualSize
+		} else {
+			log.Debug("Failed to get image info for %s: %v", path, err)
+			row.AllocatedSize, _ = ctx.WSL.FileSize(wslPath)
+		}
+
+		if n := len(entry.SizeHistory); n > 0 {
+			last := entry.SizeHistory[n-1]
+			growth := row.AllocatedSize - last.AllocatedSize
+			row.Growth = &growth
+			row.LastReport = last.Time
+		}
+
+		if !noSave {
+			sample := types.SizeSample{Time: now, VirtualSize: row.VirtualSize, AllocatedSize: row.AllocatedSize}
+			if err := ctx.Tracker.AppendSizeSample(path, sample, ctx.Config.HistoryLimit); err != nil {
+				log.Warn("Failed to record size sample for %s: %v", path, err)
+			}
+		}
+
+		report.VHDs = append(report.VHDs, row)
+
+		drive := utils.WindowsDrive(path)
+		d, ok := drives[drive]
+		if !ok {
+			d = &reportDrive{Drive: drive}
+			drives[drive] = d
+		}
+		d.Count++
+		d.VirtualSize += row.VirtualSize
+		d.AllocatedSize += row.AllocatedSize
+	}
+
+	for _, d := range drives {
+		report.Drives = append(report.Drives, *d)
+	}
+	sort.Slice(report.Drives, func(i, j int) bool {
+		return report.Drives[i].Drive < report.Drives[j].Drive
+	})
+
+	switch format {
+	case "json", "yaml":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if format == "yaml" {
+			data, err = encodeYAML(report)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+		fmt.Println(strings.TrimRight(string(data), "\n"))
+		return nil
+	case "csv":
+		return printCSV(nonNil(report.VHDs))
+	case "html":
+		return reportHTMLTemplate.Execute(os.Stdout, report)
+	}
+
+	if ctx.Config.Quiet {
+		for _, row := range report.VHDs {
+			printQuiet(quietLine{Key: row.Path, State: fmt.Sprintf("%s allocated of %s", utils.BytesToHuman(row.AllocatedSize), utils.BytesToHuman(row.VirtualSize))})
+		}
+		return nil
+	}
+
+	if len(report.VHDs) == 0 {
+		log.Info("No tracked VHDs found")
+		return nil
+	}
+
+	printCapacityReport(ctx, report)
+	return nil
+}
+
+func printCapacityReport(ctx *AppContext, report capacityReport) {
+	fmt.Println()
+	fmt.Println("VHD Capacity Report")
+	fmt.Println()
+
+	colWidths := []int{40, 8, 10, 10, 6, 10, 12}
+	headers := []string{"Path", "Format", "Virtual", "Allocated", "Use%", "Growth", "Status"}
+	utils.PrintTableHeader(colWidths, headers)
+	for _, row := range report.VHDs {
+		virtual := "-"
+		if row.VirtualSize > 0 {
+			virtual = utils.BytesToHuman(row.VirtualSize)
+		}
+		use := row.FSUse
+		if use == "" {
+			use = "-"
+		}
+		utils.PrintTableRow(colWidths, row.Path, row.Format, virtual,
+			utils.BytesToHuman(row.AllocatedSize), use, formatGrowth(row.Growth), colorizeStatus(ctx.Theme, string(row.State)))
+	}
+	utils.PrintTableFooter(colWidths)
+
+	fmt.Println()
+	fmt.Println("Footprint by Drive")
+	fmt.Println()
+
+	colWidths = []int{6, 6, 12, 12}
+	utils.PrintTableHeader(colWidths, []string{"Drive", "VHDs", "Virtual", "Allocated"})
+	for _, d := range report.Drives {
+		drive := d.Drive
+		if drive == "" {
+			drive = "-"
+		}
+		utils.PrintTableRow(colWidths, drive, fmt.Sprintf("%d", d.Count),
+			utils.BytesToHuman(d.VirtualSize), utils.BytesToHuman(d.AllocatedSize))
+	}
+	utils.PrintTableFooter(colWidths)
+}
+
+// formatGrowth renders a growth delta as "+1.2GB", "-5MB" or "-" when unknown
+func formatGrowth(growth *int64) string {
+	if growth == nil {
+		return "-"
+	}
+	if *growth < 0 {
+		return "-" + utils.BytesToHuman(-*growth)
+	}
+	return "+" + utils.BytesToHuman(*growth)
+}
+
+var reportHTMLTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"human":  utils.BytesToHuman,
+	"growth": formatGrowth,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>VHD Capacity Report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #eee; }
+</style>
+</head>
+<body>
+<h1>VHD Capacity Report</h1>
+<p>Generated at {{.GeneratedAt}}</p>
+<table>
+<tr><th>Path</th><th>Format</th><th>Virtual</th><th>Allocated</th><th>Use%</th><th>Growth</th><th>Status</th></tr>
+{{- range .VHDs}}
+<tr><td>{{.Path}}</td><td>{{.Format}}</td><td>{{human .VirtualSize}}</td><td>{{human .AllocatedSize}}</td><td>{{.FSUse}}</td><td>{{growth .Growth}}</td><td>{{.State}}</td></tr>
+{{- end}}
+</table>
+<h2>Footprint by Drive</h2>
+<table>
+<tr><th>Drive</th><th>VHDs</th><th>Virtual</th><th>Allocated</th></tr>
+{{- range .Drives}}
+<tr><td>{{.Drive}}</td><td>{{.Count}}</td><td>{{human .VirtualSize}}</td><td>{{human .AllocatedSize}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
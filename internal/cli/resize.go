@@ -4,59 +4,126 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/rjdinis/vhdm/internal/types"
 	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
 	"github.com/rjdinis/vhdm/pkg/utils"
 )
 
 func newResizeCmd() *cobra.Command {
 	var (
-		vhdPath string
-		newSize string
+		vhdPath    string
+		newSize    string
+		unpin      bool
+		copyData   bool
+		verify     string
+		tempDir    string
+		stagingDir string
+		resume     bool
+		abort      bool
 	)
 	cmd := &cobra.Command{
 		Use:   "resize",
 		Short: "Resize a VHD file",
 		Long: `Resize a VHD file to a new size.
 
-This operation creates a new VHD with the specified size, copies all data
-from the original VHD, and preserves the original as a backup (*_bkp.vhdx).
+A VHD that grows is expanded in place: the VHD file is expanded with diskpart
+(accept the UAC prompt; qemu-img cannot resize VHD/VHDX images) and the
+filesystem grown to fill it, with e2fsck and resize2fs for ext2/3/4 or
+xfs_growfs/'btrfs filesystem resize' on a temporary mount. The UUID stays the
+same and no backup is kept. Other filesystems, and --copy, use the copy
+described below.
+
+To shrink a VHD, this operation creates a new VHD with the specified size,
+copies all data from the original VHD, and preserves the original as a backup
+(*_bkp.vhdx).
 
 If the VHD is currently mounted or attached, it will be automatically
 unmounted and detached before resizing, then re-mounted to the original
 mount point after completion.
 
-The process:
+The copy process:
 1. Unmounts and detaches the VHD if needed (saves mount point)
 2. Creates a new VHD with the new size
 3. Attaches both VHDs
 4. Formats new VHD with same filesystem type
 5. Mounts both to temporary directories
 6. Copies data using rsync
-7. Verifies file counts match
+7. Verifies the copy (see --verify)
 8. Unmounts and detaches both
 9. Renames original to backup
 10. Renames new to original name
-11. Re-attaches and re-mounts to original mount point (if was mounted)`,
+11. Re-attaches and re-mounts to original mount point (if was mounted)
+
+The new VHD is created next to the original as *_new.vhdx; --staging-dir
+(or VHDM_RESIZE_STAGING_DIR) creates it in another Windows directory, e.g. on
+a drive with more free space, and moves it into place at the end. The
+temporary mount points are created under --temp-dir (or VHDM_RESIZE_TEMP_DIR),
+default $TMPDIR; they are also used to grow xfs and btrfs.
+
+Each step of the copy is recorded in a journal next to the tracking file. A
+copy interrupted by a crash, Ctrl-C or a restart of WSL is finished with
+--resume, which continues from the last step done (rsync picks up where the
+copy stopped), or rolled back with --abort, which puts the original back in
+place, removes the *_new VHD and re-mounts the original as it was. Until then
+the VHD cannot be resized again.
+
+--verify sets how the copy is checked: 'count' (the default) compares the
+number of files and only warns on a mismatch, while 'checksum' compares every
+file by checksum with rsync and aborts, keeping the original VHD unchanged,
+when a file differs or the check cannot run. Checksums read all data twice.
+
+Pinned VHDs (see 'vhdm pin') are only resized with --unpin.`,
 		Example: `  vhdm resize --vhd-path C:/VMs/disk.vhdx --size 20G
-  vhdm resize --vhd-path C:/VMs/disk.vhdx --size 10G -y`,
+  vhdm resize --vhd-path C:/VMs/disk.vhdx --size 10G -y
+  vhdm resize --vhd-path C:/VMs/disk.vhdx --size 40G --copy
+  vhdm resize --vhd-path C:/VMs/disk.vhdx --size 10G --verify checksum
+  vhdm resize --vhd-path C:/VMs/disk.vhdx --resume -y
+  vhdm resize --vhd-path C:/VMs/disk.vhdx --size 200G --staging-dir D:/staging`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runResize(vhdPath, newSize)
+			ctx := appContext(cmd)
+			if !cmd.Flags().Changed("temp-dir") {
+				tempDir = ctx.Config.ResizeTempDir
+			}
+			if !cmd.Flags().Changed("staging-dir") {
+				stagingDir = ctx.Config.ResizeStagingDir
+			}
+			switch {
+			case resume:
+				return resumeResize(ctx, vhdPath, tempDir)
+			case abort:
+				return abortResize(ctx, vhdPath)
+			}
+			return runResize(ctx, vhdPath, newSize, unpin, copyData, verify, tempDir, stagingDir)
 		},
+		Annotations: map[string]string{annotationSudo: "true"},
 	}
 	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
 	cmd.Flags().StringVar(&newSize, "size", "", "New VHD size (e.g., 10G, 20G)")
+	cmd.Flags().BoolVar(&unpin, "unpin", false, "Remove the pin of a pinned VHD and resize it")
+	cmd.Flags().BoolVar(&copyData, "copy", false, "Copy the data to a new VHD, keeping a backup, also when growing")
+	cmd.Flags().StringVar(&verify, "verify", "count", "How to check the copied data: count or checksum")
+	cmd.RegisterFlagCompletionFunc("verify", cobra.FixedCompletions(resizeVerifyModes, cobra.ShellCompDirectiveNoFileComp))
+	cmd.Flags().StringVar(&tempDir, "temp-dir", "", "Directory for the temporary mount points (default: $TMPDIR)")
+	cmd.Flags().StringVar(&stagingDir, "staging-dir", "", "Windows directory for the intermediate *_new VHD (default: next to the VHD)")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Finish an interrupted resize from its journal")
+	cmd.Flags().BoolVar(&abort, "abort", false, "Roll an interrupted resize back to the original VHD")
 	cmd.MarkFlagRequired("vhd-path")
-	cmd.MarkFlagRequired("size")
+	cmd.MarkFlagsOneRequired("size", "resume", "abort")
+	cmd.MarkFlagsMutuallyExclusive("size", "resume", "abort")
+	cmd.RegisterFlagCompletionFunc("size", completeSizes)
 	return cmd
 }
 
-func runResize(vhdPath, newSize string) error {
-	ctx := getContext()
+// resizeVerifyModes are the values of resize --verify
+var resizeVerifyModes = []string{"count", "checksum"}
+
+func runResize(ctx *AppContext, vhdPath, newSize string, unpin, copyData bool, verify, tempDir, stagingDir string) error {
 	log := ctx.Logger
 
 	// Validate inputs
@@ -66,6 +133,35 @@ func runResize(vhdPath, newSize string) error {
 	if err := validation.ValidateSizeString(newSize); err != nil {
 		return &types.VHDError{Op: "resize", Err: err}
 	}
+	newBytes, err := utils.ConvertSizeToBytes(newSize)
+	if err != nil {
+		return &types.VHDError{Op: "resize", Err: err}
+	}
+	if err := validateResizeDirs(ctx, tempDir, stagingDir); err != nil {
+		return err
+	}
+	if !slices.Contains(resizeVerifyModes, verify) {
+		return &types.VHDError{Op: "resize", Err: fmt.Errorf("invalid --verify %q: use %s", verify, strings.Join(resizeVerifyModes, " or "))}
+	}
+
+	if err := ensureNotReference(ctx, "resize", vhdPath); err != nil {
+		return err
+	}
+	if err := checkPinned(ctx, "resize", vhdPath, unpin); err != nil {
+		return err
+	}
+
+	lock, err := lockVHDOperation(ctx, "resize", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if j, err := loadResizeJournal(ctx, vhdPath); err != nil {
+		return &types.VHDError{Op: "resize", Path: vhdPath, Err: err}
+	} else if j != nil {
+		return interruptedResizeError(j)
+	}
 
 	log.Debug("Resize operation starting for: %s to size: %s", vhdPath, newSize)
 
@@ -75,6 +171,16 @@ func runResize(vhdPath, newSize string) error {
 		return fmt.Errorf("VHD file not found: %s", vhdPath)
 	}
 
+	// A VHD that grows is expanded in place rather than copied
+	grow := false
+	if !copyData {
+		img, err := ctx.WSL.GetImageInfo(wslPath)
+		if err != nil {
+			return &types.VHDError{Op: "resize", Path: vhdPath, Err: err}
+		}
+		grow = newBytes > img.VirtualSize
+	}
+
 	// Check if VHD is currently attached - unmount and detach if needed
 	// Save original mount point to restore after resize
 	var originalMountPoint string
@@ -101,69 +207,196 @@ func runResize(vhdPath, newSize string) error {
 			} else {
 				log.Success("VHD detached")
 			}
-			// Remove mapping from tracking
-			ctx.Tracker.RemoveMapping(vhdPath)
+			if grow {
+				// The UUID stays the same
+				markDetached(ctx, vhdPath)
+			} else {
+				// Remove mapping from tracking
+				ctx.Tracker.RemoveMapping(vhdPath)
+			}
 		}
 	}
 
 	// restoreOriginalMount re-attaches and re-mounts original VHD if it was mounted
-	restoreOriginalMount := func() {
-		if originalMountPoint == "" {
-			return
-		}
-		log.Info("Restoring original VHD to %s...", originalMountPoint)
-		// Re-attach original VHD
-		_, err := ctx.WSL.AttachVHD(vhdPath)
-		if err != nil {
-			if !types.IsAlreadyAttached(err) {
-				log.Warn("Failed to re-attach original VHD: %v", err)
-				return
-			}
-		}
-		// Re-mount to original mount point
-		if err := ctx.WSL.MountByUUID(uuid, originalMountPoint); err != nil {
-			log.Warn("Failed to re-mount original VHD: %v", err)
-			return
-		}
-		// Get device name and update tracking
-		devName, _ := ctx.WSL.GetDeviceByUUID(uuid)
-		if err := ctx.Tracker.SaveMapping(vhdPath, uuid, originalMountPoint, devName); err != nil {
-			log.Warn("Failed to update tracking: %v", err)
-		}
-		log.Success("Original VHD restored to %s", originalMountPoint)
+	restoreOriginalMount := func() { restoreResizedMount(ctx, vhdPath, uuid, originalMountPoint) }
+
+	// The original is renamed to the backup at the end; fail now rather than
+	// after the copy when Windows holds it open
+	if err := ensureNotInUseByWindows(ctx, "resize", vhdPath); err != nil {
+		restoreOriginalMount()
+		return err
 	}
 
 	// Confirm resize
 	if !ctx.Config.Yes {
-		log.Warn("This will resize: %s to %s", vhdPath, newSize)
-		log.Warn("The original VHD will be preserved as a backup (*_bkp.vhdx)")
+		if grow {
+			log.Warn("This will grow %s to %s in place", vhdPath, newSize)
+			log.Warn("No backup is kept; use --copy to keep the original as *_bkp.vhdx")
+		} else {
+			log.Warn("This will resize: %s to %s", vhdPath, newSize)
+			log.Warn("The original VHD will be preserved as a backup (*_bkp.vhdx)")
+		}
 		log.Warn("Run with --yes to confirm")
 		restoreOriginalMount()
 		return fmt.Errorf("operation cancelled")
 	}
+	releasePin(ctx, vhdPath)
+
+	if grow {
+		return growVHD(ctx, vhdPath, newSize, newBytes, tempDir, originalMountPoint, restoreOriginalMount)
+	}
 
 	// Generate paths
-	newVHDPath := generateNewVHDPath(vhdPath)
+	newVHDPath := generateNewVHDPath(vhdPath, stagingDir)
 	backupVHDPath := generateBackupPath(vhdPath)
-	newWSLPath := ctx.WSL.ConvertPath(newVHDPath)
-	backupWSLPath := ctx.WSL.ConvertPath(backupVHDPath)
 
 	// Check if backup already exists
-	if ctx.WSL.FileExists(backupWSLPath) {
+	if ctx.WSL.FileExists(ctx.WSL.ConvertPath(backupVHDPath)) {
 		restoreOriginalMount()
 		return fmt.Errorf("backup file already exists: %s - please remove or rename it first", backupVHDPath)
 	}
 
+	j := &resizeJournal{
+		Path:       vhdPath,
+		NewSize:    newSize,
+		NewPath:    newVHDPath,
+		Backup:     backupVHDPath,
+		OldUUID:    uuid,
+		MountPoint: originalMountPoint,
+		Verify:     verify,
+	}
+	if err := newResizeJournal(ctx, j); err != nil {
+		restoreOriginalMount()
+		return err
+	}
+	return copyResize(ctx, j, tempDir, restoreOriginalMount)
+}
+
+// copyResize runs the steps of the copying resize recorded in j that are not
+// done yet, recording each in the journal: the data is copied to a new VHD,
+// which then takes the place of the original, kept as the backup. A failing
+// step rolls the resize back; an interrupted one leaves the journal for
+// --resume and --abort.
+func copyResize(ctx *AppContext, j *resizeJournal, tempDir string, restoreOriginalMount func()) error {
+	log := ctx.Logger
+	vhdPath := j.Path
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	newWSLPath := ctx.WSL.ConvertPath(j.NewPath)
+	backupWSLPath := ctx.WSL.ConvertPath(j.Backup)
+
+	if !j.done(resizeStepCopied) {
+		if err := copyResizeData(ctx, j, tempDir, restoreOriginalMount); err != nil {
+			return err
+		}
+	}
+
+	// Rename original to backup
+	if !j.done(resizeStepBackedUp) {
+		log.Info("Creating backup of original VHD...")
+		if err := ensureNotInUseByWindows(ctx, "resize", vhdPath); err != nil {
+			log.Warn("The resized copy is kept at %s; finish with 'vhdm resize --vhd-path %s --resume'", j.NewPath, vhdPath)
+			return err
+		}
+		if err := ctx.WSL.RenameFile(wslPath, backupWSLPath); err != nil {
+			return fmt.Errorf("failed to create backup: %w", err)
+		}
+		if err := j.record(resizeStepBackedUp); err != nil {
+			return err
+		}
+	}
+
+	// Rename new to original name
+	if !j.done(resizeStepRenamed) {
+		log.Info("Finalizing resize...")
+		if err := ctx.WSL.RenameFile(newWSLPath, wslPath); err != nil {
+			// Try to restore original
+			if ctx.WSL.RenameFile(backupWSLPath, wslPath) == nil {
+				j.rewind(resizeStepCopied)
+			}
+			return fmt.Errorf("failed to rename new VHD: %w", err)
+		}
+		if err := j.record(resizeStepRenamed); err != nil {
+			return err
+		}
+	}
+
+	// Update tracking with new UUID
+	if err := ctx.Tracker.SaveMapping(vhdPath, j.NewUUID, "", ""); err != nil {
+		log.Collect("Failed to update tracking: %v", err)
+	}
+	j.remove(ctx)
+	updateServiceUUID(ctx, vhdPath, j.OldUUID, j.NewUUID)
+
+	// Re-mount to original mount point if it was originally mounted
+	var finalDevName string
+	if j.MountPoint != "" {
+		log.Info("Re-attaching resized VHD...")
+		var attached bool
+		var err error
+		finalDevName, attached, err = ctx.WSL.AttachVHDAndDetect(vhdPath)
+		if err != nil && !attached {
+			log.Warn("Failed to re-attach VHD: %v", err)
+		} else if err != nil {
+			log.Warn("Failed to detect device after re-attach: %v", err)
+		} else {
+			log.Success("VHD re-attached as /dev/%s", finalDevName)
+
+			log.Info("Re-mounting to %s...", j.MountPoint)
+			if err := ctx.WSL.MountByUUID(j.NewUUID, j.MountPoint); err != nil {
+				log.Warn("Failed to re-mount VHD: %v", err)
+			} else {
+				log.Success("VHD re-mounted to %s", j.MountPoint)
+				// Update tracking with mount point and device
+				ctx.Tracker.SaveMapping(vhdPath, j.NewUUID, j.MountPoint, finalDevName)
+			}
+		}
+	}
+
+	// Output
+	log.Success("VHD resized successfully!")
+	res := ResizeResult{
+		Path:       vhdPath,
+		NewSize:    j.NewSize,
+		Method:     "copy",
+		NewUUID:    j.NewUUID,
+		OldUUID:    j.OldUUID,
+		Backup:     j.Backup,
+		Verified:   j.Verified,
+		MountPoint: j.MountPoint,
+		DeviceName: finalDevName,
+	}
+	if err := printResult(ctx, res); err != nil {
+		return err
+	}
+
+	log.Info("")
+	log.Info("Original VHD preserved as: %s", j.Backup)
+	log.Info("Please verify the resized VHD works correctly, then delete the backup manually")
+
+	return nil
+}
+
+// copyResizeData creates and formats the new VHD of a copying resize and
+// copies the data of the original into it, skipping the steps j records as
+// done. On failure the new VHD is removed, the original restored and the
+// journal dropped.
+func copyResizeData(ctx *AppContext, j *resizeJournal, tempDir string, restoreOriginalMount func()) error {
+	log := ctx.Logger
+	vhdPath := j.Path
+	newWSLPath := ctx.WSL.ConvertPath(j.NewPath)
+
 	// Create temporary mount points
-	tmpOld, err := os.MkdirTemp("", "vhdm-resize-old-")
+	tmpOld, err := os.MkdirTemp(tempDir, "vhdm-resize-old-")
 	if err != nil {
+		j.remove(ctx)
 		restoreOriginalMount()
 		return fmt.Errorf("failed to create temp mount point: %w", err)
 	}
 	defer os.RemoveAll(tmpOld)
 
-	tmpNew, err := os.MkdirTemp("", "vhdm-resize-new-")
+	tmpNew, err := os.MkdirTemp(tempDir, "vhdm-resize-new-")
 	if err != nil {
+		j.remove(ctx)
 		restoreOriginalMount()
 		return fmt.Errorf("failed to create temp mount point: %w", err)
 	}
@@ -176,36 +409,35 @@ func runResize(vhdPath, newSize string) error {
 		ctx.WSL.Unmount(tmpOld)
 		ctx.WSL.Unmount(tmpNew)
 		ctx.WSL.DetachVHD(vhdPath)
-		ctx.WSL.DetachVHD(newVHDPath)
+		ctx.WSL.DetachVHD(j.NewPath)
 		// Remove new VHD on failure
 		ctx.WSL.DeleteVHD(newWSLPath)
+		j.remove(ctx)
 		// Restore original VHD to its mount point
 		restoreOriginalMount()
 	}
 
-	log.Info("Creating new VHD: %s (%s)...", newVHDPath, newSize)
-	if err := ctx.WSL.CreateVHD(newWSLPath, newSize); err != nil {
-		restoreOriginalMount()
-		return fmt.Errorf("failed to create new VHD: %w", err)
+	if !j.done(resizeStepCreated) {
+		log.Info("Creating new VHD: %s (%s)...", j.NewPath, j.NewSize)
+		if err := ctx.WSL.CreateVHD(newWSLPath, j.NewSize); err != nil {
+			j.remove(ctx)
+			restoreOriginalMount()
+			return fmt.Errorf("failed to create new VHD: %w", err)
+		}
+		if err := j.record(resizeStepCreated); err != nil {
+			cleanup()
+			return err
+		}
 	}
 
 	// Attach original VHD
 	log.Info("Attaching original VHD...")
-	oldDevices, err := ctx.WSL.GetBlockDevices()
-	if err != nil {
-		cleanup()
-		return fmt.Errorf("failed to get block devices: %w", err)
-	}
-
-	_, err = ctx.WSL.AttachVHD(vhdPath)
-	if err != nil {
-		cleanup()
-		return fmt.Errorf("failed to attach original VHD: %w", err)
-	}
-
-	oldDevName, err := ctx.WSL.DetectNewDevice(oldDevices)
+	oldDevName, attached, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
 	if err != nil {
 		cleanup()
+		if !attached {
+			return fmt.Errorf("failed to attach original VHD: %w", err)
+		}
 		return fmt.Errorf("failed to detect original VHD device: %w", err)
 	}
 	log.Debug("Original VHD attached as /dev/%s", oldDevName)
@@ -216,6 +448,7 @@ func runResize(vhdPath, newSize string) error {
 		cleanup()
 		return fmt.Errorf("original VHD is not formatted - cannot resize")
 	}
+	j.OldUUID = oldUUID
 
 	fsType, err := ctx.WSL.GetFilesystemType(oldDevName)
 	if err != nil || fsType == "" {
@@ -226,33 +459,31 @@ func runResize(vhdPath, newSize string) error {
 
 	// Attach new VHD
 	log.Info("Attaching new VHD...")
-	newDevices, err := ctx.WSL.GetBlockDevices()
-	if err != nil {
-		cleanup()
-		return fmt.Errorf("failed to get block devices: %w", err)
-	}
-
-	_, err = ctx.WSL.AttachVHD(newVHDPath)
-	if err != nil {
-		cleanup()
-		return fmt.Errorf("failed to attach new VHD: %w", err)
-	}
-
-	newDevName, err := ctx.WSL.DetectNewDevice(newDevices)
+	newDevName, attached, err := ctx.WSL.AttachVHDAndDetect(j.NewPath)
 	if err != nil {
 		cleanup()
+		if !attached {
+			return fmt.Errorf("failed to attach new VHD: %w", err)
+		}
 		return fmt.Errorf("failed to detect new VHD device: %w", err)
 	}
 	log.Debug("New VHD attached as /dev/%s", newDevName)
 
 	// Format new VHD
-	log.Info("Formatting new VHD with %s...", fsType)
-	newUUID, err := ctx.WSL.Format(newDevName, fsType)
-	if err != nil {
-		cleanup()
-		return fmt.Errorf("failed to format new VHD: %w", err)
+	if !j.done(resizeStepFormatted) {
+		log.Info("Formatting new VHD with %s...", fsType)
+		newUUID, err := ctx.WSL.Format(newDevName, fsType)
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("failed to format new VHD: %w", err)
+		}
+		log.Debug("New VHD UUID: %s", newUUID)
+		j.NewUUID = newUUID
+		if err := j.record(resizeStepFormatted); err != nil {
+			cleanup()
+			return err
+		}
 	}
-	log.Debug("New VHD UUID: %s", newUUID)
 
 	// Mount both VHDs
 	log.Info("Mounting VHDs for data transfer...")
@@ -261,42 +492,49 @@ func runResize(vhdPath, newSize string) error {
 		return fmt.Errorf("failed to mount original VHD: %w", err)
 	}
 
-	if err := ctx.WSL.MountByUUID(newUUID, tmpNew); err != nil {
+	if err := ctx.WSL.MountByUUID(j.NewUUID, tmpNew); err != nil {
 		cleanup()
 		return fmt.Errorf("failed to mount new VHD: %w", err)
 	}
 
 	// Get file count before copy
-	oldFileCount, err := ctx.WSL.CountFiles(tmpOld)
-	if err != nil {
-		log.Warn("Could not count files in source: %v", err)
-		oldFileCount = -1
+	oldFileCount := -1
+	if j.Verify == "count" {
+		oldFileCount, err = ctx.WSL.CountFiles(tmpOld)
+		if err != nil {
+			log.Warn("Could not count files in source: %v", err)
+			oldFileCount = -1
+		}
+		log.Debug("Source file count: %d", oldFileCount)
 	}
-	log.Debug("Source file count: %d", oldFileCount)
 
-	// Copy data using rsync
+	// Copy data using rsync; on resume it continues where it stopped
 	log.Info("Copying data (this may take a while)...")
-	if err := ctx.WSL.RsyncCopy(tmpOld, tmpNew); err != nil {
+	progress := newCopyProgress(ctx)
+	err = ctx.WSL.RsyncCopy(tmpOld, tmpNew, progress.callback())
+	progress.done()
+	if err != nil {
 		cleanup()
 		return fmt.Errorf("failed to copy data: %w", err)
 	}
 	log.Success("Data copy complete")
 
-	// Verify file counts match
-	if oldFileCount > 0 {
-		newFileCount, err := ctx.WSL.CountFiles(tmpNew)
-		if err != nil {
-			log.Warn("Could not verify file count: %v", err)
-		} else {
-			log.Debug("Destination file count: %d", newFileCount)
-			if newFileCount != oldFileCount {
-				log.Warn("File count mismatch: source=%d, dest=%d", oldFileCount, newFileCount)
-				log.Warn("Proceeding anyway - please verify data manually")
-			} else {
-				log.Success("File count verified: %d files", newFileCount)
-			}
+	verified, err := verifyResizeCopy(ctx, j.Verify, tmpOld, tmpNew, oldFileCount)
+	if err != nil {
+		cleanup()
+		return &types.VHDError{
+			Op:   "resize",
+			Path: vhdPath,
+			Err:  err,
+			Help: fmt.Sprintf("The original VHD is unchanged. Check it with 'vhdm verify --vhd-path %s --fsck' before trying again", vhdPath),
 		}
 	}
+	j.Verified = verified
+
+	// The copied ID file names the old filesystem UUID
+	if err := stampIDFile(ctx, vhdPath, j.NewUUID, tmpNew); err != nil {
+		log.Warn("Failed to update the ID file: %v", err)
+	}
 
 	// Unmount both VHDs
 	log.Info("Unmounting VHDs...")
@@ -312,98 +550,277 @@ func runResize(vhdPath, newSize string) error {
 	if err := ctx.WSL.DetachVHD(vhdPath); err != nil {
 		log.Warn("Failed to detach original: %v", err)
 	}
-	if err := ctx.WSL.DetachVHD(newVHDPath); err != nil {
+	if err := ctx.WSL.DetachVHD(j.NewPath); err != nil {
 		log.Warn("Failed to detach new: %v", err)
 	}
+	return j.record(resizeStepCopied)
+}
 
-	// Rename original to backup
-	log.Info("Creating backup of original VHD...")
-	if err := ctx.WSL.RenameFile(wslPath, backupWSLPath); err != nil {
-		return fmt.Errorf("failed to create backup: %w", err)
+// restoreResizedMount re-attaches the original VHD of a resize that did not
+// happen and re-mounts it at mountPoint, when it was mounted there
+func restoreResizedMount(ctx *AppContext, vhdPath, uuid, mountPoint string) {
+	log := ctx.Logger
+	if mountPoint == "" {
+		return
+	}
+	log.Info("Restoring original VHD to %s...", mountPoint)
+	// Re-attach original VHD
+	_, err := ctx.WSL.AttachVHD(vhdPath)
+	if err != nil {
+		if !types.IsAlreadyAttached(err) {
+			log.Warn("Failed to re-attach original VHD: %v", err)
+			return
+		}
+	}
+	// Re-mount to original mount point
+	if err := ctx.WSL.MountByUUID(uuid, mountPoint); err != nil {
+		log.Warn("Failed to re-mount original VHD: %v", err)
+		return
+	}
+	// Get device name and update tracking
+	devName, _ := ctx.WSL.GetDeviceByUUID(uuid)
+	if err := ctx.Tracker.SaveMapping(vhdPath, uuid, mountPoint, devName); err != nil {
+		log.Collect("Failed to update tracking: %v", err)
 	}
+	log.Success("Original VHD restored to %s", mountPoint)
+}
 
-	// Rename new to original name
-	log.Info("Finalizing resize...")
-	if err := ctx.WSL.RenameFile(newWSLPath, wslPath); err != nil {
-		// Try to restore original
-		ctx.WSL.RenameFile(backupWSLPath, wslPath)
-		return fmt.Errorf("failed to rename new VHD: %w", err)
+// verifyResizeCopy checks the data copied from tmpOld to tmpNew as --verify
+// asks and returns how it was verified, or "" when the file count could not
+// be compared. Checksum mode fails when a file differs; a count mismatch only
+// warns.
+func verifyResizeCopy(ctx *AppContext, verify, tmpOld, tmpNew string, oldFileCount int) (string, error) {
+	log := ctx.Logger
+
+	if verify == "checksum" {
+		log.Info("Verifying the copy by checksum (this may take a while)...")
+		diffs, err := ctx.WSL.CompareTrees(tmpOld, tmpNew)
+		if err != nil {
+			return "", fmt.Errorf("failed to verify the copy: %w", err)
+		}
+		if len(diffs) > 0 {
+			for _, name := range diffs {
+				log.Debug("Differs after the copy: %s", name)
+			}
+			return "", fmt.Errorf("%d file(s) differ after the copy, e.g. %s", len(diffs), diffs[0])
+		}
+		log.Success("Copy verified by checksum")
+		return "checksum", nil
 	}
 
-	// Update tracking with new UUID
-	if err := ctx.Tracker.SaveMapping(vhdPath, newUUID, "", ""); err != nil {
-		log.Warn("Failed to update tracking: %v", err)
+	// Verify file counts match
+	if oldFileCount <= 0 {
+		return "", nil
+	}
+	newFileCount, err := ctx.WSL.CountFiles(tmpNew)
+	if err != nil {
+		log.Warn("Could not verify file count: %v", err)
+		return "", nil
+	}
+	log.Debug("Destination file count: %d", newFileCount)
+	if newFileCount != oldFileCount {
+		log.Warn("File count mismatch: source=%d, dest=%d", oldFileCount, newFileCount)
+		log.Warn("Proceeding anyway - please verify data manually")
+		return "", nil
 	}
+	log.Success("File count verified: %d files", newFileCount)
+	return "count", nil
+}
 
-	// Re-mount to original mount point if it was originally mounted
-	var finalDevName string
+// growVHD grows the detached VHD at vhdPath to newBytes in place and its
+// filesystem to fill it, then mounts it again at originalMountPoint;
+// restoreOriginalMount undoes the unmount when nothing changed
+func growVHD(ctx *AppContext, vhdPath, newSize string, newBytes int64, tempDir, originalMountPoint string, restoreOriginalMount func()) error {
+	log := ctx.Logger
+
+	// The filesystem type decides how it grows
+	log.Info("Attaching VHD...")
+	devName, attached, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
+	if err != nil {
+		if attached {
+			ctx.WSL.DetachVHD(vhdPath)
+		}
+		restoreOriginalMount()
+		return &types.VHDError{Op: "resize", Path: vhdPath, Err: fmt.Errorf("failed to attach VHD: %w", err)}
+	}
+	uuid, _ := ctx.WSL.GetUUIDByDevice(devName)
+	fsType, _ := ctx.WSL.GetFilesystemType(devName)
+	if err := ctx.WSL.DetachVHD(vhdPath); err != nil {
+		log.Warn("Failed to detach VHD: %v", err)
+	}
+	if uuid == "" || fsType == "" {
+		return &types.VHDError{
+			Op:   "resize",
+			Path: vhdPath,
+			Err:  types.ErrVHDNotFormatted,
+			Help: "An unformatted VHD has no data to keep; delete it and create a larger one",
+		}
+	}
+	if !wsl.GrowableFilesystem(fsType) {
+		restoreOriginalMount()
+		return &types.VHDError{
+			Op:   "resize",
+			Path: vhdPath,
+			Err:  fmt.Errorf("cannot grow a %s filesystem in place", fsType),
+			Help: fmt.Sprintf("Resize it by copying with 'vhdm resize --vhd-path %s --size %s --copy'", vhdPath, newSize),
+		}
+	}
+
+	log.Info("Expanding %s to %s (accept the UAC prompt)...", vhdPath, newSize)
+	if err := ctx.WSL.ExpandVHDFile(vhdPath, newBytes); err != nil {
+		restoreOriginalMount()
+		return &types.VHDError{
+			Op:   "resize",
+			Path: vhdPath,
+			Err:  err,
+			Help: "diskpart needs administrator rights; the VHD is unchanged",
+		}
+	}
+
+	log.Info("Growing the %s filesystem...", fsType)
+	devName, _, err = ctx.WSL.AttachVHDAndDetect(vhdPath)
+	if err == nil {
+		err = growFilesystem(ctx, devName, fsType, uuid, tempDir)
+	}
+	if err != nil {
+		restoreOriginalMount()
+		return &types.VHDError{
+			Op:   "resize",
+			Path: vhdPath,
+			Err:  err,
+			Help: "The VHD file was expanded but the filesystem was not; grow it with resize2fs on its device (ext2/3/4) or xfs_growfs/'btrfs filesystem resize max' on its mount point",
+		}
+	}
+
+	res := ResizeResult{
+		Path:    vhdPath,
+		NewSize: newSize,
+		Method:  "grow",
+		NewUUID: uuid,
+		OldUUID: uuid,
+	}
 	if originalMountPoint != "" {
-		log.Info("Re-attaching resized VHD...")
-		beforeDevices, err := ctx.WSL.GetBlockDevices()
-		if err != nil {
-			log.Warn("Failed to get block devices for re-attach: %v", err)
+		log.Info("Re-mounting to %s...", originalMountPoint)
+		if err := ctx.WSL.MountByUUID(uuid, originalMountPoint); err != nil {
+			log.Warn("Failed to re-mount VHD: %v", err)
 		} else {
-			_, err = ctx.WSL.AttachVHD(vhdPath)
-			if err != nil {
-				log.Warn("Failed to re-attach VHD: %v", err)
-			} else {
-				finalDevName, err = ctx.WSL.DetectNewDevice(beforeDevices)
-				if err != nil {
-					log.Warn("Failed to detect device after re-attach: %v", err)
-				} else {
-					log.Success("VHD re-attached as /dev/%s", finalDevName)
-
-					log.Info("Re-mounting to %s...", originalMountPoint)
-					if err := ctx.WSL.MountByUUID(newUUID, originalMountPoint); err != nil {
-						log.Warn("Failed to re-mount VHD: %v", err)
-					} else {
-						log.Success("VHD re-mounted to %s", originalMountPoint)
-						// Update tracking with mount point and device
-						ctx.Tracker.SaveMapping(vhdPath, newUUID, originalMountPoint, finalDevName)
-					}
-				}
+			res.MountPoint, res.DeviceName = originalMountPoint, devName
+			if err := ctx.Tracker.SaveMapping(vhdPath, uuid, originalMountPoint, devName); err != nil {
+				log.Collect("Failed to update tracking: %v", err)
 			}
 		}
 	}
+	if res.MountPoint == "" {
+		if err := ctx.WSL.DetachVHD(vhdPath); err != nil {
+			log.Warn("Failed to detach VHD: %v", err)
+		}
+		markDetached(ctx, vhdPath)
+	}
 
-	// Output
-	if ctx.Config.Quiet {
-		fmt.Printf("%s (%s): resized to %s\n", vhdPath, newUUID, newSize)
-		return nil
+	log.Success("VHD grown to %s", newSize)
+	return printResult(ctx, res)
+}
+
+// growFilesystem grows the filesystem on the attached device devName to fill
+// it, mounting xfs and btrfs under tempDir for it
+func growFilesystem(ctx *AppContext, devName, fsType, uuid, tempDir string) error {
+	if strings.HasPrefix(fsType, "ext") {
+		return ctx.WSL.GrowFilesystem(devName, fsType, "")
 	}
+	tmp, err := os.MkdirTemp(tempDir, "vhdm-resize-grow-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp mount point: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := ctx.WSL.MountByUUID(uuid, tmp); err != nil {
+		return fmt.Errorf("failed to mount VHD: %w", err)
+	}
+	err = ctx.WSL.GrowFilesystem(devName, fsType, tmp)
+	if uerr := ctx.WSL.Unmount(tmp); uerr != nil {
+		ctx.Logger.Warn("Failed to unmount %s: %v", tmp, uerr)
+	}
+	return err
+}
 
-	log.Success("VHD resized successfully!")
+// ResizeResult is the outcome of 'vhdm resize'
+type ResizeResult struct {
+	Path       string `json:"path"`
+	NewSize    string `json:"newSize"`
+	Method     string `json:"method"` // "grow" in place or "copy" to a new VHD
+	NewUUID    string `json:"newUUID"`
+	OldUUID    string `json:"oldUUID"`
+	Backup     string `json:"backup,omitempty"`
+	Verified   string `json:"verified,omitempty"` // How the copy was verified: "checksum" or "count"
+	MountPoint string `json:"mountPoint,omitempty"`
+	DeviceName string `json:"deviceName,omitempty"`
+	RolledBack bool   `json:"rolledBack,omitempty"` // An interrupted resize was aborted
+}
 
+func (r ResizeResult) table(utils.Theme) (string, [][2]string) {
 	pairs := [][2]string{
-		{"Path", vhdPath},
-		{"New Size", newSize},
-		{"New UUID", newUUID},
-		{"Old UUID", oldUUID},
-		{"Backup", backupVHDPath},
+		{"Path", r.Path},
+		{"New Size", r.NewSize},
+		{"Method", r.Method},
 	}
-	if originalMountPoint != "" {
-		pairs = append(pairs, [2]string{"Mount Point", originalMountPoint})
+	if r.NewUUID == r.OldUUID {
+		pairs = append(pairs, [2]string{"UUID", r.NewUUID})
+	} else {
+		pairs = append(pairs, [2]string{"New UUID", r.NewUUID}, [2]string{"Old UUID", r.OldUUID})
+	}
+	if r.Backup != "" {
+		pairs = append(pairs, [2]string{"Backup", r.Backup})
 	}
-	if finalDevName != "" {
-		pairs = append(pairs, [2]string{"Device", "/dev/" + finalDevName})
+	if r.Verified != "" {
+		pairs = append(pairs, [2]string{"Verified", "by " + r.Verified})
+	}
+	if r.MountPoint != "" {
+		pairs = append(pairs, [2]string{"Mount Point", r.MountPoint})
+	}
+	pairs = appendDevice(pairs, r.DeviceName)
+	if r.RolledBack {
+		return "Resize Result", append(pairs, [2]string{"Status", "rolled back"})
 	}
 	pairs = append(pairs, [2]string{"Status", "resized"})
-	utils.KeyValueTable("Resize Result", pairs, 14, 50)
-
-	fmt.Println()
-	log.Info("Original VHD preserved as: %s", backupVHDPath)
-	log.Info("Please verify the resized VHD works correctly, then delete the backup manually")
+	return "Resize Result", pairs
+}
 
-	return nil
+func (r ResizeResult) quiet() quietLine {
+	if r.RolledBack {
+		return quietLine{Key: r.Path, UUID: r.OldUUID, State: "resize to " + r.NewSize + " rolled back"}
+	}
+	return quietLine{Key: r.Path, UUID: r.NewUUID, State: "resized to " + r.NewSize}
 }
 
-// generateNewVHDPath generates a temporary path for the new VHD
-func generateNewVHDPath(originalPath string) string {
+// generateNewVHDPath generates a temporary path for the new VHD, next to the
+// original or in stagingDir (Windows format) when it is set
+func generateNewVHDPath(originalPath, stagingDir string) string {
 	ext := filepath.Ext(originalPath)
 	base := strings.TrimSuffix(originalPath, ext)
+	if stagingDir != "" {
+		base = strings.TrimRight(stagingDir, `/\`) + "/" + filepath.Base(strings.ReplaceAll(base, `\`, "/"))
+	}
 	return base + "_new" + ext
 }
 
+// validateResizeDirs checks that the temp and staging directories of a resize
+// exist
+func validateResizeDirs(ctx *AppContext, tempDir, stagingDir string) error {
+	if tempDir != "" {
+		if info, err := os.Stat(tempDir); err != nil || !info.IsDir() {
+			return &types.VHDError{Op: "resize", Path: tempDir, Err: fmt.Errorf("temp directory does not exist")}
+		}
+	}
+	if stagingDir != "" {
+		if err := validation.ValidateWindowsPath(stagingDir); err != nil {
+			return &types.VHDError{Op: "resize", Path: stagingDir, Err: err}
+		}
+		if info, err := os.Stat(ctx.WSL.ConvertPath(stagingDir)); err != nil || !info.IsDir() {
+			return &types.VHDError{Op: "resize", Path: stagingDir, Err: fmt.Errorf("staging directory does not exist")}
+		}
+	}
+	return nil
+}
+
 // generateBackupPath generates a backup path for the original VHD
 func generateBackupPath(originalPath string) string {
 	ext := filepath.Ext(originalPath)
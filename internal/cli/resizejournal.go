@@ -0,0 +1,324 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+// Steps of a copying resize, recorded in its journal once done
+const (
+	resizeStepCreated   = "created"   // The *_new VHD was created
+	resizeStepFormatted = "formatted" // The new VHD was formatted, NewUUID is set
+	resizeStepCopied    = "copied"    // The data was copied and verified, both VHDs detached
+	resizeStepBackedUp  = "backed-up" // The original was renamed to the backup
+	resizeStepRenamed   = "renamed"   // The new VHD was renamed to the original
+)
+
+// resizeJournal records the progress of a copying resize, so an interrupted
+// one can be resumed or rolled back ('vhdm resize --resume/--abort'). It is
+// written before the first step and removed once the resized VHD is tracked
+// in place of the original, or the resize was rolled back.
+type resizeJournal struct {
+	Path       string   `json:"path"`
+	NewSize    string   `json:"newSize"`
+	NewPath    string   `json:"newPath"` // The *_new VHD
+	Backup     string   `json:"backup"`  // Where the original is kept
+	OldUUID    string   `json:"oldUUID,omitempty"`
+	NewUUID    string   `json:"newUUID,omitempty"`
+	MountPoint string   `json:"mountPoint,omitempty"` // Mount point to restore at the end
+	Verify     string   `json:"verify"`
+	Verified   string   `json:"verified,omitempty"`
+	Started    string   `json:"started"`
+	Steps      []string `json:"steps"`
+
+	file string
+}
+
+// resizeJournalPath returns the journal file of a resize of vhdPath, next to
+// the tracking file so it survives a restart of WSL. Paths are compared
+// case-insensitively, like the tracking file does.
+func resizeJournalPath(ctx *AppContext, vhdPath string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.ReplaceAll(vhdPath, "\\", "/"))))
+	return filepath.Join(filepath.Dir(ctx.Config.TrackingFile), "resize-journal", hex.EncodeToString(sum[:8])+".json")
+}
+
+// newResizeJournal starts the journal of a resize and writes it
+func newResizeJournal(ctx *AppContext, j *resizeJournal) error {
+	j.file = resizeJournalPath(ctx, j.Path)
+	j.Started = time.Now().Format(time.RFC3339)
+	j.Steps = []string{}
+	if err := os.MkdirAll(filepath.Dir(j.file), 0755); err != nil {
+		return fmt.Errorf("failed to create resize journal: %w", err)
+	}
+	return j.save()
+}
+
+// loadResizeJournal reads the journal of an interrupted resize of vhdPath,
+// or returns nil when there is none
+func loadResizeJournal(ctx *AppContext, vhdPath string) (*resizeJournal, error) {
+	file := resizeJournalPath(ctx, vhdPath)
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resize journal: %w", err)
+	}
+	j := &resizeJournal{file: file}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("failed to parse resize journal %s: %w", file, err)
+	}
+	return j, nil
+}
+
+// save writes the journal atomically, so an interruption leaves either the
+// old or the new record
+func (j *resizeJournal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := j.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write resize journal: %w", err)
+	}
+	if err := os.Rename(tmp, j.file); err != nil {
+		return fmt.Errorf("failed to write resize journal: %w", err)
+	}
+	return nil
+}
+
+// record marks a step done
+func (j *resizeJournal) record(step string) error {
+	j.Steps = append(j.Steps, step)
+	return j.save()
+}
+
+// rewind forgets the steps after step, once they were undone
+func (j *resizeJournal) rewind(step string) error {
+	if i := slices.Index(j.Steps, step); i >= 0 {
+		j.Steps = j.Steps[:i+1]
+	}
+	return j.save()
+}
+
+// done reports whether a step was recorded
+func (j *resizeJournal) done(step string) bool {
+	return slices.Contains(j.Steps, step)
+}
+
+// last returns the last step done, or "started"
+func (j *resizeJournal) last() string {
+	if len(j.Steps) == 0 {
+		return "started"
+	}
+	return j.Steps[len(j.Steps)-1]
+}
+
+// resizeSteps are the steps of a copying resize, in order
+var resizeSteps = []string{resizeStepCreated, resizeStepFormatted, resizeStepCopied, resizeStepBackedUp, resizeStepRenamed}
+
+// syncRenames brings the recorded steps in line with the files on disk. Each
+// rename is recorded only once it happened, so a resize killed in between
+// has moved a file the journal does not know about yet. Which of the
+// original, the backup and the new VHD exist tells how far the renames got.
+func (j *resizeJournal) syncRenames(ctx *AppContext) error {
+	if !j.done(resizeStepCopied) {
+		// The renames only start once the copy is recorded
+		return nil
+	}
+	original := ctx.WSL.FileExists(ctx.WSL.ConvertPath(j.Path))
+	backup := ctx.WSL.FileExists(ctx.WSL.ConvertPath(j.Backup))
+	resized := ctx.WSL.FileExists(ctx.WSL.ConvertPath(j.NewPath))
+
+	var step string
+	switch {
+	case original && !backup && resized:
+		step = resizeStepCopied
+	case !original && backup && resized:
+		step = resizeStepBackedUp
+	case original && backup && !resized:
+		step = resizeStepRenamed
+	default:
+		return fmt.Errorf("cannot tell how far the resize got: %s %s, %s %s, %s %s",
+			j.Path, existence(original), j.Backup, existence(backup), j.NewPath, existence(resized))
+	}
+	if step == j.last() {
+		return nil
+	}
+	ctx.Logger.Debug("Resize journal at step %q, files on disk at step %q", j.last(), step)
+	j.Steps = slices.Clone(resizeSteps[:slices.Index(resizeSteps, step)+1])
+	return j.save()
+}
+
+// existence describes whether a file exists, for messages
+func existence(exists bool) string {
+	if exists {
+		return "exists"
+	}
+	return "is missing"
+}
+
+// remove deletes the journal once the resize completed or was rolled back
+func (j *resizeJournal) remove(ctx *AppContext) {
+	if err := os.Remove(j.file); err != nil && !os.IsNotExist(err) {
+		ctx.Logger.Collect("Failed to remove resize journal %s: %v", j.file, err)
+	}
+}
+
+// interruptedResizeError is returned by a resize of a VHD whose last resize
+// was interrupted
+func interruptedResizeError(j *resizeJournal) error {
+	return &types.VHDError{
+		Op:   "resize",
+		Path: j.Path,
+		Err:  fmt.Errorf("a resize to %s started %s was interrupted after step %q", j.NewSize, j.Started, j.last()),
+		Help: fmt.Sprintf("Finish it with 'vhdm resize --vhd-path %s --resume', or roll it back with --abort", j.Path),
+	}
+}
+
+// releaseResizeVHDs unmounts and detaches the VHDs of an interrupted resize,
+// which stay attached at its temporary mount points when vhdm was killed
+func releaseResizeVHDs(ctx *AppContext, j *resizeJournal) {
+	for _, uuid := range []string{j.OldUUID, j.NewUUID} {
+		if uuid == "" {
+			continue
+		}
+		if mountPoint, _ := ctx.WSL.GetMountPoint(uuid); mountPoint != "" {
+			if err := ctx.WSL.Unmount(mountPoint); err != nil {
+				ctx.Logger.Warn("Failed to unmount %s: %v", mountPoint, err)
+			}
+		}
+	}
+	for _, path := range []string{j.Path, j.NewPath, j.Backup} {
+		if err := ctx.WSL.DetachVHD(path); err != nil && !types.IsNotAttached(err) {
+			ctx.Logger.Debug("Failed to detach %s: %v", path, err)
+		}
+	}
+}
+
+// resumeResize finishes the interrupted resize of vhdPath from its journal
+func resumeResize(ctx *AppContext, vhdPath, tempDir string) error {
+	log := ctx.Logger
+
+	lock, err := lockVHDOperation(ctx, "resize", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	j, err := loadResizeJournal(ctx, vhdPath)
+	if err != nil {
+		return &types.VHDError{Op: "resize", Path: vhdPath, Err: err}
+	}
+	if j == nil {
+		return &types.VHDError{Op: "resize", Path: vhdPath, Err: fmt.Errorf("no interrupted resize to resume")}
+	}
+	log.Info("Resuming the resize of %s to %s after step %q...", vhdPath, j.NewSize, j.last())
+
+	if !ctx.Config.Yes {
+		log.Warn("This will finish resizing %s to %s", vhdPath, j.NewSize)
+		log.Warn("Run with --yes to confirm")
+		return fmt.Errorf("operation cancelled")
+	}
+
+	releaseResizeVHDs(ctx, j)
+	if err := j.syncRenames(ctx); err != nil {
+		return &types.VHDError{Op: "resize", Path: vhdPath, Err: err}
+	}
+	// A new VHD whose creation was interrupted is created anew
+	if newWSLPath := ctx.WSL.ConvertPath(j.NewPath); !j.done(resizeStepCreated) && ctx.WSL.FileExists(newWSLPath) {
+		if err := ctx.WSL.DeleteVHD(newWSLPath); err != nil {
+			return &types.VHDError{Op: "resize", Path: vhdPath, Err: fmt.Errorf("failed to remove the incomplete %s: %w", j.NewPath, err)}
+		}
+	}
+	return copyResize(ctx, j, tempDir, func() { restoreResizedMount(ctx, j.Path, j.OldUUID, j.MountPoint) })
+}
+
+// abortResize rolls the interrupted resize of vhdPath back: the original VHD
+// is put back in place, tracked and mounted as before, and the new VHD
+// removed
+func abortResize(ctx *AppContext, vhdPath string) error {
+	log := ctx.Logger
+
+	lock, err := lockVHDOperation(ctx, "resize", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	j, err := loadResizeJournal(ctx, vhdPath)
+	if err != nil {
+		return &types.VHDError{Op: "resize", Path: vhdPath, Err: err}
+	}
+	if j == nil {
+		return &types.VHDError{Op: "resize", Path: vhdPath, Err: fmt.Errorf("no interrupted resize to abort")}
+	}
+	log.Info("Rolling back the resize of %s to %s after step %q...", vhdPath, j.NewSize, j.last())
+
+	if !ctx.Config.Yes {
+		log.Warn("This will restore the original %s and remove %s", vhdPath, j.NewPath)
+		log.Warn("Run with --yes to confirm")
+		return fmt.Errorf("operation cancelled")
+	}
+
+	releaseResizeVHDs(ctx, j)
+	if err := j.syncRenames(ctx); err != nil {
+		return &types.VHDError{Op: "resize", Path: vhdPath, Err: err}
+	}
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	newWSLPath := ctx.WSL.ConvertPath(j.NewPath)
+	backupWSLPath := ctx.WSL.ConvertPath(j.Backup)
+
+	// Undo the renames, newest first
+	if j.done(resizeStepRenamed) {
+		if err := ctx.WSL.RenameFile(wslPath, newWSLPath); err != nil {
+			return &types.VHDError{Op: "resize", Path: vhdPath, Err: fmt.Errorf("failed to move the resized VHD aside: %w", err)}
+		}
+		if err := j.rewind(resizeStepBackedUp); err != nil {
+			return err
+		}
+	}
+	if j.done(resizeStepBackedUp) {
+		if err := ctx.WSL.RenameFile(backupWSLPath, wslPath); err != nil {
+			return &types.VHDError{Op: "resize", Path: vhdPath, Err: fmt.Errorf("failed to restore the original from %s: %w", j.Backup, err)}
+		}
+		if err := j.rewind(resizeStepCopied); err != nil {
+			return err
+		}
+	}
+	if ctx.WSL.FileExists(newWSLPath) {
+		if err := ctx.WSL.DeleteVHD(newWSLPath); err != nil {
+			return &types.VHDError{Op: "resize", Path: vhdPath, Err: fmt.Errorf("failed to remove %s: %w", j.NewPath, err)}
+		}
+	}
+
+	if j.OldUUID != "" {
+		if err := ctx.Tracker.SaveMapping(vhdPath, j.OldUUID, "", ""); err != nil {
+			log.Collect("Failed to save tracking info: %v", err)
+		}
+	}
+	j.remove(ctx)
+	restoreResizedMount(ctx, vhdPath, j.OldUUID, j.MountPoint)
+
+	log.Success("Resize rolled back")
+	return printResult(ctx, ResizeResult{
+		Path:       vhdPath,
+		NewSize:    j.NewSize,
+		Method:     "copy",
+		NewUUID:    j.OldUUID,
+		OldUUID:    j.OldUUID,
+		MountPoint: j.MountPoint,
+		RolledBack: true,
+	})
+}
@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newRestoreCmd() *cobra.Command {
+	var (
+		vhdPath string
+		backup  string
+		unpin   bool
+		noMount bool
+	)
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Replace a VHD with a backup of it",
+		Long: `Restore a VHD file from a backup (e.g. one written by 'vhdm backup').
+
+The backup is checked with 'qemu-img check' first and refused when corrupt;
+formats without a check (.vhd, raw) are restored with a warning.
+It is copied next to the VHD and attached once to read its filesystem UUID,
+then replaces the VHD file; the current VHD is unmounted and detached first.
+Tracking is updated with the UUID of the backup, and the VHD is mounted again
+at its tracked mount point (the current one, else the last one) unless
+--no-mount is given. The VHD file does not need to exist, so a lost disk can
+be restored to its tracked path.
+
+The backup path may be a Linux path or a Windows path (C:/...).`,
+		Example: `  vhdm restore --backup D:/Backups/data-2024.vhdx --vhd-path C:/VMs/data.vhdx --yes
+  vhdm restore --backup /mnt/nas/data.vhdx --vhd-path C:/VMs/data.vhdx --yes --no-mount`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRestore(appContext(cmd), vhdPath, backup, unpin, noMount)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path to restore (Windows format)")
+	cmd.Flags().StringVar(&backup, "backup", "", "Backup file to restore from")
+	cmd.Flags().BoolVar(&unpin, "unpin", false, "Remove the pin of a pinned VHD and restore it")
+	cmd.Flags().BoolVar(&noMount, "no-mount", false, "Leave the restored VHD detached")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("backup")
+	return cmd
+}
+
+func runRestore(ctx *AppContext, vhdPath, backup string, unpin, noMount bool) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "restore", Path: vhdPath, Err: err}
+	}
+	if err := ensureNotReference(ctx, "restore", vhdPath); err != nil {
+		return err
+	}
+	if err := checkPinned(ctx, "restore", vhdPath, unpin); err != nil {
+		return err
+	}
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	backupWSLPath := ctx.WSL.ConvertPath(backup)
+	if backupWSLPath == wslPath {
+		return &types.VHDError{Op: "restore", Path: vhdPath, Err: fmt.Errorf("backup file is the VHD itself")}
+	}
+	if !ctx.WSL.FileExists(backupWSLPath) {
+		return &types.VHDError{Op: "restore", Path: backup, Err: types.ErrVHDNotFound}
+	}
+	ext := path.Ext(vhdPath)
+	tmpPath := strings.TrimSuffix(vhdPath, ext) + "_restore" + ext
+	tmpWSLPath := ctx.WSL.ConvertPath(tmpPath)
+	if ctx.WSL.FileExists(tmpWSLPath) {
+		return fmt.Errorf("temporary file already exists: %s - please remove or rename it first", tmpPath)
+	}
+
+	entry, _ := ctx.Tracker.GetEntry(vhdPath)
+	mountPoint := entry.LastMount
+	if len(entry.MountPoints) > 0 {
+		mountPoint = entry.MountPoints[0]
+	}
+	if noMount {
+		mountPoint = ""
+	}
+
+	lock, err := lockVHDOperation(ctx, "restore", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	log.Debug("Restore operation starting")
+
+	// Verify the backup
+	log.Info("Checking backup %s...", backup)
+	check, err := ctx.WSL.CheckImage(backupWSLPath)
+	switch {
+	case errors.Is(err, wsl.ErrCheckUnsupported):
+		log.Warn("The format of %s has no consistency check; restoring it unverified", backup)
+	case err != nil:
+		return &types.VHDError{Op: "restore", Path: backup, Err: fmt.Errorf("backup could not be verified: %w", err)}
+	case check.CheckErrors > 0 || check.Corruptions > 0:
+		return &types.VHDError{
+			Op:   "restore",
+			Path: backup,
+			Err:  fmt.Errorf("backup is corrupt (%d corruptions, %d check errors)", check.Corruptions, check.CheckErrors),
+		}
+	case check.Leaks > 0:
+		log.Warn("Backup has %d leaked clusters (harmless, they only waste space)", check.Leaks)
+	}
+
+	exists := ctx.WSL.FileExists(wslPath)
+	if exists {
+		if !ctx.Config.Yes {
+			log.Warn("This will replace %s with the backup %s, discarding all later changes", vhdPath, backup)
+			log.Warn("Run with --yes to confirm")
+			return fmt.Errorf("operation cancelled")
+		}
+		if err := confirmByName(ctx, "restore", path.Base(vhdPath), vhdDiskSize(ctx, wslPath)); err != nil {
+			return err
+		}
+	}
+	releasePin(ctx, vhdPath)
+
+	// Unmount and detach the current VHD
+	if entry.UUID != "" {
+		if attached, _ := ctx.WSL.IsAttached(entry.UUID); attached {
+			for _, mp := range entry.MountPoints {
+				log.Info("Unmounting %s...", mp)
+				if err := ctx.WSL.Unmount(mp); err != nil {
+					return &types.VHDError{Op: "restore", Path: vhdPath, Err: fmt.Errorf("failed to unmount VHD: %w", err)}
+				}
+			}
+			log.Info("Detaching VHD...")
+			if err := ctx.WSL.DetachVHD(vhdPath); err != nil && !types.IsNotAttached(err) {
+				return &types.VHDError{Op: "restore", Path: vhdPath, Err: fmt.Errorf("failed to detach VHD: %w", err)}
+			}
+			markDetached(ctx, vhdPath)
+		}
+	}
+
+	// remountCurrent mounts the current VHD back where it was when the
+	// restore fails before replacing it
+	remountCurrent := func() {
+		if len(entry.MountPoints) == 0 {
+			return
+		}
+		if err := restoreMount(ctx, vhdPath, entry.UUID, entry.MountPoints[0], entry.MountOptions); err != nil {
+			log.Warn("Failed to mount the VHD again at %s: %v", entry.MountPoints[0], err)
+		}
+	}
+
+	// The VHD file is replaced at the end; fail now rather than after the
+	// copy when Windows holds it open
+	if exists {
+		if err := ensureNotInUseByWindows(ctx, "restore", vhdPath); err != nil {
+			remountCurrent()
+			return err
+		}
+	}
+
+	// Copy next to the VHD first, so a failed copy leaves it intact
+	log.Info("Copying %s to %s (this may take a while)...", backup, tmpPath)
+	if err := ctx.WSL.CopyFile(backupWSLPath, tmpWSLPath); err != nil {
+		remountCurrent()
+		return &types.VHDError{Op: "restore", Path: vhdPath, Err: err}
+	}
+	uuid, fsType, err := readFilesystemID(ctx, tmpPath)
+	if err != nil {
+		ctx.WSL.DeleteVHD(tmpWSLPath)
+		remountCurrent()
+		return &types.VHDError{Op: "restore", Path: backup, Err: err}
+	}
+	if err := ctx.WSL.RenameFile(tmpWSLPath, wslPath); err != nil {
+		ctx.WSL.DeleteVHD(tmpWSLPath)
+		remountCurrent()
+		return &types.VHDError{Op: "restore", Path: vhdPath, Err: err}
+	}
+
+	// The filesystem is the one of the backup now
+	if err := ctx.Tracker.SaveMapping(vhdPath, uuid, "", ""); err != nil {
+		log.Collect("Failed to save tracking info: %v", err)
+	}
+	if fsType != "" {
+		ctx.Tracker.SetMountInfo(vhdPath, entry.MountOptions, fsType)
+	}
+	updateServiceUUID(ctx, vhdPath, entry.UUID, uuid)
+
+	res := RestoreResult{Path: vhdPath, Backup: backup, UUID: uuid, OldUUID: entry.UUID}
+	if mountPoint != "" && uuid != "" {
+		if err := restoreMount(ctx, vhdPath, uuid, mountPoint, entry.MountOptions); err != nil {
+			log.Warn("Failed to mount the restored VHD at %s: %v", mountPoint, err)
+		} else {
+			res.MountPoint = mountPoint
+		}
+	}
+
+	// Output
+	log.Success("VHD restored from %s", backup)
+	return printResult(ctx, res)
+}
+
+// readFilesystemID attaches a detached VHD once to read the UUID and type of
+// its filesystem
+func readFilesystemID(ctx *AppContext, vhdPath string) (uuid, fsType string, err error) {
+	devName, attached, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
+	if attached {
+		defer func() {
+			if err := ctx.WSL.DetachVHD(vhdPath); err != nil {
+				ctx.Logger.Warn("Failed to detach %s: %v", vhdPath, err)
+			}
+		}()
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to attach %s: %w", vhdPath, err)
+	}
+	uuid, _ = ctx.WSL.GetUUIDByDevice(devName)
+	if uuid == "" {
+		return "", "", fmt.Errorf("%w: no filesystem found", types.ErrVHDNotFormatted)
+	}
+	fsType, _ = ctx.WSL.GetFilesystemType(devName)
+	return uuid, fsType, nil
+}
+
+// restoreMount attaches a restored VHD and mounts it at mountPoint, updating
+// its ID file for the path it was restored to
+func restoreMount(ctx *AppContext, vhdPath, uuid, mountPoint, options string) error {
+	devName, attached, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
+	if err != nil {
+		if attached {
+			ctx.WSL.DetachVHD(vhdPath)
+		}
+		return err
+	}
+	if err := ctx.WSL.MountByUUIDWithOptions(uuid, mountPoint, options); err != nil {
+		return err
+	}
+	if err := ctx.Tracker.SaveMapping(vhdPath, uuid, mountPoint, devName); err != nil {
+		ctx.Logger.Collect("Failed to save tracking info: %v", err)
+	}
+	if err := stampIDFile(ctx, vhdPath, uuid, mountPoint); err != nil {
+		ctx.Logger.Warn("Failed to update the ID file: %v", err)
+	}
+	ctx.Logger.Success("Mounted at %s", mountPoint)
+	return nil
+}
+
+// RestoreResult is the outcome of 'vhdm restore'
+type RestoreResult struct {
+	Path       string `json:"path"`
+	Backup     string `json:"backup"`
+	UUID       string `json:"uuid"`
+	OldUUID    string `json:"oldUuid,omitempty"`
+	MountPoint string `json:"mountPoint,omitempty"`
+}
+
+func (r RestoreResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{
+		{"Path", r.Path},
+		{"Backup", r.Backup},
+		{"UUID", r.UUID},
+	}
+	if r.OldUUID != "" && r.OldUUID != r.UUID {
+		pairs = append(pairs, [2]string{"Previous UUID", r.OldUUID})
+	}
+	status := "restored (detached)"
+	if r.MountPoint != "" {
+		pairs = append(pairs, [2]string{"Mount Point", r.MountPoint})
+		status = "restored and mounted"
+	}
+	return "Restore Result", append(pairs, [2]string{"Status", status})
+}
+
+func (r RestoreResult) quiet() quietLine {
+	return quietLine{Key: r.Path, UUID: r.UUID, State: "restored from " + r.Backup}
+}
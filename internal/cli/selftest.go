@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newSelfTestCmd() *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:   "selftest",
+		Short: "Check that VHD create, attach, format and mount work on this machine",
+		Long: `Run a quick end-to-end check of the whole stack: create a 64M VHD, attach,
+format and mount it, write and read a file, then unmount, detach and delete it.
+Useful after WSL or Windows updates.
+
+The VHD is created in the Windows %TEMP% directory unless --dir is given. It is
+not tracked, and is cleaned up even when a step fails.`,
+		Example: `  vhdm selftest
+  vhdm selftest --dir D:/scratch`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSelfTest(appContext(cmd), dir)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&dir, "dir", "", "Windows directory for the test VHD (default: %TEMP%)")
+	return cmd
+}
+
+func runSelfTest(ctx *AppContext, dir string) error {
+	log := ctx.Logger
+
+	if err := checkListOutput(ctx, "selftest"); err != nil {
+		return err
+	}
+
+	if dir == "" {
+		var err error
+		if dir, err = ctx.WSL.WindowsTempDir(); err != nil {
+			return &types.VHDError{Op: "selftest", Err: err, Help: "Pass a Windows directory with --dir"}
+		}
+	}
+	if err := validation.ValidateWindowsPath(dir); err != nil {
+		return &types.VHDError{Op: "selftest", Path: dir, Err: err}
+	}
+
+	log.Info("Running self-test in %s...", dir)
+	steps, err := ctx.WSL.SelfTest(dir, func(step wsl.SelfTestStep) {
+		if step.Err != nil {
+			log.Debug("Self-test step %s failed: %v", step.Name, step.Err)
+		} else {
+			log.Debug("Self-test step %s passed in %s", step.Name, step.Duration)
+		}
+	})
+
+	switch {
+	case structuredOutput(ctx):
+		if err := printStructured(ctx, selfTestRows(steps)); err != nil {
+			return err
+		}
+	case ctx.Config.Quiet:
+		for _, step := range steps {
+			printQuiet(quietLine{Key: step.Name, State: selfTestState(step)})
+		}
+	default:
+		printSelfTestTable(ctx, steps)
+	}
+
+	if err != nil {
+		return &types.VHDError{
+			Op:   "selftest",
+			Err:  err,
+			Help: "Re-run with --debug to see the commands of the failing step",
+		}
+	}
+	log.Success("Self-test passed")
+	return nil
+}
+
+func selfTestState(step wsl.SelfTestStep) string {
+	if step.Err != nil {
+		return "failed"
+	}
+	return "ok"
+}
+
+// selfTestRow is one self-test step in structured output
+type selfTestRow struct {
+	Step       string `json:"step"`
+	Result     string `json:"result"`
+	DurationMS int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+func selfTestRows(steps []wsl.SelfTestStep) []selfTestRow {
+	rows := make([]selfTestRow, 0, len(steps))
+	for _, step := range steps {
+		row := selfTestRow{Step: step.Name, Result: selfTestState(step), DurationMS: step.Duration.Milliseconds()}
+		if step.Err != nil {
+			row.Error = step.Err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func printSelfTestTable(ctx *AppContext, steps []wsl.SelfTestStep) {
+	fmt.Println()
+	fmt.Println("Self-Test")
+	fmt.Println()
+
+	colWidths := []int{10, 8, 10, 50}
+	headers := []string{"Step", "Result", "Time", "Error"}
+
+	utils.PrintTableHeader(colWidths, headers)
+
+	for _, step := range steps {
+		result := ctx.Theme.Green("ok")
+		errMsg := "-"
+		if step.Err != nil {
+			result = ctx.Theme.Red("failed")
+			errMsg = step.Err.Error()
+		}
+		utils.PrintTableRow(colWidths, step.Name, result, step.Duration.Round(10*time.Millisecond).String(), errMsg)
+	}
+
+	utils.PrintTableFooter(colWidths)
+}
@@ -1,10 +1,14 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +16,7 @@ import (
 
 	"github.com/rjdinis/vhdm/internal/types"
 	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
 )
 
 func newServiceCmd() *cobra.Command {
@@ -23,6 +28,9 @@ func newServiceCmd() *cobra.Command {
 This command creates, enables, disables, or removes systemd system services
 that will automatically attach and mount VHDs when your WSL instance starts.
 
+Units are created in /etc/systemd/system, or in $VHDM_UNIT_DIR. Units created
+by earlier versions in /usr/lib/systemd/system are still listed and removed.
+
 Note: These operations require root privileges (sudo).`,
 	}
 
@@ -33,6 +41,7 @@ Note: These operations require root privileges (sudo).`,
 		newServiceRemoveCmd(),
 		newServiceStatusCmd(),
 		newServiceListCmd(),
+		newServiceOverrideCmd(),
 		newServiceMonitorCmd(),
 	)
 
@@ -46,6 +55,10 @@ func newServiceCreateCmd() *cobra.Command {
 		fsType             string
 		serviceName        string
 		healthCheckInterval int
+		automount           bool
+		idleTimeout         int
+		mountOpts           string
+		dryRun              bool
 	)
 
 	cmd := &cobra.Command{
@@ -60,22 +73,41 @@ The service will:
 - Monitor mount health with configurable interval
 - Run automatically when WSL starts
 
-Note: Requires root privileges (sudo).`,
+With --automount, systemd automount units are installed instead: the VHD is only
+attached and mounted on first access under the mount point, and with
+--idle-timeout it is unmounted and detached again after that many idle seconds.
+
+The VHD is mounted with the options it was last mounted with by 'vhdm mount'
+(see 'mount --options'), so boot mounts match manual ones; --options overrides
+them.
+
+With --dry-run, the unit files are printed instead of written, as a diff
+against the installed ones when units with the same name exist, and nothing
+is enabled or started.
+
+Note: Requires root privileges (sudo), except with --dry-run.`,
 		Example: `  vhdm service create --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/data
   vhdm service create --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/data --name my-disk
-  vhdm service create --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/data --health-check-interval 60`,
+  vhdm service create --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/data --health-check-interval 60
+  vhdm service create --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/data --automount --idle-timeout 600
+  vhdm service create --vhd-path C:/VMs/disk.vhdx --mount-point /mnt/data --dry-run`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runServiceCreate(vhdPath, mountPoint, fsType, serviceName, healthCheckInterval)
+			return runServiceCreate(appContext(cmd), vhdPath, mountPoint, fsType, serviceName, healthCheckInterval, automount, idleTimeout, mountOpts, dryRun)
 		},
 	}
 
 	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (required)")
 	cmd.Flags().StringVar(&mountPoint, "mount-point", "", "Mount point path (required)")
-	cmd.Flags().StringVar(&fsType, "type", "ext4", "Filesystem type")
-	cmd.Flags().StringVar(&serviceName, "name", "", "Service name (auto-generated if not provided)")
+	cmd.Flags().StringVar(&fsType, "type", "", "Filesystem type (default: recorded at the last mount, else ext4)")
+	cmd.Flags().StringVar(&serviceName, "name", "", "Service name (auto-generated if not provided; not with --automount)")
 	cmd.Flags().IntVar(&healthCheckInterval, "health-check-interval", 30, "Health check interval in seconds")
+	cmd.Flags().BoolVar(&automount, "automount", false, "Mount on first access via systemd automount instead of on boot")
+	cmd.Flags().IntVar(&idleTimeout, "idle-timeout", 0, "With --automount, unmount and detach after this many idle seconds (0 to never)")
+	cmd.Flags().StringVarP(&mountOpts, "options", "o", "", "Mount options (default: those of the last 'vhdm mount')")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the unit files (as a diff against existing ones) instead of installing them")
 	cmd.MarkFlagRequired("vhd-path")
 	cmd.MarkFlagRequired("mount-point")
+	cmd.RegisterFlagCompletionFunc("type", completeFilesystemTypes)
 
 	return cmd
 }
@@ -88,7 +120,7 @@ func newServiceEnableCmd() *cobra.Command {
 		Short:   "Enable a VHD mount service to start on boot",
 		Example: `  vhdm service enable --name vhdm-mount-data`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runServiceEnable(serviceName)
+			return runServiceEnable(appContext(cmd), serviceName)
 		},
 	}
 
@@ -106,7 +138,7 @@ func newServiceDisableCmd() *cobra.Command {
 		Short:   "Disable a VHD mount service from starting on boot",
 		Example: `  vhdm service disable --name vhdm-mount-data`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runServiceDisable(serviceName)
+			return runServiceDisable(appContext(cmd), serviceName)
 		},
 	}
 
@@ -124,7 +156,7 @@ func newServiceRemoveCmd() *cobra.Command {
 		Short:   "Remove a VHD mount service",
 		Example: `  vhdm service remove --name vhdm-mount-data`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runServiceRemove(serviceName)
+			return runServiceRemove(appContext(cmd), serviceName)
 		},
 	}
 
@@ -138,11 +170,18 @@ func newServiceStatusCmd() *cobra.Command {
 	var serviceName string
 
 	cmd := &cobra.Command{
-		Use:     "status",
-		Short:   "Show status of a VHD mount service",
-		Example: `  vhdm service status --name vhdm-mount-data`,
+		Use:   "status",
+		Short: "Show status of a VHD mount service",
+		Long: `Show the status of a VHD mount service as 'systemctl status' does.
+
+With --output json|yaml|csv|sh or --quiet, the service is printed like an
+entry of 'vhdm service list' instead: whether it is enabled and active, the
+VHD, UUID and mount point it mounts, how often systemd restarted it and how it
+last failed.`,
+		Example: `  vhdm service status --name vhdm-mount-data
+  vhdm service status --name vhdm-mount-data --output json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runServiceStatus(serviceName)
+			return runServiceStatus(appContext(cmd), serviceName)
 		},
 	}
 
@@ -156,29 +195,60 @@ func newServiceListCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "list",
 		Short: "List all VHD mount services",
+		Long: `List the services created by 'vhdm service create' and 'vhdm automount',
+with whether they are enabled and active and the VHD they mount.
+
+With --output json|yaml|csv each service is an object with its name, enabled
+and active state, VHD path, UUID and mount point, the number of restarts and
+its last failure (the systemd result, exit status and time), so configuration
+management tools can assert the set of boot mounts.`,
+		Example: `  vhdm service list
+  vhdm service list --output json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runServiceList()
+			return runServiceList(appContext(cmd))
 		},
 	}
 }
 
-func runServiceCreate(vhdPath, mountPoint, fsType, serviceName string, healthCheckInterval int) error {
-	ctx := getContext()
+func runServiceCreate(ctx *AppContext, vhdPath, mountPoint, fsType, serviceName string, healthCheckInterval int, automount bool, idleTimeout int, mountOpts string, dryRun bool) error {
 	log := ctx.Logger
 
 	// Validate inputs
 	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
 		return &types.VHDError{Op: "service create", Path: vhdPath, Err: err}
 	}
+	// Placeholders are expanded now since units need a concrete mount point
+	mountPoint = expandMountPoint(ctx, mountPoint, vhdPath, "")
 	if err := validation.ValidateMountPoint(mountPoint); err != nil {
 		return &types.VHDError{Op: "service create", Err: err}
 	}
-	if err := validation.ValidateFilesystemType(fsType); err != nil {
-		return &types.VHDError{Op: "service create", Err: err}
+	if fsType != "" {
+		if err := validation.ValidateFilesystemType(fsType); err != nil {
+			return &types.VHDError{Op: "service create", Err: err}
+		}
 	}
 	if healthCheckInterval < 1 {
 		return &types.VHDError{Op: "service create", Err: fmt.Errorf("health check interval must be at least 1 second")}
 	}
+	if idleTimeout < 0 {
+		return &types.VHDError{Op: "service create", Err: fmt.Errorf("idle timeout must not be negative")}
+	}
+	if idleTimeout > 0 && !automount {
+		return &types.VHDError{Op: "service create", Err: fmt.Errorf("--idle-timeout requires --automount")}
+	}
+	if serviceName != "" && automount {
+		// systemd derives mount and automount unit names from the mount point
+		return &types.VHDError{
+			Op:   "service create",
+			Err:  fmt.Errorf("%w: --name cannot be used with --automount", types.ErrInvalidInput),
+			Help: "Automount units are named after the mount point (vhdm-automount-<mount-unit>)",
+		}
+	}
+	if mountOpts != "" {
+		if err := validation.ValidateMountOptions(mountOpts); err != nil {
+			return &types.VHDError{Op: "service create", Err: err}
+		}
+	}
 
 	// Check if VHD file exists
 	wslPath := ctx.WSL.ConvertPath(vhdPath)
@@ -198,7 +268,7 @@ func runServiceCreate(vhdPath, mountPoint, fsType, serviceName string, healthChe
 		return &types.VHDError{
 			Op:   "service create",
 			Path: vhdPath,
-			Err:  fmt.Errorf("VHD is not tracked in the system"),
+			Err:  types.ErrVHDNotTracked,
 			Help: fmt.Sprintf("The VHD must be attached and mounted at least once before creating a service.\n"+
 				"This ensures the filesystem UUID is known and prevents device detection race conditions.\n\n"+
 				"To fix this:\n"+
@@ -214,14 +284,40 @@ func runServiceCreate(vhdPath, mountPoint, fsType, serviceName string, healthChe
 
 	log.Debug("VHD is tracked with UUID: %s", uuid)
 
+	if err := checkMountPointClaims(ctx, "service create", vhdPath, mountPoint); err != nil {
+		return err
+	}
+
+	// Reuse the options and filesystem type of the last manual mount so boot
+	// mounts match it
+	if entry, err := ctx.Tracker.GetEntry(vhdPath); err == nil {
+		if mountOpts == "" {
+			mountOpts = entry.MountOptions
+		}
+		if fsType == "" {
+			fsType = entry.FSType
+		}
+	}
+	if fsType == "" {
+		fsType = "ext4"
+	}
+	if mountOpts != "" {
+		log.Debug("Mount options: %s", mountOpts)
+	}
+
+	// Units of VHDs declared with 'vhdm depend' must be up before this one
+	deps := dependencyUnits(ctx, vhdPath)
+	if len(deps) > 0 {
+		log.Debug("Service depends on: %s", strings.Join(deps, " "))
+	}
+
+	if automount {
+		return installAutomount(ctx, vhdPath, uuid, mountPoint, fsType, mountOpts, idleTimeout, deps, dryRun)
+	}
+
 	// Generate service name if not provided
 	if serviceName == "" {
-		// Extract filename without extension and sanitize
-		base := filepath.Base(vhdPath)
-		base = strings.TrimSuffix(base, filepath.Ext(base))
-		base = strings.ReplaceAll(base, " ", "-")
-		base = strings.ToLower(base)
-		serviceName = fmt.Sprintf("vhdm-mount-%s", base)
+		serviceName = defaultServiceName(vhdPath)
 	}
 
 	// Ensure service name ends with .service
@@ -231,39 +327,19 @@ func runServiceCreate(vhdPath, mountPoint, fsType, serviceName string, healthChe
 
 	log.Debug("Creating service: %s", serviceName)
 
-	// Get tracking file path (use the context's config which handles SUDO_USER)
-	trackingFile := ctx.Config.TrackingFile
-
 	// Get vhdm binary path
 	vhdmPath, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("failed to get vhdm executable path: %w", err)
 	}
 
-	// Create systemd service content
-	// Use 'vhdm service monitor' subcommand with health monitoring for automatic restart if mount fails
-	// Use UUID instead of path to avoid device detection race conditions
-	// when multiple services start concurrently
-	serviceContent := fmt.Sprintf(`[Unit]
-Description=Auto-mount VHD: %s
-After=local-fs.target mnt-c.mount
-Requires=mnt-c.mount
-Before=network.target
-
-[Service]
-Type=simple
-Environment="PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin:/mnt/c/WINDOWS/system32:/mnt/c/WINDOWS"
-Environment="VHDM_TRACKING_FILE=%s"
-Environment="HOME=%s"
-ExecStart=%s service monitor --uuid "%s" --mount-point "%s" --interval %d
-Restart=on-failure
-RestartSec=10
-TimeoutStartSec=60
-TimeoutStopSec=30
+	serviceContent := buildServiceUnit(ctx, vhdPath, uuid, mountPoint, healthCheckInterval, mountOpts, deps, vhdmPath)
 
-[Install]
-WantedBy=multi-user.target
-`, vhdPath, trackingFile, os.Getenv("HOME"), vhdmPath, uuid, mountPoint, healthCheckInterval)
+	systemdDir := ctx.Config.UnitDir
+	servicePath := filepath.Join(systemdDir, serviceName)
+	if dryRun {
+		return previewUnitFile(ctx, unitFilePath(ctx, serviceName), serviceContent)
+	}
 
 	// System services require root privileges
 	if os.Geteuid() != 0 {
@@ -271,24 +347,28 @@ WantedBy=multi-user.target
 	}
 
 	// Create systemd system directory if it doesn't exist
-	// Use /usr/lib/systemd/system (standard location for package-installed services)
-	// When enabled, systemd will create a symlink in /etc/systemd/system
-	systemdDir := "/usr/lib/systemd/system"
 	if err := os.MkdirAll(systemdDir, 0755); err != nil {
 		return fmt.Errorf("failed to create systemd directory: %w", err)
 	}
 
 	// Write service file
-	servicePath := filepath.Join(systemdDir, serviceName)
 	if err := os.WriteFile(servicePath, []byte(serviceContent), 0644); err != nil {
 		return fmt.Errorf("failed to write service file: %w", err)
 	}
+	removeLegacyUnit(ctx, serviceName)
+	recordService(ctx, vhdPath, serviceName)
 
-	log.Info("✓ Service created: %s", serviceName)
+	log.Info("%s Service created: %s", ctx.Theme.Success, serviceName)
 	log.Info("  Service file: %s", servicePath)
 	log.Info("  VHD Path: %s", vhdPath)
 	log.Info("  Mount Point: %s", mountPoint)
 	log.Info("  UUID: %s", uuid)
+	if mountOpts != "" {
+		log.Info("  Options: %s", mountOpts)
+	}
+	if len(deps) > 0 {
+		log.Info("  After: %s", strings.Join(deps, " "))
+	}
 	log.Info("")
 	log.Info("Features:")
 	log.Info("  • UUID-based mounting (prevents race conditions)")
@@ -298,38 +378,253 @@ WantedBy=multi-user.target
 
 	// Reload systemd daemon
 	log.Info("Reloading systemd daemon...")
-	if err := exec.Command("systemctl", "daemon-reload").Run(); err != nil {
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
 		log.Warn("Failed to reload systemd daemon: %v", err)
 	}
 
 	// Enable service
 	log.Info("Enabling service...")
-	cmd := exec.Command("systemctl", "enable", serviceName)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := systemctl(ctx, "enable", serviceName); err != nil {
 		return fmt.Errorf("failed to enable service: %w\n%s", err, string(output))
 	}
-	log.Info("✓ Service enabled (will start on boot)")
+	log.Info("%s Service enabled (will start on boot)", ctx.Theme.Success)
 
 	// Start service
 	log.Info("Starting service...")
-	cmd = exec.Command("systemctl", "start", serviceName)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := systemctl(ctx, "start", serviceName); err != nil {
 		return fmt.Errorf("failed to start service: %w\n%s", err, string(output))
 	}
-	log.Info("✓ Service started")
+	log.Info("%s Service started", ctx.Theme.Success)
 	log.Info("")
 
 	// Show service status
 	log.Info("Service Status:")
-	cmd = exec.Command("systemctl", "status", serviceName, "--no-pager", "--lines=10")
-	output, _ := cmd.CombinedOutput()
+	output, _ := systemctl(ctx, "status", serviceName, "--no-pager", "--lines=10")
 	fmt.Println(string(output))
 
 	return nil
 }
 
-func runServiceEnable(serviceName string) error {
-	ctx := getContext()
+// previewUnitFile prints the unit file a dry run would write to path: whole
+// when there is none yet, else as a unified diff against the installed one
+func previewUnitFile(ctx *AppContext, path, content string) error {
+	old, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		fmt.Printf("# %s (new)\n%s", path, content)
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to read unit file: %w", err)
+	case string(old) == content:
+		fmt.Printf("# %s (unchanged)\n", path)
+		return nil
+	}
+
+	cmd := exec.Command("diff", "-u", "--label", path, "--label", path+" (new)", path, "-")
+	cmd.Stdin = strings.NewReader(content)
+	output, err := cmd.Output()
+	// diff exits with 1 when the files differ
+	var exitErr *exec.ExitError
+	if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() != 1) {
+		ctx.Logger.Warn("Could not diff against the installed unit file: %v", err)
+		fmt.Printf("# %s (changed)\n%s", path, content)
+		return nil
+	}
+	fmt.Print(string(output))
+	return nil
+}
+
+// buildServiceUnit generates the service that mounts a VHD on boot. It runs
+// 'vhdm service monitor', which restarts the service if the mount fails, and
+// identifies the VHD by UUID instead of path to avoid device detection race
+// conditions when multiple services start concurrently.
+func buildServiceUnit(ctx *AppContext, vhdPath, uuid, mountPoint string, interval int, mountOpts string, deps []string, vhdmPath string) string {
+	execStart := []string{vhdmPath, "service", "monitor", "--uuid", uuid, "--mount-point", mountPoint, "--interval", strconv.Itoa(interval)}
+	if mountOpts != "" {
+		execStart = append(execStart, "--options", mountOpts)
+	}
+
+	// The tracking file of the context's config handles SUDO_USER
+	return fmt.Sprintf(`[Unit]
+Description=Auto-mount VHD: %s
+%s%sBefore=network.target
+
+[Service]
+Type=simple
+Environment="PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin:/mnt/c/WINDOWS/system32:/mnt/c/WINDOWS:/mnt/c/WINDOWS/System32/WindowsPowerShell/v1.0"
+%s
+%s
+%sExecStart=%s
+Restart=on-failure
+RestartSec=10
+TimeoutStartSec=60
+TimeoutStopSec=30
+
+[Install]
+WantedBy=multi-user.target
+`, systemdEscapeSpecifiers(vhdPath), windowsMountLines(vhdPath), unitDependencyLines(deps),
+		systemdEnvironment("VHDM_TRACKING_FILE", ctx.Config.TrackingFile), systemdEnvironment("HOME", os.Getenv("HOME")),
+		notifyEnvLine(ctx), systemdCommandLine(execStart...))
+}
+
+// defaultServiceName derives the service name from the VHD file name
+// (e.g., C:/VMs/My Data.vhdx -> vhdm-mount-my-data)
+func defaultServiceName(vhdPath string) string {
+	// Extract filename without extension and sanitize
+	base := filepath.Base(vhdPath)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	base = strings.ReplaceAll(base, " ", "-")
+	base = strings.ToLower(base)
+	return fmt.Sprintf("vhdm-mount-%s", base)
+}
+
+// dependencyUnits returns the systemd units that mount the VHDs the given VHD
+// depends on (see mountUnitFor)
+func dependencyUnits(ctx *AppContext, vhdPath string) []string {
+	entry, err := ctx.Tracker.GetEntry(vhdPath)
+	if err != nil {
+		return nil
+	}
+
+	var units []string
+	for _, dep := range entry.After {
+		units = append(units, mountUnitFor(ctx, dep))
+	}
+	return units
+}
+
+// mountUnitFor returns the systemd unit that mounts a VHD: the .mount unit of
+// its automount if one is installed, otherwise its service, by default name
+// when none is recorded
+func mountUnitFor(ctx *AppContext, vhdPath string) string {
+	entry, _ := ctx.Tracker.GetEntry(vhdPath)
+	if strings.HasPrefix(entry.Service, automountPrefix) {
+		return strings.TrimSuffix(strings.TrimPrefix(entry.Service, automountPrefix), ".service") + ".mount"
+	}
+	if entry.Service != "" {
+		return entry.Service
+	}
+	if entry.LastMount != "" {
+		stem := systemdEscapePath(entry.LastMount)
+		if _, err := os.Stat(unitFilePath(ctx, automountPrefix+stem+".service")); err == nil {
+			return stem + ".mount"
+		}
+	}
+	return defaultServiceName(vhdPath) + ".service"
+}
+
+// trackedService returns the unit installed for a VHD by 'vhdm service
+// create', as recorded in its tracking entry. Entries recorded before services
+// were tracked fall back to an installed unit with the default name, but only
+// when no other VHD claims that unit and its ExecStart= mounts this VHD's
+// UUID: VHDs with the same file name in other directories share the name.
+func trackedService(ctx *AppContext, vhdPath string) string {
+	entry, _ := ctx.Tracker.GetEntry(vhdPath)
+	if entry.Service != "" || entry.UUID == "" {
+		return entry.Service
+	}
+	unit := defaultServiceName(vhdPath) + ".service"
+	if owner, _ := ctx.Tracker.LookupPathByService(unit); owner != "" {
+		return ""
+	}
+	content, err := os.ReadFile(unitFilePath(ctx, unit))
+	if err != nil || !unitMountsUUID(string(content), entry.UUID) {
+		return ""
+	}
+	return unit
+}
+
+// unitMountsUUID reports whether the ExecStart= command of a unit file names
+// a filesystem UUID
+func unitMountsUUID(content, uuid string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		value, ok := strings.CutPrefix(line, "ExecStart=")
+		if !ok {
+			continue
+		}
+		for _, word := range splitUnitCommandLine(value) {
+			if strings.EqualFold(word, uuid) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recordService records unit as the service of a VHD in its tracking entry;
+// an empty unit clears it
+func recordService(ctx *AppContext, vhdPath, unit string) {
+	err := ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+		entry.Service = unit
+	})
+	if err != nil {
+		ctx.Logger.Collect("Failed to save tracking info: %v", err)
+	}
+}
+
+// forgetService clears unit from the tracking entry of the VHD it was
+// recorded for, after it was removed
+func forgetService(ctx *AppContext, unit string) {
+	if vhdPath, _ := ctx.Tracker.LookupPathByService(unit); vhdPath != "" {
+		recordService(ctx, vhdPath, "")
+	}
+}
+
+// legacyUnitDir is where units were created before the unit directory became
+// configurable; units found there are still listed and removed
+const legacyUnitDir = "/usr/lib/systemd/system"
+
+// unitFilePath returns the file of an installed unit, looking in the unit
+// directory, then in legacyUnitDir; for a unit that is not installed it is
+// the file it would be created as
+func unitFilePath(ctx *AppContext, unit string) string {
+	for _, dir := range []string{ctx.Config.UnitDir, legacyUnitDir} {
+		path := filepath.Join(dir, unit)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(ctx.Config.UnitDir, unit)
+}
+
+// removeLegacyUnit removes the copy of a unit in legacyUnitDir once it was
+// written to the unit directory, so removing the unit does not leave the old
+// one active
+func removeLegacyUnit(ctx *AppContext, unit string) {
+	if filepath.Clean(ctx.Config.UnitDir) == legacyUnitDir {
+		return
+	}
+	path := filepath.Join(legacyUnitDir, unit)
+	if err := os.Remove(path); err == nil {
+		ctx.Logger.Debug("Removed legacy unit file: %s", path)
+	} else if !os.IsNotExist(err) {
+		ctx.Logger.Warn("Failed to remove legacy unit file %s: %v", path, err)
+	}
+}
+
+// removeUnitFile removes the file of an installed unit along with its drop-in
+// directory (see 'vhdm service override')
+func removeUnitFile(ctx *AppContext, unit string) error {
+	path := unitFilePath(ctx, unit)
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(ctx.Config.UnitDir, unit+".d")); err != nil {
+		ctx.Logger.Warn("Failed to remove the drop-ins of %s: %v", unit, err)
+	}
+	return nil
+}
+
+// unitDependencyLines renders After=/Requires= lines for a [Unit] section
+func unitDependencyLines(units []string) string {
+	if len(units) == 0 {
+		return ""
+	}
+	joined := strings.Join(units, " ")
+	return fmt.Sprintf("After=%s\nRequires=%s\n", joined, joined)
+}
+
+func runServiceEnable(ctx *AppContext, serviceName string) error {
 	log := ctx.Logger
 
 	// Ensure service name ends with .service
@@ -345,17 +640,16 @@ func runServiceEnable(serviceName string) error {
 	}
 
 	// Reload systemd daemon
-	if err := exec.Command("systemctl", "daemon-reload").Run(); err != nil {
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
 		log.Debug("Failed to reload systemd daemon: %v", err)
 	}
 
 	// Enable service
-	cmd := exec.Command("systemctl", "enable", serviceName)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := systemctl(ctx, "enable", serviceName); err != nil {
 		return fmt.Errorf("failed to enable service: %w\n%s", err, string(output))
 	}
 
-	log.Info("✓ Service enabled: %s", serviceName)
+	log.Info("%s Service enabled: %s", ctx.Theme.Success, serviceName)
 	log.Info("  The service will start automatically on next boot")
 	log.Info("")
 	log.Info("To start the service now:")
@@ -364,8 +658,7 @@ func runServiceEnable(serviceName string) error {
 	return nil
 }
 
-func runServiceDisable(serviceName string) error {
-	ctx := getContext()
+func runServiceDisable(ctx *AppContext, serviceName string) error {
 	log := ctx.Logger
 
 	// Ensure service name ends with .service
@@ -381,19 +674,17 @@ func runServiceDisable(serviceName string) error {
 	}
 
 	// Disable service
-	cmd := exec.Command("systemctl", "disable", serviceName)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := systemctl(ctx, "disable", serviceName); err != nil {
 		return fmt.Errorf("failed to disable service: %w\n%s", err, string(output))
 	}
 
-	log.Info("✓ Service disabled: %s", serviceName)
+	log.Info("%s Service disabled: %s", ctx.Theme.Success, serviceName)
 	log.Info("  The service will no longer start on boot")
 
 	return nil
 }
 
-func runServiceRemove(serviceName string) error {
-	ctx := getContext()
+func runServiceRemove(ctx *AppContext, serviceName string) error {
 	log := ctx.Logger
 
 	// Ensure service name ends with .service
@@ -408,45 +699,59 @@ func runServiceRemove(serviceName string) error {
 		return fmt.Errorf("removing system services requires root privileges. Please run with sudo")
 	}
 
+	// Automount attach units are removed together with their mount and automount units
+	if strings.HasPrefix(serviceName, automountPrefix) {
+		return removeAutomount(ctx, serviceName)
+	}
+
 	// Stop service if running
-	stopCmd := exec.Command("systemctl", "stop", serviceName)
-	if err := stopCmd.Run(); err != nil {
+	if _, err := systemctl(ctx, "stop", serviceName); err != nil {
 		log.Debug("Service not running or already stopped")
 	}
 
 	// Disable service
-	disableCmd := exec.Command("systemctl", "disable", serviceName)
-	if err := disableCmd.Run(); err != nil {
+	if _, err := systemctl(ctx, "disable", serviceName); err != nil {
 		log.Debug("Service not enabled or already disabled")
 	}
 
-	// Remove service file from /usr/lib/systemd/system
-	systemdDir := "/usr/lib/systemd/system"
-	servicePath := filepath.Join(systemdDir, serviceName)
-
-	if err := os.Remove(servicePath); err != nil {
+	// Remove service file
+	servicePath := unitFilePath(ctx, serviceName)
+	if err := removeUnitFile(ctx, serviceName); err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("service file not found: %s", servicePath)
 		}
 		return fmt.Errorf("failed to remove service file: %w", err)
 	}
+	forgetService(ctx, serviceName)
 
 	// Reload systemd daemon
-	if err := exec.Command("systemctl", "daemon-reload").Run(); err != nil {
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
 		log.Debug("Failed to reload systemd daemon: %v", err)
 	}
 
-	log.Info("✓ Service removed: %s", serviceName)
+	log.Info("%s Service removed: %s", ctx.Theme.Success, serviceName)
 
 	return nil
 }
 
-func runServiceStatus(serviceName string) error {
+func runServiceStatus(ctx *AppContext, serviceName string) error {
 	// Ensure service name ends with .service
 	if !strings.HasSuffix(serviceName, ".service") {
 		serviceName += ".service"
 	}
 
+	if structuredOutput(ctx) || ctx.Config.Output == "sh" || ctx.Config.Quiet {
+		entry := serviceEntry(ctx, serviceName, trackedOwners(ctx))
+		if entry.Loaded == "not-found" {
+			return &types.VHDError{
+				Op:   "service status",
+				Err:  fmt.Errorf("service %s not found", serviceName),
+				Help: "List the services with 'vhdm service list'",
+			}
+		}
+		return printResult(ctx, entry)
+	}
+
 	// Show service status
 	cmd := exec.Command("systemctl", "status", serviceName)
 	cmd.Stdout = os.Stdout
@@ -466,66 +771,240 @@ func runServiceStatus(serviceName string) error {
 	return nil
 }
 
-func runServiceList() error {
-	ctx := getContext()
+func runServiceList(ctx *AppContext) error {
 	log := ctx.Logger
 
-	systemdDir := "/usr/lib/systemd/system"
-
-	// Check if directory exists
-	if _, err := os.Stat(systemdDir); os.IsNotExist(err) {
-		log.Info("No VHD mount services found")
-		return nil
-	}
-
-	// List all vhdm-mount-* services
-	entries, err := os.ReadDir(systemdDir)
-	if err != nil {
-		return fmt.Errorf("failed to read systemd directory: %w", err)
+	if err := checkListOutput(ctx, "service list"); err != nil {
+		return err
 	}
 
+	// List all vhdm-mount-* services, in the unit directory and the legacy one
 	var services []string
-	for _, entry := range entries {
-		if entry.IsDir() {
+	for _, systemdDir := range []string{ctx.Config.UnitDir, legacyUnitDir} {
+		entries, err := os.ReadDir(systemdDir)
+		if os.IsNotExist(err) {
 			continue
 		}
-		name := entry.Name()
-		if strings.HasPrefix(name, "vhdm-mount-") && strings.HasSuffix(name, ".service") {
-			services = append(services, name)
+		if err != nil {
+			return fmt.Errorf("failed to read systemd directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			name := entry.Name()
+			if (strings.HasPrefix(name, "vhdm-mount-") || strings.HasPrefix(name, automountPrefix)) && strings.HasSuffix(name, ".service") && !slices.Contains(services, name) {
+				services = append(services, name)
+			}
+		}
+	}
+
+	// Services recorded in tracking may have custom names
+	owners := trackedOwners(ctx)
+	for service := range owners {
+		if _, err := os.Stat(unitFilePath(ctx, service)); err == nil && !slices.Contains(services, service) {
+			services = append(services, service)
 		}
 	}
+	slices.Sort(services)
+
+	entries := make([]ServiceEntry, len(services))
+	for i, service := range services {
+		entries[i] = serviceEntry(ctx, service, owners)
+	}
+
+	if structuredOutput(ctx) {
+		return printStructured(ctx, nonNil(entries))
+	}
 
 	if len(services) == 0 {
 		log.Info("No VHD mount services found")
 		return nil
 	}
 
+	if ctx.Config.Quiet {
+		for _, entry := range entries {
+			printQuiet(entry.quiet())
+		}
+		return nil
+	}
+
 	fmt.Println()
 	fmt.Println("VHD Mount Services")
 	fmt.Println()
+	for _, entry := range entries {
+		statusSymbol := ctx.Theme.Inactive
+		if entry.Active == "active" {
+			statusSymbol = ctx.Theme.Active
+		}
 
-	for _, service := range services {
-		// Get service status
-		cmd := exec.Command("systemctl", "is-enabled", service)
-		output, _ := cmd.Output()
-		enabled := strings.TrimSpace(string(output))
+		fmt.Printf("  %s %s\n", statusSymbol, strings.TrimSuffix(entry.Name, ".service"))
+		if entry.Path != "" {
+			fmt.Printf("     VHD:          %s\n", entry.Path)
+		}
+		if entry.MountPoint != "" {
+			fmt.Printf("     Mount Point:  %s\n", entry.MountPoint)
+		}
+		fmt.Printf("     Enabled:      %s\n", entry.Enabled)
+		fmt.Printf("     Active:       %s\n", entry.Active)
+		if entry.LastFailure != nil {
+			fmt.Printf("     Last Failure: %s\n", ctx.Theme.Red(entry.LastFailure.describe(ctx)))
+		}
+		fmt.Println()
+	}
 
-		cmd = exec.Command("systemctl", "is-active", service)
-		output, _ = cmd.Output()
-		active := strings.TrimSpace(string(output))
+	return nil
+}
 
-		statusSymbol := "○"
-		if active == "active" {
-			statusSymbol = "●"
+// trackedOwners returns the VHDs by the services recorded for them in
+// tracking
+func trackedOwners(ctx *AppContext) map[string]string {
+	owners := map[string]string{}
+	paths, _ := ctx.Tracker.GetAllPaths()
+	for _, p := range paths {
+		entry, err := ctx.Tracker.GetEntry(p)
+		if err != nil || entry.Service == "" {
+			continue
 		}
+		owners[entry.Service] = p
+	}
+	return owners
+}
 
-		fmt.Printf("  %s %s\n", statusSymbol, strings.TrimSuffix(service, ".service"))
-		fmt.Printf("     Enabled: %s\n", enabled)
-		fmt.Printf("     Active:  %s\n", active)
-		fmt.Println()
+// ServiceEntry is a service in the output of 'vhdm service list' and
+// 'vhdm service status'
+type ServiceEntry struct {
+	Name        string          `json:"name"`
+	Enabled     string          `json:"enabled"` // As 'systemctl is-enabled' reports it: enabled, disabled, ...
+	Active      string          `json:"active"`  // As 'systemctl is-active' reports it: active, inactive, failed, ...
+	Path        string          `json:"path,omitempty"`
+	UUID        string          `json:"uuid,omitempty"`
+	MountPoint  string          `json:"mountPoint,omitempty"`
+	Restarts    int             `json:"restarts"`
+	LastFailure *ServiceFailure `json:"lastFailure,omitempty"`
+	Loaded      string          `json:"-"` // LoadState, not-found for unknown units
+}
+
+// ServiceFailure is how a service last failed, as systemd recorded it
+type ServiceFailure struct {
+	Result     string `json:"result"`     // Result of the service: exit-code, signal, timeout, ...
+	ExitStatus int    `json:"exitStatus"` // Exit status or signal of the main process
+	Time       string `json:"time,omitempty"`
+}
+
+// describe summarizes the failure for display
+func (f *ServiceFailure) describe(ctx *AppContext) string {
+	desc := fmt.Sprintf("%s (status %d)", f.Result, f.ExitStatus)
+	if f.Time != "" {
+		desc += " at " + displayTime(ctx, f.Time)
 	}
+	return desc
+}
 
-	return nil
+// serviceShowProperties are the properties of a service read by serviceEntry
+const serviceShowProperties = "LoadState,UnitFileState,ActiveState,Result,ExecMainStatus,ExecMainExitTimestamp,NRestarts"
+
+// systemdTimestamp is the layout of timestamps in 'systemctl show' output
+const systemdTimestamp = "Mon 2006-01-02 15:04:05 MST"
+
+// serviceEntry describes a service: its state from 'systemctl show' and the
+// VHD, UUID and mount point from its unit files, completed from tracking.
+// owners are the VHDs of the services recorded in tracking.
+func serviceEntry(ctx *AppContext, service string, owners map[string]string) ServiceEntry {
+	entry := ServiceEntry{Name: service}
+	output, _ := systemctlOutput(ctx, "show", service, "--property="+serviceShowProperties)
+	props := map[string]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			props[key] = value
+		}
+	}
+	entry.Loaded = props["LoadState"]
+	entry.Enabled, entry.Active = props["UnitFileState"], props["ActiveState"]
+	entry.Restarts, _ = strconv.Atoi(props["NRestarts"])
+	if result := props["Result"]; result != "" && result != "success" {
+		entry.LastFailure = &ServiceFailure{Result: result}
+		entry.LastFailure.ExitStatus, _ = strconv.Atoi(props["ExecMainStatus"])
+		if t, err := time.Parse(systemdTimestamp, props["ExecMainExitTimestamp"]); err == nil {
+			entry.LastFailure.Time = t.Format(time.RFC3339)
+		}
+	}
+
+	entry.Path, entry.UUID, entry.MountPoint = serviceTarget(ctx, service)
+	if owner, ok := owners[service]; ok {
+		entry.Path = owner
+	}
+	if entry.Path != "" && entry.UUID == "" {
+		tracked, _ := ctx.Tracker.GetEntry(entry.Path)
+		entry.UUID = tracked.UUID
+	}
+	return entry
+}
+
+// serviceTarget reads the VHD path, UUID and mount point a service mounts
+// from its unit files: the description and 'vhdm service monitor' command of
+// a boot mount service, or the mount unit of an automount
+func serviceTarget(ctx *AppContext, service string) (vhdPath, uuid, mountPoint string) {
+	settings := func(unit string) map[string]string {
+		content, err := os.ReadFile(unitFilePath(ctx, unit))
+		if err != nil {
+			return nil
+		}
+		values := map[string]string{}
+		editUnitSettings(string(content), func(key, value string) string {
+			values[key] = value
+			return value
+		})
+		return values
+	}
+
+	unit := settings(service)
+	if _, desc, ok := strings.Cut(unit["Description"], ": "); ok {
+		vhdPath = strings.ReplaceAll(desc, "%%", "%")
+	}
+	if strings.HasPrefix(service, automountPrefix) {
+		mount := settings(serviceUnits(service)[1])
+		uuid = strings.TrimPrefix(mount["What"], "/dev/disk/by-uuid/")
+		mountPoint = strings.ReplaceAll(mount["Where"], "%%", "%")
+		return vhdPath, uuid, mountPoint
+	}
+	words := splitUnitCommandLine(unit["ExecStart"])
+	for i := 0; i+1 < len(words); i++ {
+		switch words[i] {
+		case "--uuid":
+			uuid = words[i+1]
+		case "--mount-point":
+			mountPoint = words[i+1]
+		}
+	}
+	return vhdPath, uuid, mountPoint
+}
+
+func (e ServiceEntry) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{{"Service", e.Name}}
+	if e.Path != "" {
+		pairs = append(pairs, [2]string{"VHD", e.Path})
+	}
+	if e.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", e.UUID})
+	}
+	if e.MountPoint != "" {
+		pairs = append(pairs, [2]string{"Mount Point", e.MountPoint})
+	}
+	pairs = append(pairs,
+		[2]string{"Enabled", e.Enabled},
+		[2]string{"Active", e.Active},
+		[2]string{"Restarts", strconv.Itoa(e.Restarts)},
+	)
+	return "Service Status", pairs
+}
+
+func (e ServiceEntry) quiet() quietLine {
+	state := e.Active + "," + e.Enabled
+	if e.LastFailure != nil {
+		state += ",last failure " + e.LastFailure.Result
+	}
+	return quietLine{Key: e.Name, UUID: e.UUID, State: state}
 }
 
 func newServiceMonitorCmd() *cobra.Command {
@@ -533,6 +1012,7 @@ func newServiceMonitorCmd() *cobra.Command {
 		uuid     string
 		mountPoint string
 		interval int
+		mountOpts string
 	)
 
 	cmd := &cobra.Command{
@@ -548,33 +1028,39 @@ This command is used internally by systemd services to:
 
 This command should not be run manually - it's called by systemd services.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runServiceMonitor(uuid, mountPoint, interval)
+			return runServiceMonitor(appContext(cmd), uuid, mountPoint, interval, mountOpts)
 		},
 	}
 
 	cmd.Flags().StringVar(&uuid, "uuid", "", "VHD filesystem UUID (required)")
 	cmd.Flags().StringVar(&mountPoint, "mount-point", "", "Mount point path (required)")
 	cmd.Flags().IntVar(&interval, "interval", 30, "Health check interval in seconds")
+	cmd.Flags().StringVar(&mountOpts, "options", "", "Mount options")
 	cmd.MarkFlagRequired("uuid")
 	cmd.MarkFlagRequired("mount-point")
 
 	return cmd
 }
 
-func runServiceMonitor(uuid, mountPoint string, interval int) error {
-	ctx := getContext()
+func runServiceMonitor(ctx *AppContext, uuid, mountPoint string, interval int, mountOpts string) error {
 	log := ctx.Logger
 
 	// Validate inputs
 	if err := validation.ValidateUUID(uuid); err != nil {
 		return &types.VHDError{Op: "service monitor", Err: err}
 	}
+	mountPoint = expandMountPoint(ctx, mountPoint, "", uuid)
 	if err := validation.ValidateMountPoint(mountPoint); err != nil {
 		return &types.VHDError{Op: "service monitor", Err: err}
 	}
 	if interval < 1 {
 		return &types.VHDError{Op: "service monitor", Err: fmt.Errorf("health check interval must be at least 1 second")}
 	}
+	if mountOpts != "" {
+		if err := validation.ValidateMountOptions(mountOpts); err != nil {
+			return &types.VHDError{Op: "service monitor", Err: err}
+		}
+	}
 
 	log.Info("Starting VHD mount monitor")
 	log.Info("  UUID: %s", uuid)
@@ -583,26 +1069,42 @@ func runServiceMonitor(uuid, mountPoint string, interval int) error {
 
 	// First, mount the VHD
 	log.Info("Mounting VHD...")
-	if err := runMount("", uuid, "", mountPoint); err != nil {
-		return fmt.Errorf("failed to mount VHD: %w", err)
+	if err := runMount(ctx, "", uuid, "", mountPoint, mountOpts, false, false); err != nil {
+		err = fmt.Errorf("failed to mount VHD: %w", err)
+		notifyMonitorFailure(ctx, mountPoint, "boot mount of "+mountPoint+" failed", err)
+		return err
 	}
 
-	log.Info("✓ Mount successful")
+	log.Info("%s Mount successful", ctx.Theme.Success)
 	log.Info("Starting health check loop (every %d seconds)...", interval)
 
 	// Health check loop
 	for {
 		// Check if mount point is accessible
-		cmd := exec.Command("mountpoint", "-q", mountPoint)
-		if err := cmd.Run(); err != nil {
+		if _, err := ctx.Runner.CombinedOutput(context.Background(), "mountpoint", "-q", mountPoint); err != nil {
 			log.Error("Health check failed: mount point inaccessible")
 			log.Error("Mount point: %s", mountPoint)
-			return fmt.Errorf("mount point %s is no longer accessible - triggering systemd restart", mountPoint)
+			err := fmt.Errorf("mount point %s is no longer accessible - triggering systemd restart", mountPoint)
+			notifyMonitorFailure(ctx, mountPoint, "health check of "+mountPoint+" failed", err)
+			return err
 		}
 
 		log.Debug("Health check passed: mount point is accessible")
+		monitorRecovered(mountPoint)
 		
 		// Wait for configured interval before next check
 		time.Sleep(time.Duration(interval) * time.Second)
 	}
 }
+
+// systemctl runs systemctl through the context's runner and returns its
+// standard output and standard error together
+func systemctl(ctx *AppContext, args ...string) ([]byte, error) {
+	return ctx.Runner.CombinedOutput(context.Background(), "systemctl", args...)
+}
+
+// systemctlOutput runs a systemctl query through the context's runner and
+// returns its standard output
+func systemctlOutput(ctx *AppContext, args ...string) ([]byte, error) {
+	return ctx.Runner.Output(context.Background(), "systemctl", args...)
+}
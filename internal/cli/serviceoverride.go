@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+// overrideFileName is the drop-in written by 'vhdm service override'. It is
+// separate from the override.conf of 'systemctl edit', so both can be used.
+const overrideFileName = "vhdm-override.conf"
+
+func newServiceOverrideCmd() *cobra.Command {
+	var (
+		unit   string
+		set    []string
+		unset  []string
+		reset  bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "override",
+		Short: "Override settings of a service with a drop-in file",
+		Long: `Change settings of a unit created by 'vhdm service create' without editing it.
+
+The settings are written to a drop-in file, <unit>.d/vhdm-override.conf in the
+unit directory, which systemd applies on top of the unit. Recreating the
+service keeps them. Each --set KEY=VALUE replaces an earlier override of KEY,
+--unset KEY removes it and --reset removes the drop-in. Settings go to the
+[Unit] or [Install] section when they belong there, else to the section of
+the unit type ([Service], [Mount] or [Automount]).
+
+Without --set, --unset or --reset, the current overrides are printed. With
+--dry-run, the drop-in is printed as a diff against the current one instead of
+written.
+
+Note: Requires root privileges (sudo), except with --dry-run.`,
+		Example: `  vhdm service override --name vhdm-mount-data --set TimeoutStartSec=120
+  vhdm service override --name vhdm-mount-data --set RestartSec=30 --set After=docker.service
+  vhdm service override --name vhdm-mount-data --unset RestartSec
+  vhdm service override --name vhdm-mount-data --reset`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServiceOverride(appContext(cmd), unit, set, unset, reset, dryRun)
+		},
+	}
+
+	cmd.Flags().StringVar(&unit, "name", "", "Service name (required)")
+	cmd.Flags().StringArrayVar(&set, "set", nil, "Setting to override, as KEY=VALUE (repeatable)")
+	cmd.Flags().StringArrayVar(&unset, "unset", nil, "Overridden setting to remove (repeatable)")
+	cmd.Flags().BoolVar(&reset, "reset", false, "Remove all overrides")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the drop-in file (as a diff against the current one) instead of writing it")
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+func runServiceOverride(ctx *AppContext, unit string, set, unset []string, reset, dryRun bool) error {
+	log := ctx.Logger
+
+	// Ensure the unit name has a type, .service by default
+	if !slices.Contains([]string{".service", ".mount", ".automount"}, filepath.Ext(unit)) {
+		unit += ".service"
+	}
+	unitPath := unitFilePath(ctx, unit)
+	if _, err := os.Stat(unitPath); err != nil {
+		return &types.VHDError{
+			Op:   "service override",
+			Err:  fmt.Errorf("unit not found: %s", unit),
+			Help: "List the services with 'vhdm service list'",
+		}
+	}
+
+	dropInPath := filepath.Join(ctx.Config.UnitDir, unit+".d", overrideFileName)
+	current, err := os.ReadFile(dropInPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read drop-in file: %w", err)
+	}
+
+	// Show the current overrides
+	if !reset && len(set) == 0 && len(unset) == 0 {
+		if len(current) == 0 {
+			log.Info("No overrides for %s", unit)
+			return nil
+		}
+		fmt.Printf("# %s\n%s", dropInPath, current)
+		return nil
+	}
+
+	var d dropIn
+	if !reset {
+		d = parseDropIn(string(current))
+	}
+	for _, kv := range set {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !unitKeyRe.MatchString(key) || strings.ContainsAny(value, "\r\n") {
+			return &types.VHDError{
+				Op:  "service override",
+				Err: fmt.Errorf("%w: invalid setting %q (use KEY=VALUE, e.g. TimeoutStartSec=120)", types.ErrInvalidInput, kv),
+			}
+		}
+		d.set(overrideSection(unit, key), key, value)
+	}
+	for _, key := range unset {
+		if !d.unset(key) {
+			log.Warn("%s is not overridden", key)
+		}
+	}
+	content := d.String()
+
+	if dryRun {
+		if content == "" {
+			if len(current) > 0 {
+				fmt.Printf("# %s (removed)\n", dropInPath)
+			}
+			return nil
+		}
+		return previewUnitFile(ctx, dropInPath, content)
+	}
+
+	// System units require root privileges
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("overriding system services requires root privileges. Please run with sudo")
+	}
+
+	if content == "" {
+		if err := os.Remove(dropInPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove drop-in file: %w", err)
+		}
+		// Only removed when no other drop-in is left
+		os.Remove(filepath.Dir(dropInPath))
+	} else {
+		if err := os.MkdirAll(filepath.Dir(dropInPath), 0755); err != nil {
+			return fmt.Errorf("failed to create drop-in directory: %w", err)
+		}
+		if err := os.WriteFile(dropInPath, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write drop-in file: %w", err)
+		}
+	}
+
+	// Reload systemd daemon
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		log.Warn("Failed to reload systemd daemon: %v", err)
+	}
+
+	if content == "" {
+		log.Info("%s Overrides removed: %s", ctx.Theme.Success, unit)
+	} else {
+		log.Info("%s Overrides updated: %s", ctx.Theme.Success, unit)
+		log.Info("  Drop-in file: %s", dropInPath)
+	}
+	if output, _ := systemctlOutput(ctx, "is-active", unit); strings.TrimSpace(string(output)) == "active" {
+		log.Info("")
+		log.Info("Restart the unit to apply them now:")
+		log.Info("  sudo systemctl restart %s", unit)
+	}
+
+	return nil
+}
+
+// unitKeyRe matches systemd setting names
+var unitKeyRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// unitSectionKeys maps the settings of the [Unit] and [Install] sections to
+// their section
+var unitSectionKeys = map[string]string{
+	"Description": "Unit", "Documentation": "Unit", "Requires": "Unit", "Requisite": "Unit",
+	"Wants": "Unit", "BindsTo": "Unit", "PartOf": "Unit", "Upholds": "Unit",
+	"Conflicts": "Unit", "Before": "Unit", "After": "Unit", "OnFailure": "Unit",
+	"OnSuccess": "Unit", "RequiresMountsFor": "Unit", "WantsMountsFor": "Unit",
+	"StopWhenUnneeded": "Unit", "DefaultDependencies": "Unit", "JobTimeoutSec": "Unit",
+	"StartLimitIntervalSec": "Unit", "StartLimitBurst": "Unit", "StartLimitAction": "Unit",
+	"WantedBy": "Install", "RequiredBy": "Install", "UpheldBy": "Install",
+	"Alias": "Install", "Also": "Install", "DefaultInstance": "Install",
+}
+
+// overrideSection returns the section a setting of unit belongs to
+func overrideSection(unit, key string) string {
+	if section, ok := unitSectionKeys[key]; ok {
+		return section
+	}
+	if strings.HasPrefix(key, "Condition") || strings.HasPrefix(key, "Assert") {
+		return "Unit"
+	}
+	switch filepath.Ext(unit) {
+	case ".mount":
+		return "Mount"
+	case ".automount":
+		return "Automount"
+	default:
+		return "Service"
+	}
+}
+
+// dropIn is a systemd drop-in file, as sections of KEY=VALUE settings in
+// the order they were added
+type dropIn []dropInSection
+
+type dropInSection struct {
+	Name     string
+	Settings [][2]string
+}
+
+// parseDropIn parses a drop-in file written by dropIn.String
+func parseDropIn(content string) dropIn {
+	var d dropIn
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			d = append(d, dropInSection{Name: line[1 : len(line)-1]})
+		case len(d) > 0:
+			if key, value, ok := strings.Cut(line, "="); ok {
+				last := &d[len(d)-1]
+				last.Settings = append(last.Settings, [2]string{key, value})
+			}
+		}
+	}
+	return d
+}
+
+// set replaces the value of key in section, or adds it
+func (d *dropIn) set(section, key, value string) {
+	i := slices.IndexFunc(*d, func(s dropInSection) bool { return s.Name == section })
+	if i < 0 {
+		*d = append(*d, dropInSection{Name: section})
+		i = len(*d) - 1
+	}
+	s := &(*d)[i]
+	for j := range s.Settings {
+		if s.Settings[j][0] == key {
+			s.Settings[j][1] = value
+			return
+		}
+	}
+	s.Settings = append(s.Settings, [2]string{key, value})
+}
+
+// unset removes key from all sections, reporting whether it was set
+func (d *dropIn) unset(key string) bool {
+	found := false
+	for i := range *d {
+		s := &(*d)[i]
+		n := len(s.Settings)
+		s.Settings = slices.DeleteFunc(s.Settings, func(kv [2]string) bool { return kv[0] == key })
+		found = found || len(s.Settings) < n
+	}
+	return found
+}
+
+// String renders the drop-in file, empty when it has no settings
+func (d dropIn) String() string {
+	var b strings.Builder
+	for _, s := range d {
+		if len(s.Settings) == 0 {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("# Written by 'vhdm service override'\n")
+		}
+		fmt.Fprintf(&b, "\n[%s]\n", s.Name)
+		for _, kv := range s.Settings {
+			fmt.Fprintf(&b, "%s=%s\n", kv[0], kv[1])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,421 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Checkpoint a VHD and roll it back",
+		Long: `Take point-in-time copies of a tracked VHD and roll back to them.
+
+Snapshots are full copies of the VHD file, stored next to it as
+<name>@<snapshot>.vhdx (VHDX has no internal snapshots), and recorded in the
+tracking file. A mounted VHD is frozen with fsfreeze while it is copied, so
+the snapshot is crash-consistent without unmounting.
+
+Reverting replaces the VHD file with a copy of the snapshot, so the snapshot
+stays available. The VHD must be detached, and pinned VHDs are only reverted
+with --unpin.`,
+	}
+
+	cmd.AddCommand(
+		newSnapshotCreateCmd(),
+		newSnapshotListCmd(),
+		newSnapshotRevertCmd(),
+		newSnapshotDeleteCmd(),
+	)
+
+	return cmd
+}
+
+func newSnapshotCreateCmd() *cobra.Command {
+	var vhdPath, name string
+	var noFreeze bool
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Take a snapshot of a VHD",
+		Example: `  vhdm snapshot create --vhd-path C:/VMs/data.vhdx --name before-upgrade
+  vhdm snapshot create --vhd-path C:/VMs/data.vhdx`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshotCreate(appContext(cmd), vhdPath, name, noFreeze)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&name, "name", "", "Snapshot name (default: the current time, e.g. 20260102-150405)")
+	cmd.Flags().BoolVar(&noFreeze, "no-freeze", false, "Copy a mounted VHD without freezing it (the snapshot may be inconsistent)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func newSnapshotListCmd() *cobra.Command {
+	var vhdPath string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the snapshots of a VHD",
+		Example: `  vhdm snapshot list --vhd-path C:/VMs/data.vhdx
+  vhdm snapshot list --vhd-path C:/VMs/data.vhdx --output json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshotList(appContext(cmd), vhdPath)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func newSnapshotRevertCmd() *cobra.Command {
+	var vhdPath, name string
+	var unpin bool
+	cmd := &cobra.Command{
+		Use:     "revert",
+		Short:   "Roll a detached VHD back to a snapshot",
+		Example: `  vhdm snapshot revert --vhd-path C:/VMs/data.vhdx --name before-upgrade --yes`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshotRevert(appContext(cmd), vhdPath, name, unpin)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&name, "name", "", "Snapshot name")
+	cmd.Flags().BoolVar(&unpin, "unpin", false, "Remove the pin of a pinned VHD and revert it")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func newSnapshotDeleteCmd() *cobra.Command {
+	var vhdPath, name string
+	cmd := &cobra.Command{
+		Use:     "delete",
+		Short:   "Delete a snapshot of a VHD",
+		Example: `  vhdm snapshot delete --vhd-path C:/VMs/data.vhdx --name before-upgrade`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSnapshotDelete(appContext(cmd), vhdPath, name)
+		},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().StringVar(&name, "name", "", "Snapshot name")
+	cmd.MarkFlagRequired("vhd-path")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+// snapshotFile returns the path of the snapshot file of a VHD:
+// C:/VMs/data.vhdx, before -> C:/VMs/data@before.vhdx
+func snapshotFile(vhdPath, name string) string {
+	ext := path.Ext(vhdPath)
+	return strings.TrimSuffix(vhdPath, ext) + "@" + name + ext
+}
+
+// findSnapshot returns the snapshot of a tracked VHD with a name
+func findSnapshot(ctx *AppContext, op, vhdPath, name string) (types.TrackingEntry, types.Snapshot, error) {
+	entry, err := trackedEntry(ctx, op, vhdPath)
+	if err != nil {
+		return entry, types.Snapshot{}, err
+	}
+	for _, snap := range entry.Snapshots {
+		if snap.Name == name {
+			return entry, snap, nil
+		}
+	}
+	return entry, types.Snapshot{}, &types.VHDError{
+		Op:   op,
+		Path: vhdPath,
+		Err:  fmt.Errorf("no snapshot named %q", name),
+		Help: fmt.Sprintf("List the snapshots with 'vhdm snapshot list --vhd-path %s'", vhdPath),
+	}
+}
+
+func runSnapshotCreate(ctx *AppContext, vhdPath, name string, noFreeze bool) error {
+	log := ctx.Logger
+
+	if name == "" {
+		name = time.Now().Format("20060102-150405")
+	}
+	if err := validation.ValidateSnapshotName(name); err != nil {
+		return &types.VHDError{Op: "snapshot create", Err: err}
+	}
+	entry, err := trackedEntry(ctx, "snapshot create", vhdPath)
+	if err != nil {
+		return err
+	}
+	for _, snap := range entry.Snapshots {
+		if snap.Name == name {
+			return &types.VHDError{Op: "snapshot create", Path: vhdPath, Err: fmt.Errorf("snapshot %q already exists", name)}
+		}
+	}
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	if !ctx.WSL.FileExists(wslPath) {
+		return &types.VHDError{Op: "snapshot create", Path: vhdPath, Err: types.ErrVHDNotFound}
+	}
+	file := snapshotFile(vhdPath, name)
+	snapWSLPath := ctx.WSL.ConvertPath(file)
+	if ctx.WSL.FileExists(snapWSLPath) {
+		return &types.VHDError{Op: "snapshot create", Path: vhdPath, Err: fmt.Errorf("file already exists: %s", file)}
+	}
+
+	lock, err := lockVHDOperation(ctx, "snapshot", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	log.Debug("Snapshot operation starting")
+
+	mountPoint, err := copyVHDFile(ctx, vhdPath, snapWSLPath, noFreeze)
+	if err != nil {
+		return &types.VHDError{Op: "snapshot create", Path: vhdPath, Err: err}
+	}
+
+	size, _ := ctx.WSL.FileSize(snapWSLPath)
+	snap := types.Snapshot{
+		Name:    name,
+		File:    file,
+		Created: time.Now().Format(time.RFC3339),
+		UUID:    entry.UUID,
+		Size:    size,
+	}
+	err = ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+		entry.Snapshots = append(entry.Snapshots, snap)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record snapshot: %w", err)
+	}
+
+	// Output
+	log.Success("Snapshot %s created", name)
+	return printResult(ctx, SnapshotResult{
+		Path:   vhdPath,
+		Name:   name,
+		File:   file,
+		Size:   size,
+		Frozen: mountPoint != "" && !noFreeze,
+	})
+}
+
+// snapshotRow is one snapshot of the snapshot list
+type snapshotRow struct {
+	Name    string `json:"name"`
+	Created string `json:"created"`
+	Size    int64  `json:"size"`
+	File    string `json:"file"`
+	Missing bool   `json:"missing,omitempty"` // The snapshot file no longer exists
+}
+
+func runSnapshotList(ctx *AppContext, vhdPath string) error {
+	if err := checkListOutput(ctx, "snapshot list"); err != nil {
+		return err
+	}
+	entry, err := trackedEntry(ctx, "snapshot list", vhdPath)
+	if err != nil {
+		return err
+	}
+
+	rows := []snapshotRow{}
+	for _, snap := range entry.Snapshots {
+		rows = append(rows, snapshotRow{
+			Name:    snap.Name,
+			Created: displayTime(ctx, snap.Created),
+			Size:    snap.Size,
+			File:    snap.File,
+			Missing: !ctx.WSL.FileExists(ctx.WSL.ConvertPath(snap.File)),
+		})
+	}
+
+	switch {
+	case structuredOutput(ctx):
+		return printStructured(ctx, rows)
+	case ctx.Config.Quiet:
+		for _, row := range rows {
+			printQuiet(quietLine{Key: row.Name, State: row.File})
+		}
+	case len(rows) == 0:
+		ctx.Logger.Info("No snapshots of %s", vhdPath)
+	default:
+		printSnapshotTable(ctx, vhdPath, rows)
+	}
+	return nil
+}
+
+func printSnapshotTable(ctx *AppContext, vhdPath string, rows []snapshotRow) {
+	fmt.Println()
+	fmt.Printf("Snapshots of %s\n", vhdPath)
+	fmt.Println()
+
+	colWidths := []int{24, 25, 10, 50}
+	utils.PrintTableHeader(colWidths, []string{"Name", "Created", "Size", "File"})
+	for _, row := range rows {
+		file := row.File
+		if row.Missing {
+			file = ctx.Theme.Red("(missing) ") + file
+		}
+		utils.PrintTableRow(colWidths, row.Name, row.Created, utils.BytesToHuman(row.Size), file)
+	}
+	utils.PrintTableFooter(colWidths)
+}
+
+func runSnapshotRevert(ctx *AppContext, vhdPath, name string, unpin bool) error {
+	log := ctx.Logger
+
+	entry, snap, err := findSnapshot(ctx, "snapshot revert", vhdPath, name)
+	if err != nil {
+		return err
+	}
+	if err := ensureNotReference(ctx, "snapshot revert", vhdPath); err != nil {
+		return err
+	}
+	if err := checkPinned(ctx, "snapshot revert", vhdPath, unpin); err != nil {
+		return err
+	}
+
+	snapWSLPath := ctx.WSL.ConvertPath(snap.File)
+	if !ctx.WSL.FileExists(snapWSLPath) {
+		return &types.VHDError{Op: "snapshot revert", Path: vhdPath, Err: fmt.Errorf("snapshot file not found: %s", snap.File)}
+	}
+	if entry.UUID != "" {
+		if attached, _ := ctx.WSL.IsAttached(entry.UUID); attached {
+			return &types.VHDError{
+				Op:   "snapshot revert",
+				Path: vhdPath,
+				Err:  fmt.Errorf("VHD is attached"),
+				Help: fmt.Sprintf("Detach it first with 'vhdm detach --vhd-path %s'", vhdPath),
+			}
+		}
+	}
+
+	lock, err := lockVHDOperation(ctx, "snapshot", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := ensureNotInUseByWindows(ctx, "snapshot revert", vhdPath); err != nil {
+		return err
+	}
+
+	// Confirm
+	if !ctx.Config.Yes {
+		log.Warn("This will replace %s with snapshot %s, discarding all later changes", vhdPath, name)
+		log.Warn("Run with --yes to confirm")
+		return fmt.Errorf("operation cancelled")
+	}
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	if err := confirmByName(ctx, "snapshot revert", path.Base(vhdPath), vhdDiskSize(ctx, wslPath)); err != nil {
+		return err
+	}
+	releasePin(ctx, vhdPath)
+
+	// Copy next to the VHD first, so a failed copy leaves it intact
+	tmpPath := wslPath + ".reverting"
+	log.Info("Restoring snapshot %s (this may take a while)...", name)
+	if err := ctx.WSL.CopyFile(snapWSLPath, tmpPath); err != nil {
+		return &types.VHDError{Op: "snapshot revert", Path: vhdPath, Err: err}
+	}
+	if err := ctx.WSL.RenameFile(tmpPath, wslPath); err != nil {
+		ctx.WSL.DeleteVHD(tmpPath)
+		return &types.VHDError{Op: "snapshot revert", Path: vhdPath, Err: err}
+	}
+
+	// The filesystem is the one of the snapshot again
+	if snap.UUID != "" && snap.UUID != entry.UUID {
+		err := ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+			entry.UUID = snap.UUID
+		})
+		if err != nil {
+			log.Collect("Failed to save tracking info: %v", err)
+		}
+	}
+
+	// Output
+	log.Success("VHD reverted to snapshot %s", name)
+	return printResult(ctx, SnapshotRevertResult{Path: vhdPath, Name: name, UUID: snap.UUID})
+}
+
+func runSnapshotDelete(ctx *AppContext, vhdPath, name string) error {
+	_, snap, err := findSnapshot(ctx, "snapshot delete", vhdPath, name)
+	if err != nil {
+		return err
+	}
+
+	snapWSLPath := ctx.WSL.ConvertPath(snap.File)
+	if ctx.WSL.FileExists(snapWSLPath) {
+		if err := ctx.WSL.DeleteVHD(snapWSLPath); err != nil {
+			return &types.VHDError{Op: "snapshot delete", Path: vhdPath, Err: err}
+		}
+	}
+	err = ctx.Tracker.Update(vhdPath, func(entry *types.TrackingEntry) {
+		for i, s := range entry.Snapshots {
+			if s.Name == name {
+				entry.Snapshots = append(entry.Snapshots[:i], entry.Snapshots[i+1:]...)
+				break
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove snapshot from tracking: %w", err)
+	}
+
+	if ctx.Config.Quiet {
+		printQuiet(quietLine{Key: vhdPath, State: "snapshot " + name + " deleted"})
+		return nil
+	}
+	ctx.Logger.Success("Snapshot %s of %s deleted", name, vhdPath)
+	return nil
+}
+
+// SnapshotResult is the outcome of 'vhdm snapshot create'
+type SnapshotResult struct {
+	Path   string `json:"path"`
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Size   int64  `json:"size"`
+	Frozen bool   `json:"frozen"` // The mounted VHD was frozen during the copy
+}
+
+func (r SnapshotResult) table(utils.Theme) (string, [][2]string) {
+	return "Snapshot Result", [][2]string{
+		{"Path", r.Path},
+		{"Snapshot", r.Name},
+		{"File", r.File},
+		{"Size", utils.BytesToHuman(r.Size)},
+		{"Status", "created"},
+	}
+}
+
+func (r SnapshotResult) quiet() quietLine {
+	return quietLine{Key: r.Path, State: "snapshot " + r.Name + " created"}
+}
+
+// SnapshotRevertResult is the outcome of 'vhdm snapshot revert'
+type SnapshotRevertResult struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	UUID string `json:"uuid,omitempty"`
+}
+
+func (r SnapshotRevertResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{
+		{"Path", r.Path},
+		{"Snapshot", r.Name},
+	}
+	if r.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", r.UUID})
+	}
+	return "Revert Result", append(pairs, [2]string{"Status", "reverted"})
+}
+
+func (r SnapshotRevertResult) quiet() quietLine {
+	return quietLine{Key: r.Path, UUID: r.UUID, State: "reverted to snapshot " + r.Name}
+}
@@ -2,7 +2,10 @@ package cli
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
 
@@ -18,6 +21,8 @@ func newStatusCmd() *cobra.Command {
 		uuid       string
 		mountPoint string
 		showAll    bool
+		sortBy     string
+		format     string
 	)
 	cmd := &cobra.Command{
 		Use:   "status",
@@ -26,23 +31,37 @@ func newStatusCmd() *cobra.Command {
 
 Without flags, shows all disks and tracked VHDs.
 Use specific flags to query particular VHDs.
-VHDs that no longer exist are automatically removed from tracking.`,
+VHDs that no longer exist are automatically removed from tracking.
+
+Tracked VHDs are listed by path; --sort orders them by state, mount point or
+last seen time instead (ties keep path order).
+
+--format prints each tracked VHD with a Go template instead of the tables, using
+the fields of the JSON output by Go name: .Path, .UUID, .DeviceName,
+.MountPoint, .State, .LastSeen, .Note, ...`,
 		Example: `  vhdm status
   vhdm status --vhd-path C:/VMs/disk.vhdx
-  vhdm status --uuid 57fd0f3a-4077-44b8-91ba-5abdee575293`,
+  vhdm status --uuid 57fd0f3a-4077-44b8-91ba-5abdee575293
+  vhdm status --all --sort state
+  vhdm status --format '{{.UUID}} {{.MountPoint}}'`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runStatus(vhdPath, uuid, mountPoint, showAll)
+			ctx := appContext(cmd)
+			if err := setFormat(ctx, format); err != nil {
+				return err
+			}
+			return runStatus(ctx, vhdPath, uuid, mountPoint, showAll, sortBy)
 		},
 	}
 	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path")
-	cmd.Flags().StringVar(&uuid, "uuid", "", "VHD UUID")
+	cmd.Flags().StringVar(&uuid, "uuid", "", uuidFlagUsage)
 	cmd.Flags().StringVar(&mountPoint, "mount-point", "", "Mount point path")
 	cmd.Flags().BoolVar(&showAll, "all", false, "Show all tracked VHDs")
+	cmd.Flags().StringVar(&sortBy, "sort", "path", "Order of tracked VHDs: path, state, mount-point, last-seen")
+	addFormatFlag(cmd, &format)
 	return cmd
 }
 
-func runStatus(vhdPath, uuid, mountPoint string, showAll bool) error {
-	ctx := getContext()
+func runStatus(ctx *AppContext, vhdPath, uuid, mountPoint string, showAll bool, sortBy string) error {
 	log := ctx.Logger
 
 	// Default to --all if no flags
@@ -57,26 +76,45 @@ func runStatus(vhdPath, uuid, mountPoint string, showAll bool) error {
 		}
 	}
 	if uuid != "" {
-		if err := validation.ValidateUUID(uuid); err != nil {
-			return &types.VHDError{Op: "status", Err: err}
+		resolved, err := resolveUUID(ctx, "status", uuid)
+		if err != nil {
+			return err
+		}
+		uuid = resolved
+	}
+
+	if _, ok := vhdSortKeys[sortBy]; !ok {
+		return fmt.Errorf("invalid --sort: %s (must be path, state, mount-point or last-seen)", sortBy)
+	}
+
+	if showAll {
+		if err := checkListOutput(ctx, "status"); err != nil {
+			return err
 		}
 	}
 
 	log.Debug("Status operation starting")
 
 	if showAll {
-		return showAllStatus(ctx)
+		return showAllStatus(ctx, sortBy)
 	}
 
 	// Single VHD status
 	return showSingleStatus(ctx, vhdPath, uuid, mountPoint)
 }
 
-func showAllStatus(ctx *AppContext) error {
+func showAllStatus(ctx *AppContext, sortBy string) error {
+	// Check the files of all tracked VHDs at once
+	paths, err := ctx.Tracker.GetAllPaths()
+	if err != nil {
+		return fmt.Errorf("failed to get tracked VHDs: %w", err)
+	}
+	files := probeVHDFiles(ctx, paths)
+
 	// Auto-cleanup: remove tracked VHDs where file no longer exists
 	fileExists := func(path string) bool {
-		wslPath := ctx.WSL.ConvertPath(path)
-		return ctx.WSL.FileExists(wslPath)
+		f := files.get(ctx, path)
+		return f.exists || f.archived
 	}
 	removed, err := ctx.Tracker.CleanupNonExistent(fileExists)
 	if err != nil {
@@ -86,27 +124,56 @@ func showAllStatus(ctx *AppContext) error {
 		ctx.Logger.Debug("Removed non-existent VHD from tracking: %s", path)
 	}
 
-	// Get all disks first (including system disks)
-	allDisks, err := ctx.WSL.GetAllDisks()
-	if err != nil {
-		ctx.Logger.Debug("Failed to get disks: %v", err)
+	// Get all disks first (including system disks); every tracked VHD is
+	// evaluated against this one listing
+	allDisks, disksErr := ctx.WSL.GetAllDisks()
+	if disksErr != nil {
+		ctx.Logger.Debug("Failed to get disks: %v", disksErr)
 	}
+	sort.SliceStable(allDisks, func(i, j int) bool { return allDisks[i].Name < allDisks[j].Name })
 
 	// Auto-discover: track any formatted, mounted, non-system disks not already tracked
 	if err := autoDiscoverMountedVHDs(ctx, allDisks); err != nil {
 		ctx.Logger.Debug("Failed to auto-discover VHDs: %v", err)
 	}
 
-	// Get tracked VHDs
-	paths, err := ctx.Tracker.GetAllPaths()
+	// Get tracked VHDs, including the ones discovered
+	paths, err = ctx.Tracker.GetAllPaths()
 	if err != nil {
 		return fmt.Errorf("failed to get tracked VHDs: %w", err)
 	}
 
 	var vhds []types.VHDInfo
+	var leftovers []resizeLeftover
 	for _, path := range paths {
-		info := getVHDStatus(ctx, path)
-		vhds = append(vhds, info)
+		f := files.get(ctx, path)
+		vhds = append(vhds, vhdStatus(ctx, path, f, allDisks, disksErr))
+		// Warn about resize leftovers next to tracked VHDs
+		leftovers = append(leftovers, f.leftovers...)
+	}
+	sortVHDInfos(vhds, sortBy)
+
+	if structuredOutput(ctx) || ctx.Config.Format != "" {
+		var err error
+		switch {
+		case ctx.Config.Format != "":
+			err = printTemplate(ctx, nonNil(vhds))
+		case ctx.Config.Output == "csv":
+			// A CSV file holds one table: the tracked VHDs
+			err = printStructured(ctx, nonNil(vhds))
+		default:
+			err = printStructured(ctx, StatusReport{Disks: nonNil(allDisks), VHDs: nonNil(vhds)})
+		}
+		if err != nil {
+			return err
+		}
+		if len(leftovers) > 0 {
+			printResizeLeftovers(ctx, leftovers)
+		}
+		for _, vhd := range vhds {
+			warnImageCheck(ctx, vhd)
+		}
+		return nil
 	}
 
 	if ctx.Config.Quiet {
@@ -117,20 +184,19 @@ func showAllStatus(ctx *AppContext) error {
 			if mp == "" {
 				mp = "(not mounted)"
 			}
-			fmt.Printf("%s: %s at %s\n", disk.Name, disk.FSType, mp)
+			printQuiet(quietLine{Key: "/dev/" + disk.Name, UUID: disk.UUID, State: fmt.Sprintf("%s at %s", valueOrNone(disk.FSType), mp)})
 		}
 		// Print tracked VHDs
 		for _, vhd := range vhds {
-			status := strings.ToLower(string(vhd.State))
-			if vhd.UUID != "" {
-				fmt.Printf("%s (%s): %s\n", vhd.Path, vhd.UUID, status)
-			} else {
-				fmt.Printf("%s: %s\n", vhd.Path, status)
-			}
+			printQuiet(vhdQuietLine(vhd))
 		}
 		return nil
 	}
 
+	if ctx.Config.Profile != "" {
+		ctx.Logger.Info("Profile: %s", ctx.Config.Profile)
+	}
+
 	// Print all disks table
 	if len(allDisks) > 0 {
 		printAllDisksTable(allDisks)
@@ -138,13 +204,20 @@ func showAllStatus(ctx *AppContext) error {
 
 	// Print tracked VHDs table
 	if len(vhds) > 0 {
-		printStatusTable(vhds)
+		printStatusTable(ctx, vhds)
 	} else {
 		fmt.Println()
 		ctx.Logger.Info("No tracked VHDs found")
 		ctx.Logger.Info("Use 'vhdm attach' or 'vhdm mount' to attach a VHD")
 	}
 
+	if len(leftovers) > 0 {
+		printResizeLeftovers(ctx, leftovers)
+	}
+	for _, vhd := range vhds {
+		warnImageCheck(ctx, vhd)
+	}
+
 	// Get and print WSL distributions
 	distributions, err := ctx.WSL.GetWSLDistributions()
 	if err != nil {
@@ -156,6 +229,29 @@ func showAllStatus(ctx *AppContext) error {
 	return nil
 }
 
+// StatusReport is the structured output of 'vhdm status --all': every block
+// device and every tracked VHD
+type StatusReport struct {
+	Disks []wsl.BlockDevice `json:"disks"`
+	VHDs  []types.VHDInfo   `json:"vhds"`
+}
+
+// vhdSortKeys maps --sort values to the VHDInfo field they order by. Paths
+// come from the tracker already sorted, so a stable sort keeps them as the
+// tie-breaker.
+var vhdSortKeys = map[string]func(types.VHDInfo) string{
+	"path":        func(v types.VHDInfo) string { return "" },
+	"state":       func(v types.VHDInfo) string { return string(v.State) },
+	"mount-point": func(v types.VHDInfo) string { return v.MountPoint },
+	"last-seen":   func(v types.VHDInfo) string { return v.LastSeen },
+}
+
+// sortVHDInfos orders tracked VHDs by a --sort key
+func sortVHDInfos(vhds []types.VHDInfo, sortBy string) {
+	key := vhdSortKeys[sortBy]
+	sort.SliceStable(vhds, func(i, j int) bool { return key(vhds[i]) < key(vhds[j]) })
+}
+
 // autoDiscoverMountedVHDs automatically tracks formatted, mounted, non-system disks
 // that are not already in the tracking file
 func autoDiscoverMountedVHDs(ctx *AppContext, allDisks []wsl.BlockDevice) error {
@@ -235,21 +331,162 @@ func showSingleStatus(ctx *AppContext, vhdPath, uuid, mountPoint string) error {
 
 	info := getVHDStatus(ctx, vhdPath)
 
-	if ctx.Config.Quiet {
-		status := strings.ToLower(string(info.State))
-		if info.UUID != "" {
-			fmt.Printf("%s (%s): %s\n", info.Path, info.UUID, status)
-		} else {
-			fmt.Printf("%s: %s\n", info.Path, status)
+	switch {
+	case ctx.Config.Format != "":
+		if err := printTemplate(ctx, info); err != nil {
+			return err
+		}
+	case structuredOutput(ctx):
+		if err := printStructured(ctx, info); err != nil {
+			return err
+		}
+	case ctx.Config.Output == "sh":
+		for _, line := range shellAssignments(info) {
+			fmt.Println(line)
 		}
+	case ctx.Config.Quiet:
+		printQuiet(vhdQuietLine(info))
 		return nil
+	default:
+		printSingleStatus(ctx, info)
 	}
-
-	printSingleStatus(info)
+	if leftovers := findResizeLeftovers(ctx, vhdPath); len(leftovers) > 0 {
+		printResizeLeftovers(ctx, leftovers)
+	}
+	warnImageCheck(ctx, info)
+	printStatusFormatHint(ctx, info)
 	return nil
 }
 
+// resizeLeftover is a *_bkp or *_new file left next to a tracked VHD by resize
+type resizeLeftover struct {
+	Path     string // Leftover file (Windows format)
+	Original string // Tracked VHD it belongs to
+	Backup   bool   // true for *_bkp (the pre-resize original), false for *_new
+	Size     int64
+}
+
+// findResizeLeftovers returns the resize backup and staging files that exist
+// next to a tracked VHD or in the configured staging directory
+func findResizeLeftovers(ctx *AppContext, vhdPath string) []resizeLeftover {
+	if strings.HasPrefix(vhdPath, "unknown-") {
+		return nil
+	}
+
+	candidates := []resizeLeftover{
+		{Path: generateBackupPath(vhdPath), Original: vhdPath, Backup: true},
+		{Path: generateNewVHDPath(vhdPath, ""), Original: vhdPath},
+	}
+	if dir := ctx.Config.ResizeStagingDir; dir != "" {
+		candidates = append(candidates, resizeLeftover{Path: generateNewVHDPath(vhdPath, dir), Original: vhdPath})
+	}
+
+	var leftovers []resizeLeftover
+	for _, candidate := range candidates {
+		size, err := ctx.WSL.FileSize(ctx.WSL.ConvertPath(candidate.Path))
+		if err != nil {
+			continue
+		}
+		candidate.Size = size
+		leftovers = append(leftovers, candidate)
+	}
+	return leftovers
+}
+
+func printResizeLeftovers(ctx *AppContext, leftovers []resizeLeftover) {
+	var total int64
+	ctx.Logger.Info("")
+	for _, l := range leftovers {
+		total += l.Size
+		if j, _ := loadResizeJournal(ctx, l.Original); j != nil {
+			ctx.Logger.Warn("Interrupted resize of %s to %s left %s (%s)", l.Original, j.NewSize, l.Path, utils.BytesToHuman(l.Size))
+			ctx.Logger.Info("  Finish it with 'vhdm resize --vhd-path %s --resume', or roll it back with --abort", l.Original)
+		} else if l.Backup {
+			ctx.Logger.Warn("Resize backup %s (%s) shadows %s", l.Path, utils.BytesToHuman(l.Size), l.Original)
+			ctx.Logger.Info("  Verify the resized VHD mounts and its data is intact, then remove it: rm %q", ctx.WSL.ConvertPath(l.Path))
+		} else {
+			ctx.Logger.Warn("Unfinished resize copy %s (%s) next to %s", l.Path, utils.BytesToHuman(l.Size), l.Original)
+			ctx.Logger.Info("  Left by an interrupted resize; the original is unchanged. Remove it: rm %q", ctx.WSL.ConvertPath(l.Path))
+		}
+	}
+	if len(leftovers) > 1 {
+		ctx.Logger.Warn("Resize leftovers use %s in total", utils.BytesToHuman(total))
+	}
+}
+
+// vhdFiles is what status knows of the files of a tracked VHD
+type vhdFiles struct {
+	exists    bool
+	archived  bool             // Only the archive exists
+	leftovers []resizeLeftover // Only filled in by probeVHDFiles
+}
+
+// vhdFileProbe holds the files of tracked VHDs by normalized path
+type vhdFileProbe map[string]vhdFiles
+
+// statusProbeWorkers bounds the concurrent file checks of 'status --all'
+const statusProbeWorkers = 8
+
+// probeVHDFiles checks the files and resize leftovers of the tracked VHDs at
+// paths concurrently: every check goes through the Windows drive mount, which
+// adds up with many tracked VHDs
+func probeVHDFiles(ctx *AppContext, paths []string) vhdFileProbe {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		files = make(vhdFileProbe, len(paths))
+		slots = make(chan struct{}, statusProbeWorkers)
+	)
+	for _, path := range paths {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			f := checkVHDFiles(ctx, path)
+			f.leftovers = findResizeLeftovers(ctx, path)
+			mu.Lock()
+			files[utils.NormalizePath(path)] = f
+			mu.Unlock()
+		}(path)
+	}
+	wg.Wait()
+	return files
+}
+
+// get returns the files of the VHD at path, checking them now when it was
+// not probed, e.g. a VHD discovered since
+func (p vhdFileProbe) get(ctx *AppContext, path string) vhdFiles {
+	if f, ok := p[utils.NormalizePath(path)]; ok {
+		return f
+	}
+	f := checkVHDFiles(ctx, path)
+	f.leftovers = findResizeLeftovers(ctx, path)
+	return f
+}
+
+// checkVHDFiles checks whether the file of a VHD, or its archive, exists
+func checkVHDFiles(ctx *AppContext, path string) vhdFiles {
+	wslPath := ctx.WSL.ConvertPath(path)
+	if ctx.WSL.FileExists(wslPath) {
+		return vhdFiles{exists: true}
+	}
+	return vhdFiles{archived: ctx.WSL.FileExists(wslPath + wsl.ArchiveExt)}
+}
+
 func getVHDStatus(ctx *AppContext, path string) types.VHDInfo {
+	files := checkVHDFiles(ctx, path)
+	if !files.exists {
+		return vhdStatus(ctx, path, files, nil, nil)
+	}
+	devices, err := ctx.WSL.GetAllDisks()
+	return vhdStatus(ctx, path, files, devices, err)
+}
+
+// vhdStatus evaluates the state of the tracked VHD at path from its files and
+// a listing of the block devices (devErr is the error listing them)
+func vhdStatus(ctx *AppContext, path string, files vhdFiles, devices []wsl.BlockDevice, devErr error) types.VHDInfo {
 	info := types.VHDInfo{
 		Path:  path,
 		State: types.StateNotFound,
@@ -257,50 +494,182 @@ func getVHDStatus(ctx *AppContext, path string) types.VHDInfo {
 
 	// Get from tracking
 	entry, err := ctx.Tracker.GetEntry(path)
-	if err == nil {
+	tracked := err == nil
+	if tracked {
 		info.UUID = entry.UUID
+		info.Label = entry.Label
+		info.PartUUID = entry.PartUUID
 		info.DeviceName = entry.DeviceName
 		info.MountPoint = strings.Join(entry.MountPoints, ",")
 		info.LastSeen = entry.LastSeen
+		info.Note = entry.Note
+		info.Pinned = entry.Pinned
+		info.ImageCheck = entry.ImageCheck
+		info.FSType = entry.FSType
+		info.External = entry.External
+		info.BackingFile = entry.BackingFile
+		info.Service = entry.Service
 	}
 
 	// Check VHD file exists
-	wslPath := ctx.WSL.ConvertPath(path)
-	if !ctx.WSL.FileExists(wslPath) {
+	if !files.exists {
 		info.State = types.StateNotFound
+		if files.archived {
+			info.State = types.StateArchived
+		}
 		return info
 	}
 
 	// Check if attached
 	if info.UUID != "" {
-		attached, _ := ctx.WSL.IsAttached(info.UUID)
-		if attached {
+		dev := deviceByUUID(devices, info.UUID)
+		if tracked && devErr == nil {
+			var systemMPs []string
+			if dev != nil {
+				systemMPs = filterEmptyMountPoints(dev.MountPoints)
+			}
+			if reconcileMountPoints(ctx, path, entry, dev != nil, systemMPs) {
+				info.External = true
+			}
+			if dev != nil {
+				recordDeviceIdentifiers(ctx, path, *dev)
+			}
+		}
+		if dev != nil {
 			info.State = types.StateAttachedFormatted
 
-			// Get full disk info (mount points, available space, usage)
-			diskInfo, _ := ctx.WSL.GetVHDInfo(info.UUID)
-			if diskInfo != nil {
-				if diskInfo.MountPoint != "" {
-					info.State = types.StateMounted
-					info.MountPoint = diskInfo.MountPoint
-				}
-				if diskInfo.DeviceName != "" {
-					info.DeviceName = diskInfo.DeviceName
-				}
-				info.FSAvail = diskInfo.FSAvail
-				info.FSUse = diskInfo.FSUse
+			// Mount points, available space and usage
+			if mps := filterEmptyMountPoints(dev.MountPoints); len(mps) > 0 {
+				info.State = types.StateMounted
+				info.MountPoint = mps[0]
 			}
+			info.DeviceName = dev.Name
+			info.FSAvail = dev.FSAvail
+			info.FSUse = dev.FSUseP
 		} else {
 			info.State = types.StateDetached
 		}
+	} else if dev, stale := attachedUnformatted(ctx, path, devices, info.DeviceName); dev != nil {
+		// No UUID: attached without a filesystem while the device recorded
+		// at attach is still there without one
+		info.State = types.StateAttachedUnformatted
+		info.DeviceName = dev.Name
 	} else {
-		// No UUID - might be unformatted or detached
 		info.State = types.StateDetached
+		if stale && tracked {
+			clearStaleDevice(ctx, path, info.DeviceName)
+			info.DeviceName = ""
+		}
 	}
 
 	return info
 }
 
+// unformattedDevice returns the dynamically attached device named devName in
+// a listing when it has no filesystem, or nil
+func unformattedDevice(devices []wsl.BlockDevice, devName string) *wsl.BlockDevice {
+	if devName == "" || !wsl.IsDynamicDevice(devName) {
+		return nil
+	}
+	for i := range devices {
+		if devices[i].Name == devName {
+			if devices[i].UUID != "" || devices[i].FSType != "" {
+				return nil
+			}
+			return &devices[i]
+		}
+	}
+	return nil
+}
+
+// attachedUnformatted returns the device of a VHD without a filesystem UUID
+// when it is still attached: the device recorded at attach must be there
+// without a filesystem, and Windows must still hold the VHD file open. A VHD
+// detached outside vhdm leaves its device name behind, which may have gone to
+// another VHD since; stale reports that case.
+func attachedUnformatted(ctx *AppContext, path string, devices []wsl.BlockDevice, devName string) (dev *wsl.BlockDevice, stale bool) {
+	dev = unformattedDevice(devices, devName)
+	if dev == nil {
+		return nil, false
+	}
+	if inUse, err := ctx.WSL.FileInUseByWindows(path); err == nil && !inUse {
+		return nil, true
+	}
+	return dev, false
+}
+
+// clearStaleDevice forgets the device recorded for a VHD that is no longer
+// attached, unless it was attached again meanwhile
+func clearStaleDevice(ctx *AppContext, path, devName string) {
+	err := ctx.Tracker.Update(path, func(entry *types.TrackingEntry) {
+		if entry.UUID == "" && entry.DeviceName == devName {
+			entry.DeviceName = ""
+		}
+	})
+	if err != nil {
+		ctx.Logger.Debug("Failed to clear the device of %s: %v", path, err)
+	}
+}
+
+// printStatusFormatHint tells how to format a VHD that is attached without a
+// filesystem
+func printStatusFormatHint(ctx *AppContext, info types.VHDInfo) {
+	if info.State != types.StateAttachedUnformatted {
+		return
+	}
+	ctx.Logger.Info("")
+	ctx.Logger.Info("The VHD has no filesystem. To format it, run:")
+	ctx.Logger.Info("  vhdm format --dev-name %s --type ext4", info.DeviceName)
+}
+
+// vhdQuietLine returns the quiet line of a tracked VHD
+func vhdQuietLine(vhd types.VHDInfo) quietLine {
+	return quietLine{Key: vhd.Path, UUID: vhd.UUID, State: strings.ToLower(string(vhd.State))}
+}
+
+// deviceByUUID returns the device with a filesystem UUID in a listing, or nil
+func deviceByUUID(devices []wsl.BlockDevice, uuid string) *wsl.BlockDevice {
+	for i := range devices {
+		if devices[i].UUID == uuid {
+			return &devices[i]
+		}
+	}
+	return nil
+}
+
+// reconcileMountPoints updates the tracked mount points of a VHD when they no
+// longer match the system, i.e. it was mounted, unmounted or detached by hand,
+// and flags the entry as externally managed. Returns true when it did.
+func reconcileMountPoints(ctx *AppContext, path string, entry types.TrackingEntry, attached bool, systemMPs []string) bool {
+	if sameMountPoints(entry.MountPoints, systemMPs) && (attached || entry.DeviceName == "") {
+		return false
+	}
+
+	ctx.Logger.Info("%s changed outside vhdm: mount points %s -> %s", path,
+		valueOrNone(strings.Join(entry.MountPoints, ",")), valueOrNone(strings.Join(systemMPs, ",")))
+	err := ctx.Tracker.Update(path, func(e *types.TrackingEntry) {
+		e.MountPoints = systemMPs
+		if len(systemMPs) > 0 {
+			e.LastMount = systemMPs[0]
+		}
+		if !attached {
+			e.DeviceName = ""
+		}
+		e.External = true
+	})
+	if err != nil {
+		ctx.Logger.Debug("Failed to update tracking of %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+// sameMountPoints reports whether two mount point lists hold the same points,
+// in any order
+func sameMountPoints(a, b []string) bool {
+	return slices.Equal(slices.Sorted(slices.Values(a)), slices.Sorted(slices.Values(b)))
+}
+
 // filterEmptyMountPoints removes empty strings from mount points
 func filterEmptyMountPoints(mps []string) []string {
 	var result []string
@@ -373,17 +742,18 @@ func printAllDisksTable(disks []wsl.BlockDevice) {
 	utils.PrintTableFooter(colWidths)
 }
 
-func printStatusTable(vhds []types.VHDInfo) {
+func printStatusTable(ctx *AppContext, vhds []types.VHDInfo) {
 	fmt.Println()
 	fmt.Println("Tracked VHD Disks")
 	fmt.Println()
 
 	// Calculate column widths
-	colWidths := []int{40, 36, 8, 20, 12, 20}
+	colWidths := []int{40, 36, 8, 20, 12, 25}
 	headers := []string{"Path", "UUID", "Device", "Mount Point", "Status", "Last Seen"}
 
 	utils.PrintTableHeader(colWidths, headers)
 
+	external := false
 	for _, vhd := range vhds {
 		uuid := vhd.UUID
 		if uuid == "" {
@@ -397,18 +767,28 @@ func printStatusTable(vhds []types.VHDInfo) {
 		if mp == "" {
 			mp = "-"
 		}
-		// Format LastSeen timestamp (truncate to datetime)
-		lastSeen := vhd.LastSeen
-		if len(lastSeen) > 19 {
-			lastSeen = lastSeen[:19]
+		if vhd.External {
+			mp += " *"
+			external = true
 		}
+		lastSeen := displayTime(ctx, vhd.LastSeen)
 		if lastSeen == "" {
 			lastSeen = "-"
 		}
-		utils.PrintTableRow(colWidths, vhd.Path, uuid, dev, mp, colorizeStatus(string(vhd.State)), lastSeen)
+		utils.PrintTableRow(colWidths, vhd.Path, uuid, dev, mp, colorizeStatus(ctx.Theme, string(vhd.State)), lastSeen)
 	}
 
 	utils.PrintTableFooter(colWidths)
+	if external {
+		fmt.Println("  * mounted or unmounted outside vhdm ('vhdm refresh' or a vhdm mount/umount clears this)")
+	}
+
+	// Notes are free text, listed below the table rather than truncated in it
+	for _, vhd := range vhds {
+		if vhd.Note != "" {
+			fmt.Printf("  %s: %s\n", vhd.Path, vhd.Note)
+		}
+	}
 }
 
 func printWSLDistributionsTable(dists []wsl.WSLDistribution) {
@@ -441,7 +821,7 @@ func printWSLDistributionsTable(dists []wsl.WSLDistribution) {
 	utils.PrintTableFooter(colWidths)
 }
 
-func printSingleStatus(info types.VHDInfo) {
+func printSingleStatus(ctx *AppContext, info types.VHDInfo) {
 	// Helper to show "-" for empty values
 	valOrDash := func(s string) string {
 		if s == "" {
@@ -455,36 +835,70 @@ func printSingleStatus(info types.VHDInfo) {
 		device = "/dev/" + info.DeviceName
 	}
 
-	// Format LastSeen timestamp
-	lastSeen := info.LastSeen
-	if len(lastSeen) > 19 {
-		lastSeen = lastSeen[:19]
-	}
+	lastSeen := displayTime(ctx, info.LastSeen)
 
 	pairs := [][2]string{
 		{"Path", info.Path},
 		{"UUID", valOrDash(info.UUID)},
 		{"Device", device},
 		{"Mount Point", valOrDash(info.MountPoint)},
+		{"Filesystem", valOrDash(info.FSType)},
 		{"Available", valOrDash(info.FSAvail)},
 		{"Usage", valOrDash(info.FSUse)},
 		{"Last Seen", valOrDash(lastSeen)},
-		{"Status", colorizeStatus(string(info.State))},
+		{"Status", colorizeStatus(ctx.Theme, string(info.State))},
+	}
+	if info.Label != "" {
+		pairs = append(pairs, [2]string{"Label", info.Label})
+	}
+	if info.PartUUID != "" {
+		pairs = append(pairs, [2]string{"PARTUUID", info.PartUUID})
+	}
+	if info.Note != "" {
+		pairs = append(pairs, [2]string{"Note", info.Note})
+	}
+	if info.Pinned {
+		pairs = append(pairs, [2]string{"Pinned", "yes (see 'vhdm unpin')"})
+	}
+	if info.BackingFile != "" {
+		pairs = append(pairs, [2]string{"Linked Clone", "of " + info.BackingFile})
+	}
+	if info.Service != "" {
+		pairs = append(pairs, [2]string{"Service", info.Service})
+	}
+	if info.External {
+		pairs = append(pairs, [2]string{"Managed", "externally (mounted or unmounted outside vhdm)"})
+	}
+	if check := info.ImageCheck; check != nil {
+		result := check.Problem()
+		if result == "" {
+			result = "ok"
+		}
+		pairs = append(pairs, [2]string{"Image Check", fmt.Sprintf("%s (%s)", result, displayTime(ctx, check.Time))})
 	}
 
 	utils.KeyValueTable("VHD Status", pairs, 14, 50)
 }
 
-func colorizeStatus(status string) string {
+// warnImageCheck warns when the last 'vhdm check-image' of a VHD found damage
+func warnImageCheck(ctx *AppContext, info types.VHDInfo) {
+	if info.ImageCheck == nil || !info.ImageCheck.Damaged() {
+		return
+	}
+	ctx.Logger.Warn("Image check of %s found %s (%s); back up its data and repair it with 'qemu-img check -r all'",
+		info.Path, info.ImageCheck.Problem(), displayTime(ctx, info.ImageCheck.Time))
+}
+
+func colorizeStatus(t utils.Theme, status string) string {
 	switch types.VHDState(status) {
 	case types.StateMounted:
-		return utils.Green(status)
+		return t.Green(status)
 	case types.StateAttachedFormatted, types.StateAttachedUnformatted:
-		return utils.Yellow(status)
-	case types.StateDetached:
-		return utils.Blue(status)
+		return t.Yellow(status)
+	case types.StateDetached, types.StateArchived:
+		return t.Blue(status)
 	case types.StateNotFound:
-		return utils.Red(status)
+		return t.Red(status)
 	default:
 		return status
 	}
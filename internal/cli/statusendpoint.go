@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/wsl"
+)
+
+// StatusSnapshot is the document served by the status endpoint
+type StatusSnapshot struct {
+	Time string          `json:"time"`
+	VHDs []types.VHDInfo `json:"vhds"`
+}
+
+// validateStatusAddr checks that a status endpoint address is host:port on a
+// loopback address. WSL forwards localhost ports to Windows, so a tray app
+// reaches it at the same address without the endpoint being on the network.
+func validateStatusAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid status address %q (use host:port, e.g. 127.0.0.1:7380)", addr)
+	}
+	if port == "" {
+		return fmt.Errorf("invalid status address %q: missing port", addr)
+	}
+	if !isLoopbackHost(host) {
+		return fmt.Errorf("status address %q is not a loopback address (use 127.0.0.1 or localhost)", addr)
+	}
+	return nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// startStatusEndpoint serves GET /status on addr until the process exits. The
+// listener is opened before returning, so address errors surface at startup.
+func startStatusEndpoint(ctx *AppContext, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to open status endpoint: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		serveStatus(ctx, w, r)
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			ctx.Logger.Warn("Status endpoint stopped: %v", err)
+		}
+	}()
+	ctx.Logger.Info("  Status Endpoint: http://%s/status", ln.Addr())
+	return nil
+}
+
+func serveStatus(ctx *AppContext, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Pages on other sites could reach a loopback port through DNS rebinding;
+	// they cannot send a loopback Host header
+	if host, _, err := net.SplitHostPort(r.Host); err != nil || !isLoopbackHost(host) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	vhds, err := statusSnapshot(ctx)
+	if err != nil {
+		ctx.Logger.Debug("Status endpoint: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(StatusSnapshot{Time: time.Now().Format(time.RFC3339), VHDs: vhds})
+}
+
+// statusSnapshot returns the state of every tracked VHD. Unlike 'vhdm
+// status' it never writes tracking, and reads the block devices once.
+func statusSnapshot(ctx *AppContext) ([]types.VHDInfo, error) {
+	paths, err := ctx.Tracker.GetAllPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked VHDs: %w", err)
+	}
+	devices, err := ctx.WSL.GetBlockDevicesWithInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list block devices: %w", err)
+	}
+	byUUID := make(map[string]wsl.BlockDevice, len(devices))
+	for _, dev := range devices {
+		if dev.UUID != "" {
+			byUUID[dev.UUID] = dev
+		}
+	}
+
+	vhds := make([]types.VHDInfo, 0, len(paths))
+	for _, path := range paths {
+		entry, _ := ctx.Tracker.GetEntry(path)
+		info := types.VHDInfo{
+			Path:     path,
+			UUID:     entry.UUID,
+			Label:    entry.Label,
+			PartUUID: entry.PartUUID,
+			LastSeen: entry.LastSeen,
+			Note:     entry.Note,
+			Pinned:   entry.Pinned,
+			FSType:   entry.FSType,
+			External: entry.External,
+			State:    types.StateDetached,
+		}
+
+		wslPath := ctx.WSL.ConvertPath(path)
+		var unformatted *wsl.BlockDevice
+		if entry.UUID == "" {
+			unformatted, _ = attachedUnformatted(ctx, path, devices, entry.DeviceName)
+		}
+		switch dev, attached := byUUID[entry.UUID]; {
+		case entry.UUID != "" && attached:
+			info.State = types.StateAttachedFormatted
+			info.DeviceName = dev.Name
+			info.FSAvail = dev.FSAvail
+			info.FSUse = dev.FSUseP
+			if mps := filterEmptyMountPoints(dev.MountPoints); len(mps) > 0 {
+				info.State = types.StateMounted
+				info.MountPoint = mps[0]
+			}
+		case unformatted != nil:
+			info.State = types.StateAttachedUnformatted
+			info.DeviceName = entry.DeviceName
+		case entry.Archived || ctx.WSL.FileExists(wslPath+wsl.ArchiveExt):
+			info.State = types.StateArchived
+		case !ctx.WSL.FileExists(wslPath):
+			info.State = types.StateNotFound
+		}
+		vhds = append(vhds, info)
+	}
+	return vhds, nil
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+func TestValidateStatusAddr(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		"127.0.0.1:7380": true,
+		"localhost:7380": true,
+		"[::1]:7380":     true,
+		"0.0.0.0:7380":   false,
+		"192.168.1.5:80": false,
+		"127.0.0.1":      false,
+		"127.0.0.1:":     false,
+	} {
+		if err := validateStatusAddr(addr); (err == nil) != ok {
+			t.Errorf("validateStatusAddr(%q) error = %v, want ok %v", addr, err, ok)
+		}
+	}
+}
+
+func TestServeStatus(t *testing.T) {
+	ctx, fake := newTestContext(t)
+	disk := fake.AddVHD("C:/VMs/data.vhdx", 1<<30)
+	disk.Device = "sdd"
+	disk.UUID = "33333333-3333-4333-8333-333333333333"
+	disk.MountPoints = []string{"/mnt/data"}
+	fake.AddVHD("C:/VMs/spare.vhdx", 1<<30)
+	ctx.Tracker.SaveMapping("C:/VMs/data.vhdx", disk.UUID, "/mnt/data", "sdd")
+	ctx.Tracker.SaveMapping("C:/VMs/spare.vhdx", "44444444-4444-4444-8444-444444444444", "", "")
+	ctx.Tracker.SaveMapping("C:/VMs/gone.vhdx", "55555555-5555-4555-8555-555555555555", "", "")
+
+	rec := httptest.NewRecorder()
+	serveStatus(ctx, rec, httptest.NewRequest(http.MethodGet, "http://127.0.0.1:7380/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code = %d, body %s", rec.Code, rec.Body)
+	}
+	var snap StatusSnapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &snap); err != nil {
+		t.Fatal(err)
+	}
+	states := make(map[string]types.VHDState)
+	for _, v := range snap.VHDs {
+		states[v.Path] = v.State
+	}
+	want := map[string]types.VHDState{
+		"C:/VMs/data.vhdx":  types.StateMounted,
+		"C:/VMs/spare.vhdx": types.StateDetached,
+		"C:/VMs/gone.vhdx":  types.StateNotFound,
+	}
+	for path, state := range want {
+		if states[path] != state {
+			t.Errorf("state of %s = %q, want %q", path, states[path], state)
+		}
+	}
+	if len(fake.Calls) != 0 {
+		t.Errorf("status changed the system: %v", fake.Calls)
+	}
+
+	rec = httptest.NewRecorder()
+	serveStatus(ctx, rec, httptest.NewRequest(http.MethodGet, "http://evil.example:7380/status", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("non-loopback Host: status code = %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	serveStatus(ctx, rec, httptest.NewRequest(http.MethodPost, "http://127.0.0.1:7380/status", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status code = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+// tempMount describes a VHD made available at a mount point for a one-off
+// operation. Only the steps performed by acquireTempMount are undone on release,
+// so a VHD that was already mounted by the user stays mounted.
+type tempMount struct {
+	VHDPath    string
+	UUID       string
+	DeviceName string
+	MountPoint string
+
+	attachedByUs bool
+	mountedByUs  bool
+	tmpDir       string
+}
+
+// acquireTempMount ensures the VHD is attached and mounted. If it is not mounted,
+// it is mounted to a temporary directory (read-only when readOnly is set).
+func acquireTempMount(ctx *AppContext, vhdPath string, readOnly bool) (*tempMount, error) {
+	options := ""
+	if readOnly {
+		options = "ro"
+	}
+	return acquireMount(ctx, vhdPath, "", options)
+}
+
+// acquireMount ensures the VHD is attached and mounted at mountPoint, or at a
+// temporary directory when mountPoint is empty. An existing mount is reused, but
+// it is an error if the VHD is mounted somewhere other than the requested mountPoint.
+func acquireMount(ctx *AppContext, vhdPath, mountPoint, options string) (*tempMount, error) {
+	log := ctx.Logger
+	m := &tempMount{VHDPath: vhdPath}
+
+	if err := ensureNotReference(ctx, "mount", vhdPath); err != nil {
+		return nil, err
+	}
+
+	wslPath := ctx.WSL.ConvertPath(vhdPath)
+	if !ctx.WSL.FileExists(wslPath) {
+		return nil, types.ErrVHDNotFound
+	}
+
+	// Check tracking and verify the tracked UUID is attached
+	uuid, _ := ctx.Tracker.LookupUUIDByPath(vhdPath)
+	attached := false
+	if uuid != "" {
+		attached, _ = ctx.WSL.IsAttached(uuid)
+	}
+
+	if !attached {
+		log.Debug("Attaching %s for temporary use", vhdPath)
+		if uuid == "" {
+			devName, attachedNow, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
+			if err != nil {
+				if types.IsAlreadyAttached(err) {
+					return nil, fmt.Errorf("VHD is already attached but not tracked - track it with 'vhdm adopt --dev-name <device> --vhd-path %s' first", vhdPath)
+				}
+				if !attachedNow {
+					return nil, fmt.Errorf("failed to attach: %w", err)
+				}
+				m.attachedByUs = true
+				m.release(ctx)
+				return nil, fmt.Errorf("failed to detect device: %w", err)
+			}
+			m.attachedByUs = true
+			m.DeviceName = devName
+			uuid, _ = ctx.WSL.GetUUIDByDevice(devName)
+			if uuid == "" {
+				m.release(ctx)
+				return nil, types.ErrVHDNotFormatted
+			}
+			if err := ctx.Tracker.SaveMapping(vhdPath, uuid, "", devName); err != nil {
+				log.Collect("Failed to save tracking info: %v", err)
+			}
+		} else if _, err := ctx.WSL.AttachVHD(vhdPath); err != nil {
+			if !types.IsAlreadyAttached(err) {
+				return nil, fmt.Errorf("failed to attach: %w", err)
+			}
+		} else {
+			m.attachedByUs = true
+		}
+	}
+	m.UUID = uuid
+
+	if m.DeviceName == "" {
+		m.DeviceName, _ = ctx.WSL.GetDeviceByUUID(uuid)
+	}
+
+	// Reuse an existing mount
+	if mp, _ := ctx.WSL.GetMountPoint(uuid); mp != "" {
+		if mountPoint != "" && mp != mountPoint {
+			m.release(ctx)
+			return nil, fmt.Errorf("VHD is already mounted at %s", mp)
+		}
+		log.Debug("VHD already mounted at %s", mp)
+		m.MountPoint = mp
+		return m, nil
+	}
+
+	if mountPoint == "" {
+		tmpDir, err := os.MkdirTemp("", "vhdm-mnt-")
+		if err != nil {
+			m.release(ctx)
+			return nil, fmt.Errorf("failed to create temp mount point: %w", err)
+		}
+		m.tmpDir = tmpDir
+		mountPoint = tmpDir
+	}
+
+	if err := ctx.WSL.MountByUUIDWithOptions(uuid, mountPoint, options); err != nil {
+		m.release(ctx)
+		return nil, fmt.Errorf("failed to mount: %w", err)
+	}
+	m.mountedByUs = true
+	m.MountPoint = mountPoint
+	log.Debug("Mounted %s at %s", vhdPath, mountPoint)
+
+	return m, nil
+}
+
+// release undoes whatever acquireMount did: unmounts, detaches and removes
+// the temporary directory. Failures are logged as warnings.
+func (m *tempMount) release(ctx *AppContext) {
+	log := ctx.Logger
+
+	if m.mountedByUs {
+		if err := ctx.WSL.Unmount(m.MountPoint); err != nil {
+			log.Warn("Failed to unmount %s: %v", m.MountPoint, err)
+		}
+		m.mountedByUs = false
+	}
+	if m.tmpDir != "" {
+		os.Remove(m.tmpDir)
+		m.tmpDir = ""
+	}
+	if m.attachedByUs {
+		if err := ctx.WSL.DetachVHD(m.VHDPath); err != nil {
+			log.Warn("Failed to detach %s: %v", m.VHDPath, err)
+		} else {
+			markDetached(ctx, m.VHDPath)
+		}
+		m.attachedByUs = false
+	}
+}
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newTopCmd() *cobra.Command {
+	var (
+		interval   int
+		iterations int
+	)
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Show live I/O activity of tracked VHDs",
+		Long: `Show a live view of the tracked VHDs with their state, mount point, space
+usage and I/O rates, refreshed every interval. VHDs are ordered by throughput,
+so the disk a runaway build is hammering comes first.
+
+Rates are computed from the block device I/O counters between two refreshes,
+so the first screen shows none. Press Ctrl+C to quit, or use --iterations to
+stop after a number of refreshes (e.g. to capture a sample in a script).`,
+		Example: `  vhdm top
+  vhdm top --interval 5
+  vhdm top --iterations 3`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTop(appContext(cmd), interval, iterations)
+		},
+	}
+	cmd.Flags().IntVar(&interval, "interval", 1, "Seconds between refreshes")
+	cmd.Flags().IntVarP(&iterations, "iterations", "n", 0, "Stop after this many refreshes (0: run until interrupted)")
+	return cmd
+}
+
+// topRow is one VHD of the top view
+type topRow struct {
+	Path       string
+	State      string
+	MountPoint string
+	Use        string
+	Avail      string
+	ReadRate   float64 // bytes per second, -1 when unknown
+	WriteRate  float64
+	IOPS       float64
+}
+
+// topSample is the last I/O counter sample of an attached VHD
+type topSample struct {
+	devName string
+	stats   wsl.IOStats
+	time    time.Time
+}
+
+func runTop(ctx *AppContext, interval, iterations int) error {
+	if interval < 1 {
+		return &types.VHDError{Op: "top", Err: fmt.Errorf("interval must be at least 1 second")}
+	}
+	if iterations < 0 {
+		return &types.VHDError{Op: "top", Err: fmt.Errorf("iterations must not be negative")}
+	}
+
+	clearScreen := stdoutIsTerminal() && !ctx.Config.Quiet
+	samples := make(map[string]topSample)
+	for i := 1; ; i++ {
+		rows, err := collectTopRows(ctx, samples)
+		if err != nil {
+			return err
+		}
+
+		if ctx.Config.Quiet {
+			printTopQuiet(rows)
+		} else {
+			if clearScreen {
+				fmt.Print("\033[H\033[2J")
+			}
+			printTopTable(ctx, rows, interval)
+		}
+
+		if iterations > 0 && i >= iterations {
+			return nil
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+	}
+}
+
+// collectTopRows returns the rows of all tracked VHDs, busiest first, and
+// updates samples with their current I/O counters
+func collectTopRows(ctx *AppContext, samples map[string]topSample) ([]topRow, error) {
+	paths, err := ctx.Tracker.GetAllPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked VHDs: %w", err)
+	}
+	devices, err := ctx.WSL.GetBlockDevicesWithInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list block devices: %w", err)
+	}
+	byUUID := make(map[string]wsl.BlockDevice, len(devices))
+	for _, dev := range devices {
+		if dev.UUID != "" {
+			byUUID[dev.UUID] = dev
+		}
+	}
+
+	now := time.Now()
+	rows := make([]topRow, 0, len(paths))
+	for _, path := range paths {
+		row := topRow{Path: path, State: "detached", ReadRate: -1}
+		entry, _ := ctx.Tracker.GetEntry(path)
+		if entry.Archived {
+			row.State = "archived"
+		}
+
+		dev, ok := byUUID[entry.UUID]
+		if entry.UUID == "" || !ok {
+			delete(samples, path)
+			rows = append(rows, row)
+			continue
+		}
+
+		row.State = "attached"
+		if mps := filterEmptyMountPoints(dev.MountPoints); len(mps) > 0 {
+			row.State = "mounted"
+			row.MountPoint = mps[0]
+			row.Use = dev.FSUseP
+			row.Avail = dev.FSAvail
+		}
+
+		stats, err := ctx.WSL.DeviceIOStats(dev.Name)
+		if err != nil {
+			ctx.Logger.Debug("No I/O counters for %s: %v", path, err)
+			rows = append(rows, row)
+			continue
+		}
+		if prev, ok := samples[path]; ok && prev.devName == dev.Name && !countersReset(prev.stats, stats) {
+			if secs := now.Sub(prev.time).Seconds(); secs > 0 {
+				row.ReadRate = float64(stats.ReadBytes-prev.stats.ReadBytes) / secs
+				row.WriteRate = float64(stats.WrittenBytes-prev.stats.WrittenBytes) / secs
+				row.IOPS = float64(stats.Reads+stats.Writes-prev.stats.Reads-prev.stats.Writes) / secs
+			}
+		}
+		samples[path] = topSample{devName: dev.Name, stats: stats, time: now}
+		rows = append(rows, row)
+	}
+
+	slices.SortStableFunc(rows, func(a, b topRow) int {
+		return cmp.Or(
+			cmp.Compare(b.ReadRate+b.WriteRate, a.ReadRate+a.WriteRate),
+			cmp.Compare(topStateOrder(a.State), topStateOrder(b.State)),
+			cmp.Compare(a.Path, b.Path),
+		)
+	})
+	return rows, nil
+}
+
+// countersReset reports whether I/O counters went backwards, which happens
+// when the device was detached and another VHD got its name in between
+func countersReset(prev, cur wsl.IOStats) bool {
+	return cur.Reads < prev.Reads || cur.Writes < prev.Writes ||
+		cur.ReadBytes < prev.ReadBytes || cur.WrittenBytes < prev.WrittenBytes
+}
+
+// topStateOrder ranks states so attached VHDs come before detached ones
+func topStateOrder(state string) int {
+	switch state {
+	case "mounted":
+		return 0
+	case "attached":
+		return 1
+	case "detached":
+		return 2
+	}
+	return 3
+}
+
+func printTopTable(ctx *AppContext, rows []topRow, interval int) {
+	fmt.Printf("vhdm top - %s - every %ds - Ctrl+C to quit\n\n", time.Now().Format("15:04:05"), interval)
+
+	colWidths := []int{40, 9, 22, 5, 8, 10, 10, 7}
+	headers := []string{"Path", "State", "Mount Point", "Use%", "Avail", "Read/s", "Write/s", "IOPS"}
+
+	utils.PrintTableHeader(colWidths, headers)
+
+	for _, row := range rows {
+		state := row.State
+		switch state {
+		case "mounted":
+			state = ctx.Theme.Green(state)
+		case "attached":
+			state = ctx.Theme.Yellow(state)
+		}
+		read, write, iops := "-", "-", "-"
+		if row.ReadRate >= 0 {
+			read = formatRate(row.ReadRate)
+			write = formatRate(row.WriteRate)
+			iops = fmt.Sprintf("%.0f", row.IOPS)
+		}
+		utils.PrintTableRow(colWidths, row.Path, state, row.MountPoint, row.Use, row.Avail, read, write, iops)
+	}
+
+	utils.PrintTableFooter(colWidths)
+}
+
+func printTopQuiet(rows []topRow) {
+	for _, row := range rows {
+		if row.ReadRate < 0 {
+			printQuiet(quietLine{Key: row.Path, State: row.State})
+			continue
+		}
+		printQuiet(quietLine{Key: row.Path, State: fmt.Sprintf("%s read %s write %s", row.State, formatRate(row.ReadRate), formatRate(row.WriteRate))})
+	}
+}
+
+// formatRate formats a byte rate as e.g. "12MB/s"
+func formatRate(bytesPerSec float64) string {
+	return utils.BytesToHuman(int64(bytesPerSec)) + "/s"
+}
+
+// stdoutIsTerminal reports whether stdout is an interactive terminal
+func stdoutIsTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
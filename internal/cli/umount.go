@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -19,6 +20,7 @@ func newUmountCmd() *cobra.Command {
 		mountPoint string
 		doDetach   bool
 		force      bool
+		allPoints  bool
 	)
 	cmd := &cobra.Command{
 		Use:     "umount",
@@ -26,26 +28,32 @@ func newUmountCmd() *cobra.Command {
 		Short:   "Unmount a VHD",
 		Long: `Unmount a VHD from the filesystem.
 
-By default, only unmounts. Use --vhd-path to also detach after unmounting.`,
+By default, only unmounts. Use --vhd-path to also detach after unmounting.
+
+A VHD mounted at several mount points (see 'vhdm mount --add') is only
+unmounted from --mount-point, which is removed from tracking; use
+--all-points to unmount it everywhere. Detaching always unmounts every point.`,
 		Example: `  vhdm umount --mount-point /mnt/data
   vhdm umount --uuid 57fd0f3a-4077-44b8-91ba-5abdee575293
   vhdm umount --dev-name sde
-  vhdm umount --vhd-path C:/VMs/disk.vhdx  # unmount and detach`,
+  vhdm umount --vhd-path C:/VMs/disk.vhdx  # unmount and detach
+  vhdm umount --uuid 57fd0f3a-4077-44b8-91ba-5abdee575293 --all-points`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runUmount(vhdPath, uuid, devName, mountPoint, doDetach, force)
+			return runUmount(appContext(cmd), vhdPath, uuid, devName, mountPoint, doDetach, force, allPoints)
 		},
+		Annotations: map[string]string{annotationSudo: "true"},
 	}
 	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (unmount + detach)")
-	cmd.Flags().StringVar(&uuid, "uuid", "", "VHD UUID")
+	cmd.Flags().StringVar(&uuid, "uuid", "", uuidFlagUsage)
 	cmd.Flags().StringVar(&devName, "dev-name", "", "Device name (e.g., sde)")
 	cmd.Flags().StringVar(&mountPoint, "mount-point", "", "Mount point path")
 	cmd.Flags().BoolVar(&doDetach, "detach", false, "Also detach after unmounting")
 	cmd.Flags().BoolVar(&force, "force", false, "Force unmount (lazy)")
+	cmd.Flags().BoolVar(&allPoints, "all-points", false, "Unmount from every mount point of the VHD")
 	return cmd
 }
 
-func runUmount(vhdPath, uuid, devName, mountPoint string, doDetach, force bool) error {
-	ctx := getContext()
+func runUmount(ctx *AppContext, vhdPath, uuid, devName, mountPoint string, doDetach, force, allPoints bool) error {
 	log := ctx.Logger
 
 	// Validate inputs
@@ -60,9 +68,11 @@ func runUmount(vhdPath, uuid, devName, mountPoint string, doDetach, force bool)
 		doDetach = true // vhd-path implies detach
 	}
 	if uuid != "" {
-		if err := validation.ValidateUUID(uuid); err != nil {
-			return &types.VHDError{Op: "umount", Err: err}
+		resolved, err := resolveUUID(ctx, "umount", uuid)
+		if err != nil {
+			return err
 		}
+		uuid = resolved
 	}
 	if devName != "" {
 		if err := validation.ValidateDeviceName(devName); err != nil {
@@ -72,6 +82,7 @@ func runUmount(vhdPath, uuid, devName, mountPoint string, doDetach, force bool)
 		devName = strings.TrimPrefix(devName, "/dev/")
 	}
 	if mountPoint != "" {
+		mountPoint = expandMountPoint(ctx, mountPoint, vhdPath, uuid)
 		if err := validation.ValidateMountPoint(mountPoint); err != nil {
 			return &types.VHDError{Op: "umount", Err: err}
 		}
@@ -92,10 +103,25 @@ func runUmount(vhdPath, uuid, devName, mountPoint string, doDetach, force bool)
 		}
 	}
 
-	// Find mount point if not provided
-	if mountPoint == "" && uuid != "" {
-		mountPoint, _ = ctx.WSL.GetMountPoint(uuid)
+	// Decide which mount points to unmount
+	var mountPoints []string
+	if uuid != "" {
+		mountPoints, _ = ctx.WSL.GetMountPoints(uuid)
+	}
+	targets := mountPoints
+	if mountPoint != "" && !allPoints && !doDetach {
+		targets = []string{mountPoint}
+	} else if len(mountPoints) > 1 && !allPoints && !doDetach {
+		return &types.VHDError{
+			Op:   "umount",
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD is mounted at %d mount points: %s", len(mountPoints), strings.Join(mountPoints, ", ")),
+			Help: "Choose one with --mount-point, or pass --all-points to unmount all of them",
+		}
+	} else if len(mountPoints) == 0 && mountPoint != "" {
+		targets = []string{mountPoint}
 	}
+	mountPoint = strings.Join(targets, ", ")
 
 	// Find device name if not yet determined
 	if devName == "" && uuid != "" {
@@ -109,35 +135,42 @@ func runUmount(vhdPath, uuid, devName, mountPoint string, doDetach, force bool)
 
 	// Check if mounted
 	if mountPoint == "" {
-		if ctx.Config.Quiet {
-			fmt.Printf("not mounted\n")
-		} else {
-			log.Info("VHD is not mounted")
-		}
+		log.Info("VHD is not mounted")
 
 		// Even if not mounted, might want to detach
 		if doDetach && vhdPath != "" {
 			log.Info("Detaching VHD...")
-			return runDetach(vhdPath, uuid, devName)
+			return runDetach(ctx, vhdPath, uuid, devName)
 		}
-		return nil
+		return printResult(ctx, UmountResult{Path: vhdPath, UUID: uuid, DeviceName: devName, Status: "not mounted"})
 	}
 
-	// Unmount
-	var err error
-	if force {
-		err = ctx.WSL.ForceUnmount(mountPoint)
-	} else {
-		err = ctx.WSL.Unmount(mountPoint)
-	}
-	if err != nil {
-		return fmt.Errorf("failed to unmount: %w", err)
+	// Unmount, bind mounts (added after the original mount) first
+	for i := len(targets) - 1; i >= 0; i-- {
+		var err error
+		if force {
+			err = ctx.WSL.ForceUnmount(targets[i])
+		} else {
+			err = ctx.WSL.Unmount(targets[i])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to unmount: %w", err)
+		}
 	}
 
-	// Update tracking - remove mount point
-	if vhdPath != "" {
-		ctx.Tracker.UpdateMountPoints(vhdPath, []string{})
+	// Update tracking - remove the unmounted points
+	trackedPath := vhdPath
+	if trackedPath == "" && uuid != "" {
+		trackedPath, _ = ctx.Tracker.LookupPathByUUID(uuid)
 	}
+	if trackedPath != "" {
+		remaining := slices.DeleteFunc(slices.Clone(mountPoints), func(mp string) bool {
+			return slices.Contains(targets, mp)
+		})
+		ctx.Tracker.UpdateMountPoints(trackedPath, remaining)
+	}
+
+	res := UmountResult{Path: vhdPath, UUID: uuid, DeviceName: devName, MountPoint: mountPoint, Status: "unmounted"}
 
 	// Detach if requested
 	if doDetach && vhdPath != "" {
@@ -145,45 +178,53 @@ func runUmount(vhdPath, uuid, devName, mountPoint string, doDetach, force bool)
 			log.Warn("Failed to detach: %v", err)
 		} else {
 			// Update tracking - keep entry but clear device/mount info
-			if uuid != "" {
-				ctx.Tracker.SaveMapping(vhdPath, uuid, "", "")
-			}
+			markDetached(ctx, vhdPath)
 			log.Success("VHD unmounted and detached")
-			printUmountResult(vhdPath, uuid, devName, mountPoint, true)
-			return nil
+			res.Status = "unmounted and detached"
+			return printResult(ctx, res)
 		}
 	}
 
 	// Output
-	if ctx.Config.Quiet {
-		fmt.Printf("%s: unmounted\n", mountPoint)
-		return nil
-	}
-
 	log.Success("VHD unmounted successfully")
-	printUmountResult(vhdPath, uuid, devName, mountPoint, false)
-	return nil
+	return printResult(ctx, res)
+}
+
+// UmountResult is the outcome of 'vhdm umount'
+type UmountResult struct {
+	Path       string `json:"path,omitempty"`
+	UUID       string `json:"uuid,omitempty"`
+	DeviceName string `json:"deviceName,omitempty"`
+	MountPoint string `json:"mountPoint,omitempty"`
+	Status     string `json:"status"`
 }
 
-func printUmountResult(path, uuid, devName, mountPoint string, wasDetached bool) {
+func (r UmountResult) table(utils.Theme) (string, [][2]string) {
 	pairs := [][2]string{}
 
-	if path != "" {
-		pairs = append(pairs, [2]string{"Path", path})
+	if r.Path != "" {
+		pairs = append(pairs, [2]string{"Path", r.Path})
 	}
-	if uuid != "" {
-		pairs = append(pairs, [2]string{"UUID", uuid})
+	if r.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", r.UUID})
 	}
-	if devName != "" {
-		pairs = append(pairs, [2]string{"Device", "/dev/" + devName})
+	pairs = appendDevice(pairs, r.DeviceName)
+	if r.MountPoint != "" {
+		pairs = append(pairs, [2]string{"Mount Point", r.MountPoint})
 	}
-	pairs = append(pairs, [2]string{"Mount Point", mountPoint})
+	pairs = append(pairs, [2]string{"Status", r.Status})
 
-	status := "unmounted"
-	if wasDetached {
-		status = "unmounted and detached"
-	}
-	pairs = append(pairs, [2]string{"Status", status})
+	return "VHD Umount Result", pairs
+}
 
-	utils.KeyValueTable("VHD Umount Result", pairs, 14, 50)
+func (r UmountResult) quiet() quietLine {
+	switch {
+	case r.Path == "" && r.MountPoint == "":
+		return quietLine{Key: "/dev/" + r.DeviceName, UUID: r.UUID, State: r.Status}
+	case r.Path == "":
+		return quietLine{Key: r.MountPoint, UUID: r.UUID, State: r.Status}
+	case r.MountPoint == "":
+		return quietLine{Key: r.Path, UUID: r.UUID, State: r.Status}
+	}
+	return quietLine{Key: r.Path, UUID: r.UUID, State: r.Status + " from " + r.MountPoint}
 }
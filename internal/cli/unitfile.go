@@ -0,0 +1,366 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// systemdSafeWord matches command line words that systemd reads back
+// unchanged without quoting
+var systemdSafeWord = regexp.MustCompile(`^[A-Za-z0-9_@+=:,./-]+$`)
+
+// systemdEscapeSpecifiers escapes the % of unit specifiers (%h, %n, ...), for
+// settings such as Description= and Where= that expand them
+func systemdEscapeSpecifiers(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// systemdQuote returns arg as a word of a unit command line (ExecStart= and
+// ExecStop=) that systemd parses back to arg. Specifiers and environment
+// variable references are escaped, and words with other characters than
+// systemdSafeWord are double-quoted with C-style escapes.
+func systemdQuote(arg string) string {
+	return quoteUnitWord(arg, true)
+}
+
+// systemdQuotePath returns a path as a word of a path list setting such as
+// RequiresMountsFor=, which expands specifiers but not $ references
+func systemdQuotePath(p string) string {
+	return quoteUnitWord(p, false)
+}
+
+func quoteUnitWord(s string, escapeDollar bool) string {
+	if systemdSafeWord.MatchString(s) {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '%':
+			b.WriteString("%%")
+		case r == '$' && escapeDollar:
+			b.WriteString("$$")
+		default:
+			writeCEscaped(&b, r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// systemdCommandLine renders args as the value of an ExecStart= setting
+func systemdCommandLine(args ...string) string {
+	words := make([]string, len(args))
+	for i, arg := range args {
+		words[i] = systemdQuote(arg)
+	}
+	return strings.Join(words, " ")
+}
+
+// splitUnitCommandLine splits the value of an ExecStart= setting into the
+// words systemd passes to the command, undoing systemdQuote
+func splitUnitCommandLine(line string) []string {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+		quoted bool
+	)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case !quoted && (c == ' ' || c == '\t'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		case c == '"':
+			quoted = !quoted
+		case (c == '%' || c == '$') && i+1 < len(line) && line[i+1] == c:
+			word.WriteByte(c)
+			i++
+		case c == '\\' && quoted && i+1 < len(line):
+			i++
+			switch line[i] {
+			case 'n':
+				word.WriteByte('\n')
+			case 't':
+				word.WriteByte('\t')
+			case 'x':
+				if n, err := strconv.ParseUint(line[min(i+1, len(line)):min(i+3, len(line))], 16, 8); err == nil {
+					word.WriteByte(byte(n))
+					i += 2
+				}
+			default:
+				word.WriteByte(line[i])
+			}
+		default:
+			word.WriteByte(c)
+		}
+		inWord = true
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// systemdEnvironment renders an Environment= setting of one variable. Unlike
+// command lines, environment values do not expand $ references.
+func systemdEnvironment(name, value string) string {
+	var b strings.Builder
+	b.WriteString(`Environment="`)
+	for _, r := range name + "=" + value {
+		if r == '%' {
+			b.WriteString("%%")
+			continue
+		}
+		writeCEscaped(&b, r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// windowsMountLines renders the [Unit] lines that order a unit for vhdPath
+// after the Windows drives it needs: the drive of the VHD and C:, where
+// wsl.exe lives. RequiresMountsFor= on the VHD file also covers drives
+// mounted below other paths.
+func windowsMountLines(vhdPath string) string {
+	units := []string{"mnt-c.mount"}
+	if drive := utils.WindowsDrive(vhdPath); drive != "" && drive != "C:" {
+		units = append([]string{systemdEscapePath(utils.ConvertWindowsToWSLPath(drive+"/")) + ".mount"}, units...)
+	}
+	joined := strings.Join(units, " ")
+	return fmt.Sprintf("After=local-fs.target %s\nRequires=%s\nRequiresMountsFor=%s\n",
+		joined, joined, systemdQuotePath(utils.ConvertWindowsToWSLPath(vhdPath)))
+}
+
+// writeCEscaped writes r as systemd reads it inside double quotes
+func writeCEscaped(b *strings.Builder, r rune) {
+	switch {
+	case r == '\\' || r == '"':
+		b.WriteByte('\\')
+		b.WriteRune(r)
+	case r == '\n':
+		b.WriteString(`\n`)
+	case r == '\t':
+		b.WriteString(`\t`)
+	case r < 0x20 || r == 0x7f:
+		fmt.Fprintf(b, `\x%02x`, r)
+	default:
+		b.WriteRune(r)
+	}
+}
+
+// legacyWindowsMountLines are the Windows drive dependencies of units created
+// before windowsMountLines
+const legacyWindowsMountLines = "After=local-fs.target mnt-c.mount\nRequires=mnt-c.mount\n"
+
+// rewriteUnitPaths replaces the VHD path oldPath with newPath in the unit
+// files of the unit directory and legacyUnitDir, and returns the units it
+// changed. The path is matched as a whole command line word (also quoted the
+// way units were before systemdQuote) or in the description, and the Windows
+// drive dependencies of the units naming it follow the new path.
+func rewriteUnitPaths(ctx *AppContext, oldPath, newPath string) ([]string, error) {
+	return editUnitFiles(ctx, unitPathEdit(oldPath, newPath))
+}
+
+// unitsNamingPath returns the units rewriteUnitPaths would change to move the
+// VHD at oldPath to newPath, without changing them
+func unitsNamingPath(ctx *AppContext, oldPath, newPath string) ([]string, error) {
+	return changeUnitFiles(ctx, unitPathEdit(oldPath, newPath), false)
+}
+
+// unitPathEdit returns the edit of rewriteUnitPaths
+func unitPathEdit(oldPath, newPath string) func(content string) string {
+	execRe := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(systemdQuote(oldPath)) + `|"` + regexp.QuoteMeta(oldPath) + `"`)
+	descRe := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(systemdEscapeSpecifiers(oldPath)))
+	drivesRe := regexp.MustCompile(`(?im)` + regexp.QuoteMeta(windowsMountLines(oldPath)) + `|^` + regexp.QuoteMeta(legacyWindowsMountLines))
+
+	return func(content string) string {
+		updated := editUnitSettings(content, func(key, value string) string {
+			switch key {
+			case "ExecStart", "ExecStop":
+				return replaceWords(execRe, value, systemdQuote(newPath))
+			case "Description":
+				return descRe.ReplaceAllLiteralString(value, systemdEscapeSpecifiers(newPath))
+			}
+			return value
+		})
+		if updated == content {
+			return content
+		}
+		return drivesRe.ReplaceAllLiteralString(updated, windowsMountLines(newPath))
+	}
+}
+
+// rewriteUnitReferences replaces the unit oldUnit with newUnit in the
+// dependencies of the units in the unit directory and legacyUnitDir, and
+// returns the units it changed
+func rewriteUnitReferences(ctx *AppContext, oldUnit, newUnit string) ([]string, error) {
+	unitRe := regexp.MustCompile(regexp.QuoteMeta(oldUnit))
+	return editUnitFiles(ctx, func(content string) string {
+		return editUnitSettings(content, func(key, value string) string {
+			switch key {
+			case "After", "Before", "Requires", "Wants", "BindsTo", "PartOf":
+				return replaceWords(unitRe, value, newUnit)
+			}
+			return value
+		})
+	})
+}
+
+// serviceUnits returns the unit files making up a service recorded in
+// tracking: the service itself, or all units of an automount
+func serviceUnits(service string) []string {
+	if !strings.HasPrefix(service, automountPrefix) {
+		return []string{service}
+	}
+	stem := strings.TrimSuffix(strings.TrimPrefix(service, automountPrefix), ".service")
+	return []string{service, stem + ".mount", stem + ".automount"}
+}
+
+// rewriteServiceUUID replaces the filesystem UUID oldUUID with newUUID in the
+// units of a service, once the VHD was formatted anew, and reports whether
+// any unit changed
+func rewriteServiceUUID(ctx *AppContext, service, oldUUID, newUUID string) (bool, error) {
+	uuidRe := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(oldUUID))
+	changed := false
+	for _, unit := range serviceUnits(service) {
+		unitPath := unitFilePath(ctx, unit)
+		content, err := os.ReadFile(unitPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		updated := editUnitSettings(string(content), func(key, value string) string {
+			switch key {
+			case "ExecStart", "ExecStop":
+				return replaceWords(uuidRe, value, newUUID)
+			case "What":
+				if strings.EqualFold(value, "/dev/disk/by-uuid/"+oldUUID) {
+					return "/dev/disk/by-uuid/" + newUUID
+				}
+			}
+			return value
+		})
+		if updated == string(content) {
+			continue
+		}
+		if err := os.WriteFile(unitPath, []byte(updated), 0644); err != nil {
+			return changed, err
+		}
+		ctx.Logger.Debug("Rewrote unit file: %s", unitPath)
+		changed = true
+	}
+	return changed, nil
+}
+
+// updateServiceUUID points the service of a VHD at the filesystem UUID it has
+// after being formatted anew (by resize or restore) and reloads systemd
+func updateServiceUUID(ctx *AppContext, vhdPath, oldUUID, newUUID string) {
+	service := trackedService(ctx, vhdPath)
+	if service == "" || oldUUID == "" || strings.EqualFold(oldUUID, newUUID) {
+		return
+	}
+	changed, err := rewriteServiceUUID(ctx, service, oldUUID, newUUID)
+	if err != nil {
+		ctx.Logger.Warn("Failed to update service %s to UUID %s: %v", service, newUUID, err)
+		return
+	}
+	if !changed {
+		return
+	}
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		ctx.Logger.Warn("Failed to reload systemd daemon: %v", err)
+	}
+	ctx.Logger.Info("Service %s updated to UUID %s", service, newUUID)
+}
+
+// editUnitFiles rewrites the unit files of the unit directory and
+// legacyUnitDir with edit, and returns the units it changed
+func editUnitFiles(ctx *AppContext, edit func(content string) string) ([]string, error) {
+	return changeUnitFiles(ctx, edit, true)
+}
+
+// changeUnitFiles returns the units of the unit directory and legacyUnitDir
+// that edit changes, writing the changes when write is set
+func changeUnitFiles(ctx *AppContext, edit func(content string) string, write bool) ([]string, error) {
+	var changed []string
+	for _, dir := range []string{ctx.Config.UnitDir, legacyUnitDir} {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || !slices.Contains([]string{".service", ".mount", ".automount"}, filepath.Ext(name)) {
+				continue
+			}
+			unitPath := filepath.Join(dir, name)
+			content, err := os.ReadFile(unitPath)
+			if err != nil {
+				return changed, err
+			}
+			updated := edit(string(content))
+			if updated == string(content) {
+				continue
+			}
+			if write {
+				if err := os.WriteFile(unitPath, []byte(updated), 0644); err != nil {
+					return changed, err
+				}
+				ctx.Logger.Debug("Rewrote unit file: %s", unitPath)
+			}
+			if !slices.Contains(changed, name) {
+				changed = append(changed, name)
+			}
+		}
+	}
+	return changed, nil
+}
+
+// editUnitSettings replaces the value of each KEY=VALUE line of a unit file
+// with edit(KEY, VALUE)
+func editUnitSettings(content string, edit func(key, value string) string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if key, value, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(line, "#") {
+			lines[i] = key + "=" + edit(key, value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replaceWords replaces the matches of re in a command line that are whole
+// words, separated by whitespace, with repl
+func replaceWords(re *regexp.Regexp, line, repl string) string {
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' }
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringIndex(line, -1) {
+		if (m[0] > 0 && !isSpace(line[m[0]-1])) || (m[1] < len(line) && !isSpace(line[m[1]])) {
+			continue
+		}
+		b.WriteString(line[last:m[0]])
+		b.WriteString(repl)
+		last = m[1]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newVerifyCmd() *cobra.Command {
+	var (
+		vhdPath string
+		fsck    bool
+		force   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check a VHD file, and optionally its filesystem, for corruption",
+		Long: `Verify a VHD before relying on it, e.g. as the source of a backup.
+
+The image is checked with 'qemu-img check' and the result recorded in
+tracking, as by 'vhdm check-image'. With --fsck the filesystem is checked too,
+read-only (e2fsck -n, xfs_repair -n, btrfs check --readonly or fsck.vfat -n):
+a detached VHD is attached for the check and detached again. Nothing is
+repaired.
+
+The VHD must be detached, since Windows or the mounted filesystem may change
+it during the check; --force checks an attached VHD anyway, but its filesystem
+only when it is not mounted. The command fails when corruption is found or a
+check could not run.`,
+		Example: `  vhdm verify --vhd-path C:/VMs/data.vhdx
+  sudo vhdm verify --vhd-path C:/VMs/data.vhdx --fsck`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVerify(appContext(cmd), vhdPath, fsck, force)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().StringVar(&vhdPath, "vhd-path", "", "VHD file path (Windows format)")
+	cmd.Flags().BoolVar(&fsck, "fsck", false, "Also check the filesystem read-only")
+	cmd.Flags().BoolVar(&force, "force", false, "Also check an attached VHD")
+	cmd.MarkFlagRequired("vhd-path")
+	return cmd
+}
+
+func runVerify(ctx *AppContext, vhdPath string, fsck, force bool) error {
+	log := ctx.Logger
+
+	// Validate
+	if err := validation.ValidateWindowsPath(vhdPath); err != nil {
+		return &types.VHDError{Op: "verify", Path: vhdPath, Err: err}
+	}
+	if !ctx.WSL.FileExists(ctx.WSL.ConvertPath(vhdPath)) {
+		return &types.VHDError{Op: "verify", Path: vhdPath, Err: types.ErrVHDNotFound}
+	}
+
+	lock, err := lockVHDOperation(ctx, "verify", vhdPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	log.Debug("Verify operation starting")
+
+	// An attached VHD is checked where it is, on its device
+	var devName string
+	entry, _ := ctx.Tracker.GetEntry(vhdPath)
+	if entry.UUID != "" {
+		if attached, _ := ctx.WSL.IsAttached(entry.UUID); attached {
+			if !force {
+				return &types.VHDError{
+					Op:   "verify",
+					Path: vhdPath,
+					Err:  fmt.Errorf("VHD is attached"),
+					Help: fmt.Sprintf("Detach it first with 'vhdm detach --vhd-path %s', or pass --force to check it anyway", vhdPath),
+				}
+			}
+			if fsck && len(entry.MountPoints) > 0 {
+				return &types.VHDError{
+					Op:   "verify",
+					Path: vhdPath,
+					Err:  fmt.Errorf("VHD is mounted at %s", entry.MountPoints[0]),
+					Help: fmt.Sprintf("Unmount it first with 'vhdm umount --vhd-path %s' to check its filesystem", vhdPath),
+				}
+			}
+			devName, _ = ctx.WSL.GetDeviceByUUID(entry.UUID)
+		}
+	}
+
+	log.Info("Checking image %s...", vhdPath)
+	res := VerifyResult{Path: vhdPath, Image: recordImageCheck(ctx, vhdPath)}
+
+	if fsck {
+		log.Info("Checking filesystem read-only...")
+		check, fsType, err := checkFilesystemReadOnly(ctx, vhdPath, devName)
+		if err != nil {
+			return &types.VHDError{Op: "verify", Path: vhdPath, Err: err}
+		}
+		res.FSType, res.Filesystem = fsType, check
+		if !check.Clean && check.Output != "" && !structuredOutput(ctx) {
+			log.Warn("Filesystem check report:\n%s", check.Output)
+		}
+	}
+
+	if err := printResult(ctx, res); err != nil {
+		return err
+	}
+	if res.damaged() {
+		return &types.VHDError{
+			Op:   "verify",
+			Path: vhdPath,
+			Err:  fmt.Errorf("VHD failed verification: %s", res.problem()),
+			Help: "Back up the data, then repair the detached VHD with 'qemu-img check -r all <file>' and the filesystem's fsck",
+		}
+	}
+	log.Success("VHD verified")
+	return nil
+}
+
+// checkFilesystemReadOnly checks the filesystem of a VHD without changing it,
+// on devName when the VHD is attached, else attaching it for the check
+func checkFilesystemReadOnly(ctx *AppContext, vhdPath, devName string) (*wsl.FilesystemCheck, string, error) {
+	if devName == "" {
+		dev, attached, err := ctx.WSL.AttachVHDAndDetect(vhdPath)
+		if attached {
+			defer func() {
+				if err := ctx.WSL.DetachVHD(vhdPath); err != nil {
+					ctx.Logger.Warn("Failed to detach %s: %v", vhdPath, err)
+				}
+			}()
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to attach %s: %w", vhdPath, err)
+		}
+		devName = dev
+	}
+	fsType, _ := ctx.WSL.GetFilesystemType(devName)
+	if fsType == "" {
+		return nil, "", fmt.Errorf("%w: no filesystem found", types.ErrVHDNotFormatted)
+	}
+	check, err := ctx.WSL.CheckFilesystem(devName, fsType)
+	return check, fsType, err
+}
+
+// VerifyResult is the outcome of 'vhdm verify'
+type VerifyResult struct {
+	Path       string                 `json:"path"`
+	Image      types.ImageCheckResult `json:"image"`
+	FSType     string                 `json:"fsType,omitempty"`
+	Filesystem *wsl.FilesystemCheck   `json:"filesystem,omitempty"` // Read-only check, with --fsck
+}
+
+// damaged reports whether a check found corruption or could not run; an
+// image whose format has no check is skipped, not damaged
+func (r VerifyResult) damaged() bool {
+	return r.Image.Damaged() || r.Image.Error != "" || (r.Filesystem != nil && !r.Filesystem.Clean)
+}
+
+// problem describes what failed verification
+func (r VerifyResult) problem() string {
+	var problems []string
+	if r.Image.Damaged() || r.Image.Error != "" {
+		problems = append(problems, "image: "+r.Image.Problem())
+	}
+	if r.Filesystem != nil && !r.Filesystem.Clean {
+		problems = append(problems, r.FSType+" filesystem: errors found")
+	}
+	return strings.Join(problems, "; ")
+}
+
+func (r VerifyResult) table(t utils.Theme) (string, [][2]string) {
+	image := r.Image.Problem()
+	switch {
+	case image == "" && r.Image.Skipped != "":
+		image = "skipped (" + r.Image.Skipped + ")"
+	case image == "":
+		image = "ok"
+	}
+	if r.Image.Damaged() || r.Image.Error != "" {
+		image = t.Red(image)
+	}
+	pairs := [][2]string{
+		{"Path", r.Path},
+		{"Image", image},
+	}
+	if r.Filesystem != nil {
+		fs := r.FSType + ": clean"
+		if !r.Filesystem.Clean {
+			fs = t.Red(r.FSType + ": errors found")
+		}
+		pairs = append(pairs, [2]string{"Filesystem", fs})
+	}
+	status := t.Green("verified")
+	if r.damaged() {
+		status = t.Red("damaged")
+	}
+	return "Verify Result", append(pairs, [2]string{"Status", status})
+}
+
+func (r VerifyResult) quiet() quietLine {
+	if r.damaged() {
+		return quietLine{Key: r.Path, State: fmt.Sprintf("damaged (%s)", r.problem())}
+	}
+	return quietLine{Key: r.Path, State: "ok"}
+}
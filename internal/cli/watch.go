@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// idleState tracks the last observed I/O activity of an attached VHD
+type idleState struct {
+	devName    string
+	ioCount    uint64
+	lastActive time.Time
+}
+
+func newWatchCmd() *cobra.Command {
+	var (
+		idleTimeout int
+		interval    int
+		exclude     []string
+		statusAddr  string
+	)
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Unmount and detach tracked VHDs that have been idle",
+		Long: `Watch attached tracked VHDs and unmount and detach those that have seen no
+filesystem activity for the idle timeout. This frees device slots and releases
+the backing files so they can be moved, copied or compacted from Windows.
+
+Activity is detected from the block device I/O counters, so reads served from
+the page cache do not count. VHDs whose mount point is busy are left alone and
+retried on the next idle period.
+
+The watcher runs until interrupted and is suitable for running as a systemd
+service. Use --exclude for VHDs managed by 'vhdm service' health monitors.
+
+--status-addr additionally serves the state of the tracked VHDs as JSON at
+http://<addr>/status, read-only. The address must be on loopback; WSL
+forwards it to Windows localhost, so tray apps can poll it without running
+wsl.exe.`,
+		Example: `  vhdm watch
+  vhdm watch --idle-timeout 600 --interval 30
+  vhdm watch --exclude C:/VMs/always-on.vhdx
+  vhdm watch --status-addr 127.0.0.1:7380`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWatch(appContext(cmd), idleTimeout, interval, exclude, statusAddr)
+		},
+		Annotations: map[string]string{annotationSudo: "true"},
+	}
+	cmd.Flags().IntVar(&idleTimeout, "idle-timeout", 1800, "Seconds without activity before a VHD is unmounted and detached")
+	cmd.Flags().IntVar(&interval, "interval", 60, "Seconds between activity checks")
+	cmd.Flags().StringSliceVar(&exclude, "exclude", nil, "VHD paths to never unmount (repeatable)")
+	cmd.Flags().StringVar(&statusAddr, "status-addr", "", "Serve read-only VHD status as JSON on this loopback host:port (off by default)")
+	return cmd
+}
+
+func runWatch(ctx *AppContext, idleTimeout, interval int, exclude []string, statusAddr string) error {
+	log := ctx.Logger
+
+	// Validate
+	if idleTimeout < 1 {
+		return &types.VHDError{Op: "watch", Err: fmt.Errorf("idle timeout must be at least 1 second")}
+	}
+	if interval < 1 {
+		return &types.VHDError{Op: "watch", Err: fmt.Errorf("interval must be at least 1 second")}
+	}
+	excluded := make(map[string]bool, len(exclude))
+	for _, path := range exclude {
+		if err := validation.ValidateWindowsPath(path); err != nil {
+			return &types.VHDError{Op: "watch", Path: path, Err: err}
+		}
+		excluded[utils.NormalizePath(path)] = true
+	}
+	if statusAddr != "" {
+		if err := validateStatusAddr(statusAddr); err != nil {
+			return &types.VHDError{Op: "watch", Err: err}
+		}
+	}
+
+	log.Info("Watching tracked VHDs for inactivity")
+	log.Info("  Idle Timeout: %ds", idleTimeout)
+	log.Info("  Check Interval: %ds", interval)
+	if statusAddr != "" {
+		if err := startStatusEndpoint(ctx, statusAddr); err != nil {
+			return &types.VHDError{Op: "watch", Err: err, Help: "Choose another port with --status-addr"}
+		}
+	}
+
+	// Re-check immediately when the tracking file changes (e.g., a VHD was
+	// mounted), otherwise every interval
+	watcher, err := ctx.Tracker.WatchChanges()
+	if err != nil {
+		log.Debug("Tracking change notifications unavailable: %v", err)
+	} else {
+		defer watcher.Close()
+	}
+
+	states := make(map[string]*idleState)
+	for {
+		checkIdleVHDs(ctx, states, excluded, time.Duration(idleTimeout)*time.Second)
+		if watcher == nil {
+			time.Sleep(time.Duration(interval) * time.Second)
+			continue
+		}
+		if changed, err := watcher.Wait(time.Duration(interval) * time.Second); err != nil {
+			log.Debug("Waiting for tracking changes failed: %v", err)
+			time.Sleep(time.Duration(interval) * time.Second)
+		} else if changed {
+			log.Debug("Tracking file changed, re-checking")
+		}
+	}
+}
+
+// checkIdleVHDs samples I/O activity of attached tracked VHDs and releases
+// those idle for longer than idleTimeout
+func checkIdleVHDs(ctx *AppContext, states map[string]*idleState, excluded map[string]bool, idleTimeout time.Duration) {
+	log := ctx.Logger
+
+	paths, err := ctx.Tracker.GetAllPaths()
+	if err != nil {
+		log.Warn("Failed to get tracked VHDs: %v", err)
+		return
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		key := utils.NormalizePath(path)
+		if excluded[key] {
+			continue
+		}
+
+		uuid, _ := ctx.Tracker.LookupUUIDByPath(path)
+		if uuid == "" {
+			continue
+		}
+		devName, _ := ctx.WSL.GetDeviceByUUID(uuid)
+		if devName == "" {
+			continue // Not attached
+		}
+		count, err := ctx.WSL.DeviceIOCount(devName)
+		if err != nil {
+			log.Debug("Skipping %s: %v", path, err)
+			continue
+		}
+		seen[key] = true
+
+		state, ok := states[key]
+		if !ok || state.devName != devName || state.ioCount != count {
+			states[key] = &idleState{devName: devName, ioCount: count, lastActive: now}
+			continue
+		}
+
+		idle := now.Sub(state.lastActive)
+		if idle < idleTimeout {
+			log.Debug("%s idle for %s", path, idle.Truncate(time.Second))
+			continue
+		}
+
+		log.Info("%s idle for %s, unmounting and detaching", path, idle.Truncate(time.Second))
+		if err := releaseIdleVHD(ctx, path, uuid); err != nil {
+			log.Warn("Failed to release %s: %v", path, err)
+			// Wait a full idle period before retrying
+			state.lastActive = now
+			continue
+		}
+		log.Success("Released idle VHD: %s", path)
+		delete(states, key)
+	}
+
+	// Forget VHDs detached or untracked by other means
+	for key := range states {
+		if !seen[key] {
+			delete(states, key)
+		}
+	}
+}
+
+// releaseIdleVHD unmounts (if mounted) and detaches a VHD, keeping it tracked
+func releaseIdleVHD(ctx *AppContext, vhdPath, uuid string) error {
+	if mountPoint, _ := ctx.WSL.GetMountPoint(uuid); mountPoint != "" {
+		if err := ctx.WSL.Unmount(mountPoint); err != nil {
+			return fmt.Errorf("failed to unmount %s: %w", mountPoint, err)
+		}
+	}
+	if err := ctx.WSL.DetachVHD(vhdPath); err != nil && !types.IsNotAttached(err) {
+		return fmt.Errorf("failed to detach: %w", err)
+	}
+	if err := markDetached(ctx, vhdPath); err != nil {
+		ctx.Logger.Warn("Failed to update tracking: %v", err)
+	}
+	return nil
+}
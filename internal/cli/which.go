@@ -0,0 +1,253 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/validation"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+func newWhichCmd() *cobra.Command {
+	var (
+		devName    string
+		mountPoint string
+		search     []string
+	)
+	cmd := &cobra.Command{
+		Use:   "which",
+		Short: "Show which VHD file backs a device or mount point",
+		Long: `Resolve an attached block device (or the device mounted at a mount point)
+back to the Windows VHD file it belongs to.
+
+Tracked VHDs are found by filesystem UUID. For devices vhdm does not track,
+Windows is asked for the virtual size of candidate files (Get-VHD, or qemu-img
+when the Hyper-V module is missing): tracked VHDs that are not attached, plus
+the .vhd/.vhdx files in each --search directory. A candidate matches when its
+size equals the device size and Windows holds the file open.`,
+		Example: `  vhdm which --dev-name sde
+  vhdm which --mount-point /mnt/data
+  vhdm which --dev-name sdf --search C:/VMs --search D:/disks`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWhich(appContext(cmd), devName, mountPoint, search)
+		},
+	}
+	cmd.Flags().StringVar(&devName, "dev-name", "", "Device name (e.g., sde)")
+	cmd.Flags().StringVar(&mountPoint, "mount-point", "", "Mount point of the device")
+	cmd.Flags().StringSliceVar(&search, "search", nil, "Windows directory with VHD files to consider for untracked devices (repeatable)")
+	cmd.MarkFlagsOneRequired("dev-name", "mount-point")
+	cmd.MarkFlagsMutuallyExclusive("dev-name", "mount-point")
+	return cmd
+}
+
+func runWhich(ctx *AppContext, devName, mountPoint string, search []string) error {
+	log := ctx.Logger
+
+	devName = strings.TrimPrefix(devName, "/dev/")
+	if devName != "" {
+		if err := validation.ValidateDeviceName(devName); err != nil {
+			return &types.VHDError{Op: "which", Path: devName, Err: err}
+		}
+	}
+	if mountPoint != "" {
+		if err := validation.ValidateMountPoint(mountPoint); err != nil {
+			return &types.VHDError{Op: "which", Path: mountPoint, Err: err}
+		}
+	}
+	for _, dir := range search {
+		if err := validation.ValidateWindowsPath(dir); err != nil {
+			return &types.VHDError{Op: "which", Path: dir, Err: err}
+		}
+	}
+
+	devices, err := ctx.WSL.GetBlockDevicesWithInfo()
+	if err != nil {
+		return fmt.Errorf("failed to list block devices: %w", err)
+	}
+	dev, err := findWhichDevice(devices, devName, mountPoint)
+	if err != nil {
+		return err
+	}
+
+	res := WhichResult{
+		Device:      dev.Name,
+		UUID:        dev.UUID,
+		MountPoints: filterEmptyMountPoints(dev.MountPoints),
+	}
+
+	if dev.UUID != "" {
+		res.Path, _ = ctx.Tracker.LookupPathByUUID(dev.UUID)
+	} else {
+		res.Path, _ = ctx.Tracker.LookupPathByDevName(dev.Name)
+	}
+	if res.Path != "" {
+		res.Source = "tracking"
+		return printResult(ctx, res)
+	}
+
+	log.Info("/dev/%s is not tracked, asking Windows...", dev.Name)
+	matches, err := correlateWindowsVHD(ctx, dev.Name, devices, search)
+	if err != nil {
+		return &types.VHDError{Op: "which", Path: "/dev/" + dev.Name, Err: err}
+	}
+	switch len(matches) {
+	case 0:
+		return &types.VHDError{
+			Op:   "which",
+			Path: "/dev/" + dev.Name,
+			Err:  fmt.Errorf("no VHD file found for the device"),
+			Help: "Pass the directories holding your VHD files with --search",
+		}
+	case 1:
+		res.Path = matches[0]
+		res.Source = "windows"
+		return printResult(ctx, res)
+	default:
+		return &types.VHDError{
+			Op:   "which",
+			Path: "/dev/" + dev.Name,
+			Err:  fmt.Errorf("%d VHD files match the device: %s", len(matches), strings.Join(matches, ", ")),
+			Help: "Detach the others, or narrow --search",
+		}
+	}
+}
+
+// findWhichDevice returns the dynamically attached device named devName, or
+// the one mounted at mountPoint
+func findWhichDevice(devices []wsl.BlockDevice, devName, mountPoint string) (wsl.BlockDevice, error) {
+	for _, dev := range devices {
+		if devName != "" && dev.Name == devName {
+			if !wsl.IsDynamicDevice(dev.Name) {
+				return dev, &types.VHDError{Op: "which", Path: "/dev/" + devName, Err: fmt.Errorf("device is a WSL system disk")}
+			}
+			return dev, nil
+		}
+		if mountPoint != "" && slices.Contains(dev.MountPoints, filepath.Clean(mountPoint)) && wsl.IsDynamicDevice(dev.Name) {
+			return dev, nil
+		}
+	}
+	if devName != "" {
+		return wsl.BlockDevice{}, &types.VHDError{Op: "which", Path: "/dev/" + devName, Err: types.ErrDeviceNotFound}
+	}
+	return wsl.BlockDevice{}, &types.VHDError{Op: "which", Path: mountPoint, Err: fmt.Errorf("no attached VHD is mounted here")}
+}
+
+// correlateWindowsVHD returns the candidate VHD files whose virtual size
+// equals the size of /dev/devName and that Windows holds open. Candidates are
+// tracked VHDs not attached as another device, and the VHD files in the
+// search directories.
+func correlateWindowsVHD(ctx *AppContext, devName string, devices []wsl.BlockDevice, search []string) ([]string, error) {
+	log := ctx.Logger
+
+	devSize, err := ctx.WSL.GetDeviceSize(devName)
+	if err != nil {
+		return nil, err
+	}
+
+	attachedUUIDs := make(map[string]bool)
+	for _, dev := range devices {
+		if dev.UUID != "" {
+			attachedUUIDs[dev.UUID] = true
+		}
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if key := utils.NormalizePath(path); !seen[key] {
+			seen[key] = true
+			candidates = append(candidates, path)
+		}
+	}
+	paths, err := ctx.Tracker.GetAllPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked VHDs: %w", err)
+	}
+	for _, path := range paths {
+		entry, _ := ctx.Tracker.GetEntry(path)
+		if entry.UUID != "" && attachedUUIDs[entry.UUID] {
+			continue
+		}
+		add(path)
+	}
+	for _, dir := range search {
+		entries, err := os.ReadDir(ctx.WSL.ConvertPath(dir))
+		if err != nil {
+			log.Warn("Cannot read %s: %v", dir, err)
+			continue
+		}
+		for _, e := range entries {
+			ext := strings.ToLower(filepath.Ext(e.Name()))
+			if !e.IsDir() && (ext == ".vhd" || ext == ".vhdx") {
+				add(strings.TrimRight(dir, `/\`) + "/" + e.Name())
+			}
+		}
+	}
+	log.Debug("Correlating /dev/%s (%d bytes) with %d candidate file(s)", devName, devSize, len(candidates))
+
+	sizes, err := ctx.WSL.WindowsVHDSizes(candidates)
+	if errors.Is(err, wsl.ErrHyperVUnavailable) {
+		log.Debug("Get-VHD unavailable, reading sizes with qemu-img")
+		sizes = make(map[string]int64)
+		for _, path := range candidates {
+			if info, err := ctx.WSL.GetImageInfo(ctx.WSL.ConvertPath(path)); err == nil {
+				sizes[path] = info.VirtualSize
+			}
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, path := range candidates {
+		if size, ok := sizes[path]; !ok || size != devSize {
+			continue
+		}
+		if inUse, err := ctx.WSL.FileInUseByWindows(path); err != nil || !inUse {
+			log.Debug("%s has the device size but is not in use", path)
+			continue
+		}
+		matches = append(matches, path)
+	}
+	return matches, nil
+}
+
+// WhichResult is the outcome of which
+type WhichResult struct {
+	Device      string   `json:"device"`
+	UUID        string   `json:"uuid,omitempty"`
+	MountPoints []string `json:"mountPoints,omitempty"`
+	Path        string   `json:"path"`
+	Source      string   `json:"source"` // tracking or windows
+}
+
+func (r WhichResult) table(utils.Theme) (string, [][2]string) {
+	pairs := [][2]string{{"Device", "/dev/" + r.Device}}
+
+	if r.UUID != "" {
+		pairs = append(pairs, [2]string{"UUID", r.UUID})
+	}
+	if len(r.MountPoints) > 0 {
+		pairs = append(pairs, [2]string{"Mount Point", strings.Join(r.MountPoints, ", ")})
+	}
+	pairs = append(pairs, [2]string{"VHD Path", r.Path})
+	source := "tracking"
+	if r.Source == "windows" {
+		source = "Windows (size match of a file in use; not tracked)"
+	}
+	pairs = append(pairs, [2]string{"Found By", source})
+
+	return "VHD Backing File", pairs
+}
+
+func (r WhichResult) quiet() quietLine {
+	return quietLine{Key: r.Path, UUID: r.UUID, State: "attached as /dev/" + r.Device}
+}
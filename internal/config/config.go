@@ -2,9 +2,12 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,8 +18,37 @@ type Config struct {
 	Debug bool
 	Yes   bool
 
+	// Output format of command results: table, json, yaml, csv or sh
+	Output string
+	// Go template for each result, overriding Output (--format)
+	Format string
+
+	// Theme of status colors and symbols (see utils.ParseTheme)
+	Theme string
+
+	// Timestamp format of displayed times and log lines (see utils.TimeFormats)
+	TimeFormat    string
+	LogTimestamps bool
+
+	// Profile selecting a separate tracking file and VHDM_<PROFILE>_* settings,
+	// empty for the default profile
+	Profile string
+
 	// Paths
 	TrackingFile string
+	Helper       string // vhdm-helper path, "off" to run privileged steps under sudo directly
+
+	// Base directories of relative VHD paths: "" for "disk.vhdx", "D:" for
+	// the drive-relative "D:disk.vhdx" (see utils.ResolveVHDPath)
+	BaseDirs map[string]string
+
+	// Directory of the systemd units created by 'vhdm service'
+	UnitDir string
+
+	// Resize staging: Linux directory for the temporary mount points and
+	// Windows directory for the intermediate *_new VHD (default: next to the VHD)
+	ResizeTempDir    string
+	ResizeStagingDir string
 
 	// Timeouts
 	SleepAfterAttach time.Duration
@@ -26,50 +58,147 @@ type Config struct {
 	DefaultVHDSize string
 	DefaultFSType  string
 	HistoryLimit   int
+
+	// Safety
+	ConfirmNameAbove string // Size above which delete/format ask for the VHD name ("0" disables)
+
+	// Desktop notification of mount failures: off, auto, toast or notify-send
+	Notify string
 }
 
-// Load loads configuration from environment
-func Load() (*Config, error) {
+// profileRe matches valid profile names
+var profileRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Load loads configuration from environment for a profile, or for
+// $VHDM_PROFILE when profile is empty. Within a profile, VHDM_<PROFILE>_<KEY>
+// overrides VHDM_<KEY> (e.g. VHDM_WORK_DEFAULT_SIZE for profile "work").
+func Load(profile string) (*Config, error) {
+	if profile == "" {
+		profile = os.Getenv("VHDM_PROFILE")
+	}
+	if profile != "" && !profileRe.MatchString(profile) {
+		return nil, fmt.Errorf("invalid profile name: %q (use lowercase letters, digits, '-' and '_')", profile)
+	}
+	env := envSource{profile: profile}
+
 	cfg := &Config{
-		Quiet:            envBool("VHDM_QUIET", false),
-		Debug:            envBool("VHDM_DEBUG", false),
-		Yes:              envBool("VHDM_YES", false),
-		SleepAfterAttach: time.Duration(envInt("VHDM_SLEEP_AFTER_ATTACH", 2)) * time.Second,
-		DetachTimeout:    time.Duration(envInt("VHDM_DETACH_TIMEOUT", 30)) * time.Second,
-		DefaultVHDSize:   envStr("VHDM_DEFAULT_SIZE", "1G"),
-		DefaultFSType:    envStr("VHDM_DEFAULT_FSTYPE", "ext4"),
-		HistoryLimit:     envInt("VHDM_HISTORY_LIMIT", 10),
+		Profile:          profile,
+		Quiet:            env.boolVal("VHDM_QUIET", false),
+		Debug:            env.boolVal("VHDM_DEBUG", false),
+		Yes:              env.boolVal("VHDM_YES", false),
+		Output:           env.strVal("VHDM_OUTPUT", "table"),
+		Theme:            env.strVal("VHDM_THEME", defaultTheme()),
+		TimeFormat:       env.strVal("VHDM_TIME_FORMAT", "local"),
+		LogTimestamps:    env.boolVal("VHDM_LOG_TIMESTAMPS", false),
+		SleepAfterAttach: time.Duration(env.intVal("VHDM_SLEEP_AFTER_ATTACH", 2)) * time.Second,
+		DetachTimeout:    time.Duration(env.intVal("VHDM_DETACH_TIMEOUT", 30)) * time.Second,
+		DefaultVHDSize:   env.strVal("VHDM_DEFAULT_SIZE", "1G"),
+		DefaultFSType:    env.strVal("VHDM_DEFAULT_FSTYPE", "ext4"),
+		HistoryLimit:     env.intVal("VHDM_HISTORY_LIMIT", 10),
+		ConfirmNameAbove: env.strVal("VHDM_CONFIRM_NAME_ABOVE", "100G"),
+		Helper:           env.strVal("VHDM_HELPER", ""),
+		UnitDir:          env.strVal("VHDM_UNIT_DIR", "/etc/systemd/system"),
+		ResizeTempDir:    env.strVal("VHDM_RESIZE_TEMP_DIR", ""),
+		ResizeStagingDir: env.strVal("VHDM_RESIZE_STAGING_DIR", ""),
+		Notify:           env.strVal("VHDM_NOTIFY", "off"),
 	}
 
-	// Set default tracking file path
+	cfg.BaseDirs = map[string]string{"": env.strVal("VHDM_BASE_DIR", "")}
+	for drive := 'A'; drive <= 'Z'; drive++ {
+		if dir := env.get("VHDM_BASE_DIR_" + string(drive)); dir != "" {
+			cfg.BaseDirs[string(drive)+":"] = dir
+		}
+	}
+
+	// Set default tracking file path, one per profile
 	// When running with sudo, use the original user's home directory
 	home := getUserHomeDir()
 	defaultTrackingFile := filepath.Join(home, ".config", "vhdm", "vhd_tracking.json")
-	cfg.TrackingFile = envStr("VHDM_TRACKING_FILE", defaultTrackingFile)
+	if profile != "" {
+		defaultTrackingFile = filepath.Join(profilesDir(), profile, "vhd_tracking.json")
+		// A VHDM_TRACKING_FILE meant for the default profile must not leak
+		// into another profile
+		cfg.TrackingFile = env.profileValue("VHDM_TRACKING_FILE")
+		if cfg.TrackingFile == "" {
+			cfg.TrackingFile = defaultTrackingFile
+		}
+	} else {
+		cfg.TrackingFile = env.strVal("VHDM_TRACKING_FILE", defaultTrackingFile)
+	}
 
 	return cfg, nil
 }
 
-func (c *Config) SetQuiet(v bool) { c.Quiet = v }
-func (c *Config) SetDebug(v bool) { c.Debug = v }
-func (c *Config) SetYes(v bool)   { c.Yes = v }
+// profilesDir holds the tracking files of the non-default profiles
+func profilesDir() string {
+	return filepath.Join(getUserHomeDir(), ".config", "vhdm", "profiles")
+}
+
+// ProfileNames returns the profiles that have a tracking directory
+func ProfileNames() []string {
+	entries, _ := os.ReadDir(profilesDir())
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && profileRe.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// defaultTheme is "mono" when NO_COLOR is set (https://no-color.org)
+func defaultTheme() string {
+	if os.Getenv("NO_COLOR") != "" {
+		return "mono"
+	}
+	return "default"
+}
+
+func (c *Config) SetQuiet(v bool)    { c.Quiet = v }
+func (c *Config) SetDebug(v bool)    { c.Debug = v }
+func (c *Config) SetYes(v bool)      { c.Yes = v }
+func (c *Config) SetOutput(v string) { c.Output = v }
+func (c *Config) SetFormat(v string) { c.Format = v }
+
+// envSource reads settings from the environment, preferring the
+// VHDM_<PROFILE>_* section of the selected profile
+type envSource struct {
+	profile string
+}
+
+// profileValue returns the profile section's value of key, e.g.
+// VHDM_WORK_HELPER for VHDM_HELPER in profile "work"
+func (e envSource) profileValue(key string) string {
+	if e.profile == "" {
+		return ""
+	}
+	section := strings.ToUpper(strings.ReplaceAll(e.profile, "-", "_"))
+	return os.Getenv("VHDM_" + section + "_" + strings.TrimPrefix(key, "VHDM_"))
+}
+
+func (e envSource) get(key string) string {
+	if v := e.profileValue(key); v != "" {
+		return v
+	}
+	return os.Getenv(key)
+}
 
-func envStr(key, def string) string {
-	if v := os.Getenv(key); v != "" {
+func (e envSource) strVal(key, def string) string {
+	if v := e.get(key); v != "" {
 		return v
 	}
 	return def
 }
 
-func envBool(key string, def bool) bool {
-	if v := os.Getenv(key); v != "" {
+func (e envSource) boolVal(key string, def bool) bool {
+	if v := e.get(key); v != "" {
 		return v == "1" || v == "true" || v == "yes"
 	}
 	return def
 }
 
-func envInt(key string, def int) int {
-	if v := os.Getenv(key); v != "" {
+func (e envSource) intVal(key string, def int) int {
+	if v := e.get(key); v != "" {
 		if i, err := strconv.Atoi(v); err == nil {
 			return i
 		}
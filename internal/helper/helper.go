@@ -0,0 +1,454 @@
+// Package helper defines the verbs of vhdm-helper, the small binary that runs
+// the steps of vhdm needing root. Each verb maps validated arguments to one
+// fixed command line, so a sudoers rule for vhdm-helper grants those commands
+// and nothing else, and vhdm itself can run unprivileged. Before running a
+// verb the helper also checks its arguments against the host (see Check), so
+// it only ever touches attached VHDs and their mount points.
+package helper
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/rjdinis/vhdm/internal/validation"
+)
+
+// Name is the file name of the helper binary
+const Name = "vhdm-helper"
+
+// IDFileName is the file vhdm writes at the root of the filesystems it
+// formats to identify their VHD (see the write-id verb)
+const IDFileName = ".vhdm.json"
+
+var (
+	// Filesystem UUIDs: ext4/xfs/btrfs UUIDs and the shorter vfat/ntfs serials
+	uuidRe = regexp.MustCompile(`^[0-9A-Fa-f]+(-[0-9A-Fa-f]+)*$`)
+	// Mount options (-o): comma separated words, no leading dash
+	optionsRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_=,.:+/-]*$`)
+	// Filesystem type for mount -t (e.g., xfs, btrfs, vfat)
+	fsTypeRe = regexp.MustCompile(`^[a-z0-9]+$`)
+	// User names accepted by chown
+	userRe = regexp.MustCompile(`^[a-z_][a-z0-9_.-]*\$?$`)
+)
+
+// maxDepth bounds the depth arguments of du and find
+const maxDepth = 1000
+
+// verb describes one helper verb
+type verb struct {
+	usage   string
+	minArgs int
+	maxArgs int
+	build   func(args []string) ([]string, error)
+	// check validates the arguments against the host (see Check)
+	check func(h *Host, args []string) error
+}
+
+// devices checks that the arguments at the given positions are attached,
+// non-system VHD devices
+func devices(at ...int) func(h *Host, args []string) error {
+	return func(h *Host, args []string) error {
+		for _, i := range at {
+			if err := h.vhdDevice(args[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// mountPoints checks that the arguments at the given positions are mount
+// points of attached, non-system VHDs
+func mountPoints(at ...int) func(h *Host, args []string) error {
+	return func(h *Host, args []string) error {
+		for _, i := range at {
+			if err := h.vhdMountPoint(args[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+var verbs = map[string]verb{
+	"enable-interop": {"", 0, 0, func(args []string) ([]string, error) {
+		return []string{"sh", "-c", `echo ":WSLInterop:M::MZ::/init:PF" > /proc/sys/fs/binfmt_misc/register`}, nil
+	}, nil},
+	"blkid-uuid": {"DEVICE", 1, 1, func(args []string) ([]string, error) {
+		dev, err := device(args[0])
+		return []string{"blkid", "-s", "UUID", "-o", "value", dev}, err
+	}, nil},
+	"blkid-type": {"DEVICE", 1, 1, func(args []string) ([]string, error) {
+		dev, err := device(args[0])
+		return []string{"blkid", "-s", "TYPE", "-o", "value", dev}, err
+	}, nil},
+	"mkfs": {"FSTYPE DEVICE", 2, 2, func(args []string) ([]string, error) {
+		if err := validation.ValidateFilesystemType(args[0]); err != nil {
+			return nil, err
+		}
+		dev, err := device(args[1])
+		return []string{"mkfs", "-t", args[0], dev}, err
+	}, devices(1)},
+	"e2fsck": {"DEVICE", 1, 1, func(args []string) ([]string, error) {
+		dev, err := device(args[0])
+		return []string{"e2fsck", "-f", "-p", dev}, err
+	}, devices(0)},
+	"resize2fs": {"DEVICE", 1, 1, func(args []string) ([]string, error) {
+		dev, err := device(args[0])
+		return []string{"resize2fs", dev}, err
+	}, devices(0)},
+	"grow-fs": {"FSTYPE DIR", 2, 2, func(args []string) ([]string, error) {
+		dir, err := mountPoint(args[1])
+		if err != nil {
+			return nil, err
+		}
+		switch args[0] {
+		case "xfs":
+			return []string{"xfs_growfs", dir}, nil
+		case "btrfs":
+			return []string{"btrfs", "filesystem", "resize", "max", dir}, nil
+		}
+		return nil, fmt.Errorf("cannot grow a mounted %q filesystem", args[0])
+	}, mountPoints(1)},
+	"fsck-ro": {"FSTYPE DEVICE", 2, 2, func(args []string) ([]string, error) {
+		dev, err := device(args[1])
+		if err != nil {
+			return nil, err
+		}
+		switch args[0] {
+		case "ext2", "ext3", "ext4":
+			return []string{"e2fsck", "-f", "-n", dev}, nil
+		case "xfs":
+			return []string{"xfs_repair", "-n", dev}, nil
+		case "btrfs":
+			return []string{"btrfs", "check", "--readonly", dev}, nil
+		case "vfat":
+			return []string{"fsck.vfat", "-n", dev}, nil
+		}
+		return nil, fmt.Errorf("cannot check a %q filesystem read-only", args[0])
+	}, devices(1)},
+	"new-uuid": {"FSTYPE DEVICE", 2, 2, func(args []string) ([]string, error) {
+		dev, err := device(args[1])
+		if err != nil {
+			return nil, err
+		}
+		switch args[0] {
+		case "ext2", "ext3", "ext4":
+			return []string{"tune2fs", "-U", "random", dev}, nil
+		case "xfs":
+			return []string{"xfs_admin", "-U", "generate", dev}, nil
+		case "btrfs":
+			return []string{"btrfstune", "-f", "-u", dev}, nil
+		}
+		return nil, fmt.Errorf("cannot change the UUID of a %q filesystem", args[0])
+	}, devices(1)},
+	"dd": {"SOURCE DEVICE", 2, 2, func(args []string) ([]string, error) {
+		src, err := device(args[0])
+		if err != nil {
+			return nil, err
+		}
+		dst, err := device(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if src == dst {
+			return nil, fmt.Errorf("source and target are the same device: %s", src)
+		}
+		return []string{"dd", "if=" + src, "of=" + dst, "bs=4M", "conv=sparse,fsync", "status=none"}, nil
+	}, devices(0, 1)},
+	"mount": {"UUID DIR [OPTIONS]", 2, 3, func(args []string) ([]string, error) {
+		return mountCommand(nil, args)
+	}, func(h *Host, args []string) error {
+		if err := h.uuidDevice(args[0]); err != nil {
+			return err
+		}
+		return h.mountTarget(args[1])
+	}},
+	"mount-type": {"FSTYPE UUID DIR [OPTIONS]", 3, 4, func(args []string) ([]string, error) {
+		if !fsTypeRe.MatchString(args[0]) {
+			return nil, fmt.Errorf("invalid filesystem type: %q", args[0])
+		}
+		return mountCommand([]string{"-t", args[0]}, args[1:])
+	}, func(h *Host, args []string) error {
+		if err := h.uuidDevice(args[1]); err != nil {
+			return err
+		}
+		return h.mountTarget(args[2])
+	}},
+	"bind": {"SOURCE DIR", 2, 2, func(args []string) ([]string, error) {
+		src, err := path(args[0])
+		if err != nil {
+			return nil, err
+		}
+		dir, err := mountDir(args[1])
+		return []string{"mount", "--bind", "-o", "nosuid,nodev", src, dir}, err
+	}, func(h *Host, args []string) error {
+		if err := h.vhdMountPoint(args[0]); err != nil {
+			return err
+		}
+		return h.mountTarget(args[1])
+	}},
+	"umount": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := mountDir(args[0])
+		return []string{"umount", dir}, err
+	}, mountPoints(0)},
+	"umount-lazy": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := mountDir(args[0])
+		return []string{"umount", "-l", dir}, err
+	}, mountPoints(0)},
+	"fsfreeze": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := mountDir(args[0])
+		return []string{"fsfreeze", "--freeze", dir}, err
+	}, mountPoints(0)},
+	"fsthaw": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := mountDir(args[0])
+		return []string{"fsfreeze", "--unfreeze", dir}, err
+	}, mountPoints(0)},
+	"write-id": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		// install replaces the file rather than writing through a symlink
+		dir, err := mountPoint(args[0])
+		return []string{"install", "-m", "644", "/dev/stdin", filepath.Join(dir, IDFileName)}, err
+	}, mountPoints(0)},
+	"fstrim": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := mountDir(args[0])
+		return []string{"fstrim", dir}, err
+	}, mountPoints(0)},
+	"mkdir": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := mountDir(args[0])
+		return []string{"mkdir", "-p", "-m", "755", dir}, err
+	}, func(h *Host, args []string) error {
+		return h.newDir(args[0])
+	}},
+	"chmod": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := mountDir(args[0])
+		return []string{"chmod", "755", dir}, err
+	}, mountPoints(0)},
+	"chown": {"USER DIR", 2, 2, func(args []string) ([]string, error) {
+		if !userRe.MatchString(args[0]) {
+			return nil, fmt.Errorf("invalid user name: %q", args[0])
+		}
+		dir, err := mountDir(args[1])
+		return []string{"chown", args[0] + ":" + args[0], dir}, err
+	}, func(h *Host, args []string) error {
+		if err := h.owner(args[0]); err != nil {
+			return err
+		}
+		return h.vhdMountPoint(args[1])
+	}},
+	"lsof": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := path(args[0])
+		return []string{"lsof", "+D", dir}, err
+	}, mountPoints(0)},
+	"count-files": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := path(args[0])
+		return []string{"find", dir, "-type", "f"}, err
+	}, mountPoints(0)},
+	"du": {"DEPTH DIR", 2, 2, func(args []string) ([]string, error) {
+		n, err := depth(args[0])
+		if err != nil {
+			return nil, err
+		}
+		dir, err := path(args[1])
+		return []string{"du", "-x", "-b", "--max-depth=" + strconv.Itoa(n), dir}, err
+	}, mountPoints(1)},
+	"find": {"DIR MAXDEPTH PATTERN", 3, 3, func(args []string) ([]string, error) {
+		dir, err := path(args[0])
+		if err != nil {
+			return nil, err
+		}
+		n, err := depth(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if args[2] == "" || strings.HasPrefix(args[2], "-") || strings.Contains(args[2], "/") {
+			return nil, fmt.Errorf("invalid name pattern: %q", args[2])
+		}
+		argv := []string{"find", dir, "-xdev"}
+		if n > 0 {
+			argv = append(argv, "-maxdepth", strconv.Itoa(n))
+		}
+		return append(argv, "-iname", args[2], "-print"), nil
+	}, mountPoints(0)},
+	"rsync": {"SOURCE DEST [--delete]", 2, 3, func(args []string) ([]string, error) {
+		src, err := path(args[0])
+		if err != nil {
+			return nil, err
+		}
+		dst, err := path(args[1])
+		if err != nil {
+			return nil, err
+		}
+		argv := []string{"rsync", "-aHAX", "--info=progress2"}
+		if len(args) == 3 {
+			if args[2] != "--delete" {
+				return nil, fmt.Errorf("unknown rsync option: %q", args[2])
+			}
+			argv = append(argv, "--delete")
+		}
+		return append(argv, src+"/", dst+"/"), nil
+	}, mountPoints(0, 1)},
+	"rsync-compare": {"SOURCE DEST", 2, 2, func(args []string) ([]string, error) {
+		src, err := path(args[0])
+		if err != nil {
+			return nil, err
+		}
+		dst, err := path(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return []string{"rsync", "-aHAX", "--checksum", "--dry-run", "--itemize-changes", "--delete", src + "/", dst + "/"}, nil
+	}, mountPoints(0, 1)},
+	"tar-create": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := path(args[0])
+		return []string{"tar", "-C", dir, "--numeric-owner", "-cf", "-", "."}, err
+	}, mountPoints(0)},
+	"tar-extract": {"DIR", 1, 1, func(args []string) ([]string, error) {
+		dir, err := path(args[0])
+		return []string{"tar", "-C", dir, "--numeric-owner", "-xpf", "-"}, err
+	}, mountPoints(0)},
+}
+
+// Command validates a verb and its arguments and returns the command line it
+// runs. Unknown verbs and arguments outside the verb's narrow shape are
+// rejected.
+func Command(name string, args []string) ([]string, error) {
+	v, ok := verbs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown verb: %q", name)
+	}
+	if len(args) < v.minArgs || len(args) > v.maxArgs {
+		return nil, fmt.Errorf("usage: %s %s %s", Name, name, v.usage)
+	}
+	return v.build(args)
+}
+
+// Usage returns the list of verbs with their arguments, one per line
+func Usage() string {
+	names := make([]string, 0, len(verbs))
+	for name := range verbs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(strings.TrimSpace("  " + name + " " + verbs[name].usage))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Locate returns the absolute path of the helper binary, or "" when vhdm
+// should run commands under sudo directly. override is VHDM_HELPER: a path
+// to use, or "off" to disable the helper. Otherwise the helper is looked up
+// next to the running executable, then in PATH.
+func Locate(override string) string {
+	switch override {
+	case "off":
+		return ""
+	case "":
+	default:
+		if abs, err := filepath.Abs(override); err == nil {
+			return abs
+		}
+		return override
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		candidate := filepath.Join(filepath.Dir(exe), Name)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	if p, err := exec.LookPath(Name); err == nil {
+		if abs, err := filepath.Abs(p); err == nil {
+			return abs
+		}
+	}
+	return ""
+}
+
+// mountCommand builds "mount [flags] -o [OPTIONS,]nosuid,nodev UUID=<uuid> DIR"
+// from the UUID DIR [OPTIONS] arguments of the mount verbs
+func mountCommand(flags, args []string) ([]string, error) {
+	if !uuidRe.MatchString(args[0]) {
+		return nil, fmt.Errorf("invalid UUID: %q", args[0])
+	}
+	dir, err := mountDir(args[1])
+	if err != nil {
+		return nil, err
+	}
+	// nosuid,nodev come last so they override suid or dev in OPTIONS
+	options := "nosuid,nodev"
+	if len(args) == 3 {
+		if !optionsRe.MatchString(args[2]) {
+			return nil, fmt.Errorf("invalid mount options: %q", args[2])
+		}
+		options = args[2] + "," + options
+	}
+	argv := append([]string{"mount"}, flags...)
+	return append(argv, "-o", options, "UUID="+args[0], dir), nil
+}
+
+// device validates a block device name (sdX, with or without /dev/) and
+// returns its /dev path
+func device(name string) (string, error) {
+	if err := validation.ValidateDeviceName(name); err != nil {
+		return "", fmt.Errorf("%w: %q", err, name)
+	}
+	return "/dev/" + strings.TrimPrefix(name, "/dev/"), nil
+}
+
+// path validates an absolute path without ".." components and returns it
+// cleaned
+func path(p string) (string, error) {
+	if !filepath.IsAbs(p) {
+		return "", fmt.Errorf("path must be absolute: %q", p)
+	}
+	if strings.ContainsRune(p, 0) || slices.Contains(strings.Split(p, "/"), "..") {
+		return "", fmt.Errorf("invalid path: %q", p)
+	}
+	return filepath.Clean(p), nil
+}
+
+// mountDir validates a directory that is mounted on or changed; "/" is refused
+func mountDir(p string) (string, error) {
+	dir, err := path(p)
+	if err != nil {
+		return "", err
+	}
+	if dir == "/" {
+		return "", fmt.Errorf("refusing to operate on /")
+	}
+	return dir, nil
+}
+
+// mountPoint validates a directory that must be the root of a mounted
+// filesystem, as opposed to any directory files may be written to
+func mountPoint(p string) (string, error) {
+	dir, err := mountDir(p)
+	if err != nil {
+		return "", err
+	}
+	var st, parent syscall.Stat_t
+	if syscall.Lstat(dir, &st) != nil || syscall.Lstat(filepath.Dir(dir), &parent) != nil || st.Dev == parent.Dev {
+		return "", fmt.Errorf("not a mount point: %q", p)
+	}
+	return dir, nil
+}
+
+// depth parses a du/find depth argument
+func depth(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > maxDepth {
+		return 0, fmt.Errorf("invalid depth: %q", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,190 @@
+package helper
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		verb string
+		args []string
+		want string
+	}{
+		{"blkid-uuid", []string{"sdd"}, "blkid -s UUID -o value /dev/sdd"},
+		{"blkid-type", []string{"/dev/sde"}, "blkid -s TYPE -o value /dev/sde"},
+		{"mkfs", []string{"ext4", "sdd"}, "mkfs -t ext4 /dev/sdd"},
+		{"e2fsck", []string{"sdd"}, "e2fsck -f -p /dev/sdd"},
+		{"resize2fs", []string{"sdd"}, "resize2fs /dev/sdd"},
+		{"dd", []string{"sdd", "sde"}, "dd if=/dev/sdd of=/dev/sde bs=4M conv=sparse,fsync status=none"},
+		{"fsck-ro", []string{"ext4", "sdd"}, "e2fsck -f -n /dev/sdd"},
+		{"fsck-ro", []string{"xfs", "/dev/sdd"}, "xfs_repair -n /dev/sdd"},
+		{"new-uuid", []string{"ext4", "sdd"}, "tune2fs -U random /dev/sdd"},
+		{"new-uuid", []string{"xfs", "/dev/sdd"}, "xfs_admin -U generate /dev/sdd"},
+		{"mount", []string{"57fd0f3a-4077-44b8-91ba-5abdee575293", "/mnt/data"}, "mount -o nosuid,nodev UUID=57fd0f3a-4077-44b8-91ba-5abdee575293 /mnt/data"},
+		{"mount", []string{"ABCD-1234", "/mnt/data", "ro,noatime"}, "mount -o ro,noatime,nosuid,nodev UUID=ABCD-1234 /mnt/data"},
+		{"mount-type", []string{"xfs", "ABCD-1234", "/mnt/data", "noatime"}, "mount -t xfs -o noatime,nosuid,nodev UUID=ABCD-1234 /mnt/data"},
+		{"bind", []string{"/mnt/data", "/srv/data/"}, "mount --bind -o nosuid,nodev /mnt/data /srv/data"},
+		{"umount-lazy", []string{"/mnt/data"}, "umount -l /mnt/data"},
+		{"fsfreeze", []string{"/mnt/data"}, "fsfreeze --freeze /mnt/data"},
+		{"fsthaw", []string{"/mnt/data/"}, "fsfreeze --unfreeze /mnt/data"},
+		{"fstrim", []string{"/mnt/data"}, "fstrim /mnt/data"},
+		{"chown", []string{"alice", "/mnt/data"}, "chown alice:alice /mnt/data"},
+		{"du", []string{"2", "/mnt/data"}, "du -x -b --max-depth=2 /mnt/data"},
+		{"find", []string{"/mnt/data", "0", "*.log"}, "find /mnt/data -xdev -iname *.log -print"},
+		{"find", []string{"/mnt/data", "3", "*.log"}, "find /mnt/data -xdev -maxdepth 3 -iname *.log -print"},
+		{"rsync", []string{"/mnt/a/", "/mnt/b", "--delete"}, "rsync -aHAX --info=progress2 --delete /mnt/a/ /mnt/b/"},
+		{"rsync-compare", []string{"/mnt/a", "/mnt/b"}, "rsync -aHAX --checksum --dry-run --itemize-changes --delete /mnt/a/ /mnt/b/"},
+		{"tar-extract", []string{"/mnt/data"}, "tar -C /mnt/data --numeric-owner -xpf -"},
+	}
+
+	for _, tt := range tests {
+		got, err := Command(tt.verb, tt.args)
+		if err != nil {
+			t.Errorf("Command(%q, %q) error: %v", tt.verb, tt.args, err)
+			continue
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("Command(%q, %q) = %q, want %q", tt.verb, tt.args, strings.Join(got, " "), tt.want)
+		}
+	}
+}
+
+func TestCommandRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		verb string
+		args []string
+	}{
+		{"unknown verb", "rm", []string{"/"}},
+		{"missing args", "mount", []string{"ABCD-1234"}},
+		{"extra args", "umount", []string{"/mnt/a", "/mnt/b"}},
+		{"bad device", "mkfs", []string{"ext4", "nvme0n1"}},
+		{"bad fstype", "mkfs", []string{"ntfs", "sdd"}},
+		{"option injection in UUID", "mount", []string{"--bind", "/mnt/a"}},
+		{"option injection in options", "mount", []string{"ABCD-1234", "/mnt/a", "-t"}},
+		{"relative path", "umount", []string{"mnt/a"}},
+		{"path traversal", "chmod", []string{"/mnt/../etc"}},
+		{"mount on root", "mount", []string{"ABCD-1234", "/"}},
+		{"option injection in fstype", "mount-type", []string{"-oremount", "ABCD-1234", "/mnt/a"}},
+		{"bad user", "chown", []string{"root:root", "/mnt/a"}},
+		{"bad depth", "du", []string{"-1", "/mnt/a"}},
+		{"find option as pattern", "find", []string{"/mnt/a", "0", "-delete"}},
+		{"unknown rsync flag", "rsync", []string{"/mnt/a", "/mnt/b", "--remove-source-files"}},
+		{"dd onto the source", "dd", []string{"sdd", "/dev/sdd"}},
+		{"new UUID of unsupported fstype", "new-uuid", []string{"vfat", "sdd"}},
+		{"read-only check of unsupported fstype", "fsck-ro", []string{"ntfs", "sdd"}},
+		{"id file outside a mount point", "write-id", []string{"/etc"}},
+		{"grow outside a mount point", "grow-fs", []string{"xfs", "/etc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Command(tt.verb, tt.args); err == nil {
+				t.Errorf("Command(%q, %q) = %q, want error", tt.verb, tt.args, got)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	host := &Host{
+		Mounts: []Mount{
+			{"/dev/sdc", "/"},
+			{"/dev/sdd", "/mnt/data"},
+			{"/dev/sdd", "/srv/data"},
+			{"/dev/sde", "/mnt/b"},
+			{"tmpfs", "/tmp"},
+		},
+		Swaps:   []string{"sdf"},
+		Disks:   []string{"sda", "sdb", "sdc", "sdd", "sde", "sdf", "sdg"},
+		UUIDs:   map[string]string{"ABCD-1234": "sdg", "1111-2222": "sdc"},
+		SudoUID: "1000",
+		Stat: func(p string) (PathInfo, error) {
+			switch p {
+			case "/mnt/new":
+				return PathInfo{IsDir: true, Empty: true, UID: "1000"}, nil
+			case "/mnt/full":
+				return PathInfo{IsDir: true, UID: "1000"}, nil
+			case "/home/bob/mnt":
+				return PathInfo{IsDir: true, Empty: true, UID: "1001"}, nil
+			case "/etc/sudoers.d":
+				return PathInfo{IsDir: true, Empty: true, UID: "0"}, nil
+			}
+			return PathInfo{}, os.ErrNotExist
+		},
+		LookupUID: func(name string) (string, error) {
+			return map[string]string{"alice": "1000", "bob": "1001"}[name], nil
+		},
+	}
+
+	allowed := []struct {
+		verb string
+		args []string
+	}{
+		{"mkfs", []string{"ext4", "sdg"}},
+		{"dd", []string{"sdd", "/dev/sdg"}},
+		{"mount", []string{"ABCD-1234", "/mnt/new", "ro"}},
+		{"bind", []string{"/mnt/data", "/mnt/new"}},
+		{"umount", []string{"/srv/data"}},
+		{"chown", []string{"alice", "/mnt/data"}},
+		{"tar-extract", []string{"/mnt/data/"}},
+		{"rsync", []string{"/mnt/data", "/mnt/b", "--delete"}},
+		{"mkdir", []string{"/mnt/other/data"}},
+	}
+	for _, tt := range allowed {
+		if err := Check(tt.verb, tt.args, host); err != nil {
+			t.Errorf("Check(%q, %q) error: %v", tt.verb, tt.args, err)
+		}
+	}
+
+	refused := []struct {
+		name string
+		verb string
+		args []string
+	}{
+		{"dd onto the root disk", "dd", []string{"sdd", "sdc"}},
+		{"dd onto a WSL system disk", "dd", []string{"sdd", "sda"}},
+		{"dd from the root disk", "dd", []string{"sdc", "sdg"}},
+		{"mkfs on swap", "mkfs", []string{"ext4", "sdf"}},
+		{"fsck of a detached device", "e2fsck", []string{"sdh"}},
+		{"new UUID on the root disk", "new-uuid", []string{"ext4", "sdc"}},
+		{"extract into /", "tar-extract", []string{"/"}},
+		{"extract into a system directory", "tar-extract", []string{"/etc"}},
+		{"archive a non-VHD mount", "tar-create", []string{"/tmp"}},
+		{"read a plain directory", "du", []string{"1", "/home/alice"}},
+		{"chown /etc", "chown", []string{"alice", "/etc"}},
+		{"chown to another user", "chown", []string{"bob", "/mnt/data"}},
+		{"bind over /etc", "bind", []string{"/mnt/data", "/etc"}},
+		{"bind from a plain directory", "bind", []string{"/tmp/x", "/mnt/new"}},
+		{"mount over a system directory", "mount", []string{"ABCD-1234", "/etc/sudoers.d"}},
+		{"mount over a non-empty directory", "mount", []string{"ABCD-1234", "/mnt/full"}},
+		{"mount on another user's directory", "mount", []string{"ABCD-1234", "/home/bob/mnt"}},
+		{"mount the root disk", "mount", []string{"1111-2222", "/mnt/new"}},
+		{"rsync --delete into a plain directory", "rsync", []string{"/mnt/data", "/home/alice", "--delete"}},
+		{"rsync-compare of a plain directory", "rsync-compare", []string{"/home/alice", "/mnt/b"}},
+		{"mkdir of an existing directory", "mkdir", []string{"/mnt/new"}},
+		{"chmod of a plain directory", "chmod", []string{"/home/alice"}},
+	}
+	for _, tt := range refused {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Check(tt.verb, tt.args, host); err == nil {
+				t.Errorf("Check(%q, %q) = nil, want error", tt.verb, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseMountInfo(t *testing.T) {
+	input := "24 1 8:32 / / rw,relatime - ext4 /dev/sdc rw\n" +
+		"90 24 8:48 / /mnt/my\\040data rw,relatime shared:1 - ext4 /dev/sdd rw\n"
+	mounts, err := parseMountInfo(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Mount{{"/dev/sdc", "/"}, {"/dev/sdd", "/mnt/my data"}}
+	if len(mounts) != len(want) || mounts[0] != want[0] || mounts[1] != want[1] {
+		t.Errorf("parseMountInfo() = %v, want %v", mounts, want)
+	}
+}
@@ -0,0 +1,319 @@
+package helper
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// vhdDeviceRe matches the devices WSL gives dynamically attached VHDs; sda to
+// sdc hold the WSL system distribution, swap and the distribution itself
+var vhdDeviceRe = regexp.MustCompile(`^sd[d-z][a-z]*$`)
+
+// systemDirs are the directories nothing may be mounted on or below: a VHD
+// mounted there would replace configuration or binaries root relies on
+var systemDirs = []string{
+	"/bin", "/boot", "/dev", "/etc", "/lib", "/lib32", "/lib64", "/libx32",
+	"/proc", "/root", "/run", "/sbin", "/sys", "/usr", "/var",
+}
+
+// Mount is one entry of the mount table
+type Mount struct {
+	Source string
+	Target string
+}
+
+// PathInfo describes a path as seen by Host.Stat
+type PathInfo struct {
+	IsDir bool
+	Empty bool
+	UID   string
+}
+
+// Host is the state of the machine the arguments of a verb are checked
+// against before vhdm-helper runs it as root. Command only checks the shape of
+// arguments; Check makes sure they name attached VHDs and their mount points
+// rather than the system disks or arbitrary directories.
+type Host struct {
+	// Mounts is the mount table, from /proc/self/mountinfo
+	Mounts []Mount
+	// Swaps lists the swap devices, from /proc/swaps
+	Swaps []string
+	// Disks lists the attached block devices, from /sys/block
+	Disks []string
+	// UUIDs maps filesystem UUIDs to their devices, from /dev/disk/by-uuid
+	UUIDs map[string]string
+	// SudoUID is the uid of the user running the helper through sudo, or ""
+	// when root runs it directly
+	SudoUID string
+	// Stat describes a path without following symlinks
+	Stat func(path string) (PathInfo, error)
+	// LookupUID returns the uid of a user name
+	LookupUID func(name string) (string, error)
+}
+
+// LoadHost reads the state of the running system
+func LoadHost() (*Host, error) {
+	h := &Host{
+		UUIDs:     map[string]string{},
+		SudoUID:   os.Getenv("SUDO_UID"),
+		Stat:      statPath,
+		LookupUID: lookupUID,
+	}
+
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	h.Mounts, err = parseMountInfo(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := os.ReadFile("/proc/swaps"); err == nil {
+		h.Swaps = parseSwaps(string(data))
+	}
+
+	entries, err := os.ReadDir("/sys/block")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		h.Disks = append(h.Disks, e.Name())
+	}
+
+	links, _ := os.ReadDir("/dev/disk/by-uuid")
+	for _, l := range links {
+		if target, err := os.Readlink(filepath.Join("/dev/disk/by-uuid", l.Name())); err == nil {
+			h.UUIDs[l.Name()] = filepath.Base(target)
+		}
+	}
+	return h, nil
+}
+
+// Check validates a verb and its arguments like Command, then checks them
+// against the host: devices must be attached, non-system VHDs, directories
+// the mount points of such VHDs, and new mounts must land on an empty
+// directory outside the system directories.
+func Check(name string, args []string, h *Host) error {
+	if _, err := Command(name, args); err != nil {
+		return err
+	}
+	if c := verbs[name].check; c != nil {
+		return c(h, args)
+	}
+	return nil
+}
+
+// vhdDevice checks that a device (sdX, with or without /dev/) is an attached
+// VHD and not a system or swap disk
+func (h *Host) vhdDevice(name string) error {
+	name = strings.TrimPrefix(name, "/dev/")
+	if !slices.Contains(h.Disks, name) {
+		return fmt.Errorf("device not attached: %q", name)
+	}
+	if h.systemDisk(name) {
+		return fmt.Errorf("refusing to operate on system disk %q", name)
+	}
+	return nil
+}
+
+// systemDisk reports whether a disk is one the helper must never touch: a
+// WSL system disk, a swap device or the disk holding /
+func (h *Host) systemDisk(name string) bool {
+	if !vhdDeviceRe.MatchString(name) || slices.Contains(h.Swaps, name) {
+		return true
+	}
+	for _, m := range h.Mounts {
+		if m.Target == "/" && sourceDisk(m.Source) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// uuidDevice checks that the filesystem with a UUID is on an attached VHD
+func (h *Host) uuidDevice(uuid string) error {
+	dev, ok := h.UUIDs[uuid]
+	if !ok {
+		return fmt.Errorf("no attached filesystem with UUID %q", uuid)
+	}
+	return h.vhdDevice(sourceDisk(dev))
+}
+
+// vhdMountPoint checks that a directory is where a VHD is mounted
+func (h *Host) vhdMountPoint(p string) error {
+	dir, err := mountDir(p)
+	if err != nil {
+		return err
+	}
+	source := ""
+	for _, m := range h.Mounts {
+		// Later entries are mounted on top of earlier ones
+		if m.Target == dir {
+			source = m.Source
+		}
+	}
+	if source == "" {
+		return fmt.Errorf("not a mount point: %q", p)
+	}
+	disk := sourceDisk(source)
+	if disk == "" || h.vhdDevice(disk) != nil {
+		return fmt.Errorf("not the mount point of an attached VHD: %q", p)
+	}
+	return nil
+}
+
+// mountTarget checks a directory something is about to be mounted on: an
+// empty directory outside the system directories, owned by root or the user
+// running the helper
+func (h *Host) mountTarget(p string) error {
+	dir, err := mountDir(p)
+	if err != nil {
+		return err
+	}
+	for _, sys := range systemDirs {
+		if dir == sys || strings.HasPrefix(dir, sys+"/") {
+			return fmt.Errorf("refusing to mount on system directory %q", p)
+		}
+	}
+	info, err := h.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("invalid mount point %q: %w", p, err)
+	}
+	switch {
+	case !info.IsDir:
+		return fmt.Errorf("mount point is not a directory: %q", p)
+	case !info.Empty:
+		return fmt.Errorf("mount point is not empty: %q", p)
+	case info.UID != "0" && h.SudoUID != "" && info.UID != h.SudoUID:
+		return fmt.Errorf("mount point belongs to another user: %q", p)
+	}
+	return nil
+}
+
+// newDir checks a directory mkdir is about to create; existing paths are
+// refused so mkdir never touches them
+func (h *Host) newDir(p string) error {
+	dir, err := mountDir(p)
+	if err != nil {
+		return err
+	}
+	if _, err := h.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("already exists: %q", p)
+	}
+	return nil
+}
+
+// owner checks that a user is the one running the helper; root running it
+// directly may name anyone
+func (h *Host) owner(name string) error {
+	if h.SudoUID == "" {
+		return nil
+	}
+	uid, err := h.LookupUID(name)
+	if err != nil {
+		return fmt.Errorf("unknown user %q: %w", name, err)
+	}
+	if uid != h.SudoUID {
+		return fmt.Errorf("can only give ownership to the invoking user, not %q", name)
+	}
+	return nil
+}
+
+// sourceDisk returns the disk name of a mount source such as /dev/sdd or
+// /dev/sdd1, or "" when the source is not a /dev/sdX device
+func sourceDisk(source string) string {
+	if !strings.HasPrefix(source, "/dev/sd") && !strings.HasPrefix(source, "sd") {
+		return ""
+	}
+	return strings.TrimRight(strings.TrimPrefix(source, "/dev/"), "0123456789")
+}
+
+// parseMountInfo parses /proc/self/mountinfo into mount sources and targets
+func parseMountInfo(r io.Reader) ([]Mount, error) {
+	var mounts []Mount
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		// id parent maj:min root target options [optional...] - fstype source superoptions
+		fields := strings.Fields(sc.Text())
+		sep := slices.Index(fields, "-")
+		if len(fields) < 5 || sep < 0 || sep+2 >= len(fields) {
+			continue
+		}
+		mounts = append(mounts, Mount{
+			Source: unescapeMountField(fields[sep+2]),
+			Target: unescapeMountField(fields[4]),
+		})
+	}
+	return mounts, sc.Err()
+}
+
+// unescapeMountField decodes the octal escapes (\040 for space, ...) of the
+// mount table
+func unescapeMountField(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseSwaps returns the device names listed in /proc/swaps
+func parseSwaps(data string) []string {
+	var swaps []string
+	for i, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if i == 0 || len(fields) == 0 {
+			continue
+		}
+		swaps = append(swaps, strings.TrimPrefix(fields[0], "/dev/"))
+	}
+	return swaps
+}
+
+// statPath implements Host.Stat on the real filesystem
+func statPath(p string) (PathInfo, error) {
+	fi, err := os.Lstat(p)
+	if err != nil {
+		return PathInfo{}, err
+	}
+	info := PathInfo{IsDir: fi.IsDir()}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		info.UID = strconv.FormatUint(uint64(st.Uid), 10)
+	}
+	if info.IsDir {
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return PathInfo{}, err
+		}
+		info.Empty = len(entries) == 0
+	}
+	return info, nil
+}
+
+// lookupUID implements Host.LookupUID with the system user database
+func lookupUID(name string) (string, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	return u.Uid, nil
+}
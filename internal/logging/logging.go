@@ -4,32 +4,56 @@ package logging
 import (
 	"fmt"
 	"os"
-)
+	"slices"
+	"sync"
+	"time"
 
-const (
-	colorReset  = "\033[0m"
-	colorRed    = "\033[31m"
-	colorGreen  = "\033[32m"
-	colorYellow = "\033[33m"
-	colorBlue   = "\033[34m"
+	"github.com/rjdinis/vhdm/pkg/utils"
 )
 
 // Logger handles structured logging
 type Logger struct {
 	quiet bool
 	debug bool
+
+	// timestamp formats the time that prefixes each line, nil for none
+	timestamp func(time.Time) string
+	// theme colors the level tags
+	theme utils.Theme
+
+	mu       sync.Mutex
+	warnings []string // Collected by Collect, reported with the result
 }
 
 // New creates a new logger
 func New(quiet, debug bool) *Logger {
-	return &Logger{quiet: quiet, debug: debug}
+	return &Logger{quiet: quiet, debug: debug, theme: utils.DefaultTheme()}
+}
+
+// SetTheme colors the level tags and marks with theme
+func (l *Logger) SetTheme(theme utils.Theme) {
+	l.theme = theme
+}
+
+// SetTimestamps prefixes each log line with the current time formatted by
+// format; nil turns timestamps off
+func (l *Logger) SetTimestamps(format func(time.Time) string) {
+	l.timestamp = format
+}
+
+// prefix returns the timestamp prefix of a log line
+func (l *Logger) prefix() string {
+	if l.timestamp == nil {
+		return ""
+	}
+	return l.timestamp(time.Now()) + " "
 }
 
 // Debug logs a debug message (only when debug mode is enabled)
 func (l *Logger) Debug(format string, args ...interface{}) {
 	if l.debug {
 		msg := fmt.Sprintf(format, args...)
-		fmt.Fprintf(os.Stderr, "%s[DEBUG]%s %s\n", colorBlue, colorReset, msg)
+		fmt.Fprintf(os.Stderr, "%s%s %s\n", l.prefix(), l.theme.Blue("[DEBUG]"), msg)
 	}
 }
 
@@ -37,7 +61,7 @@ func (l *Logger) Debug(format string, args ...interface{}) {
 func (l *Logger) Info(format string, args ...interface{}) {
 	if !l.quiet {
 		msg := fmt.Sprintf(format, args...)
-		fmt.Fprintf(os.Stderr, "%s\n", msg)
+		fmt.Fprintf(os.Stderr, "%s%s\n", l.prefix(), msg)
 	}
 }
 
@@ -45,20 +69,51 @@ func (l *Logger) Info(format string, args ...interface{}) {
 func (l *Logger) Warn(format string, args ...interface{}) {
 	if !l.quiet {
 		msg := fmt.Sprintf(format, args...)
-		fmt.Fprintf(os.Stderr, "%s[WARN]%s %s\n", colorYellow, colorReset, msg)
+		fmt.Fprintf(os.Stderr, "%s%s %s\n", l.prefix(), l.theme.Yellow("[WARN]"), msg)
+	}
+}
+
+// Collect records a warning about a non-fatal problem (e.g. tracking could
+// not be saved), to be reported once with the result of the command rather
+// than among the progress messages; see TakeWarnings. In debug mode it is
+// logged right away too.
+func (l *Logger) Collect(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.Debug("Warning: %s", msg)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !slices.Contains(l.warnings, msg) {
+		l.warnings = append(l.warnings, msg)
+	}
+}
+
+// TakeWarnings returns the warnings collected so far and forgets them
+func (l *Logger) TakeWarnings() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	warnings := l.warnings
+	l.warnings = nil
+	return warnings
+}
+
+// FlushWarnings logs the collected warnings that no result reported, e.g.
+// when the command failed or printed no result
+func (l *Logger) FlushWarnings() {
+	for _, msg := range l.TakeWarnings() {
+		l.Warn("%s", msg)
 	}
 }
 
 // Error logs an error message (always shown)
 func (l *Logger) Error(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	fmt.Fprintf(os.Stderr, "%s[ERROR]%s %s\n", colorRed, colorReset, msg)
+	fmt.Fprintf(os.Stderr, "%s%s %s\n", l.prefix(), l.theme.Red("[ERROR]"), msg)
 }
 
 // Success logs a success message (hidden in quiet mode)
 func (l *Logger) Success(format string, args ...interface{}) {
 	if !l.quiet {
 		msg := fmt.Sprintf(format, args...)
-		fmt.Fprintf(os.Stderr, "%s✓%s %s\n", colorGreen, colorReset, msg)
+		fmt.Fprintf(os.Stderr, "%s%s %s\n", l.prefix(), l.theme.Green(l.theme.Success), msg)
 	}
 }
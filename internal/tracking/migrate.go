@@ -0,0 +1,184 @@
+package tracking
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+// legacyEntryKeys are the keys of tracking entries written by the bash
+// version of vhdm, with their current names, in order of preference
+var legacyEntryKeys = [][2]string{
+	{"last_attached", "last_seen"},
+	{"devname", "dev_name"},
+	{"device", "dev_name"},
+	{"mount_point", "mount_points"},
+	{"path", "original_path"},
+	{"vhd_path", "original_path"},
+}
+
+// legacyHistoryKey is the top-level detach history of the bash version, whose
+// 'history' command the Go version dropped
+const legacyHistoryKey = "detach_history"
+
+// Migration reports what MigrateLegacy changed in a tracking file
+type Migration struct {
+	Entries    int    // Entries in the migrated file
+	Keys       int    // Legacy keys renamed
+	Paths      int    // Entries whose key or original path was fixed
+	Merged     int    // Entries merged into another with the same path
+	History    int    // Detach history records dropped
+	BackupPath string // Copy of the file before the migration
+}
+
+// Changed reports whether the migration changed anything
+func (m *Migration) Changed() bool {
+	return m.Keys > 0 || m.Paths > 0 || m.Merged > 0 || m.History > 0
+}
+
+// MigrateLegacy converts a tracking file written by the bash version of vhdm
+// to the current schema: legacy keys are renamed, paths normalized, original
+// paths filled in and the detach history dropped. The file as it was is
+// copied to backupPath first. With dryRun, or when the file is already in the
+// current format, nothing is written.
+func (t *Tracker) MigrateLegacy(backupPath string, dryRun bool) (*Migration, error) {
+	var m *Migration
+	err := t.locked(func() error {
+		data, err := os.ReadFile(t.filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read tracking file: %w", err)
+		}
+		var tf types.TrackingFile
+		if err := json.Unmarshal(data, &tf); err != nil {
+			return fmt.Errorf("failed to parse tracking file: %w", err)
+		}
+
+		m = migrateLegacy(&tf)
+		if !m.Changed() || dryRun {
+			return nil
+		}
+
+		if _, err := os.Stat(backupPath); err == nil {
+			return fmt.Errorf("backup file already exists: %s", backupPath)
+		}
+		if err := os.WriteFile(backupPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to back up tracking file: %w", err)
+		}
+		m.BackupPath = backupPath
+		return t.write(&tf)
+	})
+	return m, err
+}
+
+// migrateLegacy applies MigrateLegacy to a parsed tracking file
+func migrateLegacy(tf *types.TrackingFile) *Migration {
+	m := &Migration{}
+	if tf.Version == "" {
+		tf.Version = "1.0"
+	}
+
+	// The history records the paths with their original casing
+	casing := make(map[string]string)
+	if raw, ok := tf.Extra[legacyHistoryKey]; ok {
+		var history []map[string]any
+		json.Unmarshal(raw, &history)
+		for _, record := range history {
+			for _, key := range []string{"path", "vhd_path", "original_path"} {
+				if p, ok := record[key].(string); ok && p != "" {
+					casing[normalizePath(p)] = strings.ReplaceAll(p, `\`, "/")
+				}
+			}
+		}
+		m.History = len(history)
+		delete(tf.Extra, legacyHistoryKey)
+	}
+
+	mappings := make(map[string]types.TrackingEntry, len(tf.Mappings))
+	for _, key := range sortedKeys(tf) {
+		entry := tf.Mappings[key]
+		m.Keys += renameLegacyKeys(&entry)
+
+		normalized := normalizePath(key)
+		switch {
+		case strings.HasPrefix(key, "unknown-"):
+			// Placeholders of auto-discovered VHDs have no path yet
+		case entry.OriginalPath == "":
+			entry.OriginalPath = originalPath(key, casing)
+			m.Paths++
+		case normalized != key:
+			m.Paths++
+		}
+
+		if existing, ok := mappings[normalized]; ok {
+			// Keep the entry seen last
+			m.Merged++
+			if existing.LastSeen > entry.LastSeen {
+				continue
+			}
+		}
+		mappings[normalized] = entry
+	}
+	tf.Mappings = mappings
+	m.Entries = len(mappings)
+	return m
+}
+
+// renameLegacyKeys moves the legacy keys kept in an entry's Extra to their
+// current fields, returning how many it renamed. Current fields that are
+// already set win.
+func renameLegacyKeys(entry *types.TrackingEntry) int {
+	renamed := 0
+	for _, keys := range legacyEntryKeys {
+		old, key := keys[0], keys[1]
+		raw, ok := entry.Extra[old]
+		if !ok {
+			continue
+		}
+		delete(entry.Extra, old)
+		renamed++
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil || value == "" {
+			continue
+		}
+		switch key {
+		case "last_seen":
+			if entry.LastSeen == "" {
+				entry.LastSeen = value
+			}
+		case "dev_name":
+			if entry.DeviceName == "" {
+				entry.DeviceName = strings.TrimPrefix(value, "/dev/")
+			}
+		case "mount_points":
+			if len(entry.MountPoints) == 0 {
+				entry.MountPoints = strings.Split(value, ",")
+			}
+		case "original_path":
+			if entry.OriginalPath == "" {
+				entry.OriginalPath = strings.ReplaceAll(value, `\`, "/")
+			}
+		}
+	}
+	if len(entry.Extra) == 0 {
+		entry.Extra = nil
+	}
+	return renamed
+}
+
+// originalPath returns the path to record as the original casing of a legacy
+// entry: the casing seen in the detach history, else the key itself with
+// forward slashes and an upper-case drive letter
+func originalPath(key string, casing map[string]string) string {
+	if p, ok := casing[normalizePath(key)]; ok {
+		return p
+	}
+	p := strings.ReplaceAll(key, `\`, "/")
+	if len(p) >= 2 && p[1] == ':' {
+		p = strings.ToUpper(p[:1]) + p[1:]
+	}
+	return p
+}
@@ -0,0 +1,94 @@
+package tracking
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// ChangeSentinel returns the file touched after every write to the tracking
+// file. Long-running consumers (watch, status monitors, exporters) can watch
+// it to refresh on changes instead of polling the tracking file.
+func (t *Tracker) ChangeSentinel() string {
+	return t.filePath + ".changed"
+}
+
+// touchSentinel records a change; failures are ignored since notifications
+// are best-effort
+func (t *Tracker) touchSentinel() {
+	os.WriteFile(t.ChangeSentinel(), []byte(time.Now().Format(time.RFC3339Nano)+"\n"), 0644)
+}
+
+// ChangeWatcher waits for tracking file changes using inotify
+type ChangeWatcher struct {
+	f        *os.File
+	sentinel string
+}
+
+// WatchChanges starts watching for tracking file changes. Changes made after
+// this call are reported by Wait, including those made before Wait is called.
+func (t *Tracker) WatchChanges() (*ChangeWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+	dir := filepath.Dir(t.filePath)
+	if _, err := syscall.InotifyAddWatch(fd, dir, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	return &ChangeWatcher{
+		f:        os.NewFile(uintptr(fd), "inotify"),
+		sentinel: filepath.Base(t.ChangeSentinel()),
+	}, nil
+}
+
+// Wait blocks until the tracking file changes or timeout elapses, and
+// reports whether it changed
+func (w *ChangeWatcher) Wait(timeout time.Duration) (bool, error) {
+	if err := w.f.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return false, err
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := w.f.Read(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read inotify events: %w", err)
+		}
+		if sentinelChanged(buf[:n], w.sentinel) {
+			return true, nil
+		}
+	}
+}
+
+// Close stops watching
+func (w *ChangeWatcher) Close() error {
+	return w.f.Close()
+}
+
+// sentinelChanged reports whether a buffer of inotify events names the sentinel
+func sentinelChanged(events []byte, sentinel string) bool {
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(events); {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&events[offset]))
+		nameStart := offset + syscall.SizeofInotifyEvent
+		nameEnd := nameStart + int(event.Len)
+		if nameEnd > len(events) {
+			break
+		}
+		name := string(bytes.TrimRight(events[nameStart:nameEnd], "\x00"))
+		if name == sentinel {
+			return true
+		}
+		offset = nameEnd
+	}
+	return false
+}
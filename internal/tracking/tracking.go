@@ -10,19 +10,35 @@ package tracking
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/pkg/utils"
 )
 
+// lockTimeout bounds how long a write waits for other vhdm processes
+const lockTimeout = 30 * time.Second
+
 // Tracker manages VHD tracking state
 type Tracker struct {
 	filePath string
 	mu       sync.RWMutex
+	// update serializes read-modify-write cycles, so concurrent callers in
+	// one process (e.g., 'mount --all --parallel') don't lose updates. The
+	// flock taken by modify does the same across processes.
+	update sync.Mutex
+
+	// Parsed copy of the file, valid while the file on disk is unchanged
+	cache     *types.TrackingFile
+	cacheStat os.FileInfo
 }
 
 // New creates a new Tracker
@@ -40,19 +56,77 @@ func (t *Tracker) init() error {
 		return fmt.Errorf("failed to create tracking directory: %w", err)
 	}
 
-	if _, err := os.Stat(t.filePath); os.IsNotExist(err) {
-		tf := &types.TrackingFile{
+	if _, err := os.Stat(t.filePath); !os.IsNotExist(err) {
+		return nil
+	}
+
+	// Re-check under the lock: another process may have created it meanwhile
+	return t.locked(func() error {
+		if _, err := os.Stat(t.filePath); !os.IsNotExist(err) {
+			return nil
+		}
+		return t.write(&types.TrackingFile{
 			Version:  "1.0",
 			Mappings: make(map[string]types.TrackingEntry),
+		})
+	})
+}
+
+// locked runs fn holding both the in-process update mutex and an flock on
+// <file>.lock shared by all vhdm processes
+func (t *Tracker) locked(fn func() error) error {
+	t.update.Lock()
+	defer t.update.Unlock()
+
+	lock, err := utils.LockFile(t.filePath+".lock", lockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock tracking file: %w", err)
+	}
+	defer lock.Unlock()
+
+	return fn()
+}
+
+// modify runs a read-modify-write cycle of the tracking file. The file is
+// re-read from disk under the lock and fn applies its change to that copy, so
+// processes writing at the same time (e.g., boot services all saving their
+// mapping) merge their changes instead of overwriting each other's. The file
+// is only written when fn reports a change.
+func (t *Tracker) modify(fn func(tf *types.TrackingFile) (bool, error)) error {
+	return t.locked(func() error {
+		// Skip the cache: another process may have written since it was filled
+		t.mu.Lock()
+		t.cache = nil
+		t.mu.Unlock()
+
+		tf, err := t.read()
+		if err != nil {
+			return err
+		}
+		changed, err := fn(tf)
+		if err != nil || !changed {
+			return err
 		}
 		return t.write(tf)
-	}
-	return nil
+	})
 }
 
+// read returns the tracking file. The parsed file is cached and only re-read
+// when the file changes on disk (other processes write it by renaming a new
+// file in place, which changes its inode and mtime). Callers get their own
+// copy and may modify it.
 func (t *Tracker) read() (*types.TrackingFile, error) {
 	t.mu.RLock()
-	defer t.mu.RUnlock()
+	info, statErr := os.Stat(t.filePath)
+	if statErr == nil && t.cache != nil && sameFileVersion(t.cacheStat, info) {
+		tf := cloneTrackingFile(t.cache)
+		t.mu.RUnlock()
+		return tf, nil
+	}
+	t.mu.RUnlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
 	data, err := os.ReadFile(t.filePath)
 	if err != nil {
@@ -67,9 +141,51 @@ func (t *Tracker) read() (*types.TrackingFile, error) {
 	if tf.Mappings == nil {
 		tf.Mappings = make(map[string]types.TrackingEntry)
 	}
+
+	// Cache only if the file did not change while it was read
+	if statErr == nil {
+		if after, err := os.Stat(t.filePath); err == nil && sameFileVersion(info, after) {
+			t.cache = cloneTrackingFile(&tf)
+			t.cacheStat = after
+		}
+	}
 	return &tf, nil
 }
 
+// sameFileVersion reports whether two stats describe the same, unmodified file
+func sameFileVersion(a, b os.FileInfo) bool {
+	return a != nil && b != nil && os.SameFile(a, b) &&
+		a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
+
+// cloneTrackingFile deep-copies a tracking file, so callers can modify entries
+// without touching the cache
+func cloneTrackingFile(tf *types.TrackingFile) *types.TrackingFile {
+	clone := &types.TrackingFile{
+		Version:      tf.Version,
+		Mappings:     make(map[string]types.TrackingEntry, len(tf.Mappings)),
+		DeviceDelays: slices.Clone(tf.DeviceDelays),
+		Extra:        maps.Clone(tf.Extra),
+	}
+	for key, entry := range tf.Mappings {
+		entry.Extra = maps.Clone(entry.Extra)
+		entry.MountPoints = slices.Clone(entry.MountPoints)
+		entry.SizeHistory = slices.Clone(entry.SizeHistory)
+		entry.After = slices.Clone(entry.After)
+		entry.Snapshots = slices.Clone(entry.Snapshots)
+		if entry.ImageCheck != nil {
+			check := *entry.ImageCheck
+			entry.ImageCheck = &check
+		}
+		if entry.MountCheck != nil {
+			check := *entry.MountCheck
+			entry.MountCheck = &check
+		}
+		clone.Mappings[key] = entry
+	}
+	return clone
+}
+
 func (t *Tracker) write(tf *types.TrackingFile) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -86,8 +202,22 @@ func (t *Tracker) write(tf *types.TrackingFile) error {
 
 	if err := os.Rename(tmpFile, t.filePath); err != nil {
 		os.Remove(tmpFile)
+		t.cache = nil
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
+
+	t.touchSentinel()
+
+	// Cache what was written, parsed back so it matches a fresh read
+	t.cache = nil
+	var written types.TrackingFile
+	if info, err := os.Stat(t.filePath); err == nil && json.Unmarshal(data, &written) == nil {
+		if written.Mappings == nil {
+			written.Mappings = make(map[string]types.TrackingEntry)
+		}
+		t.cache = &written
+		t.cacheStat = info
+	}
 	return nil
 }
 
@@ -100,11 +230,14 @@ func normalizePath(path string) string {
 
 // SaveMapping saves or updates a VHD mapping
 func (t *Tracker) SaveMapping(path, uuid, mountPoint, devName string) error {
-	tf, err := t.read()
-	if err != nil {
-		return err
-	}
+	return t.modify(func(tf *types.TrackingFile) (bool, error) {
+		saveMapping(tf, path, uuid, mountPoint, devName)
+		return true, nil
+	})
+}
 
+// saveMapping applies SaveMapping to a tracking file
+func saveMapping(tf *types.TrackingFile, path, uuid, mountPoint, devName string) {
 	// Remove any placeholder entries for this UUID (auto-discovered entries)
 	// This prevents duplicates when the real path is learned
 	for key, entry := range tf.Mappings {
@@ -114,18 +247,20 @@ func (t *Tracker) SaveMapping(path, uuid, mountPoint, devName string) error {
 	}
 
 	normalized := normalizePath(path)
-	entry := types.TrackingEntry{
-		UUID:         uuid,
-		LastSeen:     time.Now().Format(time.RFC3339),
-		DeviceName:   devName,
-		OriginalPath: path, // Preserve original case
-	}
+
+	// Start from the existing entry so fields not managed here survive
+	entry := tf.Mappings[normalized]
+	entry.UUID = uuid
+	entry.LastSeen = time.Now().Format(time.RFC3339)
+	entry.DeviceName = devName
+	entry.OriginalPath = path // Preserve original case
+	entry.External = false
+	entry.MountPoints = nil
 	if mountPoint != "" {
 		entry.MountPoints = []string{mountPoint}
+		entry.LastMount = mountPoint
 	}
 	tf.Mappings[normalized] = entry
-
-	return t.write(tf)
 }
 
 // LookupUUIDByPath looks up UUID by VHD path
@@ -150,7 +285,8 @@ func (t *Tracker) LookupPathByUUID(uuid string) (string, error) {
 		return "", err
 	}
 
-	for path, entry := range tf.Mappings {
+	for _, path := range sortedKeys(tf) {
+		entry := tf.Mappings[path]
 		if entry.UUID == uuid {
 			// Return original path if available, fallback to normalized key
 			if entry.OriginalPath != "" {
@@ -170,7 +306,8 @@ func (t *Tracker) LookupPathByDevName(devName string) (string, error) {
 		return "", err
 	}
 
-	for path, entry := range tf.Mappings {
+	for _, path := range sortedKeys(tf) {
+		entry := tf.Mappings[path]
 		if entry.DeviceName == devName {
 			// Return original path if available, fallback to normalized key
 			if entry.OriginalPath != "" {
@@ -182,6 +319,28 @@ func (t *Tracker) LookupPathByDevName(devName string) (string, error) {
 	return "", nil
 }
 
+// LookupPathByService looks up the VHD path whose service, installed by
+// 'vhdm service create', is unit.
+// Returns the original path with preserved casing (e.g., C:/aNOS/VMs/disk.vhdx).
+func (t *Tracker) LookupPathByService(unit string) (string, error) {
+	tf, err := t.read()
+	if err != nil {
+		return "", err
+	}
+
+	for _, path := range sortedKeys(tf) {
+		entry := tf.Mappings[path]
+		if entry.Service != "" && entry.Service == unit {
+			// Return original path if available, fallback to normalized key
+			if entry.OriginalPath != "" {
+				return entry.OriginalPath, nil
+			}
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
 // LookupDevNameByPath looks up device name by VHD path
 func (t *Tracker) LookupDevNameByPath(path string) (string, error) {
 	tf, err := t.read()
@@ -210,7 +369,13 @@ func (t *Tracker) GetEntry(path string) (types.TrackingEntry, error) {
 	return types.TrackingEntry{}, fmt.Errorf("not found")
 }
 
-// GetAllPaths returns all tracked VHD paths.
+// sortedKeys returns the normalized paths of all mappings in sorted order, so
+// listings and first-match lookups do not depend on map iteration order
+func sortedKeys(tf *types.TrackingFile) []string {
+	return slices.Sorted(maps.Keys(tf.Mappings))
+}
+
+// GetAllPaths returns all tracked VHD paths, sorted case-insensitively.
 // Returns original paths with preserved casing (e.g., C:/aNOS/VMs/disk.vhdx).
 func (t *Tracker) GetAllPaths() ([]string, error) {
 	tf, err := t.read()
@@ -219,7 +384,8 @@ func (t *Tracker) GetAllPaths() ([]string, error) {
 	}
 
 	paths := make([]string, 0, len(tf.Mappings))
-	for path, entry := range tf.Mappings {
+	for _, path := range sortedKeys(tf) {
+		entry := tf.Mappings[path]
 		// Return original path if available, fallback to normalized key
 		if entry.OriginalPath != "" {
 			paths = append(paths, entry.OriginalPath)
@@ -230,68 +396,304 @@ func (t *Tracker) GetAllPaths() ([]string, error) {
 	return paths, nil
 }
 
-// UpdateMountPoints updates mount points for a VHD
-func (t *Tracker) UpdateMountPoints(path string, mountPoints []string) error {
+// MountPointClaims returns the tracked VHD paths, with original casing, that
+// are mounted at mountPoint or were last mounted there, and so would be
+// mounted there again by 'mount --all' or a service. Archived VHDs are skipped.
+func (t *Tracker) MountPointClaims(mountPoint string) ([]string, error) {
 	tf, err := t.read()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	normalized := normalizePath(path)
-	if entry, ok := tf.Mappings[normalized]; ok {
-		entry.MountPoints = mountPoints
+	mountPoint = path.Clean(mountPoint)
+	var paths []string
+	for _, key := range sortedKeys(tf) {
+		entry := tf.Mappings[key]
+		if entry.Archived {
+			continue
+		}
+		claimed := entry.LastMount != "" && path.Clean(entry.LastMount) == mountPoint
+		for _, mp := range entry.MountPoints {
+			claimed = claimed || path.Clean(mp) == mountPoint
+		}
+		if !claimed {
+			continue
+		}
+		if entry.OriginalPath != "" {
+			key = entry.OriginalPath
+		}
+		paths = append(paths, key)
+	}
+	return paths, nil
+}
+
+// Update applies fn to the entry of a tracked VHD and saves the result. The
+// read-modify-write runs under a single lock, and only the fields fn changes
+// are modified. Untracked paths are ignored and fn is not called.
+func (t *Tracker) Update(path string, fn func(entry *types.TrackingEntry)) error {
+	return t.modify(func(tf *types.TrackingFile) (bool, error) {
+		normalized := normalizePath(path)
+		entry, ok := tf.Mappings[normalized]
+		if !ok {
+			return false, nil
+		}
+		fn(&entry)
 		// Preserve OriginalPath if not set
 		if entry.OriginalPath == "" {
 			entry.OriginalPath = path
 		}
 		tf.Mappings[normalized] = entry
-		return t.write(tf)
-	}
-	return nil
+		return true, nil
+	})
 }
 
-// RemoveMapping removes a VHD mapping
-func (t *Tracker) RemoveMapping(path string) error {
+// UpdateMountPoints updates mount points for a VHD after vhdm changed them
+func (t *Tracker) UpdateMountPoints(path string, mountPoints []string) error {
+	return t.Update(path, func(entry *types.TrackingEntry) {
+		entry.External = false
+		entry.MountPoints = mountPoints
+		if len(mountPoints) > 0 {
+			entry.LastMount = mountPoints[0]
+		}
+	})
+}
+
+// SetMountInfo records the options a tracked VHD was last mounted with and
+// its filesystem type (kept when fsType is empty)
+func (t *Tracker) SetMountInfo(path, options, fsType string) error {
+	return t.Update(path, func(entry *types.TrackingEntry) {
+		entry.MountOptions = options
+		if fsType != "" {
+			entry.FSType = fsType
+		}
+	})
+}
+
+// SetIdentifiers records the filesystem label and partition UUID of a
+// tracked VHD, so it can be looked up by them while detached
+func (t *Tracker) SetIdentifiers(path, label, partUUID string) error {
+	return t.Update(path, func(entry *types.TrackingEntry) {
+		entry.Label = label
+		entry.PartUUID = partUUID
+	})
+}
+
+// SetArchived marks a tracked VHD as archived (compressed) or restored
+func (t *Tracker) SetArchived(path string, archived bool) error {
+	return t.Update(path, func(entry *types.TrackingEntry) {
+		entry.Archived = archived
+	})
+}
+
+// AppendSizeSample records a size sample for a tracked VHD, keeping at most
+// limit samples (oldest dropped first). A limit <= 0 keeps all samples.
+func (t *Tracker) AppendSizeSample(path string, sample types.SizeSample, limit int) error {
+	return t.Update(path, func(entry *types.TrackingEntry) {
+		entry.SizeHistory = append(entry.SizeHistory, sample)
+		if limit > 0 && len(entry.SizeHistory) > limit {
+			entry.SizeHistory = entry.SizeHistory[len(entry.SizeHistory)-limit:]
+		}
+	})
+}
+
+// deviceDelayLimit bounds the attach delay samples kept for calibration
+const deviceDelayLimit = 20
+
+// RecordDeviceDelay records how long an attached VHD's block device took to
+// appear, keeping the most recent deviceDelayLimit samples
+func (t *Tracker) RecordDeviceDelay(d time.Duration) error {
+	return t.modify(func(tf *types.TrackingFile) (bool, error) {
+		tf.DeviceDelays = append(tf.DeviceDelays, d.Milliseconds())
+		if len(tf.DeviceDelays) > deviceDelayLimit {
+			tf.DeviceDelays = tf.DeviceDelays[len(tf.DeviceDelays)-deviceDelayLimit:]
+		}
+		return true, nil
+	})
+}
+
+// DeviceDelays returns the recorded attach delay samples, oldest first
+func (t *Tracker) DeviceDelays() ([]time.Duration, error) {
 	tf, err := t.read()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	delays := make([]time.Duration, 0, len(tf.DeviceDelays))
+	for _, ms := range tf.DeviceDelays {
+		delays = append(delays, time.Duration(ms)*time.Millisecond)
+	}
+	return delays, nil
+}
 
-	normalized := normalizePath(path)
-	delete(tf.Mappings, normalized)
-	return t.write(tf)
+// SaveReference tracks a WSL distribution's system VHD as a read-only reference.
+// Existing entries keep their other fields.
+func (t *Tracker) SaveReference(path, distro string) error {
+	return t.modify(func(tf *types.TrackingFile) (bool, error) {
+		normalized := normalizePath(path)
+		entry := tf.Mappings[normalized]
+		entry.Distro = distro
+		entry.ReadOnly = true
+		entry.OriginalPath = path
+		if entry.LastSeen == "" {
+			entry.LastSeen = time.Now().Format(time.RFC3339)
+		}
+		tf.Mappings[normalized] = entry
+		return true, nil
+	})
 }
 
-// UpdateLastSeen updates the LastSeen timestamp for a VHD
-func (t *Tracker) UpdateLastSeen(path string) error {
+// SetAfter sets the VHDs that must be mounted before the given VHD.
+// Dependencies are stored with their original path casing.
+func (t *Tracker) SetAfter(path string, after []string) error {
+	return t.modify(func(tf *types.TrackingFile) (bool, error) {
+		normalized := normalizePath(path)
+		entry, ok := tf.Mappings[normalized]
+		if !ok {
+			return false, fmt.Errorf("VHD is not tracked: %s", path)
+		}
+		entry.After = after
+		if len(after) == 0 {
+			entry.After = nil
+		}
+		tf.Mappings[normalized] = entry
+		return true, nil
+	})
+}
+
+// SortByDependencies orders VHD paths so that every VHD comes after the VHDs
+// listed in its After field. Paths without dependencies keep alphabetical order.
+// Dependencies that are not in paths are ignored. Returns an error on cycles.
+func (t *Tracker) SortByDependencies(paths []string) ([]string, error) {
 	tf, err := t.read()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	normalized := normalizePath(path)
-	if entry, ok := tf.Mappings[normalized]; ok {
-		entry.LastSeen = time.Now().Format(time.RFC3339)
-		// Preserve OriginalPath if not set
-		if entry.OriginalPath == "" {
-			entry.OriginalPath = path
+	deps := make(map[string][]string, len(paths))
+	for _, path := range paths {
+		deps[path] = tf.Mappings[normalizePath(path)].After
+	}
+	return sortByDependencies(paths, deps)
+}
+
+// sortByDependencies performs a stable topological sort (Kahn's algorithm)
+// of paths, comparing paths case-insensitively
+func sortByDependencies(paths []string, deps map[string][]string) ([]string, error) {
+	byKey := make(map[string]string, len(paths))
+	for _, path := range paths {
+		byKey[normalizePath(path)] = path
+	}
+
+	indegree := make(map[string]int, len(paths))
+	dependents := make(map[string][]string)
+	for _, path := range paths {
+		key := normalizePath(path)
+		if _, ok := indegree[key]; !ok {
+			indegree[key] = 0
+		}
+		for _, dep := range deps[path] {
+			depKey := normalizePath(dep)
+			if _, ok := byKey[depKey]; !ok || depKey == key {
+				continue
+			}
+			indegree[key]++
+			dependents[depKey] = append(dependents[depKey], key)
 		}
-		tf.Mappings[normalized] = entry
-		return t.write(tf)
 	}
-	return nil
+
+	var ready []string
+	for key, n := range indegree {
+		if n == 0 {
+			ready = append(ready, key)
+		}
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		key := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, byKey[key])
+		for _, dependent := range dependents[key] {
+			indegree[dependent]--
+			if indegree[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(sorted) != len(indegree) {
+		var cyclic []string
+		for key, n := range indegree {
+			if n > 0 {
+				cyclic = append(cyclic, byKey[key])
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, fmt.Errorf("dependency cycle between: %s", strings.Join(cyclic, ", "))
+	}
+	return sorted, nil
+}
+
+// RemoveMapping removes a VHD mapping
+func (t *Tracker) RemoveMapping(path string) error {
+	return t.modify(func(tf *types.TrackingFile) (bool, error) {
+		delete(tf.Mappings, normalizePath(path))
+		return true, nil
+	})
+}
+
+// Rename moves the entry of a tracked VHD to newPath, keeping all its fields,
+// and updates the dependencies and linked clones that refer to it
+func (t *Tracker) Rename(oldPath, newPath string) error {
+	return t.modify(func(tf *types.TrackingFile) (bool, error) {
+		oldKey, newKey := normalizePath(oldPath), normalizePath(newPath)
+		entry, ok := tf.Mappings[oldKey]
+		if !ok {
+			return false, fmt.Errorf("VHD is not tracked: %s", oldPath)
+		}
+		if _, taken := tf.Mappings[newKey]; taken && newKey != oldKey {
+			return false, fmt.Errorf("path is already tracked: %s", newPath)
+		}
+		delete(tf.Mappings, oldKey)
+		entry.OriginalPath = newPath
+		tf.Mappings[newKey] = entry
+
+		for key, e := range tf.Mappings {
+			for i, dep := range e.After {
+				if normalizePath(dep) == oldKey {
+					e.After[i] = newPath
+				}
+			}
+			if e.BackingFile != "" && normalizePath(e.BackingFile) == oldKey {
+				e.BackingFile = newPath
+			}
+			tf.Mappings[key] = e
+		}
+		return true, nil
+	})
+}
+
+// UpdateLastSeen updates the LastSeen timestamp for a VHD
+func (t *Tracker) UpdateLastSeen(path string) error {
+	return t.Update(path, func(entry *types.TrackingEntry) {
+		entry.LastSeen = time.Now().Format(time.RFC3339)
+	})
 }
 
 // SaveMappingByUUID saves or updates a VHD mapping using only UUID and device info
 // when the VHD path is unknown (e.g., for auto-discovered mounted VHDs)
 func (t *Tracker) SaveMappingByUUID(uuid, mountPoint, devName string) error {
-	tf, err := t.read()
-	if err != nil {
-		return err
-	}
+	return t.modify(func(tf *types.TrackingFile) (bool, error) {
+		saveMappingByUUID(tf, uuid, mountPoint, devName)
+		return true, nil
+	})
+}
 
+// saveMappingByUUID applies SaveMappingByUUID to a tracking file
+func saveMappingByUUID(tf *types.TrackingFile, uuid, mountPoint, devName string) {
 	// Check if UUID already exists in any mapping
-	for normalized, entry := range tf.Mappings {
+	for _, normalized := range sortedKeys(tf) {
+		entry := tf.Mappings[normalized]
 		if entry.UUID == uuid {
 			// Update existing entry
 			if mountPoint != "" {
@@ -302,7 +704,7 @@ func (t *Tracker) SaveMappingByUUID(uuid, mountPoint, devName string) error {
 			}
 			entry.LastSeen = time.Now().Format(time.RFC3339)
 			tf.Mappings[normalized] = entry
-			return t.write(tf)
+			return
 		}
 	}
 
@@ -320,36 +722,29 @@ func (t *Tracker) SaveMappingByUUID(uuid, mountPoint, devName string) error {
 		entry.MountPoints = []string{mountPoint}
 	}
 	tf.Mappings[normalized] = entry
-
-	return t.write(tf)
 }
 
 // CleanupNonExistent removes tracked VHDs where the file no longer exists
 // Returns the list of removed paths
 func (t *Tracker) CleanupNonExistent(fileExists func(string) bool) ([]string, error) {
-	tf, err := t.read()
-	if err != nil {
-		return nil, err
-	}
-
 	var removed []string
-	for path, entry := range tf.Mappings {
-		if !fileExists(path) {
-			delete(tf.Mappings, path)
-			// Return original path if available for better logging
-			if entry.OriginalPath != "" {
-				removed = append(removed, entry.OriginalPath)
-			} else {
-				removed = append(removed, path)
+	err := t.modify(func(tf *types.TrackingFile) (bool, error) {
+		for _, path := range sortedKeys(tf) {
+			entry := tf.Mappings[path]
+			if !fileExists(path) {
+				delete(tf.Mappings, path)
+				// Return original path if available for better logging
+				if entry.OriginalPath != "" {
+					removed = append(removed, entry.OriginalPath)
+				} else {
+					removed = append(removed, path)
+				}
 			}
 		}
+		return len(removed) > 0, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	if len(removed) > 0 {
-		if err := t.write(tf); err != nil {
-			return nil, err
-		}
-	}
-
 	return removed, nil
 }
@@ -1,9 +1,15 @@
 package tracking
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/rjdinis/vhdm/internal/types"
 )
 
 func setupTestTracker(t *testing.T) (*Tracker, func()) {
@@ -96,6 +102,27 @@ func TestSaveAndLookupMapping(t *testing.T) {
 	}
 }
 
+func TestLookupPathByService(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	tracker.SaveMapping("C:/VMs/data.vhdx", "uuid-1", "", "")
+	tracker.SaveMapping("C:/VMs/logs.vhdx", "uuid-2", "", "")
+	tracker.Update("C:/VMs/logs.vhdx", func(entry *types.TrackingEntry) {
+		entry.Service = "logs-mount.service"
+	})
+
+	if got, _ := tracker.LookupPathByService("logs-mount.service"); got != "C:/VMs/logs.vhdx" {
+		t.Errorf("LookupPathByService() = %q, want C:/VMs/logs.vhdx", got)
+	}
+	if got, _ := tracker.LookupPathByService("vhdm-mount-data.service"); got != "" {
+		t.Errorf("LookupPathByService(untracked unit) = %q, want empty", got)
+	}
+	if got, _ := tracker.LookupPathByService(""); got != "" {
+		t.Errorf("LookupPathByService(\"\") = %q, want empty", got)
+	}
+}
+
 func TestPathNormalization(t *testing.T) {
 	tracker, cleanup := setupTestTracker(t)
 	defer cleanup()
@@ -158,6 +185,28 @@ func TestGetAllPaths(t *testing.T) {
 	}
 }
 
+func TestGetAllPathsSorted(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	for i, path := range []string{"D:/data/z.vhdx", "C:/VMs/b.vhdx", "c:/vms/A.vhdx", "C:/Apps/x.vhdx"} {
+		if err := tracker.SaveMapping(path, fmt.Sprintf("00000000-0000-0000-0000-00000000000%d", i), "", ""); err != nil {
+			t.Fatalf("SaveMapping failed: %v", err)
+		}
+	}
+
+	want := []string{"C:/Apps/x.vhdx", "c:/vms/A.vhdx", "C:/VMs/b.vhdx", "D:/data/z.vhdx"}
+	for run := 0; run < 5; run++ {
+		got, err := tracker.GetAllPaths()
+		if err != nil {
+			t.Fatalf("GetAllPaths failed: %v", err)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("GetAllPaths() = %v, want %v", got, want)
+		}
+	}
+}
+
 func TestRemoveMapping(t *testing.T) {
 	tracker, cleanup := setupTestTracker(t)
 	defer cleanup()
@@ -397,3 +446,523 @@ func TestConcurrentAccess(t *testing.T) {
 		t.Errorf("Tracking file corrupted after concurrent access: %v", err)
 	}
 }
+
+func TestConcurrentTrackersMerge(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	// Separate trackers on one file stand in for separate processes, such as
+	// boot services starting together: each write must keep the others'
+	const writers = 8
+	done := make(chan error)
+	for i := 0; i < writers; i++ {
+		go func(id int) {
+			other, err := New(tracker.filePath)
+			if err != nil {
+				done <- err
+				return
+			}
+			path := fmt.Sprintf("C:/VMs/service%d.vhdx", id)
+			done <- other.SaveMapping(path, fmt.Sprintf("uuid-%d", id), fmt.Sprintf("/mnt/service%d", id), "sdd")
+		}(i)
+	}
+	for i := 0; i < writers; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("SaveMapping() error = %v", err)
+		}
+	}
+
+	paths, err := tracker.GetAllPaths()
+	if err != nil {
+		t.Fatalf("GetAllPaths() error = %v", err)
+	}
+	if len(paths) != writers {
+		t.Errorf("GetAllPaths() returned %d paths, want %d: %v", len(paths), writers, paths)
+	}
+}
+
+func TestSetArchived(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	vhdPath := "C:/VMs/test.vhdx"
+	uuid := "761c723c-80c8-41dc-b322-6f04d1160e43"
+
+	tracker.SaveMapping(vhdPath, uuid, "", "")
+	if err := tracker.SetArchived(vhdPath, true); err != nil {
+		t.Fatalf("SetArchived failed: %v", err)
+	}
+
+	entry, err := tracker.GetEntry(vhdPath)
+	if err != nil {
+		t.Fatalf("GetEntry failed: %v", err)
+	}
+	if !entry.Archived {
+		t.Error("Expected entry to be archived")
+	}
+
+	// SaveMapping must not clear the archived flag
+	tracker.SaveMapping(vhdPath, uuid, "", "")
+	entry, _ = tracker.GetEntry(vhdPath)
+	if !entry.Archived {
+		t.Error("SaveMapping cleared the archived flag")
+	}
+
+	if err := tracker.SetArchived(vhdPath, false); err != nil {
+		t.Fatalf("SetArchived failed: %v", err)
+	}
+	entry, _ = tracker.GetEntry(vhdPath)
+	if entry.Archived {
+		t.Error("Expected entry to be unarchived")
+	}
+}
+
+func TestAppendSizeSample(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	vhdPath := "C:/VMs/test.vhdx"
+	tracker.SaveMapping(vhdPath, "761c723c-80c8-41dc-b322-6f04d1160e43", "", "")
+
+	for i := int64(1); i <= 5; i++ {
+		sample := types.SizeSample{Time: "2025-12-01T12:00:00Z", AllocatedSize: i * 100}
+		if err := tracker.AppendSizeSample(vhdPath, sample, 3); err != nil {
+			t.Fatalf("AppendSizeSample failed: %v", err)
+		}
+	}
+
+	entry, err := tracker.GetEntry(vhdPath)
+	if err != nil {
+		t.Fatalf("GetEntry failed: %v", err)
+	}
+	if len(entry.SizeHistory) != 3 {
+		t.Fatalf("Expected 3 samples, got %d", len(entry.SizeHistory))
+	}
+	if entry.SizeHistory[0].AllocatedSize != 300 || entry.SizeHistory[2].AllocatedSize != 500 {
+		t.Errorf("Expected oldest samples to be dropped, got %+v", entry.SizeHistory)
+	}
+
+	// Samples for untracked VHDs are ignored
+	if err := tracker.AppendSizeSample("C:/VMs/other.vhdx", types.SizeSample{}, 3); err != nil {
+		t.Errorf("AppendSizeSample for untracked VHD failed: %v", err)
+	}
+}
+
+func TestLastMountSurvivesUnmount(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	vhdPath := "C:/VMs/test.vhdx"
+	uuid := "761c723c-80c8-41dc-b322-6f04d1160e43"
+
+	tracker.SaveMapping(vhdPath, uuid, "/mnt/test", "sde")
+	tracker.SaveMapping(vhdPath, uuid, "", "")
+
+	entry, _ := tracker.GetEntry(vhdPath)
+	if len(entry.MountPoints) != 0 {
+		t.Errorf("Expected no mount points, got %v", entry.MountPoints)
+	}
+	if entry.LastMount != "/mnt/test" {
+		t.Errorf("LastMount = %q, want /mnt/test", entry.LastMount)
+	}
+}
+
+func TestMountPointClaims(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	tracker.SaveMapping("C:/VMs/Old.vhdx", "761c723c-80c8-41dc-b322-6f04d1160e43", "/mnt/data", "")
+	tracker.SaveMapping("C:/VMs/Old.vhdx", "761c723c-80c8-41dc-b322-6f04d1160e43", "", "")
+	tracker.SaveMapping("C:/VMs/new.vhdx", "a1b2c3d4-80c8-41dc-b322-6f04d1160e43", "/mnt/data/", "sde")
+	tracker.SaveMapping("C:/VMs/gone.vhdx", "b1b2c3d4-80c8-41dc-b322-6f04d1160e43", "/mnt/data", "")
+	tracker.SetArchived("C:/VMs/gone.vhdx", true)
+	tracker.SaveMapping("C:/VMs/other.vhdx", "c1b2c3d4-80c8-41dc-b322-6f04d1160e43", "/mnt/other", "")
+
+	claims, err := tracker.MountPointClaims("/mnt/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"C:/VMs/new.vhdx", "C:/VMs/Old.vhdx"}; !slices.Equal(claims, want) {
+		t.Errorf("MountPointClaims() = %v, want %v", claims, want)
+	}
+}
+
+func TestRename(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	tracker.SaveMapping("C:/VMs/base.vhdx", "761c723c-80c8-41dc-b322-6f04d1160e43", "/mnt/base", "")
+	tracker.SetMountInfo("C:/VMs/base.vhdx", "noatime", "ext4")
+	tracker.SaveMapping("C:/VMs/overlay.vhdx", "a1b2c3d4-80c8-41dc-b322-6f04d1160e43", "", "")
+	tracker.SetAfter("C:/VMs/overlay.vhdx", []string{"C:/VMs/Base.vhdx"})
+	tracker.SaveMapping("C:/VMs/other.vhdx", "b1b2c3d4-80c8-41dc-b322-6f04d1160e43", "", "")
+
+	if err := tracker.Rename("C:/VMs/base.vhdx", "D:/VMs/base.vhd"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if _, err := tracker.GetEntry("C:/VMs/base.vhdx"); err == nil {
+		t.Error("old path still tracked")
+	}
+	entry, err := tracker.GetEntry("d:/vms/base.vhd")
+	if err != nil || entry.OriginalPath != "D:/VMs/base.vhd" || entry.MountOptions != "noatime" || entry.LastMount != "/mnt/base" {
+		t.Errorf("renamed entry = %+v, %v", entry, err)
+	}
+	if overlay, _ := tracker.GetEntry("C:/VMs/overlay.vhdx"); !slices.Equal(overlay.After, []string{"D:/VMs/base.vhd"}) {
+		t.Errorf("After = %v, want the new path", overlay.After)
+	}
+
+	if err := tracker.Rename("D:/VMs/base.vhd", "C:/VMs/other.vhdx"); err == nil {
+		t.Error("Rename onto a tracked path succeeded")
+	}
+	if err := tracker.Rename("C:/VMs/missing.vhdx", "C:/VMs/new.vhdx"); err == nil {
+		t.Error("Rename of an untracked path succeeded")
+	}
+}
+
+func TestSetAfter(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	tracker.SaveMapping("C:/VMs/base.vhdx", "761c723c-80c8-41dc-b322-6f04d1160e43", "", "")
+	tracker.SaveMapping("C:/VMs/overlay.vhdx", "a1b2c3d4-80c8-41dc-b322-6f04d1160e43", "", "")
+
+	if err := tracker.SetAfter("C:/VMs/overlay.vhdx", []string{"C:/VMs/base.vhdx"}); err != nil {
+		t.Fatalf("SetAfter failed: %v", err)
+	}
+	entry, _ := tracker.GetEntry("c:/vms/overlay.vhdx")
+	if len(entry.After) != 1 || entry.After[0] != "C:/VMs/base.vhdx" {
+		t.Errorf("After = %v, want [C:/VMs/base.vhdx]", entry.After)
+	}
+
+	if err := tracker.SetAfter("C:/VMs/missing.vhdx", nil); err == nil {
+		t.Error("Expected error for untracked VHD")
+	}
+}
+
+func TestSortByDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		deps    map[string][]string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "no dependencies sorted alphabetically",
+			paths: []string{"C:/b.vhdx", "C:/a.vhdx"},
+			want:  []string{"C:/a.vhdx", "C:/b.vhdx"},
+		},
+		{
+			name:  "dependency first",
+			paths: []string{"C:/a.vhdx", "C:/b.vhdx"},
+			deps:  map[string][]string{"C:/a.vhdx": {"c:/B.vhdx"}},
+			want:  []string{"C:/b.vhdx", "C:/a.vhdx"},
+		},
+		{
+			name:  "chain",
+			paths: []string{"C:/c.vhdx", "C:/a.vhdx", "C:/b.vhdx"},
+			deps: map[string][]string{
+				"C:/a.vhdx": {"C:/c.vhdx"},
+				"C:/c.vhdx": {"C:/b.vhdx"},
+			},
+			want: []string{"C:/b.vhdx", "C:/c.vhdx", "C:/a.vhdx"},
+		},
+		{
+			name:  "unknown dependency ignored",
+			paths: []string{"C:/a.vhdx"},
+			deps:  map[string][]string{"C:/a.vhdx": {"C:/gone.vhdx"}},
+			want:  []string{"C:/a.vhdx"},
+		},
+		{
+			name:  "cycle",
+			paths: []string{"C:/a.vhdx", "C:/b.vhdx"},
+			deps: map[string][]string{
+				"C:/a.vhdx": {"C:/b.vhdx"},
+				"C:/b.vhdx": {"C:/a.vhdx"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sortByDependencies(tt.paths, tt.deps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sortByDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("sortByDependencies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveReference(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	vhdPath := "C:/Users/me/AppData/Local/Packages/Ubuntu/LocalState/ext4.vhdx"
+	if err := tracker.SaveReference(vhdPath, "Ubuntu"); err != nil {
+		t.Fatalf("SaveReference failed: %v", err)
+	}
+
+	entry, err := tracker.GetEntry(vhdPath)
+	if err != nil {
+		t.Fatalf("GetEntry failed: %v", err)
+	}
+	if !entry.ReadOnly || entry.Distro != "Ubuntu" {
+		t.Errorf("Expected read-only reference for Ubuntu, got %+v", entry)
+	}
+	if entry.OriginalPath != vhdPath {
+		t.Errorf("OriginalPath = %q, want %q", entry.OriginalPath, vhdPath)
+	}
+
+	// Later mappings keep the reference flags
+	tracker.SaveMapping(vhdPath, "761c723c-80c8-41dc-b322-6f04d1160e43", "", "")
+	entry, _ = tracker.GetEntry(vhdPath)
+	if !entry.ReadOnly {
+		t.Error("SaveMapping cleared the read-only flag")
+	}
+}
+
+func TestReadCache(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	if err := tracker.SaveMapping("C:/VMs/cached.vhdx", "uuid-1", "/mnt/cached", "sdd"); err != nil {
+		t.Fatalf("SaveMapping() error = %v", err)
+	}
+
+	// Modifying a returned copy must not leak into the cache
+	tf, err := tracker.read()
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+	entry := tf.Mappings["c:/vms/cached.vhdx"]
+	entry.MountPoints[0] = "/mnt/changed"
+	tf.Mappings["c:/vms/cached.vhdx"] = entry
+	delete(tf.Mappings, "c:/vms/cached.vhdx")
+
+	got, err := tracker.GetEntry("C:/VMs/cached.vhdx")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if len(got.MountPoints) != 1 || got.MountPoints[0] != "/mnt/cached" {
+		t.Errorf("MountPoints = %v, want [/mnt/cached]", got.MountPoints)
+	}
+
+	// Nor may changes to snapshots and checks, which are shared by reference
+	err = tracker.Update("C:/VMs/cached.vhdx", func(e *types.TrackingEntry) {
+		e.Snapshots = []types.Snapshot{{Name: "before"}}
+		e.ImageCheck = &types.ImageCheckResult{Corruptions: 0}
+		e.MountCheck = &types.MountCheck{Sentinel: "data"}
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	tf, err = tracker.read()
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+	entry = tf.Mappings["c:/vms/cached.vhdx"]
+	entry.Snapshots[0].Name = "changed"
+	entry.ImageCheck.Corruptions = 3
+	entry.MountCheck.Sentinel = "changed"
+
+	got, err = tracker.GetEntry("C:/VMs/cached.vhdx")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if got.Snapshots[0].Name != "before" || got.ImageCheck.Corruptions != 0 || got.MountCheck.Sentinel != "data" {
+		t.Errorf("entry = %+v, want the cached snapshots and checks unchanged", got)
+	}
+
+	// A write by another process (rename over the file) invalidates the cache
+	other, err := New(tracker.filePath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := other.SaveMapping("C:/VMs/cached.vhdx", "uuid-2", "", ""); err != nil {
+		t.Fatalf("SaveMapping() error = %v", err)
+	}
+
+	uuid, err := tracker.LookupUUIDByPath("C:/VMs/cached.vhdx")
+	if err != nil {
+		t.Fatalf("LookupUUIDByPath() error = %v", err)
+	}
+	if uuid != "uuid-2" {
+		t.Errorf("UUID = %q, want uuid-2 after external write", uuid)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	if err := tracker.SaveMapping("C:/VMs/Update.vhdx", "uuid-1", "/mnt/update", "sdd"); err != nil {
+		t.Fatalf("SaveMapping() error = %v", err)
+	}
+	if err := tracker.SetAfter("C:/VMs/Update.vhdx", []string{"C:/VMs/base.vhdx"}); err != nil {
+		t.Fatalf("SetAfter() error = %v", err)
+	}
+
+	err := tracker.Update("c:/vms/update.vhdx", func(entry *types.TrackingEntry) {
+		entry.DeviceName = ""
+		entry.MountPoints = nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	entry, err := tracker.GetEntry("C:/VMs/Update.vhdx")
+	if err != nil {
+		t.Fatalf("GetEntry() error = %v", err)
+	}
+	if entry.DeviceName != "" || len(entry.MountPoints) != 0 {
+		t.Errorf("Update() did not clear device/mount points: %+v", entry)
+	}
+	if entry.UUID != "uuid-1" || entry.LastMount != "/mnt/update" || len(entry.After) != 1 {
+		t.Errorf("Update() modified other fields: %+v", entry)
+	}
+	if entry.OriginalPath != "C:/VMs/Update.vhdx" {
+		t.Errorf("OriginalPath = %q, want original casing kept", entry.OriginalPath)
+	}
+
+	// Untracked paths are ignored
+	called := false
+	if err := tracker.Update("C:/VMs/missing.vhdx", func(*types.TrackingEntry) { called = true }); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if called {
+		t.Error("Update() called fn for an untracked path")
+	}
+	if _, err := tracker.GetEntry("C:/VMs/missing.vhdx"); err == nil {
+		t.Error("Update() created an entry for an untracked path")
+	}
+}
+
+func TestRecordDeviceDelay(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	for i := 1; i <= deviceDelayLimit+5; i++ {
+		if err := tracker.RecordDeviceDelay(time.Duration(i) * time.Millisecond); err != nil {
+			t.Fatalf("RecordDeviceDelay() error = %v", err)
+		}
+	}
+
+	delays, err := tracker.DeviceDelays()
+	if err != nil {
+		t.Fatalf("DeviceDelays() error = %v", err)
+	}
+	if len(delays) != deviceDelayLimit {
+		t.Fatalf("DeviceDelays() returned %d samples, want %d", len(delays), deviceDelayLimit)
+	}
+	if delays[0] != 6*time.Millisecond || delays[len(delays)-1] != time.Duration(deviceDelayLimit+5)*time.Millisecond {
+		t.Errorf("DeviceDelays() = %v, want the most recent samples oldest first", delays)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	content := `{"mappings":{` +
+		`"c:/vms/data.vhdx":{"uuid":"uuid-1","last_attached":"2024-01-02T10:00:00Z","mount_points":"/mnt/data","devname":"/dev/sdd"},` +
+		`"D:\\Disks\\Logs.vhdx":{"uuid":"uuid-2","mount_point":"/mnt/logs","owner":"ops"}},` +
+		`"detach_history":[{"path":"C:/VMs/Data.vhdx","uuid":"uuid-1","timestamp":"2024-01-01T09:00:00Z"}]}`
+	if err := os.WriteFile(tracker.filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	backup := tracker.filePath + ".bak"
+
+	m, err := tracker.MigrateLegacy(backup, true)
+	if err != nil {
+		t.Fatalf("MigrateLegacy(dry run) error = %v", err)
+	}
+	if !m.Changed() || m.BackupPath != "" {
+		t.Errorf("MigrateLegacy(dry run) = %+v", m)
+	}
+	if data, _ := os.ReadFile(tracker.filePath); string(data) != content {
+		t.Error("dry run changed the tracking file")
+	}
+
+	m, err = tracker.MigrateLegacy(backup, false)
+	if err != nil {
+		t.Fatalf("MigrateLegacy() error = %v", err)
+	}
+	if m.Entries != 2 || m.Keys != 3 || m.Paths != 2 || m.History != 1 || m.BackupPath != backup {
+		t.Errorf("MigrateLegacy() = %+v", m)
+	}
+	if data, _ := os.ReadFile(backup); string(data) != content {
+		t.Error("backup differs from the original file")
+	}
+
+	data, err := tracker.GetEntry("C:/VMs/Data.vhdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.OriginalPath != "C:/VMs/Data.vhdx" || data.LastSeen != "2024-01-02T10:00:00Z" || data.DeviceName != "sdd" || !slices.Equal(data.MountPoints, []string{"/mnt/data"}) {
+		t.Errorf("data entry = %+v", data)
+	}
+	logs, err := tracker.GetEntry("d:/disks/logs.vhdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logs.OriginalPath != "D:/Disks/Logs.vhdx" || !slices.Equal(logs.MountPoints, []string{"/mnt/logs"}) || string(logs.Extra["owner"]) != `"ops"` {
+		t.Errorf("logs entry = %+v", logs)
+	}
+
+	// Migrated once
+	if m, err := tracker.MigrateLegacy(backup+"2", false); err != nil || m.Changed() {
+		t.Errorf("second MigrateLegacy() = %+v, %v, want no changes", m, err)
+	}
+}
+
+func TestUnknownFieldsSurviveWrites(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	content := `{"version":"1.0","mappings":{"c:/vms/keep.vhdx":{"uuid":"uuid-1","last_seen":"","mount_points":[],"dev_name":"","owner":"ops"}}}`
+	if err := os.WriteFile(tracker.filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if err := tracker.SaveMapping("C:/VMs/keep.vhdx", "uuid-1", "/mnt/keep", "sdd"); err != nil {
+		t.Fatalf("SaveMapping() error = %v", err)
+	}
+
+	data, err := os.ReadFile(tracker.filePath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"owner": "ops"`) {
+		t.Errorf("Unknown field dropped after SaveMapping:\n%s", data)
+	}
+}
+
+func TestWatchChanges(t *testing.T) {
+	tracker, cleanup := setupTestTracker(t)
+	defer cleanup()
+
+	watcher, err := tracker.WatchChanges()
+	if err != nil {
+		t.Fatalf("WatchChanges() error = %v", err)
+	}
+	defer watcher.Close()
+
+	changed, err := watcher.Wait(50 * time.Millisecond)
+	if err != nil || changed {
+		t.Fatalf("Wait() without changes = %v, %v; want false, nil", changed, err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		tracker.SaveMapping("C:/VMs/notify.vhdx", "uuid-1", "", "")
+	}()
+
+	changed, err = watcher.Wait(5 * time.Second)
+	if err != nil || !changed {
+		t.Fatalf("Wait() after SaveMapping = %v, %v; want true, nil", changed, err)
+	}
+}
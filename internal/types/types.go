@@ -2,9 +2,12 @@
 package types
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -17,18 +20,29 @@ const (
 	StateAttachedUnformatted VHDState = "attached (unformatted)"
 	StateAttachedFormatted   VHDState = "attached"
 	StateMounted             VHDState = "mounted"
+	StateArchived            VHDState = "archived"
 )
 
 // VHDInfo holds detailed information about a VHD
 type VHDInfo struct {
-	Path       string   `json:"path,omitempty"`
-	UUID       string   `json:"uuid,omitempty"`
-	DeviceName string   `json:"deviceName,omitempty"`
-	MountPoint string   `json:"mountPoint,omitempty"`
-	FSAvail    string   `json:"fsAvail,omitempty"`
-	FSUse      string   `json:"fsUse,omitempty"`
-	LastSeen   string   `json:"lastSeen,omitempty"`
-	State      VHDState `json:"state"`
+	Path        string   `json:"path,omitempty"`
+	UUID        string   `json:"uuid,omitempty"`
+	Label       string   `json:"label,omitempty"`
+	PartUUID    string   `json:"partUUID,omitempty"`
+	DeviceName  string   `json:"deviceName,omitempty"`
+	MountPoint  string   `json:"mountPoint,omitempty"`
+	FSAvail     string   `json:"fsAvail,omitempty"`
+	FSUse       string   `json:"fsUse,omitempty"`
+	LastSeen    string   `json:"lastSeen,omitempty"`
+	Note        string   `json:"note,omitempty"`
+	Pinned      bool     `json:"pinned,omitempty"`
+	FSType      string   `json:"fsType,omitempty"`
+	External    bool     `json:"externallyManaged,omitempty"`
+	BackingFile string   `json:"backingFile,omitempty"`
+	Service     string   `json:"service,omitempty"`
+	State       VHDState `json:"state"`
+
+	ImageCheck *ImageCheckResult `json:"imageCheck,omitempty"`
 }
 
 // MountPoints handles both string and array formats for mount_points
@@ -72,17 +86,241 @@ func (m MountPoints) MarshalJSON() ([]byte, error) {
 
 // TrackingEntry represents a single entry in the VHD tracking file
 type TrackingEntry struct {
-	UUID         string      `json:"uuid"`
-	LastSeen     string      `json:"last_seen"`
-	MountPoints  MountPoints `json:"mount_points"`
-	DeviceName   string      `json:"dev_name"`
-	OriginalPath string      `json:"original_path,omitempty"` // Preserve original case
+	UUID         string       `json:"uuid"`
+	Label        string       `json:"label,omitempty"`    // Filesystem label seen while attached
+	PartUUID     string       `json:"partuuid,omitempty"` // Partition UUID seen while attached
+	LastSeen     string       `json:"last_seen"`
+	MountPoints  MountPoints  `json:"mount_points"`
+	DeviceName   string       `json:"dev_name"`
+	OriginalPath string       `json:"original_path,omitempty"` // Preserve original case
+	Archived     bool         `json:"archived,omitempty"`      // VHD compressed to <path>.zst
+	SizeHistory  []SizeSample `json:"size_history,omitempty"`  // Samples recorded by report
+	LastMount    string       `json:"last_mount,omitempty"`    // Most recent mount point, kept across unmounts
+	After        []string     `json:"after,omitempty"`         // VHD paths that must be mounted first
+	Distro       string       `json:"distro,omitempty"`        // WSL distribution owning this system VHD
+	ReadOnly     bool         `json:"read_only,omitempty"`     // Reference only: vhdm must not modify it
+	Note         string       `json:"note,omitempty"`          // User description, see 'vhdm note'
+	Pinned       bool         `json:"pinned,omitempty"`        // Protected from delete, format and resize
+	MountOptions string       `json:"mount_options,omitempty"` // Options of the last 'vhdm mount', reused by services
+	FSType       string       `json:"fs_type,omitempty"`       // Filesystem type seen at the last mount
+	External     bool         `json:"external,omitempty"`      // Mount points last changed outside vhdm
+	BackingFile  string       `json:"backing_file,omitempty"`  // Base VHD of a linked clone, see 'vhdm clone --linked'
+	Snapshots    []Snapshot   `json:"snapshots,omitempty"`     // Point-in-time copies, see 'vhdm snapshot'
+	Service      string       `json:"service,omitempty"`       // Unit installed by 'vhdm service create'
+
+	ImageCheck *ImageCheckResult `json:"image_check,omitempty"` // Last 'vhdm check-image' result
+	MountCheck *MountCheck       `json:"mount_check,omitempty"` // Verified after each mount, see 'vhdm mount-check'
+
+	// Extra holds keys this version does not know (written by newer versions
+	// or other tools), so they survive a read-modify-write
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes a tracking entry, keeping unknown keys in Extra
+func (e *TrackingEntry) UnmarshalJSON(data []byte) error {
+	type plain TrackingEntry
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	extra, err := unknownKeys(data, p)
+	if err != nil {
+		return err
+	}
+	*e = TrackingEntry(p)
+	e.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes a tracking entry, appending the keys kept in Extra
+func (e TrackingEntry) MarshalJSON() ([]byte, error) {
+	type plain TrackingEntry
+	data, err := json.Marshal(plain(e))
+	if err != nil {
+		return nil, err
+	}
+	return appendKeys(data, e.Extra, plain(e))
+}
+
+// SizeSample records the size of a VHD file at a point in time
+type SizeSample struct {
+	Time          string `json:"time"`
+	VirtualSize   int64  `json:"virtual_size"`
+	AllocatedSize int64  `json:"allocated_size"`
+}
+
+// MountCheck is how a mount of a VHD is verified before it counts as
+// successful: a sentinel file that must exist in it, a command that must
+// succeed, or both
+type MountCheck struct {
+	Sentinel string `json:"sentinel,omitempty"` // Path relative to the mount point
+	Command  string `json:"command,omitempty"`  // Run with sh -c in the mount point
+}
+
+// Snapshot is a point-in-time copy of a VHD file kept next to it
+type Snapshot struct {
+	Name    string `json:"name"`
+	File    string `json:"file"`           // Windows format path of the copy
+	Created string `json:"created"`        // RFC 3339
+	UUID    string `json:"uuid,omitempty"` // Filesystem UUID when taken
+	Size    int64  `json:"size"`           // Bytes
+}
+
+// IDFile is the content of the .vhdm.json file written at the root of each
+// filesystem vhdm formats. It names the VHD the filesystem was created in, so
+// a mount can tell when tracking points at another file.
+type IDFile struct {
+	Path    string `json:"path"`    // VHD file path (Windows format)
+	Name    string `json:"name"`    // VHD file name without extension
+	UUID    string `json:"uuid"`    // Filesystem UUID when written
+	Created string `json:"created"` // RFC 3339
+}
+
+// ImageCheckResult records the last qemu-img check of a VHD file
+type ImageCheckResult struct {
+	Time        string `json:"time"`
+	Corruptions int    `json:"corruptions"`
+	Leaks       int    `json:"leaks"`
+	CheckErrors int    `json:"check_errors"`
+	Error       string `json:"error,omitempty"`   // Why the check could not run
+	Skipped     string `json:"skipped,omitempty"` // Why the image was not checked, e.g. a format without a check
+}
+
+// Problem describes what the check found, or returns "" for a clean image.
+// Leaks only waste space; corruptions and check errors mean damage.
+func (r ImageCheckResult) Problem() string {
+	var parts []string
+	if r.Error != "" {
+		parts = append(parts, "check failed: "+r.Error)
+	}
+	if r.Corruptions > 0 {
+		parts = append(parts, plural(r.Corruptions, "corruption"))
+	}
+	if r.CheckErrors > 0 {
+		parts = append(parts, plural(r.CheckErrors, "check error"))
+	}
+	if r.Leaks > 0 {
+		parts = append(parts, plural(r.Leaks, "leaked cluster"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Damaged reports whether the check found corruptions or errors
+func (r ImageCheckResult) Damaged() bool {
+	return r.Corruptions > 0 || r.CheckErrors > 0
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
 }
 
 // TrackingFile represents the structure of the VHD tracking JSON file
 type TrackingFile struct {
 	Version  string                   `json:"version"`
 	Mappings map[string]TrackingEntry `json:"mappings"`
+
+	// DeviceDelays are the milliseconds recent attaches took until the new
+	// block device appeared, used to calibrate how long attaches wait for it
+	DeviceDelays []int64 `json:"device_delays_ms,omitempty"`
+
+	// Extra holds unknown top-level keys, see TrackingEntry.Extra
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes a tracking file, keeping unknown keys in Extra
+func (f *TrackingFile) UnmarshalJSON(data []byte) error {
+	type plain TrackingFile
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	extra, err := unknownKeys(data, p)
+	if err != nil {
+		return err
+	}
+	*f = TrackingFile(p)
+	f.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes a tracking file, appending the keys kept in Extra
+func (f TrackingFile) MarshalJSON() ([]byte, error) {
+	type plain TrackingFile
+	data, err := json.Marshal(plain(f))
+	if err != nil {
+		return nil, err
+	}
+	return appendKeys(data, f.Extra, plain(f))
+}
+
+// jsonKeys returns the JSON object keys of a struct's fields
+func jsonKeys(v any) map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		keys[name] = true
+	}
+	return keys
+}
+
+// unknownKeys returns the keys of a JSON object that v's struct fields do
+// not map, or nil if there are none
+func unknownKeys(data []byte, v any) (map[string]json.RawMessage, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	known := jsonKeys(v)
+	for key := range raw {
+		if known[key] {
+			delete(raw, key)
+		}
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	return raw, nil
+}
+
+// appendKeys appends extra keys (in sorted order) to an encoded JSON object,
+// skipping keys that v's struct fields already encode
+func appendKeys(data []byte, extra map[string]json.RawMessage, v any) ([]byte, error) {
+	if len(extra) == 0 {
+		return data, nil
+	}
+	known := jsonKeys(v)
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		if !known[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	out := bytes.TrimSuffix(bytes.TrimSpace(data), []byte("}"))
+	for _, key := range keys {
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > 1 {
+			out = append(out, ',')
+		}
+		out = append(out, name...)
+		out = append(out, ':')
+		out = append(out, extra[key]...)
+	}
+	return append(out, '}'), nil
 }
 
 // AttachResult holds the result of an attach operation
@@ -102,8 +340,73 @@ var (
 	ErrMultipleVHDs       = errors.New("multiple VHDs attached - specify UUID or path")
 	ErrDeviceNotFound     = errors.New("device not found after attach")
 	ErrDetachTimeout      = errors.New("detach operation timed out")
+	ErrFileInUse          = errors.New("file in use by Windows process")
+	ErrVHDNotTracked      = errors.New("VHD is not tracked in the system")
+	ErrVHDPinned          = errors.New("VHD is pinned")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrMountCheckFailed   = errors.New("mount check failed")
+	ErrMountPointConflict = errors.New("mount point claimed by another VHD")
 )
 
+// Error codes reported by ErrorCode. They are part of the CLI interface:
+// scripts branch on them, so they must not change once released.
+const (
+	CodeError           = "VHDM_ERROR" // Any failure without a more specific code
+	CodeNotFound        = "VHDM_NOT_FOUND"
+	CodeNotAttached     = "VHDM_NOT_ATTACHED"
+	CodeAlreadyAttached = "VHDM_ALREADY_ATTACHED"
+	CodeNotMounted      = "VHDM_NOT_MOUNTED"
+	CodeNotFormatted    = "VHDM_NOT_FORMATTED"
+	CodeMultipleVHDs    = "VHDM_MULTIPLE_VHDS"
+	CodeDeviceNotFound  = "VHDM_DEVICE_NOT_FOUND"
+	CodeDetachTimeout   = "VHDM_DETACH_TIMEOUT"
+	CodeFileInUse       = "VHDM_FILE_IN_USE"
+	CodeNotTracked      = "VHDM_NOT_TRACKED"
+	CodePinned          = "VHDM_PINNED"
+	CodeReadOnly        = "VHDM_READ_ONLY"
+	CodeInvalidInput    = "VHDM_INVALID_INPUT"
+	CodeMountCheck      = "VHDM_MOUNT_CHECK_FAILED"
+	CodeMountConflict   = "VHDM_MOUNT_POINT_CONFLICT"
+)
+
+// errorCodes maps the common errors to their codes
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrVHDNotFound, CodeNotFound},
+	{ErrVHDNotAttached, CodeNotAttached},
+	{ErrVHDAlreadyAttached, CodeAlreadyAttached},
+	{ErrVHDNotMounted, CodeNotMounted},
+	{ErrVHDNotFormatted, CodeNotFormatted},
+	{ErrMultipleVHDs, CodeMultipleVHDs},
+	{ErrDeviceNotFound, CodeDeviceNotFound},
+	{ErrDetachTimeout, CodeDetachTimeout},
+	{ErrFileInUse, CodeFileInUse},
+	{ErrVHDNotTracked, CodeNotTracked},
+	{ErrVHDPinned, CodePinned},
+	{ErrInvalidInput, CodeInvalidInput},
+	{ErrMountCheckFailed, CodeMountCheck},
+	{ErrMountPointConflict, CodeMountConflict},
+}
+
+// ErrorCode returns the machine-readable code of err: the Code of the
+// outermost VHDError that sets one, else the code of the common error it
+// wraps, else CodeError
+func ErrorCode(err error) string {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if vhdErr, ok := e.(*VHDError); ok && vhdErr.Code != "" {
+			return vhdErr.Code
+		}
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return CodeError
+}
+
 // IsAlreadyAttached checks if error indicates already attached
 func IsAlreadyAttached(err error) bool {
 	return errors.Is(err, ErrVHDAlreadyAttached)
@@ -120,6 +423,7 @@ type VHDError struct {
 	Path string
 	Err  error
 	Help string
+	Code string // Machine-readable code (see ErrorCode), derived from Err when empty
 }
 
 func (e *VHDError) Error() string {
@@ -137,3 +441,13 @@ func (e *VHDError) Unwrap() error {
 func (e *VHDError) HelpText() string {
 	return e.Help
 }
+
+// ExitError reports that a command run by vhdm (e.g., via 'vhdm exec') exited
+// with a non-zero status. The CLI exits with the same code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.Code)
+}
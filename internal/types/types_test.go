@@ -3,6 +3,8 @@ package types
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -167,6 +169,19 @@ func TestVHDError(t *testing.T) {
 	}
 }
 
+func TestExitError(t *testing.T) {
+	var err error = &ExitError{Code: 3}
+	if got := err.Error(); got != "command exited with status 3" {
+		t.Errorf("ExitError.Error() = %q", got)
+	}
+
+	wrapped := fmt.Errorf("exec: %w", err)
+	var exitErr *ExitError
+	if !errors.As(wrapped, &exitErr) || exitErr.Code != 3 {
+		t.Errorf("errors.As() did not find ExitError with code 3")
+	}
+}
+
 func TestIsAlreadyAttached(t *testing.T) {
 	tests := []struct {
 		name string
@@ -189,6 +204,28 @@ func TestIsAlreadyAttached(t *testing.T) {
 	}
 }
 
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"common error", ErrVHDNotFormatted, CodeNotFormatted},
+		{"wrapped common error", &VHDError{Op: "mount", Err: fmt.Errorf("check: %w", ErrVHDNotFormatted)}, CodeNotFormatted},
+		{"explicit code", &VHDError{Op: "resize", Err: errors.New("read-only"), Code: CodeReadOnly}, CodeReadOnly},
+		{"explicit code wins", &VHDError{Op: "attach", Err: ErrVHDNotFound, Code: CodeNotTracked}, CodeNotTracked},
+		{"generic error", errors.New("some error"), CodeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorCode(tt.err); got != tt.want {
+				t.Errorf("ErrorCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsNotAttached(t *testing.T) {
 	tests := []struct {
 		name string
@@ -220,6 +257,7 @@ func TestVHDState(t *testing.T) {
 		StateAttachedUnformatted,
 		StateAttachedFormatted,
 		StateMounted,
+		StateArchived,
 	}
 	
 	for _, s := range states {
@@ -323,3 +361,95 @@ func TestTrackingFileJSON(t *testing.T) {
 		t.Errorf("LastSeen mismatch: got %s", entry.LastSeen)
 	}
 }
+
+func TestTrackingUnknownFields(t *testing.T) {
+	input := `{
+		"version": "1.0",
+		"schema_hint": {"v": 2},
+		"mappings": {
+			"c:/vms/test.vhdx": {
+				"uuid": "761c723c-80c8-41dc-b322-6f04d1160e43",
+				"last_seen": "2025-12-01T12:00:00Z",
+				"mount_points": ["/mnt/test"],
+				"dev_name": "sdd",
+				"labels": ["db", "prod"],
+				"owner": "ops"
+			}
+		}
+	}`
+
+	var tf TrackingFile
+	if err := json.Unmarshal([]byte(input), &tf); err != nil {
+		t.Fatalf("Failed to unmarshal TrackingFile: %v", err)
+	}
+	entry := tf.Mappings["c:/vms/test.vhdx"]
+	if entry.UUID != "761c723c-80c8-41dc-b322-6f04d1160e43" || entry.DeviceName != "sdd" {
+		t.Errorf("Known fields not decoded: %+v", entry)
+	}
+	if len(entry.Extra) != 2 || len(tf.Extra) != 1 {
+		t.Fatalf("Extra = %v / %v, want 2 entry keys and 1 file key", entry.Extra, tf.Extra)
+	}
+
+	// Modify a known field and round-trip
+	entry.DeviceName = "sde"
+	tf.Mappings["c:/vms/test.vhdx"] = entry
+	data, err := json.Marshal(tf)
+	if err != nil {
+		t.Fatalf("Failed to marshal TrackingFile: %v", err)
+	}
+
+	var raw struct {
+		SchemaHint map[string]int `json:"schema_hint"`
+		Mappings   map[string]map[string]json.RawMessage
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Failed to decode output: %v", err)
+	}
+	if raw.SchemaHint["v"] != 2 {
+		t.Errorf("Top-level unknown key lost: %s", data)
+	}
+	fields := raw.Mappings["c:/vms/test.vhdx"]
+	if string(fields["owner"]) != `"ops"` || string(fields["labels"]) != `["db","prod"]` {
+		t.Errorf("Entry unknown keys lost: %s", data)
+	}
+	if string(fields["dev_name"]) != `"sde"` {
+		t.Errorf("dev_name = %s, want \"sde\"", fields["dev_name"])
+	}
+}
+
+func TestTrackingEntryExtraDoesNotOverrideKnown(t *testing.T) {
+	entry := TrackingEntry{
+		UUID:  "abc",
+		Extra: map[string]json.RawMessage{"uuid": json.RawMessage(`"stale"`)},
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Failed to marshal TrackingEntry: %v", err)
+	}
+	if strings.Count(string(data), `"uuid"`) != 1 {
+		t.Errorf("Duplicate uuid key in %s", data)
+	}
+}
+
+func TestImageCheckResultProblem(t *testing.T) {
+	tests := []struct {
+		result  ImageCheckResult
+		want    string
+		damaged bool
+	}{
+		{ImageCheckResult{}, "", false},
+		{ImageCheckResult{Leaks: 3}, "3 leaked clusters", false},
+		{ImageCheckResult{Corruptions: 1, Leaks: 1}, "1 corruption, 1 leaked cluster", true},
+		{ImageCheckResult{CheckErrors: 2}, "2 check errors", true},
+		{ImageCheckResult{Error: "image format does not support checks"}, "check failed: image format does not support checks", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.result.Problem(); got != tt.want {
+			t.Errorf("%+v.Problem() = %q, want %q", tt.result, got, tt.want)
+		}
+		if got := tt.result.Damaged(); got != tt.damaged {
+			t.Errorf("%+v.Damaged() = %v, want %v", tt.result, got, tt.damaged)
+		}
+	}
+}
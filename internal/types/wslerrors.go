@@ -0,0 +1,52 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+)
+
+// WSLErrorCode maps an error code reported by wsl.exe to a vhdm error.
+// wsl.exe prints its messages in the Windows display language, but names the
+// failure the same way in every locale: as a Wsl/<component>/<name> path
+// ending in a WSL_E_* or Win32 ERROR_* name, or as an HRESULT.
+type WSLErrorCode struct {
+	Op      string // wsl.exe operation the code applies to ("attach", "unmount"), empty for any
+	Name    string // Symbolic name, e.g. WSL_E_USER_VHD_ALREADY_ATTACHED
+	HRESULT uint32 // Numeric form, 0 when matched by name only
+	Err     error
+}
+
+// WSLErrorCodes are the wsl.exe error codes vhdm recognizes. Add an entry
+// when a new failure needs handling rather than matching message text.
+var WSLErrorCodes = []WSLErrorCode{
+	{Op: "attach", Name: "WSL_E_USER_VHD_ALREADY_ATTACHED", Err: ErrVHDAlreadyAttached},
+	{Op: "attach", Name: "ERROR_FILE_NOT_FOUND", HRESULT: 0x80070002, Err: ErrVHDNotFound},
+	{Op: "attach", Name: "ERROR_PATH_NOT_FOUND", HRESULT: 0x80070003, Err: ErrVHDNotFound},
+	{Op: "attach", Name: "ERROR_SHARING_VIOLATION", HRESULT: 0x80070020, Err: ErrFileInUse},
+	{Op: "unmount", Name: "ERROR_FILE_NOT_FOUND", HRESULT: 0x80070002, Err: ErrVHDNotAttached},
+}
+
+// LookupWSLError returns the vhdm error for the first of codes that
+// WSLErrorCodes maps for op, or nil. Codes are full Wsl/... paths, bare
+// names or HRESULTs in hexadecimal (0x80070002).
+func LookupWSLError(op string, codes []string) error {
+	for _, code := range codes {
+		for _, e := range WSLErrorCodes {
+			if (e.Op == "" || e.Op == op) && e.matches(code) {
+				return e.Err
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether a code names e. Only the last component of a
+// Wsl/... path counts.
+func (e WSLErrorCode) matches(code string) bool {
+	code = code[strings.LastIndex(code, "/")+1:]
+	if hex, ok := strings.CutPrefix(strings.ToLower(code), "0x"); ok {
+		n, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil && e.HRESULT != 0 && uint32(n) == e.HRESULT
+	}
+	return strings.EqualFold(code, e.Name)
+}
@@ -0,0 +1,26 @@
+package types
+
+import "testing"
+
+func TestLookupWSLError(t *testing.T) {
+	tests := []struct {
+		op    string
+		codes []string
+		want  error
+	}{
+		{"attach", []string{"Wsl/Service/AttachDisk/WSL_E_USER_VHD_ALREADY_ATTACHED"}, ErrVHDAlreadyAttached},
+		{"attach", []string{"wsl_e_user_vhd_already_attached"}, ErrVHDAlreadyAttached},
+		{"attach", []string{"0x80070020"}, ErrFileInUse},
+		{"unmount", []string{"Wsl/Service/DetachDisk/ERROR_FILE_NOT_FOUND"}, ErrVHDNotAttached},
+		{"unmount", []string{"0x80070002"}, ErrVHDNotAttached},
+		{"attach", []string{"0x80070002"}, ErrVHDNotFound},
+		{"unmount", []string{"E_ACCESSDENIED", "0x80070005"}, nil},
+		{"export", []string{"Wsl/ERROR_FILE_NOT_FOUND"}, nil},
+		{"attach", []string{"Wsl/Service/AttachDisk/NOT_WSL_E_USER_VHD_ALREADY_ATTACHED"}, nil},
+	}
+	for _, tt := range tests {
+		if got := LookupWSLError(tt.op, tt.codes); got != tt.want {
+			t.Errorf("LookupWSLError(%q, %q) = %v, want %v", tt.op, tt.codes, got, tt.want)
+		}
+	}
+}
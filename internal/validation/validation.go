@@ -4,73 +4,150 @@ package validation
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+
+	"github.com/rjdinis/vhdm/internal/types"
 )
 
 const (
 	maxPathLength = 4096
 )
 
+// FilesystemTypes are the filesystem types vhdm formats VHDs with
+var FilesystemTypes = []string{"ext4", "ext3", "ext2", "xfs", "btrfs"}
+
+// CommonSizes are VHD sizes suggested by shell completion; all of them pass
+// ValidateSizeString
+var CommonSizes = []string{"1G", "5G", "10G", "50G", "100G", "256G"}
+
 var (
 	// Windows path: C:/ or C:\
 	windowsPathRe = regexp.MustCompile(`^[A-Za-z]:[/\\]`)
 	// UUID format: xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
 	uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	// Volume serial numbers used as UUID by FAT/exFAT (XXXX-XXXX) and NTFS
+	serialUUIDRe = regexp.MustCompile(`^([0-9a-fA-F]{4}-[0-9a-fA-F]{4}|[0-9a-fA-F]{16})$`)
+	// MBR partition UUID: disk signature and partition number (xxxxxxxx-nn)
+	mbrPartUUIDRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{2}$`)
 	// Device name: sd[a-z]+
 	deviceNameRe = regexp.MustCompile(`^sd[a-z]+$`)
 	// Size string: number with optional unit
 	sizeRe = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[KMGT]?[B]?$`)
+	// Mount options: comma-separated words for mount -o (e.g., ro,noatime)
+	mountOptionsRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_=.:+/-]*(,[A-Za-z0-9_][A-Za-z0-9_=.:+/-]*)*$`)
+	// Snapshot name: becomes part of a file name
+	snapshotNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
 	// Dangerous shell characters
 	dangerousChars = regexp.MustCompile("[$`;&|<>\"'*?\\[\\]!~]")
 )
 
+// inputError is a validation failure; it matches types.ErrInvalidInput
+type inputError struct {
+	msg string
+}
+
+func (e *inputError) Error() string { return e.msg }
+
+func (e *inputError) Unwrap() error { return types.ErrInvalidInput }
+
+// invalidf returns an inputError with a formatted message
+func invalidf(format string, args ...any) error {
+	return &inputError{msg: fmt.Sprintf(format, args...)}
+}
+
 // ValidateWindowsPath validates a Windows path format
 func ValidateWindowsPath(path string) error {
 	if path == "" {
-		return fmt.Errorf("path cannot be empty")
+		return invalidf("path cannot be empty")
 	}
 	if len(path) > maxPathLength {
-		return fmt.Errorf("path too long")
+		return invalidf("path too long")
 	}
 	if !windowsPathRe.MatchString(path) {
-		return fmt.Errorf("invalid Windows path format")
+		return invalidf("invalid Windows path format")
 	}
 	if dangerousChars.MatchString(path) {
-		return fmt.Errorf("path contains invalid characters")
+		return invalidf("path contains invalid characters")
 	}
 	if strings.Contains(path, "..") {
-		return fmt.Errorf("path traversal not allowed")
+		return invalidf("path traversal not allowed")
 	}
 	return nil
 }
 
-// ValidateUUID validates a UUID format
+// ValidateUUID validates a filesystem UUID format. Besides standard UUIDs it
+// accepts the volume serial numbers FAT, exFAT and NTFS report as UUID.
 func ValidateUUID(uuid string) error {
 	if uuid == "" {
-		return fmt.Errorf("UUID cannot be empty")
+		return invalidf("UUID cannot be empty")
 	}
-	if !uuidRe.MatchString(uuid) {
-		return fmt.Errorf("invalid UUID format")
+	if !uuidRe.MatchString(uuid) && !serialUUIDRe.MatchString(uuid) {
+		return invalidf("invalid UUID format")
 	}
 	return nil
 }
 
+// Identifier kinds accepted by ParseIdentifier
+const (
+	IdentifierUUID     = "UUID"
+	IdentifierLabel    = "LABEL"
+	IdentifierPartUUID = "PARTUUID"
+)
+
+// ParseIdentifier parses a disk identifier as written in fstab: UUID=<uuid>,
+// LABEL=<label> or PARTUUID=<partuuid>. Without a prefix the kind is empty
+// and left to the caller to detect from the disks. Labels may contain spaces
+// but no shell metacharacters.
+func ParseIdentifier(id string) (kind, value string, err error) {
+	if id == "" {
+		return "", "", invalidf("identifier cannot be empty")
+	}
+	value = id
+	if k, v, ok := strings.Cut(id, "="); ok {
+		switch strings.ToUpper(k) {
+		case IdentifierUUID, IdentifierLabel, IdentifierPartUUID:
+			kind, value = strings.ToUpper(k), v
+		}
+	}
+
+	switch {
+	case value == "":
+		return "", "", invalidf("%s cannot be empty", kind)
+	case len(value) > 255:
+		return "", "", invalidf("identifier too long")
+	case dangerousChars.MatchString(value) || strings.ContainsAny(value, "/=\x00\n\r\t"):
+		return "", "", invalidf("identifier contains invalid characters")
+	}
+	switch kind {
+	case IdentifierUUID:
+		if err := ValidateUUID(value); err != nil {
+			return "", "", err
+		}
+	case IdentifierPartUUID:
+		if !uuidRe.MatchString(value) && !mbrPartUUIDRe.MatchString(value) {
+			return "", "", invalidf("invalid PARTUUID format")
+		}
+	}
+	return kind, value, nil
+}
+
 // ValidateMountPoint validates a mount point path
 func ValidateMountPoint(path string) error {
 	if path == "" {
-		return fmt.Errorf("mount point cannot be empty")
+		return invalidf("mount point cannot be empty")
 	}
 	if !strings.HasPrefix(path, "/") {
-		return fmt.Errorf("mount point must be absolute path")
+		return invalidf("mount point must be absolute path")
 	}
 	if len(path) > maxPathLength {
-		return fmt.Errorf("mount point path too long")
+		return invalidf("mount point path too long")
 	}
 	if dangerousChars.MatchString(path) {
-		return fmt.Errorf("mount point contains invalid characters")
+		return invalidf("mount point contains invalid characters")
 	}
 	if strings.Contains(path, "..") {
-		return fmt.Errorf("path traversal not allowed")
+		return invalidf("path traversal not allowed")
 	}
 	return nil
 }
@@ -78,12 +155,12 @@ func ValidateMountPoint(path string) error {
 // ValidateDeviceName validates a device name (e.g., sdd, sde)
 func ValidateDeviceName(name string) error {
 	if name == "" {
-		return fmt.Errorf("device name cannot be empty")
+		return invalidf("device name cannot be empty")
 	}
 	// Remove /dev/ prefix if present
 	name = strings.TrimPrefix(name, "/dev/")
 	if !deviceNameRe.MatchString(name) {
-		return fmt.Errorf("invalid device name format")
+		return invalidf("invalid device name format")
 	}
 	return nil
 }
@@ -91,23 +168,35 @@ func ValidateDeviceName(name string) error {
 // ValidateSizeString validates a size string (e.g., "5G", "500M")
 func ValidateSizeString(size string) error {
 	if size == "" {
-		return fmt.Errorf("size cannot be empty")
+		return invalidf("size cannot be empty")
 	}
 	size = strings.ToUpper(size)
 	if !sizeRe.MatchString(size) {
-		return fmt.Errorf("invalid size format (use e.g., 5G, 500M)")
+		return invalidf("invalid size format (use e.g., 5G, 500M)")
+	}
+	return nil
+}
+
+// ValidateSnapshotName validates the name of a VHD snapshot
+func ValidateSnapshotName(name string) error {
+	if !snapshotNameRe.MatchString(name) {
+		return invalidf("invalid snapshot name: %q (use up to 64 letters, digits, '.', '_' and '-')", name)
 	}
 	return nil
 }
 
 // ValidateFilesystemType validates a filesystem type
 func ValidateFilesystemType(fsType string) error {
-	allowed := map[string]bool{
-		"ext2": true, "ext3": true, "ext4": true,
-		"xfs": true, "btrfs": true,
+	if !slices.Contains(FilesystemTypes, fsType) {
+		return invalidf("unsupported filesystem type: %s (use %s)", fsType, strings.Join(FilesystemTypes, ", "))
 	}
-	if !allowed[fsType] {
-		return fmt.Errorf("unsupported filesystem type: %s (use ext2, ext3, ext4, xfs, btrfs)", fsType)
+	return nil
+}
+
+// ValidateMountOptions validates options for mount -o (e.g., "ro,noatime,discard")
+func ValidateMountOptions(options string) error {
+	if !mountOptionsRe.MatchString(options) {
+		return invalidf("invalid mount options: %q (use comma-separated options, e.g., ro,noatime)", options)
 	}
 	return nil
 }
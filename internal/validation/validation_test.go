@@ -63,6 +63,8 @@ func TestValidateUUID(t *testing.T) {
 		{"valid mixed case", "761c723C-80c8-41DC-b322-6f04D1160e43", false},
 		{"valid all zeros", "00000000-0000-0000-0000-000000000000", false},
 		{"valid all f", "ffffffff-ffff-ffff-ffff-ffffffffffff", false},
+		{"valid fat serial", "1A2B-3C4D", false},
+		{"valid ntfs serial", "5E3A9C2B1F0D4E6A", false},
 		
 		// Invalid UUIDs
 		{"empty", "", true},
@@ -230,3 +232,71 @@ func TestValidateFilesystemType(t *testing.T) {
 		})
 	}
 }
+
+func TestCompletionValuesValidate(t *testing.T) {
+	for _, size := range CommonSizes {
+		if err := ValidateSizeString(size); err != nil {
+			t.Errorf("CommonSizes entry %q is rejected: %v", size, err)
+		}
+	}
+	for _, fsType := range FilesystemTypes {
+		if err := ValidateFilesystemType(fsType); err != nil {
+			t.Errorf("FilesystemTypes entry %q is rejected: %v", fsType, err)
+		}
+	}
+}
+
+func TestParseIdentifier(t *testing.T) {
+	tests := []struct {
+		id        string
+		wantKind  string
+		wantValue string
+		wantErr   bool
+	}{
+		{"761c723c-80c8-41dc-b322-6f04d1160e43", "", "761c723c-80c8-41dc-b322-6f04d1160e43", false},
+		{"UUID=1A2B-3C4D", IdentifierUUID, "1A2B-3C4D", false},
+		{"label=Backup Disk", IdentifierLabel, "Backup Disk", false},
+		{"PARTUUID=0d9c1e5a-01", IdentifierPartUUID, "0d9c1e5a-01", false},
+		{"backup", "", "backup", false},
+		{"", "", "", true},
+		{"LABEL=", "", "", true},
+		{"UUID=backup", "", "", true},
+		{"PARTUUID=backup", "", "", true},
+		{"LABEL=a;reboot", "", "", true},
+		{"../data", "", "", true},
+	}
+
+	for _, tt := range tests {
+		kind, value, err := ParseIdentifier(tt.id)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseIdentifier(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			continue
+		}
+		if kind != tt.wantKind || value != tt.wantValue {
+			t.Errorf("ParseIdentifier(%q) = %q, %q, want %q, %q", tt.id, kind, value, tt.wantKind, tt.wantValue)
+		}
+	}
+}
+
+func TestValidateMountOptions(t *testing.T) {
+	tests := []struct {
+		options string
+		wantErr bool
+	}{
+		{"ro", false},
+		{"noatime,discard", false},
+		{"uid=1000,gid=1000,umask=022", false},
+		{"", true},
+		{"ro,", true},
+		{"-t", true},
+		{"ro noatime", true},
+		{"ro;reboot", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateMountOptions(tt.options)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateMountOptions(%q) error = %v, wantErr %v", tt.options, err, tt.wantErr)
+		}
+	}
+}
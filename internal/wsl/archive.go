@@ -0,0 +1,59 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ArchiveExt is the file extension appended to archived VHDs
+const ArchiveExt = ".zst"
+
+// CompressFile compresses src into dst using zstd, keeping src intact
+func (c *Client) CompressFile(src, dst string) error {
+	c.logger.Debug("Running: zstd -q -T0 -o %s %s", dst, src)
+
+	output, err := c.combinedOutput("zstd", "-q", "-T0", "-o", dst, src)
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("zstd compress failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// DecompressFile decompresses a zstd archive src into dst, keeping src intact
+func (c *Client) DecompressFile(src, dst string) error {
+	c.logger.Debug("Running: zstd -d -q -o %s %s", dst, src)
+
+	output, err := c.combinedOutput("zstd", "-d", "-q", "-o", dst, src)
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("zstd decompress failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// CopyFile copies the file src to dst (WSL paths), keeping holes so sparse
+// VHDs stay small where the destination filesystem allows it
+func (c *Client) CopyFile(src, dst string) error {
+	c.logger.Debug("Running: cp --sparse=always %s %s", src, dst)
+
+	output, err := c.combinedOutput("cp", "--sparse=always", src, dst)
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("copy failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// FileSize returns the size in bytes of the file at the WSL path
+func (c *Client) FileSize(wslPath string) (int64, error) {
+	fi, err := os.Stat(wslPath)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
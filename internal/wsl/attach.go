@@ -1,13 +1,16 @@
 package wsl
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/pkg/utils"
 )
 
 // EnsureInterop ensures WSL interop is enabled
@@ -22,9 +25,11 @@ func (c *Client) EnsureInterop() error {
 	c.logger.Warn("WSL interop not enabled, attempting to enable...")
 	
 	// Try to enable interop
-	cmd := exec.Command("sudo", "sh", "-c",
-		`echo ":WSLInterop:M::MZ::/init:PF" > /proc/sys/fs/binfmt_misc/register`)
-	if err := cmd.Run(); err != nil {
+	argv, err := c.privilegedArgv("enable-interop")
+	if err != nil {
+		return err
+	}
+	if _, err := c.combinedOutput("sudo", argv...); err != nil {
 		return fmt.Errorf("failed to enable WSL interop: %w", err)
 	}
 	
@@ -32,29 +37,78 @@ func (c *Client) EnsureInterop() error {
 	return nil
 }
 
+// attachLockFile serializes attaches across vhdm processes. /run/lock is
+// world-writable, so boot services running as root and users share it.
+const attachLockFile = "/run/lock/vhdm-attach.lock"
+
+// attachLockTimeout bounds how long an attach waits for other vhdm processes
+const attachLockTimeout = 2 * time.Minute
+
+// lockAttach acquires the inter-process attach lock
+func (c *Client) lockAttach() (*utils.FileLock, error) {
+	path := attachLockFile
+	if _, err := os.Stat(filepath.Dir(path)); err != nil {
+		path = filepath.Join(os.TempDir(), filepath.Base(attachLockFile))
+	}
+	c.logger.Debug("Acquiring attach lock: %s", path)
+	lock, err := utils.LockFile(path, attachLockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("another vhdm process is attaching a VHD: %w", err)
+	}
+	return lock, nil
+}
+
 // AttachVHD attaches a VHD to WSL
 func (c *Client) AttachVHD(path string) (*types.AttachResult, error) {
+	lock, err := c.lockAttach()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	return c.attachVHD(path)
+}
+
+// AttachVHDAndDetect attaches a VHD and returns its new block device. The
+// attach lock is held from the device snapshot until detection, so devices
+// attached concurrently by other vhdm processes are never mis-assigned.
+// attached reports whether the VHD was attached, which is also the case when
+// only detection failed.
+func (c *Client) AttachVHDAndDetect(path string) (devName string, attached bool, err error) {
+	lock, err := c.lockAttach()
+	if err != nil {
+		return "", false, err
+	}
+	defer lock.Unlock()
+
+	oldDevices, err := c.GetBlockDevices()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get block devices: %w", err)
+	}
+	if _, err := c.attachVHD(path); err != nil {
+		return "", false, err
+	}
+	devName, err = c.DetectNewDevice(oldDevices)
+	if err != nil {
+		return "", true, err
+	}
+	return devName, true, nil
+}
+
+// attachVHD runs wsl.exe --mount; callers must hold the attach lock
+func (c *Client) attachVHD(path string) (*types.AttachResult, error) {
 	if err := c.EnsureInterop(); err != nil {
 		return nil, err
 	}
 	
 	c.logger.Debug("Running: wsl.exe --mount --vhd %q --bare", path)
 	
-	cmd := exec.Command("wsl.exe", "--mount", "--vhd", path, "--bare")
-	output, err := cmd.CombinedOutput()
-	
-	// Clean null bytes from output
-	output = bytes.ReplaceAll(output, []byte{0}, []byte{})
-	outStr := strings.TrimSpace(string(output))
-	
+	_, err := c.runWSLExe(context.Background(), "attach", "--mount", "--vhd", path, "--bare")
 	if err != nil {
-		// Check for already attached error
-		if strings.Contains(outStr, "WSL_E_USER_VHD_ALREADY_ATTACHED") ||
-			strings.Contains(outStr, "already attached") ||
-			strings.Contains(outStr, "already mounted") {
+		if errors.Is(err, types.ErrVHDAlreadyAttached) {
 			return nil, types.ErrVHDAlreadyAttached
 		}
-		return nil, fmt.Errorf("wsl.exe attach failed: %s", outStr)
+		return nil, err
 	}
 	
 	return &types.AttachResult{WasNew: true}, nil
@@ -71,25 +125,14 @@ func (c *Client) DetachVHD(path string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.detachTimeout)
 	defer cancel()
 	
-	cmd := exec.CommandContext(ctx, "wsl.exe", "--unmount", path)
-	output, err := cmd.CombinedOutput()
-	
-	// Clean null bytes
-	output = bytes.ReplaceAll(output, []byte{0}, []byte{})
-	outStr := strings.TrimSpace(string(output))
-	
+	_, err := c.runWSLExe(ctx, "unmount", "--unmount", path)
 	if ctx.Err() == context.DeadlineExceeded {
 		return types.ErrDetachTimeout
 	}
-	
-	if err != nil {
-		if strings.Contains(outStr, "ERROR_FILE_NOT_FOUND") {
-			return types.ErrVHDNotAttached
-		}
-		return fmt.Errorf("wsl.exe unmount failed: %s", outStr)
+	if errors.Is(err, types.ErrVHDNotAttached) {
+		return types.ErrVHDNotAttached
 	}
-	
-	return nil
+	return err
 }
 
 // DeviceExists checks if a device exists
@@ -2,14 +2,18 @@
 package wsl
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/rjdinis/vhdm/internal/helper"
 	"github.com/rjdinis/vhdm/internal/logging"
 	"github.com/rjdinis/vhdm/internal/types"
 	"github.com/rjdinis/vhdm/pkg/utils"
@@ -20,6 +24,11 @@ type Client struct {
 	logger           *logging.Logger
 	sleepAfterAttach time.Duration
 	detachTimeout    time.Duration
+	helperPath       string
+	runner           Runner
+
+	deviceDelays      []time.Duration
+	recordDeviceDelay func(time.Duration)
 }
 
 // NewClient creates a new WSL client
@@ -28,7 +37,63 @@ func NewClient(logger *logging.Logger, sleepAfterAttach, detachTimeout time.Dura
 		logger:           logger,
 		sleepAfterAttach: sleepAfterAttach,
 		detachTimeout:    detachTimeout,
+		runner:           ExecRunner{},
+	}
+}
+
+// SetRunner runs the Client's commands through r instead of os/exec
+func (c *Client) SetRunner(r Runner) {
+	c.runner = r
+}
+
+// output runs a command through the runner and returns its standard output
+func (c *Client) output(name string, args ...string) ([]byte, error) {
+	return c.runner.Output(context.Background(), name, args...)
+}
+
+// combinedOutput runs a command through the runner and returns its standard
+// output and standard error together
+func (c *Client) combinedOutput(name string, args ...string) ([]byte, error) {
+	return c.runner.CombinedOutput(context.Background(), name, args...)
+}
+
+// SetHelper routes commands needing root through the vhdm-helper binary at
+// path (run with sudo). With an empty path they run under sudo directly.
+func (c *Client) SetHelper(path string) {
+	c.helperPath = path
+}
+
+// privilegedArgv returns the command line of a root-only step, named by its
+// vhdm-helper verb. The arguments are validated the same way either way.
+func (c *Client) privilegedArgv(verb string, args ...string) ([]string, error) {
+	argv, err := helper.Command(verb, args)
+	if err != nil {
+		return nil, err
 	}
+	if c.helperPath != "" {
+		return append([]string{c.helperPath, verb}, args...), nil
+	}
+	return argv, nil
+}
+
+// privileged returns the command for a root-only step that streams its
+// input or output; other steps run through the runner
+func (c *Client) privileged(verb string, args ...string) (*exec.Cmd, error) {
+	argv, err := c.privilegedArgv(verb, args...)
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command("sudo", argv...), nil
+}
+
+// runPrivileged runs a root-only step, discarding its output
+func (c *Client) runPrivileged(verb string, args ...string) error {
+	argv, err := c.privilegedArgv(verb, args...)
+	if err != nil {
+		return err
+	}
+	_, err = c.combinedOutput("sudo", argv...)
+	return err
 }
 
 // ConvertPath converts Windows path to WSL path
@@ -51,6 +116,8 @@ type lsblkOutput struct {
 type BlockDevice struct {
 	Name        string   `json:"name"`
 	UUID        string   `json:"uuid"`
+	Label       string   `json:"label"`
+	PartUUID    string   `json:"partuuid"`
 	FSType      string   `json:"fstype"`
 	MountPoints []string `json:"mountpoints"`
 	FSAvail     string   `json:"fsavail"`
@@ -61,12 +128,17 @@ type BlockDevice struct {
 // dynamicVHDPattern matches dynamically attached VHD devices (sd[d-z] and beyond)
 var dynamicVHDPattern = regexp.MustCompile(`^sd[d-z][a-z]*$`)
 
+// IsDynamicDevice reports whether a block device name (without /dev/) is a
+// dynamically attached VHD rather than a WSL system disk
+func IsDynamicDevice(name string) bool {
+	return dynamicVHDPattern.MatchString(name)
+}
+
 // GetBlockDevices returns list of block device names
 func (c *Client) GetBlockDevices() ([]string, error) {
 	c.logger.Debug("Running: lsblk -J")
 
-	cmd := exec.Command("lsblk", "-J")
-	output, err := cmd.Output()
+	output, err := c.output("lsblk", "-J")
 	if err != nil {
 		return nil, fmt.Errorf("lsblk failed: %w", err)
 	}
@@ -86,10 +158,9 @@ func (c *Client) GetBlockDevices() ([]string, error) {
 
 // GetBlockDevicesWithInfo returns detailed block device information
 func (c *Client) GetBlockDevicesWithInfo() ([]BlockDevice, error) {
-	c.logger.Debug("Running: lsblk -f -o NAME,UUID,FSTYPE,MOUNTPOINTS,FSAVAIL,FSUSE%%,SIZE -J")
+	c.logger.Debug("Running: lsblk -f -o NAME,UUID,LABEL,PARTUUID,FSTYPE,MOUNTPOINTS,FSAVAIL,FSUSE%%,SIZE -J")
 
-	cmd := exec.Command("lsblk", "-f", "-o", "NAME,UUID,FSTYPE,MOUNTPOINTS,FSAVAIL,FSUSE%,SIZE", "-J")
-	output, err := cmd.Output()
+	output, err := c.output("lsblk", "-f", "-o", "NAME,UUID,LABEL,PARTUUID,FSTYPE,MOUNTPOINTS,FSAVAIL,FSUSE%,SIZE", "-J")
 	if err != nil {
 		return nil, fmt.Errorf("lsblk failed: %w", err)
 	}
@@ -114,8 +185,11 @@ func (c *Client) GetUUIDByDevice(devName string) (string, error) {
 
 	c.logger.Debug("Running: sudo blkid -s UUID -o value /dev/%s", devName)
 
-	cmd := exec.Command("sudo", "blkid", "-s", "UUID", "-o", "value", "/dev/"+devName)
-	output, err := cmd.Output()
+	argv, err := c.privilegedArgv("blkid-uuid", devName)
+	if err != nil {
+		return "", err
+	}
+	output, err := c.output("sudo", argv...)
 	if err != nil {
 		// Device may not be formatted
 		return "", nil
@@ -190,6 +264,29 @@ func (c *Client) GetMountPoint(uuid string) (string, error) {
 	return "", nil
 }
 
+// GetMountPoints gets every mount point of a UUID, including bind mounts, in
+// the order lsblk reports them
+func (c *Client) GetMountPoints(uuid string) ([]string, error) {
+	devices, err := c.GetBlockDevicesWithInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	var mountPoints []string
+	for _, dev := range devices {
+		if dev.UUID != uuid {
+			continue
+		}
+		for _, mp := range dev.MountPoints {
+			if mp != "" {
+				mountPoints = append(mountPoints, mp)
+			}
+		}
+	}
+
+	return mountPoints, nil
+}
+
 // GetUUIDByMountPoint gets the UUID for a filesystem mounted at a mount point
 func (c *Client) GetUUIDByMountPoint(mountPoint string) (string, error) {
 	devices, err := c.GetBlockDevicesWithInfo()
@@ -306,7 +403,9 @@ func (c *Client) FindUUIDByPath(path string) (string, error) {
 	return c.FindDynamicVHDUUID()
 }
 
-// DetectNewDevice detects a newly attached device by comparing snapshots
+// DetectNewDevice detects a newly attached device by comparing snapshots. It
+// polls until the device appears, for up to the calibrated device wait (see
+// SetDeviceDelays).
 func (c *Client) DetectNewDevice(oldDevices []string) (string, error) {
 	// Build map of old dynamic VHD devices
 	oldDevMap := make(map[string]bool)
@@ -316,24 +415,51 @@ func (c *Client) DetectNewDevice(oldDevices []string) (string, error) {
 		}
 	}
 
-	c.logger.Debug("Old VHD devices: %v", oldDevMap)
+	wait := c.deviceWait()
+	c.logger.Debug("Old VHD devices: %v, waiting up to %s for the new one", oldDevMap, wait)
 
-	// Sleep to let kernel recognize device
-	time.Sleep(c.sleepAfterAttach)
+	start := time.Now()
+	for {
+		// Give the kernel time to recognize the device
+		time.Sleep(devicePollInterval)
 
-	// Get new device list
-	newDevices, err := c.GetBlockDevices()
-	if err != nil {
-		return "", err
-	}
+		newDevices, err := c.GetBlockDevices()
+		if err != nil {
+			return "", err
+		}
+
+		for _, dev := range newDevices {
+			if !oldDevMap[dev] && dynamicVHDPattern.MatchString(dev) {
+				delay := time.Since(start)
+				c.logger.Debug("New device detected: %s after %s", dev, delay.Round(time.Millisecond))
+				if c.recordDeviceDelay != nil {
+					c.recordDeviceDelay(delay)
+				}
+				return dev, nil
+			}
+		}
 
-	// Find new device
-	for _, dev := range newDevices {
-		if !oldDevMap[dev] && dynamicVHDPattern.MatchString(dev) {
-			c.logger.Debug("New device detected: %s", dev)
-			return dev, nil
+		if time.Since(start) >= wait {
+			// Record the timeout too, so the next attach waits longer on a
+			// machine where devices appear later than the current wait
+			if c.recordDeviceDelay != nil {
+				c.recordDeviceDelay(wait)
+			}
+			return "", types.ErrDeviceNotFound
 		}
 	}
+}
 
-	return "", types.ErrDeviceNotFound
+// GetDeviceSize returns the size of a block device in bytes
+func (c *Client) GetDeviceSize(devName string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join("/sys/class/block", devName, "size"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read size of /dev/%s: %w", devName, err)
+	}
+	sectors, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size of /dev/%s: %w", devName, err)
+	}
+	// sysfs always counts 512-byte sectors, whatever the logical block size
+	return sectors * 512, nil
 }
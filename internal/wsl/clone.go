@@ -0,0 +1,99 @@
+package wsl
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CreateDifferencingVHD creates childPath as a differencing VHD of
+// parentPath (Windows format paths) with diskpart: the child starts empty and
+// reads unchanged blocks from the parent, which must stay unchanged for the
+// child to remain valid.
+func (c *Client) CreateDifferencingVHD(parentPath, childPath string) error {
+	script := diskpartScript(
+		fmt.Sprintf(`create vdisk file="%s" parent="%s"`, windowsBackslashes(childPath), windowsBackslashes(parentPath)),
+	)
+	if err := c.RunElevatedPowerShell(script); err != nil {
+		return fmt.Errorf("diskpart create vdisk failed: %w", err)
+	}
+	return nil
+}
+
+// MergeDifferencingVHD merges a differencing VHD (Windows format path) into
+// its parent with diskpart, so the parent holds the contents of the child.
+// Other differencing VHDs of the same parent become invalid.
+func (c *Client) MergeDifferencingVHD(childPath string) error {
+	script := diskpartScript(
+		fmt.Sprintf(`select vdisk file="%s"`, windowsBackslashes(childPath)),
+		"merge vdisk depth=1",
+	)
+	if err := c.RunElevatedPowerShell(script); err != nil {
+		return fmt.Errorf("diskpart merge vdisk failed: %w", err)
+	}
+	return nil
+}
+
+// CopyDevice copies the block device src onto dst, skipping zero blocks so a
+// new dynamic VHD behind dst stays sparse. dst must be at least as large.
+func (c *Client) CopyDevice(src, dst string) error {
+	src = strings.TrimPrefix(src, "/dev/")
+	dst = strings.TrimPrefix(dst, "/dev/")
+	c.logger.Debug("Running: sudo dd if=/dev/%s of=/dev/%s bs=4M conv=sparse,fsync", src, dst)
+
+	argv, err := c.privilegedArgv("dd", src, dst)
+	if err != nil {
+		return err
+	}
+	if output, err := c.combinedOutput("sudo", argv...); err != nil {
+		return fmt.Errorf("dd failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// RegenerateUUID gives the unmounted filesystem on a device a new random UUID
+// and returns it, so a copy of a VHD can be attached next to the original.
+// ext2/3/4 are checked with e2fsck first, since tune2fs only changes the UUID
+// of a freshly checked filesystem.
+func (c *Client) RegenerateUUID(devName, fsType string) (string, error) {
+	devName = strings.TrimPrefix(devName, "/dev/")
+
+	if strings.HasPrefix(fsType, "ext") {
+		if err := c.checkExt(devName); err != nil {
+			return "", err
+		}
+	}
+
+	c.logger.Debug("Changing the UUID of the %s filesystem on /dev/%s", fsType, devName)
+	argv, err := c.privilegedArgv("new-uuid", fsType, devName)
+	if err != nil {
+		return "", err
+	}
+	if output, err := c.combinedOutput("sudo", argv...); err != nil {
+		return "", fmt.Errorf("failed to change UUID: %s", strings.TrimSpace(string(output)))
+	}
+
+	uuid, err := c.GetUUIDByDevice(devName)
+	if err != nil || uuid == "" {
+		return "", fmt.Errorf("failed to read the new UUID of /dev/%s", devName)
+	}
+	return uuid, nil
+}
+
+// checkExt runs e2fsck -f -p on the unmounted ext2/3/4 filesystem on a
+// device, which tune2fs and resize2fs want freshly checked
+func (c *Client) checkExt(devName string) error {
+	c.logger.Debug("Running: sudo e2fsck -f -p /dev/%s", devName)
+	argv, err := c.privilegedArgv("e2fsck", devName)
+	if err != nil {
+		return err
+	}
+	output, err := c.combinedOutput("sudo", argv...)
+	// Exit status 1 means errors were found and corrected
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return fmt.Errorf("e2fsck failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
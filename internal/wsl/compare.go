@@ -0,0 +1,39 @@
+package wsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompareTrees compares the files below dst with those below src by
+// checksum, as rsync would copy them, and returns the files that differ or
+// exist on one side only, relative to the directories. Differences in
+// attributes alone are ignored.
+func (c *Client) CompareTrees(src, dst string) ([]string, error) {
+	c.logger.Debug("Comparing %s with %s by checksum", dst, src)
+
+	argv, err := c.privilegedArgv("rsync-compare", src, dst)
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.output("sudo", argv...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare files: %w", err)
+	}
+	return parseItemizedChanges(string(output)), nil
+}
+
+// parseItemizedChanges returns the files of rsync --itemize-changes output
+// whose data would be copied, created or deleted. Lines of a change code
+// starting with '.' only update attributes.
+func parseItemizedChanges(output string) []string {
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		code, name, ok := strings.Cut(strings.TrimRight(line, "\r"), " ")
+		if !ok || code == "" || code[0] == '.' {
+			continue
+		}
+		files = append(files, strings.TrimLeft(name, " "))
+	}
+	return files
+}
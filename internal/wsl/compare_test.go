@@ -0,0 +1,22 @@
+package wsl
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseItemizedChanges(t *testing.T) {
+	output := ">fc.T...... data/corrupt.bin\n" +
+		".d..t...... data/\n" +
+		".f...p..... data/mode changed.txt\n" +
+		">f+++++++++ data/missing file.txt\n" +
+		"cL+++++++++ link -> target\n" +
+		"*deleting   data/extra.txt\n"
+	want := []string{"data/corrupt.bin", "data/missing file.txt", "link -> target", "data/extra.txt"}
+	if got := parseItemizedChanges(output); !slices.Equal(got, want) {
+		t.Errorf("parseItemizedChanges() = %q, want %q", got, want)
+	}
+	if got := parseItemizedChanges(""); got != nil {
+		t.Errorf("parseItemizedChanges(\"\") = %q, want none", got)
+	}
+}
@@ -0,0 +1,44 @@
+package wsl
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	// devicePollInterval is how often DetectNewDevice looks for the new device
+	devicePollInterval = 100 * time.Millisecond
+
+	// deviceWaitFactor scales the slowest recorded attach delay into the
+	// time DetectNewDevice waits, leaving headroom for a loaded machine
+	deviceWaitFactor = 3
+
+	// maxDeviceWait caps a calibrated wait
+	maxDeviceWait = 30 * time.Second
+)
+
+// SetDeviceDelays calibrates how long DetectNewDevice waits for a new block
+// device from the delays recorded on this machine, and registers record to
+// receive the delay of each detected device, or the wait when none appeared,
+// so it can be stored for next time
+func (c *Client) SetDeviceDelays(delays []time.Duration, record func(time.Duration)) {
+	c.deviceDelays = delays
+	c.recordDeviceDelay = record
+}
+
+// deviceWait returns how long DetectNewDevice waits for a new device
+func (c *Client) deviceWait() time.Duration {
+	return calibratedDeviceWait(c.sleepAfterAttach, c.deviceDelays)
+}
+
+// calibratedDeviceWait returns deviceWaitFactor times the slowest recorded
+// delay, at least minWait (VHDM_SLEEP_AFTER_ATTACH) and at most maxDeviceWait.
+// Detection returns as soon as the device appears, so a generous wait only
+// costs time when attaching actually fails.
+func calibratedDeviceWait(minWait time.Duration, delays []time.Duration) time.Duration {
+	wait := minWait
+	if len(delays) > 0 {
+		wait = max(wait, min(slices.Max(delays)*deviceWaitFactor, maxDeviceWait))
+	}
+	return wait
+}
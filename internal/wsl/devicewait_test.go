@@ -0,0 +1,49 @@
+package wsl
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rjdinis/vhdm/internal/logging"
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+func TestCalibratedDeviceWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		minWait time.Duration
+		delays  []time.Duration
+		want    time.Duration
+	}{
+		{"no samples", 2 * time.Second, nil, 2 * time.Second},
+		{"fast machine keeps minimum", 2 * time.Second, []time.Duration{200 * time.Millisecond, 300 * time.Millisecond}, 2 * time.Second},
+		{"slow machine waits longer", 2 * time.Second, []time.Duration{time.Second, 2500 * time.Millisecond}, 7500 * time.Millisecond},
+		{"capped", 2 * time.Second, []time.Duration{time.Minute}, maxDeviceWait},
+		{"minimum above cap", time.Minute, []time.Duration{time.Minute}, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calibratedDeviceWait(tt.minWait, tt.delays); got != tt.want {
+				t.Errorf("calibratedDeviceWait() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectNewDeviceRecordsTimeout(t *testing.T) {
+	c := NewClient(logging.New(false, false), 200*time.Millisecond, time.Second)
+	c.SetRunner(funcRunner(func(name string, args []string) ([]byte, error) {
+		return []byte(`{"blockdevices": [{"name": "sda"}, {"name": "sdd"}]}`), nil
+	}))
+	var recorded []time.Duration
+	c.SetDeviceDelays(nil, func(d time.Duration) { recorded = append(recorded, d) })
+
+	if _, err := c.DetectNewDevice([]string{"sda", "sdd"}); !errors.Is(err, types.ErrDeviceNotFound) {
+		t.Fatalf("DetectNewDevice() error = %v, want ErrDeviceNotFound", err)
+	}
+	if len(recorded) != 1 || recorded[0] != 200*time.Millisecond {
+		t.Errorf("recorded %v, want the 200ms wait", recorded)
+	}
+}
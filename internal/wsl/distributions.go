@@ -1,18 +1,18 @@
 package wsl
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"regexp"
 	"strings"
 )
 
 // WSLDistribution represents a WSL distribution from Windows registry
 type WSLDistribution struct {
-	Name     string
-	BasePath string
-	VHDPath  string
+	Name     string `json:"name"`
+	BasePath string `json:"basePath"`
+	VHDPath  string `json:"vhdPath"`
 }
 
 // GetWSLDistributions queries Windows registry to get list of WSL distributions
@@ -20,8 +20,7 @@ func (c *Client) GetWSLDistributions() ([]WSLDistribution, error) {
 	c.logger.Debug("Querying Windows registry for WSL distributions")
 
 	// Query the WSL registry key
-	cmd := exec.Command("reg.exe", "query", `HKEY_CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\Lxss`)
-	output, err := cmd.Output()
+	output, err := c.output("reg.exe", "query", `HKEY_CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\Lxss`)
 	if err != nil {
 		c.logger.Debug("Failed to query WSL registry: %v", err)
 		return nil, fmt.Errorf("failed to query WSL registry: %w", err)
@@ -69,8 +68,7 @@ func parseDistributionKeys(output string) []string {
 func (c *Client) queryDistributionDetails(guid string) (WSLDistribution, error) {
 	keyPath := fmt.Sprintf(`HKEY_CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\Lxss\%s`, guid)
 
-	cmd := exec.Command("reg.exe", "query", keyPath)
-	output, err := cmd.Output()
+	output, err := c.output("reg.exe", "query", keyPath)
 	if err != nil {
 		return WSLDistribution{}, fmt.Errorf("failed to query distribution key: %w", err)
 	}
@@ -121,6 +119,14 @@ func (c *Client) queryDistributionDetails(guid string) (WSLDistribution, error)
 	return dist, nil
 }
 
+// SystemVHDPath returns the distribution's VHD path in the forward-slash
+// Windows format used by vhdm (e.g., C:/Users/me/AppData/.../ext4.vhdx),
+// without any \\?\ long-path prefix
+func (d WSLDistribution) SystemVHDPath() string {
+	path := strings.TrimPrefix(d.VHDPath, `\\?\`)
+	return strings.ReplaceAll(path, "\\", "/")
+}
+
 // GetWSLDistributionsJSON returns WSL distributions as JSON string
 func (c *Client) GetWSLDistributionsJSON() (string, error) {
 	dists, err := c.GetWSLDistributions()
@@ -135,3 +141,32 @@ func (c *Client) GetWSLDistributionsJSON() (string, error) {
 
 	return string(data), nil
 }
+
+// FindDistribution returns the registered WSL distribution with the given
+// name (case-insensitive)
+func (c *Client) FindDistribution(name string) (*WSLDistribution, error) {
+	dists, err := c.GetWSLDistributions()
+	if err != nil {
+		return nil, err
+	}
+	for _, dist := range dists {
+		if strings.EqualFold(dist.Name, name) {
+			d := dist
+			return &d, nil
+		}
+	}
+	return nil, fmt.Errorf("WSL distribution not found: %s", name)
+}
+
+// ExportDistribution exports a WSL distribution's root filesystem as a tar
+// archive at winPath (Windows format)
+func (c *Client) ExportDistribution(name, winPath string) error {
+	if err := c.EnsureInterop(); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Running: wsl.exe --export %q %q", name, winPath)
+
+	_, err := c.runWSLExe(context.Background(), "export", "--export", name, winPath)
+	return err
+}
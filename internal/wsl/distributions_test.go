@@ -71,3 +71,25 @@ func TestQueryDistributionDetails(t *testing.T) {
 		})
 	}
 }
+
+func TestSystemVHDPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		vhdPath string
+		want    string
+	}{
+		{"backslashes", `C:\Users\me\AppData\Local\Packages\Ubuntu\LocalState\ext4.vhdx`, "C:/Users/me/AppData/Local/Packages/Ubuntu/LocalState/ext4.vhdx"},
+		{"long path prefix", `\\?\D:\WSL\Debian\ext4.vhdx`, "D:/WSL/Debian/ext4.vhdx"},
+		{"already forward slashes", "C:/WSL/ext4.vhdx", "C:/WSL/ext4.vhdx"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := WSLDistribution{VHDPath: tt.vhdPath}
+			if got := d.SystemVHDPath(); got != tt.want {
+				t.Errorf("SystemVHDPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package wsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CreateDockerVolume creates a named Docker volume that bind-mounts device (a
+// directory) using the local driver
+func (c *Client) CreateDockerVolume(name, device string) error {
+	args := []string{"volume", "create", "--driver", "local",
+		"--opt", "type=none", "--opt", "o=bind", "--opt", "device=" + device, name}
+	c.logger.Debug("Running: docker %s", strings.Join(args, " "))
+
+	output, err := c.combinedOutput("docker", args...)
+	if err != nil {
+		return fmt.Errorf("docker volume create failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// DockerVolumeDevice returns the bind device of a local Docker volume, or an
+// empty string if the volume does not exist
+func (c *Client) DockerVolumeDevice(name string) (string, error) {
+	c.logger.Debug("Running: docker volume inspect --format {{.Options.device}} %s", name)
+
+	output, err := c.combinedOutput("docker", "volume", "inspect", "--format", "{{.Options.device}}", name)
+	if err != nil {
+		if strings.Contains(strings.ToLower(string(output)), "no such volume") {
+			return "", nil
+		}
+		return "", fmt.Errorf("docker volume inspect failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	device := strings.TrimSpace(string(output))
+	if device == "<no value>" {
+		device = ""
+	}
+	return device, nil
+}
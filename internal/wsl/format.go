@@ -1,7 +1,9 @@
 package wsl
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
@@ -15,8 +17,11 @@ func (c *Client) Format(devName, fsType string) (string, error) {
 	
 	c.logger.Debug("Running: sudo mkfs -t %s %s", fsType, devicePath)
 	
-	cmd := exec.Command("sudo", "mkfs", "-t", fsType, devicePath)
-	output, err := cmd.CombinedOutput()
+	argv, err := c.privilegedArgv("mkfs", fsType, devName)
+	if err != nil {
+		return "", err
+	}
+	output, err := c.combinedOutput("sudo", argv...)
 	if err != nil {
 		return "", fmt.Errorf("format failed: %s", strings.TrimSpace(string(output)))
 	}
@@ -41,8 +46,7 @@ func (c *Client) Format(devName, fsType string) (string, error) {
 func (c *Client) CreateVHD(wslPath, size string) error {
 	c.logger.Debug("Running: qemu-img create -f vhdx %s %s", wslPath, size)
 	
-	cmd := exec.Command("qemu-img", "create", "-f", "vhdx", wslPath, size)
-	output, err := cmd.CombinedOutput()
+	output, err := c.combinedOutput("qemu-img", "create", "-f", "vhdx", wslPath, size)
 	if err != nil {
 		return fmt.Errorf("qemu-img create failed: %s", strings.TrimSpace(string(output)))
 	}
@@ -54,8 +58,7 @@ func (c *Client) CreateVHD(wslPath, size string) error {
 func (c *Client) DeleteVHD(wslPath string) error {
 	c.logger.Debug("Deleting VHD file: %s", wslPath)
 	
-	cmd := exec.Command("rm", "-f", wslPath)
-	output, err := cmd.CombinedOutput()
+	output, err := c.combinedOutput("rm", "-f", wslPath)
 	if err != nil {
 		return fmt.Errorf("delete failed: %s", strings.TrimSpace(string(output)))
 	}
@@ -78,8 +81,11 @@ func (c *Client) GetFilesystemType(devName string) (string, error) {
 
 	c.logger.Debug("Running: sudo blkid -s TYPE -o value /dev/%s", devName)
 
-	cmd := exec.Command("sudo", "blkid", "-s", "TYPE", "-o", "value", "/dev/"+devName)
-	output, err := cmd.Output()
+	argv, err := c.privilegedArgv("blkid-type", devName)
+	if err != nil {
+		return "", err
+	}
+	output, err := c.output("sudo", argv...)
 	if err != nil {
 		return "", fmt.Errorf("failed to get filesystem type: %w", err)
 	}
@@ -88,12 +94,82 @@ func (c *Client) GetFilesystemType(devName string) (string, error) {
 	return fsType, nil
 }
 
+// FilesystemCheck holds the result of a read-only filesystem check
+type FilesystemCheck struct {
+	Clean  bool   `json:"clean"`
+	Output string `json:"output,omitempty"` // Report of the checker when it found problems
+}
+
+// CheckFilesystem checks the unmounted filesystem on a device without
+// repairing it (e2fsck -n, xfs_repair -n, btrfs check --readonly or
+// fsck.vfat -n). Problems found are reported in the result; the error is for
+// checks that could not run.
+func (c *Client) CheckFilesystem(devName, fsType string) (*FilesystemCheck, error) {
+	devName = strings.TrimPrefix(devName, "/dev/")
+
+	c.logger.Debug("Checking the %s filesystem on /dev/%s read-only", fsType, devName)
+	argv, err := c.privilegedArgv("fsck-ro", fsType, devName)
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.combinedOutput("sudo", argv...)
+	if err == nil {
+		return &FilesystemCheck{Clean: true}, nil
+	}
+
+	// e2fsck exits with 4 for errors left uncorrected, the others with 1
+	problems := 1
+	if strings.HasPrefix(fsType, "ext") {
+		problems = 4
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == problems {
+		return &FilesystemCheck{Output: strings.TrimSpace(string(output))}, nil
+	}
+	return nil, fmt.Errorf("filesystem check failed: %s", strings.TrimSpace(string(output)))
+}
+
+// GrowableFilesystem reports whether GrowFilesystem can grow a filesystem type
+func GrowableFilesystem(fsType string) bool {
+	switch fsType {
+	case "ext2", "ext3", "ext4", "xfs", "btrfs":
+		return true
+	}
+	return false
+}
+
+// GrowFilesystem grows the filesystem on a device to fill it, after its VHD
+// was expanded. ext2/3/4 are grown unmounted (e2fsck, then resize2fs); xfs
+// and btrfs only grow mounted, at mountPoint.
+func (c *Client) GrowFilesystem(devName, fsType, mountPoint string) error {
+	devName = strings.TrimPrefix(devName, "/dev/")
+
+	var argv []string
+	var err error
+	if strings.HasPrefix(fsType, "ext") {
+		if err := c.checkExt(devName); err != nil {
+			return err
+		}
+		c.logger.Debug("Running: sudo resize2fs /dev/%s", devName)
+		argv, err = c.privilegedArgv("resize2fs", devName)
+	} else {
+		c.logger.Debug("Growing the %s filesystem mounted at %s", fsType, mountPoint)
+		argv, err = c.privilegedArgv("grow-fs", fsType, mountPoint)
+	}
+	if err != nil {
+		return err
+	}
+	if output, err := c.combinedOutput("sudo", argv...); err != nil {
+		return fmt.Errorf("failed to grow filesystem: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 // RenameFile renames a file
 func (c *Client) RenameFile(oldPath, newPath string) error {
 	c.logger.Debug("Renaming: %s -> %s", oldPath, newPath)
 
-	cmd := exec.Command("mv", oldPath, newPath)
-	output, err := cmd.CombinedOutput()
+	output, err := c.combinedOutput("mv", oldPath, newPath)
 	if err != nil {
 		return fmt.Errorf("rename failed: %s", strings.TrimSpace(string(output)))
 	}
@@ -105,8 +181,11 @@ func (c *Client) RenameFile(oldPath, newPath string) error {
 func (c *Client) CountFiles(path string) (int, error) {
 	c.logger.Debug("Counting files in: %s", path)
 
-	cmd := exec.Command("sudo", "find", path, "-type", "f")
-	output, err := cmd.Output()
+	argv, err := c.privilegedArgv("count-files", path)
+	if err != nil {
+		return 0, err
+	}
+	output, err := c.output("sudo", argv...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count files: %w", err)
 	}
@@ -119,8 +198,24 @@ func (c *Client) CountFiles(path string) (int, error) {
 	return len(lines), nil
 }
 
-// RsyncCopy copies data from source to destination using rsync
-func (c *Client) RsyncCopy(src, dst string) error {
+// RsyncOptions controls optional rsync behavior
+type RsyncOptions struct {
+	Delete   bool // Delete files in dst that are not in src
+	Progress bool // Stream rsync progress to stderr
+
+	// OnProgress, when set, is called with each progress report of rsync
+	// instead of streaming them
+	OnProgress func(RsyncProgress)
+}
+
+// RsyncCopy copies data from source to destination using rsync, reporting
+// its progress to onProgress when not nil
+func (c *Client) RsyncCopy(src, dst string, onProgress func(RsyncProgress)) error {
+	return c.Rsync(src, dst, RsyncOptions{OnProgress: onProgress})
+}
+
+// Rsync synchronizes the contents of src into dst using rsync
+func (c *Client) Rsync(src, dst string, opts RsyncOptions) error {
 	// Ensure paths end with / for rsync to copy contents
 	if !strings.HasSuffix(src, "/") {
 		src = src + "/"
@@ -129,11 +224,26 @@ func (c *Client) RsyncCopy(src, dst string) error {
 		dst = dst + "/"
 	}
 
-	c.logger.Debug("Running: sudo rsync -aHAX --info=progress2 %s %s", src, dst)
+	args := []string{src, dst}
+	if opts.Delete {
+		args = append(args, "--delete")
+	}
+
+	c.logger.Debug("Running: sudo rsync -aHAX --info=progress2 %s", strings.Join(args, " "))
 
-	cmd := exec.Command("sudo", "rsync", "-aHAX", "--info=progress2", src, dst)
+	cmd, err := c.privileged("rsync", args...)
+	if err != nil {
+		return err
+	}
 	cmd.Stdout = nil // Don't capture stdout to allow progress display
 	cmd.Stderr = nil
+	if opts.OnProgress != nil {
+		return runRsyncWithProgress(cmd, opts.OnProgress)
+	}
+	if opts.Progress {
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+	}
 	
 	// Run rsync and show progress
 	if err := cmd.Run(); err != nil {
@@ -0,0 +1,54 @@
+package wsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Freeze suspends writes to the filesystem mounted at mountPoint and flushes
+// it to disk (fsfreeze --freeze), so a copy of the VHD file taken meanwhile
+// is crash-consistent. Writers block until Thaw, which must always follow.
+func (c *Client) Freeze(mountPoint string) error {
+	c.logger.Debug("Running: sudo fsfreeze --freeze %s", mountPoint)
+
+	argv, err := c.privilegedArgv("fsfreeze", mountPoint)
+	if err != nil {
+		return err
+	}
+	output, err := c.combinedOutput("sudo", argv...)
+	if err != nil {
+		return fmt.Errorf("fsfreeze failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Trim discards the unused blocks of the filesystem mounted at mountPoint
+// (fstrim), so the dynamic VHD behind it can be compacted
+func (c *Client) Trim(mountPoint string) error {
+	c.logger.Debug("Running: sudo fstrim %s", mountPoint)
+
+	argv, err := c.privilegedArgv("fstrim", mountPoint)
+	if err != nil {
+		return err
+	}
+	output, err := c.combinedOutput("sudo", argv...)
+	if err != nil {
+		return fmt.Errorf("fstrim failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Thaw resumes writes to a filesystem suspended by Freeze
+func (c *Client) Thaw(mountPoint string) error {
+	c.logger.Debug("Running: sudo fsfreeze --unfreeze %s", mountPoint)
+
+	argv, err := c.privilegedArgv("fsthaw", mountPoint)
+	if err != nil {
+		return err
+	}
+	output, err := c.combinedOutput("sudo", argv...)
+	if err != nil {
+		return fmt.Errorf("fsfreeze --unfreeze failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
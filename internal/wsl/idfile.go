@@ -0,0 +1,53 @@
+package wsl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rjdinis/vhdm/internal/helper"
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+// WriteIDFile writes the ID file (.vhdm.json) at the root of the filesystem
+// mounted at mountPoint, replacing any previous one
+func (c *Client) WriteIDFile(mountPoint string, id types.IDFile) error {
+	data, err := json.MarshalIndent(id, "", "  ")
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(mountPoint, helper.IDFileName)
+	c.logger.Debug("Running: sudo install -m 644 /dev/stdin %s", target)
+
+	cmd, err := c.privileged("write-id", mountPoint)
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write %s: %s", target, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ReadIDFile reads the ID file at the root of the filesystem mounted at
+// mountPoint. It returns nil without error when there is none.
+func (c *Client) ReadIDFile(mountPoint string) (*types.IDFile, error) {
+	data, err := os.ReadFile(filepath.Join(mountPoint, helper.IDFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var id types.IDFile
+	if err := json.Unmarshal(data, &id); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", helper.IDFileName, err)
+	}
+	return &id, nil
+}
@@ -0,0 +1,59 @@
+package wsl
+
+import (
+	"testing"
+)
+
+func TestParseImageInfo(t *testing.T) {
+	data := []byte(`{
+    "virtual-size": 10737418240,
+    "filename": "/mnt/c/VMs/disk.vhdx",
+    "cluster-size": 33554432,
+    "format": "vhdx",
+    "actual-size": 2319450112,
+    "dirty-flag": false
+}`)
+
+	info, err := parseImageInfo(data)
+	if err != nil {
+		t.Fatalf("parseImageInfo() error = %v", err)
+	}
+	if info.Format != "vhdx" {
+		t.Errorf("Format = %q, want vhdx", info.Format)
+	}
+	if info.VirtualSize != 10737418240 {
+		t.Errorf("VirtualSize = %d, want 10737418240", info.VirtualSize)
+	}
+	if info.ActualSize != 2319450112 {
+		t.Errorf("ActualSize = %d, want 2319450112", info.ActualSize)
+	}
+	if info.BackingFile != "" {
+		t.Errorf("BackingFile = %q, want empty", info.BackingFile)
+	}
+}
+
+func TestParseImageInfoInvalid(t *testing.T) {
+	if _, err := parseImageInfo([]byte("not json")); err == nil {
+		t.Error("parseImageInfo() expected error for invalid JSON")
+	}
+}
+
+func TestParseImageCheck(t *testing.T) {
+	data := []byte(`{
+    "image-end-offset": 4194304,
+    "total-clusters": 16,
+    "check-errors": 0,
+    "leaks": 1,
+    "corruptions": 2,
+    "filename": "/mnt/c/VMs/disk.vhdx",
+    "format": "vhdx"
+}`)
+
+	check, err := parseImageCheck(data)
+	if err != nil {
+		t.Fatalf("parseImageCheck() error = %v", err)
+	}
+	if check.Corruptions != 2 || check.Leaks != 1 || check.CheckErrors != 0 {
+		t.Errorf("parseImageCheck() = %+v, want 2 corruptions, 1 leak, 0 check errors", check)
+	}
+}
@@ -0,0 +1,108 @@
+package wsl
+
+import "github.com/rjdinis/vhdm/internal/types"
+
+// Interface is the set of WSL operations the commands use. Client implements
+// it against the real system; wslfake.Fake implements it in memory so command
+// logic can be tested without a WSL2 host.
+type Interface interface {
+	// Privileges and paths
+	EnsureSudo() error
+	ConvertPath(winPath string) string
+
+	// VHD attach and detach
+	AttachVHD(path string) (*types.AttachResult, error)
+	AttachVHDAndDetect(path string) (devName string, attached bool, err error)
+	DetachVHD(path string) error
+	IsAttached(uuid string) (bool, error)
+	FindUUIDByPath(path string) (string, error)
+
+	// Block devices
+	GetAllDisks() ([]BlockDevice, error)
+	GetBlockDevicesWithInfo() ([]BlockDevice, error)
+	GetDeviceByUUID(uuid string) (string, error)
+	GetUUIDByDevice(devName string) (string, error)
+	GetDeviceSize(devName string) (int64, error)
+	DeviceExists(devName string) bool
+	DeviceIOCount(devName string) (uint64, error)
+	DeviceIOStats(devName string) (IOStats, error)
+	CopyDevice(src, dst string) error
+	GetVHDInfo(uuid string) (*types.VHDInfo, error)
+
+	// Filesystems
+	Format(devName, fsType string) (string, error)
+	IsFormatted(devName string) (bool, error)
+	GetFilesystemType(devName string) (string, error)
+	FilesystemTypeByUUID(uuid string) string
+	RegenerateUUID(devName, fsType string) (string, error)
+	CheckFilesystem(devName, fsType string) (*FilesystemCheck, error)
+	GrowFilesystem(devName, fsType, mountPoint string) error
+
+	// Mounts
+	MountByUUID(uuid, mountPoint string) error
+	MountByUUIDWithOptions(uuid, mountPoint, options string) error
+	BindMount(source, target string) error
+	Unmount(mountPoint string) error
+	ForceUnmount(mountPoint string) error
+	IsMounted(uuid string) (bool, error)
+	GetMountPoint(uuid string) (string, error)
+	GetMountPoints(uuid string) ([]string, error)
+	GetUUIDByMountPoint(mountPoint string) (string, error)
+	FindUUIDByMountPoint(mountPoint string) (string, error)
+	Freeze(mountPoint string) error
+	Thaw(mountPoint string) error
+	Trim(mountPoint string) error
+	WriteIDFile(mountPoint string, id types.IDFile) error
+	ReadIDFile(mountPoint string) (*types.IDFile, error)
+
+	// VHD files
+	CreateVHD(wslPath, size string) error
+	CreateDifferencingVHD(parentPath, childPath string) error
+	MergeDifferencingVHD(childPath string) error
+	DeleteVHD(wslPath string) error
+	FileExists(wslPath string) bool
+	FileSize(wslPath string) (int64, error)
+	RenameFile(oldPath, newPath string) error
+	GetImageInfo(wslPath string) (*ImageInfo, error)
+	CheckImage(wslPath string) (*ImageCheck, error)
+	ExpandVHDFile(winPath string, sizeBytes int64) error
+	CompactVHDFile(winPath string) error
+	ConvertImage(src, dst, format string) error
+	FileInUseByWindows(winPath string) (bool, error)
+	WindowsVHDSizes(winPaths []string) (map[string]int64, error)
+	WindowsTempDir() (string, error)
+
+	// Archives and copies
+	CompressFile(src, dst string) error
+	DecompressFile(src, dst string) error
+	CopyFile(src, dst string) error
+	CreateTarball(srcDir, dst string) error
+	ExtractTarball(src, dstDir string) error
+	TarballSize(src string) (int64, error)
+	Rsync(src, dst string, opts RsyncOptions) error
+	RsyncCopy(src, dst string, onProgress func(RsyncProgress)) error
+
+	// Filesystem contents
+	CountFiles(path string) (int, error)
+	CompareTrees(src, dst string) ([]string, error)
+	DiskUsage(root string, depth int) ([]DirUsage, error)
+	FindFiles(root, pattern string, maxDepth int) ([]string, error)
+
+	// WSL distributions
+	GetWSLDistributions() ([]WSLDistribution, error)
+	FindDistribution(name string) (*WSLDistribution, error)
+	IsDistributionRunning(name string) (bool, error)
+	StartDistribution(name string) error
+	TerminateDistribution(name string) error
+	ExportDistribution(name, winPath string) error
+	RunInDistribution(name string, args ...string) (string, error)
+
+	// Docker
+	CreateDockerVolume(name, device string) error
+	DockerVolumeDevice(name string) (string, error)
+
+	SelfTest(winDir string, progress func(SelfTestStep)) ([]SelfTestStep, error)
+	Notify(method, title, message string) error
+}
+
+var _ Interface = (*Client)(nil)
@@ -0,0 +1,62 @@
+package wsl
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Exit codes of the fileInUseScript
+const (
+	fileFree  = 0
+	fileInUse = 3
+)
+
+// FileInUseByWindows reports whether a file (Windows format path) is open in
+// a Windows process, such as Hyper-V, a backup tool or a virus scanner. It
+// tries to open the file for exclusive access from Windows, which fails with
+// a sharing violation while any other handle is open. Files attached with
+// 'wsl.exe --mount' are reported as in use too.
+func (c *Client) FileInUseByWindows(winPath string) (bool, error) {
+	if err := c.EnsureInterop(); err != nil {
+		return false, err
+	}
+
+	c.logger.Debug("Checking whether %s is open on the Windows side", winPath)
+
+	output, err := c.combinedOutput("powershell.exe", "-NoProfile", "-NonInteractive",
+		"-EncodedCommand", encodePowerShell(fileInUseScript(winPath)))
+	if err == nil {
+		return false, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == fileInUse {
+		return true, nil
+	}
+	outStr := strings.TrimSpace(string(output))
+	if outStr == "" {
+		outStr = err.Error()
+	}
+	return false, fmt.Errorf("failed to check whether %s is in use: %s", winPath, outStr)
+}
+
+// fileInUseScript returns the PowerShell script used by FileInUseByWindows.
+// It exits with fileInUse on a sharing or lock violation (Win32 errors 32 and
+// 33) and prints the error otherwise. Missing files are not in use.
+func fileInUseScript(winPath string) string {
+	native := strings.ReplaceAll(windowsBackslashes(winPath), "'", "''")
+	return fmt.Sprintf("if (-not (Test-Path -LiteralPath '%s')) { exit %d }\n"+
+		"try {\n"+
+		"  $f = [IO.File]::Open('%s', 'Open', 'ReadWrite', 'None')\n"+
+		"  $f.Close()\n"+
+		"  exit %d\n"+
+		"} catch {\n"+
+		"  $e = $_.Exception\n"+
+		"  while ($e.InnerException) { $e = $e.InnerException }\n"+
+		"  if (($e.HResult -band 0xFFFF) -in 32, 33) { exit %d }\n"+
+		"  Write-Output $e.Message\n"+
+		"  exit 1\n"+
+		"}\n", native, fileFree, native, fileFree, fileInUse)
+}
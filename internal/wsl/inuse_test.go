@@ -0,0 +1,20 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFileInUseScript(t *testing.T) {
+	script := fileInUseScript("C:/Users/o'neil/WSL/ext4.vhdx")
+
+	for _, want := range []string{
+		`Test-Path -LiteralPath 'C:\Users\o''neil\WSL\ext4.vhdx'`,
+		`[IO.File]::Open('C:\Users\o''neil\WSL\ext4.vhdx', 'Open', 'ReadWrite', 'None')`,
+		"-in 32, 33) { exit 3 }",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("fileInUseScript() missing %q in:\n%s", want, script)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DeviceIOCount returns the number of completed read and write requests of a
+// block device since boot, read from /sys/block/<dev>/stat. The value only
+// changes when the filesystem on the device sees activity, so it can be
+// sampled to detect idle devices.
+func (c *Client) DeviceIOCount(devName string) (uint64, error) {
+	statPath := fmt.Sprintf("/sys/block/%s/stat", devName)
+	data, err := os.ReadFile(statPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", statPath, err)
+	}
+	return parseBlockStat(string(data))
+}
+
+// IOStats are the cumulative I/O counters of a block device since boot
+type IOStats struct {
+	Reads        uint64 // Completed read requests
+	ReadBytes    uint64
+	Writes       uint64 // Completed write requests
+	WrittenBytes uint64
+}
+
+// DeviceIOStats returns the I/O counters of a block device, read from
+// /sys/block/<dev>/stat. Sampling them twice gives I/O rates.
+func (c *Client) DeviceIOStats(devName string) (IOStats, error) {
+	statPath := fmt.Sprintf("/sys/block/%s/stat", devName)
+	data, err := os.ReadFile(statPath)
+	if err != nil {
+		return IOStats{}, fmt.Errorf("failed to read %s: %w", statPath, err)
+	}
+	return parseIOStats(string(data))
+}
+
+// parseBlockStat sums the "reads completed" and "writes completed" fields of a
+// /sys/block/<dev>/stat line (fields 1 and 5)
+func parseBlockStat(data string) (uint64, error) {
+	stats, err := parseIOStats(data)
+	if err != nil {
+		return 0, err
+	}
+	return stats.Reads + stats.Writes, nil
+}
+
+// parseIOStats parses the request and sector counts of a /sys/block/<dev>/stat
+// line (fields 1, 3, 5 and 7). Sectors are always 512 bytes there.
+func parseIOStats(data string) (IOStats, error) {
+	fields := strings.Fields(data)
+	if len(fields) < 7 {
+		return IOStats{}, fmt.Errorf("unexpected block stat format: %q", strings.TrimSpace(data))
+	}
+
+	var values [4]uint64
+	for i, field := range []int{0, 2, 4, 6} {
+		v, err := strconv.ParseUint(fields[field], 10, 64)
+		if err != nil {
+			return IOStats{}, fmt.Errorf("invalid field %d: %w", field+1, err)
+		}
+		values[i] = v
+	}
+	return IOStats{
+		Reads:        values[0],
+		ReadBytes:    values[1] * 512,
+		Writes:       values[2],
+		WrittenBytes: values[3] * 512,
+	}, nil
+}
@@ -0,0 +1,62 @@
+package wsl
+
+import (
+	"testing"
+)
+
+func TestParseBlockStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    uint64
+		wantErr bool
+	}{
+		{
+			name:  "typical stat line",
+			input: "     1520      310   123456     2040      870      115    40960     9910        0     5120    11950        0        0        0        0\n",
+			want:  1520 + 870,
+		},
+		{
+			name:  "idle device",
+			input: "0 0 0 0 0 0 0 0 0 0 0",
+			want:  0,
+		},
+		{
+			name:    "too few fields",
+			input:   "1 2 3",
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric",
+			input:   "a 0 0 0 b 0 0 0 0 0 0",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBlockStat(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBlockStat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseBlockStat() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIOStats(t *testing.T) {
+	got, err := parseIOStats("     1520      310   123456     2040      870      115    40960     9910        0     5120    11950        0        0        0        0\n")
+	if err != nil {
+		t.Fatalf("parseIOStats() error = %v", err)
+	}
+	want := IOStats{Reads: 1520, ReadBytes: 123456 * 512, Writes: 870, WrittenBytes: 40960 * 512}
+	if got != want {
+		t.Errorf("parseIOStats() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseIOStats("1 2 3 4 5"); err == nil {
+		t.Error("parseIOStats() accepted a short line")
+	}
+}
@@ -3,16 +3,30 @@ package wsl
 import (
 	"fmt"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
 )
 
+// nonPOSIXFilesystems have no Unix ownership; owner and permissions of their
+// files come from the uid=, gid= and umask= mount options
+var nonPOSIXFilesystems = map[string]bool{
+	"vfat": true, "exfat": true, "ntfs": true, "ntfs3": true,
+}
+
+// ownershipOptionKeys are the mount options that only non-POSIX filesystems accept
+var ownershipOptionKeys = []string{"uid", "gid", "umask", "fmask", "dmask"}
+
 // CreateMountPoint creates a mount point directory
 func (c *Client) CreateMountPoint(path string) error {
 	c.logger.Debug("Creating mount point: %s", path)
 	
 	if err := os.MkdirAll(path, 0755); err != nil {
-		return fmt.Errorf("failed to create mount point: %w", err)
+		if !os.IsPermission(err) {
+			return fmt.Errorf("failed to create mount point: %w", err)
+		}
+		if err := c.runPrivileged("mkdir", path); err != nil {
+			return fmt.Errorf("failed to create mount point: %w", err)
+		}
 	}
 	
 	return nil
@@ -20,7 +34,36 @@ func (c *Client) CreateMountPoint(path string) error {
 
 // MountByUUID mounts a filesystem by UUID to a mount point
 func (c *Client) MountByUUID(uuid, mountPoint string) error {
-	c.logger.Debug("Running: sudo mount UUID=%s %s", uuid, mountPoint)
+	return c.MountByUUIDWithOptions(uuid, mountPoint, "")
+}
+
+// MountByUUIDWithOptions mounts a filesystem by UUID passing options to mount -o
+// (e.g., "ro", "noatime,discard"). Read-only mounts skip the permission fixups.
+// Non-POSIX filesystems (vfat, exfat, ntfs) are mounted owned by the invoking
+// user with umask 022 unless options set uid=, gid= or umask= explicitly.
+//
+// If mount cannot work out the filesystem type itself (as on minimal distros
+// without /etc/filesystems), it is retried with -t and the type from blkid.
+func (c *Client) MountByUUIDWithOptions(uuid, mountPoint, options string) error {
+	fsType := c.FilesystemTypeByUUID(uuid)
+	nonPOSIX := nonPOSIXFilesystems[fsType]
+	if nonPOSIX {
+		uid, gid := invokingUser()
+		options = ownershipOptions(options, uid, gid)
+	} else {
+		for _, key := range ownershipOptionKeys {
+			if hasMountOptionKey(options, key) {
+				return fmt.Errorf("the %s= option only applies to vfat, exfat and ntfs filesystems (found %s)", key, fsType)
+			}
+		}
+	}
+
+	args := []string{uuid, mountPoint}
+	if options != "" {
+		args = append(args, options)
+	}
+
+	c.logger.Debug("Running: sudo mount -o %q UUID=%s %s", options, uuid, mountPoint)
 	
 	// Create mount point if needed
 	if err := c.CreateMountPoint(mountPoint); err != nil {
@@ -28,36 +71,94 @@ func (c *Client) MountByUUID(uuid, mountPoint string) error {
 	}
 	
 	// Mount
-	cmd := exec.Command("sudo", "mount", "UUID="+uuid, mountPoint)
-	output, err := cmd.CombinedOutput()
+	argv, err := c.privilegedArgv("mount", args...)
+	if err != nil {
+		return err
+	}
+	output, err := c.combinedOutput("sudo", argv...)
+	if err != nil && fsType != "" && isFSTypeError(string(output)) {
+		c.logger.Debug("Mount failed (%s), retrying with -t %s", strings.TrimSpace(string(output)), fsType)
+		argv, err = c.privilegedArgv("mount-type", append([]string{fsType}, args...)...)
+		if err != nil {
+			return err
+		}
+		output, err = c.combinedOutput("sudo", argv...)
+	}
 	if err != nil {
 		return fmt.Errorf("mount failed: %s", strings.TrimSpace(string(output)))
 	}
+
+	if hasMountOption(options, "ro") || nonPOSIX {
+		return nil
+	}
 	
 	// Set permissions
 	c.logger.Debug("Setting permissions on mount point")
 	
-	if err := exec.Command("sudo", "chmod", "755", mountPoint).Run(); err != nil {
-		c.logger.Warn("Failed to set permissions: %v", err)
+	if err := c.runPrivileged("chmod", mountPoint); err != nil {
+		c.logger.Collect("Failed to set permissions on %s: %v", mountPoint, err)
 	}
 	
 	// Get current user
 	user := os.Getenv("USER")
 	if user != "" {
-		if err := exec.Command("sudo", "chown", user+":"+user, mountPoint).Run(); err != nil {
-			c.logger.Warn("Failed to set owner: %v", err)
+		if err := c.runPrivileged("chown", user, mountPoint); err != nil {
+			c.logger.Collect("Failed to set owner of %s: %v", mountPoint, err)
 		}
 	}
 	
 	return nil
 }
 
+// FilesystemTypeByUUID returns the filesystem type (from blkid) of the attached
+// device holding a UUID, or "" when it cannot be determined
+func (c *Client) FilesystemTypeByUUID(uuid string) string {
+	devName, err := c.GetDeviceByUUID(uuid)
+	if err != nil || devName == "" {
+		return ""
+	}
+	fsType, _ := c.GetFilesystemType(devName)
+	return fsType
+}
+
+// isFSTypeError reports whether mount output says it could not determine or
+// handle the filesystem type, which an explicit -t may fix
+func isFSTypeError(output string) bool {
+	output = strings.ToLower(output)
+	return strings.Contains(output, "wrong fs type") ||
+		strings.Contains(output, "unknown filesystem type") ||
+		strings.Contains(output, "no such device")
+}
+
+// BindMount makes the filesystem mounted at source also available at target
+func (c *Client) BindMount(source, target string) error {
+	c.logger.Debug("Running: sudo mount --bind %s %s", source, target)
+
+	if err := c.CreateMountPoint(target); err != nil {
+		return err
+	}
+
+	argv, err := c.privilegedArgv("bind", source, target)
+	if err != nil {
+		return err
+	}
+	output, err := c.combinedOutput("sudo", argv...)
+	if err != nil {
+		return fmt.Errorf("bind mount failed: %s", strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
 // Unmount unmounts a filesystem from a mount point
 func (c *Client) Unmount(mountPoint string) error {
 	c.logger.Debug("Running: sudo umount %s", mountPoint)
 	
-	cmd := exec.Command("sudo", "umount", mountPoint)
-	output, err := cmd.CombinedOutput()
+	argv, err := c.privilegedArgv("umount", mountPoint)
+	if err != nil {
+		return err
+	}
+	output, err := c.combinedOutput("sudo", argv...)
 	if err != nil {
 		outStr := strings.TrimSpace(string(output))
 		
@@ -65,8 +166,10 @@ func (c *Client) Unmount(mountPoint string) error {
 		c.logger.Error("Failed to unmount: %s", outStr)
 		c.logger.Info("Checking for processes using the mount point...")
 		
-		lsofCmd := exec.Command("sudo", "lsof", "+D", mountPoint)
-		lsofOutput, _ := lsofCmd.CombinedOutput()
+		var lsofOutput []byte
+		if argv, err := c.privilegedArgv("lsof", mountPoint); err == nil {
+			lsofOutput, _ = c.combinedOutput("sudo", argv...)
+		}
 		if len(lsofOutput) > 0 {
 			c.logger.Info("Processes using mount point:\n%s", string(lsofOutput))
 		} else {
@@ -85,8 +188,11 @@ func (c *Client) Unmount(mountPoint string) error {
 func (c *Client) ForceUnmount(mountPoint string) error {
 	c.logger.Debug("Running: sudo umount -l %s", mountPoint)
 	
-	cmd := exec.Command("sudo", "umount", "-l", mountPoint)
-	output, err := cmd.CombinedOutput()
+	argv, err := c.privilegedArgv("umount-lazy", mountPoint)
+	if err != nil {
+		return err
+	}
+	output, err := c.combinedOutput("sudo", argv...)
 	if err != nil {
 		return fmt.Errorf("force unmount failed: %s", strings.TrimSpace(string(output)))
 	}
@@ -119,3 +225,55 @@ func (c *Client) FindUUIDByMountPoint(mountPoint string) (string, error) {
 	
 	return "", nil
 }
+
+// ownershipOptions adds uid=, gid= and umask=022 to a mount option list,
+// keeping any of them the caller already set
+func ownershipOptions(options string, uid, gid int) string {
+	var opts []string
+	if options != "" {
+		opts = strings.Split(options, ",")
+	}
+	if !hasMountOptionKey(options, "uid") {
+		opts = append(opts, "uid="+strconv.Itoa(uid))
+	}
+	if !hasMountOptionKey(options, "gid") {
+		opts = append(opts, "gid="+strconv.Itoa(gid))
+	}
+	if !hasMountOptionKey(options, "umask") && !hasMountOptionKey(options, "fmask") && !hasMountOptionKey(options, "dmask") {
+		opts = append(opts, "umask=022")
+	}
+	return strings.Join(opts, ",")
+}
+
+// invokingUser returns the uid and gid of the user running vhdm, looking
+// through sudo
+func invokingUser() (int, int) {
+	uid, gid := os.Getuid(), os.Getgid()
+	if v, err := strconv.Atoi(os.Getenv("SUDO_UID")); err == nil {
+		uid = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SUDO_GID")); err == nil {
+		gid = v
+	}
+	return uid, gid
+}
+
+// hasMountOptionKey reports whether a comma-separated option list sets key=
+func hasMountOptionKey(options, key string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if strings.HasPrefix(strings.TrimSpace(o), key+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMountOption reports whether a comma-separated option list contains opt
+func hasMountOption(options, opt string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if strings.TrimSpace(o) == opt {
+			return true
+		}
+	}
+	return false
+}
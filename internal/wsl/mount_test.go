@@ -0,0 +1,70 @@
+package wsl
+
+import "testing"
+
+func TestOwnershipOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		want    string
+	}{
+		{
+			name: "defaults",
+			want: "uid=1000,gid=1000,umask=022",
+		},
+		{
+			name:    "keeps other options",
+			options: "ro,noatime",
+			want:    "ro,noatime,uid=1000,gid=1000,umask=022",
+		},
+		{
+			name:    "explicit values win",
+			options: "uid=0,umask=077",
+			want:    "uid=0,umask=077,gid=1000",
+		},
+		{
+			name:    "fmask and dmask replace umask",
+			options: "fmask=133,dmask=022",
+			want:    "fmask=133,dmask=022,uid=1000,gid=1000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ownershipOptions(tt.options, 1000, 1000); got != tt.want {
+				t.Errorf("ownershipOptions(%q) = %q, want %q", tt.options, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasMountOptionKey(t *testing.T) {
+	if !hasMountOptionKey("ro,uid=1000", "uid") {
+		t.Error("hasMountOptionKey() should find uid=")
+	}
+	if hasMountOptionKey("ro,uidx=1", "uid") {
+		t.Error("hasMountOptionKey() should not match a longer key")
+	}
+	if hasMountOptionKey("", "uid") {
+		t.Error("hasMountOptionKey() should not match empty options")
+	}
+}
+
+func TestIsFSTypeError(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"mount: /mnt/data: wrong fs type, bad option, bad superblock on /dev/sdd, missing codepage or helper program, or other error.", true},
+		{"mount: unknown filesystem type 'btrfs'", true},
+		{"mount: mounting /dev/sdd on /mnt/data failed: No such device", true},
+		{"mount: /mnt/data: permission denied.", false},
+		{"mount: /mnt/data: /dev/sdd already mounted on /mnt/other.", false},
+	}
+
+	for _, tt := range tests {
+		if got := isFSTypeError(tt.output); got != tt.want {
+			t.Errorf("isFSTypeError(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
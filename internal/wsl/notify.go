@@ -0,0 +1,89 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Notification methods of Notify
+const (
+	NotifyOff        = "off"
+	NotifyAuto       = "auto"
+	NotifyToast      = "toast"
+	NotifyNotifySend = "notify-send"
+)
+
+// NotifyMethods lists the valid VHDM_NOTIFY values
+var NotifyMethods = []string{NotifyOff, NotifyAuto, NotifyToast, NotifyNotifySend}
+
+// powerShellAppID is the AppUserModelID Windows shows toasts of PowerShell
+// under; a console process has none of its own
+const powerShellAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// Notify shows a desktop notification, as a Windows toast or with
+// notify-send in WSLg. NotifyAuto uses notify-send when a WSLg display is
+// available and a toast otherwise, which covers boot services that have no
+// display.
+func (c *Client) Notify(method, title, message string) error {
+	if method == NotifyAuto {
+		method = NotifyToast
+		if hasDisplay() {
+			if _, err := exec.LookPath("notify-send"); err == nil {
+				method = NotifyNotifySend
+			}
+		}
+	}
+
+	c.logger.Debug("Sending %s notification: %s", method, title)
+
+	var (
+		output []byte
+		err    error
+	)
+	switch method {
+	case NotifyOff, "":
+		return nil
+	case NotifyNotifySend:
+		output, err = c.combinedOutput("notify-send", "--app-name=vhdm", "--urgency=critical", title, message)
+	case NotifyToast:
+		if err := c.EnsureInterop(); err != nil {
+			return err
+		}
+		output, err = c.combinedOutput("powershell.exe", "-NoProfile", "-NonInteractive",
+			"-EncodedCommand", encodePowerShell(toastScript(title, message)))
+	default:
+		return fmt.Errorf("invalid notification method: %s (use %s)", method, strings.Join(NotifyMethods, ", "))
+	}
+
+	if err != nil {
+		outStr := strings.TrimSpace(string(output))
+		if outStr == "" {
+			outStr = err.Error()
+		}
+		return fmt.Errorf("failed to send %s notification: %s", method, outStr)
+	}
+	return nil
+}
+
+// hasDisplay reports whether a WSLg (Wayland or X11) display is set
+func hasDisplay() bool {
+	return os.Getenv("WAYLAND_DISPLAY") != "" || os.Getenv("DISPLAY") != ""
+}
+
+// toastScript returns the PowerShell script used by Notify to show a toast
+// with a title and a message line
+func toastScript(title, message string) string {
+	quote := func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return "$ErrorActionPreference = 'Stop'\n" +
+		"[void][Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime]\n" +
+		"$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)\n" +
+		"$text = $xml.GetElementsByTagName('text')\n" +
+		"[void]$text.Item(0).AppendChild($xml.CreateTextNode(" + quote(title) + "))\n" +
+		"[void]$text.Item(1).AppendChild($xml.CreateTextNode(" + quote(message) + "))\n" +
+		"$toast = [Windows.UI.Notifications.ToastNotification]::new($xml)\n" +
+		"[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier(" + quote(powerShellAppID) + ").Show($toast)\n"
+}
@@ -0,0 +1,20 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToastScript(t *testing.T) {
+	script := toastScript("vhdm: mount failed", "C:/VMs/o'neil.vhdx: device not found")
+
+	for _, want := range []string{
+		`CreateTextNode('vhdm: mount failed')`,
+		`CreateTextNode('C:/VMs/o''neil.vhdx: device not found')`,
+		`CreateToastNotifier('{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe')`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("toastScript() missing %q in:\n%s", want, script)
+		}
+	}
+}
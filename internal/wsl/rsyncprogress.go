@@ -0,0 +1,84 @@
+package wsl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// RsyncProgress is a progress report of rsync --info=progress2
+type RsyncProgress struct {
+	Bytes   int64  // Bytes transferred so far
+	Percent int    // Of the whole transfer, as estimated by rsync
+	Rate    string // e.g. "12.34MB/s"
+	ETA     string // e.g. "0:01:23"; the time taken on the last report
+}
+
+// parseRsyncProgress parses a progress2 line such as
+// "  1,048,576  45%   12.34MB/s    0:00:12 (xfr#3, to-chk=7/12)"
+func parseRsyncProgress(line string) (RsyncProgress, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || !strings.HasSuffix(fields[1], "%") {
+		return RsyncProgress{}, false
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(fields[0], ",", ""), 10, 64)
+	if err != nil {
+		return RsyncProgress{}, false
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(fields[1], "%"))
+	if err != nil {
+		return RsyncProgress{}, false
+	}
+	return RsyncProgress{Bytes: n, Percent: percent, Rate: fields[2], ETA: fields[3]}, true
+}
+
+// scanRsyncProgress calls fn with each progress report read from r. rsync
+// redraws its progress line with carriage returns, so lines are split on
+// both \r and \n; other output is ignored.
+func scanRsyncProgress(r io.Reader, fn func(RsyncProgress)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	for scanner.Scan() {
+		if p, ok := parseRsyncProgress(scanner.Text()); ok {
+			fn(p)
+		}
+	}
+	return scanner.Err()
+}
+
+// runRsyncWithProgress runs an rsync command, passing its progress reports to
+// fn; its error output is kept for the error
+func runRsyncWithProgress(cmd *exec.Cmd, fn func(RsyncProgress)) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start rsync: %w", err)
+	}
+	scanErr := scanRsyncProgress(stdout, fn)
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("rsync failed: %w: %s", err, msg)
+		}
+		return fmt.Errorf("rsync failed: %w", err)
+	}
+	if scanErr != nil {
+		return fmt.Errorf("failed to read rsync progress: %w", scanErr)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScanRsyncProgress(t *testing.T) {
+	output := "sending incremental file list\n" +
+		"         32,768   0%    0.00kB/s    0:00:00  \r" +
+		"    524,288,000  48%  250.00MB/s    0:00:02 (xfr#3, to-chk=7/12)\r" +
+		"  1,073,741,824 100%  255.12MB/s    0:00:04 (xfr#12, to-chk=0/12)\n"
+	var got []RsyncProgress
+	if err := scanRsyncProgress(strings.NewReader(output), func(p RsyncProgress) { got = append(got, p) }); err != nil {
+		t.Fatal(err)
+	}
+	want := []RsyncProgress{
+		{Bytes: 32768, Percent: 0, Rate: "0.00kB/s", ETA: "0:00:00"},
+		{Bytes: 524288000, Percent: 48, Rate: "250.00MB/s", ETA: "0:00:02"},
+		{Bytes: 1073741824, Percent: 100, Rate: "255.12MB/s", ETA: "0:00:04"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("scanRsyncProgress() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("report %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package wsl
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rjdinis/vhdm/internal/logging"
+)
+
+// Runner runs external commands. The Client and the systemd steps of the
+// commands run lsblk, blkid, mkfs, wsl.exe, systemctl and the other tools
+// through one, so tests can record them and a dry run can skip them.
+// Commands that stream through pipes or the terminal (tar, rsync, sudo
+// prompts, interactive shells) still use os/exec directly.
+type Runner interface {
+	// Output runs a command and returns its standard output. A non-zero exit
+	// returns an *exec.ExitError, as exec.Cmd.Output does.
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
+	// CombinedOutput runs a command and returns its standard output and
+	// standard error together
+	CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner runs commands with os/exec
+type ExecRunner struct{}
+
+func (ExecRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+func (ExecRunner) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+// DryRunner logs the commands that would change the system instead of
+// running them, and succeeds with no output. Read-only queries such as lsblk
+// and systemctl is-active still run through Next, so the steps after them
+// see the real state.
+type DryRunner struct {
+	Logger *logging.Logger
+	Next   Runner
+}
+
+// NewDryRunner returns a DryRunner running queries with next
+func NewDryRunner(logger *logging.Logger, next Runner) *DryRunner {
+	return &DryRunner{Logger: logger, Next: next}
+}
+
+func (r *DryRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if IsQueryCommand(name, args) {
+		return r.Next.Output(ctx, name, args...)
+	}
+	r.Logger.Info("Would run: %s", CommandLine(name, args))
+	return nil, nil
+}
+
+func (r *DryRunner) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if IsQueryCommand(name, args) {
+		return r.Next.CombinedOutput(ctx, name, args...)
+	}
+	r.Logger.Info("Would run: %s", CommandLine(name, args))
+	return nil, nil
+}
+
+// queryCommands are commands that never change the system, by program and,
+// where the program also makes changes, its first argument
+var queryCommands = map[string][]string{
+	"lsblk":      nil,
+	"mountpoint": nil,
+	"blkid":      nil,
+	"lsof":       nil,
+	"du":         nil,
+	"find":       nil,
+	"reg.exe":    {"query"},
+	"qemu-img":   {"info", "check"},
+	"wsl.exe":    {"--list"},
+	"systemctl":  {"is-enabled", "is-active", "show", "status", "cat"},
+}
+
+// privilegedQueries are the read-only vhdm-helper verbs
+var privilegedQueries = []string{"blkid-uuid", "blkid-type", "count-files", "du", "find", "lsof", "rsync-compare"}
+
+// IsQueryCommand reports whether a command only reads the system state.
+// Root-only steps are recognized by their vhdm-helper verb, or by the
+// program when they run under sudo directly.
+func IsQueryCommand(name string, args []string) bool {
+	if name == "sudo" && len(args) > 0 {
+		if filepath.Base(args[0]) == "vhdm-helper" {
+			return len(args) > 1 && slices.Contains(privilegedQueries, args[1])
+		}
+		name, args = args[0], args[1:]
+	}
+	if name == "docker" {
+		return len(args) > 1 && args[0] == "volume" && args[1] == "inspect"
+	}
+	first, ok := queryCommands[name]
+	if !ok {
+		return false
+	}
+	if first == nil {
+		return true
+	}
+	return len(args) > 0 && slices.Contains(first, args[0])
+}
+
+// CommandLine formats a command for display, quoting arguments with spaces
+func CommandLine(name string, args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	for _, s := range append([]string{name}, args...) {
+		if s == "" || strings.ContainsAny(s, " \t\"'") {
+			s = `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,70 @@
+package wsl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rjdinis/vhdm/internal/logging"
+)
+
+func TestIsQueryCommand(t *testing.T) {
+	for _, tt := range []struct {
+		args  []string
+		query bool
+	}{
+		{[]string{"lsblk", "-J"}, true},
+		{[]string{"systemctl", "is-active", "x.service"}, true},
+		{[]string{"systemctl", "enable", "x.service"}, false},
+		{[]string{"wsl.exe", "--list", "--running", "--quiet"}, true},
+		{[]string{"wsl.exe", "--mount", "--vhd", "C:/a.vhdx", "--bare"}, false},
+		{[]string{"sudo", "blkid", "-s", "UUID", "-o", "value", "/dev/sdd"}, true},
+		{[]string{"sudo", "mkfs", "-t", "ext4", "/dev/sdd"}, false},
+		{[]string{"sudo", "/usr/local/bin/vhdm-helper", "blkid-type", "sdd"}, true},
+		{[]string{"sudo", "/usr/local/bin/vhdm-helper", "mkfs", "ext4", "sdd"}, false},
+		{[]string{"docker", "volume", "inspect", "data"}, true},
+		{[]string{"docker", "volume", "create", "data"}, false},
+		{[]string{"rm", "-f", "/mnt/c/a.vhdx"}, false},
+	} {
+		if got := IsQueryCommand(tt.args[0], tt.args[1:]); got != tt.query {
+			t.Errorf("IsQueryCommand(%q) = %v, want %v", tt.args, got, tt.query)
+		}
+	}
+}
+
+// funcRunner is a Runner calling a function for every command
+type funcRunner func(name string, args []string) ([]byte, error)
+
+func (f funcRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return f(name, args)
+}
+
+func (f funcRunner) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return f(name, args)
+}
+
+func TestDryRunner(t *testing.T) {
+	var ran []string
+	next := funcRunner(func(name string, args []string) ([]byte, error) {
+		ran = append(ran, CommandLine(name, args))
+		return []byte("out"), nil
+	})
+	r := NewDryRunner(logging.New(true, false), next)
+
+	if out, err := r.Output(context.Background(), "systemctl", "is-enabled", "x.service"); err != nil || string(out) != "out" {
+		t.Errorf("query: output %q, error %v; want it run", out, err)
+	}
+	if out, err := r.CombinedOutput(context.Background(), "systemctl", "enable", "x.service"); err != nil || out != nil {
+		t.Errorf("change: output %q, error %v; want it skipped", out, err)
+	}
+	if len(ran) != 1 || ran[0] != "systemctl is-enabled x.service" {
+		t.Errorf("ran %q, want only the query", ran)
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	got := CommandLine("wsl.exe", []string{"--mount", "--vhd", "C:/My VMs/a.vhdx", ""})
+	want := `wsl.exe --mount --vhd "C:/My VMs/a.vhdx" ""`
+	if got != want {
+		t.Errorf("CommandLine() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// selfTestSize is the size of the throwaway VHD created by SelfTest
+const selfTestSize = "64M"
+
+// SelfTestStep is one stage of SelfTest and its outcome
+type SelfTestStep struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// WindowsTempDir returns the Windows %TEMP% directory as a vhdm path
+// (C:/Users/me/AppData/Local/Temp)
+func (c *Client) WindowsTempDir() (string, error) {
+	if err := c.EnsureInterop(); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("cmd.exe", "/c", "echo %TEMP%")
+	// cmd.exe warns about UNC working directories; start it on a Windows drive
+	if _, err := os.Stat("/mnt/c"); err == nil {
+		cmd.Dir = "/mnt/c"
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %%TEMP%%: %w", err)
+	}
+	dir := strings.ReplaceAll(strings.TrimSpace(string(output)), `\`, "/")
+	if dir == "" || strings.Contains(dir, "%") {
+		return "", fmt.Errorf("%%TEMP%% is not set")
+	}
+	return dir, nil
+}
+
+// SelfTest checks the whole VHD stack end to end: it creates a tiny VHD in
+// winDir (a Windows directory), attaches, formats and mounts it, writes and
+// reads a file, then unmounts, detaches and deletes it. Once a step fails the
+// remaining ones are skipped, but whatever was set up is still cleaned up.
+// progress, if not nil, is called after each step.
+func (c *Client) SelfTest(winDir string, progress func(SelfTestStep)) ([]SelfTestStep, error) {
+	winPath := fmt.Sprintf("%s/vhdm-selftest-%d.vhdx", strings.TrimSuffix(winDir, "/"), os.Getpid())
+	wslPath := c.ConvertPath(winPath)
+
+	var (
+		steps      []SelfTestStep
+		failed     error
+		devName    string
+		uuid       string
+		mountPoint string
+		created    bool
+		attached   bool
+		mounted    bool
+	)
+	run := func(name string, fn func() error) {
+		if failed != nil {
+			return
+		}
+		start := time.Now()
+		err := fn()
+		step := SelfTestStep{Name: name, Duration: time.Since(start), Err: err}
+		steps = append(steps, step)
+		if progress != nil {
+			progress(step)
+		}
+		if err != nil {
+			failed = fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	run("create", func() error {
+		if c.FileExists(wslPath) {
+			return fmt.Errorf("%s already exists", winPath)
+		}
+		if err := c.CreateVHD(wslPath, selfTestSize); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	run("attach", func() error {
+		var err error
+		devName, attached, err = c.AttachVHDAndDetect(winPath)
+		return err
+	})
+	run("format", func() error {
+		var err error
+		uuid, err = c.Format(devName, "ext4")
+		return err
+	})
+	run("mount", func() error {
+		var err error
+		if mountPoint, err = os.MkdirTemp("", "vhdm-selftest-"); err != nil {
+			return err
+		}
+		if err := c.MountByUUID(uuid, mountPoint); err != nil {
+			return err
+		}
+		mounted = true
+		return nil
+	})
+	run("write", func() error {
+		file := filepath.Join(mountPoint, "selftest")
+		want := []byte(winPath)
+		if err := os.WriteFile(file, want, 0644); err != nil {
+			return err
+		}
+		got, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if string(got) != string(want) {
+			return fmt.Errorf("read back %q, wrote %q", got, want)
+		}
+		return nil
+	})
+
+	// Tear down whatever was set up, reporting failures only when everything
+	// before succeeded
+	teardown := func(name string, needed bool, fn func() error) {
+		if !needed {
+			return
+		}
+		if failed == nil {
+			run(name, fn)
+			return
+		}
+		if err := fn(); err != nil {
+			c.logger.Warn("Self-test cleanup (%s) failed: %v", name, err)
+		}
+	}
+	teardown("unmount", mounted, func() error { return c.Unmount(mountPoint) })
+	if mountPoint != "" {
+		os.Remove(mountPoint)
+	}
+	teardown("detach", attached, func() error { return c.DetachVHD(winPath) })
+	teardown("delete", created, func() error { return c.DeleteVHD(wslPath) })
+
+	return steps, failed
+}
@@ -0,0 +1,52 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// sudoRefreshInterval is how often EnsureSudo refreshes the sudo timestamp,
+// well under sudo's default 5-15 minute timeout
+const sudoRefreshInterval = time.Minute
+
+// EnsureSudo validates sudo credentials once, up front, so the privileged
+// steps of a command (blkid, mkfs, mount, chmod, chown, ...) don't each prompt
+// for a password. The timestamp is then refreshed in the background for the
+// life of the process, so long operations such as resize never prompt midway.
+// It does nothing as root or when sudoers lets vhdm-helper run without a
+// password, and only prompts when stdin is a terminal.
+func (c *Client) EnsureSudo() error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	if c.helperPath != "" && exec.Command("sudo", "-n", "-l", c.helperPath).Run() == nil {
+		return nil
+	}
+
+	if exec.Command("sudo", "-n", "-v").Run() != nil {
+		fi, err := os.Stdin.Stat()
+		if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+			// Nothing to prompt on; the sudo steps report their own errors
+			c.logger.Debug("sudo needs a password but stdin is not a terminal")
+			return nil
+		}
+
+		c.logger.Debug("Running: sudo -v")
+		cmd := exec.Command("sudo", "-v")
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("sudo authentication failed: %w", err)
+		}
+	}
+
+	go func() {
+		for range time.Tick(sudoRefreshInterval) {
+			exec.Command("sudo", "-n", "-v").Run()
+		}
+	}()
+	return nil
+}
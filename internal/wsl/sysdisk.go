@@ -0,0 +1,195 @@
+package wsl
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// TerminateDistribution stops a running WSL distribution
+func (c *Client) TerminateDistribution(name string) error {
+	if err := c.EnsureInterop(); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Running: wsl.exe --terminate %q", name)
+
+	_, err := c.runWSLExe(context.Background(), "terminate", "--terminate", name)
+	return err
+}
+
+// RunningDistributions returns the names of the WSL distributions that are
+// currently running
+func (c *Client) RunningDistributions() ([]string, error) {
+	if err := c.EnsureInterop(); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Running: wsl.exe --list --running --quiet")
+
+	outStr, err := c.runWSLExe(context.Background(), "list", "--list", "--running", "--quiet")
+	if err != nil {
+		// wsl.exe exits non-zero when nothing is running. Versions that
+		// print no error code only have the English message to go by.
+		if wslErrorCode(err, "WSL_E_DEFAULT_DISTRO_NOT_FOUND") ||
+			strings.Contains(strings.ToLower(outStr), "no running") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return parseDistributionList(outStr), nil
+}
+
+// IsDistributionRunning reports whether the named distribution is running
+func (c *Client) IsDistributionRunning(name string) (bool, error) {
+	running, err := c.RunningDistributions()
+	if err != nil {
+		return false, err
+	}
+	for _, r := range running {
+		if strings.EqualFold(r, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// StartDistribution boots a WSL distribution by running a no-op command in it
+func (c *Client) StartDistribution(name string) error {
+	_, err := c.RunInDistribution(name, "true")
+	return err
+}
+
+// parseDistributionList parses 'wsl.exe --list --quiet' output (already
+// stripped of UTF-16 null bytes) into distribution names
+func parseDistributionList(output string) []string {
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		name := strings.TrimSpace(strings.TrimPrefix(line, "\uFEFF"))
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// RunInDistribution runs a command as root inside a WSL distribution and
+// returns its output. The distribution is started if it is not running.
+func (c *Client) RunInDistribution(name string, args ...string) (string, error) {
+	if err := c.EnsureInterop(); err != nil {
+		return "", err
+	}
+
+	wslArgs := append([]string{"-d", name, "-u", "root", "--"}, args...)
+	c.logger.Debug("Running: wsl.exe %s", strings.Join(wslArgs, " "))
+
+	output, err := c.runWSLExe(context.Background(), args[0], wslArgs...)
+	if err != nil {
+		return "", fmt.Errorf("%s failed in %s: %s", args[0], name, output)
+	}
+
+	return output, nil
+}
+
+// RunElevatedPowerShell runs a PowerShell script in an elevated Windows
+// process. Windows shows a UAC prompt; declining it fails the command. The
+// script's output is not captured, only its exit code.
+func (c *Client) RunElevatedPowerShell(script string) error {
+	if err := c.EnsureInterop(); err != nil {
+		return err
+	}
+
+	c.logger.Debug("Running elevated PowerShell:\n%s", script)
+
+	outer := fmt.Sprintf("$p = Start-Process -FilePath powershell.exe -Verb RunAs -Wait -PassThru -WindowStyle Hidden "+
+		"-ArgumentList '-NoProfile','-NonInteractive','-EncodedCommand','%s'; exit $p.ExitCode", encodePowerShell(script))
+	output, err := c.combinedOutput("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", outer)
+	if err != nil {
+		outStr := strings.TrimSpace(string(output))
+		if outStr == "" {
+			outStr = err.Error()
+		}
+		return fmt.Errorf("elevated PowerShell failed: %s", outStr)
+	}
+
+	return nil
+}
+
+// ExpandVHDFile grows the virtual size of a VHD file (Windows format path) to
+// sizeBytes with diskpart. The VHD must not be in use.
+func (c *Client) ExpandVHDFile(winPath string, sizeBytes int64) error {
+	script := diskpartScript(
+		fmt.Sprintf(`select vdisk file="%s"`, windowsBackslashes(winPath)),
+		fmt.Sprintf("expand vdisk maximum=%d", bytesToMiB(sizeBytes)),
+	)
+	if err := c.RunElevatedPowerShell(script); err != nil {
+		return fmt.Errorf("diskpart expand failed: %w", err)
+	}
+	return nil
+}
+
+// CompactVHDFile reclaims unused space in a dynamic VHD file (Windows format
+// path). Optimize-VHD is used when the Hyper-V module is installed, diskpart
+// otherwise. The VHD must not be in use.
+func (c *Client) CompactVHDFile(winPath string) error {
+	if err := c.RunElevatedPowerShell(compactScript(winPath)); err != nil {
+		return fmt.Errorf("VHD compaction failed: %w", err)
+	}
+	return nil
+}
+
+// compactScript returns the PowerShell script used by CompactVHDFile
+func compactScript(winPath string) string {
+	native := windowsBackslashes(winPath)
+	optimize := fmt.Sprintf("if (Get-Command Optimize-VHD -ErrorAction SilentlyContinue) {\n"+
+		"  try { Optimize-VHD -Path '%s' -Mode Full -ErrorAction Stop; exit 0 } catch { exit 1 }\n"+
+		"}\n", strings.ReplaceAll(native, "'", "''"))
+	return optimize + diskpartScript(
+		fmt.Sprintf(`select vdisk file="%s"`, native),
+		"attach vdisk readonly",
+		"compact vdisk",
+		"detach vdisk",
+	)
+}
+
+// diskpartScript returns a PowerShell script that runs the given diskpart
+// commands from a temporary script file and exits with diskpart's exit code
+func diskpartScript(commands ...string) string {
+	var b strings.Builder
+	b.WriteString("$f = New-TemporaryFile\n")
+	b.WriteString("Set-Content -Path $f.FullName -Encoding ASCII -Value @'\n")
+	for _, command := range commands {
+		b.WriteString(command + "\n")
+	}
+	b.WriteString("'@\n")
+	b.WriteString("diskpart.exe /s $f.FullName\n")
+	b.WriteString("$rc = $LASTEXITCODE\n")
+	b.WriteString("Remove-Item $f.FullName\n")
+	b.WriteString("exit $rc\n")
+	return b.String()
+}
+
+// encodePowerShell encodes a script for powershell.exe -EncodedCommand
+// (base64 of UTF-16LE), which avoids quoting it through several shells
+func encodePowerShell(script string) string {
+	units := utf16.Encode([]rune(script))
+	buf := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		buf = append(buf, byte(u), byte(u>>8))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// windowsBackslashes converts a vhdm path (C:/dir/file) to native Windows form
+func windowsBackslashes(winPath string) string {
+	return strings.ReplaceAll(winPath, "/", `\`)
+}
+
+// bytesToMiB converts bytes to whole MiB, rounding up
+func bytesToMiB(bytes int64) int64 {
+	const mib = 1024 * 1024
+	return (bytes + mib - 1) / mib
+}
@@ -0,0 +1,88 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodePowerShell(t *testing.T) {
+	// Reference value: [Convert]::ToBase64String([Text.Encoding]::Unicode.GetBytes('exit 0'))
+	if got, want := encodePowerShell("exit 0"), "ZQB4AGkAdAAgADAA"; got != want {
+		t.Errorf("encodePowerShell() = %q, want %q", got, want)
+	}
+}
+
+func TestDiskpartScript(t *testing.T) {
+	script := diskpartScript(`select vdisk file="C:\VMs\ext4.vhdx"`, "expand vdisk maximum=1024")
+
+	for _, want := range []string{
+		"select vdisk file=\"C:\\VMs\\ext4.vhdx\"\nexpand vdisk maximum=1024\n'@\n",
+		"diskpart.exe /s $f.FullName",
+		"exit $rc",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("diskpartScript() missing %q in:\n%s", want, script)
+		}
+	}
+}
+
+func TestBytesToMiB(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  int64
+	}{
+		{0, 0},
+		{1, 1},
+		{1024 * 1024, 1},
+		{1024*1024 + 1, 2},
+		{512 * 1024 * 1024 * 1024, 512 * 1024},
+	}
+
+	for _, tt := range tests {
+		if got := bytesToMiB(tt.bytes); got != tt.want {
+			t.Errorf("bytesToMiB(%d) = %d, want %d", tt.bytes, got, tt.want)
+		}
+	}
+}
+
+func TestCompactScript(t *testing.T) {
+	script := compactScript("C:/Users/o'neil/WSL/ext4.vhdx")
+
+	for _, want := range []string{
+		`Optimize-VHD -Path 'C:\Users\o''neil\WSL\ext4.vhdx' -Mode Full`,
+		`select vdisk file="C:\Users\o'neil\WSL\ext4.vhdx"`,
+		"attach vdisk readonly\ncompact vdisk\ndetach vdisk\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("compactScript() missing %q in:\n%s", want, script)
+		}
+	}
+}
+
+func TestParseDistributionList(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "CRLF lines with BOM",
+			input: "\uFEFFUbuntu\r\ndocker-desktop\r\n\r\n",
+			want:  []string{"Ubuntu", "docker-desktop"},
+		},
+		{
+			name:  "empty",
+			input: "",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseDistributionList(tt.input)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") || len(got) != len(tt.want) {
+				t.Errorf("parseDistributionList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
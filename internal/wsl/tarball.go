@@ -0,0 +1,213 @@
+package wsl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// tarCompressors maps an archive compression to its compress/decompress commands
+var tarCompressors = map[string]struct {
+	compress   []string
+	decompress []string
+}{
+	"zstd": {[]string{"zstd", "-q", "-T0"}, []string{"zstd", "-q", "-d", "-c"}},
+	"gzip": {[]string{"gzip", "-c"}, []string{"gzip", "-d", "-c"}},
+	"xz":   {[]string{"xz", "-c", "-T0"}, []string{"xz", "-d", "-c"}},
+}
+
+// tarCompression returns the compression of an archive path based on its
+// extension: "zstd" (.tar.zst), "gzip" (.tar.gz/.tgz), "xz" (.tar.xz) or "" (.tar)
+func tarCompression(archivePath string) (string, error) {
+	lower := strings.ToLower(archivePath)
+	switch {
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return "zstd", nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "gzip", nil
+	case strings.HasSuffix(lower, ".tar.xz"), strings.HasSuffix(lower, ".txz"):
+		return "xz", nil
+	case strings.HasSuffix(lower, ".tar"):
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported archive format: %s (use .tar.zst, .tar.gz, .tar.xz or .tar)", archivePath)
+}
+
+// CreateTarball streams the contents of srcDir into a (compressed) tar archive at dst
+func (c *Client) CreateTarball(srcDir, dst string) error {
+	compression, err := tarCompression(dst)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer out.Close()
+
+	c.logger.Debug("Running: sudo tar -C %s -cf - . (compression: %s) > %s", srcDir, compression, dst)
+
+	tarCmd, err := c.privileged("tar-create", srcDir)
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	var tarErr strings.Builder
+	tarCmd.Stderr = &tarErr
+
+	if compression == "" {
+		tarCmd.Stdout = out
+		if err := tarCmd.Run(); err != nil {
+			os.Remove(dst)
+			return fmt.Errorf("tar failed: %s", strings.TrimSpace(tarErr.String()))
+		}
+		return nil
+	}
+
+	args := tarCompressors[compression].compress
+	compCmd := exec.Command(args[0], args[1:]...)
+	compCmd.Stdout = out
+	var compErr strings.Builder
+	compCmd.Stderr = &compErr
+
+	// A compressor that fails reports the cause; tar then only fails with a
+	// broken pipe
+	tarRunErr, compRunErr := pipeCommands(tarCmd, compCmd)
+	if compRunErr != nil {
+		os.Remove(dst)
+		return commandError(compression, compRunErr, compErr.String())
+	}
+	if tarRunErr != nil {
+		os.Remove(dst)
+		return commandError("tar", tarRunErr, tarErr.String())
+	}
+
+	return nil
+}
+
+// ExtractTarball extracts a (compressed) tar archive into dstDir, preserving
+// ownership and permissions
+func (c *Client) ExtractTarball(src, dstDir string) error {
+	compression, err := tarCompression(src)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer in.Close()
+
+	c.logger.Debug("Running: sudo tar -C %s -xpf - (compression: %s) < %s", dstDir, compression, src)
+
+	tarCmd, err := c.privileged("tar-extract", dstDir)
+	if err != nil {
+		return err
+	}
+	var tarErr strings.Builder
+	tarCmd.Stderr = &tarErr
+
+	if compression == "" {
+		tarCmd.Stdin = in
+		if err := tarCmd.Run(); err != nil {
+			return fmt.Errorf("tar failed: %s", strings.TrimSpace(tarErr.String()))
+		}
+		return nil
+	}
+
+	args := tarCompressors[compression].decompress
+	decompCmd := exec.Command(args[0], args[1:]...)
+	decompCmd.Stdin = in
+	var decompErr strings.Builder
+	decompCmd.Stderr = &decompErr
+
+	// A tar that fails reports the cause; the decompressor then only fails
+	// with a broken pipe
+	decompRunErr, tarRunErr := pipeCommands(decompCmd, tarCmd)
+	if tarRunErr != nil {
+		return commandError("tar", tarRunErr, tarErr.String())
+	}
+	if decompRunErr != nil {
+		return commandError(compression, decompRunErr, decompErr.String())
+	}
+
+	return nil
+}
+
+// pipeCommands runs from | to and waits for both. The parent closes its
+// copies of the pipe once both have started, so when either exits early the
+// other sees end of file or a broken pipe instead of blocking forever.
+func pipeCommands(from, to *exec.Cmd) (fromErr, toErr error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipe: %w", err)
+	}
+	from.Stdout = w
+	to.Stdin = r
+
+	if err := from.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return fmt.Errorf("failed to start %s: %w", from.Args[0], err), nil
+	}
+	if err := to.Start(); err != nil {
+		r.Close()
+		w.Close()
+		from.Process.Kill()
+		from.Wait()
+		return nil, fmt.Errorf("failed to start %s: %w", to.Args[0], err)
+	}
+	r.Close()
+	w.Close()
+
+	toErr = to.Wait()
+	fromErr = from.Wait()
+	return fromErr, toErr
+}
+
+// commandError describes a failed command by its standard error, or by err
+// when it printed nothing
+func commandError(name string, err error, stderr string) error {
+	if msg := strings.TrimSpace(stderr); msg != "" {
+		return fmt.Errorf("%s failed: %s", name, msg)
+	}
+	return fmt.Errorf("%s failed: %w", name, err)
+}
+
+// TarballSize returns the uncompressed size in bytes of a tar archive
+func (c *Client) TarballSize(src string) (int64, error) {
+	compression, err := tarCompression(src)
+	if err != nil {
+		return 0, err
+	}
+
+	if compression == "" {
+		return c.FileSize(src)
+	}
+
+	decompress := tarCompressors[compression].decompress
+	args := append(append([]string{}, decompress...), src)
+	c.logger.Debug("Running: %s | wc -c", strings.Join(args, " "))
+
+	cmd := exec.Command(args[0], args[1:]...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", compression, err)
+	}
+	n, copyErr := io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return 0, fmt.Errorf("%s failed: %w", compression, err)
+	}
+	if copyErr != nil {
+		return 0, fmt.Errorf("failed to read archive: %w", copyErr)
+	}
+
+	return n, nil
+}
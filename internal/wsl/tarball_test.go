@@ -0,0 +1,79 @@
+package wsl
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestTarCompression(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"zstd", "/tmp/data.tar.zst", "zstd", false},
+		{"zstd short", "/tmp/data.tzst", "zstd", false},
+		{"gzip", "/tmp/data.tar.gz", "gzip", false},
+		{"gzip short", "/tmp/data.tgz", "gzip", false},
+		{"xz", "/tmp/data.tar.xz", "xz", false},
+		{"uppercase", "/tmp/DATA.TAR.ZST", "zstd", false},
+		{"plain tar", "/tmp/data.tar", "", false},
+		{"unsupported", "/tmp/data.zip", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tarCompression(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("tarCompression(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("tarCompression(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeCommandsConsumerFails(t *testing.T) {
+	// More than a pipe buffer, so the producer blocks unless the pipe is
+	// closed once the consumer exits
+	from := exec.Command("head", "-c", "10000000", "/dev/zero")
+	to := exec.Command("false")
+
+	done := make(chan error, 1)
+	go func() {
+		_, toErr := pipeCommands(from, to)
+		done <- toErr
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("pipeCommands() did not report the failing consumer")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("pipeCommands() hung after the consumer exited")
+	}
+}
+
+func TestPipeCommandsConsumerExitsEarly(t *testing.T) {
+	// A compressor that stops reading without failing: the producer must
+	// end with a broken pipe rather than block on the full pipe
+	from := exec.Command("head", "-c", "10000000", "/dev/zero")
+	to := exec.Command("head", "-c", "1")
+
+	done := make(chan error, 1)
+	go func() {
+		fromErr, _ := pipeCommands(from, to)
+		done <- fromErr
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("pipeCommands() did not report the producer's broken pipe")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("pipeCommands() hung after the consumer exited")
+	}
+}
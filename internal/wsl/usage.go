@@ -0,0 +1,91 @@
+package wsl
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DirUsage holds the disk usage of a directory
+type DirUsage struct {
+	Path  string `json:"path"` // Path relative to the scanned root ("." for the root itself)
+	Bytes int64  `json:"bytes"`
+}
+
+// DiskUsage returns directory usage under root up to the given depth, largest first.
+// The root itself is included as ".".
+func (c *Client) DiskUsage(root string, depth int) ([]DirUsage, error) {
+	c.logger.Debug("Running: sudo du -x -b --max-depth=%d %s", depth, root)
+
+	argv, err := c.privilegedArgv("du", strconv.Itoa(depth), root)
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.output("sudo", argv...)
+	if err != nil && len(output) == 0 {
+		return nil, fmt.Errorf("du failed: %w", err)
+	}
+
+	return parseDuOutput(string(output), root), nil
+}
+
+// parseDuOutput parses "du -b" output lines ("<bytes>\t<path>") into usages
+// relative to root, sorted by size (largest first)
+func parseDuOutput(output, root string) []DirUsage {
+	root = filepath.Clean(root)
+
+	var usages []DirUsage
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		bytes, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, filepath.Clean(parts[1]))
+		if err != nil {
+			rel = parts[1]
+		}
+		usages = append(usages, DirUsage{Path: rel, Bytes: bytes})
+	}
+
+	sort.SliceStable(usages, func(i, j int) bool {
+		return usages[i].Bytes > usages[j].Bytes
+	})
+
+	return usages
+}
+
+// FindFiles searches root (without crossing filesystems) for entries whose name
+// matches the glob pattern, case-insensitively. Returned paths are relative to root.
+func (c *Client) FindFiles(root, pattern string, maxDepth int) ([]string, error) {
+	c.logger.Debug("Running: sudo find %s -xdev -maxdepth %d -iname %q -print", root, maxDepth, pattern)
+
+	argv, err := c.privilegedArgv("find", root, strconv.Itoa(maxDepth), pattern)
+	if err != nil {
+		return nil, err
+	}
+	output, err := c.output("sudo", argv...)
+	if err != nil && len(output) == 0 {
+		return nil, fmt.Errorf("find failed: %w", err)
+	}
+
+	var matches []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == "" {
+			continue
+		}
+		rel, err := filepath.Rel(root, line)
+		if err != nil || rel == "." {
+			continue
+		}
+		matches = append(matches, rel)
+	}
+	sort.Strings(matches)
+
+	return matches, nil
+}
@@ -0,0 +1,37 @@
+package wsl
+
+import (
+	"testing"
+)
+
+func TestParseDuOutput(t *testing.T) {
+	output := "4096\t/mnt/data/empty\n" +
+		"1048576\t/mnt/data/projects/a\n" +
+		"2097152\t/mnt/data/projects\n" +
+		"garbage line\n" +
+		"3149824\t/mnt/data\n"
+
+	got := parseDuOutput(output, "/mnt/data/")
+
+	want := []DirUsage{
+		{".", 3149824},
+		{"projects", 2097152},
+		{"projects/a", 1048576},
+		{"empty", 4096},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("parseDuOutput() returned %d entries, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseDuOutput()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseDuOutputEmpty(t *testing.T) {
+	if got := parseDuOutput("", "/mnt/data"); len(got) != 0 {
+		t.Errorf("parseDuOutput(\"\") = %v, want empty", got)
+	}
+}
@@ -0,0 +1,81 @@
+package wsl
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrHyperVUnavailable reports that the Hyper-V PowerShell module (Get-VHD)
+// is not installed on Windows
+var ErrHyperVUnavailable = errors.New("Hyper-V PowerShell module not installed")
+
+// exit code of vhdSizeScript when Get-VHD is missing
+const noHyperV = 3
+
+// WindowsVHDSizes returns the virtual size in bytes of VHD files (Windows
+// format paths) as reported by Get-VHD, keyed by the given path. Unlike
+// qemu-img it also reads files that are attached and locked by Windows.
+// Files Get-VHD cannot read are left out.
+func (c *Client) WindowsVHDSizes(winPaths []string) (map[string]int64, error) {
+	if len(winPaths) == 0 {
+		return map[string]int64{}, nil
+	}
+	if err := c.EnsureInterop(); err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Running Get-VHD for %d file(s)", len(winPaths))
+
+	output, err := c.output("powershell.exe", "-NoProfile", "-NonInteractive",
+		"-EncodedCommand", encodePowerShell(vhdSizeScript(winPaths)))
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == noHyperV {
+			return nil, ErrHyperVUnavailable
+		}
+		return nil, fmt.Errorf("Get-VHD failed: %w", err)
+	}
+	return parseVHDSizes(string(output), winPaths), nil
+}
+
+// vhdSizeScript returns the PowerShell script used by WindowsVHDSizes. It
+// prints "<index>\t<size>" for each file Get-VHD reads.
+func vhdSizeScript(winPaths []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "if (-not (Get-Command Get-VHD -ErrorAction SilentlyContinue)) { exit %d }\n", noHyperV)
+	b.WriteString("$paths = @(\n")
+	for _, p := range winPaths {
+		fmt.Fprintf(&b, "  '%s'\n", strings.ReplaceAll(windowsBackslashes(p), "'", "''"))
+	}
+	b.WriteString(")\n")
+	b.WriteString("for ($i = 0; $i -lt $paths.Count; $i++) {\n")
+	b.WriteString("  $v = Get-VHD -Path $paths[$i] -ErrorAction SilentlyContinue\n")
+	b.WriteString("  if ($v) { Write-Output (\"{0}`t{1}\" -f $i, $v.Size) }\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// parseVHDSizes maps the "<index>\t<size>" lines of vhdSizeScript back to
+// the paths they were asked for
+func parseVHDSizes(output string, winPaths []string) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, line := range strings.Split(output, "\n") {
+		index, size, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= len(winPaths) {
+			continue
+		}
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			continue
+		}
+		sizes[winPaths[i]] = n
+	}
+	return sizes
+}
@@ -0,0 +1,30 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVHDSizeScript(t *testing.T) {
+	script := vhdSizeScript([]string{"C:/VMs/a.vhdx", "D:/o'neil/b.vhd"})
+
+	for _, want := range []string{
+		"Get-Command Get-VHD",
+		"exit 3",
+		"  'C:\\VMs\\a.vhdx'\n  'D:\\o''neil\\b.vhd'\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("vhdSizeScript() missing %q in:\n%s", want, script)
+		}
+	}
+}
+
+func TestParseVHDSizes(t *testing.T) {
+	paths := []string{"C:/VMs/a.vhdx", "C:/VMs/b.vhdx", "C:/VMs/c.vhdx"}
+	output := "0\t1073741824\r\n2\t5368709120\r\nnoise\n7\t1\n1\tbad\n"
+
+	got := parseVHDSizes(output, paths)
+	if len(got) != 2 || got["C:/VMs/a.vhdx"] != 1073741824 || got["C:/VMs/c.vhdx"] != 5368709120 {
+		t.Errorf("parseVHDSizes() = %v", got)
+	}
+}
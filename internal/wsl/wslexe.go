@@ -0,0 +1,125 @@
+package wsl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+// wslExeError is a failure reported by wsl.exe. Its messages are localized,
+// so it is recognized by the language-independent error codes wsl.exe prints
+// along with them ("Error code: Wsl/Service/AttachDisk/WSL_E_...") instead.
+// Codes listed in types.WSLErrorCodes make it wrap the vhdm error they map
+// to, for errors.Is.
+type wslExeError struct {
+	Op     string   // Operation for the message, e.g. "attach"
+	Output string   // Decoded output of wsl.exe
+	Codes  []string // Error codes found in Output
+	Err    error    // The exit or context error
+	Known  error    // vhdm error the codes map to, or nil
+}
+
+func (e *wslExeError) Error() string {
+	if e.Output == "" {
+		return fmt.Sprintf("wsl.exe %s failed: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("wsl.exe %s failed: %s", e.Op, e.Output)
+}
+
+func (e *wslExeError) Unwrap() []error {
+	if e.Known == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Known, e.Err}
+}
+
+// Has reports whether wsl.exe reported an error code, given either in full
+// or as its last component (WSL_E_USER_VHD_ALREADY_ATTACHED matches
+// Wsl/Service/AttachDisk/WSL_E_USER_VHD_ALREADY_ATTACHED)
+func (e *wslExeError) Has(code string) bool {
+	for _, c := range e.Codes {
+		if strings.EqualFold(c, code) || strings.HasSuffix(strings.ToUpper(c), "/"+strings.ToUpper(code)) {
+			return true
+		}
+	}
+	return false
+}
+
+// wslErrorCode reports whether err is a wsl.exe failure with an error code
+func wslErrorCode(err error, code string) bool {
+	var werr *wslExeError
+	return errors.As(err, &werr) && werr.Has(code)
+}
+
+// wslCodeRe matches the error codes in wsl.exe output: Wsl/... paths,
+// WSL_E_* and Win32 ERROR_* names, and HRESULTs
+var wslCodeRe = regexp.MustCompile(`\bWsl/[A-Za-z0-9_/]+|\b(?:WSL_E|ERROR|E)_[A-Z0-9_]+\b|\b0x[0-9a-fA-F]{8}\b`)
+
+// parseWSLErrorCodes returns the error codes in decoded wsl.exe output
+func parseWSLErrorCodes(output string) []string {
+	return wslCodeRe.FindAllString(output, -1)
+}
+
+// runWSLExe runs wsl.exe and returns its decoded output. Failures are
+// returned as a *wslExeError describing op.
+func (c *Client) runWSLExe(ctx context.Context, op string, args ...string) (string, error) {
+	output, err := c.runner.CombinedOutput(ctx, "wsl.exe", args...)
+	outStr := strings.TrimSpace(decodeWSLOutput(output))
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		codes := parseWSLErrorCodes(outStr)
+		return outStr, &wslExeError{Op: op, Output: outStr, Codes: codes, Err: err, Known: types.LookupWSLError(op, codes)}
+	}
+	return outStr, nil
+}
+
+// decodeWSLOutput decodes the output of wsl.exe. Its own messages are
+// UTF-16LE (unless WSL_UTF8=1 is set), while the output of commands run in
+// a distribution is UTF-8, so the encoding is detected (see isUTF16LE). NUL
+// bytes in other output, such as both mixed, are dropped. Line endings are
+// normalized to \n.
+func decodeWSLOutput(b []byte) string {
+	var s string
+	if isUTF16LE(b) {
+		b = bytes.TrimPrefix(b, []byte{0xff, 0xfe})
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			units[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+		}
+		s = string(utf16.Decode(units))
+	} else {
+		s = string(bytes.ReplaceAll(b, []byte{0}, nil))
+	}
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
+
+// isUTF16LE reports whether b looks like UTF-16LE text. ASCII characters,
+// which wsl.exe output has plenty of even in other scripts (error codes,
+// paths), have a NUL high byte at the odd positions.
+func isUTF16LE(b []byte) bool {
+	if len(b) >= 2 && b[0] == 0xff && b[1] == 0xfe {
+		return true
+	}
+	if len(b)%2 != 0 {
+		return false
+	}
+	var odd, even int
+	for i, c := range b {
+		if c == 0 {
+			if i%2 == 1 {
+				odd++
+			} else {
+				even++
+			}
+		}
+	}
+	return odd > 0 && odd > even
+}
@@ -0,0 +1,85 @@
+package wsl
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/rjdinis/vhdm/internal/logging"
+	"github.com/rjdinis/vhdm/internal/types"
+)
+
+// utf16LE encodes s the way wsl.exe writes its messages
+func utf16LE(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+func TestDecodeWSLOutput(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"utf-16", utf16LE("Opera\u00e7\u00e3o conclu\u00edda.\r\n"), "Opera\u00e7\u00e3o conclu\u00edda.\n"},
+		{"utf-16 bom", append([]byte{0xff, 0xfe}, utf16LE("ok")...), "ok"},
+		{"utf-16 cjk", utf16LE("\u4e00\u4e01 Wsl/E_FAIL"), "\u4e00\u4e01 Wsl/E_FAIL"},
+		{"utf-8", []byte("Ubuntu\nDebian\n"), "Ubuntu\nDebian\n"},
+		{"stray nul", []byte("Ubuntu\x00\n"), "Ubuntu\n"},
+	} {
+		if got := decodeWSLOutput(tt.in); got != tt.want {
+			t.Errorf("%s: decodeWSLOutput() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWSLExeErrorHas(t *testing.T) {
+	out := "O disco j\u00e1 est\u00e1 anexado.\nC\u00f3digo de erro: Wsl/Service/AttachDisk/WSL_E_USER_VHD_ALREADY_ATTACHED"
+	err := &wslExeError{Op: "attach", Output: out, Codes: parseWSLErrorCodes(out)}
+
+	if !err.Has("WSL_E_USER_VHD_ALREADY_ATTACHED") {
+		t.Errorf("Has(last component) = false, codes %q", err.Codes)
+	}
+	if !err.Has("Wsl/Service/AttachDisk/WSL_E_USER_VHD_ALREADY_ATTACHED") {
+		t.Error("Has(full code) = false")
+	}
+	if err.Has("ERROR_FILE_NOT_FOUND") || err.Has("ATTACHED") {
+		t.Error("Has() matched a code wsl.exe did not report")
+	}
+}
+
+func TestAttachDetachLocalizedErrors(t *testing.T) {
+	var output []byte
+	c := NewClient(logging.New(true, false), 0, time.Second)
+	c.SetRunner(funcRunner(func(name string, args []string) ([]byte, error) {
+		if name != "wsl.exe" {
+			return nil, nil
+		}
+		return output, errors.New("exit status 1")
+	}))
+
+	output = utf16LE("O disco j\u00e1 est\u00e1 anexado.\r\nC\u00f3digo de erro: Wsl/Service/AttachDisk/WSL_E_USER_VHD_ALREADY_ATTACHED\r\n")
+	if _, err := c.attachVHD("C:/VMs/data.vhdx"); err != types.ErrVHDAlreadyAttached {
+		t.Errorf("attachVHD() error = %v, want ErrVHDAlreadyAttached", err)
+	}
+
+	output = utf16LE("Le fichier sp\u00e9cifi\u00e9 est introuvable.\r\nCode d\u2019erreur\u00a0: Wsl/Service/DetachDisk/ERROR_FILE_NOT_FOUND\r\n")
+	if err := c.DetachVHD("C:/VMs/data.vhdx"); err != types.ErrVHDNotAttached {
+		t.Errorf("DetachVHD() error = %v, want ErrVHDNotAttached", err)
+	}
+
+	output = utf16LE("Der Prozess kann nicht auf die Datei zugreifen.\r\nFehlercode: Wsl/Service/AttachDisk/0x80070020\r\n")
+	if _, err := c.attachVHD("C:/VMs/data.vhdx"); !errors.Is(err, types.ErrFileInUse) {
+		t.Errorf("attachVHD() error = %v, want ErrFileInUse", err)
+	}
+
+	output = utf16LE("Acesso negado.\r\nC\u00f3digo de erro: Wsl/Service/DetachDisk/E_ACCESSDENIED\r\n")
+	err := c.DetachVHD("C:/VMs/data.vhdx")
+	if err == nil || err.Error() != "wsl.exe unmount failed: Acesso negado.\nC\u00f3digo de erro: Wsl/Service/DetachDisk/E_ACCESSDENIED" {
+		t.Errorf("DetachVHD() error = %v", err)
+	}
+}
@@ -0,0 +1,971 @@
+// Package wslfake provides an in-memory implementation of wsl.Interface, so
+// command logic can be tested without a WSL2 host.
+//
+// A Fake models VHD files, the block devices they become when attached, and
+// their filesystems and mount points. Operations on the Windows side that
+// have no observable state here (compaction, rsync, docker, ...) succeed
+// without effect. Every state-changing call is recorded in Calls, and Errors
+// makes a method fail.
+package wslfake
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rjdinis/vhdm/internal/types"
+	"github.com/rjdinis/vhdm/internal/wsl"
+	"github.com/rjdinis/vhdm/pkg/utils"
+)
+
+// Disk is a VHD file of the fake
+type Disk struct {
+	Size        int64         // Virtual size in bytes
+	Device      string        // Block device name while attached, empty when detached
+	UUID        string        // Filesystem UUID, empty when unformatted
+	Label       string        // Filesystem label
+	PartUUID    string        // Partition UUID
+	FSType      string        // Filesystem type, empty when unformatted
+	MountPoints []string      // Mount points while attached
+	IDFile      *types.IDFile // Contents of .vhdm.json in the filesystem
+	Parent      string        // WSL path of the parent of a differencing VHD
+	FSErrors    string        // Problems reported by CheckFilesystem, empty when clean
+}
+
+// Fake is an in-memory wsl.Interface. The zero value is not usable; create
+// one with New.
+type Fake struct {
+	mu sync.Mutex
+
+	// Disks are the VHD files by WSL path (see AddVHD)
+	Disks map[string]*Disk
+	// Files are other files (archives, tarballs) by WSL path, with their size
+	Files map[string]int64
+	// SystemDisks are the WSL system block devices, listed before VHDs
+	SystemDisks []wsl.BlockDevice
+	// Distributions are the registered WSL distributions
+	Distributions []wsl.WSLDistribution
+	// Running holds the running distributions by name
+	Running map[string]bool
+	// Volumes are the Docker volumes by name, with their bind source
+	Volumes map[string]string
+
+	// Errors makes the method of that name (e.g. "AttachVHD") return the error
+	Errors map[string]error
+	// Calls records state-changing calls as "Method arg...", in order
+	Calls []string
+	// Frozen are the mount points frozen by Freeze and not yet thawed
+	Frozen []string
+	// Differences are the files CompareTrees reports as differing
+	Differences []string
+
+	nextUUID int
+}
+
+var _ wsl.Interface = (*Fake)(nil)
+
+// New returns a Fake with the three WSL system disks and nothing else
+func New() *Fake {
+	return &Fake{
+		Disks: make(map[string]*Disk),
+		Files: make(map[string]int64),
+		SystemDisks: []wsl.BlockDevice{
+			{Name: "sda", FSType: "ext4", Size: "388.4M"},
+			{Name: "sdb", FSType: "swap", Size: "2G"},
+			{Name: "sdc", UUID: "11111111-1111-4111-8111-111111111111", FSType: "ext4", MountPoints: []string{"/mnt/wslg/distro", "/"}, Size: "1T"},
+		},
+		Running: make(map[string]bool),
+		Volumes: make(map[string]string),
+		Errors:  make(map[string]error),
+	}
+}
+
+// AddVHD adds a detached, unformatted VHD file at a Windows path and returns
+// it for further setup
+func (f *Fake) AddVHD(winPath string, size int64) *Disk {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := &Disk{Size: size}
+	f.Disks[utils.ConvertWindowsToWSLPath(winPath)] = d
+	return d
+}
+
+// Disk returns the VHD file at a Windows path, or nil
+func (f *Fake) Disk(winPath string) *Disk {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Disks[utils.ConvertWindowsToWSLPath(winPath)]
+}
+
+// record logs a state-changing call and returns the error configured for it
+func (f *Fake) record(method string, args ...string) error {
+	f.Calls = append(f.Calls, strings.TrimSpace(method+" "+strings.Join(args, " ")))
+	return f.Errors[method]
+}
+
+// byDevice returns the attached disk with a block device name
+func (f *Fake) byDevice(devName string) *Disk {
+	devName = strings.TrimPrefix(devName, "/dev/")
+	for _, d := range f.Disks {
+		if d.Device != "" && d.Device == devName {
+			return d
+		}
+	}
+	return nil
+}
+
+// byUUID returns the attached disk with a filesystem UUID
+func (f *Fake) byUUID(uuid string) *Disk {
+	for _, d := range f.Disks {
+		if d.Device != "" && uuid != "" && d.UUID == uuid {
+			return d
+		}
+	}
+	return nil
+}
+
+// freeDevice returns the first unused dynamic device name (sdd, sde, ...)
+func (f *Fake) freeDevice() string {
+	for c := 'd'; c <= 'z'; c++ {
+		name := "sd" + string(c)
+		if f.byDevice(name) == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+func (f *Fake) attach(path string) (*Disk, error) {
+	d := f.Disks[utils.ConvertWindowsToWSLPath(path)]
+	if d == nil {
+		return nil, fmt.Errorf("wsl.exe mount failed: %w", types.ErrVHDNotFound)
+	}
+	if d.Device != "" {
+		return nil, types.ErrVHDAlreadyAttached
+	}
+	d.Device = f.freeDevice()
+	return d, nil
+}
+
+// EnsureSudo always succeeds
+func (f *Fake) EnsureSudo() error { return nil }
+
+// ConvertPath converts a Windows path like the real client
+func (f *Fake) ConvertPath(winPath string) string {
+	return utils.ConvertWindowsToWSLPath(winPath)
+}
+
+func (f *Fake) AttachVHD(path string) (*types.AttachResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("AttachVHD", path); err != nil {
+		return nil, err
+	}
+	d, err := f.attach(path)
+	if err != nil {
+		return nil, err
+	}
+	return &types.AttachResult{WasNew: true, DeviceName: d.Device, UUID: d.UUID}, nil
+}
+
+func (f *Fake) AttachVHDAndDetect(path string) (string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("AttachVHDAndDetect", path); err != nil {
+		return "", false, err
+	}
+	d, err := f.attach(path)
+	if err != nil {
+		return "", false, err
+	}
+	return d.Device, true, nil
+}
+
+func (f *Fake) DetachVHD(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("DetachVHD", path); err != nil {
+		return err
+	}
+	d := f.Disks[utils.ConvertWindowsToWSLPath(path)]
+	if d == nil || d.Device == "" {
+		return types.ErrVHDNotAttached
+	}
+	d.Device = ""
+	d.MountPoints = nil
+	return nil
+}
+
+func (f *Fake) IsAttached(uuid string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.byUUID(uuid) != nil, f.Errors["IsAttached"]
+}
+
+func (f *Fake) FindUUIDByPath(path string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := f.Disks[utils.ConvertWindowsToWSLPath(path)]
+	if d == nil {
+		return "", types.ErrVHDNotFound
+	}
+	if d.Device == "" {
+		return "", nil
+	}
+	return d.UUID, nil
+}
+
+func (f *Fake) GetAllDisks() ([]wsl.BlockDevice, error) {
+	return f.GetBlockDevicesWithInfo()
+}
+
+func (f *Fake) GetBlockDevicesWithInfo() ([]wsl.BlockDevice, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.Errors["GetBlockDevicesWithInfo"]; err != nil {
+		return nil, err
+	}
+	devices := slices.Clone(f.SystemDisks)
+	var vhds []wsl.BlockDevice
+	for _, d := range f.Disks {
+		if d.Device == "" {
+			continue
+		}
+		vhds = append(vhds, wsl.BlockDevice{
+			Name:        d.Device,
+			UUID:        d.UUID,
+			Label:       d.Label,
+			PartUUID:    d.PartUUID,
+			FSType:      d.FSType,
+			MountPoints: slices.Clone(d.MountPoints),
+			Size:        utils.BytesToHuman(d.Size),
+		})
+	}
+	sort.Slice(vhds, func(i, j int) bool { return vhds[i].Name < vhds[j].Name })
+	return append(devices, vhds...), nil
+}
+
+func (f *Fake) GetDeviceByUUID(uuid string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d := f.byUUID(uuid); d != nil {
+		return d.Device, nil
+	}
+	return "", fmt.Errorf("no device with UUID %s", uuid)
+}
+
+func (f *Fake) GetUUIDByDevice(devName string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d := f.byDevice(devName); d != nil {
+		return d.UUID, nil
+	}
+	return "", nil
+}
+
+func (f *Fake) GetDeviceSize(devName string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d := f.byDevice(devName); d != nil {
+		return d.Size, nil
+	}
+	return 0, types.ErrDeviceNotFound
+}
+
+func (f *Fake) DeviceExists(devName string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.byDevice(devName) != nil
+}
+
+// DeviceIOCount reports no I/O
+func (f *Fake) DeviceIOCount(devName string) (uint64, error) { return 0, nil }
+
+// DeviceIOStats reports no I/O
+func (f *Fake) DeviceIOStats(devName string) (wsl.IOStats, error) { return wsl.IOStats{}, nil }
+
+func (f *Fake) GetVHDInfo(uuid string) (*types.VHDInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := f.byUUID(uuid)
+	if d == nil {
+		return nil, nil
+	}
+	info := &types.VHDInfo{UUID: uuid, DeviceName: d.Device, State: types.StateAttachedFormatted}
+	if len(d.MountPoints) > 0 {
+		info.MountPoint = d.MountPoints[0]
+		info.State = types.StateMounted
+	}
+	return info, nil
+}
+
+// Format gives the device a new filesystem with a fresh UUID
+func (f *Fake) Format(devName, fsType string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Format", devName, fsType); err != nil {
+		return "", err
+	}
+	d := f.byDevice(devName)
+	if d == nil {
+		return "", fmt.Errorf("format failed: /dev/%s: %w", devName, types.ErrDeviceNotFound)
+	}
+	f.nextUUID++
+	d.UUID = fmt.Sprintf("00000000-0000-4000-8000-%012d", f.nextUUID)
+	d.FSType = fsType
+	d.IDFile = nil
+	return d.UUID, nil
+}
+
+// RegenerateUUID gives the filesystem on an attached disk the next fake UUID
+func (f *Fake) RegenerateUUID(devName, fsType string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("RegenerateUUID", devName, fsType); err != nil {
+		return "", err
+	}
+	d := f.byDevice(devName)
+	if d == nil || d.UUID == "" {
+		return "", fmt.Errorf("failed to change UUID: /dev/%s is not formatted", devName)
+	}
+	f.nextUUID++
+	d.UUID = fmt.Sprintf("00000000-0000-4000-8000-%012d", f.nextUUID)
+	return d.UUID, nil
+}
+
+// CheckFilesystem reports the problems of FSErrors for the filesystem on an
+// attached disk; filesystems without them are clean
+func (f *Fake) CheckFilesystem(devName, fsType string) (*wsl.FilesystemCheck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CheckFilesystem", devName, fsType); err != nil {
+		return nil, err
+	}
+	d := f.byDevice(devName)
+	if d == nil || d.UUID == "" {
+		return nil, fmt.Errorf("filesystem check failed: /dev/%s is not formatted", devName)
+	}
+	if len(d.MountPoints) > 0 {
+		return nil, fmt.Errorf("filesystem check failed: /dev/%s is mounted", devName)
+	}
+	return &wsl.FilesystemCheck{Clean: d.FSErrors == "", Output: d.FSErrors}, nil
+}
+
+// GrowFilesystem checks that the filesystem on an attached disk can grow:
+// ext2/3/4 unmounted, the others mounted at mountPoint
+func (f *Fake) GrowFilesystem(devName, fsType, mountPoint string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("GrowFilesystem", devName, fsType, mountPoint); err != nil {
+		return err
+	}
+	d := f.byDevice(devName)
+	if d == nil || d.UUID == "" {
+		return fmt.Errorf("failed to grow filesystem: /dev/%s is not formatted", devName)
+	}
+	if strings.HasPrefix(fsType, "ext") {
+		if len(d.MountPoints) > 0 {
+			return fmt.Errorf("failed to grow filesystem: /dev/%s is mounted", devName)
+		}
+	} else if !slices.Contains(d.MountPoints, mountPoint) {
+		return fmt.Errorf("failed to grow filesystem: /dev/%s is not mounted at %s", devName, mountPoint)
+	}
+	return nil
+}
+
+func (f *Fake) IsFormatted(devName string) (bool, error) {
+	uuid, err := f.GetUUIDByDevice(devName)
+	return uuid != "", err
+}
+
+func (f *Fake) GetFilesystemType(devName string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d := f.byDevice(devName); d != nil {
+		return d.FSType, nil
+	}
+	return "", types.ErrDeviceNotFound
+}
+
+func (f *Fake) FilesystemTypeByUUID(uuid string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d := f.byUUID(uuid); d != nil {
+		return d.FSType
+	}
+	return ""
+}
+
+func (f *Fake) MountByUUID(uuid, mountPoint string) error {
+	return f.MountByUUIDWithOptions(uuid, mountPoint, "")
+}
+
+func (f *Fake) MountByUUIDWithOptions(uuid, mountPoint, options string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("MountByUUID", uuid, mountPoint, options); err != nil {
+		return err
+	}
+	d := f.byUUID(uuid)
+	if d == nil {
+		return fmt.Errorf("mount failed: no device with UUID %s", uuid)
+	}
+	if !slices.Contains(d.MountPoints, mountPoint) {
+		d.MountPoints = append(d.MountPoints, mountPoint)
+	}
+	return nil
+}
+
+// BindMount adds target as a mount point of the disk mounted at source or
+// above it
+func (f *Fake) BindMount(source, target string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("BindMount", source, target); err != nil {
+		return err
+	}
+	for _, d := range f.Disks {
+		for _, mp := range d.MountPoints {
+			if source == mp || strings.HasPrefix(source, mp+"/") {
+				d.MountPoints = append(d.MountPoints, target)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("bind mount failed: %s is not on a VHD", source)
+}
+
+func (f *Fake) Unmount(mountPoint string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Unmount", mountPoint); err != nil {
+		return err
+	}
+	return f.unmount(mountPoint)
+}
+
+func (f *Fake) ForceUnmount(mountPoint string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("ForceUnmount", mountPoint); err != nil {
+		return err
+	}
+	return f.unmount(mountPoint)
+}
+
+func (f *Fake) Freeze(mountPoint string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Freeze", mountPoint); err != nil {
+		return err
+	}
+	f.Frozen = append(f.Frozen, mountPoint)
+	return nil
+}
+
+func (f *Fake) Thaw(mountPoint string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Thaw", mountPoint); err != nil {
+		return err
+	}
+	f.Frozen = slices.DeleteFunc(f.Frozen, func(mp string) bool { return mp == mountPoint })
+	return nil
+}
+
+// WriteIDFile stores the ID file in the disk mounted at mountPoint
+func (f *Fake) WriteIDFile(mountPoint string, id types.IDFile) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("WriteIDFile", mountPoint); err != nil {
+		return err
+	}
+	d := f.byMountPoint(mountPoint)
+	if d == nil {
+		return fmt.Errorf("%s: not a mount point", mountPoint)
+	}
+	d.IDFile = &id
+	return nil
+}
+
+// ReadIDFile returns the ID file of the disk mounted at mountPoint
+func (f *Fake) ReadIDFile(mountPoint string) (*types.IDFile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.Errors["ReadIDFile"]; err != nil {
+		return nil, err
+	}
+	if d := f.byMountPoint(mountPoint); d != nil && d.IDFile != nil {
+		id := *d.IDFile
+		return &id, nil
+	}
+	return nil, nil
+}
+
+// byMountPoint returns the attached disk mounted at mountPoint
+func (f *Fake) byMountPoint(mountPoint string) *Disk {
+	mountPoint = strings.TrimSuffix(mountPoint, "/")
+	for _, d := range f.Disks {
+		if d.Device != "" && slices.Contains(d.MountPoints, mountPoint) {
+			return d
+		}
+	}
+	return nil
+}
+
+// Trim records the call
+func (f *Fake) Trim(mountPoint string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record("Trim", mountPoint)
+}
+
+func (f *Fake) unmount(mountPoint string) error {
+	mountPoint = strings.TrimSuffix(mountPoint, "/")
+	for _, d := range f.Disks {
+		if i := slices.Index(d.MountPoints, mountPoint); i >= 0 {
+			d.MountPoints = slices.Delete(d.MountPoints, i, i+1)
+			return nil
+		}
+	}
+	return fmt.Errorf("umount: %s: not mounted", mountPoint)
+}
+
+func (f *Fake) IsMounted(uuid string) (bool, error) {
+	mp, err := f.GetMountPoint(uuid)
+	return mp != "", err
+}
+
+func (f *Fake) GetMountPoint(uuid string) (string, error) {
+	mps, err := f.GetMountPoints(uuid)
+	if len(mps) == 0 {
+		return "", err
+	}
+	return mps[0], err
+}
+
+func (f *Fake) GetMountPoints(uuid string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if d := f.byUUID(uuid); d != nil {
+		return slices.Clone(d.MountPoints), nil
+	}
+	return nil, nil
+}
+
+func (f *Fake) GetUUIDByMountPoint(mountPoint string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, d := range f.Disks {
+		if d.Device != "" && d.UUID != "" && slices.Contains(d.MountPoints, mountPoint) {
+			return d.UUID, nil
+		}
+	}
+	return "", nil
+}
+
+func (f *Fake) FindUUIDByMountPoint(mountPoint string) (string, error) {
+	return f.GetUUIDByMountPoint(strings.TrimSuffix(mountPoint, "/"))
+}
+
+func (f *Fake) CreateVHD(wslPath, size string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CreateVHD", wslPath, size); err != nil {
+		return err
+	}
+	if f.Disks[wslPath] != nil {
+		return fmt.Errorf("qemu-img create failed: %s already exists", wslPath)
+	}
+	bytes, err := utils.ConvertSizeToBytes(size)
+	if err != nil {
+		return err
+	}
+	f.Disks[wslPath] = &Disk{Size: bytes}
+	return nil
+}
+
+// CreateDifferencingVHD creates a VHD with the contents of its parent
+func (f *Fake) CreateDifferencingVHD(parentPath, childPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CreateDifferencingVHD", parentPath, childPath); err != nil {
+		return err
+	}
+	parent := f.Disks[utils.ConvertWindowsToWSLPath(parentPath)]
+	if parent == nil {
+		return fmt.Errorf("diskpart create vdisk failed: %s not found", parentPath)
+	}
+	child := utils.ConvertWindowsToWSLPath(childPath)
+	if f.Disks[child] != nil {
+		return fmt.Errorf("diskpart create vdisk failed: %s already exists", childPath)
+	}
+	d := parent.copy()
+	d.Parent = utils.ConvertWindowsToWSLPath(parentPath)
+	f.Disks[child] = d
+	return nil
+}
+
+// MergeDifferencingVHD gives the parent of a differencing VHD its contents
+func (f *Fake) MergeDifferencingVHD(childPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("MergeDifferencingVHD", childPath); err != nil {
+		return err
+	}
+	child := f.Disks[utils.ConvertWindowsToWSLPath(childPath)]
+	if child == nil || child.Parent == "" || f.Disks[child.Parent] == nil {
+		return fmt.Errorf("diskpart merge vdisk failed: %s is not a differencing VHD", childPath)
+	}
+	f.Disks[child.Parent].setContents(child)
+	return nil
+}
+
+// CopyDevice gives the disk attached as dst the contents of the one at src
+func (f *Fake) CopyDevice(src, dst string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CopyDevice", src, dst); err != nil {
+		return err
+	}
+	s, d := f.byDevice(src), f.byDevice(dst)
+	if s == nil || d == nil {
+		return fmt.Errorf("dd failed: %w", types.ErrDeviceNotFound)
+	}
+	d.setContents(s)
+	return nil
+}
+
+// setContents gives d the filesystem of src
+func (d *Disk) setContents(src *Disk) {
+	c := src.copy()
+	d.UUID, d.Label, d.PartUUID, d.FSType, d.IDFile = c.UUID, c.Label, c.PartUUID, c.FSType, c.IDFile
+}
+
+// copy returns a detached disk with the contents of d
+func (d *Disk) copy() *Disk {
+	c := &Disk{Size: d.Size, UUID: d.UUID, Label: d.Label, PartUUID: d.PartUUID, FSType: d.FSType, Parent: d.Parent}
+	if d.IDFile != nil {
+		id := *d.IDFile
+		c.IDFile = &id
+	}
+	return c
+}
+
+func (f *Fake) DeleteVHD(wslPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("DeleteVHD", wslPath); err != nil {
+		return err
+	}
+	if _, ok := f.Files[wslPath]; ok {
+		delete(f.Files, wslPath)
+		return nil
+	}
+	if f.Disks[wslPath] == nil {
+		return fmt.Errorf("failed to delete VHD: %s: %w", wslPath, types.ErrVHDNotFound)
+	}
+	delete(f.Disks, wslPath)
+	return nil
+}
+
+func (f *Fake) FileExists(wslPath string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.Files[wslPath]
+	return ok || f.Disks[wslPath] != nil
+}
+
+// FileSize returns the size of a file; VHD files are as large as their
+// virtual size
+func (f *Fake) FileSize(wslPath string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if size, ok := f.Files[wslPath]; ok {
+		return size, nil
+	}
+	if d := f.Disks[wslPath]; d != nil {
+		return d.Size, nil
+	}
+	return 0, fmt.Errorf("stat %s: no such file", wslPath)
+}
+
+func (f *Fake) RenameFile(oldPath, newPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("RenameFile", oldPath, newPath); err != nil {
+		return err
+	}
+	if size, ok := f.Files[oldPath]; ok {
+		delete(f.Files, oldPath)
+		f.Files[newPath] = size
+		return nil
+	}
+	if d := f.Disks[oldPath]; d != nil {
+		delete(f.Disks, oldPath)
+		f.Disks[newPath] = d
+		return nil
+	}
+	return fmt.Errorf("rename %s: no such file", oldPath)
+}
+
+func (f *Fake) GetImageInfo(wslPath string) (*wsl.ImageInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := f.Disks[wslPath]
+	if d == nil {
+		return nil, fmt.Errorf("qemu-img info failed: %s: %w", wslPath, types.ErrVHDNotFound)
+	}
+	return &wsl.ImageInfo{Format: "vhdx", VirtualSize: d.Size, ActualSize: d.Size, BackingFile: d.Parent}, nil
+}
+
+// CheckImage reports every image as clean
+func (f *Fake) CheckImage(wslPath string) (*wsl.ImageCheck, error) {
+	if !f.FileExists(wslPath) {
+		return nil, fmt.Errorf("qemu-img check failed: %s: %w", wslPath, types.ErrVHDNotFound)
+	}
+	return &wsl.ImageCheck{}, f.Errors["CheckImage"]
+}
+
+func (f *Fake) ExpandVHDFile(winPath string, sizeBytes int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("ExpandVHDFile", winPath, fmt.Sprint(sizeBytes)); err != nil {
+		return err
+	}
+	d := f.Disks[utils.ConvertWindowsToWSLPath(winPath)]
+	if d == nil {
+		return types.ErrVHDNotFound
+	}
+	if d.Device != "" {
+		return fmt.Errorf("diskpart expand failed: the virtual disk is attached")
+	}
+	d.Size = sizeBytes
+	return nil
+}
+
+func (f *Fake) CompactVHDFile(winPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record("CompactVHDFile", winPath)
+}
+
+// FileInUseByWindows reports attached VHDs as in use
+func (f *Fake) FileInUseByWindows(winPath string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := f.Disks[utils.ConvertWindowsToWSLPath(winPath)]
+	return d != nil && d.Device != "", f.Errors["FileInUseByWindows"]
+}
+
+func (f *Fake) WindowsVHDSizes(winPaths []string) (map[string]int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sizes := make(map[string]int64)
+	for _, p := range winPaths {
+		if d := f.Disks[utils.ConvertWindowsToWSLPath(p)]; d != nil {
+			sizes[p] = d.Size
+		}
+	}
+	return sizes, f.Errors["WindowsVHDSizes"]
+}
+
+func (f *Fake) WindowsTempDir() (string, error) {
+	return "C:/Users/vhdm/AppData/Local/Temp", nil
+}
+
+func (f *Fake) Notify(method, title, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record("Notify", method, title)
+}
+
+func (f *Fake) CompressFile(src, dst string) error {
+	return f.copyFile("CompressFile", src, dst)
+}
+
+func (f *Fake) DecompressFile(src, dst string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("DecompressFile", src, dst); err != nil {
+		return err
+	}
+	size, ok := f.Files[src]
+	if !ok {
+		return fmt.Errorf("zstd: %s: no such file", src)
+	}
+	f.Disks[dst] = &Disk{Size: size}
+	return nil
+}
+
+// ConvertImage copies src like CopyFile
+func (f *Fake) ConvertImage(src, dst, format string) error {
+	return f.copyFile("ConvertImage", src, dst)
+}
+
+func (f *Fake) CopyFile(src, dst string) error {
+	return f.copyFile("CopyFile", src, dst)
+}
+
+// copyFile writes dst as a plain file as large as src, or as a detached
+// copy of src when it is a VHD file
+func (f *Fake) copyFile(method, src, dst string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record(method, src, dst); err != nil {
+		return err
+	}
+	if d := f.Disks[src]; d != nil {
+		f.Disks[dst] = d.copy()
+		return nil
+	}
+	size, ok := f.Files[src]
+	if !ok {
+		return fmt.Errorf("%s: no such file", src)
+	}
+	f.Files[dst] = size
+	return nil
+}
+
+func (f *Fake) CreateTarball(srcDir, dst string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CreateTarball", srcDir, dst); err != nil {
+		return err
+	}
+	f.Files[dst] = 0
+	return nil
+}
+
+func (f *Fake) ExtractTarball(src, dstDir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record("ExtractTarball", src, dstDir)
+}
+
+func (f *Fake) TarballSize(src string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	size, ok := f.Files[src]
+	if !ok {
+		return 0, fmt.Errorf("%s: no such file", src)
+	}
+	return size, nil
+}
+
+func (f *Fake) Rsync(src, dst string, opts wsl.RsyncOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record("Rsync", src, dst)
+}
+
+// RsyncCopy reports the copy done in one step
+func (f *Fake) RsyncCopy(src, dst string, onProgress func(wsl.RsyncProgress)) error {
+	f.mu.Lock()
+	err := f.record("RsyncCopy", src, dst)
+	f.mu.Unlock()
+	if err == nil && onProgress != nil {
+		onProgress(wsl.RsyncProgress{Bytes: 1 << 20, Percent: 100, Rate: "1.00MB/s", ETA: "0:00:01"})
+	}
+	return err
+}
+
+// CountFiles reports empty filesystems
+func (f *Fake) CountFiles(path string) (int, error) { return 0, nil }
+
+// CompareTrees reports Differences
+func (f *Fake) CompareTrees(src, dst string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Differences, f.Errors["CompareTrees"]
+}
+
+// DiskUsage reports empty filesystems
+func (f *Fake) DiskUsage(root string, depth int) ([]wsl.DirUsage, error) {
+	return []wsl.DirUsage{{Path: ".", Bytes: 0}}, nil
+}
+
+// FindFiles finds nothing
+func (f *Fake) FindFiles(root, pattern string, maxDepth int) ([]string, error) { return nil, nil }
+
+func (f *Fake) GetWSLDistributions() ([]wsl.WSLDistribution, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.Distributions), nil
+}
+
+func (f *Fake) FindDistribution(name string) (*wsl.WSLDistribution, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, d := range f.Distributions {
+		if strings.EqualFold(d.Name, name) {
+			return &d, nil
+		}
+	}
+	return nil, fmt.Errorf("distribution %s not found", name)
+}
+
+func (f *Fake) IsDistributionRunning(name string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Running[name], nil
+}
+
+func (f *Fake) StartDistribution(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("StartDistribution", name); err != nil {
+		return err
+	}
+	f.Running[name] = true
+	return nil
+}
+
+func (f *Fake) TerminateDistribution(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("TerminateDistribution", name); err != nil {
+		return err
+	}
+	delete(f.Running, name)
+	return nil
+}
+
+func (f *Fake) ExportDistribution(name, winPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("ExportDistribution", name, winPath); err != nil {
+		return err
+	}
+	f.Files[utils.ConvertWindowsToWSLPath(winPath)] = 0
+	return nil
+}
+
+func (f *Fake) RunInDistribution(name string, args ...string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return "", f.record("RunInDistribution", append([]string{name}, args...)...)
+}
+
+func (f *Fake) CreateDockerVolume(name, device string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CreateDockerVolume", name, device); err != nil {
+		return err
+	}
+	f.Volumes[name] = device
+	return nil
+}
+
+func (f *Fake) DockerVolumeDevice(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Volumes[name], nil
+}
+
+// SelfTest runs no steps
+func (f *Fake) SelfTest(winDir string, progress func(wsl.SelfTestStep)) ([]wsl.SelfTestStep, error) {
+	return nil, f.Errors["SelfTest"]
+}
@@ -0,0 +1,48 @@
+package wslfake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rjdinis/vhdm/internal/wsl"
+)
+
+// Runner is a wsl.Runner that records commands instead of running them.
+// Commands succeed with no output unless Outputs or Errors has an entry for
+// their command line (see wsl.CommandLine), e.g. "systemctl is-active x".
+type Runner struct {
+	mu sync.Mutex
+
+	// Outputs are the outputs of commands by command line
+	Outputs map[string]string
+	// Errors makes the command with that command line fail
+	Errors map[string]error
+	// Commands records the command lines run, in order
+	Commands []string
+}
+
+var _ wsl.Runner = (*Runner)(nil)
+
+// NewRunner returns a Runner on which every command succeeds
+func NewRunner() *Runner {
+	return &Runner{
+		Outputs: make(map[string]string),
+		Errors:  make(map[string]error),
+	}
+}
+
+func (r *Runner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return r.run(name, args)
+}
+
+func (r *Runner) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return r.run(name, args)
+}
+
+func (r *Runner) run(name string, args []string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	line := wsl.CommandLine(name, args)
+	r.Commands = append(r.Commands, line)
+	return []byte(r.Outputs[line]), r.Errors[line]
+}
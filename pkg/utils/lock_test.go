@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	first, err := LockFile(path, time.Second)
+	if err != nil {
+		t.Fatalf("LockFile() error = %v", err)
+	}
+
+	if _, err := LockFile(path, 100*time.Millisecond); !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("LockFile() while held error = %v, want ErrLockTimeout", err)
+	}
+
+	first.Unlock()
+
+	second, err := LockFile(path, time.Second)
+	if err != nil {
+		t.Fatalf("LockFile() after unlock error = %v", err)
+	}
+	second.Unlock()
+}
+
+func TestLockHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	lock, err := LockFile(path, time.Second)
+	if err != nil {
+		t.Fatalf("LockFile() error = %v", err)
+	}
+	if _, err := os.Stat("/proc/locks"); err != nil {
+		lock.Unlock()
+		t.Skip("/proc/locks not available")
+	}
+
+	if pid, err := LockHolder(path); err != nil || pid != os.Getpid() {
+		t.Errorf("LockHolder() = %d, %v; want %d", pid, err, os.Getpid())
+	}
+
+	lock.Unlock()
+	if pid, err := LockHolder(path); err != nil || pid != 0 {
+		t.Errorf("LockHolder() after unlock = %d, %v; want 0", pid, err)
+	}
+}
+
+func TestParseLockHolder(t *testing.T) {
+	procLocks := "1: POSIX  ADVISORY  WRITE 99 08:20:5678 0 EOF\n" +
+		"2: FLOCK  ADVISORY  WRITE 1234 08:20:5678 0 EOF\n" +
+		"3: FLOCK  ADVISORY  WRITE 4321 00:1a:42 0 EOF\n"
+
+	// dev 0x0820 = major 8, minor 32
+	if got := parseLockHolder(procLocks, 0x0820, 5678); got != 1234 {
+		t.Errorf("parseLockHolder() = %d, want 1234", got)
+	}
+	if got := parseLockHolder(procLocks, 0x001a, 42); got != 4321 {
+		t.Errorf("parseLockHolder() = %d, want 4321", got)
+	}
+	if got := parseLockHolder(procLocks, 0x0820, 1); got != 0 {
+		t.Errorf("parseLockHolder() for unlocked inode = %d, want 0", got)
+	}
+}
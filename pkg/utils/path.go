@@ -22,3 +22,37 @@ func ConvertWindowsToWSLPath(winPath string) string {
 func NormalizePath(path string) string {
 	return strings.ToLower(strings.ReplaceAll(path, "\\", "/"))
 }
+
+// WindowsDrive returns the uppercase drive of a Windows path (e.g., "C:"),
+// or an empty string if the path has no drive letter
+func WindowsDrive(winPath string) string {
+	if len(winPath) >= 2 && winPath[1] == ':' {
+		return strings.ToUpper(winPath[:1]) + ":"
+	}
+	return ""
+}
+
+// ResolveVHDPath resolves a relative VHD path against the base directories
+// in baseDirs: "disk.vhdx" against baseDirs[""], and the drive-relative
+// "D:disk.vhdx" against baseDirs["D:"], else the root of D:. Absolute paths,
+// and relative paths without a base directory, are returned unchanged.
+func ResolveVHDPath(path string, baseDirs map[string]string) string {
+	if path == "" || strings.HasPrefix(path, "/") || strings.HasPrefix(path, "\\") {
+		return path
+	}
+	base, rel := baseDirs[""], path
+	if drive := WindowsDrive(path); drive != "" {
+		rel = path[2:]
+		if rel == "" || rel[0] == '/' || rel[0] == '\\' {
+			return path
+		}
+		base = baseDirs[drive]
+		if base == "" {
+			base = drive + "/"
+		}
+	}
+	if base == "" {
+		return path
+	}
+	return strings.TrimRight(base, "/\\") + "/" + strings.TrimPrefix(rel, "./")
+}
@@ -50,3 +50,53 @@ func TestNormalizePath(t *testing.T) {
 		})
 	}
 }
+
+func TestWindowsDrive(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"empty", "", ""},
+		{"C drive", "C:/VMs/disk.vhdx", "C:"},
+		{"lowercase d", "d:/data/disk.vhdx", "D:"},
+		{"backslashes", "E:\\VMs\\disk.vhdx", "E:"},
+		{"no drive", "unknown-761c723c", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WindowsDrive(tt.path); got != tt.want {
+				t.Errorf("WindowsDrive(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveVHDPath(t *testing.T) {
+	baseDirs := map[string]string{"": "C:/VMs/", "D:": "D:/Data/VMs"}
+	tests := []struct {
+		name     string
+		path     string
+		baseDirs map[string]string
+		want     string
+	}{
+		{"empty", "", baseDirs, ""},
+		{"absolute", "E:/disk.vhdx", baseDirs, "E:/disk.vhdx"},
+		{"absolute backslash", "E:\\disk.vhdx", baseDirs, "E:\\disk.vhdx"},
+		{"linux path", "/mnt/c/disk.vhdx", baseDirs, "/mnt/c/disk.vhdx"},
+		{"relative", "disk.vhdx", baseDirs, "C:/VMs/disk.vhdx"},
+		{"relative dot", "./sub/disk.vhdx", baseDirs, "C:/VMs/sub/disk.vhdx"},
+		{"drive relative", "d:disk.vhdx", baseDirs, "D:/Data/VMs/disk.vhdx"},
+		{"drive relative no base", "E:disk.vhdx", baseDirs, "E:/disk.vhdx"},
+		{"relative no base", "disk.vhdx", nil, "disk.vhdx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveVHDPath(tt.path, tt.baseDirs); got != tt.want {
+				t.Errorf("ResolveVHDPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,18 +7,23 @@ import (
 
 // Color codes
 const (
-	colorReset  = "\033[0m"
-	colorRed    = "\033[31m"
-	colorGreen  = "\033[32m"
-	colorYellow = "\033[33m"
-	colorBlue   = "\033[34m"
+	colorReset       = "\033[0m"
+	colorRed         = "\033[31m"
+	colorGreen       = "\033[32m"
+	colorYellow      = "\033[33m"
+	colorBlue        = "\033[34m"
+	colorMagenta     = "\033[35m"
+	colorCyan        = "\033[36m"
+	colorBold        = "\033[1m"
+	colorBoldMagenta = "\033[1;35m"
 )
 
-// Color functions
-func Red(s string) string    { return colorRed + s + colorReset }
-func Green(s string) string  { return colorGreen + s + colorReset }
-func Yellow(s string) string { return colorYellow + s + colorReset }
-func Blue(s string) string   { return colorBlue + s + colorReset }
+// Color methods, by meaning: green for OK, yellow for warnings, blue for
+// information and red for errors. The actual colors are those of the theme.
+func (t Theme) Red(s string) string    { return colorize(t.Error, s) }
+func (t Theme) Green(s string) string  { return colorize(t.OK, s) }
+func (t Theme) Yellow(s string) string { return colorize(t.Warn, s) }
+func (t Theme) Blue(s string) string   { return colorize(t.Info, s) }
 
 // PrintTableHeader prints table header
 func PrintTableHeader(widths []int, headers []string) {
@@ -65,11 +70,7 @@ func printTableRow(widths []int, values []string) {
 
 func visibleLen(s string) int {
 	// Remove ANSI color codes for length calculation
-	clean := s
-	for _, code := range []string{colorReset, colorRed, colorGreen, colorYellow, colorBlue} {
-		clean = strings.ReplaceAll(clean, code, "")
-	}
-	return len(clean)
+	return len(ansiRe.ReplaceAllString(s, ""))
 }
 
 func truncate(s string, maxLen int) string {
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"os"
+	"os/user"
+	"path"
+	"strings"
+)
+
+// ExpandTemplate replaces {name} placeholders in s with values from vars.
+// Unknown placeholders are left untouched.
+func ExpandTemplate(s string, vars map[string]string) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	pairs := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// MountPointVars returns the placeholder values available in mount point templates:
+// {user} (the invoking user, preferring SUDO_USER), {hostname}, and {vhdname}
+// (the VHD file name without extension)
+func MountPointVars(vhdPath string) map[string]string {
+	vars := map[string]string{
+		"user":     currentUser(),
+		"hostname": "",
+		"vhdname":  "",
+	}
+	if host, err := os.Hostname(); err == nil {
+		vars["hostname"] = host
+	}
+	if vhdPath != "" {
+		vars["vhdname"] = VHDName(vhdPath)
+	}
+	return vars
+}
+
+// VHDName returns the file name of a VHD path without extension
+func VHDName(vhdPath string) string {
+	base := path.Base(strings.ReplaceAll(vhdPath, "\\", "/"))
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// ExpandMountPoint expands {user}, {hostname} and {vhdname} in a mount point
+func ExpandMountPoint(mountPoint, vhdPath string) string {
+	if !strings.Contains(mountPoint, "{") {
+		return mountPoint
+	}
+	return ExpandTemplate(mountPoint, MountPointVars(vhdPath))
+}
+
+// currentUser returns the invoking user name, preferring SUDO_USER when running with sudo
+func currentUser() string {
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		return sudoUser
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package utils
+
+import "testing"
+
+func TestExpandTemplate(t *testing.T) {
+	vars := map[string]string{"user": "alice", "hostname": "devbox", "vhdname": "data"}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"no placeholders", "/mnt/data", "/mnt/data"},
+		{"user", "/home/{user}/vhd", "/home/alice/vhd"},
+		{"multiple", "/mnt/{hostname}/{user}/{vhdname}", "/mnt/devbox/alice/data"},
+		{"repeated", "/mnt/{vhdname}-{vhdname}", "/mnt/data-data"},
+		{"unknown kept", "/mnt/{unknown}", "/mnt/{unknown}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExpandTemplate(tt.input, vars)
+			if got != tt.want {
+				t.Errorf("ExpandTemplate(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMountPointVars(t *testing.T) {
+	t.Setenv("SUDO_USER", "bob")
+
+	tests := []struct {
+		name    string
+		vhdPath string
+		want    string
+	}{
+		{"forward slashes", "C:/VMs/project-data.vhdx", "project-data"},
+		{"backslashes", "C:\\VMs\\disk.vhd", "disk"},
+		{"empty path", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := MountPointVars(tt.vhdPath)
+			if vars["vhdname"] != tt.want {
+				t.Errorf("vhdname = %q, want %q", vars["vhdname"], tt.want)
+			}
+			if vars["user"] != "bob" {
+				t.Errorf("user = %q, want bob", vars["user"])
+			}
+		})
+	}
+}
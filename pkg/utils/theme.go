@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Theme holds the colors and symbols of status output. Colors are ANSI
+// escape sequences, empty for uncolored text.
+type Theme struct {
+	OK    string // Mounted VHDs, successes (Green)
+	Warn  string // Attached VHDs, warnings (Yellow)
+	Info  string // Detached VHDs, debug output (Blue)
+	Error string // Missing VHDs, errors (Red)
+
+	Active   string // Bullet of an active service
+	Inactive string // Bullet of an inactive service
+	Success  string // Mark of a successful step
+}
+
+// themes are the built-in themes selectable with VHDM_THEME
+var themes = map[string]Theme{
+	"default": {
+		OK: colorGreen, Warn: colorYellow, Info: colorBlue, Error: colorRed,
+		Active: "●", Inactive: "○", Success: "✓",
+	},
+	// Blue and yellow stay distinguishable with red-green color blindness
+	"colorblind": {
+		OK: colorBlue, Warn: colorYellow, Info: colorCyan, Error: colorBoldMagenta,
+		Active: "●", Inactive: "○", Success: "✓",
+	},
+	"mono": {
+		Active: "●", Inactive: "○", Success: "✓",
+	},
+	"ascii": {
+		Active: "*", Inactive: "-", Success: "OK",
+	},
+}
+
+// themeColors are the color names accepted in theme overrides
+var themeColors = map[string]string{
+	"none":    "",
+	"red":     colorRed,
+	"green":   colorGreen,
+	"yellow":  colorYellow,
+	"blue":    colorBlue,
+	"magenta": colorMagenta,
+	"cyan":    colorCyan,
+	"bold":    colorBold,
+}
+
+// ansiRe matches ANSI color escape sequences
+var ansiRe = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// ThemeNames returns the names of the built-in themes
+func ThemeNames() []string {
+	return slices.Sorted(maps.Keys(themes))
+}
+
+// ParseTheme parses a theme spec: a built-in theme name, optionally followed by
+// comma-separated overrides of its colors (ok, warn, info, error) and symbols
+// (active, inactive, success), e.g. "colorblind,active=*,error=red".
+func ParseTheme(spec string) (Theme, error) {
+	parts := strings.Split(spec, ",")
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		name = "default"
+	}
+	theme, ok := themes[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q (use %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return Theme{}, fmt.Errorf("invalid theme override %q (use key=value)", part)
+		}
+		var field *string
+		switch key {
+		case "ok":
+			field = &theme.OK
+		case "warn":
+			field = &theme.Warn
+		case "info":
+			field = &theme.Info
+		case "error":
+			field = &theme.Error
+		case "active":
+			theme.Active = value
+			continue
+		case "inactive":
+			theme.Inactive = value
+			continue
+		case "success":
+			theme.Success = value
+			continue
+		default:
+			return Theme{}, fmt.Errorf("unknown theme key %q (use ok, warn, info, error, active, inactive or success)", key)
+		}
+		color, ok := themeColors[value]
+		if !ok {
+			return Theme{}, fmt.Errorf("unknown color %q for %s (use %s)", value, key, strings.Join(slices.Sorted(maps.Keys(themeColors)), ", "))
+		}
+		*field = color
+	}
+	return theme, nil
+}
+
+// DefaultTheme returns the theme used when VHDM_THEME is not set
+func DefaultTheme() Theme {
+	return themes["default"]
+}
+
+// colorize wraps s in an ANSI color, leaving it plain when color is empty
+func colorize(color, s string) string {
+	if color == "" {
+		return s
+	}
+	return color + s + colorReset
+}
@@ -0,0 +1,37 @@
+package utils
+
+import "testing"
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme("colorblind,active=*,error=red")
+	if err != nil {
+		t.Fatalf("ParseTheme() error = %v", err)
+	}
+	if theme.OK != colorBlue || theme.Error != colorRed || theme.Active != "*" || theme.Inactive != "○" {
+		t.Errorf("ParseTheme() = %+v, want colorblind with active and error overridden", theme)
+	}
+
+	if theme, err := ParseTheme(""); err != nil || theme != themes["default"] {
+		t.Errorf("ParseTheme(\"\") = %+v, %v, want the default theme", theme, err)
+	}
+
+	for _, spec := range []string{"neon", "ascii,ok", "ascii,ok=purple", "ascii,bullet=*"} {
+		if _, err := ParseTheme(spec); err == nil {
+			t.Errorf("ParseTheme(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestColorMethodsFollowTheme(t *testing.T) {
+	if got := themes["ascii"].Green("mounted"); got != "mounted" {
+		t.Errorf("Green() with ascii theme = %q, want plain text", got)
+	}
+
+	theme := DefaultTheme()
+	if got := theme.Green("mounted"); got != colorGreen+"mounted"+colorReset {
+		t.Errorf("Green() with default theme = %q", got)
+	}
+	if n := visibleLen(theme.Red("abc")); n != 3 {
+		t.Errorf("visibleLen() = %d, want 3", n)
+	}
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"strconv"
+	"time"
+)
+
+// TimeFormats are the timestamp formats accepted by FormatTime: local date
+// and time, RFC 3339, or seconds since the Unix epoch
+var TimeFormats = []string{"local", "rfc3339", "unix"}
+
+// FormatTime formats t in one of TimeFormats; unknown formats use "local"
+func FormatTime(t time.Time, format string) string {
+	switch format {
+	case "rfc3339":
+		return t.Format(time.RFC3339)
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	default:
+		return t.Local().Format("2006-01-02 15:04:05")
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatTime(t *testing.T) {
+	ts := time.Date(2025, 3, 4, 5, 6, 7, 0, time.FixedZone("WET", 0))
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"rfc3339", "2025-03-04T05:06:07Z"},
+		{"unix", "1741064767"},
+		{"local", ts.Local().Format("2006-01-02 15:04:05")},
+		{"", ts.Local().Format("2006-01-02 15:04:05")},
+	}
+
+	for _, tt := range tests {
+		if got := FormatTime(ts, tt.format); got != tt.want {
+			t.Errorf("FormatTime(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
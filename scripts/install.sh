@@ -6,7 +6,9 @@
 #   curl -sSL https://raw.githubusercontent.com/rjdinis/vhdm/go/scripts/install.sh | bash
 #
 # Options:
-#   INSTALL_DIR  - Installation directory (default: /usr/local/bin)
+#   INSTALL_DIR  - Installation directory (default: /usr/local/bin). vhdm-helper
+#                  is only installed there when the directory and its parents
+#                  are root-owned and not writable by other users
 #
 
 set -euo pipefail
@@ -58,6 +60,32 @@ get_install_dir() {
     fi
 }
 
+# Run a command as root
+as_root() {
+    if [[ $EUID -eq 0 ]]; then
+        "$@"
+    else
+        sudo "$@"
+    fi
+}
+
+# Check that only root can replace files in a directory: it and all its
+# parents must be root-owned and not group or world writable. Otherwise the
+# sudoers rule for vhdm-helper would let users run any program as root.
+root_only_dir() {
+    local dir owner mode
+    dir=$(realpath "$1")
+    while :; do
+        read -r owner mode < <(stat -c '%u %a' "$dir")
+        if [[ "$owner" != 0 ]] || (( 8#$mode & 022 )); then
+            log_error "$dir is not root-owned or is writable by other users"
+            return 1
+        fi
+        [[ "$dir" == / ]] && return 0
+        dir=$(dirname "$dir")
+    done
+}
+
 # Install from source
 install_from_source() {
     local install_dir="$1"
@@ -93,6 +121,8 @@ install_from_source() {
     
     go build -ldflags "-s -w -X main.version=$VERSION -X main.commit=$COMMIT -X main.date=$DATE" \
         -o vhdm ./cmd/vhdm
+    go build -ldflags "-s -w -X main.version=$VERSION -X main.commit=$COMMIT -X main.date=$DATE" \
+        -o vhdm-helper ./cmd/vhdm-helper
     
     # Install binary
     log_info "Installing to $install_dir..."
@@ -103,6 +133,18 @@ install_from_source() {
     fi
     
     log_success "vhdm installed to $install_dir/vhdm"
+
+    # The helper runs as root through sudo, so it must not be replaceable by
+    # anyone but root
+    if ! root_only_dir "$install_dir"; then
+        log_error "NOT installing vhdm-helper: a sudoers rule for a user-writable path grants root to any program put there."
+        log_warn "vhdm will run its privileged steps under sudo directly. To use the helper, rerun with a root-owned INSTALL_DIR such as /usr/local/bin."
+        return
+    fi
+    as_root install -o root -g root -m 0755 vhdm-helper "$install_dir/vhdm-helper"
+    log_success "vhdm-helper installed to $install_dir/vhdm-helper"
+    log_info "To run it without a password, add with 'sudo visudo -f /etc/sudoers.d/vhdm':"
+    echo "    %sudo ALL=(root) NOPASSWD: $install_dir/vhdm-helper"
 }
 
 # Setup shell completions
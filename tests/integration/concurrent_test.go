@@ -197,7 +197,7 @@ func TestConcurrentServiceStartup(t *testing.T) {
 		for _, serviceName := range serviceNames {
 			exec.Command("sudo", "systemctl", "stop", serviceName+".service").Run()
 			exec.Command("sudo", "systemctl", "disable", serviceName+".service").Run()
-			exec.Command("sudo", "rm", filepath.Join("/usr/lib/systemd/system", serviceName+".service")).Run()
+			exec.Command("sudo", "rm", filepath.Join("/etc/systemd/system", serviceName+".service")).Run()
 		}
 		exec.Command("sudo", "systemctl", "daemon-reload").Run()
 	}()